@@ -0,0 +1,93 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+)
+
+// Common errors.
+var (
+	ErrRejectedSource = errors.New("packet rejected by access control")
+)
+
+// applyOptions sets the access control, the rate limits, the logger
+// and the parse options of the conn from o.
+func (s *connState) applyOptions(o options) {
+	s.allowSource, s.allowMethod = o.allowSource, o.allowMethod
+	s.limiter = newRateLimiter(o)
+	s.logger = o.logger
+	s.parse = o.parse
+}
+
+// Rejected returns the number of packets and messages that were dropped by
+// WithAccessControl and WithMethodAccessControl.
+func (s *connState) Rejected() uint64 {
+	s.rejectedMu.Lock()
+	defer s.rejectedMu.Unlock()
+	return s.rejected
+}
+
+// admit returns an error if a packet of size bytes from sender is rejected
+// by the access control, which is counted and reported, or by the rate limits,
+// which is only counted.
+func (s *connState) admit(sender net.Addr, size int) error {
+	if s.allowSource != nil && !s.allowSource(sender) {
+		err := fmt.Errorf("packet from %s: %w", sender, ErrRejectedSource)
+		s.reject(err)
+		return err
+	}
+	if s.limiter != nil && !s.limiter.allow(s.clock.Now(), sender, size) {
+		s.countDrop(ReasonRateLimited)
+		if s.logger != nil {
+			s.log(slog.LevelDebug, "osc: packet dropped by the rate limit", remoteAttr(sender))
+		}
+		return fmt.Errorf("packet from %s: %w", sender, ErrRateLimited)
+	}
+	return nil
+}
+
+// admitMessage returns false if msg is rejected,
+// after counting and reporting it.
+func (s *connState) admitMessage(msg Message) bool {
+	if s.allowMethod == nil || s.allowMethod(msg.Sender, msg.Address) {
+		return true
+	}
+	s.reject(fmt.Errorf("message to %s from %s: %w", msg.Address, msg.Sender, ErrRejectedSource))
+	return false
+}
+
+// admitBundle returns b without the messages that are rejected.
+func (s *connState) admitBundle(b Bundle) Bundle {
+	if s.allowMethod == nil {
+		return b
+	}
+	packets := make([]Packet, 0, len(b.Packets))
+	for _, p := range b.Packets {
+		switch x := p.(type) {
+		case Message:
+			if !s.admitMessage(x) {
+				continue
+			}
+		case Bundle:
+			p = s.admitBundle(x)
+		}
+		packets = append(packets, p)
+	}
+	b.Packets = packets
+	return b
+}
+
+// reject counts and reports a packet or a message that was dropped.
+func (s *connState) reject(err error) {
+	s.rejectedMu.Lock()
+	s.rejected++
+	s.rejectedMu.Unlock()
+	s.countDrop(ReasonRejected)
+
+	if s.logger != nil {
+		s.log(slog.LevelDebug, "osc: rejected by access control", errorAttr(err))
+	}
+	s.handleError(err)
+}
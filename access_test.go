@@ -0,0 +1,119 @@
+package osc
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessControl(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var clients []*UDPConn
+	for i := 0; i < 2; i++ {
+		client, err := ListenUDP("udp", laddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = client.Close() }() // Best effort.
+
+		clients = append(clients, client)
+	}
+	allowed, denied := clients[0], clients[1]
+
+	server, err := ListenUDP("udp", laddr,
+		WithAccessControl(func(addr net.Addr) bool {
+			return addr.String() == allowed.LocalAddr().String()
+		}),
+		WithMethodAccessControl(func(addr net.Addr, oscAddr string) bool {
+			return !strings.HasPrefix(oscAddr, "/admin/")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	var (
+		dispatched = make(chan Message, 8)
+		errs       = make(chan error, 8)
+	)
+	server.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			Default: Method(func(msg Message) error {
+				dispatched <- msg
+				return nil
+			}),
+		})
+	}()
+	for i, testcase := range []struct {
+		From     *UDPConn
+		Packet   Packet
+		Expected []string
+		Rejected int
+	}{
+		{From: denied, Packet: MustMessage("/synth/1"), Rejected: 1},
+		{From: allowed, Packet: MustMessage("/synth/1"), Expected: []string{"/synth/1"}},
+		{From: allowed, Packet: MustMessage("/admin/reset"), Rejected: 1},
+		{
+			From: allowed,
+			Packet: Bundle{Timetag: Immediately, Packets: []Packet{
+				MustMessage("/synth/2"),
+				MustMessage("/admin/reset"),
+				MustMessage("/synth/3"),
+			}},
+			Expected: []string{"/synth/2", "/synth/3"},
+			Rejected: 1,
+		},
+		{
+			From:     denied,
+			Packet:   Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/synth/4")}},
+			Rejected: 1,
+		},
+	} {
+		before := server.Rejected()
+		if err := testcase.From.SendTo(server.LocalAddr(), testcase.Packet); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < testcase.Rejected; j++ {
+			select {
+			case err := <-errs:
+				if !errors.Is(err, ErrRejectedSource) {
+					t.Fatalf("(testcase %d) expected ErrRejectedSource, got %v", i, err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("(testcase %d) timeout waiting for rejection %d", i, j)
+			}
+		}
+		for j, expected := range testcase.Expected {
+			select {
+			case msg := <-dispatched:
+				if msg.Address != expected {
+					t.Fatalf("(testcase %d) message %d: expected %s, got %s", i, j, expected, msg.Address)
+				}
+				if msg.Sender.String() != allowed.LocalAddr().String() {
+					t.Fatalf("(testcase %d) message %d: expected sender %s, got %s", i, j, allowed.LocalAddr(), msg.Sender)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("(testcase %d) timeout waiting for message %d", i, j)
+			}
+		}
+		if rejected := server.Rejected() - before; rejected != uint64(testcase.Rejected) {
+			t.Fatalf("(testcase %d) expected %d rejected, got %d", i, testcase.Rejected, rejected)
+		}
+	}
+	select {
+	case msg := <-dispatched:
+		t.Fatalf("expected no more messages, got %s", msg.Address)
+	case err := <-errs:
+		t.Fatalf("expected no more errors, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
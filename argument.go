@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-
-	"github.com/pkg/errors"
+	"math"
 )
 
 // Argument represents an OSC argument.
@@ -19,32 +19,26 @@ type Argument interface {
 	Bytes() []byte
 	Equal(Argument) bool
 	ReadInt32() (int32, error)
+	ReadInt64() (int64, error)
 	ReadFloat32() (float32, error)
+	ReadFloat64() (float64, error)
 	ReadBool() (bool, error)
 	ReadString() (string, error)
 	ReadBlob() ([]byte, error)
+	ReadChar() (rune, error)
+	ReadRGBA() (RGBA, error)
+	ReadMIDI() (MIDI, error)
+	ReadSymbol() (string, error)
+	ReadTimetag() (Timetag, error)
+	ReadArray() (Arguments, error)
 	String() string
 	Typetag() byte
 }
 
 // ReadArguments reads all arguments from the reader and adds it to the OSC message.
+// Arguments whose typetags are enclosed in '[' and ']' are grouped into an Array.
 func ReadArguments(typetags, data []byte) ([]Argument, error) {
-	args := []Argument{}
-
-	// Strip off the prefix.
-	if len(typetags) > 0 && typetags[0] == TypetagPrefix {
-		typetags = typetags[1:]
-	}
-
-	for i, tt := range typetags {
-		arg, idx, err := ReadArgument(tt, data)
-		if err != nil {
-			return nil, errors.Wrapf(err, "read argument %d", i)
-		}
-		args = append(args, arg)
-		data = data[idx:]
-	}
-	return args, nil
+	return newArgumentReader(typetags, data).read(0)
 }
 
 // ReadArgument parses an OSC message argument given a type tag and some data.
@@ -54,17 +48,106 @@ func ReadArgument(tt byte, data []byte) (Argument, int64, error) {
 		return ReadIntFrom(data)
 	case TypetagFloat:
 		return ReadFloatFrom(data)
+	case TypetagInt64:
+		return ReadInt64From(data)
+	case TypetagDouble:
+		return ReadDoubleFrom(data)
 	case TypetagTrue:
 		return Bool(true), 0, nil
 	case TypetagFalse:
 		return Bool(false), 0, nil
+	case TypetagChar:
+		return ReadCharFrom(data)
+	case TypetagRGBA:
+		return ReadRGBAFrom(data)
+	case TypetagMIDI:
+		return ReadMIDIFrom(data)
+	case TypetagNil:
+		return Nil{}, 0, nil
+	case TypetagInfinitum:
+		return Infinitum{}, 0, nil
 	case TypetagString:
 		s, idx := ReadString(data)
 		return String(s), idx, nil
 	case TypetagBlob:
 		return ReadBlobFrom(data)
+	case TypetagSymbol:
+		s, idx := ReadString(data)
+		return Symbol(s), idx, nil
+	case TypetagTimetag:
+		return ReadTimetagFrom(data)
+	default:
+		return nil, 0, fmt.Errorf("typetag %q: %w", string(tt), ErrInvalidTypeTag)
+	}
+}
+
+// argumentsSize returns the size of the encoded args and how many typetags they have.
+// Nil arguments can not be encoded.
+func argumentsSize(args []Argument) (size, typetags int, err error) {
+	for i, a := range args {
+		switch x := a.(type) {
+		case nil:
+			return 0, 0, fmt.Errorf("argument %d is nil: %w", i, ErrUnsupportedType)
+		case Array:
+			n, tt, err := argumentsSize(x)
+			if err != nil {
+				return 0, 0, fmt.Errorf("argument %d: %w", i, err)
+			}
+			size, typetags = size+n, typetags+tt+2
+		default:
+			size, typetags = size+argumentSize(a), typetags+1
+		}
+	}
+	return size, typetags, nil
+}
+
+// argumentSize returns the size of the encoded argument, which must not be an Array.
+func argumentSize(a Argument) int {
+	switch x := a.(type) {
+	case Bool, Nil, Infinitum:
+		return 0
+	case Int, Float, Char, RGBA, MIDI:
+		return 4
+	case Int64, Double, Timetag:
+		return 8
+	case String:
+		return stringSize(string(x))
+	case Symbol:
+		return padded(len(x) + 1)
+	case Blob:
+		return 4 + padded(len(x))
+	default:
+		return len(a.Bytes())
+	}
+}
+
+// appendArgument appends the encoded argument to b, which must have a multiple of 4 bytes.
+// It encodes the same bytes as a.Bytes() without allocating.
+func appendArgument(b []byte, a Argument) []byte {
+	switch x := a.(type) {
+	case Int:
+		return appendUint32(b, uint32(x))
+	case Float:
+		return appendUint32(b, math.Float32bits(float32(x)))
+	case Int64:
+		return appendUint64(b, uint64(x))
+	case Double:
+		return appendUint64(b, math.Float64bits(float64(x)))
+	case Timetag:
+		return appendUint64(b, uint64(x))
+	case String:
+		return appendString(b, string(x))
+	case Symbol:
+		return Pad(append(append(b, x...), 0))
+	case Blob:
+		return Pad(append(appendUint32(b, uint32(len(x))), x...))
+	case Array:
+		for _, elem := range x {
+			b = appendArgument(b, elem)
+		}
+		return b
 	default:
-		return nil, 0, errors.Wrapf(ErrInvalidTypeTag, "typetag %q", string(tt))
+		return append(b, a.Bytes()...)
 	}
 }
 
@@ -75,7 +158,7 @@ type Int int32
 func ReadIntFrom(data []byte) (Argument, int64, error) {
 	var i Int
 	if err := binary.Read(bytes.NewReader(data), byteOrder, &i); err != nil {
-		return nil, 0, errors.Wrap(err, "read int argument")
+		return nil, 0, fmt.Errorf("read int argument: %w", err)
 	}
 	return i, 4, nil
 }
@@ -95,16 +178,25 @@ func (i Int) Equal(other Argument) bool {
 	if other.Typetag() != TypetagInt {
 		return false
 	}
-	i2 := other.(Int)
+	i2, ok := other.(Int)
+	if !ok {
+		return equalEncoded(i, other)
+	}
 	return i == i2
 }
 
 // ReadInt32 reads a 32-bit integer from the arg.
 func (i Int) ReadInt32() (int32, error) { return int32(i), nil }
 
+// ReadInt64 reads a 64-bit integer from the arg.
+func (i Int) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadFloat32 reads a 32-bit float from the arg.
 func (i Int) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadFloat64 reads a 64-bit float from the arg.
+func (i Int) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadBool bool reads a boolean from the arg.
 func (i Int) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
 
@@ -114,6 +206,24 @@ func (i Int) ReadString() (string, error) { return "", ErrInvalidTypeTag }
 // ReadBlob reads a slice of bytes from the arg.
 func (i Int) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
 
+// ReadChar reads an ASCII character from the arg.
+func (i Int) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (i Int) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (i Int) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (i Int) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (i Int) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (i Int) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
 // String converts the arg to a string.
 func (i Int) String() string { return fmt.Sprintf("Int(%d)", i) }
 
@@ -133,7 +243,7 @@ type Float float32
 func ReadFloatFrom(data []byte) (Argument, int64, error) {
 	var f Float
 	if err := binary.Read(bytes.NewReader(data), byteOrder, &f); err != nil {
-		return nil, 0, errors.Wrap(err, "read float argument")
+		return nil, 0, fmt.Errorf("read float argument: %w", err)
 	}
 	return f, 4, nil
 }
@@ -148,20 +258,30 @@ func (f Float) Bytes() []byte {
 }
 
 // Equal returns true if the argument equals the other one, false otherwise.
+// Floats are equal if they have the same bits, so NaN equals itself and 0 does not equal -0.
 func (f Float) Equal(other Argument) bool {
 	if other.Typetag() != TypetagFloat {
 		return false
 	}
-	f2 := other.(Float)
-	return f == f2
+	f2, ok := other.(Float)
+	if !ok {
+		return equalEncoded(f, other)
+	}
+	return math.Float32bits(float32(f)) == math.Float32bits(float32(f2))
 }
 
 // ReadInt32 reads a 32-bit integer from the arg.
 func (f Float) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadInt64 reads a 64-bit integer from the arg.
+func (f Float) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadFloat32 reads a 32-bit float from the arg.
 func (f Float) ReadFloat32() (float32, error) { return float32(f), nil }
 
+// ReadFloat64 reads a 64-bit float from the arg.
+func (f Float) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadBool bool reads a boolean from the arg.
 func (f Float) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
 
@@ -171,6 +291,24 @@ func (f Float) ReadString() (string, error) { return "", ErrInvalidTypeTag }
 // ReadBlob reads a slice of bytes from the arg.
 func (f Float) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
 
+// ReadChar reads an ASCII character from the arg.
+func (f Float) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (f Float) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (f Float) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (f Float) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (f Float) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (f Float) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
 // String converts the arg to a string.
 func (f Float) String() string { return fmt.Sprintf("Float(%f)", f) }
 
@@ -183,6 +321,169 @@ func (f Float) WriteTo(w io.Writer) (int64, error) {
 	return int64(written), err
 }
 
+// Int64 represents a 64-bit integer.
+type Int64 int64
+
+// ReadInt64From reads a 64-bit integer from a byte slice.
+func ReadInt64From(data []byte) (Argument, int64, error) {
+	if len(data) < 8 {
+		return nil, 0, fmt.Errorf("read int64 argument: %d bytes left: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	return Int64(byteOrder.Uint64(data)), 8, nil
+}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (i Int64) Bytes() []byte {
+	b := make([]byte, 8)
+	byteOrder.PutUint64(b, uint64(i))
+	return b
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (i Int64) Equal(other Argument) bool {
+	if other.Typetag() != TypetagInt64 {
+		return false
+	}
+	i2, ok := other.(Int64)
+	if !ok {
+		return equalEncoded(i, other)
+	}
+	return i == i2
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (i Int64) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (i Int64) ReadInt64() (int64, error) { return int64(i), nil }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (i Int64) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (i Int64) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (i Int64) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (i Int64) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (i Int64) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (i Int64) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (i Int64) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (i Int64) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (i Int64) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (i Int64) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (i Int64) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (i Int64) String() string { return fmt.Sprintf("Int64(%d)", i) }
+
+// Typetag returns the argument's type tag.
+func (i Int64) Typetag() byte { return TypetagInt64 }
+
+// WriteTo writes the arg to an io.Writer.
+func (i Int64) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprintf(w, "%d", i)
+	return int64(written), err
+}
+
+// Double represents a 64-bit float.
+type Double float64
+
+// ReadDoubleFrom reads a 64-bit float from a byte slice.
+func ReadDoubleFrom(data []byte) (Argument, int64, error) {
+	if len(data) < 8 {
+		return nil, 0, fmt.Errorf("read double argument: %d bytes left: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	return Double(math.Float64frombits(byteOrder.Uint64(data))), 8, nil
+}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (d Double) Bytes() []byte {
+	b := make([]byte, 8)
+	byteOrder.PutUint64(b, math.Float64bits(float64(d)))
+	return b
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+// Doubles are equal if they have the same bits, so NaN equals itself and 0 does not equal -0.
+func (d Double) Equal(other Argument) bool {
+	if other.Typetag() != TypetagDouble {
+		return false
+	}
+	d2, ok := other.(Double)
+	if !ok {
+		return equalEncoded(d, other)
+	}
+	return math.Float64bits(float64(d)) == math.Float64bits(float64(d2))
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (d Double) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (d Double) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (d Double) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (d Double) ReadFloat64() (float64, error) { return float64(d), nil }
+
+// ReadBool bool reads a boolean from the arg.
+func (d Double) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (d Double) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (d Double) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (d Double) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (d Double) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (d Double) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (d Double) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (d Double) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (d Double) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (d Double) String() string { return fmt.Sprintf("Double(%f)", d) }
+
+// Typetag returns the argument's type tag.
+func (d Double) Typetag() byte { return TypetagDouble }
+
+// WriteTo writes the arg to an io.Writer.
+func (d Double) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprintf(w, "%f", d)
+	return int64(written), err
+}
+
 // Bool represents a boolean value.
 type Bool bool
 
@@ -196,16 +497,25 @@ func (b Bool) Equal(other Argument) bool {
 	if other.Typetag() != TypetagFalse && other.Typetag() != TypetagTrue {
 		return false
 	}
-	b2 := other.(Bool)
+	b2, ok := other.(Bool)
+	if !ok {
+		return equalEncoded(b, other)
+	}
 	return b == b2
 }
 
 // ReadInt32 reads a 32-bit integer from the arg.
 func (b Bool) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadInt64 reads a 64-bit integer from the arg.
+func (b Bool) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadFloat32 reads a 32-bit float from the arg.
 func (b Bool) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadFloat64 reads a 64-bit float from the arg.
+func (b Bool) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadBool bool reads a boolean from the arg.
 func (b Bool) ReadBool() (bool, error) { return bool(b), nil }
 
@@ -215,6 +525,24 @@ func (b Bool) ReadString() (string, error) { return "", ErrInvalidTypeTag }
 // ReadBlob reads a slice of bytes from the arg.
 func (b Bool) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
 
+// ReadChar reads an ASCII character from the arg.
+func (b Bool) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (b Bool) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (b Bool) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (b Bool) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (b Bool) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (b Bool) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
 // String converts the arg to a string.
 func (b Bool) String() string { return fmt.Sprintf("Bool(%t)", b) }
 
@@ -232,6 +560,136 @@ func (b Bool) WriteTo(w io.Writer) (int64, error) {
 	return int64(written), err
 }
 
+// Nil is the OSC 1.1 nil argument.
+// It has no data, only a typetag.
+type Nil struct{}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (n Nil) Bytes() []byte {
+	return []byte{}
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (n Nil) Equal(other Argument) bool {
+	return other.Typetag() == TypetagNil
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (n Nil) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (n Nil) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (n Nil) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (n Nil) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (n Nil) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (n Nil) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (n Nil) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (n Nil) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (n Nil) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (n Nil) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (n Nil) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (n Nil) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (n Nil) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (n Nil) String() string { return "Nil" }
+
+// Typetag returns the argument's type tag.
+func (n Nil) Typetag() byte { return TypetagNil }
+
+// WriteTo writes the arg to an io.Writer.
+func (n Nil) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprint(w, "nil")
+	return int64(written), err
+}
+
+// Infinitum is the OSC 1.1 infinitum argument, sometimes called impulse.
+// It has no data, only a typetag.
+type Infinitum struct{}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (i Infinitum) Bytes() []byte {
+	return []byte{}
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (i Infinitum) Equal(other Argument) bool {
+	return other.Typetag() == TypetagInfinitum
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (i Infinitum) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (i Infinitum) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (i Infinitum) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (i Infinitum) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (i Infinitum) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (i Infinitum) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (i Infinitum) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (i Infinitum) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (i Infinitum) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (i Infinitum) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (i Infinitum) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (i Infinitum) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (i Infinitum) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (i Infinitum) String() string { return "Infinitum" }
+
+// Typetag returns the argument's type tag.
+func (i Infinitum) Typetag() byte { return TypetagInfinitum }
+
+// WriteTo writes the arg to an io.Writer.
+func (i Infinitum) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprint(w, "infinitum")
+	return int64(written), err
+}
+
 // String is a string.
 type String string
 
@@ -245,16 +703,25 @@ func (s String) Equal(other Argument) bool {
 	if other.Typetag() != TypetagString {
 		return false
 	}
-	s2 := other.(String)
+	s2, ok := other.(String)
+	if !ok {
+		return equalEncoded(s, other)
+	}
 	return s == s2
 }
 
 // ReadInt32 reads a 32-bit integer from the arg.
 func (s String) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadInt64 reads a 64-bit integer from the arg.
+func (s String) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadFloat32 reads a 32-bit float from the arg.
 func (s String) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadFloat64 reads a 64-bit float from the arg.
+func (s String) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadBool bool reads a boolean from the arg.
 func (s String) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
 
@@ -264,6 +731,24 @@ func (s String) ReadString() (string, error) { return string(s), nil }
 // ReadBlob reads a slice of bytes from the arg.
 func (s String) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
 
+// ReadChar reads an ASCII character from the arg.
+func (s String) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (s String) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (s String) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (s String) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (s String) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (s String) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
 // String converts the arg to a string.
 func (s String) String() string { return string(s) }
 
@@ -276,25 +761,110 @@ func (s String) WriteTo(w io.Writer) (int64, error) {
 	return int64(written), err
 }
 
+// Symbol is a string that is sent with the 'S' typetag,
+// which SuperCollider uses for symbols.
+type Symbol string
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+// Unlike String, the empty symbol is encoded with a null byte and padding.
+func (s Symbol) Bytes() []byte {
+	return Pad(append([]byte(s), 0))
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (s Symbol) Equal(other Argument) bool {
+	if other.Typetag() != TypetagSymbol {
+		return false
+	}
+	s2, ok := other.(Symbol)
+	if !ok {
+		return equalEncoded(s, other)
+	}
+	return s == s2
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (s Symbol) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (s Symbol) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (s Symbol) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (s Symbol) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (s Symbol) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+// Symbols are read with ReadSymbol.
+func (s Symbol) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (s Symbol) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (s Symbol) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (s Symbol) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (s Symbol) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (s Symbol) ReadSymbol() (string, error) { return string(s), nil }
+
+// ReadTimetag reads a timetag from the arg.
+func (s Symbol) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (s Symbol) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (s Symbol) String() string { return fmt.Sprintf("Symbol(%s)", string(s)) }
+
+// Typetag returns the argument's type tag.
+func (s Symbol) Typetag() byte { return TypetagSymbol }
+
+// WriteTo writes the arg to an io.Writer.
+func (s Symbol) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprintf(w, "%s", string(s))
+	return int64(written), err
+}
+
 // Blob is a slice of bytes.
 type Blob []byte
 
+// Errors of the blobs whose length does not match the data they are read from.
+var (
+	ErrNegativeBlobLength = errors.New("negative blob length")
+	ErrBlobPastEnd        = errors.New("blob length is past the end of the data")
+)
+
 // ReadBlobFrom reads a binary blob from the provided data.
+// The blob is a view of data, without its padding, so it changes if data does.
 func ReadBlobFrom(data []byte) (Argument, int64, error) {
 	var length int32
 	if err := binary.Read(bytes.NewReader(data), byteOrder, &length); err != nil {
-		return nil, 0, errors.Wrap(err, "read blob argument")
+		return nil, 0, fmt.Errorf("read blob argument: %w", err)
+	}
+	if length < 0 {
+		return nil, 0, fmt.Errorf("read blob argument: length %d: %w", length, ErrNegativeBlobLength)
 	}
-	b, bl := ReadBlob(length, data[4:])
-	return Blob(b), bl + 4, nil
+	// The length comes from the peer, so it is checked before it is used.
+	if rest := len(data) - 4; int64(length) > int64(rest) {
+		return nil, 0, fmt.Errorf("read blob argument: length %d with %d bytes left: %w: %w", length, rest, ErrBlobPastEnd, io.ErrUnexpectedEOF)
+	}
+	end := 4 + int(length)
+	return Blob(data[4:end:end]), int64(4 + padded(int(length))), nil
 }
 
 // Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
 func (b Blob) Bytes() []byte {
-	return Pad(bytes.Join([][]byte{
-		Int(len(b)).Bytes(),
-		[]byte(b),
-	}, []byte{}))
+	return appendArgument(make([]byte, 0, 4+padded(len(b))), b)
 }
 
 // Equal returns true if the argument equals the other one, false otherwise.
@@ -302,7 +872,10 @@ func (b Blob) Equal(other Argument) bool {
 	if other.Typetag() != TypetagBlob {
 		return false
 	}
-	b2 := other.(Blob)
+	b2, ok := other.(Blob)
+	if !ok {
+		return equalEncoded(b, other)
+	}
 	if len(b) != len(b2) {
 		return false
 	}
@@ -312,18 +885,43 @@ func (b Blob) Equal(other Argument) bool {
 // ReadInt32 reads a 32-bit integer from the arg.
 func (b Blob) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadInt64 reads a 64-bit integer from the arg.
+func (b Blob) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadFloat32 reads a 32-bit float from the arg.
 func (b Blob) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
 
+// ReadFloat64 reads a 64-bit float from the arg.
+func (b Blob) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
 // ReadBool bool reads a boolean from the arg.
 func (b Blob) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
 
 // ReadString string reads a string from the arg.
 func (b Blob) ReadString() (string, error) { return "", ErrInvalidTypeTag }
 
-// ReadBlob reads a slice of bytes from the arg.
+// ReadBlob reads a slice of bytes from the arg, which is the blob itself.
+// The blob of a message that was parsed is a view of the data it was parsed from.
 func (b Blob) ReadBlob() ([]byte, error) { return []byte(b), nil }
 
+// ReadChar reads an ASCII character from the arg.
+func (b Blob) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (b Blob) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (b Blob) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (b Blob) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (b Blob) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (b Blob) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
 // String converts the arg to a string.
 func (b Blob) String() string { return base64.StdEncoding.EncodeToString([]byte(b)) }
 
@@ -336,5 +934,256 @@ func (b Blob) WriteTo(w io.Writer) (int64, error) {
 	return int64(written), err
 }
 
+// Char is an ASCII character, which is sent as 32 bits.
+type Char rune
+
+// ReadCharFrom reads a character from a byte slice.
+func ReadCharFrom(data []byte) (Argument, int64, error) {
+	if len(data) < 4 {
+		return nil, 0, fmt.Errorf("read char argument: %d bytes left: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	return Char(byteOrder.Uint32(data)), 4, nil
+}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (c Char) Bytes() []byte {
+	return Int(c).Bytes()
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (c Char) Equal(other Argument) bool {
+	if other.Typetag() != TypetagChar {
+		return false
+	}
+	c2, ok := other.(Char)
+	if !ok {
+		return equalEncoded(c, other)
+	}
+	return c == c2
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (c Char) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (c Char) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (c Char) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (c Char) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (c Char) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (c Char) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (c Char) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+// Characters above 0x7F are rejected with ErrNonASCIIChar,
+// since OSC does not say how they are encoded.
+func (c Char) ReadChar() (rune, error) {
+	if c < 0 || c > 0x7F {
+		return 0, fmt.Errorf("char 0x%X: %w", uint32(c), ErrNonASCIIChar)
+	}
+	return rune(c), nil
+}
+
+// ReadRGBA reads a color from the arg.
+func (c Char) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (c Char) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (c Char) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (c Char) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (c Char) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (c Char) String() string { return fmt.Sprintf("Char(%q)", rune(c)) }
+
+// Typetag returns the argument's type tag.
+func (c Char) Typetag() byte { return TypetagChar }
+
+// WriteTo writes the arg to an io.Writer.
+func (c Char) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprintf(w, "%c", rune(c))
+	return int64(written), err
+}
+
+// RGBA is a 32-bit color.
+type RGBA struct {
+	R, G, B, A byte
+}
+
+// ReadRGBAFrom reads a color from a byte slice.
+func ReadRGBAFrom(data []byte) (Argument, int64, error) {
+	if len(data) < 4 {
+		return nil, 0, fmt.Errorf("read rgba argument: %d bytes left: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	return RGBA{R: data[0], G: data[1], B: data[2], A: data[3]}, 4, nil
+}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (c RGBA) Bytes() []byte {
+	return []byte{c.R, c.G, c.B, c.A}
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (c RGBA) Equal(other Argument) bool {
+	if other.Typetag() != TypetagRGBA {
+		return false
+	}
+	c2, ok := other.(RGBA)
+	if !ok {
+		return equalEncoded(c, other)
+	}
+	return c == c2
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (c RGBA) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (c RGBA) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (c RGBA) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (c RGBA) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (c RGBA) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (c RGBA) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (c RGBA) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (c RGBA) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (c RGBA) ReadRGBA() (RGBA, error) { return c, nil }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (c RGBA) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (c RGBA) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (c RGBA) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (c RGBA) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (c RGBA) String() string { return fmt.Sprintf("RGBA(#%02x%02x%02x%02x)", c.R, c.G, c.B, c.A) }
+
+// Typetag returns the argument's type tag.
+func (c RGBA) Typetag() byte { return TypetagRGBA }
+
+// WriteTo writes the arg to an io.Writer.
+func (c RGBA) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprintf(w, "#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	return int64(written), err
+}
+
+// MIDI is a 4-byte MIDI message.
+// Data1 and Data2 are the data bytes that follow the status byte.
+type MIDI struct {
+	Port, Status, Data1, Data2 byte
+}
+
+// ReadMIDIFrom reads a MIDI message from a byte slice.
+func ReadMIDIFrom(data []byte) (Argument, int64, error) {
+	if len(data) < 4 {
+		return nil, 0, fmt.Errorf("read midi argument: %d bytes left: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	return MIDI{Port: data[0], Status: data[1], Data1: data[2], Data2: data[3]}, 4, nil
+}
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (m MIDI) Bytes() []byte {
+	return []byte{m.Port, m.Status, m.Data1, m.Data2}
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (m MIDI) Equal(other Argument) bool {
+	if other.Typetag() != TypetagMIDI {
+		return false
+	}
+	m2, ok := other.(MIDI)
+	if !ok {
+		return equalEncoded(m, other)
+	}
+	return m == m2
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (m MIDI) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (m MIDI) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (m MIDI) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (m MIDI) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (m MIDI) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (m MIDI) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (m MIDI) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (m MIDI) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (m MIDI) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (m MIDI) ReadMIDI() (MIDI, error) { return m, nil }
+
+// ReadSymbol reads a symbol from the arg.
+func (m MIDI) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (m MIDI) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (m MIDI) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the arg to a string.
+func (m MIDI) String() string {
+	return fmt.Sprintf("MIDI(%02x %02x %02x %02x)", m.Port, m.Status, m.Data1, m.Data2)
+}
+
+// Typetag returns the argument's type tag.
+func (m MIDI) Typetag() byte { return TypetagMIDI }
+
+// WriteTo writes the arg to an io.Writer.
+func (m MIDI) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprintf(w, "%02x %02x %02x %02x", m.Port, m.Status, m.Data1, m.Data2)
+	return int64(written), err
+}
+
 // Arguments is a slice of Argument.
 type Arguments []Argument
@@ -3,10 +3,11 @@ package osc
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"testing"
-
-	"github.com/pkg/errors"
+	"time"
 )
 
 type equalTest struct {
@@ -166,6 +167,278 @@ func TestFloatWriteTo(t *testing.T) {
 	}
 }
 
+func TestInt64AndDouble(t *testing.T) {
+	for _, testcase := range []struct {
+		Arg     Argument
+		Bytes   []byte
+		Typetag byte
+		String  string
+	}{
+		{Arg: Int64(1), Bytes: []byte{0, 0, 0, 0, 0, 0, 0, 1}, Typetag: TypetagInt64, String: "Int64(1)"},
+		{Arg: Int64(-2), Bytes: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, Typetag: TypetagInt64, String: "Int64(-2)"},
+		{Arg: Int64(1 << 40), Bytes: []byte{0, 0, 1, 0, 0, 0, 0, 0}, Typetag: TypetagInt64, String: "Int64(1099511627776)"},
+		{Arg: Double(1), Bytes: []byte{0x3f, 0xf0, 0, 0, 0, 0, 0, 0}, Typetag: TypetagDouble, String: "Double(1.000000)"},
+		{Arg: Double(-0.5), Bytes: []byte{0xbf, 0xe0, 0, 0, 0, 0, 0, 0}, Typetag: TypetagDouble, String: "Double(-0.500000)"},
+	} {
+		arg := testcase.Arg
+		if expected, got := testcase.Bytes, arg.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("expected %x, got %x", expected, got)
+		}
+		parsed, consumed, err := ReadArgument(testcase.Typetag, testcase.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if consumed != 8 {
+			t.Fatalf("expected 8 bytes to be consumed, got %d", consumed)
+		}
+		equalTest{
+			arg:      arg,
+			equal:    []Argument{parsed},
+			notEqual: []Argument{Int(1), Float(1), String("1")},
+		}.run(t)
+
+		if expected, got := testcase.Typetag, arg.Typetag(); expected != got {
+			t.Fatalf("expected %c, got %c", expected, got)
+		}
+		if expected, got := testcase.String, arg.String(); expected != got {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+		if _, err := arg.WriteTo(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := arg.ReadInt32(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadFloat32(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadBool(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadString(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadBlob(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+	}
+}
+
+func TestInt64ReadInt64(t *testing.T) {
+	arg := Int64(-1 << 62)
+	i, err := arg.ReadInt64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := int64(-1<<62), i; expected != got {
+		t.Fatalf("expected %d, got %d", expected, got)
+	}
+	if _, err := arg.ReadFloat64(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if _, err := Int(1).ReadInt64(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+}
+
+func TestDoubleReadFloat64(t *testing.T) {
+	arg := Double(3.141592653589793)
+	f, err := arg.ReadFloat64()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 3.141592653589793, f; expected != got {
+		t.Fatalf("expected %f, got %f", expected, got)
+	}
+	if _, err := arg.ReadInt64(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if _, err := Float(1).ReadFloat64(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+}
+
+func TestCharRGBAMIDI(t *testing.T) {
+	for _, testcase := range []struct {
+		Arg     Argument
+		Bytes   []byte
+		Typetag byte
+		String  string
+	}{
+		{Arg: Char('a'), Bytes: []byte{0, 0, 0, 'a'}, Typetag: TypetagChar, String: "Char('a')"},
+		{Arg: RGBA{R: 0xff, G: 0x80, B: 0, A: 0x7f}, Bytes: []byte{0xff, 0x80, 0, 0x7f}, Typetag: TypetagRGBA, String: "RGBA(#ff80007f)"},
+		{Arg: MIDI{Port: 0, Status: 0x90, Data1: 60, Data2: 100}, Bytes: []byte{0, 0x90, 60, 100}, Typetag: TypetagMIDI, String: "MIDI(00 90 3c 64)"},
+	} {
+		arg := testcase.Arg
+		if expected, got := testcase.Bytes, arg.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("expected %x, got %x", expected, got)
+		}
+		parsed, consumed, err := ReadArgument(testcase.Typetag, testcase.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if consumed != 4 {
+			t.Fatalf("expected 4 bytes to be consumed, got %d", consumed)
+		}
+		equalTest{
+			arg:      arg,
+			equal:    []Argument{parsed},
+			notEqual: []Argument{Int(0x61), String("a"), Blob(testcase.Bytes)},
+		}.run(t)
+
+		if expected, got := testcase.Typetag, arg.Typetag(); expected != got {
+			t.Fatalf("expected %c, got %c", expected, got)
+		}
+		if expected, got := testcase.String, arg.String(); expected != got {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+		if _, err := arg.WriteTo(ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ReadArgument(testcase.Typetag, testcase.Bytes[:3]); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		if _, err := arg.ReadInt32(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadString(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+	}
+}
+
+func TestCharReadChar(t *testing.T) {
+	c, err := Char('~').ReadChar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := '~', c; expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	for _, r := range []rune{0x80, 'é', '♪'} {
+		if _, err := Char(r).ReadChar(); !errors.Is(err, ErrNonASCIIChar) {
+			t.Fatalf("expected ErrNonASCIIChar, got %+v", err)
+		}
+	}
+	if _, err := Int('a').ReadChar(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+}
+
+func TestReadRGBAAndMIDI(t *testing.T) {
+	color, err := RGBA{R: 1, G: 2, B: 3, A: 4}.ReadRGBA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := (RGBA{R: 1, G: 2, B: 3, A: 4}), color; expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	midi, err := MIDI{Port: 1, Status: 0xB0, Data1: 7, Data2: 127}.ReadMIDI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := (MIDI{Port: 1, Status: 0xB0, Data1: 7, Data2: 127}), midi; expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if _, err := midi.ReadRGBA(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if _, err := color.ReadMIDI(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+}
+
+func TestSymbol(t *testing.T) {
+	for _, testcase := range []struct {
+		Symbol Symbol
+		Bytes  []byte
+	}{
+		{Symbol: "", Bytes: []byte{0, 0, 0, 0}},
+		{Symbol: "sin", Bytes: []byte{'s', 'i', 'n', 0}},
+		{Symbol: "default", Bytes: []byte{'d', 'e', 'f', 'a', 'u', 'l', 't', 0}},
+		{Symbol: "freq", Bytes: []byte{'f', 'r', 'e', 'q', 0, 0, 0, 0}},
+	} {
+		if expected, got := testcase.Bytes, testcase.Symbol.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("expected %q, got %q", expected, got)
+		}
+		arg, consumed, err := ReadArgument(TypetagSymbol, testcase.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected, got := int64(len(testcase.Bytes)), consumed; expected != got {
+			t.Fatalf("expected %d bytes to be consumed, got %d", expected, got)
+		}
+		equalTest{
+			arg:      testcase.Symbol,
+			equal:    []Argument{arg},
+			notEqual: []Argument{String(testcase.Symbol), Blob(testcase.Symbol)},
+		}.run(t)
+	}
+	sym := Symbol("freq")
+	s, err := sym.ReadSymbol()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "freq", s; expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if _, err := sym.ReadString(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if _, err := String("freq").ReadSymbol(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if expected, got := "Symbol(freq)", sym.String(); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if expected, got := TypetagSymbol, sym.Typetag(); expected != got {
+		t.Fatalf("expected %c, got %c", expected, got)
+	}
+}
+
+func TestTimetagArgument(t *testing.T) {
+	data := []byte{0xBC, 0x17, 0xC2, 0x00, 0x80, 0, 0, 0}
+
+	arg, consumed, err := ReadArgument(TypetagTimetag, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumed != TimetagSize {
+		t.Fatalf("expected %d bytes to be consumed, got %d", TimetagSize, consumed)
+	}
+	tt, err := arg.ReadTimetag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := time.Date(2000, 1, 1, 0, 0, 0, 5e8, time.UTC), tt.Time(); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if !bytes.Equal(data, arg.Bytes()) {
+		t.Fatalf("expected %x, got %x", data, arg.Bytes())
+	}
+	equalTest{
+		arg:      arg,
+		equal:    []Argument{Timetag(0xBC17C20080000000)},
+		notEqual: []Argument{Timetag(0xBC17C200), Int64(0x3C17C20080000000), Double(0)},
+	}.run(t)
+
+	if expected, got := TypetagTimetag, arg.Typetag(); expected != got {
+		t.Fatalf("expected %c, got %c", expected, got)
+	}
+	if _, err := arg.ReadInt64(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if _, err := Int64(1).ReadTimetag(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+	if _, _, err := ReadArgument(TypetagTimetag, data[:7]); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if _, err := arg.WriteTo(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestBoolBytes(t *testing.T) {
 	arg := Bool(false)
 	if expected, got := []byte{}, arg.Bytes(); !bytes.Equal(expected, got) {
@@ -235,6 +508,58 @@ func TestBoolWriteTo(t *testing.T) {
 	}
 }
 
+func TestNilAndInfinitum(t *testing.T) {
+	for _, testcase := range []struct {
+		Arg     Argument
+		Other   Argument
+		Typetag byte
+		String  string
+		WriteTo string
+	}{
+		{Arg: Nil{}, Other: Infinitum{}, Typetag: TypetagNil, String: "Nil", WriteTo: "nil"},
+		{Arg: Infinitum{}, Other: Nil{}, Typetag: TypetagInfinitum, String: "Infinitum", WriteTo: "infinitum"},
+	} {
+		arg := testcase.Arg
+		if got := arg.Bytes(); len(got) != 0 {
+			t.Fatalf("expected no bytes, got %x", got)
+		}
+		equalTest{
+			arg:      arg,
+			equal:    []Argument{testcase.Arg},
+			notEqual: []Argument{testcase.Other, Bool(false), Int(0)},
+		}.run(t)
+
+		if expected, got := testcase.Typetag, arg.Typetag(); expected != got {
+			t.Fatalf("expected %c, got %c", expected, got)
+		}
+		if expected, got := testcase.String, arg.String(); expected != got {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+		buf := &bytes.Buffer{}
+		if _, err := arg.WriteTo(buf); err != nil {
+			t.Fatal(err)
+		}
+		if expected, got := testcase.WriteTo, buf.String(); expected != got {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+		if _, err := arg.ReadInt32(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadFloat32(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadBool(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadString(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+		if _, err := arg.ReadBlob(); err != ErrInvalidTypeTag {
+			t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+		}
+	}
+}
+
 func TestStringBytes(t *testing.T) {
 	arg := String("foo")
 	if expected, got := []byte{'f', 'o', 'o', 0}, arg.Bytes(); !bytes.Equal(expected, got) {
@@ -410,6 +735,30 @@ func TestReadArgument(t *testing.T) {
 			Input:    Input{tt: TypetagFalse},
 			Expected: Output{Argument: Bool(false)},
 		},
+		{
+			Input:    Input{tt: TypetagInt64, data: []byte{0, 0, 0, 0, 0, 0, 0, 1}},
+			Expected: Output{Argument: Int64(1), Consumed: 8},
+		},
+		{
+			Input:    Input{tt: TypetagInt64, data: []byte{0, 0, 0, 1}},
+			Expected: Output{Err: errors.New("read int64 argument: 4 bytes left: unexpected EOF")},
+		},
+		{
+			Input:    Input{tt: TypetagDouble, data: []byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}},
+			Expected: Output{Argument: Double(3.141592653589793), Consumed: 8},
+		},
+		{
+			Input:    Input{tt: TypetagDouble, data: []byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d}},
+			Expected: Output{Err: errors.New("read double argument: 7 bytes left: unexpected EOF")},
+		},
+		{
+			Input:    Input{tt: TypetagNil, data: []byte{0, 0, 0, 1}},
+			Expected: Output{Argument: Nil{}},
+		},
+		{
+			Input:    Input{tt: TypetagInfinitum, data: []byte{0, 0, 0, 1}},
+			Expected: Output{Argument: Infinitum{}},
+		},
 		{
 			Input:    Input{tt: TypetagString, data: []byte{'a', 'b', 'c', 'd', 'e'}},
 			Expected: Output{Argument: String("abcde"), Consumed: 8},
@@ -417,7 +766,7 @@ func TestReadArgument(t *testing.T) {
 		{
 			// Length followed by blob
 			Input:    Input{tt: TypetagBlob, data: []byte{0, 0, 0, 5, 'a', 'b', 'c', 'd', 'e'}},
-			Expected: Output{Argument: Blob([]byte{'a', 'b', 'c', 'd', 'e', 0, 0, 0}), Consumed: 12},
+			Expected: Output{Argument: Blob([]byte{'a', 'b', 'c', 'd', 'e'}), Consumed: 12},
 		},
 		{
 			Input:    Input{tt: TypetagBlob, data: []byte{}},
@@ -425,7 +774,7 @@ func TestReadArgument(t *testing.T) {
 		},
 		{
 			Input:    Input{tt: 'Q'},
-			Expected: Output{Err: errors.Wrap(ErrInvalidTypeTag, `typetag "Q"`)},
+			Expected: Output{Err: fmt.Errorf("%s: %w", `typetag "Q"`, ErrInvalidTypeTag)},
 		},
 	} {
 		a, consumed, err := ReadArgument(testcase.Input.tt, testcase.Input.data)
@@ -473,12 +822,9 @@ func TestReadArguments(t *testing.T) {
 			Expected: Output{Arguments: []Argument{Int(1)}},
 		},
 		{
-			Input: Input{Typetags: []byte{TypetagBlob}, Data: []byte{0, 0, 1, 1, 4, 5, 6, 7}},
-			Expected: Output{
-				Arguments: []Argument{
-					Blob([]byte{4, 5, 6, 7}),
-				},
-			},
+			// The length of the blob is larger than the data.
+			Input:    Input{Typetags: []byte{TypetagBlob}, Data: []byte{0, 0, 1, 1, 4, 5, 6, 7}},
+			Expected: Output{Err: errors.New("read argument 0: read blob argument: length 257 with 4 bytes left: blob length is past the end of the data: unexpected EOF")},
 		},
 	} {
 		args, err := ReadArguments(testcase.Input.Typetags, testcase.Input.Data)
@@ -0,0 +1,248 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Common errors.
+var (
+	ErrUnbalancedArray = errors.New("unbalanced array typetags")
+)
+
+// Array is a group of arguments.
+// The typetags of its elements are enclosed in '[' and ']'.
+type Array []Argument
+
+// Bytes converts the arg to a byte slice suitable for adding to the binary representation of an OSC message.
+func (a Array) Bytes() []byte {
+	b := []byte{}
+	for _, arg := range a {
+		b = append(b, arg.Bytes()...)
+	}
+	return b
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (a Array) Equal(other Argument) bool {
+	if other.Typetag() != TypetagArrayStart {
+		return false
+	}
+	a2, ok := other.(Array)
+	if !ok {
+		return equalEncoded(a, other)
+	}
+	if len(a) != len(a2) {
+		return false
+	}
+	for i, arg := range a {
+		if !arg.Equal(a2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (a Array) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (a Array) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (a Array) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (a Array) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (a Array) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (a Array) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (a Array) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (a Array) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (a Array) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (a Array) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (a Array) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (a Array) ReadTimetag() (Timetag, error) { return 0, ErrInvalidTypeTag }
+
+// ReadArray reads an array of arguments from the arg.
+func (a Array) ReadArray() (Arguments, error) { return Arguments(a), nil }
+
+// String converts the arg to a string.
+func (a Array) String() string {
+	parts := make([]string, len(a))
+	for i, arg := range a {
+		parts[i] = arg.String()
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// Typetag returns the typetag that opens the array.
+// Use Typetags to get the typetags of the whole array.
+func (a Array) Typetag() byte { return TypetagArrayStart }
+
+// Typetags returns the typetags of the array, including the brackets.
+func (a Array) Typetags() []byte {
+	return appendTypetags([]byte{TypetagArrayStart}, a, TypetagArrayEnd)
+}
+
+// WriteTo writes the arg to an io.Writer.
+func (a Array) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprint(w, a.String())
+	return int64(written), err
+}
+
+// appendTypetags appends the typetags of args to tt, followed by end.
+func appendTypetags(tt []byte, args []Argument, end ...byte) []byte {
+	for _, arg := range args {
+		if a, ok := arg.(Array); ok {
+			tt = append(tt, a.Typetags()...)
+			continue
+		}
+		tt = append(tt, arg.Typetag())
+	}
+	return append(tt, end...)
+}
+
+// argumentReader reads the arguments of a message, which may contain arrays.
+type argumentReader struct {
+	typetags []byte
+	data     []byte
+
+	// short is true if the last argument that was read is missing its padding.
+	short bool
+
+	// If skipUnknown is true an unknown typetag that is not followed by known ones
+	// ends the arguments, and truncated is set.
+	skipUnknown bool
+	truncated   bool
+
+	// unknown holds the typetags and the data that were not read when truncated was set.
+	unknown argumentView
+
+	// maxBlobSize is the size of the largest blob that is read, if it is positive.
+	// If paddedBlobs is true a blob that is missing its padding is an error,
+	// even when it is the last argument.
+	maxBlobSize int
+	paddedBlobs bool
+
+	// n is the number of arguments that have been read, not counting arrays.
+	n int
+
+	// offset is the number of bytes of data that have been read.
+	offset int
+}
+
+// newArgumentReader creates a reader for the arguments of a message.
+func newArgumentReader(typetags, data []byte) *argumentReader {
+	// Strip off the prefix.
+	if len(typetags) > 0 && typetags[0] == TypetagPrefix {
+		typetags = typetags[1:]
+	}
+	return &argumentReader{typetags: typetags, data: data}
+}
+
+// maxArrayDepth is how deeply the arrays of a message that is parsed may be nested,
+// so that a crafted message can not make the parser recurse for every byte of its typetags.
+const maxArrayDepth = 64
+
+// read reads arguments until the typetags run out, or, if depth > 0,
+// until the array that is being read is closed.
+func (r *argumentReader) read(depth int) ([]Argument, error) {
+	args := make([]Argument, 0, countArguments(r.typetags))
+
+	for len(r.typetags) > 0 {
+		tt := r.typetags[0]
+		if r.skipUnknown && !isTypetag(tt) && !hasTypetag(r.typetags) {
+			// The size of an unknown argument is unknown, so nothing after it can be read.
+			r.unknown = argumentView{typetags: r.typetags, data: r.data}
+			r.typetags, r.truncated = nil, true
+			break
+		}
+		r.typetags = r.typetags[1:]
+
+		switch tt {
+		case TypetagArrayStart:
+			if depth >= maxArrayDepth {
+				return nil, fmt.Errorf("arrays nested deeper than %d: %w", maxArrayDepth, ErrInvalidTypeTag)
+			}
+			a, err := r.read(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, Array(a))
+		case TypetagArrayEnd:
+			if depth == 0 {
+				return nil, fmt.Errorf("%q without %q: %w", TypetagArrayEnd, TypetagArrayStart, ErrUnbalancedArray)
+			}
+			return args, nil
+		default:
+			if tt == TypetagBlob && r.maxBlobSize > 0 && len(r.data) >= 4 {
+				if size := int64(int32(byteOrder.Uint32(r.data))); size > int64(r.maxBlobSize) {
+					return nil, fmt.Errorf("read argument %d: blob of %d bytes, the maximum is %d: %w", r.n, size, r.maxBlobSize, ErrBlobTooLarge)
+				}
+			}
+			arg, idx, err := ReadArgument(tt, r.data)
+			if err != nil {
+				return nil, fmt.Errorf("read argument %d: %w", r.n, err)
+			}
+			if idx > int64(len(r.data)) && tt == TypetagBlob && r.paddedBlobs {
+				return nil, fmt.Errorf("read argument %d: blob is missing %d bytes of padding: %w", r.n, idx-int64(len(r.data)), ErrMissingPadding)
+			}
+			args = append(args, arg)
+			if idx > int64(len(r.data)) {
+				idx = int64(len(r.data)) // The last argument may be missing its padding.
+				r.short = true
+			}
+			r.data = r.data[idx:]
+			r.offset += int(idx)
+			r.n++
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("%q without %q: %w", TypetagArrayStart, TypetagArrayEnd, ErrUnbalancedArray)
+	}
+	return args, nil
+}
+
+// countArguments returns how many arguments typetags has before the end of the array
+// that starts before them, if any, counting the arrays in them as one argument each,
+// so that read allocates the arguments once instead of growing them.
+func countArguments(typetags []byte) int {
+	n, depth := 0, 0
+	for _, tt := range typetags {
+		switch tt {
+		case TypetagArrayStart:
+			depth++
+			if depth == 1 {
+				n++
+			}
+		case TypetagArrayEnd:
+			if depth == 0 {
+				return n
+			}
+			depth--
+		default:
+			if depth == 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
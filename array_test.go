@@ -0,0 +1,115 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestArrayRoundTrip(t *testing.T) {
+	msg := Message{Address: "/list"}
+	msg.WriteInt32(1)
+	msg.BeginArray()
+	msg.WriteString("a")
+	msg.BeginArray()
+	msg.WriteFloat32(0.5)
+	msg.BeginArray()
+	if err := msg.EndArray(); err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.EndArray(); err != nil {
+		t.Fatal(err)
+	}
+	if err := msg.EndArray(); err != nil {
+		t.Fatal(err)
+	}
+	msg.WriteInt32(2)
+
+	expected := Message{
+		Address:   "/list",
+		Arguments: Arguments{Int(1), Array{String("a"), Array{Float(0.5), Array{}}}, Int(2)},
+	}
+	if !expected.Equal(msg) {
+		t.Fatalf("expected %s, got %s", expected, msg)
+	}
+	if expected, got := []byte(",i[s[f[]]]i\x00"), msg.Typetags(); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	parsed, err := ParseMessage(msg.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Equal(parsed) {
+		t.Fatalf("expected %s, got %s", msg, parsed)
+	}
+	if expected, got := `/list ,i[s[f[]]]i 1 ["a" [0.5 []]] 2`, parsed.String(); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	elems, err := parsed.Arguments[1].ReadArray()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 2, len(elems); expected != got {
+		t.Fatalf("expected %d elements, got %d", expected, got)
+	}
+	if _, err := parsed.Arguments[0].ReadArray(); err != ErrInvalidTypeTag {
+		t.Fatalf("expected ErrInvalidTypeTag, got %+v", err)
+	}
+}
+
+// TestParseMessageArray parses a message like the ones Max sends for a list.
+func TestParseMessageArray(t *testing.T) {
+	data := bytes.Join([][]byte{
+		{'/', 'x', 'y', 0},
+		{TypetagPrefix, TypetagArrayStart, TypetagFloat, TypetagFloat, TypetagArrayEnd, 0, 0, 0},
+		{0x3f, 0x80, 0, 0},
+		{0x40, 0, 0, 0},
+	}, []byte{})
+
+	msg, err := ParseMessage(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Message{Address: "/xy", Arguments: Arguments{Array{Float(1), Float(2)}}}
+	if !expected.Equal(msg) {
+		t.Fatalf("expected %s, got %s", expected, msg)
+	}
+	if got := expected.Bytes(); !bytes.Equal(data, got) {
+		t.Fatalf("expected %x, got %x", data, got)
+	}
+}
+
+func TestReadArgumentsUnbalanced(t *testing.T) {
+	for i, typetags := range []string{
+		",]",
+		",i]",
+		",[i]]",
+		",[",
+		",[[i]",
+	} {
+		_, err := ReadArguments([]byte(typetags), []byte{0, 0, 0, 1})
+		if !errors.Is(err, ErrUnbalancedArray) {
+			t.Fatalf("(testcase %d) expected ErrUnbalancedArray, got %v", i, err)
+		}
+	}
+	// Arguments inside arrays are numbered like any other.
+	_, err := ReadArguments([]byte(",i[i]"), []byte{0, 0, 0, 1})
+	if expected, got := "read argument 1: read int argument: EOF", err.Error(); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestMessageEndArrayUnbalanced(t *testing.T) {
+	msg := Message{Address: "/foo"}
+	if err := msg.EndArray(); !errors.Is(err, ErrUnbalancedArray) {
+		t.Fatalf("expected ErrUnbalancedArray, got %v", err)
+	}
+}
+
+func TestArrayEqual(t *testing.T) {
+	equalTest{
+		arg:      Array{Int(1), String("a")},
+		equal:    []Argument{Array{Int(1), String("a")}},
+		notEqual: []Argument{Array{Int(1)}, Array{Int(1), String("b")}, Int(1), Array{}},
+	}.run(t)
+}
@@ -0,0 +1,160 @@
+package osc
+
+import (
+	"context"
+	"sync"
+)
+
+// AsyncSender sends packets to the peer of a conn from a goroutine of its own,
+// so that the goroutine that enqueues them, e.g. an audio thread, never waits for the network.
+// The packets are sent in the order they were enqueued. The errors of the sends are counted
+// in the Stats of the conn, passed to its send hook and to its error handler.
+// Its methods are safe to call from many goroutines at once.
+type AsyncSender struct {
+	s       *connState
+	send    func(p Packet) error
+	policy  QueuePolicy
+	packets chan Packet
+
+	// mu is held for reading while a packet is enqueued, so that packets is not closed in the meantime.
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{} // Closed by Close, to unblock Enqueue.
+	closeOnce sync.Once
+	done      chan struct{} // Closed once every packet has been sent.
+
+	// enqueued and handled count the packets that entered the queue and
+	// the ones that left it, by being sent or dropped, for Flush.
+	progressMu sync.Mutex
+	enqueued   uint64
+	handled    uint64
+	progressed chan struct{} // Closed and replaced whenever handled increases.
+}
+
+// newAsyncSender starts an AsyncSender that sends the packets with send and counts them in s.
+func newAsyncSender(s *connState, send func(p Packet) error, queueSize int, policy QueuePolicy) *AsyncSender {
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	a := &AsyncSender{
+		s:          s,
+		send:       send,
+		policy:     policy,
+		packets:    make(chan Packet, queueSize),
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+		progressed: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// run sends the queued packets until the queue is closed and empty.
+func (a *AsyncSender) run() {
+	defer close(a.done)
+
+	for p := range a.packets {
+		if err := a.send(p); err != nil {
+			a.s.handleError(err)
+		}
+		a.progress(false)
+	}
+}
+
+// Enqueue queues p to be sent, and returns false if it was not queued.
+// When the queue is full Enqueue waits for room if the policy is BlockWhenFull,
+// drops the packet that has waited the longest to make room if it is DropOldest,
+// and drops p if it is DropNewest. Dropped packets are counted in SendDropped.
+// Only BlockWhenFull can make Enqueue wait, until there is room or Close is called.
+// Enqueue returns false once the sender is closed.
+// p must not be modified until it has been sent.
+func (a *AsyncSender) Enqueue(p Packet) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return false
+	}
+	for {
+		select {
+		case a.packets <- p:
+			a.progress(true)
+			return true
+		default:
+		}
+		switch a.policy {
+		case DropNewest:
+			a.s.countSendDropped()
+			return false
+		case DropOldest:
+			select {
+			case <-a.packets:
+				a.s.countSendDropped()
+				a.progress(false)
+			default:
+				// Another goroutine took the room that was made, or the sender has just sent the packet.
+			}
+		default:
+			select {
+			case a.packets <- p:
+				a.progress(true)
+				return true
+			case <-a.closing:
+				return false
+			}
+		}
+	}
+}
+
+// progress counts a packet that entered the queue, or one that left it.
+func (a *AsyncSender) progress(enqueued bool) {
+	a.progressMu.Lock()
+	defer a.progressMu.Unlock()
+
+	if enqueued {
+		a.enqueued++
+		return
+	}
+	a.handled++
+	close(a.progressed)
+	a.progressed = make(chan struct{})
+}
+
+// Flush waits until every packet that was enqueued before it was called has been sent,
+// or dropped, and returns ctx.Err() if ctx is done first.
+func (a *AsyncSender) Flush(ctx context.Context) error {
+	a.progressMu.Lock()
+	target := a.enqueued
+	a.progressMu.Unlock()
+
+	for {
+		a.progressMu.Lock()
+		handled, progressed := a.handled, a.progressed
+		a.progressMu.Unlock()
+
+		if handled >= target {
+			return nil
+		}
+		select {
+		case <-progressed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops the sender from taking packets, and returns once the packets
+// that were queued have been sent. The Enqueues that are waiting for room return false.
+// It does not close the conn. Closing the sender again does nothing.
+func (a *AsyncSender) Close() error {
+	a.closeOnce.Do(func() {
+		close(a.closing)
+
+		a.mu.Lock()
+		a.closed = true
+		close(a.packets)
+		a.mu.Unlock()
+	})
+	<-a.done
+	return nil
+}
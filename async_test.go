@@ -0,0 +1,137 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// pausedUDPConn returns a conn whose writes block until fake stops blocking.
+func pausedUDPConn(t *testing.T) (*UDPConn, *blockingUDPConn) {
+	t.Helper()
+
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = udp.Close() }) // Best effort.
+
+	fake := &blockingUDPConn{udpConn: udp.udpConn, blocked: make(chan struct{}, 1), blocking: true}
+	return &UDPConn{udpConn: fake, connState: newConnState(context.Background())}, fake
+}
+
+func TestAsyncSender_Drop(t *testing.T) {
+	for i, testcase := range []struct {
+		Policy   QueuePolicy
+		Enqueued []bool
+		Written  string
+	}{
+		{Policy: DropNewest, Enqueued: []bool{true, true, false, false}, Written: "/0 /1 /2"},
+		{Policy: DropOldest, Enqueued: []bool{true, true, true, true}, Written: "/0 /3 /4"},
+	} {
+		conn, fake := pausedUDPConn(t)
+		sender := conn.AsyncSender(2, testcase.Policy)
+
+		// /0 is being written while /1 and /2 fill the queue, so there is no room for /3 and /4.
+		if !sender.Enqueue(MustMessage("/0")) {
+			t.Fatalf("(testcase %d) expected /0 to be enqueued", i)
+		}
+		<-fake.blocked
+		for j, addr := range []string{"/1", "/2", "/3", "/4"} {
+			if got := sender.Enqueue(MustMessage(addr)); got != testcase.Enqueued[j] {
+				t.Fatalf("(testcase %d) expected Enqueue(%s) to return %t, got %t", i, addr, testcase.Enqueued[j], got)
+			}
+		}
+		if got := conn.Stats().SendDropped; got != 2 {
+			t.Fatalf("(testcase %d) expected 2 dropped packets, got %d", i, got)
+		}
+		fake.setBlocking(false)
+		if err := sender.Flush(context.Background()); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if got := fake.writtenAddresses(); got != testcase.Written {
+			t.Fatalf("(testcase %d) expected %s to be sent, got %s", i, testcase.Written, got)
+		}
+		if err := sender.Close(); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+	}
+}
+
+func TestAsyncSender_Block(t *testing.T) {
+	conn, fake := pausedUDPConn(t)
+	sender := conn.AsyncSender(1, BlockWhenFull)
+
+	// /0 is being written and /1 fills the queue, so /2 waits for room.
+	for _, addr := range []string{"/0", "/1"} {
+		if !sender.Enqueue(MustMessage(addr)) {
+			t.Fatalf("expected %s to be enqueued", addr)
+		}
+		if addr == "/0" {
+			<-fake.blocked
+		}
+	}
+	enqueued := make(chan bool, 1)
+	go func() {
+		enqueued <- sender.Enqueue(MustMessage("/2"))
+	}()
+	select {
+	case <-enqueued:
+		t.Fatal("expected Enqueue to wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sender.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Flush to time out, got %v", err)
+	}
+
+	fake.setBlocking(false)
+	if !<-enqueued {
+		t.Fatal("expected /2 to be enqueued")
+	}
+	if err := sender.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "/0 /1 /2", fake.writtenAddresses(); expected != got {
+		t.Fatalf("expected %s to be sent, got %s", expected, got)
+	}
+
+	// Close waits for the queued packets to be sent.
+	fake.setBlocking(true)
+	if !sender.Enqueue(MustMessage("/3")) {
+		t.Fatal("expected /3 to be enqueued")
+	}
+	<-fake.blocked
+	closed := make(chan error, 1)
+	go func() {
+		closed <- sender.Close()
+	}()
+	select {
+	case <-closed:
+		t.Fatal("expected Close to wait for /3")
+	case <-time.After(20 * time.Millisecond):
+	}
+	fake.setBlocking(false)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "/0 /1 /2 /3", fake.writtenAddresses(); expected != got {
+		t.Fatalf("expected %s to be sent, got %s", expected, got)
+	}
+	if sender.Enqueue(MustMessage("/4")) {
+		t.Fatal("expected a closed sender not to enqueue")
+	}
+	if err := sender.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := conn.Stats(); stats.PacketsSent != 4 || stats.SendDropped != 0 {
+		t.Fatalf("expected 4 packets sent and none dropped, got %+v", stats)
+	}
+}
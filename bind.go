@@ -0,0 +1,233 @@
+package osc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	messageType = reflect.TypeOf(Message{})
+)
+
+// BindOptions are the options of Bind.
+type BindOptions struct {
+	// Name returns the address of a method from its name, relative to the prefix,
+	// such as "/level" for Level. The default lowercases the leading capitals
+	// of the name, so HTTPPort is at "/httpPort".
+	Name func(method string) string
+}
+
+// Bind returns a dispatcher with a typed method for every exported method of v,
+// at the prefix followed by the address derived from the name of the method,
+// so the method Level of a mixer bound at "/mixer" is at "/mixer/level".
+// The methods are described by BindOptions.Bind, which uses the default options.
+func Bind(prefix string, v interface{}) (Dispatcher, error) {
+	return BindOptions{}.Bind(prefix, v)
+}
+
+// Bind returns a dispatcher with a typed method for every exported method of v,
+// at the prefix followed by the address that o.Name derives from the name of the method.
+//
+// The parameters of the methods can be of type int32, int64, float32, float64, string,
+// []byte or bool, of a type whose pointer implements Unmarshaler, or a pointer to
+// one of those, which is nil if the message does not have its argument, like the
+// fields that Decode fills. The typetags the methods expect are those of the parameters,
+// TypetagAny for the bool and Unmarshaler ones, and TypetagOptional after the pointers,
+// which must be the last parameters. The first parameter can be a Message too,
+// which is passed the message itself, e.g. to reply to it.
+// The methods should return an error, which is returned by the method of the dispatcher,
+// or nothing. Methods of other signatures are an error naming them.
+//
+// The address of a method can be set with an `osc:"Level=/gain"` tag on a blank field
+// of the struct v is or points to, with the addresses of other methods after commas,
+// and a method tagged "-", like `osc:"String=-"`, is not bound.
+func (o BindOptions) Bind(prefix string, v interface{}) (Dispatcher, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("bind nil: %w", ErrUnsupportedType)
+	}
+	addrs, err := bindTags(reflect.Indirect(rv).Type(), rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	name := o.Name
+	if name == nil {
+		name = methodAddress
+	}
+	router := NewRouter()
+	seen := map[string]string{}
+
+	for i := 0; i < rv.NumMethod(); i++ {
+		method := rv.Type().Method(i)
+		addr, ok := addrs[method.Name]
+		if !ok {
+			addr = name(method.Name)
+		}
+		if addr == "-" {
+			continue
+		}
+		addr = prefix + addr
+
+		bm, err := newBoundMethod(method.Name, rv.Method(i))
+		if err != nil {
+			return nil, fmt.Errorf("method %s: %w", method.Name, err)
+		}
+		if other, ok := seen[addr]; ok {
+			return nil, fmt.Errorf("methods %s and %s are both at %s", other, method.Name, addr)
+		}
+		seen[addr] = method.Name
+
+		if err := router.AddTyped(addr, bm.typetags, bm); err != nil {
+			return nil, fmt.Errorf("method %s: %w", method.Name, err)
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("%T has no methods to bind", v)
+	}
+	return router, nil
+}
+
+// bindTags returns the addresses that the osc tags of the blank fields of t,
+// if it is a struct, set for the methods of methods.
+func bindTags(t reflect.Type, methods reflect.Type) (map[string]string, error) {
+	addrs := map[string]string{}
+	if t.Kind() != reflect.Struct {
+		return addrs, nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("osc")
+		if f.Name != "_" || !ok {
+			continue
+		}
+		for _, pair := range strings.Split(tag, ",") {
+			name, addr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || addr == "" {
+				return nil, fmt.Errorf("invalid osc tag %q", tag)
+			}
+			if _, ok := methods.MethodByName(name); !ok {
+				return nil, fmt.Errorf("osc tag %q: %s has no method %s", tag, methods, name)
+			}
+			addrs[name] = addr
+		}
+	}
+	return addrs, nil
+}
+
+// methodAddress is the default address of a method, its name with the leading
+// capitals lowercased, but the one that starts the next word.
+func methodAddress(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return "/" + string(runes)
+}
+
+// boundMethod is the method of the dispatcher of Bind that calls a method of a value.
+type boundMethod struct {
+	name     string
+	fn       reflect.Value
+	message  bool           // If the first parameter is the message.
+	params   []reflect.Type // The parameters of the arguments.
+	typetags string
+}
+
+// newBoundMethod checks the signature of fn and returns its method.
+func newBoundMethod(name string, fn reflect.Value) (boundMethod, error) {
+	t := fn.Type()
+	if t.IsVariadic() {
+		return boundMethod{}, fmt.Errorf("variadic: %w", ErrUnsupportedType)
+	}
+	if t.NumOut() > 1 || t.NumOut() == 1 && t.Out(0) != errorType {
+		out := make([]string, t.NumOut())
+		for i := range out {
+			out[i] = t.Out(i).String()
+		}
+		return boundMethod{}, fmt.Errorf("returns (%s), expected error or nothing: %w", strings.Join(out, ", "), ErrUnsupportedType)
+	}
+	bm := boundMethod{name: name, fn: fn}
+	var typetags strings.Builder
+
+	for i := 0; i < t.NumIn(); i++ {
+		param := t.In(i)
+		if i == 0 && param == messageType {
+			bm.message = true
+			continue
+		}
+		tt, err := paramTypetag(param)
+		if err != nil {
+			return boundMethod{}, fmt.Errorf("parameter %d: %w", i, err)
+		}
+		typetags.WriteByte(tt)
+		if param.Kind() == reflect.Ptr {
+			typetags.WriteByte(TypetagOptional)
+		}
+		bm.params = append(bm.params, param)
+	}
+	bm.typetags = typetags.String()
+	return bm, nil
+}
+
+// paramTypetag returns the typetag of the arguments of a parameter of type t.
+func paramTypetag(t reflect.Type) (byte, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return TypetagAny, nil
+	}
+	tt, err := fieldTypetag(t)
+	if err != nil {
+		return 0, err
+	}
+	if tt == TypetagTrue {
+		return TypetagAny, nil // Bools are 'T' or 'F'.
+	}
+	return tt, nil
+}
+
+// Handle decodes the arguments of msg and calls the method.
+func (m boundMethod) Handle(msg Message) error {
+	var (
+		args = msg.arguments()
+		in   = make([]reflect.Value, 0, len(m.params)+1)
+	)
+	if m.message {
+		in = append(in, reflect.ValueOf(msg))
+	}
+	for i, param := range m.params {
+		ptr := param.Kind() == reflect.Ptr
+		if i >= len(args) {
+			if !ptr {
+				return fmt.Errorf("%s: %s has no argument %d: %w", m.name, msg.Address, i, ErrArgumentCount)
+			}
+			in = append(in, reflect.Zero(param))
+			continue
+		}
+		if ptr {
+			param = param.Elem()
+		}
+		v := reflect.New(param)
+		if err := decodeArgument(v.Elem(), args[i]); err != nil {
+			return fmt.Errorf("%s: argument %d: %w", m.name, i, err)
+		}
+		if !ptr {
+			v = v.Elem()
+		}
+		in = append(in, v)
+	}
+	if out := m.fn.Call(in); len(out) == 1 && !out[0].IsNil() {
+		return out[0].Interface().(error)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package osc_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/scgolang/osc"
+	"github.com/scgolang/osc/osctest"
+)
+
+// mixer sends the calls of its methods to a channel.
+type mixer struct {
+	calls chan string
+}
+
+func (m *mixer) Level(ch int32, v float32) error {
+	m.calls <- fmt.Sprintf("Level %d %g", ch, v)
+	return nil
+}
+
+func (m *mixer) Mute(ch int32, on bool) error {
+	m.calls <- fmt.Sprintf("Mute %d %t", ch, on)
+	return nil
+}
+
+func TestBind_Loopback(t *testing.T) {
+	m := &mixer{calls: make(chan string, 8)}
+	d, err := osc.Bind("/mixer", m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := osctest.NewServer(t, d).Dial()
+
+	for _, p := range []osc.Packet{
+		osc.MustMessage("/mixer/level", int32(2), float32(0.75)),
+		osc.Bundle{
+			Timetag: osc.Immediately,
+			Packets: []osc.Packet{osc.MustMessage("/mixer/mute", int32(3), true)},
+		},
+	} {
+		if err := conn.Send(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, expected := range []string{"Level 2 0.75", "Mute 3 true"} {
+		select {
+		case got := <-m.calls:
+			if expected != got {
+				t.Fatalf("expected %q, got %q", expected, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %q", expected)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testMixer records the calls of its methods.
+type testMixer struct {
+	_ struct{} `osc:"Blackout=/panic, String=-"`
+
+	calls []string
+}
+
+func (m *testMixer) Level(ch int32, v float32) error {
+	m.calls = append(m.calls, fmt.Sprintf("Level %d %g", ch, v))
+	return nil
+}
+
+func (m *testMixer) Mute(ch int32, on bool) {
+	m.calls = append(m.calls, fmt.Sprintf("Mute %d %t", ch, on))
+}
+
+func (m *testMixer) Label(msg Message, name string, data []byte) error {
+	m.calls = append(m.calls, fmt.Sprintf("Label %s %s %s", msg.Address, name, data))
+	return nil
+}
+
+func (m *testMixer) Pan(ch int64, pan *float64) error {
+	if pan == nil {
+		m.calls = append(m.calls, fmt.Sprintf("Pan %d center", ch))
+		return nil
+	}
+	m.calls = append(m.calls, fmt.Sprintf("Pan %d %g", ch, *pan))
+	return nil
+}
+
+func (m *testMixer) Tint(c color) error {
+	m.calls = append(m.calls, fmt.Sprintf("Tint %+v", c))
+	return nil
+}
+
+func (m *testMixer) Blackout() error {
+	return errors.New("blackout failed")
+}
+
+func (m *testMixer) String() string { return "mixer" }
+
+func TestBind(t *testing.T) {
+	mixer := &testMixer{}
+	d, err := Bind("/mixer", mixer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs := d.(*Router).Addresses()
+	sort.Strings(addrs)
+	if expected, got := "/mixer/label /mixer/level /mixer/mute /mixer/pan /mixer/panic /mixer/tint", strings.Join(addrs, " "); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	for i, method := range []struct {
+		Addr     string
+		Typetags string
+	}{
+		{Addr: "/mixer/level", Typetags: "if"},
+		{Addr: "/mixer/mute", Typetags: "i*"},
+		{Addr: "/mixer/label", Typetags: "sb"},
+		{Addr: "/mixer/pan", Typetags: "hd?"},
+		{Addr: "/mixer/tint", Typetags: "*"},
+		{Addr: "/mixer/panic", Typetags: ""},
+	} {
+		typed, ok := d.(*Router).Methods()[method.Addr].(typedHandler)
+		if !ok {
+			t.Fatalf("(testcase %d) expected a typed method at %s", i, method.Addr)
+		}
+		if expected, got := method.Typetags, typed.Typetags(); expected != got {
+			t.Fatalf("(testcase %d) expected typetags %q, got %q", i, expected, got)
+		}
+	}
+	for i, msg := range []Message{
+		MustMessage("/mixer/level", int32(2), float32(0.75)),
+		MustMessage("/mixer/mute", int32(3), true),
+		MustMessage("/mixer/mute", int32(3), false),
+		MustMessage("/mixer/label", "drums", []byte("kit")),
+		MustMessage("/mixer/pan", int64(4), -0.5),
+		MustMessage("/mixer/pan", int64(4)),
+		{Address: "/mixer/tint", Arguments: Arguments{RGBA{R: 1, G: 2, B: 3, A: 4}}},
+	} {
+		if err := d.Invoke(msg, true); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+	}
+	if expected, got := []string{
+		"Level 2 0.75",
+		"Mute 3 true",
+		"Mute 3 false",
+		"Label /mixer/label drums kit",
+		"Pan 4 -0.5",
+		"Pan 4 center",
+		"Tint {R:1 G:2 B:3 A:4}",
+	}, mixer.calls; !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestBind_HandleErrors(t *testing.T) {
+	d, err := Bind("/mixer", &testMixer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		Message  Message
+		Cause    error
+		Expected string
+	}{
+		{
+			Message:  MustMessage("/mixer/level", int32(2), 0.75),
+			Cause:    ErrTypetagMismatch,
+			Expected: `/mixer/level: expected typetags "if", got "id": typetag mismatch`,
+		},
+		{
+			Message:  MustMessage("/mixer/mute", int32(2), "on"),
+			Cause:    ErrInvalidTypeTag,
+			Expected: `Mute: argument 1: expected typetag 'T', got 's': invalid type tag`,
+		},
+		{
+			Message:  MustMessage("/mixer/tint", "red"),
+			Cause:    ErrInvalidTypeTag,
+			Expected: `Tint: argument 0: *osc.color: UnmarshalOSC: expected a color, got 's': invalid type tag`,
+		},
+		{
+			Message:  MustMessage("/mixer/panic"),
+			Expected: "blackout failed",
+		},
+	} {
+		err := d.Invoke(testcase.Message, true)
+		if err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+		if testcase.Cause != nil && !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+type testBadParam struct{}
+
+func (testBadParam) Level(ch int, v float32) error { return nil }
+
+type testBadReturn struct{}
+
+func (testBadReturn) Level() (int, error) { return 0, nil }
+
+type testVariadic struct{}
+
+func (testVariadic) Level(v ...float32) error { return nil }
+
+type testRequiredAfterOptional struct{}
+
+func (testRequiredAfterOptional) Level(ch *int32, v float32) error { return nil }
+
+type testBadTag struct {
+	_ struct{} `osc:"Levle=/gain"`
+}
+
+func (testBadTag) Level() error { return nil }
+
+type testStringer struct{}
+
+func (testStringer) Level() error   { return nil }
+func (testStringer) String() string { return "stringer" }
+
+type testNoMethods struct{}
+
+func TestBind_Errors(t *testing.T) {
+	for i, testcase := range []struct {
+		Options  BindOptions
+		Value    interface{}
+		Cause    error
+		Expected string
+	}{
+		{
+			Value:    testBadParam{},
+			Cause:    ErrUnsupportedType,
+			Expected: "method Level: parameter 0: int: unsupported argument type",
+		},
+		{
+			Value:    testBadReturn{},
+			Cause:    ErrUnsupportedType,
+			Expected: "method Level: returns (int, error), expected error or nothing: unsupported argument type",
+		},
+		{
+			Value:    testVariadic{},
+			Cause:    ErrUnsupportedType,
+			Expected: "method Level: variadic: unsupported argument type",
+		},
+		{
+			Value:    testRequiredAfterOptional{},
+			Cause:    ErrInvalidTypeTag,
+			Expected: `method Level: /m/level: typetags "i?f": required argument 2 after an optional one: invalid type tag`,
+		},
+		{
+			Value:    testBadTag{},
+			Expected: `osc tag "Levle=/gain": osc.testBadTag has no method Levle`,
+		},
+		{
+			Value:    testStringer{},
+			Cause:    ErrUnsupportedType,
+			Expected: "method String: returns (string), expected error or nothing: unsupported argument type",
+		},
+		{
+			Value:    testNoMethods{},
+			Expected: "osc.testNoMethods has no methods to bind",
+		},
+		{
+			Value:    nil,
+			Cause:    ErrUnsupportedType,
+			Expected: "bind nil: unsupported argument type",
+		},
+		{
+			Value:    &testMixer{},
+			Options:  BindOptions{Name: func(method string) string { return "/same" }},
+			Expected: "methods Label and Level are both at /m/same",
+		},
+	} {
+		_, err := testcase.Options.Bind("/m", testcase.Value)
+		if err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+		if testcase.Cause != nil && !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestMethodAddress(t *testing.T) {
+	for i, testcase := range []struct {
+		Name     string
+		Expected string
+	}{
+		{Name: "Level", Expected: "/level"},
+		{Name: "MasterVolume", Expected: "/masterVolume"},
+		{Name: "EQ", Expected: "/eq"},
+		{Name: "HTTPPort", Expected: "/httpPort"},
+		{Name: "X", Expected: "/x"},
+	} {
+		if expected, got := testcase.Expected, methodAddress(testcase.Name); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBlobPadding(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, 4, 5, 8, 48 * 1024, 48*1024 + 1} {
+		blob := bytes.Repeat([]byte{0xAB}, n)
+		msg := MustMessage("/blob", blob, int32(7))
+		data := msg.Bytes()
+
+		// The blob is its length, its bytes and up to 3 null bytes, and the int after it is aligned.
+		payload := data[len("/blob\x00\x00\x00,bi\x00"):]
+		if size := 4 + padded(n) + 4; len(payload) != size {
+			t.Fatalf("(blob of %d bytes) expected a payload of %d bytes, got %d", n, size, len(payload))
+		}
+		if length := int(byteOrder.Uint32(payload)); length != n {
+			t.Fatalf("(blob of %d bytes) expected length %d, got %d", n, n, length)
+		}
+		if padding := payload[4+n : 4+padded(n)]; !bytes.Equal(padding, make([]byte, len(padding))) {
+			t.Fatalf("(blob of %d bytes) expected null padding, got %x", n, padding)
+		}
+
+		// Parsed blobs have their exact bytes, without the padding.
+		parsed, err := ParseMessage(data, nil)
+		if err != nil {
+			t.Fatalf("(blob of %d bytes) %s", n, err)
+		}
+		view, err := parseMessageView(data, nil)
+		if err != nil {
+			t.Fatalf("(blob of %d bytes) %s", n, err)
+		}
+		for _, m := range []Message{parsed, view} {
+			b, err := m.BlobAt(0)
+			if err != nil {
+				t.Fatalf("(blob of %d bytes) %s", n, err)
+			}
+			if !bytes.Equal(b, blob) {
+				t.Fatalf("(blob of %d bytes) expected the blob back, got %d bytes", n, len(b))
+			}
+			if i, err := m.Int32At(1); err != nil || i != 7 {
+				t.Fatalf("(blob of %d bytes) expected 7 after the blob, got %d (%v)", n, i, err)
+			}
+		}
+
+		// The builder encodes the same bytes, from a slice or from a reader.
+		for _, b := range []*Builder{
+			NewBuilder("/blob").Blob(blob).Int32(7),
+			NewBuilder("/blob").BlobFrom(bytes.NewReader(blob), n).Int32(7),
+		} {
+			built, err := b.AppendTo(nil)
+			if err != nil {
+				t.Fatalf("(blob of %d bytes) %s", n, err)
+			}
+			if !bytes.Equal(built, data) {
+				t.Fatalf("(blob of %d bytes) expected the builder to encode %x, got %x", n, data, built)
+			}
+		}
+	}
+}
+
+func TestBlobViews(t *testing.T) {
+	data := MustMessage("/blob", []byte("abcdef")).Bytes()
+	msg, err := ParseMessage(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := msg.BlobAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := msg.CopyBlobAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(data[len(data)-8:], "ABCDEF")
+	if string(b) != "ABCDEF" {
+		t.Fatalf("expected the blob to be a view of the data, got %q", b)
+	}
+	if string(copied) != "abcdef" {
+		t.Fatalf("expected the copied blob to be left alone, got %q", copied)
+	}
+	// Appending to the view does not overwrite the padding.
+	_ = append(b, 'G')
+	if data[len(data)-2] != 0 {
+		t.Fatalf("expected the padding to be left alone, got %x", data)
+	}
+	if _, err := msg.CopyBlobAt(1); !errors.Is(err, ErrArgumentIndex) {
+		t.Fatalf("expected ErrArgumentIndex, got %v", err)
+	}
+}
+
+func TestParseOptions_MaxBlobSize(t *testing.T) {
+	for i, testcase := range []struct {
+		Size, Max int
+		Err       bool
+	}{
+		{Size: 0, Max: 0},
+		{Size: 1024, Max: 0},
+		{Size: 0, Max: 4},
+		{Size: 4, Max: 4},
+		{Size: 5, Max: 4, Err: true},
+		{Size: 48 * 1024, Max: 32 * 1024, Err: true},
+	} {
+		data := MustMessage("/blob", Array{Blob(make([]byte, testcase.Size))}).Bytes()
+		_, err := ParseOptions{MaxBlobSize: testcase.Max}.ParsePacket(data)
+		if !testcase.Err {
+			if err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrBlobTooLarge) {
+			t.Fatalf("(testcase %d) expected ErrBlobTooLarge, got %v", i, err)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("(testcase %d) expected a *ParseError, got %T", i, err)
+		}
+		if s := err.Error(); !strings.Contains(s, "blob of "+strconv.Itoa(testcase.Size)+" bytes") || !strings.Contains(s, "maximum is "+strconv.Itoa(testcase.Max)) {
+			t.Fatalf("(testcase %d) expected the error to name the size and the limit, got %q", i, s)
+		}
+	}
+}
+
+func TestBuilderBlobFrom(t *testing.T) {
+	b := NewBuilder("/blob").BlobFrom(strings.NewReader("abc"), 4)
+	if err := b.Err(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if err := NewBuilder("/blob").BlobFrom(strings.NewReader(""), -1).Err(); err == nil {
+		t.Fatal("expected an error for a negative size")
+	}
+
+	// Blobs stream into the buffers of the builder.
+	var (
+		sample = bytes.Repeat([]byte{1}, 8*1024)
+		r      = bytes.NewReader(sample)
+		buf    []byte
+		err    error
+	)
+	b = NewBuilder("/sample")
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset("/sample")
+		r.Reset(sample)
+		if buf, err = b.BlobFrom(r, len(sample)).AppendTo(buf[:0]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 && !raceEnabled {
+		t.Fatalf("expected no allocations, got %f", allocs)
+	}
+	if msg, err := ParseMessage(buf, nil); err != nil || !msg.Equal(MustMessage("/sample", sample)) {
+		t.Fatalf("expected the sample, got %v (%v)", msg, err)
+	}
+	// The padding is zeroed, even where the builder held other bytes.
+	b.Reset("/sample")
+	if buf, err = b.BlobFrom(strings.NewReader("abc"), 3).AppendTo(buf[:0]); err != nil {
+		t.Fatal(err)
+	}
+	if expected := MustMessage("/sample", []byte("abc")).Bytes(); !bytes.Equal(expected, buf) {
+		t.Fatalf("expected %q, got %q", expected, buf)
+	}
+}
+
+func TestParseMalformedBlobs(t *testing.T) {
+	const header = "/b\x00\x00,bi\x00" // The blob, then an int, start at offset 8.
+	for i, testcase := range []struct {
+		Name    string
+		Data    string
+		Err     error
+		Offset  int
+		Lenient bool // True if LenientParsing accepts the message.
+	}{
+		{Name: "negative length", Data: header + "\xff\xff\xff\xff\x00\x00\x00\x01", Err: ErrNegativeBlobLength, Offset: 8},
+		{Name: "most negative length", Data: header + "\x80\x00\x00\x00", Err: ErrNegativeBlobLength, Offset: 8},
+		{Name: "length past the end", Data: header + "\x00\x00\x00\x08abcd", Err: ErrBlobPastEnd, Offset: 8},
+		{Name: "huge length", Data: header + "\x7f\xff\xff\xffabcd", Err: ErrBlobPastEnd, Offset: 8},
+		{Name: "length into the next argument", Data: header + "\x00\x00\x00\x05abcd\x00\x00\x00\x01", Err: io.EOF, Offset: 20},
+		{Name: "no length", Data: header + "\x00\x00", Err: io.ErrUnexpectedEOF, Offset: 8},
+		{Name: "missing padding", Data: "/b\x00\x00,b\x00\x00\x00\x00\x00\x03abc", Err: ErrMissingPadding, Offset: 8, Lenient: true},
+		{Name: "missing some padding", Data: "/b\x00\x00,b\x00\x00\x00\x00\x00\x01a\x00", Err: ErrMissingPadding, Offset: 8, Lenient: true},
+	} {
+		data := []byte(testcase.Data)
+		for _, parse := range []func() (Packet, error){
+			func() (Packet, error) { return ParseMessage(data, nil) },
+			func() (Packet, error) { return ParsePacket(data) },
+			func() (Packet, error) { return StrictParsing.ParsePacket(data) },
+			func() (Packet, error) { return parseMessageView(data, nil) },
+		} {
+			_, err := parse()
+			if !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d, %s) expected %v, got %v", i, testcase.Name, testcase.Err, err)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("(testcase %d, %s) expected a *ParseError, got %T", i, testcase.Name, err)
+			}
+			if pe.Offset != testcase.Offset {
+				t.Fatalf("(testcase %d, %s) expected offset %d, got %d", i, testcase.Name, testcase.Offset, pe.Offset)
+			}
+		}
+		if _, err := LenientParsing.ParsePacket(data); (err == nil) != testcase.Lenient {
+			t.Fatalf("(testcase %d, %s) expected lenient parsing to accept the message: %t, got %v", i, testcase.Name, testcase.Lenient, err)
+		}
+	}
+}
+
+func TestEmptyBlob(t *testing.T) {
+	for i, blob := range []Blob{nil, {}} {
+		msg := Message{Address: "/empty", Arguments: []Argument{blob, Int(1)}}
+		data := msg.Bytes()
+		if expected := "/empty\x00\x00,bi\x00\x00\x00\x00\x00\x00\x00\x00\x01"; string(data) != expected {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, data)
+		}
+		if b := blob.Bytes(); !bytes.Equal(b, []byte{0, 0, 0, 0}) {
+			t.Fatalf("(testcase %d) expected a zero length, got %x", i, b)
+		}
+		parsed, err := ParseMessage(data, nil)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !parsed.Equal(msg) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, msg, parsed)
+		}
+		if b, err := parsed.BlobAt(0); err != nil || b == nil || len(b) != 0 {
+			t.Fatalf("(testcase %d) expected an empty blob, got %q (%v)", i, b, err)
+		}
+	}
+}
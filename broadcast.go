@@ -0,0 +1,51 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+)
+
+// WithBroadcast enables sending to broadcast addresses on a UDP connection
+// by setting SO_BROADCAST on the socket.
+// The net package already sets it on most platforms, but relying on that
+// is not portable.
+// Stream-oriented connections reject this option.
+func WithBroadcast() Option {
+	return func(o *options) error {
+		o.broadcast = true
+		return nil
+	}
+}
+
+// Broadcast sends a packet to the limited broadcast address, 255.255.255.255,
+// on the given port.
+// The connection must have been created by ListenUDP with WithBroadcast,
+// since a dialed connection can only send to its remote address.
+func (conn *UDPConn) Broadcast(port int, p Packet) error {
+	return conn.SendTo(&net.UDPAddr{IP: net.IPv4bcast, Port: port}, p)
+}
+
+// setBroadcastOption sets SO_BROADCAST if the options ask for it.
+func (conn *UDPConn) setBroadcastOption(o options) error {
+	if !o.broadcast {
+		return nil
+	}
+	c, ok := conn.udpConn.(*net.UDPConn)
+	if !ok {
+		return fmt.Errorf("WithBroadcast requires a UDP socket: %w", ErrUnsupportedOption)
+	}
+	rc, err := c.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("setting SO_BROADCAST: %w", err)
+	}
+	var sockErr error
+	if err := rc.Control(func(fd uintptr) {
+		sockErr = setBroadcast(fd)
+	}); err != nil {
+		return fmt.Errorf("setting SO_BROADCAST: %w", err)
+	}
+	if sockErr != nil {
+		return fmt.Errorf("setting SO_BROADCAST: %w", sockErr)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// getBroadcast returns the value of SO_BROADCAST on the conn's socket.
+func getBroadcast(t *testing.T, conn *UDPConn) int {
+	rc, err := conn.udpConn.(*net.UDPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		value   int
+		sockErr error
+	)
+	if err := rc.Control(func(fd uintptr) {
+		value, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	return value
+}
+
+func TestUDPConnBroadcast(t *testing.T) {
+	laddr := &net.UDPAddr{IP: net.IPv4zero}
+
+	plain, err := ListenUDP("udp4", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = plain.Close() }() // Best effort.
+
+	conn, err := ListenUDP("udp4", laddr, WithBroadcast())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }() // Best effort.
+
+	if got := getBroadcast(t, conn); got != 1 {
+		t.Fatalf("expected SO_BROADCAST 1, got %d", got)
+	}
+	if err := conn.Broadcast(plain.LocalAddr().(*net.UDPAddr).Port, Message{Address: "/hello"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithBroadcastTCP(t *testing.T) {
+	if _, err := ListenTCP("tcp", nil, WithBroadcast()); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+	if _, err := DialTCP("tcp", nil, nil, WithBroadcast()); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+}
+
+func TestWithBroadcastError(t *testing.T) {
+	socket := listenSocket(t)
+	o, err := newOptions([]Option{WithBroadcast()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := &UDPConn{udpConn: socketUDPConn{udpConn: socket}, connState: newConnState(context.Background())}
+	if _, err := uc.initialize(o); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+	checkSocketClosed(t, socket)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package osc
+
+import (
+	"syscall"
+)
+
+// setBroadcast sets SO_BROADCAST on the socket fd.
+func setBroadcast(fd uintptr) error {
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+}
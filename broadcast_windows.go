@@ -0,0 +1,10 @@
+package osc
+
+import (
+	"syscall"
+)
+
+// setBroadcast sets SO_BROADCAST on the socket fd.
+func setBroadcast(fd uintptr) error {
+	return syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+}
@@ -0,0 +1,121 @@
+package osc
+
+import "sync"
+
+// poisonByte overwrites the read buffers that are released in poison mode,
+// so that methods which keep a message past their return read garbage.
+const poisonByte = 0xA5
+
+// readBuffer is a buffer that a packet is read into.
+// The pool holds pointers so that putting a buffer back does not allocate.
+type readBuffer struct {
+	data []byte
+}
+
+// readBuffers recycles the buffers that packets are read into.
+// A buffer is released once nothing that was parsed from it is used any more:
+// right after parsing for packets that were copied out of it, and after
+// dispatching for messages that are views over it.
+// Packets with blobs, which share memory with the buffer, keep it.
+// A nil *readBuffers allocates every buffer and recycles none.
+type readBuffers struct {
+	pool   sync.Pool
+	noPool bool
+	poison bool
+}
+
+// get returns a buffer of the given size.
+func (b *readBuffers) get(size int) *readBuffer {
+	if b == nil || b.noPool {
+		return &readBuffer{data: make([]byte, size)}
+	}
+	buf, _ := b.pool.Get().(*readBuffer)
+	if buf == nil || cap(buf.data) < size {
+		return &readBuffer{data: make([]byte, size)}
+	}
+	buf.data = buf.data[:size]
+	return buf
+}
+
+// put releases buf, which must not be used any more.
+func (b *readBuffers) put(buf *readBuffer) {
+	if b == nil || buf == nil {
+		return
+	}
+	if b.poison {
+		data := buf.data[:cap(buf.data)]
+		for i := range data {
+			data[i] = poisonByte
+		}
+	}
+	if !b.noPool {
+		b.pool.Put(buf)
+	}
+}
+
+// release releases buf, which p was parsed from, unless p has blobs that share memory
+// with it. The other slices of buf that p holds, the data of Raw and the payload
+// of the arguments of unknown typetags, are copied first if buf is going to be
+// reused or poisoned, so that p can still be dispatched.
+func (b *readBuffers) release(buf *readBuffer, p Packet) Packet {
+	if sharesMemory(p) {
+		return p
+	}
+	if b != nil && buf != nil && (!b.noPool || b.poison) {
+		p = detach(p)
+	}
+	b.put(buf)
+	return p
+}
+
+// detach returns p with copies of the slices of its read buffer other than blobs.
+// The elements of a bundle are detached in place, since p was just parsed.
+func detach(p Packet) Packet {
+	switch x := p.(type) {
+	case Message:
+		if x.raw != nil {
+			x.raw = append([]byte(nil), x.raw...)
+		}
+		if x.unknown.data != nil {
+			x.unknown.data = append([]byte(nil), x.unknown.data...)
+		}
+		return x
+	case Bundle:
+		for i, packet := range x.Packets {
+			x.Packets[i] = detach(packet)
+		}
+		return x
+	}
+	return p
+}
+
+// sharesMemory returns true if p holds a slice of the buffer it was parsed from,
+// which is the case if it has any blobs.
+func sharesMemory(p Packet) bool {
+	switch x := p.(type) {
+	case Message:
+		return argumentsShareMemory(x.Arguments)
+	case Bundle:
+		for _, packet := range x.Packets {
+			if sharesMemory(packet) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// argumentsShareMemory returns true if any of args is a blob.
+func argumentsShareMemory(args []Argument) bool {
+	for _, a := range args {
+		switch x := a.(type) {
+		case Blob:
+			return true
+		case Array:
+			if argumentsShareMemory(x) {
+				return true
+			}
+		}
+	}
+	return false
+}
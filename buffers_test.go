@@ -0,0 +1,171 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Run with -race.
+func TestServeBufferPooling(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("lazy=%t", lazy), func(t *testing.T) {
+			c1, c2 := Pipe()
+			defer func() { _ = c1.Close() }() // Best effort.
+
+			c2.SetLazyArguments(lazy)
+			c2.SetPoisonBuffers(true)
+
+			const n = 200
+			var (
+				mu      sync.Mutex
+				blobs   [][]byte
+				handled = make(chan error, n)
+			)
+			go func() {
+				_ = c2.Serve(4, PatternMatching{
+					"/n": Method(func(msg Message) error {
+						i, err := msg.Int32At(0)
+						if err != nil {
+							handled <- err
+							return nil
+						}
+						s, err := msg.StringAt(1)
+						if err == nil && s != fmt.Sprint(i) {
+							err = fmt.Errorf("expected %d, got %s", i, s)
+						}
+						if expected := MustMessage("/n", i, s).Bytes(); err == nil && !bytes.Equal(expected, msg.Raw()) {
+							err = fmt.Errorf("(message %d) expected raw %q, got %q", i, expected, msg.Raw())
+						}
+						handled <- err
+						return nil
+					}),
+					"/blob": Method(func(msg Message) error {
+						b, err := msg.BlobAt(0)
+						if !lazy {
+							// Blobs keep their buffer, so they can be kept.
+							mu.Lock()
+							blobs = append(blobs, b)
+							mu.Unlock()
+						}
+						handled <- err
+						return nil
+					}),
+				})
+			}()
+			for i := 0; i < n; i++ {
+				msg := MustMessage("/n", int32(i), fmt.Sprint(i))
+				if i%10 == 0 {
+					msg = MustMessage("/blob", []byte("blob"))
+				}
+				if err := c1.Send(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < n; i++ {
+				select {
+				case err := <-handled:
+					if err != nil {
+						t.Fatal(err)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("timeout waiting for message %d", i)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for i, b := range blobs {
+				if string(b) != "blob" {
+					t.Fatalf("(blob %d) expected blob, got %q", i, b)
+				}
+			}
+		})
+	}
+}
+
+// unknownTypetagMessage is a message to /foo with the int i and the unknown typetag 'Q',
+// whose payload is i again.
+func unknownTypetagMessage(i int32) unknownTypetagPacket {
+	return unknownTypetagPacket{typetags: ",iQ", data: append(Int(i).Bytes(), Int(i).Bytes()...)}
+}
+
+// Run with -race.
+func TestServeBufferPooling_UnknownTypetags(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	c2.parse = &ParseOptions{UnknownTypetags: true}
+	c2.SetPoisonBuffers(true)
+	c2.SetErrorHandler(func(err error) {}) // For the arguments that are truncated.
+
+	const n = 200
+	handled := make(chan error, n)
+	go func() {
+		_ = c2.Serve(4, PatternMatching{
+			"/foo": Method(func(msg Message) error {
+				args, err := msg.RawArguments()
+				if err != nil || len(args) != 2 {
+					handled <- fmt.Errorf("expected 2 raw arguments, got %d: %v", len(args), err)
+					return nil
+				}
+				// The payload of the unknown typetag is the int again.
+				i, err := args[0].ReadInt32()
+				if expected := Int(i).Bytes(); err == nil && !bytes.Equal(expected, args[1].Bytes()) {
+					err = fmt.Errorf("(message %d) expected the payload %q, got %q", i, expected, args[1].Bytes())
+				}
+				if expected := unknownTypetagMessage(i).Bytes(); err == nil && !bytes.Equal(expected, msg.Raw()) {
+					err = fmt.Errorf("(message %d) expected raw %q, got %q", i, expected, msg.Raw())
+				}
+				handled <- err
+				return nil
+			}),
+		})
+	}()
+	for i := 0; i < n; i++ {
+		if err := c1.Send(unknownTypetagMessage(int32(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-handled:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for message %d", i)
+		}
+	}
+}
+
+func BenchmarkServeBufferPooling(b *testing.B) {
+	for _, pool := range []bool{false, true} {
+		b.Run(fmt.Sprintf("pool=%t", pool), func(b *testing.B) {
+			c1, c2 := Pipe()
+			defer func() { _ = c2.Close() }() // Best effort.
+
+			c2.SetPoolBuffers(pool)
+			handled := make(chan struct{})
+			go func() {
+				_ = c2.Serve(4, PatternMatching{
+					"/a": Method(func(msg Message) error {
+						handled <- struct{}{}
+						return nil
+					}),
+				})
+			}()
+			msg := MustMessage("/a", int32(1), float32(2), "three")
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := c1.Send(msg); err != nil {
+					b.Fatal(err)
+				}
+				<-handled
+			}
+		})
+	}
+}
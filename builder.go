@@ -0,0 +1,291 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Builder encodes a message one argument at a time, with a typed writer for every type:
+//
+//	b := osc.NewBuilder("/s_new")
+//	b.String("sine").Int32(1001).Float32(440)
+//	msg, err := b.Message()
+//
+// Every writer appends the typetag and the payload of its argument to the encoded message,
+// so the builder can be Reset and used again for the next message without allocating.
+// Errors, such as an invalid address or a string with a null byte, do not stop
+// the writers that follow, and are all returned by Err, Message and AppendTo.
+// A Builder is not safe for concurrent use.
+type Builder struct {
+	address  string
+	typetags []byte
+	payload  []byte
+
+	// n is the number of arguments that were written, and depth the number of open arrays.
+	n     int
+	depth int
+	errs  []error
+
+	options ArgumentOptions
+}
+
+// NewBuilder returns a Builder for a message to the given address.
+func NewBuilder(address string) *Builder {
+	b := &Builder{}
+	b.Reset(address)
+	return b
+}
+
+// SetOptions makes the number writers encode the numbers with the typetags
+// of o.Floats and o.Ints, e.g. a Float64 with 'f' for a receiver that only understands floats.
+// The options are kept by Reset.
+func (b *Builder) SetOptions(o ArgumentOptions) *Builder {
+	b.options = o
+	return b
+}
+
+// Reset empties the builder for a message to the given address,
+// and keeps its buffers and options for the arguments of that message.
+func (b *Builder) Reset(address string) {
+	b.address = address
+	b.typetags = append(b.typetags[:0], TypetagPrefix)
+	b.payload = b.payload[:0]
+	b.n, b.depth = 0, 0
+	b.errs = b.errs[:0]
+	if err := validateAddressPattern(address); err != nil {
+		b.errs = append(b.errs, err)
+	}
+}
+
+// arg counts an argument with the given typetag, and returns the payload to append it to.
+func (b *Builder) arg(typetag byte) []byte {
+	b.typetags = append(b.typetags, typetag)
+	b.n++
+	return b.payload
+}
+
+// fail records the error of the argument that could not be written.
+func (b *Builder) fail(err error) *Builder {
+	b.errs = append(b.errs, fmt.Errorf("argument %d: %w", b.n, err))
+	b.n++
+	return b
+}
+
+// Int32 appends an int32 argument.
+func (b *Builder) Int32(i int32) *Builder { return b.int(int64(i), TypetagInt) }
+
+// Int64 appends an int64 argument.
+func (b *Builder) Int64(i int64) *Builder { return b.int(i, TypetagInt64) }
+
+// int appends an int whose own typetag is tt, with the typetag of the options.
+func (b *Builder) int(i int64, tt byte) *Builder {
+	tt, err := b.options.intTypetag(i, tt)
+	if err != nil {
+		return b.fail(err)
+	}
+	if tt == TypetagInt64 {
+		b.payload = appendUint64(b.arg(tt), uint64(i))
+	} else {
+		b.payload = appendUint32(b.arg(tt), uint32(i))
+	}
+	return b
+}
+
+// Float32 appends a float32 argument.
+func (b *Builder) Float32(f float32) *Builder { return b.float(float64(f), TypetagFloat) }
+
+// Float64 appends a float64 argument.
+func (b *Builder) Float64(f float64) *Builder { return b.float(f, TypetagDouble) }
+
+// float appends a float whose own typetag is tt, with the typetag of the options.
+func (b *Builder) float(f float64, tt byte) *Builder {
+	tt, err := b.options.floatTypetag(f, tt)
+	if err != nil {
+		return b.fail(err)
+	}
+	if tt == TypetagDouble {
+		b.payload = appendUint64(b.arg(tt), math.Float64bits(f))
+	} else {
+		b.payload = appendUint32(b.arg(tt), math.Float32bits(float32(f)))
+	}
+	return b
+}
+
+// Bool appends a boolean argument, which is encoded as the typetag 'T' or 'F'.
+func (b *Builder) Bool(v bool) *Builder {
+	if v {
+		b.arg(TypetagTrue)
+	} else {
+		b.arg(TypetagFalse)
+	}
+	return b
+}
+
+// String appends a string argument. Strings with a null byte are an error.
+func (b *Builder) String(s string) *Builder {
+	if err := validateString(s); err != nil {
+		return b.fail(err)
+	}
+	b.payload = appendString(b.arg(TypetagString), s)
+	return b
+}
+
+// Symbol appends a symbol argument. Symbols with a null byte are an error.
+func (b *Builder) Symbol(s string) *Builder {
+	if err := validateString(s); err != nil {
+		return b.fail(err)
+	}
+	b.payload = Pad(append(append(b.arg(TypetagSymbol), s...), 0))
+	return b
+}
+
+// Blob appends a blob argument.
+// A nil blob is an error, since it is most likely a value that was never set:
+// the empty blob is written by an empty non-nil slice, and the lack of a value by Nil.
+func (b *Builder) Blob(data []byte) *Builder {
+	if data == nil {
+		return b.fail(errors.New("nil blob, write []byte{} or Nil instead"))
+	}
+	b.payload = Pad(append(appendUint32(b.arg(TypetagBlob), uint32(len(data))), data...))
+	return b
+}
+
+// BlobFrom appends a blob argument of n bytes that are read from r straight into
+// the encoded message, e.g. a large sample buffer, without a slice of its own.
+// A reader that has fewer than n bytes is an error, and nothing is appended.
+func (b *Builder) BlobFrom(r io.Reader, n int) *Builder {
+	if n < 0 || int64(n) > math.MaxInt32 {
+		return b.fail(fmt.Errorf("blob of %d bytes: %w", n, ErrBlobTooLarge))
+	}
+	payload := appendUint32(b.payload, uint32(n))
+	start, end := len(payload), len(payload)+padded(n)
+	if cap(payload) < end {
+		grown := make([]byte, len(payload), end+end/2)
+		copy(grown, payload)
+		payload = grown
+	}
+	payload = payload[:end]
+	for i := start + n; i < end; i++ {
+		payload[i] = 0 // The spare capacity of the payload may hold the bytes of a previous message.
+	}
+	if _, err := io.ReadFull(r, payload[start:start+n]); err != nil {
+		return b.fail(fmt.Errorf("blob of %d bytes: %w", n, err))
+	}
+	b.payload = payload
+	b.arg(TypetagBlob)
+	return b
+}
+
+// Timetag appends a timetag argument.
+func (b *Builder) Timetag(tt Timetag) *Builder {
+	b.payload = appendUint64(b.arg(TypetagTimetag), uint64(tt))
+	return b
+}
+
+// Char appends a character argument. Characters that are not ASCII are an error.
+func (b *Builder) Char(c rune) *Builder {
+	if c < 0 || c > 0x7F {
+		return b.fail(fmt.Errorf("char %U: %w", c, ErrNonASCIIChar))
+	}
+	b.payload = appendUint32(b.arg(TypetagChar), uint32(c))
+	return b
+}
+
+// RGBA appends a color argument.
+func (b *Builder) RGBA(red, green, blue, alpha byte) *Builder {
+	b.payload = append(b.arg(TypetagRGBA), red, green, blue, alpha)
+	return b
+}
+
+// MIDI appends a MIDI message argument.
+func (b *Builder) MIDI(port, status, data1, data2 byte) *Builder {
+	b.payload = append(b.arg(TypetagMIDI), port, status, data1, data2)
+	return b
+}
+
+// Nil appends a nil argument.
+func (b *Builder) Nil() *Builder {
+	b.arg(TypetagNil)
+	return b
+}
+
+// Infinitum appends an infinitum argument.
+func (b *Builder) Infinitum() *Builder {
+	b.arg(TypetagInfinitum)
+	return b
+}
+
+// Argument appends an argument of any type, such as one from another package.
+// Arrays are appended with their elements.
+func (b *Builder) Argument(a Argument) *Builder {
+	if err := validateArgument(a); err != nil {
+		return b.fail(err)
+	}
+	if arr, ok := a.(Array); ok {
+		b.typetags = appendTypetags(b.typetags, []Argument{arr})
+	} else {
+		b.typetags = append(b.typetags, a.Typetag())
+	}
+	b.payload = appendArgument(b.payload, a)
+	b.n++
+	return b
+}
+
+// BeginArray starts an array argument.
+// The arguments that are written until the matching EndArray are its elements.
+func (b *Builder) BeginArray() *Builder {
+	b.typetags = append(b.typetags, TypetagArrayStart)
+	b.depth++
+	return b
+}
+
+// EndArray ends the array started by the last call to BeginArray.
+// Ending an array that was not begun is an error wrapping ErrUnbalancedArray.
+func (b *Builder) EndArray() *Builder {
+	if b.depth == 0 {
+		b.errs = append(b.errs, fmt.Errorf("EndArray without BeginArray: %w", ErrUnbalancedArray))
+		return b
+	}
+	b.typetags = append(b.typetags, TypetagArrayEnd)
+	b.depth--
+	return b
+}
+
+// Err returns the errors of the writers since the builder was created or reset,
+// joined with errors.Join, or nil if there were none.
+func (b *Builder) Err() error {
+	errs := b.errs
+	if b.depth > 0 {
+		errs = append(errs[:len(errs):len(errs)], fmt.Errorf("%s: BeginArray without EndArray: %w", b.address, ErrUnbalancedArray))
+	}
+	return errors.Join(errs...)
+}
+
+// AppendTo appends the encoded message to dst and returns the extended slice,
+// or dst and Err if a writer failed. It encodes the same bytes as the Bytes
+// of the message it builds, and does not allocate if dst has room for them.
+func (b *Builder) AppendTo(dst []byte) ([]byte, error) {
+	if err := b.Err(); err != nil {
+		return dst, err
+	}
+	if len(dst)%4 != 0 {
+		// The encoding is padded to multiples of 4 bytes from its start.
+		data, _ := b.AppendTo(nil)
+		return append(dst, data...), nil
+	}
+	dst = appendString(dst, b.address)
+	dst = Pad(append(append(dst, b.typetags...), 0))
+	return append(dst, b.payload...), nil
+}
+
+// Message returns the message that was built, or Err if a writer failed.
+// The message does not share any memory with the builder.
+func (b *Builder) Message() (Message, error) {
+	data, err := b.AppendTo(nil)
+	if err != nil {
+		return Message{}, err
+	}
+	return parseMessage(data, nil, nil)
+}
@@ -0,0 +1,169 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	server, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := Dial(server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	tt := FromTime(time.Unix(1700000000, 0))
+	b := NewBuilder("/builder")
+	for i, testcase := range []struct {
+		Build    func(b *Builder)
+		Expected func(msg *Message)
+	}{
+		{Build: func(b *Builder) {}, Expected: func(msg *Message) {}},
+		{Build: func(b *Builder) { b.Int32(-7) }, Expected: func(msg *Message) { msg.WriteInt32(-7) }},
+		{Build: func(b *Builder) { b.Int64(1 << 40) }, Expected: func(msg *Message) { msg.WriteInt64(1 << 40) }},
+		{Build: func(b *Builder) { b.Float32(440) }, Expected: func(msg *Message) { msg.WriteFloat32(440) }},
+		{Build: func(b *Builder) { b.Float64(0.25) }, Expected: func(msg *Message) { msg.WriteFloat64(0.25) }},
+		{Build: func(b *Builder) { b.Bool(true).Bool(false) }, Expected: func(msg *Message) { msg.WriteBool(true); msg.WriteBool(false) }},
+		{Build: func(b *Builder) { b.String("sine") }, Expected: func(msg *Message) { msg.WriteString("sine") }},
+		{Build: func(b *Builder) { b.String("four") }, Expected: func(msg *Message) { msg.WriteString("four") }},
+		{Build: func(b *Builder) { b.Symbol("sym") }, Expected: func(msg *Message) { msg.WriteSymbol("sym") }},
+		{Build: func(b *Builder) { b.Blob([]byte{1, 2, 3, 4}) }, Expected: func(msg *Message) { msg.WriteBlob([]byte{1, 2, 3, 4}) }},
+		{Build: func(b *Builder) { b.Blob([]byte{}) }, Expected: func(msg *Message) { msg.WriteBlob([]byte{}) }},
+		{Build: func(b *Builder) { b.Timetag(tt) }, Expected: func(msg *Message) { msg.WriteTimetag(tt) }},
+		{Build: func(b *Builder) { b.Char('x') }, Expected: func(msg *Message) { msg.WriteChar('x') }},
+		{Build: func(b *Builder) { b.RGBA(1, 2, 3, 4) }, Expected: func(msg *Message) { msg.WriteRGBA(1, 2, 3, 4) }},
+		{Build: func(b *Builder) { b.MIDI(0, 0x90, 60, 127) }, Expected: func(msg *Message) { msg.WriteMIDI(0, 0x90, 60, 127) }},
+		{Build: func(b *Builder) { b.Nil() }, Expected: func(msg *Message) { msg.WriteNil() }},
+		{Build: func(b *Builder) { b.Infinitum() }, Expected: func(msg *Message) { msg.WriteInfinitum() }},
+		{Build: func(b *Builder) { b.Argument(Int(3)) }, Expected: func(msg *Message) { msg.WriteInt32(3) }},
+		{
+			Build:    func(b *Builder) { b.Argument(Array{Int(1), String("a")}) },
+			Expected: func(msg *Message) { msg.Arguments = append(msg.Arguments, Array{Int(1), String("a")}) },
+		},
+		{
+			Build: func(b *Builder) { b.String("sine").BeginArray().Int32(1).BeginArray().EndArray().EndArray().Float32(1) },
+			Expected: func(msg *Message) {
+				msg.WriteString("sine")
+				msg.BeginArray()
+				msg.WriteInt32(1)
+				msg.BeginArray()
+				_ = msg.EndArray()
+				_ = msg.EndArray()
+				msg.WriteFloat32(1)
+			},
+		},
+		{
+			Build: func(b *Builder) { b.String("sine").Int32(1001).Float32(440).Blob([]byte("data")) },
+			Expected: func(msg *Message) {
+				msg.WriteString("sine")
+				msg.WriteInt32(1001)
+				msg.WriteFloat32(440)
+				msg.WriteBlob([]byte("data"))
+			},
+		},
+	} {
+		b.Reset("/builder")
+		testcase.Build(b)
+		expected := Message{Address: "/builder"}
+		testcase.Expected(&expected)
+
+		msg, err := b.Message()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !msg.Equal(expected) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, expected, msg)
+		}
+		data, err := b.AppendTo(nil)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if string(data) != string(expected.Bytes()) {
+			t.Fatalf("(testcase %d) expected %x, got %x", i, expected.Bytes(), data)
+		}
+		// The message reads back the same over loopback.
+		if err := client.Send(msg); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		p, _, err := server.ReceivePacket(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !p.Equal(expected) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, expected, p)
+		}
+	}
+}
+
+func TestBuilder_Errors(t *testing.T) {
+	for i, testcase := range []struct {
+		Address  string
+		Build    func(b *Builder)
+		Expected []error
+	}{
+		{Address: "nope", Build: func(b *Builder) {}},
+		{Address: "/nil", Build: func(b *Builder) { b.Blob(nil) }},
+		{Address: "/null", Build: func(b *Builder) { b.String("a\x00b") }},
+		{Address: "/char", Build: func(b *Builder) { b.Char('é') }, Expected: []error{ErrNonASCIIChar}},
+		{Address: "/end", Build: func(b *Builder) { b.EndArray() }, Expected: []error{ErrUnbalancedArray}},
+		{Address: "/begin", Build: func(b *Builder) { b.BeginArray().Int32(1) }, Expected: []error{ErrUnbalancedArray}},
+		// The errors accumulate, and the writers after them still work.
+		{Address: "/both", Build: func(b *Builder) { b.Char('é').Int32(1).EndArray() }, Expected: []error{ErrNonASCIIChar, ErrUnbalancedArray}},
+	} {
+		b := NewBuilder(testcase.Address)
+		testcase.Build(b)
+		if _, err := b.Message(); err == nil {
+			t.Fatalf("(testcase %d) expected an error", i)
+		}
+		data, err := b.AppendTo([]byte{1, 2, 3, 4})
+		if err == nil || len(data) != 4 {
+			t.Fatalf("(testcase %d) expected an error and dst, got %v and %x", i, err, data)
+		}
+		for _, expected := range testcase.Expected {
+			if !errors.Is(b.Err(), expected) {
+				t.Fatalf("(testcase %d) expected %v, got %v", i, expected, b.Err())
+			}
+		}
+		// Reset forgets them.
+		b.Reset("/ok")
+		if err := b.Int32(1).Err(); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+	}
+}
+
+func TestBuilder_Reset(t *testing.T) {
+	var (
+		b    = NewBuilder("/synth/new")
+		buf  = make([]byte, 0, 256)
+		data = []byte("data")
+	)
+	build := func() {
+		b.Reset("/synth/new")
+		var err error
+		if buf, err = b.String("sine").Int32(1001).Float32(440).Blob(data).AppendTo(buf[:0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	build()
+	if allocs := testing.AllocsPerRun(100, build); allocs != 0 {
+		t.Fatalf("expected a reset builder not to allocate, got %.1f allocations", allocs)
+	}
+	msg, err := ParseMessage(buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := MustMessage("/synth/new", "sine", int32(1001), float32(440), data); !msg.Equal(expected) {
+		t.Fatalf("expected %v, got %v", expected, msg)
+	}
+}
@@ -3,9 +3,10 @@ package osc
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"net"
-
-	"github.com/pkg/errors"
 )
 
 const (
@@ -19,6 +20,9 @@ var (
 	ErrEndOfPackets = errors.New("end of packets")
 )
 
+// udpMaxPacketSize is the largest payload of a UDP datagram over IPv4.
+const udpMaxPacketSize = 65507
+
 // Bundle is an OSC bundle.
 // An OSC Bundle consists of the OSC-string "#bundle" followed by an OSC Time Tag,
 // followed by zero or more bundle elements. The OSC-timetag is a 64-bit fixed
@@ -30,15 +34,26 @@ type Bundle struct {
 }
 
 // ParseBundle parses a bundle from a byte slice.
+// Blob arguments share memory with data, so use Clone if data is going to be reused.
+// Errors are a *ParseError.
 func ParseBundle(data []byte, sender net.Addr) (Bundle, error) {
-	return parseBundle(data, sender, -1)
+	b, err := parseBundle(data, sender, -1, newParseState(nil))
+	if err != nil {
+		return b, parseError(err, sender)
+	}
+	return b, nil
 }
 
 // parseBundle parses a bundle from a byte slice.
 // It will stop after reading limit bytes.
 // If you wish to have it consume as many bytes as possible, pass -1 as the limit.
-func parseBundle(data []byte, sender net.Addr, limit int32) (Bundle, error) {
-	b := Bundle{}
+// The bundle and its elements are parsed within the limits of ps.
+func parseBundle(data []byte, sender net.Addr, limit int32, ps *parseState) (Bundle, error) {
+	b := Bundle{Sender: sender}
+	if ps.depth++; ps.depth > ps.maxDepth {
+		return b, &ParseError{Sender: sender, Err: fmt.Errorf("bundle at depth %d: %w", ps.depth, ErrBundleTooDeep)}
+	}
+	defer func() { ps.depth-- }()
 
 	// If 0 <= limit < 16 this is an error.
 	// We have to be able to read at least the bundle tag and a timetag.
@@ -48,12 +63,12 @@ func parseBundle(data []byte, sender net.Addr, limit int32) (Bundle, error) {
 
 	data, err := sliceBundleTag(data)
 	if err != nil {
-		return b, errors.Wrap(err, "slice bundle tag")
+		return b, fmt.Errorf("slice bundle tag: %w", err)
 	}
 
 	tt, err := ReadTimetag(data)
 	if err != nil {
-		return b, errors.Wrap(err, "read timetag")
+		return b, &ParseError{Sender: sender, Offset: len(BundleTag) + 1, Err: fmt.Errorf("read timetag: %w", err)}
 	}
 	b.Timetag = tt
 	data = data[8:]
@@ -64,29 +79,138 @@ func parseBundle(data []byte, sender net.Addr, limit int32) (Bundle, error) {
 	}
 
 	// We take away 16 from limit so that readPackets doesn't have to know we have already read 16 bytes.
-	packets, err := readPackets(data, sender, limit-16)
+	packets, err := readPackets(data, sender, limit-16, ps)
 	if err != nil {
-		return b, errors.Wrap(err, "read packets")
+		shiftParseError(err, 16)
+		return b, fmt.Errorf("read packets: %w", err)
 	}
 	b.Packets = packets
 
 	return b, nil
 }
 
+// encodeBundle encodes a bundle of msgs with the given timetag.
+// It returns an error if the encoded bundle is larger than limit bytes.
+func encodeBundle(tt Timetag, msgs []Message, limit int) (Packet, error) {
+	packets := make([]Packet, len(msgs))
+	for i, msg := range msgs {
+		packets[i] = msg
+	}
+	data := Bundle{Timetag: tt, Packets: packets}.Bytes()
+	if len(data) > limit {
+		return nil, fmt.Errorf("bundle is %d bytes, the limit is %d: %w", len(data), limit, ErrPacketTooLarge)
+	}
+	return encodedPacket(data), nil
+}
+
+// encodedPacket is a packet that has already been encoded.
+type encodedPacket []byte
+
+// Bytes returns the encoded packet.
+func (p encodedPacket) Bytes() []byte {
+	return p
+}
+
+// Equal returns true if other encodes to the same bytes.
+func (p encodedPacket) Equal(other Packet) bool {
+	return bytes.Equal(p, other.Bytes())
+}
+
 // Bytes returns the contents of the bundle as a slice of bytes.
 func (b Bundle) Bytes() []byte {
-	bss := [][]byte{
-		ToBytes(BundleTag),
-		b.Timetag.Bytes(),
+	return b.appendTo(nil)
+}
+
+// AppendTo appends the encoded bundle to dst and returns the extended slice,
+// which is only reallocated if dst does not have room for it.
+// It encodes the same bytes as Bytes.
+func (b Bundle) AppendTo(dst []byte) []byte {
+	if len(dst)%4 != 0 {
+		// The encoding is padded to multiples of 4 bytes from its start.
+		return append(dst, b.appendTo(nil)...)
+	}
+	return b.appendTo(dst)
+}
+
+// WriteTo writes the encoded bundle to w with a single call to Write
+// and returns the number of bytes written.
+// Errors are reported the same way as for Message.WriteTo.
+func (b Bundle) WriteTo(w io.Writer) (int64, error) {
+	size, err := b.encodedSize()
+	if err != nil {
+		return 0, err
+	}
+	return writeEncoded(w, b.appendTo(make([]byte, 0, size)))
+}
+
+// encodedSize returns the size of the encoded bundle,
+// or an error if one of its packets can not be encoded.
+func (b Bundle) encodedSize() (int, error) {
+	size := stringSize(BundleTag) + 8
+	for i, p := range b.Packets {
+		n, err := packetSize(p)
+		if err != nil {
+			return 0, fmt.Errorf("packet %d: %w", i, err)
+		}
+		size += 4 + n
 	}
+	return size, nil
+}
+
+// appendTo appends the encoded bundle to b, which must have a multiple of 4 bytes.
+func (b Bundle) appendTo(buf []byte) []byte {
+	buf = appendUint64(appendString(buf, BundleTag), uint64(b.Timetag))
 	for _, p := range b.Packets {
-		var (
-			bs     = p.Bytes()
-			length = Int(int32(len(bs)))
-		)
-		bss = append(bss, length.Bytes(), p.Bytes())
+		start := len(buf)
+		buf = appendPacket(appendUint32(buf, 0), p)
+		byteOrder.PutUint32(buf[start:], uint32(len(buf)-start-4))
+	}
+	return buf
+}
+
+// packetSize returns the size of the encoded packet.
+func packetSize(p Packet) (int, error) {
+	switch x := p.(type) {
+	case Message:
+		return x.encodedSize()
+	case Bundle:
+		return x.encodedSize()
+	case nil:
+		return 0, errors.New("nil packet")
+	default:
+		return len(p.Bytes()), nil
+	}
+}
+
+// appendPacket appends the encoded packet to b, which must have a multiple of 4 bytes.
+func appendPacket(b []byte, p Packet) []byte {
+	switch x := p.(type) {
+	case Message:
+		return x.appendTo(b)
+	case Bundle:
+		return x.appendTo(b)
+	default:
+		return append(b, p.Bytes()...)
+	}
+}
+
+// Clone returns a deep copy of the bundle.
+func (b Bundle) Clone() Bundle {
+	clone := b
+	if b.Packets != nil {
+		clone.Packets = make([]Packet, len(b.Packets))
+		for i, p := range b.Packets {
+			switch x := p.(type) {
+			case Message:
+				clone.Packets[i] = x.Clone()
+			case Bundle:
+				clone.Packets[i] = x.Clone()
+			default:
+				clone.Packets[i] = p
+			}
+		}
 	}
-	return bytes.Join(bss, []byte{})
+	return clone
 }
 
 // Equal returns true if one bundle equals another, and false otherwise.
@@ -114,33 +238,35 @@ func (b Bundle) Equal(other Packet) bool {
 func sliceBundleTag(data []byte) ([]byte, error) {
 	bundleTag := append([]byte(BundleTag), 0)
 	if len(data) < len(bundleTag) {
-		return nil, errors.Errorf("expected %q, got %q", bundleTag, data)
+		return nil, fmt.Errorf("expected %q, got %q", bundleTag, data)
 	}
 	idx := bytes.Index(data, bundleTag)
 	if idx == -1 {
-		return nil, errors.Errorf("expected %q, got %q", bundleTag, data[:len(bundleTag)])
+		return nil, fmt.Errorf("expected %q, got %q", bundleTag, data[:len(bundleTag)])
 	}
 	return data[len(bundleTag):], nil
 }
 
 // readPackets reads bundle packets from a byte slice.
-func readPackets(data []byte, sender net.Addr, limit int32) ([]Packet, error) {
-	ps := []Packet{}
+func readPackets(data []byte, sender net.Addr, limit int32, ps *parseState) ([]Packet, error) {
+	packets := []Packet{}
 
 	var (
-		p   Packet
-		l   int32
-		err error
+		p      Packet
+		l      int32
+		err    error
+		offset int
 	)
 	for {
-		p, l, err = readPacket(data, sender)
+		p, l, err = readPacket(data, sender, ps)
 		if err == ErrEndOfPackets {
-			return ps, nil
+			return packets, nil
 		}
 		if err != nil {
-			return nil, errors.Wrap(err, "read packet")
+			shiftParseError(err, offset)
+			return nil, fmt.Errorf("read packet: %w", err)
 		}
-		ps = append(ps, p)
+		packets = append(packets, p)
 		if l+4 == int32(len(data)) {
 			break
 		}
@@ -148,8 +274,18 @@ func readPackets(data []byte, sender net.Addr, limit int32) ([]Packet, error) {
 			break
 		}
 		data = data[l+4:]
+		offset += int(l) + 4
+	}
+	return packets, nil
+}
+
+// shiftParseError adds n to the offset of the *ParseError in the chain of err,
+// which is relative to a part of the packet that starts at offset n.
+func shiftParseError(err error, n int) {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Offset += n
 	}
-	return ps, nil
 }
 
 // readPacket reads an OSC bundle packet from a byte slice.
@@ -158,8 +294,11 @@ func readPackets(data []byte, sender net.Addr, limit int32) ([]Packet, error) {
 // If ErrEndOfPackets is returned then Packet will always be nil.
 // The returned packet length includes the length of the packet length integer itself,
 // so it is actually packet_length + 4.
-func readPacket(data []byte, sender net.Addr) (Packet, int32, error) {
+func readPacket(data []byte, sender net.Addr, ps *parseState) (Packet, int32, error) {
 	if len(data) < 4 {
+		if o := ps.options; len(data) > 0 && o != nil && !o.UnalignedSize {
+			return nil, 0, &ParseError{Sender: sender, Err: fmt.Errorf("%d bytes after the last element: %w", len(data), ErrUnalignedPacket)}
+		}
 		return nil, int32(len(data)), ErrEndOfPackets
 	}
 	var l int32
@@ -170,24 +309,35 @@ func readPacket(data []byte, sender net.Addr) (Packet, int32, error) {
 
 	data = data[4:]
 
+	if l < 0 {
+		return nil, 0, &ParseError{Sender: sender, Err: fmt.Errorf("negative packet length %d", l)}
+	}
 	if int32(len(data)) < l {
-		return nil, 0, errors.Errorf("packet length %d is greater than data length %d", l, len(data))
+		return nil, 0, &ParseError{Sender: sender, Err: fmt.Errorf("packet length %d is greater than data length %d", l, len(data))}
+	}
+	// A packet, in particular a nested bundle, must not read past its own length.
+	data = data[:l]
+
+	if ps.elements++; ps.elements > ps.maxElements {
+		return nil, 0, &ParseError{Sender: sender, Err: fmt.Errorf("more than %d elements: %w", ps.maxElements, ErrTooManyElements)}
 	}
 
 	switch data[0] {
 	case MessageChar:
-		msg, err := ParseMessage(data, sender)
+		msg, err := parseMessage(data, sender, ps.options)
 		if err != nil {
-			return nil, 0, errors.Wrap(err, "parse message from packet")
+			shiftParseError(err, 4)
+			return nil, 0, fmt.Errorf("parse message from packet: %w", err)
 		}
 		return msg, l, nil // The returned length includes the packet length integer.
 	case BundleTag[0]:
-		bundle, err := parseBundle(data, sender, l)
+		bundle, err := parseBundle(data, sender, l, ps)
 		if err != nil {
-			return nil, 0, errors.Wrap(err, "parse bundle from packet")
+			shiftParseError(err, 4)
+			return nil, 0, fmt.Errorf("parse bundle from packet: %w", err)
 		}
 		return bundle, l, nil // The returned length includes the packet length integer.
 	default:
-		return nil, 0, errors.Errorf("packet should never start with %c", data[0])
+		return nil, 0, &ParseError{Sender: sender, Offset: 4, Err: fmt.Errorf("packet should never start with %c", data[0])}
 	}
 }
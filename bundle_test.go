@@ -2,9 +2,10 @@ package osc
 
 import (
 	"bytes"
+	"errors"
+	"net"
 	"testing"
-
-	"github.com/pkg/errors"
+	"time"
 )
 
 func TestBundleBytes(t *testing.T) {
@@ -385,8 +386,203 @@ func TestParseBundle(t *testing.T) {
 
 func TestParseBundleLimit(t *testing.T) {
 	// Test the limit parameter of parseBundle.
-	_, limitErr := parseBundle(nil, nil, 10)
+	_, limitErr := parseBundle(nil, nil, 10, newParseState(nil))
 	if expected, got := errors.New("limit must be >= 16 or < 0"), limitErr; got == nil || (expected.Error() != got.Error()) {
 		t.Fatalf("expected %s, got %s", expected, got)
 	}
 }
+
+func TestParseBundleSender(t *testing.T) {
+	var (
+		sender = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 57120}
+		input  = Bundle{
+			Timetag: Immediately,
+			Packets: []Packet{Message{Address: "/foo"}},
+		}
+	)
+	b, err := ParseBundle(input.Bytes(), sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Sender != sender {
+		t.Fatalf("expected bundle sender %s, got %v", sender, b.Sender)
+	}
+	if msg := b.Packets[0].(Message); msg.Sender != sender {
+		t.Fatalf("expected message sender %s, got %v", sender, msg.Sender)
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	nested := Bundle{
+		Timetag: Timetag(5),
+		Packets: []Packet{
+			Message{Address: "/a", Arguments: Arguments{Int(1)}},
+			Bundle{Timetag: Timetag(6)},
+		},
+	}
+	for i, b := range []Bundle{
+		{Timetag: Immediately},
+		{
+			Timetag: Timetag(2),
+			Packets: []Packet{
+				Message{Address: "/x"},
+				Message{Address: "/y", Arguments: Arguments{String("s"), Blob{1, 2, 3, 4}}},
+			},
+		},
+		{
+			Timetag: Timetag(3),
+			Packets: []Packet{nested, Message{Address: "/z"}},
+		},
+		{
+			Timetag: Timetag(4),
+			Packets: []Packet{Message{Address: "/z"}, nested, Bundle{Timetag: Timetag(7)}},
+		},
+	} {
+		got, err := ParseBundle(b.Bytes(), nil)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !b.Equal(got) {
+			t.Fatalf("(testcase %d) expected %#v, got %#v", i, b, got)
+		}
+	}
+}
+
+func TestParseBundleBadPacketLength(t *testing.T) {
+	data := append(Bundle{Timetag: Immediately}.Bytes(), Int(-4).Bytes()...)
+	if _, err := ParseBundle(data, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestBundleString(t *testing.T) {
+	b := Bundle{
+		Timetag: FromTime(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Packets: []Packet{
+			MustMessage("/n_set", 1000, "freq", float32(440)),
+			Bundle{
+				Timetag: Immediately,
+				Packets: []Packet{
+					MustMessage("/n_run", 1000, 1),
+				},
+			},
+			Message{Address: "/sync"},
+		},
+	}
+	expected := `#bundle 2000-01-01T00:00:00Z
+  /n_set ,isf 1000 "freq" 440
+  #bundle immediately
+    /n_run ,ii 1000 1
+  /sync ,`
+	if got := b.String(); expected != got {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestBundleClone(t *testing.T) {
+	b := Bundle{
+		Timetag: Immediately,
+		Packets: []Packet{
+			MustMessage("/a", []byte("blob")),
+			Bundle{Timetag: 2, Packets: []Packet{MustMessage("/b", []byte("nest"))}},
+		},
+	}
+	clone := b.Clone()
+	if !b.Equal(clone) {
+		t.Fatalf("expected %s, got %s", b, clone)
+	}
+	clone.Packets[0].(Message).Arguments[0].(Blob)[0] = 'B'
+	clone.Packets[1].(Bundle).Packets[0].(Message).Arguments[0].(Blob)[0] = 'N'
+	clone.Timetag = 3
+
+	expected := Bundle{
+		Timetag: Immediately,
+		Packets: []Packet{
+			MustMessage("/a", []byte("blob")),
+			Bundle{Timetag: 2, Packets: []Packet{MustMessage("/b", []byte("nest"))}},
+		},
+	}
+	if !expected.Equal(b) {
+		t.Fatalf("expected %s, got %s", expected, b)
+	}
+	if expected.Equal(clone) {
+		t.Fatal("expected the clone to have changed")
+	}
+}
+
+func TestBundleWriteTo(t *testing.T) {
+	b := Bundle{
+		Timetag: 2,
+		Packets: []Packet{
+			MustMessage("/a", 1, []byte("blob")),
+			Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/b", "c")}},
+			Bundle{Timetag: Immediately},
+		},
+	}
+	var buf bytes.Buffer
+	n, err := b.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := b.Bytes(), buf.Bytes(); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %x, got %x", expected, got)
+	}
+	if expected, got := int64(buf.Len()), n; expected != got {
+		t.Fatalf("expected %d bytes written, got %d", expected, got)
+	}
+
+	b.Packets = append(b.Packets, Bundle{Packets: []Packet{Message{Address: "/a b"}}})
+	if _, err := b.WriteTo(&buf); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected %v, got %v", ErrInvalidAddress, err)
+	}
+}
+
+func TestBundleAppendTo(t *testing.T) {
+	b := Bundle{
+		Timetag: 2,
+		Packets: []Packet{
+			MustMessage("/a", 1, []byte("blob")),
+			Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/b", "c")}},
+			Bundle{Timetag: Immediately},
+		},
+	}
+	// The packets are encoded the way each of them is on its own.
+	expected := append(ToBytes(BundleTag), b.Timetag.Bytes()...)
+	for _, p := range b.Packets {
+		data := p.Bytes()
+		expected = append(append(expected, Int(len(data)).Bytes()...), data...)
+	}
+	if got := b.Bytes(); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %x, got %x", expected, got)
+	}
+	for _, prefix := range []string{"", "abcd", "abc"} {
+		if got := b.AppendTo([]byte(prefix)); !bytes.Equal(append([]byte(prefix), expected...), got) {
+			t.Fatalf("(prefix %q) expected %x, got %x", prefix, expected, got)
+		}
+	}
+	buf := make([]byte, 0, 256)
+	if got := b.AppendTo(buf); &got[0] != &buf[:1][0] {
+		t.Fatal("expected the buffer to be reused")
+	}
+}
+
+func BenchmarkBundleBytes(b *testing.B) {
+	bundle := Bundle{Timetag: Immediately, Packets: []Packet{
+		MustMessage("/a", 1, 2.5, "three"),
+		MustMessage("/b", make([]byte, 512)),
+		Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/c", 4)}},
+	}}
+	b.Run("Bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = bundle.Bytes()
+		}
+	})
+	b.Run("AppendTo", func(b *testing.B) {
+		var buf []byte
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = bundle.AppendTo(buf[:0])
+		}
+	})
+}
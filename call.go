@@ -0,0 +1,179 @@
+package osc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// callTable holds the Calls of a conn that are waiting for their reply.
+type callTable struct {
+	mu      sync.Mutex
+	waiting map[string][]*pendingCall // By reply address, in the order of the Calls.
+	count   int32                     // The number of Calls waiting, read without mu.
+}
+
+// pendingCall is a Call waiting for its reply.
+type pendingCall struct {
+	reply chan Message
+
+	// match returns true if a message to the reply address is the reply.
+	// If it is nil any message is.
+	match func(Message) bool
+}
+
+// add registers a Call waiting for a message to addr that match accepts.
+func (t *callTable) add(addr string, match func(Message) bool) *pendingCall {
+	c := &pendingCall{reply: make(chan Message, 1), match: match}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.waiting == nil {
+		t.waiting = map[string][]*pendingCall{}
+	}
+	t.waiting[addr] = append(t.waiting[addr], c)
+	atomic.AddInt32(&t.count, 1)
+	return c
+}
+
+// remove unregisters c, if it has not been given a reply yet.
+func (t *callTable) remove(addr string, c *pendingCall) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(addr, c)
+}
+
+// removeLocked is remove with mu held. It returns false if c was not waiting.
+func (t *callTable) removeLocked(addr string, c *pendingCall) bool {
+	waiting := t.waiting[addr]
+	for i, w := range waiting {
+		if w != c {
+			continue
+		}
+		if len(waiting) == 1 {
+			delete(t.waiting, addr)
+		} else {
+			t.waiting[addr] = append(waiting[:i:i], waiting[i+1:]...)
+		}
+		atomic.AddInt32(&t.count, -1)
+		return true
+	}
+	return false
+}
+
+// deliver gives msg to the first Call waiting for it,
+// and returns false if there is none.
+func (t *callTable) deliver(msg Message) bool {
+	if atomic.LoadInt32(&t.count) == 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.waiting[msg.Address] {
+		if c.match != nil && !c.match(msg) {
+			continue
+		}
+		t.removeLocked(msg.Address, c)
+		c.reply <- msg.Clone() // The message may be a view over a read buffer.
+		return true
+	}
+	return false
+}
+
+// deliverBundle returns b without the messages it gave to Calls.
+func (t *callTable) deliverBundle(b Bundle) Bundle {
+	packets := make([]Packet, 0, len(b.Packets))
+	for _, p := range b.Packets {
+		switch x := p.(type) {
+		case Message:
+			if t.deliver(x) {
+				continue
+			}
+		case Bundle:
+			p = t.deliverBundle(x)
+		}
+		packets = append(packets, p)
+	}
+	b.Packets = packets
+	return b
+}
+
+// callDispatcher gives the messages that Calls are waiting for to the Calls,
+// and the others to the dispatcher being served. Subscriptions get a copy of both.
+// The messages that the access control of the conn rejects are dropped first.
+type callDispatcher struct {
+	conn  *connState
+	calls *callTable
+	subs  *subscriptions
+	next  Dispatcher
+}
+
+// Dispatch dispatches the messages of bundle that no Call is waiting for.
+func (d callDispatcher) Dispatch(bundle Bundle, exactMatch bool) error {
+	bundle = d.conn.admitBundle(bundle)
+	d.subs.publishBundle(bundle)
+	if atomic.LoadInt32(&d.calls.count) > 0 {
+		bundle = d.calls.deliverBundle(bundle)
+	}
+	return d.conn.dispatchTimed(d.next, bundle, exactMatch)
+}
+
+// Invoke invokes msg unless a Call is waiting for it.
+func (d callDispatcher) Invoke(msg Message, exactMatch bool) error {
+	if !d.conn.admitMessage(msg) {
+		return nil
+	}
+	d.subs.publish(msg)
+	if d.calls.deliver(msg) {
+		return nil
+	}
+	return d.conn.invokeTimed(d.next, msg, exactMatch)
+}
+
+// withCalls returns d with the messages that Calls are waiting for taken out,
+// and the messages published to the subscriptions.
+func (s *connState) withCalls(d Dispatcher) Dispatcher {
+	return callDispatcher{conn: s, calls: &s.calls, subs: &s.subs, next: d}
+}
+
+// call sends request and waits for the first message to replyAddress.
+func (s *connState) call(ctx context.Context, send func(Packet) error, request Message, replyAddress string) (Message, error) {
+	if err := validateAddressPattern(replyAddress); err != nil {
+		return Message{}, fmt.Errorf("reply address: %w", err)
+	}
+	if isPattern(replyAddress) {
+		return Message{}, fmt.Errorf("reply address %q is a pattern: %w", replyAddress, ErrInvalidAddress)
+	}
+	return s.await(ctx, send, request, replyAddress, nil)
+}
+
+// await sends request and waits for the first message to replyAddress that match accepts.
+func (s *connState) await(ctx context.Context, send func(Packet) error, request Message, replyAddress string, match func(Message) bool) (Message, error) {
+	c := s.calls.add(replyAddress, match)
+	defer s.calls.remove(replyAddress, c)
+
+	if err := send(request); err != nil {
+		return Message{}, err
+	}
+	select {
+	case reply := <-c.reply:
+		return reply, nil
+	case <-ctx.Done():
+	case <-s.closeChan:
+	}
+	// A reply that was delivered before the Call was removed is still returned.
+	s.calls.remove(replyAddress, c)
+	select {
+	case reply := <-c.reply:
+		return reply, nil
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+	return Message{}, net.ErrClosed
+}
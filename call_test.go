@@ -0,0 +1,177 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCallServer returns a client that is serving and a server that answers
+// /status with /status.reply and /echo with /echo.reply after delay,
+// and never answers /ignore.
+func testCallServer(t *testing.T, delay time.Duration) (*UDPConn, *UDPConn) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(4, PatternMatching{
+			"/status": ReplyMethod(func(msg Message) (*Message, error) {
+				time.Sleep(delay)
+				reply := MustMessage("/status.reply", int32(1))
+				return &reply, nil
+			}),
+			"/echo": ReplyMethod(func(msg Message) (*Message, error) {
+				time.Sleep(delay)
+				reply := Message{Address: "/echo.reply", Arguments: msg.Arguments}
+				return &reply, nil
+			}),
+			"/ignore": Method(func(msg Message) error { return nil }),
+		})
+	}()
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = client.Serve(1, PatternMatching{})
+	}()
+	return server, client
+}
+
+func TestCall(t *testing.T) {
+	server, client := testCallServer(t, 20*time.Millisecond)
+	defer func() { _ = server.Close() }() // Best effort.
+	defer func() { _ = client.Close() }() // Best effort.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reply, err := client.Call(ctx, MustMessage("/status"), "/status.reply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := MustMessage("/status.reply", int32(1)); !reply.Equal(expected) {
+		t.Fatalf("expected %s, got %s", expected, reply)
+	}
+	// Calls waiting on the same address get one reply each,
+	// and Calls waiting on different addresses get their own.
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 16)
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				_, err := client.Call(ctx, MustMessage("/status"), "/status.reply")
+				errs <- err
+				return
+			}
+			reply, err := client.Call(ctx, MustMessage("/echo", "x"), "/echo.reply")
+			if err == nil {
+				if s, _ := reply.StringAt(0); s != "x" {
+					err = fmt.Errorf("(call %d) expected x, got %s", i, reply)
+				}
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := client.calls.count; n != 0 || len(client.calls.waiting) != 0 {
+		t.Fatalf("expected no calls to be waiting, got %d", n)
+	}
+	if n := client.Unmatched(); n != 0 {
+		t.Fatalf("expected the replies not to be dispatched, got %d unmatched", n)
+	}
+}
+
+func TestCall_NoReply(t *testing.T) {
+	server, client := testCallServer(t, 0)
+	defer func() { _ = server.Close() }() // Best effort.
+	defer func() { _ = client.Close() }() // Best effort.
+
+	for i := 0; i < 4; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err := client.Call(ctx, MustMessage("/ignore"), "/ignore.reply")
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("(call %d) expected context.DeadlineExceeded, got %v", i, err)
+		}
+	}
+	for i, testcase := range []struct {
+		Request      Message
+		ReplyAddress string
+	}{
+		{Request: MustMessage("/status"), ReplyAddress: "status.reply"},
+		{Request: MustMessage("/status"), ReplyAddress: "/status.*"},
+		{Request: Message{Address: "status"}, ReplyAddress: "/status.reply"},
+	} {
+		if _, err := client.Call(context.Background(), testcase.Request, testcase.ReplyAddress); err == nil {
+			t.Fatalf("(testcase %d) expected an error", i)
+		}
+	}
+	if n := client.calls.count; n != 0 || len(client.calls.waiting) != 0 {
+		t.Fatalf("expected no calls to be waiting, got %d", n)
+	}
+}
+
+func TestCall_Bundle(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	dispatched := make(chan Message, 1)
+	go func() {
+		_ = c1.Serve(1, PatternMatching{
+			"/other": Method(func(msg Message) error {
+				dispatched <- msg
+				return nil
+			}),
+		})
+	}()
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			"/status": Method(func(msg Message) error {
+				return c2.Send(Bundle{Timetag: Immediately, Packets: []Packet{
+					MustMessage("/other"),
+					Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/status.reply", int32(2))}},
+				}})
+			}),
+		})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	reply, err := c1.Call(ctx, MustMessage("/status"), "/status.reply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, err := reply.Int32At(0); err != nil || i != 2 {
+		t.Fatalf("expected 2, got %s (%v)", reply, err)
+	}
+	select {
+	case msg := <-dispatched:
+		if msg.Address != "/other" {
+			t.Fatalf("expected /other, got %s", msg.Address)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the rest of the bundle")
+	}
+}
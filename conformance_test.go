@@ -0,0 +1,34 @@
+package osc_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/scgolang/osc"
+	"github.com/scgolang/osc/osctest"
+)
+
+// TestConformance decodes every canonical packet of osctest, and encodes it again.
+func TestConformance(t *testing.T) {
+	vectors, err := osctest.Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			p, err := osc.ParsePacket(v.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !p.Equal(v.Packet) {
+				t.Fatalf("expected %s, got %s", v.Packet, p)
+			}
+			if got := p.Bytes(); !bytes.Equal(got, v.Data) {
+				t.Fatalf("the parsed packet encodes to other bytes:\n%s", osctest.DiffHex(v.Data, got))
+			}
+			if got := v.Packet.Bytes(); !bytes.Equal(got, v.Data) {
+				t.Fatalf("the expected packet encodes to other bytes:\n%s", osctest.DiffHex(v.Data, got))
+			}
+		})
+	}
+}
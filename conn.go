@@ -3,8 +3,12 @@ package osc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -19,25 +23,611 @@ var (
 	ErrPrematureClose = errors.New("server cannot be closed before calling Listen")
 )
 
-// Conn defines the methods
+// Conn defines the methods shared by every OSC connection,
+// real or in-memory.
+// Application code that accepts a Conn rather than a concrete type
+// can be handed a UDP, TCP, unix socket, WebSocket, stream or pipe connection.
+//
+// Send and SendTo are safe for concurrent use by multiple goroutines,
+// including from within the methods invoked by Serve.
+// Serve should only be called once per connection.
+// Close may be called from any goroutine and makes a running Serve return nil.
+// The setters of the concrete types, such as SetExactMatch and SetContext,
+// must be called before Serve.
 type Conn interface {
-	net.Conn
+	// Close closes the connection.
+	Close() error
 
+	// LocalAddr returns the local network address.
+	LocalAddr() net.Addr
+
+	// Context returns the context associated with the connection.
 	Context() context.Context
-	Serve(int, Dispatcher) error
-	Send(Packet) error
-	SendTo(net.Addr, Packet) error
+
+	// Serve reads packets and dispatches them with the given number of workers
+	// until the connection is closed or an error occurs.
+	Serve(numWorkers int, dispatcher Dispatcher) error
+
+	// ServeContext is like Serve, but it also stops serving when ctx is done.
+	ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error
+
+	// Send sends a packet to the peer of a connected conn.
+	Send(p Packet) error
+
+	// SendTo sends a packet to the given address.
+	SendTo(addr net.Addr, p Packet) error
+
+	// SendBundle sends msgs to the peer of a connected conn in a single bundle,
+	// so they are all read at once.
+	SendBundle(tt Timetag, msgs ...Message) error
+
+	// SendBundleTo sends msgs to the given address in a single bundle.
+	SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error
+
+	// Reply sends msg to the sender of a message that was read from the conn.
+	Reply(to net.Addr, msg Message) error
+
+	// Call sends request and waits for the first message to replyAddress.
+	Call(ctx context.Context, request Message, replyAddress string) (Message, error)
+
+	// Receive reads packets onto a channel instead of dispatching them.
+	Receive(ctx context.Context) (<-chan Incoming, error)
+
+	// ReceivePacket reads and parses a single packet.
+	ReceivePacket(ctx context.Context) (Packet, net.Addr, error)
+
+	// Ping measures the round-trip time to a peer that answers with EchoMethod.
+	Ping(ctx context.Context, addr string) (time.Duration, error)
+
+	// Stats returns a snapshot of the counters of the connection.
+	Stats() Stats
+}
+
+// Make sure every connection type implements Conn.
+var (
+	_ Conn = (*PipeConn)(nil)
+	_ Conn = (*StreamConn)(nil)
+	_ Conn = (*TCPConn)(nil)
+	_ Conn = (*UDPConn)(nil)
+	_ Conn = (*UnixConn)(nil)
+	_ Conn = (*WSConn)(nil)
+)
+
+// ParseError is the error caused by an inbound packet that is not valid OSC.
+// ParseMessage and ParseBundle return it too.
+type ParseError struct {
+	// Sender is the address the packet came from.
+	Sender net.Addr
+
+	// Address is the address of the message that could not be parsed,
+	// if it got that far.
+	Address string
+
+	// Offset is the offset in the packet of the first byte that could not be parsed,
+	// or of the start of the part of the packet that is invalid.
+	Offset int
+
+	Err error
 }
 
-var invalidAddressRunes = []rune{'*', '?', ',', '[', ']', '{', '}', '#', ' '}
+// Error returns the error message.
+func (e *ParseError) Error() string { return e.Err.Error() }
+
+// Cause returns the underlying error.
+func (e *ParseError) Cause() error { return e.Err }
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// MethodError is the error caused by a method that returned an error
+// while handling an inbound packet.
+type MethodError struct {
+	// Sender is the address the packet came from.
+	Sender net.Addr
+	Err    error
+}
+
+// Error returns the error message.
+func (e *MethodError) Error() string { return e.Err.Error() }
+
+// Cause returns the underlying error.
+func (e *MethodError) Cause() error { return e.Err }
+
+// Unwrap returns the underlying error.
+func (e *MethodError) Unwrap() error { return e.Err }
+
+// DispatchError is the error returned by a dispatcher when a method fails,
+// and passed to the error handler when no method matches a message.
+type DispatchError struct {
+	// Address is the address of the method, or of the message if no method matched it.
+	Address string
+	Err     error
+}
+
+// Error returns the error message.
+func (e *DispatchError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *DispatchError) Unwrap() error { return e.Err }
+
+// AddressError is the error caused by an invalid address or address pattern.
+// It matches ErrInvalidAddress with errors.Is.
+type AddressError struct {
+	// Address is the invalid address.
+	Address string
+
+	// Offset is the offset in the address of the offending character.
+	Offset int
+
+	// Reason says what is wrong with the address.
+	Reason string
+}
+
+// Error returns the error message.
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("%q %s: %v", e.Address, e.Reason, ErrInvalidAddress)
+}
+
+// Unwrap returns ErrInvalidAddress.
+func (e *AddressError) Unwrap() error { return ErrInvalidAddress }
+
+// PanicError is the error caused by a method that panicked.
+// Serve reports it to the error handler as the cause of a *MethodError.
+type PanicError struct {
+	// Value is the value that was passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+// Error returns the panic value and the stack trace.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// invalidAddressChars are the characters that are not allowed in the address of a method.
+const invalidAddressChars = "*?,[]{}# "
 
 // ValidateAddress returns an error if addr contains
-// characters that are disallowed by the OSC spec.
+// characters that are disallowed by the OSC spec in the address of a method.
+// The error is an *AddressError that names the first such character and its offset.
+// Use CompilePattern to check address patterns.
 func ValidateAddress(addr string) error {
-	for _, chr := range invalidAddressRunes {
-		if strings.ContainsRune(addr, chr) {
-			return ErrInvalidAddress
-		}
+	if i := strings.IndexAny(addr, invalidAddressChars); i >= 0 {
+		return addressError(addr, i, "has %q at offset %d", addr[i], i)
 	}
 	return nil
 }
+
+// connState is the state shared by every connection type that can serve OSC.
+type connState struct {
+	closeChan   chan struct{}
+	ctx         context.Context
+	exactMatch  bool
+	noRecover   bool
+	readBufSize int
+	strict      bool
+
+	// queueSize and queuePolicy configure the queue of packets waiting for a worker.
+	queueSize   int
+	queuePolicy QueuePolicy
+
+	// sendBuf is the buffer that packets are encoded into to be sent.
+	sendBuf sendBuffer
+
+	hooks hooks
+
+	// calls are the Calls waiting for their reply.
+	calls callTable
+
+	// pingAddress is the OSC address of the pings that Ping sends.
+	pingAddress string
+
+	// subs are the subscriptions to the messages that are served.
+	subs               subscriptions
+	subscriptionPolicy QueuePolicy
+
+	// lazy, noPool and poison configure the read buffers,
+	// and the messages that are views over them.
+	lazy   bool
+	noPool bool
+	poison bool
+
+	// scheduling, latePolicy and clock configure the bundle scheduler.
+	scheduling bool
+	latePolicy LatePolicy
+	clock      clock
+
+	errMu        sync.Mutex
+	errorHandler func(error)
+
+	// unmatched is the number of messages that no method matched.
+	unmatchedMu     sync.Mutex
+	unmatched       uint64
+	unmatchedPolicy UnmatchedPolicy
+
+	// dropped is the number of packets the queue discarded.
+	droppedMu sync.Mutex
+	dropped   uint64
+
+	// allowSource and allowMethod are the access control of the conn,
+	// and rejected is the number of packets and messages they dropped.
+	allowSource func(addr net.Addr) bool
+	allowMethod func(addr net.Addr, oscAddr string) bool
+	rejectedMu  sync.Mutex
+	rejected    uint64
+
+	// limiter drops the packets beyond the rate limits, if there are any.
+	limiter *rateLimiter
+
+	counters *counters
+
+	// logger, if not nil, logs what the conn does with the packets it does not dispatch.
+	logger *slog.Logger
+
+	// parse, if not nil, are the deviations from the specification the conn tolerates.
+	parse *ParseOptions
+
+	// serveMu guards shutdown, the calls to Add on serving,
+	// and whether the conn is being served or received from.
+	serveMu      sync.Mutex
+	serving      sync.WaitGroup
+	servers      int
+	receiving    bool
+	shuttingDown bool
+	shutdown     chan struct{}
+}
+
+// newConnState creates the state for a new connection.
+func newConnState(ctx context.Context) connState {
+	return connState{
+		closeChan:   make(chan struct{}),
+		ctx:         ctx,
+		readBufSize: bufSize,
+		clock:       realClock{},
+		shutdown:    make(chan struct{}),
+		counters:    &counters{},
+	}
+}
+
+// CloseChan returns a channel that is closed when the connection gets closed.
+func (s *connState) CloseChan() <-chan struct{} {
+	return s.closeChan
+}
+
+// Context returns the context associated with the conn.
+func (s *connState) Context() context.Context {
+	return s.ctx
+}
+
+// startServing records that serve is running.
+// It returns false if the conn is shutting down,
+// and ErrReceiving if the conn is being received from.
+func (s *connState) startServing() (bool, error) {
+	s.serveMu.Lock()
+	defer s.serveMu.Unlock()
+
+	if s.shuttingDown {
+		return false, nil
+	}
+	if s.receiving {
+		return false, ErrReceiving
+	}
+	s.servers++
+	s.serving.Add(1)
+	return true, nil
+}
+
+// doneServing records that serve has returned.
+func (s *connState) doneServing() {
+	s.serveMu.Lock()
+	s.servers--
+	s.serveMu.Unlock()
+	s.serving.Done()
+}
+
+// startReceiving records that Receive is reading packets.
+// It returns an error if the conn is shutting down, serving or receiving already.
+func (s *connState) startReceiving() error {
+	s.serveMu.Lock()
+	defer s.serveMu.Unlock()
+
+	switch {
+	case s.shuttingDown:
+		return net.ErrClosed
+	case s.servers > 0:
+		return ErrServing
+	case s.receiving:
+		return ErrReceiving
+	}
+	s.receiving = true
+	s.serving.Add(1)
+	return nil
+}
+
+// doneReceiving records that Receive has stopped reading packets.
+func (s *connState) doneReceiving() {
+	s.serveMu.Lock()
+	s.receiving = false
+	s.serveMu.Unlock()
+	s.serving.Done()
+}
+
+// shutdownChan returns a channel that is closed when the conn starts shutting down.
+func (s *connState) shutdownChan() <-chan struct{} {
+	return s.shutdown
+}
+
+// shutdownAndClose tells serve to stop reading packets, waits for it to
+// return or for ctx to be done, and then calls closeConn.
+func (s *connState) shutdownAndClose(ctx context.Context, closeConn func() error) error {
+	s.serveMu.Lock()
+	if !s.shuttingDown {
+		s.shuttingDown = true
+		close(s.shutdown)
+	}
+	s.serveMu.Unlock()
+
+	if s.logger != nil {
+		s.log(slog.LevelDebug, "osc: shutting down")
+	}
+	drained := make(chan struct{})
+	go func() {
+		s.serving.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		if s.logger != nil {
+			s.log(slog.LevelDebug, "osc: shut down, every packet was dispatched")
+		}
+		return closeConn()
+	case <-ctx.Done():
+		if s.logger != nil {
+			s.log(slog.LevelWarn, "osc: shutdown gave up waiting for the methods", errorAttr(ctx.Err()))
+		}
+		_ = closeConn() // Best effort.
+		return ctx.Err()
+	}
+}
+
+// readBufferSize returns the size of the buffer each packet is read into.
+func (s *connState) readBufferSize() int {
+	if s.readBufSize == 0 {
+		return bufSize
+	}
+	return s.readBufSize
+}
+
+// sendLimit returns the size of the largest packet that may be sent.
+// It is the same as the largest packet the conn accepts.
+func (s *connState) sendLimit() int {
+	return s.readBufferSize()
+}
+
+// SetContext sets the context associated with the conn.
+func (s *connState) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// SetErrorHandler sets a function that is called with every error caused
+// by an inbound packet, which is either a *ParseError or a *MethodError,
+// and with a *DispatchError for every message that no method matched.
+// Such errors do not stop Serve unless the conn is in strict mode.
+// The handler may be called from several goroutines at once.
+func (s *connState) SetErrorHandler(handler func(error)) {
+	s.errMu.Lock()
+	s.errorHandler = handler
+	s.errMu.Unlock()
+}
+
+// handleError passes err to the error handler, if there is one.
+func (s *connState) handleError(err error) {
+	s.countError(err)
+
+	s.errMu.Lock()
+	handler := s.errorHandler
+	s.errMu.Unlock()
+
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// Unmatched returns the number of messages that were dropped because no method
+// of a PatternMatching dispatcher matched them and it has no Default method.
+func (s *connState) Unmatched() uint64 {
+	s.unmatchedMu.Lock()
+	defer s.unmatchedMu.Unlock()
+	return s.unmatched
+}
+
+// SetUnmatchedPolicy sets what the conn does with messages that no method matched.
+// The default is ErrorUnmatched.
+func (s *connState) SetUnmatchedPolicy(policy UnmatchedPolicy) {
+	s.unmatchedMu.Lock()
+	s.unmatchedPolicy = policy
+	s.unmatchedMu.Unlock()
+}
+
+// handleUnmatched counts msg, which no method matched,
+// and reports err according to the unmatched policy.
+func (s *connState) handleUnmatched(msg Message, err error) {
+	s.unmatchedMu.Lock()
+	s.unmatched++
+	policy := s.unmatchedPolicy
+	s.unmatchedMu.Unlock()
+	s.countDrop(ReasonUnmatched)
+
+	switch policy {
+	case ErrorUnmatched:
+		s.handleError(err)
+	case WarnUnmatched:
+		if s.logger != nil {
+			s.log(slog.LevelWarn, "osc: no method matched", remoteAttr(msg.Sender), addressAttr(msg.ownedAddress()))
+		}
+	}
+}
+
+// SetQueueSize makes the Serve method read packets into a queue of the given size
+// while all of the workers are busy, so a slow method does not hold up reading.
+// What happens when the queue is full is decided by the queue policy.
+// The packets in the queue are still dispatched when Serve returns because
+// the conn is closed or shut down.
+// The default is 0, which reads the next packet only once a worker is ready for it.
+// The queue size is also the size of the channel that Receive returns.
+func (s *connState) SetQueueSize(n int) {
+	s.queueSize = n
+}
+
+// SetQueuePolicy sets what Serve does with packets that arrive while the queue is full,
+// and what Receive does with packets that arrive while its channel is full.
+// The default is BlockWhenFull.
+func (s *connState) SetQueuePolicy(policy QueuePolicy) {
+	s.queuePolicy = policy
+}
+
+// Dropped returns the number of packets that were discarded because the queue was full.
+func (s *connState) Dropped() uint64 {
+	s.droppedMu.Lock()
+	defer s.droppedMu.Unlock()
+	return s.dropped
+}
+
+// countDropped counts a packet from sender that the queue discarded.
+func (s *connState) countDropped(sender net.Addr) {
+	s.droppedMu.Lock()
+	s.dropped++
+	s.droppedMu.Unlock()
+	s.countDrop(ReasonQueueFull)
+
+	if s.logger != nil {
+		s.log(slog.LevelWarn, "osc: queue full, packet dropped", remoteAttr(sender))
+	}
+}
+
+// newQueue returns the queue for the packets that pass through gate,
+// or nil if packets are not queued.
+func (s *connState) newQueue(gate *dispatchGate) *packetQueue {
+	if s.queueSize <= 0 {
+		return nil
+	}
+	return newPacketQueue(s.queueSize, s.queuePolicy, gate, s.countDropped)
+}
+
+// SetLazyArguments changes the behavior of the Serve method so that the messages
+// it reads are views over its read buffer, whose arguments are decoded by the
+// typed readers of Message, such as Int32At, when a method reads them.
+// Reading and dispatching a message to a method with a literal address
+// then allocates nothing, as long as the read buffers are pooled.
+//
+// The Arguments of the messages are nil, and the messages are only valid
+// until the method they were passed to returns: a method that keeps a message,
+// its address or the strings and blobs read from it any longer must keep
+// a Clone instead. See SetPoisonBuffers for catching the methods that don't.
+// Bundles are still parsed as a whole.
+func (s *connState) SetLazyArguments(value bool) {
+	s.lazy = value
+}
+
+// lazyArguments returns true if messages are views over their read buffer.
+func (s *connState) lazyArguments() bool {
+	return s.lazy
+}
+
+// SetPoolBuffers changes whether the Serve method reuses the buffers it reads packets into.
+// A buffer is reused once the packet read into it has been copied out of it,
+// or, for the views of SetLazyArguments, once the method it was passed to has returned.
+// Packets with blobs, which share memory with the buffer, never give it back.
+// The default is true. Turning it off gives every packet a buffer of its own,
+// which helps to tell whether a bug is caused by a buffer that is reused too early.
+func (s *connState) SetPoolBuffers(value bool) {
+	s.noPool = !value
+}
+
+// SetPoisonBuffers makes the Serve method overwrite every read buffer with garbage
+// once nothing that was parsed from it should be used any more, so that methods which
+// break the rule of SetLazyArguments fail loudly instead of seeing the next packet.
+// It is meant for debugging.
+func (s *connState) SetPoisonBuffers(value bool) {
+	s.poison = value
+}
+
+// newBuffers returns the read buffers of a server.
+func (s *connState) newBuffers() *readBuffers {
+	return &readBuffers{noPool: s.noPool, poison: s.poison}
+}
+
+// SetStrict changes the behavior of the Serve method so that the first
+// packet that can not be parsed, or the first error returned from a method,
+// makes Serve return that error.
+// The error handler is still called.
+func (s *connState) SetStrict(value bool) {
+	s.strict = value
+}
+
+// strictMode returns true if the conn is in strict mode.
+func (s *connState) strictMode() bool {
+	return s.strict
+}
+
+// SetRecoverPanics changes whether the Serve method recovers from methods that panic,
+// which it does by default. A recovered panic is passed to the error handler
+// as a *MethodError caused by a *PanicError, and Serve keeps running
+// unless the conn is in strict mode. The rest of a bundle whose method
+// panicked is not dispatched.
+// With false, a panicking method crashes the program.
+func (s *connState) SetRecoverPanics(value bool) {
+	s.noRecover = !value
+}
+
+// recoverPanics returns true if panics in methods are recovered.
+func (s *connState) recoverPanics() bool {
+	return !s.noRecover
+}
+
+// SetScheduler changes the behavior of the Serve method so that bundles
+// whose timetag is in the future are queued and dispatched at their timetag,
+// without holding up a worker while they wait.
+// Bundles with the Immediately timetag are dispatched as soon as they arrive.
+// Pending bundles are discarded when Serve returns.
+func (s *connState) SetScheduler(value bool) {
+	s.scheduling = value
+}
+
+// SetLatePolicy sets what the scheduler does with bundles whose timetag
+// has already passed. The default is DispatchLate.
+func (s *connState) SetLatePolicy(policy LatePolicy) {
+	s.latePolicy = policy
+}
+
+// newScheduler returns a scheduler for the bundles dispatched with dispatcher,
+// or nil if the scheduler is disabled.
+func (s *connState) newScheduler(dispatcher Dispatcher, exactMatch bool) *scheduler {
+	if !s.scheduling {
+		return nil
+	}
+	c := s.clock
+	if c == nil {
+		c = realClock{}
+	}
+	return &scheduler{
+		clock:       c,
+		dispatcher:  dispatcher,
+		exactMatch:  exactMatch,
+		handleError: s.handleError,
+		countLate:   s.countLate,
+		latePolicy:  s.latePolicy,
+		recover:     s.recoverPanics(),
+		maxElements: s.parse.maxBundleElements(),
+	}
+}
+
+// SetExactMatch changes the behavior of the Serve method so that
+// messages will only be dispatched to methods whose addresses
+// match the message's address exactly.
+// This should provide some performance improvement.
+func (s *connState) SetExactMatch(value bool) {
+	s.exactMatch = value
+}
@@ -1,10 +1,335 @@
 package osc
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
 	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
+// connPair creates a server and a client connected to it.
+type connPair func(t *testing.T) (server, client Conn)
+
+func udpConnPair(t *testing.T) (Conn, Conn) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+func tcpConnPair(t *testing.T) (Conn, Conn) {
+	laddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenTCP("tcp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialTCP("tcp", nil, server.LocalAddr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+func pipeConnPair(t *testing.T) (Conn, Conn) {
+	server, client := Pipe()
+	return server, client
+}
+
+func streamConnPair(t *testing.T) (Conn, Conn) {
+	server, client := testStreamPipe(t, SLIP)
+	return server, client
+}
+
+// TestConnPingPong runs the same exchange over every transport using only the Conn interface.
+func TestConnPingPong(t *testing.T) {
+	for name, newPair := range map[string]connPair{
+		"udp":    udpConnPair,
+		"tcp":    tcpConnPair,
+		"pipe":   pipeConnPair,
+		"stream": streamConnPair,
+	} {
+		server, client := newPair(t)
+		// testConnPingPong closes the client.
+		testConnPingPong(t, name, server, client)
+		_ = server.Close() // Best effort.
+	}
+}
+
+func testConnPingPong(t *testing.T, name string, server, client Conn) {
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			"/ping": Method(func(msg Message) error {
+				return server.SendTo(msg.Sender, Message{Address: "/pong", Arguments: msg.Arguments})
+			}),
+		})
+	}()
+	var (
+		pongs   = make(chan Message)
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- client.Serve(1, PatternMatching{
+			"/pong": Method(func(msg Message) error {
+				pongs <- msg
+				return nil
+			}),
+		})
+	}()
+	expected := Message{Address: "/pong", Arguments: Arguments{String(name)}}
+	if err := client.Send(Message{Address: "/ping", Arguments: expected.Arguments}); err != nil {
+		t.Fatalf("(%s) %s", name, err)
+	}
+	select {
+	case got := <-pongs:
+		if !expected.Equal(got) {
+			t.Fatalf("(%s) expected %s, got %s", name, expected, got)
+		}
+	case err := <-errChan:
+		t.Fatalf("(%s) client stopped: %v", name, err)
+	case <-time.After(2 * time.Second):
+		t.Fatalf("(%s) timeout waiting for pong", name)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("(%s) %s", name, err)
+	}
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatalf("(%s) %s", name, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("(%s) timeout waiting for Serve to return", name)
+	}
+}
+
+// Run with -race.
+func TestConnConcurrentSend(t *testing.T) {
+	const (
+		senders = 16
+		each    = 50
+	)
+	for _, testcase := range []struct {
+		Name    string
+		NewPair connPair
+		Lossy   bool
+	}{
+		{Name: "udp", NewPair: udpConnPair, Lossy: true},
+		{Name: "tcp", NewPair: tcpConnPair},
+		{Name: "pipe", NewPair: pipeConnPair},
+		{Name: "stream", NewPair: streamConnPair},
+	} {
+		server, client := testcase.NewPair(t)
+
+		intact := make(chan error, senders*each)
+		go func() {
+			_ = server.Serve(4, PatternMatching{
+				"/n": Method(func(msg Message) error {
+					sender, err := msg.Int32At(0)
+					if err != nil {
+						intact <- err
+						return nil
+					}
+					seq, err := msg.Int32At(1)
+					if err != nil {
+						intact <- err
+						return nil
+					}
+					s, err := msg.StringAt(2)
+					if err == nil && s != fmt.Sprintf("%d-%d", sender, seq) {
+						err = fmt.Errorf("message %d-%d has %q", sender, seq, s)
+					}
+					intact <- err
+					return nil
+				}),
+			})
+		}()
+		var (
+			wg      sync.WaitGroup
+			sendErr = make(chan error, senders)
+		)
+		for i := 0; i < senders; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < each; j++ {
+					msg := MustMessage("/n", int32(i), int32(j), fmt.Sprintf("%d-%d", i, j), make([]byte, 16*j))
+					if err := client.Send(msg); err != nil {
+						sendErr <- err
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(sendErr)
+		for err := range sendErr {
+			t.Fatalf("(%s) %v", testcase.Name, err)
+		}
+		// Datagrams may be dropped, but the ones that arrive must be intact.
+		received := 0
+	loop:
+		for received < senders*each {
+			select {
+			case err := <-intact:
+				if err != nil {
+					t.Fatalf("(%s) %v", testcase.Name, err)
+				}
+				received++
+			case <-time.After(time.Second):
+				if testcase.Lossy && received > 0 {
+					break loop
+				}
+				t.Fatalf("(%s) got %d of %d messages", testcase.Name, received, senders*each)
+			}
+		}
+		_ = client.Close() // Best effort.
+		_ = server.Close() // Best effort.
+	}
+}
+
+// bundleRecorder is a dispatcher that emits every bundle it dispatches.
+type bundleRecorder chan Bundle
+
+func (r bundleRecorder) Dispatch(b Bundle, exactMatch bool) error {
+	r <- b
+	return nil
+}
+
+func (r bundleRecorder) Invoke(msg Message, exactMatch bool) error {
+	return fmt.Errorf("expected a bundle, got message %s", msg.Address)
+}
+
+// waitBundle waits for a bundle to be emitted on bundles.
+func waitBundle(t *testing.T, bundles bundleRecorder) Bundle {
+	select {
+	case b := <-bundles:
+		return b
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for bundle")
+	}
+	return Bundle{}
+}
+
+var testBundleMessages = []Message{
+	{Address: "/n_set", Arguments: Arguments{Int(1000), String("freq"), Float(440)}},
+	{Address: "/n_set", Arguments: Arguments{Int(1000), String("amp"), Float(0.5)}},
+	{Address: "/n_run", Arguments: Arguments{Int(1000), Int(1)}},
+}
+
+// expectBundleMessages checks that b contains exactly testBundleMessages.
+func expectBundleMessages(t *testing.T, name string, b Bundle) {
+	if expected, got := len(testBundleMessages), len(b.Packets); expected != got {
+		t.Fatalf("(%s) expected %d packets, got %d", name, expected, got)
+	}
+	for i, msg := range testBundleMessages {
+		if !msg.Equal(b.Packets[i]) {
+			t.Fatalf("(%s) expected %s, got %s", name, msg, b.Packets[i])
+		}
+	}
+}
+
+func TestConnSendBundle(t *testing.T) {
+	for name, newPair := range map[string]connPair{
+		"udp":    udpConnPair,
+		"tcp":    tcpConnPair,
+		"pipe":   pipeConnPair,
+		"stream": streamConnPair,
+	} {
+		server, client := newPair(t)
+		bundles := make(bundleRecorder, 1)
+		go func() {
+			_ = server.Serve(1, bundles)
+		}()
+		if err := client.SendBundle(Immediately, testBundleMessages...); err != nil {
+			t.Fatalf("(%s) %s", name, err)
+		}
+		expectBundleMessages(t, name, waitBundle(t, bundles))
+
+		_ = client.Close() // Best effort.
+		_ = server.Close() // Best effort.
+	}
+}
+
+func TestUDPConnSendBundleTo(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	bundles := make(bundleRecorder, 1)
+	go func() {
+		_ = server.Serve(1, bundles)
+	}()
+	if err := client.SendBundleTo(server.LocalAddr(), Immediately, testBundleMessages...); err != nil {
+		t.Fatal(err)
+	}
+	b := waitBundle(t, bundles)
+	expectBundleMessages(t, "udp", b)
+
+	if expected, got := client.LocalAddr().String(), b.Sender.String(); expected != got {
+		t.Fatalf("expected sender %s, got %s", expected, got)
+	}
+}
+
+func TestConnSendBundleTooLarge(t *testing.T) {
+	server, client := udpConnPair(t)
+	defer func() { _ = server.Close() }() // Best effort.
+	defer func() { _ = client.Close() }() // Best effort.
+
+	big := Message{Address: "/b_setn", Arguments: Arguments{String(strings.Repeat("x", udpMaxPacketSize))}}
+
+	err := client.SendBundle(Immediately, big)
+	if !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("expected ErrPacketTooLarge, got %v", err)
+	}
+	if expected, got := "bundle is 65540 bytes, the limit is 65507", err.Error(); !strings.Contains(got, expected) {
+		t.Fatalf("expected error to contain %q, got %q", expected, got)
+	}
+	if err := client.SendBundleTo(server.LocalAddr(), Immediately, big); !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("expected ErrPacketTooLarge, got %v", err)
+	}
+
+	// Stream conns are limited by their max packet size.
+	a, b := net.Pipe()
+	defer func() { _ = a.Close() }() // Best effort.
+	defer func() { _ = b.Close() }() // Best effort.
+
+	stream, err := NewConn(a, SLIP, WithMaxPacketSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendBundle(Immediately, testBundleMessages...); !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("expected ErrPacketTooLarge, got %v", err)
+	}
+}
+
 func TestUDPConn(t *testing.T) {
 	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
 	if err != nil {
@@ -22,7 +347,185 @@ func TestValidateAddress(t *testing.T) {
 	if err := ValidateAddress("/foo"); err != nil {
 		t.Fatal(err)
 	}
-	if err := ValidateAddress("/foo@^#&*$^*%)()#($*@"); err == nil {
-		t.Fatal("expected error, got nil")
+	err := ValidateAddress("/foo@^#&*$^*%)()#($*@")
+	if !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected %v, got %v", ErrInvalidAddress, err)
+	}
+	if expected, got := `"/foo@^#&*$^*%)()#($*@" has '#' at offset 6: invalid OSC address`, err.Error(); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestServeErrorTypes(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		errs = make(chan error, 1)
+		fail = errors.New("fail")
+	)
+	c2.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			"/fail": Method(func(msg Message) error { return fail }),
+		})
+	}()
+	badTypetag := Message{Address: "/foo", Arguments: Arguments{Int(1)}}.Bytes()
+	badTypetag[10] = 'Q' // ",iQ", and the second argument starts at offset 16.
+
+	// A bad typetag.
+	if err := c1.SendRaw(badTypetag); err != nil {
+		t.Fatal(err)
+	}
+	var pe *ParseError
+	if err := waitError(t, errs); !errors.As(err, &pe) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	if pe.Address != "/foo" || pe.Offset != 16 || pe.Sender == nil || !errors.Is(pe, ErrInvalidTypeTag) {
+		t.Fatalf("expected /foo at offset 16 with an invalid typetag, got %s at offset %d: %v", pe.Address, pe.Offset, pe)
+	}
+	// A bad typetag inside of a bundle.
+	if err := c1.SendRaw(Bundle{Timetag: Immediately, Packets: []Packet{encodedPacket(badTypetag)}}.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitError(t, errs); !errors.As(err, &pe) {
+		t.Fatalf("expected *ParseError, got %v", err)
+	}
+	if pe.Address != "/foo" || pe.Offset != 36 {
+		t.Fatalf("expected /foo at offset 36, got %s at offset %d: %v", pe.Address, pe.Offset, pe)
+	}
+	// An invalid address.
+	if err := c1.SendRaw(Message{Address: "/a b"}.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	var ae *AddressError
+	if err := waitError(t, errs); !errors.As(err, &pe) || !errors.As(err, &ae) || !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected *ParseError caused by *AddressError, got %v", err)
+	}
+	if ae.Address != "/a b" || ae.Offset != 2 || pe.Offset != 2 {
+		t.Fatalf("expected /a b at offset 2, got %s at offset %d", ae.Address, ae.Offset)
+	}
+	// A method that fails.
+	if err := c1.Send(Message{Address: "/fail"}); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		me *MethodError
+		de *DispatchError
+	)
+	if err := waitError(t, errs); !errors.As(err, &me) || !errors.As(err, &de) || !errors.Is(err, fail) {
+		t.Fatalf("expected *MethodError caused by *DispatchError, got %v", err)
+	}
+	if de.Address != "/fail" {
+		t.Fatalf("expected /fail, got %s", de.Address)
+	}
+	// A message that no method matches.
+	if err := c1.Send(Message{Address: "/nothing"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitError(t, errs); !errors.As(err, &de) || !errors.Is(err, ErrNoMatchingMethod) {
+		t.Fatalf("expected *DispatchError wrapping ErrNoMatchingMethod, got %v", err)
+	}
+	if de.Address != "/nothing" {
+		t.Fatalf("expected /nothing, got %s", de.Address)
+	}
+}
+
+func TestSetUnmatchedPolicy(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		Policy  UnmatchedPolicy
+		Strict  bool
+		Errors  int
+		Entries []logEntry
+	}{
+		{Policy: ErrorUnmatched, Errors: 1},
+		{Policy: ErrorUnmatched, Strict: true, Errors: 1},
+		{Policy: WarnUnmatched, Entries: []logEntry{{Level: slog.LevelWarn, Msg: "osc: no method matched", Keys: []string{LogAddress, LogRemote}}}},
+		{Policy: DropUnmatched},
+	} {
+		h := &recordingHandler{}
+		server, err := ListenUDP("udp", laddr, WithLogger(slog.New(h)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			errs    = make(chan error, 2)
+			handled = make(chan struct{})
+			serving = make(chan error, 1)
+		)
+		server.SetErrorHandler(func(err error) { errs <- err })
+		server.SetUnmatchedPolicy(testcase.Policy)
+		server.SetStrict(testcase.Strict)
+		go func() {
+			serving <- server.Serve(1, PatternMatching{"/registered": Method(func(msg Message) error {
+				close(handled)
+				return nil
+			})})
+		}()
+		// The message that no method matches does not stop Serve,
+		// so the one after it is still dispatched.
+		for _, addr := range []string{"/unregistered", "/registered"} {
+			if err := client.Send(MustMessage(addr)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		select {
+		case <-handled:
+		case err := <-serving:
+			t.Fatalf("(testcase %d) Serve returned %v", i, err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(testcase %d) timeout waiting for /registered", i)
+		}
+		if n := len(errs); n != testcase.Errors {
+			t.Fatalf("(testcase %d) expected %d errors, got %d", i, testcase.Errors, n)
+		}
+		if testcase.Errors > 0 {
+			var de *DispatchError
+			if err := <-errs; !errors.As(err, &de) || !errors.Is(err, ErrNoMatchingMethod) || de.Address != "/unregistered" {
+				t.Fatalf("(testcase %d) expected *DispatchError for /unregistered wrapping ErrNoMatchingMethod, got %v", i, err)
+			}
+		}
+		entries := h.wait(t, len(testcase.Entries))
+		if len(entries) != len(testcase.Entries) {
+			t.Fatalf("(testcase %d) expected %d entries, got %+v", i, len(testcase.Entries), entries)
+		}
+		for j, expected := range testcase.Entries {
+			if got := entries[j]; got.Level != expected.Level || got.Msg != expected.Msg || strings.Join(got.Keys, ",") != strings.Join(expected.Keys, ",") {
+				t.Fatalf("(testcase %d) entry %d: expected %+v, got %+v", i, j, expected, got)
+			}
+		}
+		if stats := server.Stats(); stats.Unmatched != 1 {
+			t.Fatalf("(testcase %d) expected 1 unmatched message, got %d", i, stats.Unmatched)
+		}
+		_ = client.Close() // Best effort.
+		_ = server.Close() // Best effort.
+		<-serving
+	}
+}
+
+func TestInvokeJoinsErrors(t *testing.T) {
+	var (
+		err1 = errors.New("one")
+		err2 = errors.New("two")
+	)
+	err := PatternMatching{
+		"/a/1": Method(func(msg Message) error { return err1 }),
+		"/a/2": Method(func(msg Message) error { return err2 }),
+	}.Invoke(Message{Address: "/a/*"}, false)
+	if !errors.Is(err, err1) || !errors.Is(err, err2) {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+	if expected, got := "one and two", err.Error(); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
 	}
 }
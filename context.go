@@ -0,0 +1,43 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// packetInfoKey is the context key of the packetInfo of the messages that are served.
+type packetInfoKey struct{}
+
+// packetInfo describes where a packet read by a conn came from.
+type packetInfo struct {
+	sender   net.Addr
+	received time.Time
+}
+
+// packetContext returns the context of the messages of incoming,
+// which is derived from parent.
+func packetContext(parent context.Context, incoming Incoming) context.Context {
+	if parent == nil {
+		parent = context.Background()
+	}
+	return context.WithValue(parent, packetInfoKey{}, packetInfo{
+		sender:   incoming.Sender,
+		received: incoming.Received,
+	})
+}
+
+// SenderFromContext returns the address of the sender of the message
+// whose method was given ctx.
+// It returns false if the message was not read from a conn.
+func SenderFromContext(ctx context.Context) (net.Addr, bool) {
+	info, ok := ctx.Value(packetInfoKey{}).(packetInfo)
+	return info.sender, ok
+}
+
+// ReceivedFromContext returns the time the message whose method was given ctx was read.
+// It returns false if the message was not read from a conn.
+func ReceivedFromContext(ctx context.Context) (time.Time, bool) {
+	info, ok := ctx.Value(packetInfoKey{}).(packetInfo)
+	return info.received, ok
+}
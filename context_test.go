@@ -0,0 +1,130 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMethodCtx(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		before  = time.Now()
+		plain   = make(chan Message, 1)
+		withCtx = make(chan context.Context, 1)
+	)
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			"/plain": Method(func(msg Message) error {
+				plain <- msg
+				return nil
+			}),
+			"/ctx": MethodCtx(func(ctx context.Context, msg Message) error {
+				withCtx <- ctx
+				return nil
+			}),
+		})
+	}()
+	for _, addr := range []string{"/plain", "/ctx"} {
+		if err := c1.Send(Message{Address: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case msg := <-plain:
+		if sender, ok := SenderFromContext(msg.Context()); !ok || sender.String() != c1.LocalAddr().String() {
+			t.Fatalf("expected sender %s, got %v", c1.LocalAddr(), sender)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /plain")
+	}
+	var ctx context.Context
+	select {
+	case ctx = <-withCtx:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /ctx")
+	}
+	if sender, ok := SenderFromContext(ctx); !ok || sender.String() != c1.LocalAddr().String() {
+		t.Fatalf("expected sender %s, got %v", c1.LocalAddr(), sender)
+	}
+	received, ok := ReceivedFromContext(ctx)
+	if !ok || received.Before(before) || received.After(time.Now()) {
+		t.Fatalf("expected a receive time after %s, got %s", before, received)
+	}
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("expected the context to not be done, got %v", err)
+	}
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the context to be done once the conn is closed")
+	}
+	if _, ok := SenderFromContext(context.Background()); ok {
+		t.Fatal("expected no sender")
+	}
+	if _, ok := ReceivedFromContext(Message{}.Context()); ok {
+		t.Fatal("expected no receive time")
+	}
+}
+
+func TestMethodCtxShutdown(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		serveCtx, cancelServe = context.WithCancel(context.Background())
+		started               = make(chan struct{})
+		stopped               = make(chan error, 1)
+		errChan               = make(chan error, 1)
+	)
+	defer cancelServe()
+
+	go func() {
+		errChan <- server.ServeContext(serveCtx, 1, PatternMatching{
+			"/wait": MethodCtx(func(ctx context.Context, msg Message) error {
+				close(started)
+				<-ctx.Done()
+				stopped <- ctx.Err()
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/wait"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /wait")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// The method stops waiting when the server shuts down, so Shutdown does not time out.
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-stopped; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
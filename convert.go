@@ -0,0 +1,262 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Errors of the conversions of ArgumentOptions.
+var (
+	// ErrDurationRange is returned for a duration that does not fit in its encoding,
+	// such as more than about 24 days in int32 milliseconds.
+	ErrDurationRange = errors.New("duration out of range")
+
+	// ErrNumberConversion is returned for a number that can not be converted
+	// to the width of another typetag, such as an int64 that overflows an int32.
+	ErrNumberConversion = errors.New("number can not be converted")
+)
+
+// DurationEncoding is how a time.Duration is encoded as an argument.
+type DurationEncoding int
+
+// The encodings of durations.
+const (
+	// DurationSeconds encodes a duration as a float32 number of seconds,
+	// the way most applications send times, e.g. 0.25 for 250ms.
+	DurationSeconds DurationEncoding = iota
+
+	// DurationMilliseconds encodes a duration as an int32 number of milliseconds.
+	DurationMilliseconds
+)
+
+// ArgumentOptions are the conversions of arguments to and from Go types
+// that the specification does not define. The zero value converts them
+// like NewMessage and the methods of Message do, with none of the coercions.
+type ArgumentOptions struct {
+	// IntBools makes BoolAt accept int32 arguments, which many applications send
+	// for toggles, 0 being false and any other value true.
+	// Otherwise only 'T' and 'F' are booleans.
+	IntBools bool
+
+	// Durations is the encoding of the durations that are read and written.
+	Durations DurationEncoding
+
+	// Floats is the typetag that the float32 and float64 values are encoded with,
+	// TypetagFloat or TypetagDouble, for receivers that only understand one of them.
+	// Ints is the typetag of the int32, int and int64 values, TypetagInt or TypetagInt64.
+	// Zero encodes every value with the typetag of its type.
+	Floats byte
+	Ints   byte
+
+	// StrictNumbers makes the conversions to a narrower typetag fail with
+	// ErrNumberConversion when they change the value, such as a float64 that
+	// overflows a float32 or loses precision. Otherwise floats are rounded.
+	// Ints that overflow always fail, as they would otherwise wrap around.
+	StrictNumbers bool
+
+	// CoerceNumbers makes Int32At, Int64At, Float32At and Float64At read the number
+	// arguments of the other width too, converting them like the encoding does.
+	// Otherwise they only read arguments of their own typetag.
+	CoerceNumbers bool
+}
+
+// NewMessage creates a message with the given arguments like NewMessage does,
+// encoding the numbers with the typetags of o.Floats and o.Ints.
+// Values that already implement Argument, such as Float, are used as they are.
+func (o ArgumentOptions) NewMessage(addr string, args ...interface{}) (Message, error) {
+	msg := Message{Address: addr, Arguments: make([]Argument, len(args))}
+	for i, arg := range args {
+		a, err := o.toArgument(arg)
+		if err != nil {
+			return Message{}, fmt.Errorf("argument %d: %w", i, err)
+		}
+		msg.Arguments[i] = a
+	}
+	return msg, nil
+}
+
+// toArgument converts a Go value to an argument, with the typetags of o.Floats and o.Ints.
+func (o ArgumentOptions) toArgument(v interface{}) (Argument, error) {
+	switch x := v.(type) {
+	case int:
+		if o.Ints == TypetagInt64 {
+			return Int64(x), nil
+		}
+		return o.intArgument(int64(x), TypetagInt)
+	case int32:
+		return o.intArgument(int64(x), TypetagInt)
+	case int64:
+		return o.intArgument(x, TypetagInt64)
+	case float32:
+		return o.floatArgument(float64(x), TypetagFloat)
+	case float64:
+		return o.floatArgument(x, TypetagDouble)
+	default:
+		return toArgument(v)
+	}
+}
+
+// intArgument returns the argument of an int whose own typetag is tt.
+func (o ArgumentOptions) intArgument(i int64, tt byte) (Argument, error) {
+	tt, err := o.intTypetag(i, tt)
+	if err != nil {
+		return nil, err
+	}
+	if tt == TypetagInt64 {
+		return Int64(i), nil
+	}
+	return Int(i), nil
+}
+
+// floatArgument returns the argument of a float whose own typetag is tt.
+func (o ArgumentOptions) floatArgument(f float64, tt byte) (Argument, error) {
+	tt, err := o.floatTypetag(f, tt)
+	if err != nil {
+		return nil, err
+	}
+	if tt == TypetagDouble {
+		return Double(f), nil
+	}
+	return Float(f), nil
+}
+
+// intTypetag returns the typetag that an int whose own typetag is tt is encoded with.
+func (o ArgumentOptions) intTypetag(i int64, tt byte) (byte, error) {
+	switch o.Ints {
+	case 0:
+	case TypetagInt:
+		tt = TypetagInt
+	case TypetagInt64:
+		tt = TypetagInt64
+	default:
+		return 0, fmt.Errorf("ints typetag %q: %w", o.Ints, ErrInvalidTypeTag)
+	}
+	if tt == TypetagInt && (i < math.MinInt32 || i > math.MaxInt32) {
+		return 0, fmt.Errorf("int %d overflows int32: %w", i, ErrNumberConversion)
+	}
+	return tt, nil
+}
+
+// floatTypetag returns the typetag that a float whose own typetag is tt is encoded with.
+func (o ArgumentOptions) floatTypetag(f float64, tt byte) (byte, error) {
+	switch o.Floats {
+	case 0:
+	case TypetagFloat:
+		tt = TypetagFloat
+	case TypetagDouble:
+		tt = TypetagDouble
+	default:
+		return 0, fmt.Errorf("floats typetag %q: %w", o.Floats, ErrInvalidTypeTag)
+	}
+	if tt == TypetagFloat && o.StrictNumbers && float64(float32(f)) != f && !math.IsNaN(f) {
+		return 0, fmt.Errorf("float %g does not fit in a float32: %w", f, ErrNumberConversion)
+	}
+	return tt, nil
+}
+
+// Int32At returns the int32 argument at index i, like Message.Int32At,
+// or the int64 argument at index i if CoerceNumbers is set and it fits.
+func (o ArgumentOptions) Int32At(msg Message, i int) (int32, error) {
+	if o.CoerceNumbers {
+		if n, err := msg.Int64At(i); err == nil {
+			if n < math.MinInt32 || n > math.MaxInt32 {
+				return 0, fmt.Errorf("argument %d: int %d overflows int32: %w", i, n, ErrNumberConversion)
+			}
+			return int32(n), nil
+		}
+	}
+	return msg.Int32At(i)
+}
+
+// Int64At returns the int64 argument at index i, like Message.Int64At,
+// or the int32 argument at index i if CoerceNumbers is set.
+func (o ArgumentOptions) Int64At(msg Message, i int) (int64, error) {
+	if o.CoerceNumbers {
+		if n, err := msg.Int32At(i); err == nil {
+			return int64(n), nil
+		}
+	}
+	return msg.Int64At(i)
+}
+
+// Float32At returns the float32 argument at index i, like Message.Float32At,
+// or the float64 argument at index i if CoerceNumbers is set, which is rounded
+// unless StrictNumbers is set too.
+func (o ArgumentOptions) Float32At(msg Message, i int) (float32, error) {
+	if o.CoerceNumbers {
+		if f, err := msg.Float64At(i); err == nil {
+			if o.StrictNumbers && float64(float32(f)) != f && !math.IsNaN(f) {
+				return 0, fmt.Errorf("argument %d: float %g does not fit in a float32: %w", i, f, ErrNumberConversion)
+			}
+			return float32(f), nil
+		}
+	}
+	return msg.Float32At(i)
+}
+
+// Float64At returns the float64 argument at index i, like Message.Float64At,
+// or the float32 argument at index i if CoerceNumbers is set.
+func (o ArgumentOptions) Float64At(msg Message, i int) (float64, error) {
+	if o.CoerceNumbers {
+		if f, err := msg.Float32At(i); err == nil {
+			return float64(f), nil
+		}
+	}
+	return msg.Float64At(i)
+}
+
+// BoolAt returns the boolean argument at index i, like Message.BoolAt,
+// or the int32 argument at index i converted to a boolean if IntBools is set.
+func (o ArgumentOptions) BoolAt(msg Message, i int) (bool, error) {
+	if o.IntBools {
+		if n, err := msg.Int32At(i); err == nil {
+			return n != 0, nil
+		}
+	}
+	return msg.BoolAt(i)
+}
+
+// DurationAt returns the duration argument at index i, which must have the typetag
+// of the encoding, 'f' for DurationSeconds and 'i' for DurationMilliseconds.
+func (o ArgumentOptions) DurationAt(msg Message, i int) (time.Duration, error) {
+	switch o.Durations {
+	case DurationMilliseconds:
+		ms, err := msg.Int32At(i)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	case DurationSeconds:
+		s, err := msg.Float32At(i)
+		if err != nil {
+			return 0, err
+		}
+		d := float64(s) * float64(time.Second)
+		if math.IsNaN(d) || d >= math.MaxInt64 || d < math.MinInt64 {
+			return 0, fmt.Errorf("argument %d: %f seconds: %w", i, s, ErrDurationRange)
+		}
+		return time.Duration(d), nil
+	default:
+		return 0, fmt.Errorf("unknown duration encoding %d", o.Durations)
+	}
+}
+
+// WriteDuration appends a duration argument to the message in the encoding of the options.
+// Seconds are rounded to the precision of a float32, and milliseconds are truncated.
+func (o ArgumentOptions) WriteDuration(msg *Message, d time.Duration) error {
+	switch o.Durations {
+	case DurationMilliseconds:
+		ms := d.Milliseconds()
+		if ms < math.MinInt32 || ms > math.MaxInt32 {
+			return fmt.Errorf("%s: %w", d, ErrDurationRange)
+		}
+		msg.WriteInt32(int32(ms))
+	case DurationSeconds:
+		msg.WriteFloat32(float32(d.Seconds()))
+	default:
+		return fmt.Errorf("unknown duration encoding %d", o.Durations)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package osc
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestArgumentOptions_BoolAt(t *testing.T) {
+	for i, testcase := range []struct {
+		Arg      interface{}
+		IntBools bool
+		Expected bool
+		Err      error
+	}{
+		{Arg: true, Expected: true},
+		{Arg: false, Expected: false},
+		{Arg: true, IntBools: true, Expected: true},
+		{Arg: false, IntBools: true, Expected: false},
+		{Arg: int32(0), Err: ErrInvalidTypeTag},
+		{Arg: int32(1), Err: ErrInvalidTypeTag},
+		{Arg: int32(0), IntBools: true, Expected: false},
+		{Arg: int32(1), IntBools: true, Expected: true},
+		{Arg: int32(-7), IntBools: true, Expected: true},
+		{Arg: int64(1), IntBools: true, Err: ErrInvalidTypeTag},
+		{Arg: float32(1), IntBools: true, Err: ErrInvalidTypeTag},
+		{Arg: "true", IntBools: true, Err: ErrInvalidTypeTag},
+	} {
+		msg := MustMessage("/toggle", testcase.Arg)
+		b, err := ArgumentOptions{IntBools: testcase.IntBools}.BoolAt(msg, 0)
+		if testcase.Err != nil {
+			if !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if b != testcase.Expected {
+			t.Fatalf("(testcase %d) expected %t, got %t", i, testcase.Expected, b)
+		}
+	}
+	if _, err := (ArgumentOptions{IntBools: true}).BoolAt(Message{Address: "/toggle"}, 0); !errors.Is(err, ErrArgumentIndex) {
+		t.Fatalf("expected ErrArgumentIndex, got %v", err)
+	}
+}
+
+func TestArgumentOptions_Duration(t *testing.T) {
+	for i, testcase := range []struct {
+		Encoding DurationEncoding
+		Duration time.Duration
+		Arg      Argument
+		Expected time.Duration // The duration that is read back.
+	}{
+		{Encoding: DurationSeconds, Duration: 250 * time.Millisecond, Arg: Float(0.25), Expected: 250 * time.Millisecond},
+		{Encoding: DurationSeconds, Duration: -2 * time.Second, Arg: Float(-2), Expected: -2 * time.Second},
+		{Encoding: DurationSeconds, Duration: 0, Arg: Float(0), Expected: 0},
+		{Encoding: DurationMilliseconds, Duration: 1500 * time.Millisecond, Arg: Int(1500), Expected: 1500 * time.Millisecond},
+		{Encoding: DurationMilliseconds, Duration: 1500*time.Millisecond + 999*time.Microsecond, Arg: Int(1500), Expected: 1500 * time.Millisecond},
+		{Encoding: DurationMilliseconds, Duration: -time.Minute, Arg: Int(-60000), Expected: -time.Minute},
+	} {
+		o := ArgumentOptions{Durations: testcase.Encoding}
+		msg := Message{Address: "/fade"}
+		if err := o.WriteDuration(&msg, testcase.Duration); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if len(msg.Arguments) != 1 || !msg.Arguments[0].Equal(testcase.Arg) {
+			t.Fatalf("(testcase %d) expected %s, got %v", i, testcase.Arg, msg.Arguments)
+		}
+		view, err := parseMessageView(msg.Bytes(), nil)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		for _, m := range []Message{msg, view} {
+			d, err := o.DurationAt(m, 0)
+			if err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+			if d != testcase.Expected {
+				t.Fatalf("(testcase %d) expected %s, got %s", i, testcase.Expected, d)
+			}
+		}
+
+		// The other encoding does not read the argument.
+		other := ArgumentOptions{Durations: DurationSeconds}
+		if testcase.Encoding == DurationSeconds {
+			other.Durations = DurationMilliseconds
+		}
+		if _, err := other.DurationAt(msg, 0); !errors.Is(err, ErrInvalidTypeTag) {
+			t.Fatalf("(testcase %d) expected ErrInvalidTypeTag, got %v", i, err)
+		}
+	}
+
+	// Durations that do not fit.
+	msg := Message{Address: "/fade"}
+	if err := (ArgumentOptions{Durations: DurationMilliseconds}).WriteDuration(&msg, 25*24*time.Hour); !errors.Is(err, ErrDurationRange) || len(msg.Arguments) != 0 {
+		t.Fatalf("expected ErrDurationRange, got %v", err)
+	}
+	for i, f := range []float32{float32(math.NaN()), math.MaxFloat32} {
+		if _, err := (ArgumentOptions{}).DurationAt(MustMessage("/fade", f), 0); !errors.Is(err, ErrDurationRange) {
+			t.Fatalf("(testcase %d) expected ErrDurationRange, got %v", i, err)
+		}
+	}
+}
+
+func TestArgumentOptions_Numbers(t *testing.T) {
+	for i, testcase := range []struct {
+		Options  ArgumentOptions
+		Value    interface{}
+		Expected Argument // Nil if the value can not be encoded.
+	}{
+		{Value: float64(0.5), Expected: Double(0.5)},
+		{Value: float32(0.5), Expected: Float(0.5)},
+		{Options: ArgumentOptions{Floats: TypetagFloat}, Value: float64(0.5), Expected: Float(0.5)},
+		{Options: ArgumentOptions{Floats: TypetagFloat}, Value: float32(0.5), Expected: Float(0.5)},
+		{Options: ArgumentOptions{Floats: TypetagFloat}, Value: float64(0.1), Expected: Float(0.1)},
+		{Options: ArgumentOptions{Floats: TypetagFloat, StrictNumbers: true}, Value: float64(0.1)},
+		{Options: ArgumentOptions{Floats: TypetagFloat, StrictNumbers: true}, Value: float64(0.5), Expected: Float(0.5)},
+		{Options: ArgumentOptions{Floats: TypetagFloat, StrictNumbers: true}, Value: float64(math.MaxFloat32), Expected: Float(math.MaxFloat32)},
+		{Options: ArgumentOptions{Floats: TypetagFloat, StrictNumbers: true}, Value: float64(math.MaxFloat32) * 2},
+		{Options: ArgumentOptions{Floats: TypetagFloat}, Value: float64(math.MaxFloat32) * 2, Expected: Float(math.Inf(1))},
+		{Options: ArgumentOptions{Floats: TypetagFloat, StrictNumbers: true}, Value: math.Inf(-1), Expected: Float(math.Inf(-1))},
+		{Options: ArgumentOptions{Floats: TypetagDouble}, Value: float32(0.5), Expected: Double(0.5)},
+		{Options: ArgumentOptions{Floats: TypetagDouble}, Value: float64(math.MaxFloat64), Expected: Double(math.MaxFloat64)},
+		{Options: ArgumentOptions{Floats: TypetagInt}, Value: float64(1)},
+		{Value: 7, Expected: Int(7)},
+		{Value: int64(7), Expected: Int64(7)},
+		{Value: math.MaxInt32 + 1},
+		{Options: ArgumentOptions{Ints: TypetagInt64}, Value: math.MaxInt32 + 1, Expected: Int64(math.MaxInt32 + 1)},
+		{Options: ArgumentOptions{Ints: TypetagInt64}, Value: int32(7), Expected: Int64(7)},
+		{Options: ArgumentOptions{Ints: TypetagInt}, Value: int64(math.MaxInt32), Expected: Int(math.MaxInt32)},
+		{Options: ArgumentOptions{Ints: TypetagInt}, Value: int64(math.MinInt32), Expected: Int(math.MinInt32)},
+		{Options: ArgumentOptions{Ints: TypetagInt}, Value: int64(math.MaxInt32 + 1)},
+		{Options: ArgumentOptions{Ints: TypetagInt}, Value: int64(math.MinInt32 - 1)},
+		{Options: ArgumentOptions{Ints: TypetagFloat}, Value: int32(1)},
+		{Options: ArgumentOptions{Floats: TypetagFloat, Ints: TypetagInt}, Value: Double(0.5), Expected: Double(0.5)},
+		{Options: ArgumentOptions{Floats: TypetagFloat, Ints: TypetagInt}, Value: "s", Expected: String("s")},
+	} {
+		msg, err := testcase.Options.NewMessage("/number", testcase.Value)
+		if testcase.Expected == nil {
+			if err == nil {
+				t.Fatalf("(testcase %d) expected an error, got %s", i, msg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !msg.Arguments[0].Equal(testcase.Expected) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, testcase.Expected, msg.Arguments[0])
+		}
+
+		// The builder encodes the numbers the same way.
+		b := NewBuilder("/number").SetOptions(testcase.Options)
+		switch x := testcase.Value.(type) {
+		case int32:
+			b.Int32(x)
+		case int64:
+			b.Int64(x)
+		case float32:
+			b.Float32(x)
+		case float64:
+			b.Float64(x)
+		default:
+			continue
+		}
+		built, err := b.Message()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !built.Equal(msg) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, msg, built)
+		}
+	}
+}
+
+func TestArgumentOptions_CoerceNumbers(t *testing.T) {
+	msg := MustMessage("/number", float32(0.5), float64(0.1), float64(math.MaxFloat64), int32(-7), int64(math.MaxInt32), int64(math.MaxInt32+1))
+	for i, testcase := range []struct {
+		Options  ArgumentOptions
+		Read     func(o ArgumentOptions, msg Message, i int) (float64, error)
+		Index    int
+		Expected float64
+		Err      error
+	}{
+		{Read: float32At, Index: 0, Expected: 0.5},
+		{Read: float32At, Index: 1, Err: ErrInvalidTypeTag},
+		{Read: float64At, Index: 0, Err: ErrInvalidTypeTag},
+		{Read: float64At, Index: 1, Expected: 0.1},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: float32At, Index: 1, Expected: float64(float32(0.1))},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: float32At, Index: 2, Expected: math.Inf(1)},
+		{Options: ArgumentOptions{CoerceNumbers: true, StrictNumbers: true}, Read: float32At, Index: 1, Err: ErrNumberConversion},
+		{Options: ArgumentOptions{CoerceNumbers: true, StrictNumbers: true}, Read: float32At, Index: 2, Err: ErrNumberConversion},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: float64At, Index: 0, Expected: 0.5},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: float32At, Index: 3, Err: ErrInvalidTypeTag},
+		{Read: int32At, Index: 3, Expected: -7},
+		{Read: int32At, Index: 4, Err: ErrInvalidTypeTag},
+		{Read: int64At, Index: 3, Err: ErrInvalidTypeTag},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: int32At, Index: 4, Expected: math.MaxInt32},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: int32At, Index: 5, Err: ErrNumberConversion},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: int64At, Index: 3, Expected: -7},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: int64At, Index: 5, Expected: math.MaxInt32 + 1},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: int64At, Index: 0, Err: ErrInvalidTypeTag},
+		{Options: ArgumentOptions{CoerceNumbers: true}, Read: int64At, Index: 6, Err: ErrArgumentIndex},
+	} {
+		v, err := testcase.Read(testcase.Options, msg, testcase.Index)
+		if testcase.Err != nil {
+			if !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if v != testcase.Expected {
+			t.Fatalf("(testcase %d) expected %g, got %g", i, testcase.Expected, v)
+		}
+	}
+}
+
+func float32At(o ArgumentOptions, msg Message, i int) (float64, error) {
+	f, err := o.Float32At(msg, i)
+	return float64(f), err
+}
+
+func float64At(o ArgumentOptions, msg Message, i int) (float64, error) {
+	return o.Float64At(msg, i)
+}
+
+func int32At(o ArgumentOptions, msg Message, i int) (float64, error) {
+	n, err := o.Int32At(msg, i)
+	return float64(n), err
+}
+
+func int64At(o ArgumentOptions, msg Message, i int) (float64, error) {
+	n, err := o.Int64At(msg, i)
+	return float64(n), err
+}
@@ -3,16 +3,11 @@ package osc
 import (
 	"fmt"
 	"log"
-	"net"
 	"time"
 )
 
 func Example_customDispatcher() {
-	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
-	if err != nil {
-		log.Fatal(err)
-	}
-	server, err := ListenUDP("udp", laddr)
+	server, err := Listen("127.0.0.1:0")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -28,11 +23,7 @@ func Example_customDispatcher() {
 	}()
 
 	// Send a message from the client.
-	raddr, err := net.ResolveUDPAddr("udp", server.LocalAddr().String())
-	if err != nil {
-		log.Fatal(err)
-	}
-	client, err := DialUDP("udp", nil, raddr)
+	client, err := Dial(server.LocalAddr().String())
 	if err != nil {
 		log.Fatal(err)
 	}
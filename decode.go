@@ -0,0 +1,254 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Common errors.
+var (
+	ErrArgumentCount = errors.New("wrong number of arguments")
+	ErrNotStruct     = errors.New("expected a pointer to a struct")
+)
+
+// structField is a struct field that holds an argument.
+type structField struct {
+	name  string
+	index int // index of the argument
+	field int // index of the field in the struct
+}
+
+// structFields returns the fields of t that hold arguments, ordered by argument index.
+// Exported fields hold the arguments in the order they are declared,
+// unless they have an `osc:"index"` tag. Fields tagged `osc:"-"` are skipped.
+func structFields(t reflect.Type) ([]structField, error) {
+	var (
+		fields = []structField{}
+		seen   = map[int]string{}
+		next   = 0
+	)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // Unexported.
+		}
+		index := next
+		if tag, ok := f.Tag.Lookup("osc"); ok {
+			if tag == "-" {
+				continue
+			}
+			n, err := strconv.Atoi(tag)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("field %s: invalid osc tag %q", f.Name, tag)
+			}
+			index = n
+		}
+		if other, ok := seen[index]; ok {
+			return nil, fmt.Errorf("fields %s and %s both hold argument %d", other, f.Name, index)
+		}
+		seen[index] = f.Name
+		fields = append(fields, structField{name: f.Name, index: index, field: i})
+		next = index + 1
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].index < fields[j].index })
+	return fields, nil
+}
+
+// Decode stores the arguments of the message in the struct pointed to by v.
+// Exported fields are filled in the order they are declared, or by the
+// argument index in their `osc:"index"` tag.
+// Fields can be of type int32, int64, float32, float64, string, []byte or bool,
+// of a type whose pointer implements Unmarshaler, or a pointer to one of those.
+// Pointer fields are left nil if the message does not have their argument,
+// which makes them useful for optional trailing arguments.
+// An error naming the field is returned if an argument has the wrong typetag.
+func (msg Message) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("got %T: %w", v, ErrNotStruct)
+	}
+	rv = rv.Elem()
+
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	args := msg.arguments()
+	if len(fields) > 0 && len(args) > fields[len(fields)-1].index+1 {
+		return fmt.Errorf("%s has %d arguments, %s holds %d: %w", msg.Address, len(args), rv.Type(), fields[len(fields)-1].index+1, ErrArgumentCount)
+	}
+	for _, f := range fields {
+		fv := rv.Field(f.field)
+		if f.index >= len(args) {
+			if fv.Kind() == reflect.Ptr {
+				continue
+			}
+			return fmt.Errorf("field %s: %s has no argument %d: %w", f.name, msg.Address, f.index, ErrArgumentCount)
+		}
+		if fv.Kind() == reflect.Ptr {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+		if err := decodeArgument(fv, args[f.index]); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// decodeArgument stores arg in fv.
+func decodeArgument(fv reflect.Value, arg Argument) error {
+	if u, ok := unmarshaler(fv); ok {
+		return toUnmarshaler(u, arg)
+	}
+	expected, err := fieldTypetag(fv.Type())
+	if err != nil {
+		return err
+	}
+	if actual := arg.Typetag(); actual != expected && !(expected == TypetagTrue && actual == TypetagFalse) {
+		return fmt.Errorf("expected typetag %q, got %q: %w", expected, actual, ErrInvalidTypeTag)
+	}
+	switch fv.Kind() {
+	case reflect.Int32:
+		i, err := arg.ReadInt32()
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(i))
+	case reflect.Int64:
+		i, err := arg.ReadInt64()
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Float32:
+		f, err := arg.ReadFloat32()
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(float64(f))
+	case reflect.Float64:
+		f, err := arg.ReadFloat64()
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.String:
+		s, err := arg.ReadString()
+		if err != nil {
+			return err
+		}
+		fv.SetString(s)
+	case reflect.Slice:
+		b, err := arg.ReadBlob()
+		if err != nil {
+			return err
+		}
+		fv.SetBytes(b)
+	case reflect.Bool:
+		b, err := arg.ReadBool()
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	}
+	return nil
+}
+
+// fieldTypetag returns the typetag of the arguments a field of type t holds.
+// Bool fields hold both 'T' and 'F', which is reported as 'T'.
+func fieldTypetag(t reflect.Type) (byte, error) {
+	switch t.Kind() {
+	case reflect.Int32:
+		return TypetagInt, nil
+	case reflect.Int64:
+		return TypetagInt64, nil
+	case reflect.Float32:
+		return TypetagFloat, nil
+	case reflect.Float64:
+		return TypetagDouble, nil
+	case reflect.String:
+		return TypetagString, nil
+	case reflect.Bool:
+		return TypetagTrue, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TypetagBlob, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %w", t, ErrUnsupportedType)
+}
+
+// Encode creates a message whose arguments are the fields of the struct v,
+// or of the struct v points to.
+// The fields are mapped to arguments as described for Decode,
+// and fields of a type that implements Marshaler are encoded by their MarshalOSC method.
+// Nil pointer fields are left out, so they must come after all of the other fields.
+func Encode(addr string, v interface{}) (Message, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return Message{}, fmt.Errorf("got %T: %w", v, ErrNotStruct)
+	}
+	fields, err := structFields(rv.Type())
+	if err != nil {
+		return Message{}, err
+	}
+	msg := Message{Address: addr, Arguments: []Argument{}}
+
+	for _, f := range fields {
+		fv := rv.Field(f.field)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if f.index != len(msg.Arguments) {
+			return Message{}, fmt.Errorf("field %s: no field holds argument %d", f.name, len(msg.Arguments))
+		}
+		arg, err := encodeArgument(fv)
+		if err != nil {
+			return Message{}, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		msg.Arguments = append(msg.Arguments, arg)
+	}
+	// Nil pointers may only be followed by other nil pointers.
+	for _, f := range fields[len(msg.Arguments):] {
+		if fv := rv.Field(f.field); fv.Kind() != reflect.Ptr || !fv.IsNil() {
+			return Message{}, fmt.Errorf("field %s: follows a nil field", f.name)
+		}
+	}
+	return msg, nil
+}
+
+// encodeArgument converts a field to an argument.
+func encodeArgument(fv reflect.Value) (Argument, error) {
+	if m, ok := marshaler(fv); ok {
+		return fromMarshaler(m)
+	}
+	if _, err := fieldTypetag(fv.Type()); err != nil {
+		return nil, err
+	}
+	switch fv.Kind() {
+	case reflect.Int32:
+		return Int(fv.Int()), nil
+	case reflect.Int64:
+		return Int64(fv.Int()), nil
+	case reflect.Float32:
+		return Float(fv.Float()), nil
+	case reflect.Float64:
+		return Double(fv.Float()), nil
+	case reflect.String:
+		return String(fv.String()), nil
+	case reflect.Bool:
+		return Bool(fv.Bool()), nil
+	default:
+		return Blob(fv.Bytes()), nil
+	}
+}
@@ -0,0 +1,178 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type testSynth struct {
+	Node    int32
+	Name    string
+	Freq    float32
+	Gate    bool
+	unused  int // Unexported fields are skipped.
+	Samples int64
+	Gain    float64
+	Data    []byte
+}
+
+type testOptional struct {
+	Name  string
+	Level *float32
+	Pan   *float32
+}
+
+type testTagged struct {
+	Amp  float32 `osc:"2"`
+	Node int32   `osc:"0"`
+	Skip string  `osc:"-"`
+	Name string  `osc:"1"`
+}
+
+func TestMessageDecode(t *testing.T) {
+	msg := MustMessage("/synth", int32(1000), "sine", float32(440), false, int64(1<<40), 0.5, []byte("data"))
+
+	var got testSynth
+	if err := msg.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	expected := testSynth{Node: 1000, Name: "sine", Freq: 440, Samples: 1 << 40, Gain: 0.5, Data: []byte("data")}
+	if got.Node != expected.Node || got.Name != expected.Name || got.Freq != expected.Freq || got.Gate != expected.Gate ||
+		got.Samples != expected.Samples || got.Gain != expected.Gain || !bytes.Equal(got.Data, expected.Data) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	// Encoding gives back the same message.
+	encoded, err := Encode("/synth", got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Equal(encoded) {
+		t.Fatalf("expected %s, got %s", msg, encoded)
+	}
+}
+
+func TestMessageDecodeTagged(t *testing.T) {
+	msg := MustMessage("/tagged", int32(7), "saw", float32(0.25))
+
+	var got testTagged
+	if err := msg.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (testTagged{Amp: 0.25, Node: 7, Name: "saw"}); expected != got {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+	got.Skip = "not sent"
+	encoded, err := Encode("/tagged", &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Equal(encoded) {
+		t.Fatalf("expected %s, got %s", msg, encoded)
+	}
+}
+
+func TestMessageDecodeOptional(t *testing.T) {
+	var got testOptional
+	if err := MustMessage("/opt", "a", float32(0.5)).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Level == nil || *got.Level != 0.5 {
+		t.Fatalf("expected level 0.5, got %v", got.Level)
+	}
+	if got.Pan != nil {
+		t.Fatalf("expected nil pan, got %v", *got.Pan)
+	}
+	encoded, err := Encode("/opt", got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := MustMessage("/opt", "a", float32(0.5)); !expected.Equal(encoded) {
+		t.Fatalf("expected %s, got %s", expected, encoded)
+	}
+
+	// A nil field can not be followed by one that is set.
+	pan := float32(1)
+	if _, err := Encode("/opt", testOptional{Name: "a", Pan: &pan}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMessageDecodeErrors(t *testing.T) {
+	for i, testcase := range []struct {
+		Message  Message
+		Target   interface{}
+		Cause    error
+		Expected string
+	}{
+		{
+			Message:  MustMessage("/synth", "1000"),
+			Target:   &testSynth{},
+			Cause:    ErrInvalidTypeTag,
+			Expected: `field Node: expected typetag 'i', got 's': invalid type tag`,
+		},
+		{
+			Message:  MustMessage("/synth", int32(1000), "sine", 440.0),
+			Target:   &testSynth{},
+			Cause:    ErrInvalidTypeTag,
+			Expected: `field Freq: expected typetag 'f', got 'd': invalid type tag`,
+		},
+		{
+			Message:  MustMessage("/synth", int32(1000), "sine"),
+			Target:   &testSynth{},
+			Cause:    ErrArgumentCount,
+			Expected: "field Freq: /synth has no argument 2: wrong number of arguments",
+		},
+		{
+			Message:  MustMessage("/opt", "a", float32(1), float32(2), float32(3)),
+			Target:   &testOptional{},
+			Cause:    ErrArgumentCount,
+			Expected: "/opt has 4 arguments, osc.testOptional holds 3: wrong number of arguments",
+		},
+		{
+			Message:  MustMessage("/opt", "a"),
+			Target:   testOptional{},
+			Cause:    ErrNotStruct,
+			Expected: "got osc.testOptional: expected a pointer to a struct",
+		},
+		{
+			Message:  MustMessage("/n", 1),
+			Target:   &struct{ N int }{},
+			Cause:    ErrUnsupportedType,
+			Expected: "field N: int: unsupported argument type",
+		},
+		{
+			Message: MustMessage("/n", 1, 2),
+			Target: &struct {
+				A, B int32 `osc:"0"`
+			}{},
+			Expected: "fields A and B both hold argument 0",
+		},
+	} {
+		err := testcase.Message.Decode(testcase.Target)
+		if err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+		if testcase.Cause != nil && !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if _, err := Encode("/foo", 1); !errors.Is(err, ErrNotStruct) {
+		t.Fatalf("expected ErrNotStruct, got %v", err)
+	}
+	if _, err := Encode("/foo", struct{ N uint }{}); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+	if _, err := Encode("/foo", struct {
+		A int32 `osc:"1"`
+	}{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -1,15 +1,37 @@
 package osc
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 // Common errors.
 var (
-	ErrInvalidAddress = errors.New("invalid OSC address")
+	ErrInvalidAddress   = errors.New("invalid OSC address")
+	ErrNoMatchingMethod = errors.New("no method matches the address")
+)
+
+// UnmatchedPolicy decides what a conn does with a message that no method
+// of a PatternMatching dispatcher or a Router matched, when it has no Default method.
+// The message is counted by Unmatched and Stats with every policy,
+// and it never stops Serve, not even in strict mode.
+type UnmatchedPolicy int
+
+// Unmatched message policies.
+const (
+	// ErrorUnmatched passes a *DispatchError wrapping ErrNoMatchingMethod
+	// to the error handler. It is the default.
+	ErrorUnmatched UnmatchedPolicy = iota
+
+	// WarnUnmatched logs a warning with the logger of WithLogger, if there is one.
+	WarnUnmatched
+
+	// DropUnmatched only counts the message.
+	DropUnmatched
 )
 
 // Method is an OSC method
@@ -20,6 +42,18 @@ func (method Method) Handle(m Message) error {
 	return method(m)
 }
 
+// MethodCtx is an OSC method that gets the context of the message, which is done
+// when the conn stops serving, e.g. because it is shut down.
+// The sender of the message and the time it was received can be read from the context
+// with SenderFromContext and ReceivedFromContext.
+// A dispatcher can mix MethodCtx and Method.
+type MethodCtx func(ctx context.Context, msg Message) error
+
+// Handle handles an OSC message.
+func (method MethodCtx) Handle(m Message) error {
+	return method(m.Context(), m)
+}
+
 // MessageHandler is any type that can handle an OSC message.
 type MessageHandler interface {
 	Handle(Message) error
@@ -31,60 +65,187 @@ type Dispatcher interface {
 	Invoke(msg Message, exactMatch bool) error
 }
 
+// Default is the address of the method of a PatternMatching dispatcher that is invoked
+// with every message no other method matches. The messages keep their own address,
+// so the method can log or forward them.
+// Without a Default method such messages are dropped and counted by the conn's Unmatched.
+const Default = "*"
+
 // PatternMatching is a dispatcher that implements OSC 1.0 pattern matching.
 // See http://opensoundcontrol.org/spec-1_0 "OSC Message Dispatching and Pattern Matching"
+// The keys are the addresses of the methods, which may be patterns themselves.
+// Serve rejects keys that are not valid address patterns.
 type PatternMatching map[string]MessageHandler
 
 // Dispatch invokes an OSC bundle's messages.
 func (h PatternMatching) Dispatch(b Bundle, exactMatch bool) error {
-	var (
-		now = time.Now()
-		tt  = b.Timetag.Time()
-	)
-	if tt.Before(now) {
-		return h.immediately(b, exactMatch)
-	}
-	<-time.After(tt.Sub(now))
+	waitTimetag(b.Timetag)
 	return h.immediately(b, exactMatch)
 }
 
 // immediately invokes an OSC bundle immediately.
 func (h PatternMatching) immediately(b Bundle, exactMatch bool) error {
-	for _, p := range b.Packets {
-		errs := []string{}
-		if err := h.invoke(p, exactMatch); err != nil {
-			errs = append(errs, err.Error())
+	return invokeBundle(h, b, exactMatch)
+}
+
+// invoke invokes an OSC packet, which could be a message or a bundle of messages.
+func (h PatternMatching) invoke(p Packet, exactMatch bool) error {
+	return invokePacket(h, p, exactMatch)
+}
+
+// Invoke invokes every method whose address matches the address pattern of msg,
+// in the order of their addresses.
+//
+// The methods can be registered with patterns too, such as "/fader/*", which match
+// the addresses of the messages that are received. Methods registered with a literal
+// address take precedence, so the pattern methods are only invoked if none of them match.
+// A pattern method also matches a message with the same address pattern,
+// or one whose address pattern matches it literally, like "/fader/*" does.
+//
+// If exactMatch is true only the method registered with the same address as msg is invoked.
+// If no method matches msg the Default method is invoked, if there is one.
+// The errors of all of the methods that fail are returned together.
+func (h PatternMatching) Invoke(msg Message, exactMatch bool) error {
+	// A literal address matches its own method and nothing else,
+	// so it needs neither a pattern nor a slice of matches.
+	if method, ok := h[msg.Address]; ok && msg.Address != Default && !strings.ContainsAny(msg.Address, "?*[]{}") {
+		if err := handleMessage(method, msg, msg.Address); err != nil {
+			return &DispatchError{Address: msg.ownedAddress(), Err: err}
+		}
+		return nil
+	}
+	matched, err := h.matching(msg, exactMatch)
+	if err != nil {
+		return err
+	}
+	if len(matched) == 0 {
+		return h.unmatched(msg)
+	}
+	var errs []error
+	for _, address := range matched {
+		if err := handleMessage(h[address], msg, address); err != nil {
+			errs = append(errs, &DispatchError{Address: address, Err: err})
+		}
+	}
+	return joinErrors(errs)
+}
+
+// matching returns the sorted addresses of the methods that Invoke would invoke with msg,
+// not counting the Default method.
+func (h PatternMatching) matching(msg Message, exactMatch bool) ([]string, error) {
+	if exactMatch {
+		if _, ok := h[msg.Address]; ok && msg.Address != Default {
+			return []string{msg.Address}, nil
+		}
+		return nil, nil
+	}
+	p, err := CompilePattern(msg.Address)
+	if err != nil {
+		return nil, err
+	}
+	var matched, patterns []string
+	for address := range h {
+		if address == Default {
+			continue
+		}
+		if isPattern(address) {
+			patterns = append(patterns, address)
+		} else if p.Match(address) {
+			matched = append(matched, address)
+		}
+	}
+	if len(matched) == 0 {
+		for _, address := range patterns {
+			method, err := CompilePattern(address)
+			if err != nil {
+				return nil, err
+			}
+			if address == msg.Address || method.Match(msg.Address) || p.Match(address) {
+				matched = append(matched, address)
+			}
 		}
-		if len(errs) > 0 {
-			return errors.New(strings.Join(errs, " and "))
+	}
+	if len(matched) > 1 {
+		sort.Strings(matched)
+	}
+	return matched, nil
+}
+
+// unmatched invokes the Default method with a message that no other method matched.
+// If there is no Default method the conn it was read from handles the message
+// according to its UnmatchedPolicy.
+func (h PatternMatching) unmatched(msg Message) error {
+	if handler, ok := h[Default]; ok {
+		if err := handleMessage(handler, msg, Default); err != nil {
+			return &DispatchError{Address: Default, Err: err}
 		}
 		return nil
 	}
+	if msg.replier != nil {
+		err := &DispatchError{Address: msg.ownedAddress(), Err: ErrNoMatchingMethod}
+		msg.replier.handleUnmatched(msg, err)
+		traceUnmatched(msg, err)
+	}
 	return nil
 }
 
-// invoke invokes an OSC packet, which could be a message or a bundle of messages.
-func (h PatternMatching) invoke(p Packet, exactMatch bool) error {
+// waitTimetag waits until the time of tt, unless it is Immediately or has passed.
+func waitTimetag(tt Timetag) {
+	if tt == Immediately {
+		return
+	}
+	if d := time.Until(tt.Time()); d > 0 {
+		<-time.After(d)
+	}
+}
+
+// invokeBundle invokes every packet of b with d without waiting for their timetags.
+func invokeBundle(d Dispatcher, b Bundle, exactMatch bool) error {
+	var errs []error
+	for _, p := range b.Packets {
+		if err := invokePacket(d, p, exactMatch); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs)
+}
+
+// invokePacket invokes an OSC packet, which could be a message or a bundle of messages.
+func invokePacket(d Dispatcher, p Packet, exactMatch bool) error {
 	switch x := p.(type) {
 	case Message:
-		return h.Invoke(x, exactMatch)
+		return d.Invoke(x, exactMatch)
 	case Bundle:
-		return h.immediately(x, exactMatch)
+		return invokeBundle(d, x, exactMatch)
 	default:
-		return errors.Errorf("unsupported type for dispatcher: %T", p)
+		return fmt.Errorf("unsupported type for dispatcher: %T", p)
 	}
 }
 
-// Invoke invokes an OSC message.
-func (h PatternMatching) Invoke(msg Message, exactMatch bool) error {
-	for address, handler := range h {
-		matched, err := msg.Match(address, exactMatch)
-		if err != nil {
-			return err
-		}
-		if matched {
-			return handler.Handle(msg)
-		}
+// joinErrors returns nil if there are no errors, the error if there is one,
+// or an error that wraps all of them.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
 	}
-	return nil
+	return multiError(errs)
 }
+
+// multiError is the error of several methods that failed.
+// errors.Is and errors.As look at every one of them.
+type multiError []error
+
+// Error returns all of the error messages.
+func (e multiError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, " and ")
+}
+
+// Unwrap returns the errors.
+func (e multiError) Unwrap() []error { return e }
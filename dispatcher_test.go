@@ -1,10 +1,11 @@
 package osc
 
 import (
+	"errors"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 // Test a successful method invocation.
@@ -111,7 +112,288 @@ func TestDispatcherInvoke(t *testing.T) {
 	if err := d.Invoke(Message{Address: "/baz"}, false); err != nil {
 		t.Fatal(err)
 	}
-	if err := d.invoke(badPacket{}, false); err == nil {
+	if err := d.invoke(unknownTypetagPacket{typetags: ",Q"}, false); err == nil {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestDispatcherDispatchEveryPacket(t *testing.T) {
+	var addrs []string
+	d := PatternMatching{
+		"/foo": Method(func(msg Message) error {
+			addrs = append(addrs, msg.Address)
+			return nil
+		}),
+		"/bar": Method(func(msg Message) error {
+			addrs = append(addrs, msg.Address)
+			return nil
+		}),
+	}
+	b := Bundle{
+		Timetag: Immediately,
+		Packets: []Packet{
+			Message{Address: "/foo"},
+			Bundle{Timetag: Immediately, Packets: []Packet{Message{Address: "/bar"}}},
+			Message{Address: "/bar"},
+		},
+	}
+	if err := d.Dispatch(b, true); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "/foo /bar /bar", strings.Join(addrs, " "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestDispatcherInvokeAllMatches(t *testing.T) {
+	var (
+		invoked []string
+		d       = PatternMatching{}
+	)
+	for _, addr := range []string{"/mixer/1/level", "/mixer/2/level", "/mixer/10/level", "/mixer/1/pan", "/master/level"} {
+		addr := addr
+		d[addr] = Method(func(msg Message) error {
+			invoked = append(invoked, addr)
+			return nil
+		})
+	}
+	for i, testcase := range []struct {
+		Pattern  string
+		Expected string
+	}{
+		{Pattern: "/mixer/*/level", Expected: "/mixer/1/level /mixer/10/level /mixer/2/level"},
+		{Pattern: "/mixer/?/level", Expected: "/mixer/1/level /mixer/2/level"},
+		{Pattern: "/mixer/[!1]/level", Expected: "/mixer/2/level"},
+		{Pattern: "/mixer/1/{level,pan}", Expected: "/mixer/1/level /mixer/1/pan"},
+		{Pattern: "/m*/level", Expected: "/master/level"},
+		{Pattern: "/*/level", Expected: "/master/level"},
+		{Pattern: "/m?xer/[0-9]*/*", Expected: "/mixer/1/level /mixer/1/pan /mixer/10/level /mixer/2/level"},
+		{Pattern: "/mixer/3/level", Expected: ""},
+	} {
+		invoked = nil
+		if err := d.Invoke(Message{Address: testcase.Pattern}, false); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if got := strings.Join(invoked, " "); testcase.Expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, testcase.Expected, got)
+		}
+	}
+
+	// A failing method does not stop the others.
+	d["/mixer/1/level"] = Method(func(msg Message) error { return errors.New("one") })
+	d["/mixer/2/level"] = Method(func(msg Message) error { return errors.New("two") })
+	invoked = nil
+	err := d.Invoke(Message{Address: "/mixer/*/level"}, false)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if expected, got := "one and two", err.Error(); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	if expected, got := "/mixer/10/level", strings.Join(invoked, " "); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestServeAddressPattern(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		levels  = make(chan string, 2)
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- c2.Serve(1, PatternMatching{
+			"/mixer/1/level": Method(func(msg Message) error {
+				levels <- msg.Address
+				return nil
+			}),
+			"/mixer/2/level": Method(func(msg Message) error {
+				levels <- msg.Address
+				return nil
+			}),
+		})
+	}()
+	if err := c1.Send(Message{Address: "/mixer/*/level"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case addr := <-levels:
+			if expected, got := "/mixer/*/level", addr; expected != got {
+				t.Fatalf("expected %s, got %s", expected, got)
+			}
+		case err := <-errChan:
+			t.Fatalf("server stopped: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for method")
+		}
+	}
+}
+
+func BenchmarkPatternMatchingInvoke(b *testing.B) {
+	d := PatternMatching{}
+	for ch := 0; ch < 32; ch++ {
+		for _, param := range []string{"level", "pan", "mute", "solo"} {
+			d["/mixer/"+strconv.Itoa(ch)+"/"+param] = Method(func(msg Message) error { return nil })
+		}
+	}
+	for _, pattern := range []string{"/mixer/7/level", "/mixer/*/level", "/mixer/[0-9]/{level,pan}"} {
+		msg := Message{Address: pattern}
+		b.Run(pattern, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := d.Invoke(msg, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherPatternMethods(t *testing.T) {
+	var (
+		invoked []string
+		d       = PatternMatching{}
+	)
+	for _, addr := range []string{"/fader/*", "/fader/[0-9]", "/fader/1", "/{xy,rotary}/1"} {
+		addr := addr
+		d[addr] = Method(func(msg Message) error {
+			invoked = append(invoked, addr)
+			return nil
+		})
+	}
+	for i, testcase := range []struct {
+		Address  string
+		Expected string
+	}{
+		// The literal method wins over the patterns that also match.
+		{Address: "/fader/1", Expected: "/fader/1"},
+		{Address: "/fader/2", Expected: "/fader/* /fader/[0-9]"},
+		{Address: "/fader/10", Expected: "/fader/*"},
+		{Address: "/rotary/1", Expected: "/{xy,rotary}/1"},
+		{Address: "/fader/2/x", Expected: ""},
+		// Incoming patterns match pattern methods literally.
+		{Address: "/fader/?", Expected: "/fader/1"},
+		{Address: "/fader/[0-9]", Expected: "/fader/1"},
+		{Address: "/{xy,rotary}/1", Expected: "/{xy,rotary}/1"},
+		{Address: "/*/1", Expected: "/fader/1"},
+	} {
+		invoked = nil
+		if err := d.Invoke(Message{Address: testcase.Address}, false); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if got := strings.Join(invoked, " "); testcase.Expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, testcase.Expected, got)
+		}
+	}
+
+	// Exact matching ignores patterns.
+	invoked = nil
+	if err := d.Invoke(Message{Address: "/fader/2"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(invoked) != 0 {
+		t.Fatalf("expected no methods to be invoked, got %v", invoked)
+	}
+}
+
+func TestServeInvalidPatternMethod(t *testing.T) {
+	for i, addr := range []string{"/fader/[0-9", "/fader/{a,b", "fader/*", "/fader 1"} {
+		c1, c2 := Pipe()
+		err := c2.Serve(1, PatternMatching{
+			addr: Method(func(msg Message) error { return nil }),
+		})
+		_ = c1.Close() // Best effort.
+		if !errors.Is(err, ErrInvalidAddress) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, ErrInvalidAddress, err)
+		}
+	}
+}
+
+func TestServeDefaultMethod(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		known     = make(chan string, 1)
+		unmatched = make(chan string, 2)
+		errChan   = make(chan error)
+	)
+	go func() {
+		errChan <- c2.Serve(1, PatternMatching{
+			"/known": Method(func(msg Message) error {
+				known <- msg.Address
+				return nil
+			}),
+			Default: Method(func(msg Message) error {
+				unmatched <- msg.Address
+				return nil
+			}),
+		})
+	}()
+	for _, p := range []Packet{
+		Message{Address: "/known"},
+		Message{Address: "/unknown"},
+		Bundle{Timetag: Immediately, Packets: []Packet{Message{Address: "/unknown/too"}}},
+	} {
+		if err := c1.Send(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, expected := range []string{"/unknown", "/unknown/too"} {
+		select {
+		case got := <-unmatched:
+			if expected != got {
+				t.Fatalf("expected %s, got %s", expected, got)
+			}
+		case err := <-errChan:
+			t.Fatalf("server stopped: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for the default method")
+		}
+	}
+	if expected, got := "/known", <-known; expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if expected, got := uint64(0), c2.Unmatched(); expected != got {
+		t.Fatalf("expected %d unmatched messages, got %d", expected, got)
+	}
+}
+
+func TestServeUnmatchedCounted(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	// Unmatched messages are not errors, even in strict mode.
+	c2.SetStrict(true)
+
+	var (
+		known   = make(chan struct{})
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- c2.Serve(1, PatternMatching{
+			"/known": Method(func(msg Message) error {
+				known <- struct{}{}
+				return nil
+			}),
+		})
+	}()
+	for _, addr := range []string{"/unknown", "/unknown/too", "/known"} {
+		if err := c1.Send(Message{Address: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-known:
+	case err := <-errChan:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /known")
+	}
+	if expected, got := uint64(2), c2.Unmatched(); expected != got {
+		t.Fatalf("expected %d unmatched messages, got %d", expected, got)
+	}
+}
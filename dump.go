@@ -0,0 +1,436 @@
+package osc
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrDumpSyntax is returned by ScanDump for a line that is not in the format of Fprint.
+var ErrDumpSyntax = errors.New("invalid dump line")
+
+// Fprint writes p to w in the text format of oscdump, the dump tool of liblo,
+// with a line for every message:
+//
+//	/an/address ifs 1 2.500000 "three"
+//
+// The typetags follow the address without the comma, and the arguments follow
+// the typetags: integers in decimal, floats with 6 decimals, strings in double quotes,
+// symbols after a single quote, characters in single quotes, timetags as the hex
+// seconds and fraction, blobs as their size and their bytes in hex, like [2b 0x01 0x02],
+// MIDI messages and colors as their 4 bytes in hex, like MIDI [0x00 0x90 0x3c 0x7f],
+// and #T, #F, Nil and Infinitum. The brackets of arrays are arguments of their own.
+// The messages of a bundle are prefixed with the timetag of the bundle, as oscdump prints them:
+//
+//	e0d1c4ef.80000000 /cue/go i 2
+//
+// Fprint returns the number of bytes written and the first error.
+func Fprint(w io.Writer, p Packet) (int, error) {
+	var sb strings.Builder
+	if err := appendDump(&sb, p, ""); err != nil {
+		return 0, err
+	}
+	return io.WriteString(w, sb.String())
+}
+
+// appendDump writes the lines of p, every one of them prefixed with prefix.
+func appendDump(sb *strings.Builder, p Packet, prefix string) error {
+	switch x := p.(type) {
+	case Message:
+		sb.WriteString(prefix)
+		sb.WriteString(x.Address)
+		sb.WriteByte(' ')
+		sb.WriteString(x.TypetagString())
+		for _, a := range x.arguments() {
+			appendDumpArgument(sb, a)
+		}
+		sb.WriteByte('\n')
+	case Bundle:
+		for _, el := range x.Packets {
+			if err := appendDump(sb, el, formatDumpTimetag(x.Timetag)+" "); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("dump %T: %w", p, ErrUnsupportedType)
+	}
+	return nil
+}
+
+// appendDumpArgument writes a space and the argument, or its elements for an array.
+func appendDumpArgument(sb *strings.Builder, a Argument) {
+	sb.WriteByte(' ')
+	switch tt := a.Typetag(); tt {
+	case TypetagInt:
+		v, _ := a.ReadInt32()
+		sb.WriteString(strconv.FormatInt(int64(v), 10))
+	case TypetagInt64:
+		v, _ := a.ReadInt64()
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case TypetagFloat:
+		v, _ := a.ReadFloat32()
+		sb.WriteString(formatDumpFloat(float64(v)))
+	case TypetagDouble:
+		v, _ := a.ReadFloat64()
+		sb.WriteString(formatDumpFloat(v))
+	case TypetagString:
+		v, _ := a.ReadString()
+		sb.WriteString(`"` + v + `"`)
+	case TypetagSymbol:
+		v, _ := a.ReadSymbol()
+		sb.WriteString("'" + v)
+	case TypetagChar:
+		v, _ := a.ReadChar()
+		sb.WriteString("'" + string(v) + "'")
+	case TypetagTimetag:
+		v, _ := a.ReadTimetag()
+		sb.WriteString(formatDumpTimetag(v))
+	case TypetagBlob:
+		v, _ := a.ReadBlob()
+		fmt.Fprintf(sb, "[%db", len(v))
+		for _, c := range v {
+			fmt.Fprintf(sb, " 0x%02x", c)
+		}
+		sb.WriteByte(']')
+	case TypetagMIDI:
+		v, _ := a.ReadMIDI()
+		fmt.Fprintf(sb, "MIDI [0x%02x 0x%02x 0x%02x 0x%02x]", v.Port, v.Status, v.Data1, v.Data2)
+	case TypetagRGBA:
+		v, _ := a.ReadRGBA()
+		fmt.Fprintf(sb, "RGBA [0x%02x 0x%02x 0x%02x 0x%02x]", v.R, v.G, v.B, v.A)
+	case TypetagTrue, TypetagFalse:
+		sb.WriteString("#" + string(tt))
+	case TypetagNil:
+		sb.WriteString("Nil")
+	case TypetagInfinitum:
+		sb.WriteString("Infinitum")
+	case TypetagArrayStart:
+		elems, _ := a.ReadArray()
+		sb.WriteByte('[')
+		for _, elem := range elems {
+			appendDumpArgument(sb, elem)
+		}
+		sb.WriteString(" ]")
+	default:
+		sb.WriteString("0x" + hex.EncodeToString(a.Bytes()))
+	}
+}
+
+// formatDumpFloat formats f like the %f of C.
+func formatDumpFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
+
+// formatDumpTimetag formats tt as its hex seconds and fraction.
+func formatDumpTimetag(tt Timetag) string {
+	return fmt.Sprintf("%08x.%08x", uint32(tt>>32), uint32(tt))
+}
+
+// DumpDispatcher returns a dispatcher that writes every message it dispatches
+// to w with Fprint, and the messages of bundles with the timetags of their bundles,
+// as soon as they are received. Every packet is written with a single call to Write,
+// so many workers can share it. Errors writing to w are returned to the conn.
+//
+// The dispatcher is a MessageHandler too, e.g. for the Default method of a PatternMatching
+// dispatcher, to dump the messages that no other method handles.
+func DumpDispatcher(w io.Writer) Dispatcher {
+	return &dumper{w: w}
+}
+
+// dumper is the dispatcher of DumpDispatcher.
+type dumper struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Dispatch writes the messages of b.
+func (d *dumper) Dispatch(b Bundle, exactMatch bool) error { return d.dump(b) }
+
+// Invoke writes msg.
+func (d *dumper) Invoke(msg Message, exactMatch bool) error { return d.dump(msg) }
+
+// Handle writes msg.
+func (d *dumper) Handle(msg Message) error { return d.dump(msg) }
+
+// dump writes p.
+func (d *dumper) dump(p Packet) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err := Fprint(d.w, p)
+	return err
+}
+
+// ScanDump parses the lines that Fprint writes back into packets. The messages of lines
+// that are prefixed with the same timetag, one after the other, are the messages
+// of a bundle, so bundles that were nested are not told apart, nor the bundles with
+// the same timetag that were dumped one after the other.
+//
+// Only the simple cases are round-tripped: strings and symbols are written without escapes,
+// so the ones that have a double quote followed by a space, or a space, are not parsed back,
+// and arguments of unknown typetags are not parsed at all. Empty lines are skipped.
+// Lines that can not be parsed fail with an error wrapping ErrDumpSyntax and their number.
+func ScanDump(r io.Reader) ([]Packet, error) {
+	var (
+		packets []Packet
+		bundle  = -1 // The index of the bundle of the last line, if it had one.
+		scanner = bufio.NewScanner(r)
+	)
+	scanner.Buffer(make([]byte, 0, 4096), bufSize)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		tt, bundled, msg, err := scanDumpLine(line)
+		if err != nil {
+			return packets, fmt.Errorf("line %d: %w: %w", n, ErrDumpSyntax, err)
+		}
+		if !bundled {
+			bundle = -1
+			packets = append(packets, msg)
+			continue
+		}
+		if bundle < 0 || packets[bundle].(Bundle).Timetag != tt {
+			bundle = len(packets)
+			packets = append(packets, Bundle{Timetag: tt})
+		}
+		b := packets[bundle].(Bundle)
+		b.Packets = append(b.Packets, msg)
+		packets[bundle] = b
+	}
+	return packets, scanner.Err()
+}
+
+// scanDumpLine parses a line: the timetag of its bundle, if it has one, and its message.
+func scanDumpLine(line string) (Timetag, bool, Message, error) {
+	var (
+		tt      Timetag
+		bundled bool
+		err     error
+	)
+	if !strings.HasPrefix(line, "/") {
+		prefix, rest, _ := strings.Cut(line, " ")
+		if tt, err = parseDumpTimetag(prefix); err != nil {
+			return 0, false, Message{}, fmt.Errorf("bundle timetag: %w", err)
+		}
+		line, bundled = rest, true
+	}
+	address, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return 0, false, Message{}, fmt.Errorf("address %q without typetags", address)
+	}
+	typetags, rest, _ := strings.Cut(rest, " ")
+	args, rest, err := scanDumpArguments(typetags, rest)
+	if err != nil {
+		return 0, false, Message{}, fmt.Errorf("%s: %w", address, err)
+	}
+	if rest != "" {
+		return 0, false, Message{}, fmt.Errorf("%s: %q after the arguments", address, rest)
+	}
+	msg := Message{Address: address, Arguments: args}
+	if err := validateAddressPattern(address); err != nil {
+		return 0, false, Message{}, err
+	}
+	return tt, bundled, msg, nil
+}
+
+// scanDumpArguments parses the arguments of typetags from s, and returns what is left of s.
+func scanDumpArguments(typetags, s string) ([]Argument, string, error) {
+	args := []Argument{}
+	for i := 0; i < len(typetags); i++ {
+		tt := typetags[i]
+		if tt == TypetagArrayStart {
+			end := matchingArrayEnd(typetags, i)
+			if end < 0 || !strings.HasPrefix(s, "[") {
+				return nil, "", fmt.Errorf("argument %d: array: %w", len(args), ErrUnbalancedArray)
+			}
+			elems, rest, err := scanDumpArguments(typetags[i+1:end], strings.TrimPrefix(s[1:], " "))
+			if err != nil {
+				return nil, "", err
+			}
+			if !strings.HasPrefix(rest, "]") {
+				return nil, "", fmt.Errorf("argument %d: array: %w", len(args), ErrUnbalancedArray)
+			}
+			args = append(args, Array(elems))
+			s, i = strings.TrimPrefix(rest[1:], " "), end
+			continue
+		}
+		a, rest, err := scanDumpArgument(tt, s)
+		if err != nil {
+			return nil, "", fmt.Errorf("argument %d: typetag %q: %w", len(args), tt, err)
+		}
+		args = append(args, a)
+		s = strings.TrimPrefix(rest, " ")
+	}
+	return args, s, nil
+}
+
+// matchingArrayEnd returns the index of the ']' that ends the array started at typetags[start].
+func matchingArrayEnd(typetags string, start int) int {
+	depth := 0
+	for i := start; i < len(typetags); i++ {
+		switch typetags[i] {
+		case TypetagArrayStart:
+			depth++
+		case TypetagArrayEnd:
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// scanDumpArgument parses an argument with the typetag tt from the start of s,
+// and returns what is left of s.
+func scanDumpArgument(tt byte, s string) (Argument, string, error) {
+	switch tt {
+	case TypetagString:
+		if !strings.HasPrefix(s, `"`) {
+			return nil, "", errors.New("string without quotes")
+		}
+		end := strings.Index(s[1:], `" `) + 1
+		if end == 0 {
+			if end = len(s) - 1; end < 1 || s[end] != '"' {
+				return nil, "", errors.New("string without a closing quote")
+			}
+		}
+		return String(s[1:end]), s[end+1:], nil
+	case TypetagSymbol:
+		if !strings.HasPrefix(s, "'") {
+			return nil, "", errors.New("symbol without a quote")
+		}
+		v, rest, _ := strings.Cut(s[1:], " ")
+		return Symbol(v), rest, nil
+	case TypetagChar:
+		if len(s) < 3 || s[0] != '\'' || s[2] != '\'' {
+			return nil, "", errors.New("char without quotes")
+		}
+		return Char(s[1]), s[3:], nil
+	case TypetagBlob:
+		return scanDumpBlob(s)
+	case TypetagMIDI, TypetagRGBA:
+		name := "MIDI ["
+		if tt == TypetagRGBA {
+			name = "RGBA ["
+		}
+		if !strings.HasPrefix(s, name) {
+			return nil, "", fmt.Errorf("expected %q", name)
+		}
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return nil, "", errors.New("without a closing bracket")
+		}
+		var b [4]byte
+		if _, err := fmt.Sscanf(s[len(name):end], "0x%02x 0x%02x 0x%02x 0x%02x", &b[0], &b[1], &b[2], &b[3]); err != nil {
+			return nil, "", err
+		}
+		if tt == TypetagRGBA {
+			return RGBA{R: b[0], G: b[1], B: b[2], A: b[3]}, s[end+1:], nil
+		}
+		return MIDI{Port: b[0], Status: b[1], Data1: b[2], Data2: b[3]}, s[end+1:], nil
+	}
+	token, rest, _ := strings.Cut(s, " ")
+	if rest != "" {
+		rest = " " + rest
+	}
+	var (
+		a   Argument
+		err error
+	)
+	switch tt {
+	case TypetagInt:
+		var v int64
+		v, err = strconv.ParseInt(token, 10, 32)
+		a = Int(v)
+	case TypetagInt64:
+		var v int64
+		v, err = strconv.ParseInt(token, 10, 64)
+		a = Int64(v)
+	case TypetagFloat:
+		var v float64
+		v, err = strconv.ParseFloat(token, 32)
+		a = Float(v)
+	case TypetagDouble:
+		var v float64
+		v, err = strconv.ParseFloat(token, 64)
+		a = Double(v)
+	case TypetagTimetag:
+		a, err = parseDumpTimetag(token)
+	case TypetagTrue, TypetagFalse:
+		if token != "#"+string(tt) {
+			err = fmt.Errorf("expected #%c, got %q", tt, token)
+		}
+		a = Bool(tt == TypetagTrue)
+	case TypetagNil:
+		if token != "Nil" {
+			err = fmt.Errorf("expected Nil, got %q", token)
+		}
+		a = Nil{}
+	case TypetagInfinitum:
+		if token != "Infinitum" {
+			err = fmt.Errorf("expected Infinitum, got %q", token)
+		}
+		a = Infinitum{}
+	default:
+		err = ErrInvalidTypeTag
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return a, rest, nil
+}
+
+// scanDumpBlob parses a blob like [2b 0x01 0x02] from the start of s.
+func scanDumpBlob(s string) (Argument, string, error) {
+	end := strings.IndexByte(s, ']')
+	if !strings.HasPrefix(s, "[") || end < 0 {
+		return nil, "", errors.New("blob without brackets")
+	}
+	fields := strings.Fields(s[1:end])
+	if len(fields) == 0 || !strings.HasSuffix(fields[0], "b") {
+		return nil, "", errors.New("blob without a size")
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(fields[0], "b"))
+	if err != nil || size != len(fields)-1 {
+		return nil, "", fmt.Errorf("blob of %q bytes with %d bytes", fields[0], len(fields)-1)
+	}
+	blob := make(Blob, size)
+	for i, field := range fields[1:] {
+		v, err := strconv.ParseUint(strings.TrimPrefix(field, "0x"), 16, 8)
+		if err != nil {
+			return nil, "", fmt.Errorf("blob byte %d: %w", i, err)
+		}
+		blob[i] = byte(v)
+	}
+	return blob, s[end+1:], nil
+}
+
+// parseDumpTimetag parses a timetag that formatDumpTimetag formatted.
+func parseDumpTimetag(s string) (Timetag, error) {
+	secs, frac, ok := strings.Cut(s, ".")
+	if !ok || len(secs) != 8 || len(frac) != 8 {
+		return 0, fmt.Errorf("timetag %q", s)
+	}
+	v, err := strconv.ParseUint(secs+frac, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("timetag %q: %w", s, err)
+	}
+	return Timetag(v), nil
+}
@@ -0,0 +1,167 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// dumpPackets are the packets of the golden files of the dump tests.
+var dumpPackets = []struct {
+	Golden string
+	Packet Packet
+}{
+	{Golden: "empty.golden", Packet: Message{Address: "/ping"}},
+	{Golden: "numbers.golden", Packet: MustMessage("/synth/freq", 440, float32(0.5), int64(-7), 2.25)},
+	{Golden: "floats.golden", Packet: Message{Address: "/f", Arguments: Arguments{Float(math.Inf(1)), Double(math.Inf(-1)), Float(math.NaN())}}},
+	{Golden: "strings.golden", Packet: Message{Address: "/s", Arguments: Arguments{String("hello world"), Symbol("sym"), Char('k')}}},
+	{Golden: "blobs.golden", Packet: Message{Address: "/b", Arguments: Arguments{Blob{1, 2, 0xfe}, Blob{}}}},
+	{Golden: "special.golden", Packet: Message{Address: "/x", Arguments: Arguments{Bool(true), Bool(false), Nil{}, Infinitum{}}}},
+	{Golden: "midi.golden", Packet: Message{Address: "/grid", Arguments: Arguments{MIDI{Status: 0x90, Data1: 0x3c, Data2: 0x7f}, RGBA{R: 0xff, A: 0x80}}}},
+	{Golden: "array.golden", Packet: Message{Address: "/eq", Arguments: Arguments{Int(1), Array{Float(0.25), Array{}}, Int(2)}}},
+	{Golden: "timetag.golden", Packet: Message{Address: "/t", Arguments: Arguments{FromTime(time.Date(2000, 1, 1, 0, 0, 0, 5e8, time.UTC)), Immediately}}},
+	{
+		Golden: "bundle.golden",
+		Packet: Bundle{
+			Timetag: FromTime(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)),
+			Packets: []Packet{
+				MustMessage("/cue/go", 2),
+				Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/fader/1", float32(0.5))}},
+				MustMessage("/blackout"),
+			},
+		},
+	},
+}
+
+func TestFprint(t *testing.T) {
+	for i, testcase := range dumpPackets {
+		var buf bytes.Buffer
+		n, err := Fprint(&buf, testcase.Packet)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if expected, got := buf.Len(), n; expected != got {
+			t.Fatalf("(testcase %d) expected %d bytes, got %d", i, expected, got)
+		}
+		golden := filepath.Join("testdata", "dump", testcase.Golden)
+		if *update {
+			if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Fatalf("(testcase %d) expected\n%s\ngot\n%s", i, expected, buf.Bytes())
+		}
+	}
+}
+
+func TestFprint_Unsupported(t *testing.T) {
+	if _, err := Fprint(&bytes.Buffer{}, unknownTypetagPacket{typetags: ",Q"}); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestScanDump(t *testing.T) {
+	for i, testcase := range dumpPackets {
+		if testcase.Golden == "floats.golden" || testcase.Golden == "bundle.golden" {
+			continue // NaN is not equal to itself, and nested bundles are flattened.
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "dump", testcase.Golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets, err := ScanDump(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if len(packets) != 1 || !testcase.Packet.Equal(packets[0]) {
+			t.Fatalf("(testcase %d) expected %s, got %v", i, testcase.Packet, packets)
+		}
+	}
+}
+
+func TestScanDump_Bundles(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "dump", "bundle.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, err := ScanDump(bytes.NewReader(append(data, "\n/after i 1\n"...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := FromTime(time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC))
+	for i, expected := range []Packet{
+		Bundle{Timetag: tt, Packets: []Packet{MustMessage("/cue/go", 2)}},
+		Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/fader/1", float32(0.5))}},
+		Bundle{Timetag: tt, Packets: []Packet{MustMessage("/blackout")}},
+		MustMessage("/after", 1),
+	} {
+		if i >= len(packets) || !expected.Equal(packets[i]) {
+			t.Fatalf("(testcase %d) expected %s, got %v", i, expected, packets)
+		}
+	}
+	if expected, got := 4, len(packets); expected != got {
+		t.Fatalf("expected %d packets, got %d", expected, got)
+	}
+}
+
+func TestScanDump_Errors(t *testing.T) {
+	for i, line := range []string{
+		"/foo",
+		"foo i 1",
+		"nottime /foo i 1",
+		"/foo i one",
+		"/foo i 1 2",
+		"/foo s hello",
+		"/foo s \"hello",
+		"/foo S sym",
+		"/foo c k",
+		"/foo b [3b 0x01]",
+		"/foo b 0x01",
+		"/foo m [0x01 0x02 0x03 0x04]",
+		"/foo T #F",
+		"/foo N nil",
+		"/foo [i] 1",
+		"/foo [i 1",
+		"/foo Q 1",
+	} {
+		_, err := ScanDump(strings.NewReader("\n/ok i 1\n" + line + "\n"))
+		if !errors.Is(err, ErrDumpSyntax) {
+			t.Fatalf("(testcase %d) expected ErrDumpSyntax, got %v", i, err)
+		}
+		if !strings.HasPrefix(err.Error(), "line 3: ") {
+			t.Fatalf("(testcase %d) expected the line number, got %q", i, err)
+		}
+	}
+}
+
+func TestDumpDispatcher(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		d   = DumpDispatcher(&buf)
+	)
+	if err := d.Invoke(MustMessage("/a", 1), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Dispatch(Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/b", "x")}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.(MessageHandler).Handle(MustMessage("/c")); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "/a i 1\n00000000.00000001 /b s \"x\"\n/c \n", buf.String(); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
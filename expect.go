@@ -0,0 +1,148 @@
+package osc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Expect returns an error if the typetags of the message, without the leading ',',
+// are not the expected ones, e.g. "ifs" for an int, a float and a string.
+// A trailing '*' accepts any arguments after the expected ones, so "s*" expects
+// a string followed by zero or more arguments. Note that WithTypetags stands
+// for a single argument with a '*'.
+// Booleans have either typetag, so 'T' matches 'F' too, and arrays are matched
+// as a single argument, like "[ff]".
+//
+// The error wraps ErrTypetagMismatch, and names the first argument that
+// does not match, with the typetag that was expected and the one of the message.
+func (msg Message) Expect(typetags string) error {
+	expected := []byte(strings.TrimSuffix(typetags, string(TypetagAny)))
+	prefix := len(expected) < len(typetags)
+
+	var (
+		buf    [16]byte
+		actual = msg.view.typetags
+	)
+	if !msg.view.isSet() {
+		actual = appendTypetags(buf[:0], msg.Arguments)
+	}
+	i, j, n := 0, 0, 0
+	for ; i < len(expected); n++ {
+		end := nextActualTypetag(expected, i)
+		if j == len(actual) {
+			return fmt.Errorf("%s: argument %d: expected %q, got no argument: %w", msg.Address, n, expected[i:end], ErrTypetagMismatch)
+		}
+		next := nextActualTypetag(actual, j)
+		if !sameTypetags(expected[i:end], actual[j:next]) {
+			return fmt.Errorf("%s: argument %d: expected %q, got %q: %w", msg.Address, n, expected[i:end], actual[j:next], ErrTypetagMismatch)
+		}
+		i, j = end, next
+	}
+	if j < len(actual) && !prefix {
+		return fmt.Errorf("%s: argument %d: expected no argument, got %q: %w", msg.Address, n, actual[j:nextActualTypetag(actual, j)], ErrTypetagMismatch)
+	}
+	return nil
+}
+
+// sameTypetags reports whether the typetags of two arguments are the same,
+// with 'T' and 'F' being the same.
+func sameTypetags(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] && !(isBoolTypetag(a[i]) && isBoolTypetag(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// isBoolTypetag reports whether tt is the typetag of a boolean.
+func isBoolTypetag(tt byte) bool {
+	return tt == TypetagTrue || tt == TypetagFalse
+}
+
+// Unpack checks that the message has exactly one argument for every destination,
+// of its type, and stores the arguments in them, like fmt.Sscan:
+//
+//	var (
+//		id   int32
+//		freq float32
+//		name string
+//	)
+//	if err := msg.Unpack(&id, &freq, &name); err != nil {
+//		return err
+//	}
+//
+// The destinations can be of type *int32, *int64, *float32, *float64, *string,
+// *[]byte, *bool or *Timetag, and other types are an error wrapping ErrUnsupportedType.
+// If an argument does not match, Unpack returns the error of Expect,
+// and none of the destinations are modified.
+func (msg Message) Unpack(dests ...interface{}) error {
+	var buf [16]byte
+	expected := buf[:0]
+	for i, dest := range dests {
+		tt, err := destinationTypetag(dest)
+		if err == nil && reflect.ValueOf(dest).IsNil() {
+			err = fmt.Errorf("nil %T", dest)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: destination %d: %w", msg.Address, i, err)
+		}
+		expected = append(expected, tt)
+	}
+	if err := msg.Expect(string(expected)); err != nil {
+		return err
+	}
+	for i, dest := range dests {
+		var err error
+		switch d := dest.(type) {
+		case *int32:
+			*d, err = msg.Int32At(i)
+		case *int64:
+			*d, err = msg.Int64At(i)
+		case *float32:
+			*d, err = msg.Float32At(i)
+		case *float64:
+			*d, err = msg.Float64At(i)
+		case *string:
+			*d, err = msg.StringAt(i)
+		case *[]byte:
+			*d, err = msg.BlobAt(i)
+		case *bool:
+			*d, err = msg.BoolAt(i)
+		case *Timetag:
+			*d, err = msg.TimetagAt(i)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", msg.Address, err)
+		}
+	}
+	return nil
+}
+
+// destinationTypetag returns the typetag of the arguments that Unpack stores in dest.
+func destinationTypetag(dest interface{}) (byte, error) {
+	switch dest.(type) {
+	case *int32:
+		return TypetagInt, nil
+	case *int64:
+		return TypetagInt64, nil
+	case *float32:
+		return TypetagFloat, nil
+	case *float64:
+		return TypetagDouble, nil
+	case *string:
+		return TypetagString, nil
+	case *[]byte:
+		return TypetagBlob, nil
+	case *bool:
+		return TypetagTrue, nil
+	case *Timetag:
+		return TypetagTimetag, nil
+	default:
+		return 0, fmt.Errorf("%T: %w", dest, ErrUnsupportedType)
+	}
+}
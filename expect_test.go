@@ -0,0 +1,121 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageExpect(t *testing.T) {
+	var (
+		args = []interface{}{int32(1), float32(2), "three"}
+		msg  = MustMessage("/expect", args...)
+	)
+	for i, testcase := range []struct {
+		Msg      Message
+		Typetags string
+		Err      string // Empty if the typetags are expected.
+	}{
+		{Msg: msg, Typetags: "ifs"},
+		{Msg: msg, Typetags: "ifs*"},
+		{Msg: msg, Typetags: "if*"},
+		{Msg: msg, Typetags: "*"},
+		{Msg: msg, Typetags: "iff", Err: `argument 2: expected "f", got "s"`},
+		{Msg: msg, Typetags: "fis", Err: `argument 0: expected "f", got "i"`},
+		{Msg: msg, Typetags: "if", Err: `argument 2: expected no argument, got "s"`},
+		{Msg: msg, Typetags: "ifsi", Err: `argument 3: expected "i", got no argument`},
+		{Msg: msg, Typetags: "ifsi*", Err: `argument 3: expected "i", got no argument`},
+		{Msg: msg, Typetags: "", Err: `argument 0: expected no argument, got "i"`},
+		{Msg: Message{Address: "/expect"}, Typetags: ""},
+		{Msg: MustMessage("/expect", true, false), Typetags: "TT"},
+		{Msg: MustMessage("/expect", true, false), Typetags: "FF"},
+		{Msg: MustMessage("/expect", true, "false"), Typetags: "TT", Err: `argument 1: expected "T", got "s"`},
+		{Msg: MustMessage("/expect", Array{Int(1), Float(2)}, "x"), Typetags: "[if]s"},
+		{Msg: MustMessage("/expect", Array{Int(1), Float(2)}, "x"), Typetags: "[if]*"},
+		{Msg: MustMessage("/expect", Array{Int(1), Float(2)}, "x"), Typetags: "[ii]s", Err: `argument 0: expected "[ii]", got "[if]"`},
+		{Msg: MustMessage("/expect", Array{Int(1), Float(2)}, "x"), Typetags: "[i]fs", Err: `argument 0: expected "[i]", got "[if]"`},
+		{Msg: MustMessage("/expect", Array{Int(1), Float(2)}, "x"), Typetags: "if", Err: `argument 0: expected "i", got "[if]"`},
+	} {
+		msgs := []Message{testcase.Msg}
+		if view, err := parseMessageView(testcase.Msg.Bytes(), nil); err == nil {
+			msgs = append(msgs, view)
+		}
+		for _, m := range msgs {
+			err := m.Expect(testcase.Typetags)
+			if testcase.Err == "" {
+				if err != nil {
+					t.Fatalf("(testcase %d) expected %q to be expected, got %s", i, testcase.Typetags, err)
+				}
+				continue
+			}
+			if !errors.Is(err, ErrTypetagMismatch) || !strings.Contains(err.Error(), testcase.Err) {
+				t.Fatalf("(testcase %d) expected an error with %q, got %v", i, testcase.Err, err)
+			}
+		}
+	}
+}
+
+func TestMessageUnpack(t *testing.T) {
+	var (
+		i32  int32
+		i64  int64
+		f32  float32
+		f64  float64
+		s    string
+		blob []byte
+		b    bool
+		tt   Timetag
+		now  = FromTime(time.Now())
+		msg  = MustMessage("/unpack", int32(1), int64(2), float32(3), float64(4), "five", []byte("six\x00"), true, now)
+	)
+	for _, m := range []Message{msg, mustView(t, msg)} {
+		i32, i64, f32, f64, s, blob, b, tt = 0, 0, 0, 0, "", nil, false, 0
+		if err := m.Unpack(&i32, &i64, &f32, &f64, &s, &blob, &b, &tt); err != nil {
+			t.Fatal(err)
+		}
+		if i32 != 1 || i64 != 2 || f32 != 3 || f64 != 4 || s != "five" || !bytes.Equal(blob, []byte("six\x00")) || !b || tt != now {
+			t.Fatalf("unexpected arguments %d %d %f %f %q %q %t %d", i32, i64, f32, f64, s, blob, b, tt)
+		}
+	}
+
+	for k, testcase := range []struct {
+		Msg   Message
+		Dests []interface{}
+		Err   error
+	}{
+		{Msg: MustMessage("/unpack", float32(1)), Dests: []interface{}{&i32}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int32(1)), Dests: []interface{}{&i64}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int32(1)), Dests: []interface{}{&f32}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", float32(1)), Dests: []interface{}{&f64}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", Symbol("x")), Dests: []interface{}{&s}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", "x"), Dests: []interface{}{&blob}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int32(1)), Dests: []interface{}{&b}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int64(1)), Dests: []interface{}{&tt}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int32(1), int32(2)), Dests: []interface{}{&i32}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int32(1)), Dests: []interface{}{&i32, &i32}, Err: ErrTypetagMismatch},
+		{Msg: MustMessage("/unpack", int32(1)), Dests: []interface{}{i32}, Err: ErrUnsupportedType},
+		{Msg: MustMessage("/unpack", 1), Dests: []interface{}{new(int)}, Err: ErrUnsupportedType},
+		{Msg: MustMessage("/unpack", int32(1)), Dests: []interface{}{(*int32)(nil)}},
+	} {
+		i32 = 42
+		err := testcase.Msg.Unpack(testcase.Dests...)
+		if err == nil || testcase.Err != nil && !errors.Is(err, testcase.Err) {
+			t.Fatalf("(testcase %d) expected %v, got %v", k, testcase.Err, err)
+		}
+		if i32 != 42 {
+			t.Fatalf("(testcase %d) expected the destination to be left alone, got %d", k, i32)
+		}
+	}
+}
+
+// mustView returns msg parsed as a view.
+func mustView(t *testing.T, msg Message) Message {
+	t.Helper()
+	view, err := parseMessageView(msg.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return view
+}
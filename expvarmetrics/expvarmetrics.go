@@ -0,0 +1,93 @@
+// Package expvarmetrics implements an osc.MetricsHook that exports the metrics
+// of OSC conns with the expvar package, e.g. at /debug/vars.
+package expvarmetrics
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/scgolang/osc"
+)
+
+// Metrics is an osc.MetricsHook that counts the traffic of the conns it is set on.
+// It is an expvar.Var whose value is a JSON object of the counters:
+//
+//	packets_received, bytes_received, packets_sent, bytes_sent,
+//	parse_errors: integers
+//	dispatched, dispatch_ns: objects of integers by OSC address
+//	dropped: an object of integers by reason
+type Metrics struct {
+	vars expvar.Map
+
+	packetsReceived expvar.Int
+	bytesReceived   expvar.Int
+	packetsSent     expvar.Int
+	bytesSent       expvar.Int
+	parseErrors     expvar.Int
+	dispatched      expvar.Map
+	dispatchNanos   expvar.Map
+	dropped         expvar.Map
+}
+
+// Make sure Metrics implements osc.MetricsHook.
+var _ osc.MetricsHook = (*Metrics)(nil)
+
+// New creates metrics that are not published.
+func New() *Metrics {
+	m := &Metrics{}
+	m.vars.Set("packets_received", &m.packetsReceived)
+	m.vars.Set("bytes_received", &m.bytesReceived)
+	m.vars.Set("packets_sent", &m.packetsSent)
+	m.vars.Set("bytes_sent", &m.bytesSent)
+	m.vars.Set("parse_errors", &m.parseErrors)
+	m.vars.Set("dispatched", &m.dispatched)
+	m.vars.Set("dispatch_ns", &m.dispatchNanos)
+	m.vars.Set("dropped", &m.dropped)
+	return m
+}
+
+// Publish creates metrics and publishes them with expvar under name.
+// Like expvar.Publish it panics if name is already in use.
+func Publish(name string) *Metrics {
+	m := New()
+	expvar.Publish(name, m)
+	return m
+}
+
+// String returns the metrics as a JSON object.
+func (m *Metrics) String() string {
+	return m.vars.String()
+}
+
+// Get returns the expvar.Var of the metrics named name, or nil if there is none.
+func (m *Metrics) Get(name string) expvar.Var {
+	return m.vars.Get(name)
+}
+
+// PacketReceived counts a packet that was read.
+func (m *Metrics) PacketReceived(bytes int) {
+	m.packetsReceived.Add(1)
+	m.bytesReceived.Add(int64(bytes))
+}
+
+// PacketSent counts a packet that was sent.
+func (m *Metrics) PacketSent(bytes int) {
+	m.packetsSent.Add(1)
+	m.bytesSent.Add(int64(bytes))
+}
+
+// ParseError counts a packet that could not be parsed.
+func (m *Metrics) ParseError() {
+	m.parseErrors.Add(1)
+}
+
+// Dispatched counts a message dispatched to addr and the time it took.
+func (m *Metrics) Dispatched(addr string, d time.Duration) {
+	m.dispatched.Add(addr, 1)
+	m.dispatchNanos.Add(addr, int64(d))
+}
+
+// Dropped counts a packet or a message that was dropped for reason.
+func (m *Metrics) Dropped(reason string) {
+	m.dropped.Add(reason, 1)
+}
@@ -0,0 +1,83 @@
+package expvarmetrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scgolang/osc"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.PacketReceived(16)
+	m.PacketReceived(8)
+	m.PacketSent(20)
+	m.ParseError()
+	m.Dispatched("/synth/1", 2*time.Millisecond)
+	m.Dispatched("/synth/1", time.Millisecond)
+	m.Dispatched(osc.BundleAddress, time.Millisecond)
+	m.Dropped(osc.ReasonQueueFull)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(m.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"packets_received": 2.0,
+		"bytes_received":   24.0,
+		"packets_sent":     1.0,
+		"bytes_sent":       20.0,
+		"parse_errors":     1.0,
+		"dispatched":       map[string]interface{}{"/synth/1": 2.0, osc.BundleAddress: 1.0},
+		"dispatch_ns":      map[string]interface{}{"/synth/1": 3e6, osc.BundleAddress: 1e6},
+		"dropped":          map[string]interface{}{osc.ReasonQueueFull: 1.0},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if v := m.Get("packets_received"); v == nil || v.String() != "2" {
+		t.Fatalf("expected 2 packets received, got %v", v)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	m := Publish("osc_test")
+	m.PacketSent(4)
+
+	if v := expvar.Get("osc_test"); v != expvar.Var(m) {
+		t.Fatalf("expected the metrics to be published, got %v", v)
+	}
+}
+
+func TestMetrics_Conn(t *testing.T) {
+	c1, c2 := osc.Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	m := New()
+	c2.SetMetricsHook(m)
+
+	dispatched := make(chan struct{})
+	go func() {
+		_ = c2.Serve(1, osc.PatternMatching{
+			"/ok": osc.Method(func(msg osc.Message) error {
+				close(dispatched)
+				return nil
+			}),
+		})
+	}()
+	if err := c1.Send(osc.MustMessage("/ok")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-dispatched:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the message to be dispatched")
+	}
+	if v := m.Get("packets_received"); v.String() != "1" {
+		t.Fatalf("expected 1 packet received, got %s", v)
+	}
+}
@@ -0,0 +1,97 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String renders the message for logging, e.g.
+//
+//	/synth/freq ,if 440 0.5
+//
+// Strings are quoted and escaped, and blobs are shown with their size.
+func (msg Message) String() string {
+	args := msg.arguments()
+	parts := make([]string, len(args)+2)
+	parts[0] = msg.Address
+	parts[1] = string(bytes.TrimRight(msg.Typetags(), "\x00"))
+	for i, a := range args {
+		parts[i+2] = formatArgument(a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// String renders the bundle for logging.
+// The timetag is on the first line and the packets
+// follow it on their own lines, indented.
+func (b Bundle) String() string {
+	buf := &bytes.Buffer{}
+	formatBundle(buf, b, "")
+	return buf.String()
+}
+
+// formatBundle writes a bundle to buf, indenting every line.
+func formatBundle(buf *bytes.Buffer, b Bundle, indent string) {
+	buf.WriteString(indent + BundleTag + " " + formatTimetag(b.Timetag))
+
+	for _, p := range b.Packets {
+		buf.WriteString("\n")
+		switch x := p.(type) {
+		case Bundle:
+			formatBundle(buf, x, indent+"  ")
+		case Message:
+			buf.WriteString(indent + "  " + x.String())
+		default:
+			fmt.Fprintf(buf, "%s  %T", indent, p)
+		}
+	}
+}
+
+// formatTimetag renders a timetag as a time, or as "immediately".
+func formatTimetag(tt Timetag) string {
+	if tt == Immediately {
+		return "immediately"
+	}
+	return tt.Time().Format(time.RFC3339Nano)
+}
+
+// formatArgument renders an argument the way Message.String shows it.
+func formatArgument(a Argument) string {
+	switch x := a.(type) {
+	case Int:
+		return strconv.FormatInt(int64(x), 10)
+	case Int64:
+		return strconv.FormatInt(int64(x), 10)
+	case Float:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case Double:
+		return strconv.FormatFloat(float64(x), 'g', -1, 64)
+	case String:
+		return strconv.Quote(string(x))
+	case Symbol:
+		return "'" + strings.Trim(strconv.Quote(string(x)), `"`)
+	case Blob:
+		return fmt.Sprintf("blob[%d]", len(x))
+	case Bool:
+		return strconv.FormatBool(bool(x))
+	case Nil:
+		return "nil"
+	case Infinitum:
+		return "inf"
+	case Char:
+		return strconv.QuoteRune(rune(x))
+	case Timetag:
+		return formatTimetag(x)
+	case Array:
+		parts := make([]string, len(x))
+		for i, elem := range x {
+			parts[i] = formatArgument(elem)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	default:
+		return a.String()
+	}
+}
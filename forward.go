@@ -0,0 +1,89 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Forward serves from and sends every packet it reads for which filter returns
+// true to the peer of to, until ctx is done, from is closed or sending fails
+// because to is closed. A nil filter forwards every packet.
+// Bundles are forwarded whole, and filter is given the whole bundle.
+// The packets are forwarded one at a time, in the order they are read.
+// Other errors sending a packet are passed to the error handler of from as a
+// *MethodError and do not stop forwarding, unless from is in strict mode.
+func Forward(ctx context.Context, from, to Conn, filter func(Packet) bool) error {
+	return ForwardTransform(ctx, from, to, func(p Packet) (Packet, bool) {
+		if filter != nil && !filter(p) {
+			return nil, false
+		}
+		return p, true
+	})
+}
+
+// ForwardTransform is like Forward, but every packet is passed to transform,
+// which returns the packet to send instead, or false if nothing should be sent.
+func ForwardTransform(ctx context.Context, from, to Conn, transform func(Packet) (Packet, bool)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f := &forwarder{to: to, transform: transform, stop: cancel}
+	err := from.ServeContext(ctx, 1, f)
+	if fatal := f.fatalError(); fatal != nil {
+		return fatal
+	}
+	return err
+}
+
+// forwarder is the Dispatcher of Forward.
+type forwarder struct {
+	to        Conn
+	transform func(Packet) (Packet, bool)
+	stop      func()
+
+	mu    sync.Mutex
+	fatal error
+}
+
+// Dispatch forwards a bundle.
+func (f *forwarder) Dispatch(bundle Bundle, exactMatch bool) error {
+	return f.forward(bundle)
+}
+
+// Invoke forwards a message.
+func (f *forwarder) Invoke(msg Message, exactMatch bool) error {
+	return f.forward(msg)
+}
+
+// forward sends p to the peer of to, if transform lets it.
+// If to is closed forwarding stops.
+func (f *forwarder) forward(p Packet) error {
+	p, ok := f.transform(p)
+	if !ok || p == nil {
+		return nil
+	}
+	err := f.to.Send(p)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		f.mu.Lock()
+		if f.fatal == nil {
+			f.fatal = fmt.Errorf("forward: %w", err)
+		}
+		f.mu.Unlock()
+		f.stop()
+	}
+	return fmt.Errorf("forward: %w", err)
+}
+
+// fatalError returns the error that stopped forwarding, if any.
+func (f *forwarder) fatalError() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fatal
+}
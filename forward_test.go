@@ -0,0 +1,139 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForward(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := DialUDP("udp", nil, sink.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, from.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+	defer func() { _ = sink.Close() }()   // Best effort.
+	defer func() { _ = from.Close() }()   // Best effort.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	packets, err := sink.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- Forward(ctx, from, to, func(p Packet) bool {
+			msg, ok := p.(Message)
+			return !ok || strings.HasPrefix(msg.Address, "/synth/")
+		})
+	}()
+	bundle := Bundle{Timetag: Immediately, Packets: []Packet{
+		MustMessage("/mixer/1", int32(1)),
+		MustMessage("/synth/2", int32(2)),
+	}}
+	sent := []Packet{
+		MustMessage("/mixer/1", int32(0)),
+		MustMessage("/synth/1", "freq", float32(440)),
+		bundle,
+		MustMessage("/synth/3"),
+	}
+	time.Sleep(50 * time.Millisecond) // Let Forward start serving.
+	for _, p := range sent {
+		if err := client.Send(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, expected := range []Packet{sent[1], bundle, sent[3]} {
+		select {
+		case incoming := <-packets:
+			if incoming.Err != nil {
+				t.Fatal(incoming.Err)
+			}
+			if !incoming.Packet.Equal(expected) {
+				t.Fatalf("(packet %d) expected %v, got %v", i, expected, incoming.Packet)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(packet %d) timeout", i)
+		}
+	}
+	// Forwarding stops once to is closed.
+	if err := to.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(MustMessage("/synth/4")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("expected net.ErrClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Forward to return")
+	}
+}
+
+func TestForwardTransform(t *testing.T) {
+	src1, src2 := Pipe()
+	dst1, dst2 := Pipe()
+	defer func() { _ = src1.Close() }() // Best effort.
+	defer func() { _ = dst2.Close() }() // Best effort.
+
+	forwardErrs := make(chan error, 1)
+	src2.SetErrorHandler(func(err error) {
+		forwardErrs <- err
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- ForwardTransform(ctx, src2, dst1, func(p Packet) (Packet, bool) {
+			msg := p.(Message)
+			if msg.Address == "/invalid" {
+				return Message{Address: "invalid"}, true
+			}
+			msg.Address = "/bridged" + msg.Address
+			return msg, true
+		})
+	}()
+	packets, err := dst2.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []string{"/invalid", "/a"} {
+		if err := src1.Send(MustMessage(addr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The packet that can not be sent is reported and the next one is forwarded.
+	if err := <-forwardErrs; !errors.As(err, new(*MethodError)) {
+		t.Fatalf("expected a *MethodError, got %v", err)
+	}
+	if incoming := <-packets; incoming.Packet.(Message).Address != "/bridged/a" {
+		t.Fatalf("expected /bridged/a, got %v", incoming.Packet)
+	}
+	cancel()
+	if err := <-errChan; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
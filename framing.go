@@ -0,0 +1,219 @@
+package osc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SLIP special characters, see RFC 1055.
+const (
+	slipEnd    byte = 0xC0
+	slipEsc    byte = 0xDB
+	slipEscEnd byte = 0xDC
+	slipEscEsc byte = 0xDD
+)
+
+// Common errors.
+var (
+	ErrInvalidFraming  = errors.New("invalid framing")
+	ErrPacketTooLarge  = errors.New("packet too large")
+	ErrPacketTruncated = errors.New("datagram truncated by the read buffer")
+	ErrSLIPEscape      = errors.New("invalid SLIP escape sequence")
+)
+
+// Framing is the way packets are delimited on a stream.
+type Framing int
+
+// Framings.
+const (
+	// LengthPrefix prefixes every packet with its size as a 4-byte big-endian integer.
+	// This is the framing described by OSC 1.0.
+	LengthPrefix Framing = iota
+
+	// SLIP delimits packets with the END byte of RFC 1055 and escapes
+	// any END or ESC bytes inside them. This is the framing described by OSC 1.1.
+	// Packets are written with an END on both sides and empty packets
+	// are skipped when reading, so repeated END bytes are harmless.
+	SLIP
+)
+
+// String returns the name of the framing.
+func (f Framing) String() string {
+	switch f {
+	case LengthPrefix:
+		return "length-prefix"
+	case SLIP:
+		return "SLIP"
+	default:
+		return "unknown framing"
+	}
+}
+
+// WithFraming sets the framing used by stream-oriented connections.
+// The default is LengthPrefix.
+func WithFraming(f Framing) Option {
+	return func(o *options) error {
+		if f != LengthPrefix && f != SLIP {
+			return fmt.Errorf("framing %d: %w", f, ErrInvalidFraming)
+		}
+		o.framing = f
+		return nil
+	}
+}
+
+// read reads a single packet from r into data.
+// Packets larger than maxSize are rejected.
+// It returns io.EOF if r ends between packets and
+// io.ErrUnexpectedEOF if r ends in the middle of a packet.
+func (f Framing) read(r *bufio.Reader, data []byte, maxSize int) (int, error) {
+	if maxSize > len(data) {
+		maxSize = len(data)
+	}
+	if f == SLIP {
+		return readSLIP(r, data, maxSize)
+	}
+	return readLengthPrefixed(r, data, maxSize)
+}
+
+// write writes a single packet to w with a single call to Write,
+// so that concurrent writers never interleave their packets.
+func (f Framing) write(w io.Writer, data []byte) error {
+	_, err := w.Write(f.frame(data))
+	return err
+}
+
+// frame returns data framed in a new slice.
+func (f Framing) frame(data []byte) []byte {
+	if f == SLIP {
+		return encodeSLIP(data)
+	}
+	return encodeLengthPrefixed(data)
+}
+
+// ReadPacket reads a single packet from r that is prefixed with its size,
+// as written by WritePacket or sent on a stream with LengthPrefix framing.
+// Packets larger than maxSize are rejected with ErrPacketTooLarge.
+// It returns io.EOF if r ends between packets and io.ErrUnexpectedEOF
+// if r ends in the middle of a packet.
+// The returned packet does not share any memory with the data read from r.
+func ReadPacket(r io.Reader, maxSize int) (Packet, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	size := int64(byteOrder.Uint32(prefix[:]))
+	if size > int64(maxSize) {
+		return nil, fmt.Errorf("size prefix %d exceeds limit %d: %w", size, maxSize, ErrPacketTooLarge)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return parsePacket(data, nil, nil)
+}
+
+// WritePacket writes p to w prefixed with its size, so that ReadPacket can read it back.
+// The size and the packet are written with a single call to Write.
+// It returns the number of bytes written, which includes the size prefix.
+// Errors are reported the same way as for Message.WriteTo.
+func WritePacket(w io.Writer, p Packet) (int64, error) {
+	size, err := packetSize(p)
+	if err != nil {
+		return 0, err
+	}
+	data := appendPacket(appendUint32(make([]byte, 0, 4+size), uint32(size)), p)
+	return writeEncoded(w, data)
+}
+
+// readLengthPrefixed reads a size-prefixed packet from r into data.
+func readLengthPrefixed(r io.Reader, data []byte, maxSize int) (int, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, err
+	}
+	size := int64(byteOrder.Uint32(prefix[:]))
+	if size > int64(maxSize) {
+		return 0, fmt.Errorf("size prefix %d exceeds limit %d: %w", size, maxSize, ErrPacketTooLarge)
+	}
+	if _, err := io.ReadFull(r, data[:size]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	return int(size), nil
+}
+
+// encodeLengthPrefixed returns data prefixed with its size.
+func encodeLengthPrefixed(data []byte) []byte {
+	frame := make([]byte, 4, 4+len(data))
+	byteOrder.PutUint32(frame, uint32(len(data)))
+	return append(frame, data...)
+}
+
+// readSLIP reads a SLIP-encoded packet from r into data.
+func readSLIP(r *bufio.Reader, data []byte, maxSize int) (int, error) {
+	var (
+		n       int
+		escaped bool
+	)
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && (n > 0 || escaped) {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if escaped {
+			switch c {
+			case slipEscEnd:
+				c = slipEnd
+			case slipEscEsc:
+				c = slipEsc
+			default:
+				return 0, fmt.Errorf("ESC followed by 0x%02X: %w", c, ErrSLIPEscape)
+			}
+			escaped = false
+		} else {
+			switch c {
+			case slipEnd:
+				if n == 0 {
+					// Either the leading END of a double-END packet or an empty packet.
+					continue
+				}
+				return n, nil
+			case slipEsc:
+				escaped = true
+				continue
+			}
+		}
+		if n == maxSize {
+			return 0, fmt.Errorf("SLIP packet exceeds limit %d: %w", maxSize, ErrPacketTooLarge)
+		}
+		data[n] = c
+		n++
+	}
+}
+
+// encodeSLIP returns data escaped and delimited with SLIP END bytes.
+func encodeSLIP(data []byte) []byte {
+	frame := make([]byte, 0, len(data)+2)
+	frame = append(frame, slipEnd)
+	for _, c := range data {
+		switch c {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, c)
+		}
+	}
+	return append(frame, slipEnd)
+}
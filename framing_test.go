@@ -0,0 +1,226 @@
+package osc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestFramingRead(t *testing.T) {
+	for i, testcase := range []struct {
+		Framing  Framing
+		Input    []byte
+		Expected [][]byte
+		Err      error
+	}{
+		{
+			Framing: LengthPrefix,
+			Input:   []byte{},
+			Err:     io.EOF,
+		},
+		{
+			Framing: LengthPrefix,
+			Input:   []byte{0, 0},
+			Err:     io.ErrUnexpectedEOF,
+		},
+		{
+			Framing: LengthPrefix,
+			Input:   []byte{0, 0, 0, 4, 'a'},
+			Err:     io.ErrUnexpectedEOF,
+		},
+		{
+			Framing: LengthPrefix,
+			Input:   []byte{0, 0, 0, 9},
+			Err:     ErrPacketTooLarge,
+		},
+		{
+			Framing:  LengthPrefix,
+			Input:    []byte{0, 0, 0, 4, 'a', 'b', 'c', 0, 0, 0, 0, 4, 'd', 'e', 'f', 0},
+			Expected: [][]byte{{'a', 'b', 'c', 0}, {'d', 'e', 'f', 0}},
+		},
+		{
+			Framing:  SLIP,
+			Input:    []byte{slipEnd, 'a', 'b', slipEnd},
+			Expected: [][]byte{{'a', 'b'}},
+		},
+		{
+			// No leading END, and a sloppy sender that repeats END.
+			Framing:  SLIP,
+			Input:    []byte{'a', slipEnd, slipEnd, slipEnd, 'b', slipEnd, slipEnd},
+			Expected: [][]byte{{'a'}, {'b'}},
+		},
+		{
+			Framing:  SLIP,
+			Input:    []byte{slipEnd, slipEsc, slipEscEnd, 'x', slipEsc, slipEscEsc, slipEnd},
+			Expected: [][]byte{{slipEnd, 'x', slipEsc}},
+		},
+		{
+			Framing: SLIP,
+			Input:   []byte{slipEnd, slipEnd},
+			Err:     io.EOF,
+		},
+		{
+			Framing: SLIP,
+			Input:   []byte{slipEnd, 'a', 'b'},
+			Err:     io.ErrUnexpectedEOF,
+		},
+		{
+			Framing: SLIP,
+			Input:   []byte{slipEnd, 'a', slipEsc},
+			Err:     io.ErrUnexpectedEOF,
+		},
+		{
+			Framing: SLIP,
+			Input:   []byte{slipEnd, slipEsc, 'a', slipEnd},
+			Err:     ErrSLIPEscape,
+		},
+		{
+			Framing: SLIP,
+			Input:   []byte{slipEnd, 1, 2, 3, 4, 5, 6, 7, 8, 9, slipEnd},
+			Err:     ErrPacketTooLarge,
+		},
+	} {
+		var (
+			r    = bufio.NewReader(bytes.NewReader(testcase.Input))
+			data = make([]byte, 8)
+		)
+		for _, expected := range testcase.Expected {
+			n, err := testcase.Framing.read(r, data, 8)
+			if err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+			if got := data[:n]; !bytes.Equal(expected, got) {
+				t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+			}
+		}
+		expectedErr := testcase.Err
+		if expectedErr == nil {
+			expectedErr = io.EOF
+		}
+		if _, err := testcase.Framing.read(r, data, 8); !errors.Is(err, expectedErr) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, expectedErr, err)
+		}
+	}
+}
+
+func TestEncodeSLIP(t *testing.T) {
+	var (
+		input    = []byte{'a', slipEnd, slipEsc, 'b'}
+		expected = []byte{slipEnd, 'a', slipEsc, slipEscEnd, slipEsc, slipEscEsc, 'b', slipEnd}
+	)
+	if got := encodeSLIP(input); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestWithFraming(t *testing.T) {
+	if _, err := newOptions([]Option{WithFraming(Framing(42))}); !errors.Is(err, ErrInvalidFraming) {
+		t.Fatalf("expected ErrInvalidFraming, got %v", err)
+	}
+	for f, expected := range map[Framing]string{
+		LengthPrefix: "length-prefix",
+		SLIP:         "SLIP",
+		Framing(42):  "unknown framing",
+	} {
+		if got := f.String(); expected != got {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestTCPConnSLIP(t *testing.T) {
+	var server *TCPConn
+	msgs := make(chan Message)
+	server, errChan := testTCPServer(t, PatternMatching{
+		"/blob": Method(func(msg Message) error {
+			msgs <- msg
+			return server.SendTo(msg.Sender, msg)
+		}),
+	}, WithFraming(SLIP))
+	defer func() { _ = server.Close() }() // Best effort.
+
+	raddr, err := net.ResolveTCPAddr("tcp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialTCP("tcp", nil, raddr, WithFraming(SLIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	echoes := make(chan Message)
+	go func() {
+		_ = client.Serve(1, PatternMatching{
+			"/blob": Method(func(msg Message) error {
+				echoes <- msg
+				return nil
+			}),
+		})
+	}()
+	expected := Message{
+		Address: "/blob",
+		Arguments: Arguments{
+			Blob{slipEnd, slipEsc, slipEnd, slipEnd},
+			Blob{slipEsc, slipEscEnd, slipEnd, slipEscEsc},
+			Int(0xC0DB),
+		},
+	}
+	if err := client.Send(expected); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMessage(t, msgs, errChan); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if got := waitMessage(t, echoes, errChan); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestReadWritePacket(t *testing.T) {
+	packets := []Packet{
+		MustMessage("/a", 1, "two", []byte("four")),
+		Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/b")}},
+	}
+	var buf bytes.Buffer
+	for i, p := range packets {
+		n, err := WritePacket(&buf, p)
+		if err != nil {
+			t.Fatalf("(packet %d) %s", i, err)
+		}
+		if expected, got := int64(4+len(p.Bytes())), n; expected != got {
+			t.Fatalf("(packet %d) expected %d bytes written, got %d", i, expected, got)
+		}
+	}
+	for i, expected := range packets {
+		got, err := ReadPacket(&buf, 1024)
+		if err != nil {
+			t.Fatalf("(packet %d) %s", i, err)
+		}
+		if !expected.Equal(got) {
+			t.Fatalf("(packet %d) expected %s, got %s", i, expected, got)
+		}
+	}
+	if _, err := ReadPacket(&buf, 1024); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestReadPacketErrors(t *testing.T) {
+	for i, testcase := range []struct {
+		Input []byte
+		Cause error
+	}{
+		{Input: []byte{0, 0}, Cause: io.ErrUnexpectedEOF},
+		{Input: []byte{0, 0, 0, 8, '/', 'a', 0, 0}, Cause: io.ErrUnexpectedEOF},
+		{Input: []byte{0, 0, 0, 9}, Cause: ErrPacketTooLarge},
+		{Input: []byte{0, 0, 0, 4, 'x', 0, 0, 0}, Cause: ErrParse},
+	} {
+		if _, err := ReadPacket(bytes.NewReader(testcase.Input), 8); !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// parseAllocFactor bounds the bytes that parsing a packet may allocate,
+// as a multiple of the size of the packet, on top of a slack for the packet itself.
+// The packet that allocates the most for its size is a message of empty arrays:
+// every array takes two bytes of the typetags, "[]", and costs an interface
+// in the slice of arguments and its boxed slice header, 16 and 24 bytes, twice,
+// as ParsePacket clones the message it parsed. That is 40 bytes for every byte,
+// and the copies of the address and the typetags make it about 45.
+const (
+	parseAllocFactor = 48
+	parseAllocSlack  = 1 << 10
+)
+
+// fuzzAllocSlack is the slack of the fuzz targets, which is generous,
+// because the instrumentation of the fuzzer makes more values escape.
+const fuzzAllocSlack = 16 << 10
+
+// fuzzMessages are the seed messages of the fuzz targets, which have every typetag.
+func fuzzMessages() []Message {
+	return []Message{
+		MustMessage("/ping"),
+		MustMessage("/synth/1/freq", Int(440), Float(0.5), String("saw"), Blob{1, 2, 3}),
+		MustMessage("/all", Int64(-1), Double(0.25), Bool(true), Bool(false), Nil{}, Infinitum{}),
+		MustMessage("/more", Symbol("sym"), Char('x'), RGBA{R: 1, G: 2, B: 3, A: 4}, MIDI{Port: 1, Status: 0x90, Data1: 60, Data2: 127}, Timetag(1)),
+		MustMessage("/arrays", Array{Int(1), Array{String("nested"), Float(2)}}, Array{}),
+		MustMessage("/s*/[1-3]/{freq,amp}", Blob{}),
+	}
+}
+
+// fuzzBundles are the seed bundles of the fuzz targets.
+func fuzzBundles() []Bundle {
+	msgs := fuzzMessages()
+	return []Bundle{
+		{Timetag: Immediately},
+		{Timetag: Immediately, Packets: []Packet{msgs[0], msgs[1]}},
+		{Timetag: Timetag(1 << 32), Packets: []Packet{
+			msgs[2],
+			Bundle{Timetag: Immediately, Packets: []Packet{msgs[3], Bundle{Timetag: Immediately, Packets: []Packet{msgs[4]}}}},
+		}},
+	}
+}
+
+// addTruncations adds data and every prefix of it to the corpus of f.
+func addTruncations(f *testing.F, data []byte) {
+	for i := 0; i <= len(data); i++ {
+		f.Add(data[:i])
+	}
+}
+
+// checkParseAllocs fails t if parse allocates more than parseAllocFactor times
+// the size of data plus slack. The allocations of the whole process are counted,
+// so the fewest of a few runs is what parse allocated itself.
+func checkParseAllocs(t *testing.T, data []byte, slack int, parse func()) {
+	t.Helper()
+
+	var fewest uint64
+	for i := 0; i < 3; i++ {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		parse()
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; i == 0 || allocated < fewest {
+			fewest = allocated
+		}
+	}
+	if fewest > uint64(parseAllocFactor*len(data)+slack) {
+		t.Fatalf("parsing %d bytes allocated %d bytes", len(data), fewest)
+	}
+}
+
+func FuzzParseMessage(f *testing.F) {
+	for _, msg := range fuzzMessages() {
+		addTruncations(f, msg.Bytes())
+	}
+	for _, data := range fuzzRegressions {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var (
+			msg  Message
+			view Message
+			err  error
+		)
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { msg, err = ParseMessage(data, nil) })
+		if err == nil {
+			_ = msg.String()
+		}
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { view, err = parseMessageView(data, nil) })
+		if err == nil {
+			_ = view.String()
+		}
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { _, _ = LenientParsing.ParsePacket(data) })
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { _, _ = StrictParsing.ParsePacket(data) })
+	})
+}
+
+func FuzzParseBundle(f *testing.F) {
+	for _, b := range fuzzBundles() {
+		addTruncations(f, b.Bytes())
+	}
+	for _, data := range fuzzRegressions {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var (
+			b   Bundle
+			err error
+		)
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { b, err = ParseBundle(data, nil) })
+		if err == nil {
+			_ = b.Bytes()
+		}
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { _, _ = LenientParsing.ParsePacket(data) })
+		checkParseAllocs(t, data, fuzzAllocSlack, func() { _, _ = StrictParsing.ParsePacket(data) })
+	})
+}
+
+// fuzzRegressions are packets that the parser had problems with.
+var fuzzRegressions = [][]byte{
+	// A blob length that is larger than the packet.
+	[]byte("/b\x00\x00,b\x00\x00\x7f\xff\xff\xffabc\x00"),
+	// A negative blob length.
+	[]byte("/b\x00\x00,b\x00\x00\xff\xff\xff\xfc"),
+	// Arrays nested once for every byte of the typetags.
+	append(append([]byte("/a\x00\x00,"), bytes.Repeat([]byte{'['}, 100)...), bytes.Repeat([]byte{']'}, 100)...),
+	// A bundle element size that points past the end of the bundle.
+	[]byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x01\x00/a\x00\x00,\x00\x00\x00"),
+	// A negative bundle element size.
+	[]byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\xff\xff\xff\xf8/a\x00\x00,\x00\x00\x00"),
+	// A message whose typetags, which do not start with the prefix, look like a pattern.
+	[]byte("/*\x00\x00**\x00\x00*s"),
+	// An address pattern with a star for every other byte and no typetags,
+	// which lenient parsing accepts after checking the pattern.
+	append([]byte("/"), bytes.Repeat([]byte("0*"), 39)...),
+}
+
+func TestParseRegressions(t *testing.T) {
+	for i, expected := range []error{
+		io.ErrUnexpectedEOF,
+		nil,
+		ErrInvalidTypeTag,
+		nil,
+		nil,
+		ErrInvalidTypeTag,
+		io.ErrUnexpectedEOF,
+	} {
+		var (
+			data = fuzzRegressions[i]
+			err  error
+			pe   *ParseError
+		)
+		checkParseAllocs(t, data, parseAllocSlack, func() { _, err = ParsePacket(data) })
+		checkParseAllocs(t, data, parseAllocSlack, func() { _, _ = LenientParsing.ParsePacket(data) })
+		if !errors.As(err, &pe) || expected != nil && !errors.Is(err, expected) {
+			t.Fatalf("(testcase %d) expected a *ParseError wrapping %v, got %v", i, expected, err)
+		}
+		if data[0] == MessageChar {
+			if _, err := parseMessageView(data, nil); err == nil {
+				t.Fatalf("(testcase %d) expected the view to fail too", i)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+)
+
+// sendHook and recvHook are the types the hooks are stored as, so that
+// atomic.Value always holds the same type, even for a nil hook.
+type (
+	sendHook func(addr net.Addr, p Packet, n int, err error)
+	recvHook func(addr net.Addr, data []byte)
+)
+
+// hooks holds the hooks of a conn, which can be swapped at any time.
+type hooks struct {
+	send    atomic.Value // sendHook
+	recv    atomic.Value // recvHook
+	metrics atomic.Value // metricsHolder
+	trace   atomic.Value // traceFunc
+}
+
+// SetSendHook makes the conn call hook after it has sent a packet, or failed to,
+// with the address it was sent to, the packet, the number of bytes of the encoded
+// packet that were written and the error, if any. The address is nil for a
+// connected conn that has no remote address.
+// hook is called by the goroutine that sends, so it must be safe for concurrent use
+// and it should return quickly. It may be set, replaced or removed with nil at any time.
+func (s *connState) SetSendHook(hook func(addr net.Addr, p Packet, n int, err error)) {
+	s.hooks.send.Store(sendHook(hook))
+}
+
+// SetRecvHook makes the conn call hook with every packet it reads, before it is parsed,
+// and with the address it came from. data must not be modified or kept after hook returns.
+// hook is called by the goroutine that reads, so it should return quickly.
+// It may be set, replaced or removed with nil at any time.
+func (s *connState) SetRecvHook(hook func(addr net.Addr, data []byte)) {
+	s.hooks.recv.Store(recvHook(hook))
+}
+
+// send sends p to addr with the send buffer and write, and calls the send hook.
+func (s *connState) send(addr net.Addr, p Packet, write func(data []byte) error) error {
+	n, err := s.sendBuf.send(context.Background(), p, write)
+	s.sent(addr, p, n, err)
+	return err
+}
+
+// sendContext is send with the deadline and the cancellation of ctx applied to the writes of c.
+func (s *connState) sendContext(ctx context.Context, c deadliner, addr net.Addr, p Packet, write func(data []byte) error) error {
+	n, err := s.sendBuf.send(ctx, p, func(data []byte) error {
+		return writeContext(ctx, c, func() error { return write(data) })
+	})
+	err = writeTimeoutError(ctx, err)
+	s.sent(addr, p, n, err)
+	return err
+}
+
+// sent counts a packet that was sent, and calls the send hook, if there is one.
+func (s *connState) sent(addr net.Addr, p Packet, n int, err error) {
+	s.countSent(n, err)
+	if hook, _ := s.hooks.send.Load().(sendHook); hook != nil {
+		hook(addr, p, n, err)
+	}
+}
+
+// received counts a packet that was read, and calls the receive hook, if there is one.
+func (s *connState) received(addr net.Addr, data []byte) {
+	s.countReceived(len(data))
+	if hook, _ := s.hooks.recv.Load().(recvHook); hook != nil {
+		hook(addr, data)
+	}
+}
@@ -0,0 +1,140 @@
+package osc
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSendHook(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	type sent struct {
+		addr net.Addr
+		p    Packet
+		n    int
+		err  error
+	}
+	var (
+		mu    sync.Mutex
+		sends []sent
+	)
+	hook := func(addr net.Addr, p Packet, n int, err error) {
+		mu.Lock()
+		sends = append(sends, sent{addr: addr, p: p, n: n, err: err})
+		mu.Unlock()
+	}
+	client.SetSendHook(hook)
+	server.SetSendHook(hook)
+
+	msg := MustMessage("/status")
+	if err := client.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.SendTo(client.LocalAddr(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(Message{Address: "status"}); err == nil {
+		t.Fatal("expected an error for an invalid message")
+	}
+	if err := client.SendTo(client.LocalAddr(), msg); err == nil {
+		t.Fatal("expected an error sending to another address from a connected conn")
+	}
+	client.SetSendHook(nil)
+	if err := client.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	n := len(msg.Bytes())
+	for i, expected := range []sent{
+		{addr: server.LocalAddr(), n: n},
+		{addr: client.LocalAddr(), n: n},
+		{addr: server.LocalAddr()},
+		{addr: client.LocalAddr()},
+	} {
+		if i >= len(sends) {
+			t.Fatalf("expected %d sends, got %d", 4, len(sends))
+		}
+		got := sends[i]
+		if got.addr.String() != expected.addr.String() || got.n != expected.n || (got.err == nil) != (expected.n > 0) {
+			t.Fatalf("(testcase %d) expected %d bytes to %s, got %d bytes to %s (%v)", i, expected.n, expected.addr, got.n, got.addr, got.err)
+		}
+	}
+	if len(sends) != 4 {
+		t.Fatalf("expected 4 sends, got %d", len(sends))
+	}
+}
+
+// Run with -race.
+func TestRecvHook(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	raw := make(chan string, 8)
+	c2.SetRecvHook(func(addr net.Addr, data []byte) {
+		if addr.String() != c1.LocalAddr().String() {
+			t.Errorf("expected %s, got %s", c1.LocalAddr(), addr)
+		}
+		select {
+		case raw <- string(data):
+		default:
+		}
+	})
+	go func() {
+		_ = c2.Serve(1, PatternMatching{})
+	}()
+	msg := MustMessage("/a", int32(1))
+	if err := c1.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := c1.SendRaw([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []string{string(msg.Bytes()), "garbage"} {
+		select {
+		case got := <-raw:
+			if got != expected {
+				t.Fatalf("(packet %d) expected %q, got %q", i, expected, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(packet %d) timeout", i)
+		}
+	}
+	// The hooks can be swapped while packets are sent and read.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c2.SetRecvHook(nil)
+			c2.SetRecvHook(func(addr net.Addr, data []byte) {})
+			c1.SetSendHook(func(addr net.Addr, p Packet, n int, err error) {})
+			c1.SetSendHook(nil)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := c1.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
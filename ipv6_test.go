@@ -0,0 +1,168 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// skipWithoutIPv6 skips t if the IPv6 loopback address can not be listened on.
+func skipWithoutIPv6(t *testing.T) {
+	t.Helper()
+
+	c, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skipf("IPv6 is not available: %s", err)
+	}
+	_ = c.Close() // Best effort.
+}
+
+// pongServer returns a server that answers every /ping with two /pongs to its sender,
+// one sent with SendTo and one with SendBatchTo.
+func pongServer(t *testing.T, addr string, opts ...Option) (*UDPConn, chan error) {
+	server, err := Listen(addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{"/ping": Method(func(msg Message) error {
+			if err := server.SendTo(msg.Sender, MustMessage("/pong")); err != nil {
+				return err
+			}
+			return server.SendBatchTo(msg.Sender, MustMessage("/pong"))
+		})})
+	}()
+	return server, errChan
+}
+
+// pingPong sends a /ping from client to addr and waits for both /pongs.
+func pingPong(t *testing.T, client *UDPConn, addr net.Addr, serverErrs chan error) Message {
+	t.Helper()
+
+	if err := client.SendTo(addr, MustMessage("/ping")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	select {
+	case err := <-serverErrs:
+		t.Fatalf("server stopped: %v", err)
+	default:
+	}
+	var msg Message
+	for i := 0; i < 2; i++ {
+		p, _, err := client.ReceivePacket(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ok bool
+		if msg, ok = p.(Message); !ok || msg.Address != "/pong" {
+			t.Fatalf("expected /pong, got %v", p)
+		}
+	}
+	return msg
+}
+
+func TestUDPConn_IPv6Loopback(t *testing.T) {
+	skipWithoutIPv6(t)
+
+	server, errChan := pongServer(t, "udp6://[::1]:0")
+	defer func() { _ = server.Close() }() // Best effort.
+
+	// The addresses of the conns are resolved back to themselves.
+	client, err := Dial(server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	for _, addr := range []net.Addr{server.LocalAddr(), client.RemoteAddr(), client.LocalAddr()} {
+		resolved, err := net.ResolveUDPAddr("udp6", addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameUDPAddr(resolved, addr.(*net.UDPAddr)) {
+			t.Fatalf("expected %s to resolve to itself, got %s", addr, resolved)
+		}
+	}
+	msg := pingPong(t, client, client.RemoteAddr(), errChan)
+	if expected, got := server.LocalAddr().String(), msg.Sender.String(); expected != got {
+		t.Fatalf("expected a pong from %s, got one from %s", expected, got)
+	}
+}
+
+func TestUDPConn_DualStack(t *testing.T) {
+	skipWithoutIPv6(t)
+
+	for i, opts := range [][]Option{
+		nil,
+		{WithBatchSize(8)},
+	} {
+		server, errChan := pongServer(t, ":0", opts...)
+		port := server.LocalAddr().(*net.UDPAddr).Port
+
+		// The server replies to the IPv4 client at its IPv4-mapped address.
+		for _, testcase := range []struct {
+			Network string
+			IP      net.IP
+		}{
+			{Network: "udp4", IP: net.IPv4(127, 0, 0, 1)},
+			{Network: "udp6", IP: net.IPv6loopback},
+		} {
+			client, err := ListenUDP(testcase.Network, &net.UDPAddr{IP: testcase.IP}, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := pingPong(t, client, &net.UDPAddr{IP: testcase.IP, Port: port}, errChan)
+			if sender := msg.Sender.(*net.UDPAddr); !sender.IP.Equal(testcase.IP) || sender.Port != port {
+				t.Fatalf("(testcase %d) expected a pong from %s port %d, got one from %s", i, testcase.IP, port, sender)
+			}
+			_ = client.Close() // Best effort.
+		}
+		_ = server.Close() // Best effort.
+	}
+}
+
+func TestUDPAddr_Zone(t *testing.T) {
+	for i, addr := range []*net.UDPAddr{
+		{IP: net.ParseIP("fe80::1"), Port: 9000, Zone: "en0"},
+		{IP: net.ParseIP("fe80::1"), Port: 9000, Zone: "eth1"},
+		{IP: net.ParseIP("fe80::1"), Port: 9000},
+		{IP: net.ParseIP("::1"), Port: 9000},
+		{IP: net.IPv4(127, 0, 0, 1), Port: 9000},
+	} {
+		// SendTo gets the same address from a net.Addr that is only its string.
+		resolved, err := udpAddr(hostPortAddr(addr.String()))
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !sameUDPAddr(resolved, addr) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, addr, resolved)
+		}
+		// The address strings of Dial and Listen keep the zone too.
+		network, hostport, err := splitNetwork("udp://" + addr.String())
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if resolved, err = (options{}).resolveUDP(context.Background(), network, hostport); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !sameUDPAddr(resolved, addr) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, addr, resolved)
+		}
+	}
+	// The same link-local address on two interfaces is two sources.
+	var (
+		en0 = &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 1, Zone: "en0"}
+		en1 = &net.UDPAddr{IP: net.ParseIP("fe80::1"), Port: 2, Zone: "en1"}
+	)
+	if sourceKey(en0) == sourceKey(en1) {
+		t.Fatalf("expected %s and %s to be different sources", en0, en1)
+	}
+	if sameUDPAddr(en0, &net.UDPAddr{IP: en0.IP, Port: en0.Port, Zone: "en1"}) {
+		t.Fatal("expected addresses with different zones to be different")
+	}
+}
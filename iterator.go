@@ -0,0 +1,136 @@
+package osc
+
+import (
+	"fmt"
+	"io"
+)
+
+// ArgumentIterator iterates over the arguments of a message, whether they were parsed,
+// built or set by hand, and returns each of them as a Go value:
+//
+//	'i' int32      'h' int64      'f' float32    'd' float64
+//	's' string     'S' string     'c' rune       'b' []byte
+//	'T' true       'F' false      'N' nil        'I' Infinitum{}
+//	't' Timetag    'r' RGBA       'm' MIDI       '[' Arguments
+//
+// Arguments of other types, such as a RawArgument with an unknown typetag,
+// are returned as they are.
+//
+//	for it := msg.Iterator(); it.Remaining() > 0; {
+//		tt, v, err := it.Next()
+//		...
+//	}
+//
+// It decodes the arguments of a message that was read by a conn one at a time,
+// as Next is called, and boxes every value in an interface, so the methods
+// of Message such as Int32At are faster for the arguments whose types are known.
+type ArgumentIterator struct {
+	// CopyBlobs makes Next return copies of the blobs, which can be kept and modified.
+	// Otherwise the blobs share memory with the message and must not be modified.
+	CopyBlobs bool
+
+	args []Argument
+
+	// view holds the typetags and the data of a message that was read by a conn,
+	// and offset the position in its data of the next argument.
+	view   argumentView
+	offset int
+
+	// pos is the position in the typetags of the next argument, n its index, and len the number of arguments.
+	pos, n, len int
+}
+
+// Iterator returns an iterator over the arguments of the message.
+// Arrays count as a single argument.
+func (msg Message) Iterator() *ArgumentIterator {
+	if !msg.view.isSet() {
+		return &ArgumentIterator{args: msg.Arguments, len: len(msg.Arguments)}
+	}
+	it := &ArgumentIterator{view: msg.view}
+	for i := 0; i < len(msg.view.typetags); i = nextActualTypetag(msg.view.typetags, i) {
+		it.len++
+	}
+	return it
+}
+
+// Len returns the number of arguments.
+func (it *ArgumentIterator) Len() int { return it.len }
+
+// Remaining returns the number of arguments that Next has not returned yet.
+func (it *ArgumentIterator) Remaining() int { return it.len - it.n }
+
+// Reset moves the iterator back to the first argument.
+func (it *ArgumentIterator) Reset() {
+	it.pos, it.n, it.offset = 0, 0, 0
+}
+
+// Next returns the typetag and the value of the next argument,
+// or io.EOF if there are no more arguments.
+// An argument that can not be decoded, such as a RawArgument with a payload
+// that is too short, is an error, and Next moves on to the argument after it.
+func (it *ArgumentIterator) Next() (typetag byte, value interface{}, err error) {
+	if it.Remaining() == 0 {
+		return 0, nil, io.EOF
+	}
+	var arg Argument
+	if !it.view.isSet() {
+		arg = it.args[it.n]
+	} else {
+		end := nextActualTypetag(it.view.typetags, it.pos)
+		r := &argumentReader{typetags: it.view.typetags[it.pos:end], data: it.view.data[it.offset:]}
+		args, err := r.read(0)
+		if err != nil || len(args) != 1 {
+			return 0, nil, fmt.Errorf("argument %d: %w", it.n, err) // Checked by parseMessageView.
+		}
+		arg, it.pos, it.offset = args[0], end, it.offset+r.offset
+	}
+	it.n++
+
+	v, err := argumentValue(arg, it.CopyBlobs)
+	if err != nil {
+		return arg.Typetag(), nil, fmt.Errorf("argument %d: %w", it.n-1, err)
+	}
+	return arg.Typetag(), v, nil
+}
+
+// argumentValue returns the Go value of the argument a, as documented for ArgumentIterator.
+func argumentValue(a Argument, copyBlobs bool) (interface{}, error) {
+	switch a.Typetag() {
+	case TypetagInt:
+		return a.ReadInt32()
+	case TypetagInt64:
+		return a.ReadInt64()
+	case TypetagFloat:
+		return a.ReadFloat32()
+	case TypetagDouble:
+		return a.ReadFloat64()
+	case TypetagString:
+		return a.ReadString()
+	case TypetagSymbol:
+		return a.ReadSymbol()
+	case TypetagChar:
+		return a.ReadChar()
+	case TypetagBlob:
+		b, err := a.ReadBlob()
+		if err != nil || !copyBlobs {
+			return b, err
+		}
+		return append([]byte{}, b...), nil
+	case TypetagTrue, TypetagFalse:
+		return a.ReadBool()
+	case TypetagNil:
+		return nil, nil
+	case TypetagInfinitum:
+		return Infinitum{}, nil
+	case TypetagTimetag:
+		return a.ReadTimetag()
+	case TypetagRGBA:
+		return a.ReadRGBA()
+	case TypetagMIDI:
+		return a.ReadMIDI()
+	case TypetagArrayStart:
+		return a.ReadArray()
+	default:
+		return a, nil
+	}
+}
@@ -0,0 +1,109 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArgumentIterator(t *testing.T) {
+	now := FromTime(time.Now())
+	msg := MustMessage("/iterate",
+		int32(1), int64(2), float32(3), float64(4), "five", Symbol("six"), Char('7'), []byte("8888"),
+		true, false, nil, Infinitum{}, now, RGBA{R: 9}, MIDI{Port: 10}, Array{Int(11), String("12")},
+	)
+	expected := []struct {
+		Typetag byte
+		Value   interface{}
+	}{
+		{Typetag: TypetagInt, Value: int32(1)},
+		{Typetag: TypetagInt64, Value: int64(2)},
+		{Typetag: TypetagFloat, Value: float32(3)},
+		{Typetag: TypetagDouble, Value: float64(4)},
+		{Typetag: TypetagString, Value: "five"},
+		{Typetag: TypetagSymbol, Value: "six"},
+		{Typetag: TypetagChar, Value: '7'},
+		{Typetag: TypetagBlob, Value: []byte("8888")},
+		{Typetag: TypetagTrue, Value: true},
+		{Typetag: TypetagFalse, Value: false},
+		{Typetag: TypetagNil, Value: nil},
+		{Typetag: TypetagInfinitum, Value: Infinitum{}},
+		{Typetag: TypetagTimetag, Value: now},
+		{Typetag: TypetagRGBA, Value: RGBA{R: 9}},
+		{Typetag: TypetagMIDI, Value: MIDI{Port: 10}},
+		{Typetag: TypetagArrayStart, Value: Arguments{Int(11), String("12")}},
+	}
+	view, err := parseMessageView(msg.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := NewBuilder("/iterate").Argument(Int(1)).Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range []Message{msg, view} {
+		it := m.Iterator()
+		for pass := 0; pass < 2; pass++ {
+			if it.Len() != len(expected) || it.Remaining() != len(expected) {
+				t.Fatalf("(testcase %d) expected %d arguments, got %d and %d remaining", i, len(expected), it.Len(), it.Remaining())
+			}
+			for j, e := range expected {
+				tt, v, err := it.Next()
+				if err != nil {
+					t.Fatalf("(testcase %d) argument %d: %s", i, j, err)
+				}
+				if tt != e.Typetag || reflect.TypeOf(v) != reflect.TypeOf(e.Value) || !reflect.DeepEqual(v, e.Value) {
+					t.Fatalf("(testcase %d) argument %d: expected %q %#v, got %q %#v", i, j, e.Typetag, e.Value, tt, v)
+				}
+				if it.Remaining() != len(expected)-j-1 {
+					t.Fatalf("(testcase %d) argument %d: expected %d remaining, got %d", i, j, len(expected)-j-1, it.Remaining())
+				}
+			}
+			if _, _, err := it.Next(); err != io.EOF {
+				t.Fatalf("(testcase %d) expected io.EOF, got %v", i, err)
+			}
+			it.Reset()
+		}
+	}
+
+	// Builders and messages without arguments.
+	if tt, v, err := built.Iterator().Next(); err != nil || tt != TypetagInt || v != int32(1) {
+		t.Fatalf("expected 1, got %q %v (%v)", tt, v, err)
+	}
+	if it := (Message{Address: "/empty"}).Iterator(); it.Len() != 0 {
+		t.Fatalf("expected no arguments, got %d", it.Len())
+	} else if _, _, err := it.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	// Blobs are views, unless they are copied.
+	for i, copyBlobs := range []bool{false, true} {
+		data := MustMessage("/blob", []byte("abcd")).Bytes()
+		view, err := parseMessageView(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		it := view.Iterator()
+		it.CopyBlobs = copyBlobs
+		_, v, err := it.Next()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		copy(data[len(data)-4:], "wxyz")
+		if expected := map[bool]string{false: "wxyz", true: "abcd"}[copyBlobs]; !bytes.Equal(v.([]byte), []byte(expected)) {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, v)
+		}
+	}
+
+	// Arguments that can not be decoded are skipped.
+	it := (Message{Address: "/raw", Arguments: []Argument{RawArgument{Tag: TypetagInt}, Int(2)}}).Iterator()
+	if _, _, err := it.Next(); err == nil || errors.Is(err, io.EOF) && it.Remaining() == 0 {
+		t.Fatalf("expected an error, got %v", err)
+	}
+	if _, v, err := it.Next(); err != nil || v != int32(2) {
+		t.Fatalf("expected 2, got %v (%v)", v, err)
+	}
+}
@@ -0,0 +1,319 @@
+package osc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// jsonArgument is the JSON form of an argument.
+// Arguments that have no data, such as booleans, have no value.
+type jsonArgument struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// jsonMessage is the JSON form of a message.
+type jsonMessage struct {
+	Address string         `json:"address"`
+	Args    []jsonArgument `json:"args"`
+}
+
+// jsonTimetag is the JSON form of a timetag.
+// Time is for people to read, Raw is what gets decoded.
+type jsonTimetag struct {
+	Time string `json:"time"`
+	Raw  uint64 `json:"raw"`
+}
+
+// jsonBundle is the JSON form of a bundle.
+type jsonBundle struct {
+	Timetag jsonTimetag       `json:"timetag"`
+	Packets []json.RawMessage `json:"packets"`
+}
+
+// MarshalJSON encodes the message as JSON, e.g.
+//
+//	{"address":"/a","args":[{"type":"i","value":3}]}
+//
+// Blobs are base64 encoded and timetags have both a time and their raw value.
+// Floats and doubles that JSON has no number for, NaN and the infinities, are strings
+// of their IEEE 754 bits in hex, such as "0x7fc00000", so that NaN payloads survive.
+// The sender is not encoded.
+func (msg Message) MarshalJSON() ([]byte, error) {
+	args, err := marshalArguments(msg.arguments())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", msg.Address, err)
+	}
+	return json.Marshal(jsonMessage{Address: msg.Address, Args: args})
+}
+
+// UnmarshalJSON decodes a message that was encoded with MarshalJSON.
+func (msg *Message) UnmarshalJSON(data []byte) error {
+	var jm jsonMessage
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+	args, err := unmarshalArguments(jm.Args)
+	if err != nil {
+		return fmt.Errorf("%s: %w", jm.Address, err)
+	}
+	*msg = Message{Address: jm.Address, Arguments: args}
+	return nil
+}
+
+// MarshalJSON encodes the bundle as JSON, e.g.
+//
+//	{"timetag":{"time":"immediately","raw":1},"packets":[{"address":"/a","args":[]}]}
+func (b Bundle) MarshalJSON() ([]byte, error) {
+	jb := jsonBundle{
+		Timetag: marshalTimetag(b.Timetag),
+		Packets: make([]json.RawMessage, len(b.Packets)),
+	}
+	for i, p := range b.Packets {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return nil, fmt.Errorf("packet %d: %w", i, err)
+		}
+		jb.Packets[i] = data
+	}
+	return json.Marshal(jb)
+}
+
+// UnmarshalJSON decodes a bundle that was encoded with MarshalJSON.
+func (b *Bundle) UnmarshalJSON(data []byte) error {
+	var jb jsonBundle
+	if err := json.Unmarshal(data, &jb); err != nil {
+		return err
+	}
+	packets := make([]Packet, len(jb.Packets))
+	for i, raw := range jb.Packets {
+		p, err := unmarshalPacket(raw)
+		if err != nil {
+			return fmt.Errorf("packet %d: %w", i, err)
+		}
+		packets[i] = p
+	}
+	*b = Bundle{Timetag: Timetag(jb.Timetag.Raw), Packets: packets}
+	return nil
+}
+
+// unmarshalPacket decodes a message or a bundle.
+// Bundles are the objects that have packets.
+func unmarshalPacket(data []byte) (Packet, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	if _, ok := keys["packets"]; ok {
+		var b Bundle
+		err := json.Unmarshal(data, &b)
+		return b, err
+	}
+	var msg Message
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// marshalTimetag returns the JSON form of a timetag.
+func marshalTimetag(tt Timetag) jsonTimetag {
+	return jsonTimetag{Time: formatTimetag(tt), Raw: uint64(tt)}
+}
+
+// marshalArguments returns the JSON form of args.
+func marshalArguments(args []Argument) ([]jsonArgument, error) {
+	jargs := make([]jsonArgument, len(args))
+	for i, a := range args {
+		ja, err := marshalArgument(a)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		jargs[i] = ja
+	}
+	return jargs, nil
+}
+
+// marshalArgument returns the JSON form of an argument.
+func marshalArgument(a Argument) (jsonArgument, error) {
+	var v interface{}
+
+	switch x := a.(type) {
+	case Int:
+		v = int32(x)
+	case Int64:
+		v = int64(x)
+	case Float:
+		if f := float64(x); math.IsNaN(f) || math.IsInf(f, 0) {
+			v = fmt.Sprintf("0x%08x", math.Float32bits(float32(x)))
+		} else {
+			v = float32(x)
+		}
+	case Double:
+		if f := float64(x); math.IsNaN(f) || math.IsInf(f, 0) {
+			v = fmt.Sprintf("0x%016x", math.Float64bits(f))
+		} else {
+			v = f
+		}
+	case String:
+		v = string(x)
+	case Symbol:
+		v = string(x)
+	case Blob:
+		v = []byte(x)
+	case Bool, Nil, Infinitum:
+		return jsonArgument{Type: string(a.Typetag())}, nil
+	case Char:
+		v = int32(x)
+	case RGBA:
+		v = fmt.Sprintf("#%02x%02x%02x%02x", x.R, x.G, x.B, x.A)
+	case MIDI:
+		v = []int{int(x.Port), int(x.Status), int(x.Data1), int(x.Data2)}
+	case Timetag:
+		v = marshalTimetag(x)
+	case Array:
+		elems, err := marshalArguments(x)
+		if err != nil {
+			return jsonArgument{}, err
+		}
+		v = elems
+	default:
+		return jsonArgument{}, fmt.Errorf("%T: %w", a, ErrUnsupportedType)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return jsonArgument{}, err
+	}
+	return jsonArgument{Type: string(a.Typetag()), Value: data}, nil
+}
+
+// unmarshalArguments decodes the JSON form of some arguments.
+func unmarshalArguments(jargs []jsonArgument) ([]Argument, error) {
+	args := make([]Argument, len(jargs))
+	for i, ja := range jargs {
+		a, err := unmarshalArgument(ja)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		args[i] = a
+	}
+	return args, nil
+}
+
+// unmarshalArgument decodes the JSON form of an argument.
+func unmarshalArgument(ja jsonArgument) (Argument, error) {
+	if len(ja.Type) != 1 {
+		return nil, fmt.Errorf("json argument type %q: %w", ja.Type, ErrInvalidTypeTag)
+	}
+	switch tt := ja.Type[0]; tt {
+	case TypetagTrue:
+		return Bool(true), nil
+	case TypetagFalse:
+		return Bool(false), nil
+	case TypetagNil:
+		return Nil{}, nil
+	case TypetagInfinitum:
+		return Infinitum{}, nil
+	case TypetagInt:
+		var i int32
+		err := unmarshalValue(ja, &i)
+		return Int(i), err
+	case TypetagInt64:
+		var i int64
+		err := unmarshalValue(ja, &i)
+		return Int64(i), err
+	case TypetagFloat:
+		if bits, ok, err := unmarshalFloatBits(ja, 32); ok {
+			return Float(math.Float32frombits(uint32(bits))), err
+		}
+		var f float32
+		err := unmarshalValue(ja, &f)
+		return Float(f), err
+	case TypetagDouble:
+		if bits, ok, err := unmarshalFloatBits(ja, 64); ok {
+			return Double(math.Float64frombits(bits)), err
+		}
+		var f float64
+		err := unmarshalValue(ja, &f)
+		return Double(f), err
+	case TypetagString:
+		var s string
+		err := unmarshalValue(ja, &s)
+		return String(s), err
+	case TypetagSymbol:
+		var s string
+		err := unmarshalValue(ja, &s)
+		return Symbol(s), err
+	case TypetagBlob:
+		var b []byte
+		err := unmarshalValue(ja, &b)
+		return Blob(b), err
+	case TypetagChar:
+		var c int32
+		err := unmarshalValue(ja, &c)
+		return Char(c), err
+	case TypetagRGBA:
+		var (
+			s string
+			c RGBA
+		)
+		if err := unmarshalValue(ja, &s); err != nil {
+			return nil, err
+		}
+		if _, err := fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A); err != nil {
+			return nil, fmt.Errorf("rgba %q: %w", s, err)
+		}
+		return c, nil
+	case TypetagMIDI:
+		var m [4]byte
+		err := unmarshalValue(ja, &m)
+		return MIDI{Port: m[0], Status: m[1], Data1: m[2], Data2: m[3]}, err
+	case TypetagTimetag:
+		var jt jsonTimetag
+		err := unmarshalValue(ja, &jt)
+		return Timetag(jt.Raw), err
+	case TypetagArrayStart:
+		var elems []jsonArgument
+		if err := unmarshalValue(ja, &elems); err != nil {
+			return nil, err
+		}
+		a, err := unmarshalArguments(elems)
+		return Array(a), err
+	default:
+		return nil, fmt.Errorf("json argument type %q: %w", ja.Type, ErrInvalidTypeTag)
+	}
+}
+
+// unmarshalValue decodes the value of an argument into v.
+// unmarshalFloatBits decodes the IEEE 754 bits of a float of bitSize bits
+// that is NaN or infinite, which marshalArgument encodes as a string.
+// It returns false if the value is not a string.
+func unmarshalFloatBits(ja jsonArgument, bitSize int) (uint64, bool, error) {
+	if len(ja.Value) == 0 || ja.Value[0] != '"' {
+		return 0, false, nil
+	}
+	var s string
+	if err := unmarshalValue(ja, &s); err != nil {
+		return 0, true, err
+	}
+	hex, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		return 0, true, fmt.Errorf("json argument of type %q: %q is not hex bits", ja.Type, s)
+	}
+	bits, err := strconv.ParseUint(hex, 16, bitSize)
+	if err != nil {
+		return 0, true, fmt.Errorf("json argument of type %q: %w", ja.Type, err)
+	}
+	return bits, true, nil
+}
+
+func unmarshalValue(ja jsonArgument, v interface{}) error {
+	if len(ja.Value) == 0 {
+		return fmt.Errorf("json argument of type %q has no value", ja.Type)
+	}
+	if err := json.Unmarshal(ja.Value, v); err != nil {
+		return fmt.Errorf("json argument of type %q: %w", ja.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageMarshalJSON(t *testing.T) {
+	msg := MustMessage("/a", 3, "x", []byte("blob"), true, Timetag(0xBC17C20080000000))
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"address":"/a","args":[` +
+		`{"type":"i","value":3},` +
+		`{"type":"s","value":"x"},` +
+		`{"type":"b","value":"YmxvYg=="},` +
+		`{"type":"T"},` +
+		`{"type":"t","value":{"time":"2000-01-01T00:00:00.5Z","raw":13553514910998069248}}]}`
+	if got := string(data); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestMessageMarshalJSON_NonFinite(t *testing.T) {
+	msg := MustMessage("/a", float32(math.Inf(1)), math.Inf(-1), Float(math.Float32frombits(0x7fc0beef)), math.Copysign(0, -1))
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"address":"/a","args":[` +
+		`{"type":"f","value":"0x7f800000"},` +
+		`{"type":"d","value":"0xfff0000000000000"},` +
+		`{"type":"f","value":"0x7fc0beef"},` +
+		`{"type":"d","value":-0}]}`
+	if got := string(data); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	for i, msg := range []Message{
+		{Address: "/empty"},
+		MustMessage("/ints", int32(-1), int32(1<<31-1), int64(-1<<63), int64(1<<63-1)),
+		MustMessage("/floats", float32(0.1), float32(-3.4e38), 0.1, 1e-300),
+		MustMessage("/floats/nonfinite",
+			float32(math.NaN()), Float(math.Float32frombits(0x7fc0beef)), float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.Copysign(0, -1)),
+			math.NaN(), Double(math.Float64frombits(0xfff000000000beef)), math.Inf(1), math.Inf(-1), math.Copysign(0, -1),
+		),
+		MustMessage("/strings", "", "hello\nworld", Symbol(""), Symbol("sym")),
+		MustMessage("/blobs", []byte{}, []byte{0, 1, 2, 3}, []byte("1234\xff\xfe\xfd\xfc")),
+		MustMessage("/flags", true, false, nil, Infinitum{}),
+		MustMessage("/misc", Char('a'), Char(0x266A), RGBA{R: 1, G: 2, B: 0xfe, A: 0xff}, MIDI{Port: 1, Status: 0x90, Data1: 60, Data2: 127}),
+		MustMessage("/time", Immediately, FromTime(time.Date(2040, 1, 1, 0, 0, 0, 123456789, time.UTC)), Timetag(0)),
+		MustMessage("/arrays", Array{}, Array{Int(1), Array{String("a"), Array{}}}, Int(2)),
+	} {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		var got Message
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !msg.Equal(got) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, msg, got)
+		}
+		if expected, got := msg.Bytes(), got.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("(testcase %d) expected %x, got %x", i, expected, got)
+		}
+	}
+}
+
+func TestBundleJSONRoundTrip(t *testing.T) {
+	b := Bundle{
+		Timetag: FromTime(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Packets: []Packet{
+			MustMessage("/n_set", 1000, "freq", float32(440)),
+			Bundle{
+				Timetag: Immediately,
+				Packets: []Packet{MustMessage("/n_run", 1000, 1)},
+			},
+			Bundle{Timetag: Immediately, Packets: []Packet{}},
+		},
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"timetag":{"time":"2000-01-01T00:00:00Z","raw":13553514908850585600},"packets":[{"address":"/n_set"`) {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+	var got Bundle
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := b.Bytes(), got.Bytes(); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %x, got %x", expected, got)
+	}
+}
+
+func TestMessageUnmarshalJSONErrors(t *testing.T) {
+	for i, testcase := range []struct {
+		JSON     string
+		Cause    error
+		Expected string
+	}{
+		{
+			JSON:     `{"address":"/a","args":[{"type":"i","value":1},{"type":"q","value":1}]}`,
+			Cause:    ErrInvalidTypeTag,
+			Expected: `/a: argument 1: json argument type "q": invalid type tag`,
+		},
+		{
+			JSON:     `{"address":"/a","args":[{"type":"int","value":1}]}`,
+			Cause:    ErrInvalidTypeTag,
+			Expected: `/a: argument 0: json argument type "int": invalid type tag`,
+		},
+		{
+			JSON:     `{"address":"/a","args":[{"type":"[","value":[{"type":"x"}]}]}`,
+			Cause:    ErrInvalidTypeTag,
+			Expected: `/a: argument 0: argument 0: json argument type "x": invalid type tag`,
+		},
+		{
+			JSON:     `{"address":"/a","args":[{"type":"i"}]}`,
+			Expected: `/a: argument 0: json argument of type "i" has no value`,
+		},
+		{
+			JSON:     `{"address":"/a","args":[{"type":"i","value":"one"}]}`,
+			Expected: `/a: argument 0: json argument of type "i": json: cannot unmarshal string into Go value of type int32`,
+		},
+		{
+			JSON:     `{"address":"/a","args":[{"type":"f","value":"NaN"}]}`,
+			Expected: `/a: argument 0: json argument of type "f": "NaN" is not hex bits`,
+		},
+		{
+			JSON:     `{"address":"/a","args":[{"type":"f","value":"0x7ff0000000000000"}]}`,
+			Cause:    strconv.ErrRange,
+			Expected: `/a: argument 0: json argument of type "f": strconv.ParseUint: parsing "7ff0000000000000": value out of range`,
+		},
+	} {
+		var msg Message
+		err := json.Unmarshal([]byte(testcase.JSON), &msg)
+		if err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+		if testcase.Cause != nil && !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
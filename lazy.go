@@ -0,0 +1,181 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"unsafe"
+)
+
+// argumentView holds the encoded arguments of a message that is a view
+// over a read buffer. The arguments are decoded when they are read.
+type argumentView struct {
+	typetags []byte // Without the prefix. It is never nil in a view.
+	data     []byte
+}
+
+// isSet returns true if the message the view belongs to is a view.
+func (v argumentView) isSet() bool {
+	return v.typetags != nil
+}
+
+// argumentAt returns the typetag and the data of the argument at index i,
+// which must have the given typetag, or any if it is TypetagAny, with the same errors as Message.argumentAt.
+// Elements of arrays are skipped, as arrays count as a single argument.
+func (v argumentView) argumentAt(i int, typetag byte) (byte, []byte, error) {
+	var (
+		n, depth, offset int
+		found            = i >= 0
+	)
+	for _, tt := range v.typetags {
+		if depth == 0 && tt != TypetagArrayEnd {
+			if found && n == i {
+				if tt != typetag && typetag != TypetagAny && !(typetag == TypetagTrue && tt == TypetagFalse) {
+					return 0, nil, fmt.Errorf("argument %d: expected typetag %q, got %q: %w", i, typetag, tt, ErrInvalidTypeTag)
+				}
+				return tt, v.data[offset:], nil
+			}
+			n++
+		}
+		switch tt {
+		case TypetagArrayStart:
+			depth++
+		case TypetagArrayEnd:
+			depth--
+		}
+		size, _ := argumentDataSize(tt, v.data[offset:]) // Checked by parseMessageView.
+		offset += size
+	}
+	return 0, nil, fmt.Errorf("argument %d of %d: %w", i, n, ErrArgumentIndex)
+}
+
+// check returns an error if the arguments can not be read.
+func (v argumentView) check() error {
+	depth, offset := 0, 0
+	for _, tt := range v.typetags {
+		switch tt {
+		case TypetagArrayStart:
+			if depth++; depth > maxArrayDepth {
+				return ErrInvalidTypeTag
+			}
+		case TypetagArrayEnd:
+			if depth == 0 {
+				return ErrUnbalancedArray
+			}
+			depth--
+		}
+		size, err := argumentDataSize(tt, v.data[offset:])
+		if err != nil {
+			return err
+		}
+		if tt == TypetagBlob && size%4 != 0 {
+			return ErrMissingPadding
+		}
+		offset += size
+	}
+	if depth > 0 {
+		return ErrUnbalancedArray
+	}
+	return nil
+}
+
+// decode decodes all of the arguments.
+func (v argumentView) decode() []Argument {
+	args, err := newArgumentReader(v.typetags, v.data).read(0)
+	if err != nil {
+		return nil // Checked by parseMessageView.
+	}
+	return args
+}
+
+// argumentDataSize returns how many bytes at the start of data the argument
+// with typetag tt takes up. Like ParseMessage it lets the last argument
+// miss its padding.
+func argumentDataSize(tt byte, data []byte) (int, error) {
+	size := 0
+	switch tt {
+	case TypetagTrue, TypetagFalse, TypetagNil, TypetagInfinitum, TypetagArrayStart, TypetagArrayEnd:
+		return 0, nil
+	case TypetagInt, TypetagFloat:
+		size = 4
+	case TypetagInt64, TypetagDouble:
+		size = 8
+	case TypetagString, TypetagSymbol:
+		size = len(data)
+		if i := bytes.IndexByte(data, 0); i >= 0 && padded(i+1) < size {
+			size = padded(i + 1)
+		}
+		return size, nil
+	default:
+		_, n, err := ReadArgument(tt, data)
+		if err != nil {
+			return 0, err
+		}
+		if n > int64(len(data)) {
+			n = int64(len(data))
+		}
+		return int(n), nil
+	}
+	if len(data) < size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return size, nil
+}
+
+// parseMessageView parses an OSC message without copying or decoding anything:
+// the address, the typetags and the arguments are all views over data.
+// Messages that can not be parsed are passed to ParseMessage for its error.
+func parseMessageView(data []byte, sender net.Addr) (Message, error) {
+	nul := bytes.IndexByte(data, 0)
+	if nul == -1 || padded(nul+1) > len(data) {
+		return ParseMessage(data, sender)
+	}
+	offset := padded(nul + 1)
+	typetags := data[offset:]
+	if end := bytes.IndexByte(typetags, 0); end == -1 {
+		offset = len(data)
+	} else {
+		typetags = typetags[:end]
+		offset += padded(end + 1)
+		if offset > len(data) {
+			offset = len(data) // A message with no arguments may be missing its padding.
+		}
+	}
+	if len(typetags) > 0 && typetags[0] == TypetagPrefix {
+		typetags = typetags[1:]
+	}
+	view := argumentView{typetags: typetags, data: data[offset:]}
+	if err := view.check(); err != nil {
+		return ParseMessage(data, sender)
+	}
+	return Message{Address: viewString(data[:nul]), Sender: sender, view: view, raw: data[:len(data):len(data)]}, nil
+}
+
+// viewString returns b as a string that shares its memory.
+// The string changes if b does.
+func viewString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// readStringView returns the string at the start of data as a view.
+func readStringView(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return viewString(data)
+}
+
+// readFloat32 decodes the float at the start of data.
+func readFloat32(data []byte) float32 {
+	return math.Float32frombits(byteOrder.Uint32(data))
+}
+
+// readFloat64 decodes the double at the start of data.
+func readFloat64(data []byte) float64 {
+	return math.Float64frombits(byteOrder.Uint64(data))
+}
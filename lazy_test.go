@@ -0,0 +1,220 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseMessageView(t *testing.T) {
+	for i, msg := range []Message{
+		MustMessage("/a"),
+		MustMessage("/ifs", int32(3), float32(0.5), "hello"),
+		MustMessage("/b", int64(-7), 2.25, Symbol("sym"), []byte{1, 2, 3}, true, false, nil, Timetag(42)),
+		MustMessage("/arr", int32(1), Array{String("x"), Array{Int(2)}}, "after"),
+		MustMessage("/e", []byte{}, Infinitum{}, Char('c'), RGBA{R: 1}, MIDI{Port: 2}),
+	} {
+		data := msg.Bytes()
+		parsed, err := ParseMessage(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		view, err := parseMessageView(data, nil)
+		if err != nil {
+			t.Fatalf("(testcase %d) %v", i, err)
+		}
+		if !view.view.isSet() || view.Arguments != nil {
+			t.Fatalf("(testcase %d) expected a view", i)
+		}
+		if !view.Equal(parsed) || !parsed.Equal(view) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, parsed, view)
+		}
+		if expected, got := parsed.String(), view.String(); expected != got {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, expected, got)
+		}
+		if expected, got := string(parsed.Bytes()), string(view.Bytes()); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+	for i, data := range [][]byte{
+		[]byte("/a"),
+		[]byte("/a\x00\x00,i\x00\x00\x00\x00"),
+		[]byte("/a\x00\x00,[i\x00\x00\x00\x00\x01"),
+		[]byte("/a\x00\x00,x\x00\x00"),
+	} {
+		_, expected := ParseMessage(data, nil)
+		_, err := parseMessageView(data, nil)
+		if expected == nil || err == nil || expected.Error() != err.Error() {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, expected, err)
+		}
+	}
+}
+
+func TestMessageViewAccessors(t *testing.T) {
+	msg := MustMessage("/x", Array{Int(9), String("in")}, int32(3), float32(0.5), "hello", int64(4), 1.5, Symbol("sym"), []byte{7}, true, Timetag(5))
+	data := msg.Bytes()
+	view, err := parseMessageView(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, err := view.Int32At(1); err != nil || i != 3 {
+		t.Fatalf("expected 3, got %d (%v)", i, err)
+	}
+	if f, err := view.Float32At(2); err != nil || f != 0.5 {
+		t.Fatalf("expected 0.5, got %f (%v)", f, err)
+	}
+	if s, err := view.StringAt(3); err != nil || s != "hello" {
+		t.Fatalf("expected hello, got %s (%v)", s, err)
+	}
+	if i, err := view.Int64At(4); err != nil || i != 4 {
+		t.Fatalf("expected 4, got %d (%v)", i, err)
+	}
+	if f, err := view.Float64At(5); err != nil || f != 1.5 {
+		t.Fatalf("expected 1.5, got %f (%v)", f, err)
+	}
+	if s, err := view.SymbolAt(6); err != nil || s != "sym" {
+		t.Fatalf("expected sym, got %s (%v)", s, err)
+	}
+	if b, err := view.BlobAt(7); err != nil || b[0] != 7 {
+		t.Fatalf("expected [7], got %v (%v)", b, err)
+	}
+	if b, err := view.BoolAt(8); err != nil || !b {
+		t.Fatalf("expected true, got %t (%v)", b, err)
+	}
+	if tt, err := view.TimetagAt(9); err != nil || tt != 5 {
+		t.Fatalf("expected 5, got %d (%v)", tt, err)
+	}
+	for i, testcase := range []struct {
+		Index int
+		Err   error
+	}{
+		{Index: 0, Err: ErrInvalidTypeTag},
+		{Index: 2, Err: ErrInvalidTypeTag},
+		{Index: 10, Err: ErrArgumentIndex},
+		{Index: -1, Err: ErrArgumentIndex},
+	} {
+		_, expected := msg.Int32At(testcase.Index)
+		_, err := view.Int32At(testcase.Index)
+		if !errors.Is(err, testcase.Err) || err.Error() != expected.Error() {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, expected, err)
+		}
+	}
+}
+
+func TestServeLazyArguments(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		views   = make(chan Message, 2)
+		clones  = make(chan Message, 2)
+		errChan = make(chan error, 1)
+	)
+	c2.SetLazyArguments(true)
+	c2.SetPoisonBuffers(true)
+	go func() {
+		errChan <- c2.Serve(1, PatternMatching{
+			"/a": Method(func(msg Message) error {
+				if msg.Arguments != nil {
+					t.Error("expected a view")
+				}
+				views <- msg
+				clones <- msg.Clone()
+				return nil
+			}),
+		})
+	}()
+	sent := []Message{MustMessage("/a", int32(1), "first"), MustMessage("/a", int32(2), "second")}
+	for _, msg := range sent {
+		if err := c1.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, msg := range sent {
+		select {
+		case clone := <-clones:
+			if !clone.Equal(msg) {
+				t.Fatalf("(message %d) expected %s, got %s", i, msg, clone)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(message %d) timeout", i)
+		}
+	}
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	// Both buffers have been poisoned once the server has stopped.
+	for i := range sent {
+		if view := <-views; view.Address == "/a" {
+			t.Fatalf("(message %d) expected the view to be poisoned", i)
+		}
+	}
+}
+
+func TestMessageViewAllocs(t *testing.T) {
+	data := MustMessage("/synth/1", int32(3), float32(0.5), "hello").Bytes()
+	var sum float32
+	methods := PatternMatching{
+		"/synth/1": Method(func(msg Message) error {
+			i, err := msg.Int32At(0)
+			if err != nil {
+				return err
+			}
+			f, err := msg.Float32At(1)
+			if err != nil {
+				return err
+			}
+			s, err := msg.StringAt(2)
+			if err != nil {
+				return err
+			}
+			sum += float32(i) + f + float32(len(s))
+			return nil
+		}),
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		msg, err := parseMessageView(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := methods.Invoke(msg, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %f", allocs)
+	}
+	if sum != 101*8.5 {
+		t.Fatalf("expected %f, got %f", 101*8.5, sum)
+	}
+}
+
+func BenchmarkInvokeMessageView(b *testing.B) {
+	data := MustMessage("/synth/1", int32(3), float32(0.5), "hello").Bytes()
+	methods := PatternMatching{
+		"/synth/1": Method(func(msg Message) error {
+			if _, err := msg.Int32At(0); err != nil {
+				return err
+			}
+			if _, err := msg.Float32At(1); err != nil {
+				return err
+			}
+			_, err := msg.StringAt(2)
+			return err
+		}),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg, err := parseMessageView(data, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := methods.Invoke(msg, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// WithLocalAddr makes Dial send from laddr, e.g. to pin the source address that
+// a receiver which filters by source sees on a host with several addresses.
+// A zero port is chosen by the operating system.
+// DialUDP is given its local address directly, so it and Listen reject this option.
+func WithLocalAddr(laddr *net.UDPAddr) Option {
+	return func(o *options) error {
+		if laddr == nil {
+			return errors.New("local address must not be nil")
+		}
+		o.localAddr = laddr
+		return nil
+	}
+}
+
+// WithInterface makes Dial send from the address that InterfaceAddr returns
+// for the interface with the given name, in the family of the address that is dialed.
+// It can not be combined with WithLocalAddr. Listen rejects this option.
+func WithInterface(name string) Option {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("interface name must not be empty")
+		}
+		o.iface = name
+		return nil
+	}
+}
+
+// InterfaceAddr returns a local address on the interface with the given name,
+// e.g. "eth1", that UDP can be sent from: its first global unicast address,
+// or its first loopback address if it has no global unicast one.
+// The network, udp4 or udp6, restricts the family of the address, and udp allows both.
+// The port of the address is 0.
+func InterfaceAddr(name, network string) (*net.UDPAddr, error) {
+	var v4, v6 bool
+	switch network {
+	case "udp":
+		v4, v6 = true, true
+	case "udp4":
+		v4 = true
+	case "udp6":
+		v6 = true
+	default:
+		return nil, fmt.Errorf("interface %s: %w", name, net.UnknownNetworkError(network))
+	}
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("addresses of interface %s: %w", name, err)
+	}
+	var loopback net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipnet.IP
+		if is4 := ip.To4() != nil; is4 && !v4 || !is4 && !v6 {
+			continue
+		}
+		switch {
+		case ip.IsGlobalUnicast():
+			return &net.UDPAddr{IP: ip}, nil
+		case ip.IsLoopback() && loopback == nil:
+			loopback = ip
+		}
+	}
+	if loopback != nil {
+		return &net.UDPAddr{IP: loopback}, nil
+	}
+	return nil, fmt.Errorf("interface %s has no %s address to send from", name, network)
+}
+
+// localUDPAddr returns the local address that the options of Dial ask for,
+// in the family of raddr, or nil to let the operating system choose.
+func (o options) localUDPAddr(raddr *net.UDPAddr) (*net.UDPAddr, error) {
+	switch {
+	case o.localAddr != nil && o.iface != "":
+		return nil, errors.New("WithLocalAddr and WithInterface can not be combined")
+	case o.localAddr != nil:
+		return o.localAddr, nil
+	case o.iface != "":
+		network := "udp6"
+		if raddr.IP.To4() != nil {
+			network = "udp4"
+		}
+		return InterfaceAddr(o.iface, network)
+	default:
+		return nil, nil
+	}
+}
+
+// checkDialOptions returns an error if o has an option that only Dial supports.
+func (o options) checkDialOptions() error {
+	switch {
+	case o.reResolve != 0:
+		return fmt.Errorf("WithReResolve requires Dial: %w", ErrUnsupportedOption)
+	case o.localAddr != nil:
+		return fmt.Errorf("WithLocalAddr requires Dial: %w", ErrUnsupportedOption)
+	case o.iface != "":
+		return fmt.Errorf("WithInterface requires Dial: %w", ErrUnsupportedOption)
+	default:
+		return nil
+	}
+}
+
+// bindError names the interface that the local address of err came from, if there is one.
+func (o options) bindError(err error) error {
+	if o.iface == "" {
+		return err
+	}
+	return fmt.Errorf("sending from interface %s: %w", o.iface, err)
+}
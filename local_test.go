@@ -0,0 +1,139 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loopbackInterface returns the name of the loopback interface, or skips t if there is none.
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skipf("interfaces can not be listed: %s", err)
+	}
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback != 0 && ifi.Flags&net.FlagUp != 0 {
+			return ifi.Name
+		}
+	}
+	t.Skip("there is no loopback interface")
+	return ""
+}
+
+// freePort returns a UDP port on ip that nothing is bound to.
+func freePort(t *testing.T, ip net.IP) int {
+	t.Helper()
+
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	if err != nil {
+		t.Skipf("%s can not be bound to: %s", ip, err)
+	}
+	defer func() { _ = c.Close() }() // Best effort.
+	return c.LocalAddr().(*net.UDPAddr).Port
+}
+
+// sourceSeenBy sends a message from client to server and returns the sender that server reads.
+func sourceSeenBy(t *testing.T, server, client *UDPConn) *net.UDPAddr {
+	t.Helper()
+
+	if err := client.Send(MustMessage("/source")); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, sender, err := server.ReceivePacket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sender.(*net.UDPAddr)
+}
+
+func TestWithLocalAddr(t *testing.T) {
+	server, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	// Every address of 127.0.0.0/8 is on the loopback interface of most systems.
+	second := net.IPv4(127, 0, 0, 2)
+	for i, laddr := range []*net.UDPAddr{
+		{IP: net.IPv4(127, 0, 0, 1), Port: freePort(t, net.IPv4(127, 0, 0, 1))},
+		{IP: second, Port: freePort(t, second)},
+		{IP: second},
+	} {
+		client, err := Dial(server.LocalAddr().String(), WithLocalAddr(laddr))
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		sender := sourceSeenBy(t, server, client)
+		if !sender.IP.Equal(laddr.IP) || laddr.Port != 0 && sender.Port != laddr.Port {
+			t.Fatalf("(testcase %d) expected the server to see %s, got %s", i, laddr, sender)
+		}
+		if got := client.LocalAddr().(*net.UDPAddr); !sameUDPAddr(got, sender) {
+			t.Fatalf("(testcase %d) expected the local address %s, got %s", i, sender, got)
+		}
+		_ = client.Close() // Best effort.
+	}
+}
+
+func TestWithInterface(t *testing.T) {
+	name := loopbackInterface(t)
+	server, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := Dial(server.LocalAddr().String(), WithInterface(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	if sender := sourceSeenBy(t, server, client); !sender.IP.IsLoopback() || sender.IP.To4() == nil {
+		t.Fatalf("expected an IPv4 loopback source, got %s", sender)
+	}
+	// The errors name the interface.
+	if _, err := Dial(server.LocalAddr().String(), WithInterface("osc-nope0")); err == nil || !strings.Contains(err.Error(), "osc-nope0") {
+		t.Fatalf("expected an error naming osc-nope0, got %v", err)
+	}
+	if _, err := Dial(server.LocalAddr().String(), WithInterface(name), WithLocalAddr(&net.UDPAddr{})); err == nil {
+		t.Fatal("expected an error combining WithInterface and WithLocalAddr")
+	}
+	for i, opt := range []Option{WithInterface(name), WithLocalAddr(&net.UDPAddr{})} {
+		if _, err := Listen("127.0.0.1:0", opt); !errors.Is(err, ErrUnsupportedOption) {
+			t.Fatalf("(testcase %d) expected ErrUnsupportedOption, got %v", i, err)
+		}
+	}
+}
+
+func TestInterfaceAddr(t *testing.T) {
+	name := loopbackInterface(t)
+
+	addr, err := InterfaceAddr(name, "udp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !addr.IP.IsLoopback() || addr.IP.To4() == nil || addr.Port != 0 {
+		t.Fatalf("expected an IPv4 loopback address, got %s", addr)
+	}
+	for i, testcase := range []struct {
+		Name    string
+		Network string
+	}{
+		{Name: "osc-nope0", Network: "udp"},
+		{Name: name, Network: "tcp"},
+	} {
+		if _, err := InterfaceAddr(testcase.Name, testcase.Network); err == nil || !strings.Contains(err.Error(), testcase.Name) {
+			t.Fatalf("(testcase %d) expected an error naming %s, got %v", i, testcase.Name, err)
+		}
+	}
+}
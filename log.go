@@ -0,0 +1,61 @@
+package osc
+
+import (
+	"log/slog"
+	"net"
+)
+
+// The keys of the attributes that the logger of WithLogger is given.
+const (
+	// LogRemote is the address a packet came from.
+	LogRemote = "remote"
+
+	// LogAddress is the OSC address of a message.
+	LogAddress = "osc_address"
+
+	// LogTimetag is the timetag of a bundle.
+	LogTimetag = "timetag"
+
+	// LogError is an error.
+	LogError = "error"
+
+	// LogPrevious is the previous address of a peer whose address changed.
+	LogPrevious = "previous"
+)
+
+// log logs msg with the logger of the conn, which must not be nil.
+// The callers check the logger first, so that nothing is done without one.
+func (s *connState) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	s.logger.LogAttrs(s.ctx, level, msg, attrs...)
+}
+
+// countLate counts a late bundle that the scheduler dropped.
+func (s *connState) countLate(b Bundle) {
+	s.countDrop(ReasonLate)
+
+	if s.logger != nil {
+		s.log(slog.LevelWarn, "osc: late bundle dropped", remoteAttr(b.Sender), slog.Time(LogTimetag, b.Timetag.Time()))
+	}
+}
+
+// remoteAttr returns the attribute of the address a packet came from,
+// or an empty attribute, which is left out, if there is none.
+func remoteAttr(addr net.Addr) slog.Attr {
+	if addr == nil {
+		return slog.Attr{}
+	}
+	return slog.String(LogRemote, addr.String())
+}
+
+// addressAttr returns the attribute of an OSC address, or an empty attribute if it is empty.
+func addressAttr(addr string) slog.Attr {
+	if addr == "" {
+		return slog.Attr{}
+	}
+	return slog.String(LogAddress, addr)
+}
+
+// errorAttr returns the attribute of err.
+func errorAttr(err error) slog.Attr {
+	return slog.Any(LogError, err)
+}
@@ -0,0 +1,196 @@
+package osc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logEntry is a record that a recordingHandler handled.
+type logEntry struct {
+	Level slog.Level
+	Msg   string
+	Keys  []string
+}
+
+// recordingHandler is a slog.Handler that records the entries it is given.
+type recordingHandler struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := logEntry{Level: r.Level, Msg: r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		// Handlers ignore empty attributes.
+		if !a.Equal(slog.Attr{}) {
+			e.Keys = append(e.Keys, a.Key)
+		}
+		return true
+	})
+	sort.Strings(e.Keys)
+
+	h.mu.Lock()
+	h.entries = append(h.entries, e)
+	h.mu.Unlock()
+	return nil
+}
+
+// wait waits for n entries and returns them.
+func (h *recordingHandler) wait(t *testing.T, n int) []logEntry {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		h.mu.Lock()
+		entries := append([]logEntry(nil), h.entries...)
+		h.mu.Unlock()
+
+		if len(entries) >= n || time.Now().After(deadline) {
+			return entries
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		Options []Option
+		Setup   func(server *UDPConn)
+		Drive   func(server, client *UDPConn) error
+		Entries []logEntry
+	}{
+		{
+			Drive: func(server, client *UDPConn) error {
+				_, err := client.udpConn.Write([]byte("garbage!"))
+				return err
+			},
+			Entries: []logEntry{{Level: slog.LevelWarn, Msg: "osc: packet can not be parsed", Keys: []string{LogError, LogRemote}}},
+		},
+		{
+			Setup: func(server *UDPConn) {
+				server.SetScheduler(true)
+				server.SetLatePolicy(DropLate)
+			},
+			Drive: func(server, client *UDPConn) error {
+				return client.Send(Bundle{Timetag: FromTime(time.Now().Add(-time.Second)), Packets: []Packet{MustMessage("/late")}})
+			},
+			Entries: []logEntry{{Level: slog.LevelWarn, Msg: "osc: late bundle dropped", Keys: []string{LogRemote, LogTimetag}}},
+		},
+		{
+			Options: []Option{WithAccessControl(func(addr net.Addr) bool { return false })},
+			Drive: func(server, client *UDPConn) error {
+				return client.Send(MustMessage("/denied"))
+			},
+			Entries: []logEntry{{Level: slog.LevelDebug, Msg: "osc: rejected by access control", Keys: []string{LogError}}},
+		},
+		{
+			Drive: func(server, client *UDPConn) error {
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				return server.Shutdown(ctx)
+			},
+			Entries: []logEntry{
+				{Level: slog.LevelDebug, Msg: "osc: shutting down"},
+				{Level: slog.LevelDebug, Msg: "osc: shut down, every packet was dispatched"},
+			},
+		},
+	} {
+		h := &recordingHandler{}
+		server, err := ListenUDP("udp", laddr, append(testcase.Options, WithLogger(slog.New(h)))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testcase.Setup != nil {
+			testcase.Setup(server)
+		}
+		serving := make(chan struct{})
+		go func() {
+			_ = server.Serve(1, PatternMatching{Default: Method(func(msg Message) error { return nil })})
+			close(serving)
+		}()
+		time.Sleep(10 * time.Millisecond) // Let Serve start.
+
+		if err := testcase.Drive(server, client); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		entries := h.wait(t, len(testcase.Entries))
+		if len(entries) != len(testcase.Entries) {
+			t.Fatalf("(testcase %d) expected %d entries, got %+v", i, len(testcase.Entries), entries)
+		}
+		for j, expected := range testcase.Entries {
+			got := entries[j]
+			if got.Level != expected.Level || got.Msg != expected.Msg || len(got.Keys) != len(expected.Keys) {
+				t.Fatalf("(testcase %d) entry %d: expected %+v, got %+v", i, j, expected, got)
+			}
+			for k := range expected.Keys {
+				if got.Keys[k] != expected.Keys[k] {
+					t.Fatalf("(testcase %d) entry %d: expected keys %v, got %v", i, j, expected.Keys, got.Keys)
+				}
+			}
+		}
+		_ = client.Close() // Best effort.
+		select {
+		case <-server.CloseChan():
+		default:
+			_ = server.Close() // Best effort.
+		}
+		<-serving
+	}
+}
+
+func TestWithLogger_QueueFull(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &recordingHandler{}
+	server, err := ListenUDP("udp", laddr, WithLogger(slog.New(h)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	server.SetQueueSize(1)
+	server.SetQueuePolicy(DropOldest)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := server.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing reads the channel, so the second packet makes room for itself.
+	for i := 0; i < 2; i++ {
+		if err := client.Send(MustMessage("/flood")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := h.wait(t, 1)
+	if len(entries) != 1 || entries[0].Level != slog.LevelWarn || entries[0].Msg != "osc: queue full, packet dropped" {
+		t.Fatalf("expected a queue full warning, got %+v", entries)
+	}
+	if keys := entries[0].Keys; len(keys) != 1 || keys[0] != LogRemote {
+		t.Fatalf("expected the remote key, got %v", keys)
+	}
+}
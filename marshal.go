@@ -0,0 +1,116 @@
+package osc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Marshaler is implemented by types that encode themselves as an argument,
+// such as a color or a note event of an application.
+// NewMessage, MustMessage and Encode call MarshalOSC for the values that implement it.
+//
+// MarshalOSC returns the typetag of the argument and its payload, padding included.
+// The payload of a known typetag must be the size the typetag describes, e.g. 4 bytes
+// for TypetagRGBA, and the payload of an unknown typetag a multiple of 4 bytes.
+// Payloads that are not are an error wrapping ErrInvalidTypeTag, so that they
+// never corrupt the packet. Arrays can not be marshaled.
+type Marshaler interface {
+	MarshalOSC() (typetag byte, payload []byte, err error)
+}
+
+// Unmarshaler is implemented by types that decode themselves from an argument.
+// Decode and UnmarshalAt call UnmarshalOSC for the values that implement it.
+//
+// UnmarshalOSC is passed the typetag of the argument and its payload, padding included,
+// and should return an error wrapping ErrInvalidTypeTag for a typetag it does not expect.
+// The payload shares memory with the message, so it must be copied to be kept.
+type Unmarshaler interface {
+	UnmarshalOSC(typetag byte, payload []byte) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+// fromMarshaler converts the value m encodes to an argument.
+// Known typetags are decoded to their argument, and unknown ones kept as a RawArgument.
+func fromMarshaler(m Marshaler) (Argument, error) {
+	tt, payload, err := m.MarshalOSC()
+	if err != nil {
+		return nil, fmt.Errorf("%T: MarshalOSC: %w", m, err)
+	}
+	if tt == TypetagArrayStart || tt == TypetagArrayEnd {
+		return nil, fmt.Errorf("%T: MarshalOSC returned the array typetag %q: %w", m, tt, ErrInvalidTypeTag)
+	}
+	raw := RawArgument{Tag: tt, Payload: payload}
+	if err := raw.validate(); err != nil {
+		return nil, fmt.Errorf("%T: MarshalOSC: %w", m, err)
+	}
+	if !isTypetag(tt) {
+		return raw, nil
+	}
+	a, _, err := ReadArgument(tt, payload)
+	if err != nil {
+		return nil, fmt.Errorf("%T: MarshalOSC: %w", m, err)
+	}
+	return a, nil
+}
+
+// toUnmarshaler decodes arg into u.
+func toUnmarshaler(u Unmarshaler, arg Argument) error {
+	if _, ok := arg.(Array); ok {
+		return fmt.Errorf("%T: can not unmarshal an array: %w", u, ErrInvalidTypeTag)
+	}
+	if err := u.UnmarshalOSC(arg.Typetag(), arg.Bytes()); err != nil {
+		return fmt.Errorf("%T: UnmarshalOSC: %w", u, err)
+	}
+	return nil
+}
+
+// marshaler returns the Marshaler of a struct field, with a pointer receiver or not.
+func marshaler(fv reflect.Value) (Marshaler, bool) {
+	switch {
+	case fv.Type().Implements(marshalerType):
+		return fv.Interface().(Marshaler), true
+	case reflect.PtrTo(fv.Type()).Implements(marshalerType):
+		p := reflect.New(fv.Type())
+		p.Elem().Set(fv)
+		return p.Interface().(Marshaler), true
+	}
+	return nil, false
+}
+
+// unmarshaler returns the Unmarshaler of an addressable struct field.
+func unmarshaler(fv reflect.Value) (Unmarshaler, bool) {
+	if !reflect.PtrTo(fv.Type()).Implements(unmarshalerType) {
+		return nil, false
+	}
+	return fv.Addr().Interface().(Unmarshaler), true
+}
+
+// UnmarshalAt decodes the argument at index i into u, whatever its typetag.
+func (msg Message) UnmarshalAt(i int, u Unmarshaler) error {
+	if msg.view.isSet() {
+		tt, data, err := msg.view.argumentAt(i, TypetagAny)
+		if err != nil {
+			return err
+		}
+		if tt == TypetagArrayStart {
+			return fmt.Errorf("argument %d: %T: can not unmarshal an array: %w", i, u, ErrInvalidTypeTag)
+		}
+		size, _ := argumentDataSize(tt, data) // Checked by parseMessageView.
+		if err := u.UnmarshalOSC(tt, data[:size:size]); err != nil {
+			return fmt.Errorf("argument %d: %T: UnmarshalOSC: %w", i, u, err)
+		}
+		return nil
+	}
+	arg, err := msg.argumentAt(i, TypetagAny)
+	if err != nil {
+		return err
+	}
+	if err := toUnmarshaler(u, arg); err != nil {
+		return fmt.Errorf("argument %d: %w", i, err)
+	}
+	return nil
+}
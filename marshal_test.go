@@ -0,0 +1,157 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// color is encoded as an RGBA argument.
+type color struct {
+	R, G, B, A byte
+}
+
+func (c color) MarshalOSC() (byte, []byte, error) {
+	return TypetagRGBA, []byte{c.R, c.G, c.B, c.A}, nil
+}
+
+func (c *color) UnmarshalOSC(typetag byte, payload []byte) error {
+	if typetag != TypetagRGBA {
+		return fmt.Errorf("expected a color, got %q: %w", typetag, ErrInvalidTypeTag)
+	}
+	c.R, c.G, c.B, c.A = payload[0], payload[1], payload[2], payload[3]
+	return nil
+}
+
+// note is encoded as a string with its name and an int with its velocity,
+// packed in an argument of the unknown typetag 'n'.
+type note struct {
+	Name     string
+	Velocity int32
+}
+
+func (n *note) MarshalOSC() (byte, []byte, error) {
+	return 'n', appendUint32(appendString(nil, n.Name), uint32(n.Velocity)), nil
+}
+
+func (n *note) UnmarshalOSC(typetag byte, payload []byte) error {
+	if typetag != 'n' {
+		return fmt.Errorf("expected a note, got %q: %w", typetag, ErrInvalidTypeTag)
+	}
+	name, idx := ReadString(payload)
+	velocity, _, err := ReadIntFrom(payload[idx:])
+	if err != nil {
+		return err
+	}
+	n.Name, n.Velocity = name, int32(velocity.(Int))
+	return nil
+}
+
+// badMarshaler returns the typetag and the payload it holds.
+type badMarshaler struct {
+	Typetag byte
+	Payload []byte
+	Err     error
+}
+
+func (b badMarshaler) MarshalOSC() (byte, []byte, error) { return b.Typetag, b.Payload, b.Err }
+
+func TestMarshaler(t *testing.T) {
+	type event struct {
+		Color color
+		Note  *note
+		Count int32
+	}
+	expected := event{Color: color{1, 2, 3, 4}, Note: &note{Name: "C#4", Velocity: 100}, Count: 7}
+
+	encoded, err := Encode("/event", expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variadic := MustMessage("/event", color{1, 2, 3, 4}, &note{Name: "C#4", Velocity: 100}, int32(7))
+	if !encoded.Equal(variadic) {
+		t.Fatalf("expected %s, got %s", variadic, encoded)
+	}
+	if encoded.Arguments[0].Typetag() != TypetagRGBA || encoded.Arguments[1].Typetag() != 'n' {
+		t.Fatalf("expected typetags \"rni\", got %q", encoded.Typetags())
+	}
+
+	// The unknown typetag can only be parsed as the last arguments.
+	last, err := Encode("/event", struct {
+		Count int32
+		Note  note
+	}{Count: 7, Note: *expected.Note})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ParseOptions{UnknownTypetags: true}.ParsePacket(last.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := p.(Message).RawArguments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c color
+	view, err := parseMessageView(MustMessage("/color", int32(7), color{1, 2, 3, 4}).Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := view.UnmarshalAt(1, &c); err != nil || c != expected.Color {
+		t.Fatalf("expected %v, got %v (%v)", expected.Color, c, err)
+	}
+	var n note
+	if err := (Message{Arguments: raw}).UnmarshalAt(1, &n); err != nil {
+		t.Fatal(err)
+	}
+	if n != *expected.Note {
+		t.Fatalf("expected %v, got %v", *expected.Note, n)
+	}
+
+	for i, msg := range []Message{encoded, encoded.Clone()} {
+		var got event
+		if err := msg.Decode(&got); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if got.Color != expected.Color || *got.Note != *expected.Note || got.Count != expected.Count {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, expected, got)
+		}
+		var c color
+		if err := msg.UnmarshalAt(0, &c); err != nil || c != expected.Color {
+			t.Fatalf("(testcase %d) expected %v, got %v (%v)", i, expected.Color, c, err)
+		}
+		if err := msg.UnmarshalAt(2, &c); !errors.Is(err, ErrInvalidTypeTag) {
+			t.Fatalf("(testcase %d) expected ErrInvalidTypeTag, got %v", i, err)
+		}
+		if err := msg.UnmarshalAt(3, &c); !errors.Is(err, ErrArgumentIndex) {
+			t.Fatalf("(testcase %d) expected ErrArgumentIndex, got %v", i, err)
+		}
+	}
+}
+
+func TestMarshaler_Errors(t *testing.T) {
+	failure := errors.New("failure")
+	for i, testcase := range []struct {
+		Marshaler badMarshaler
+		Err       error
+	}{
+		{Marshaler: badMarshaler{Typetag: TypetagInt, Payload: []byte{0, 0, 1}}, Err: ErrInvalidTypeTag},
+		{Marshaler: badMarshaler{Typetag: TypetagInt, Payload: make([]byte, 8)}, Err: ErrInvalidTypeTag},
+		{Marshaler: badMarshaler{Typetag: TypetagString, Payload: []byte("abcd")}, Err: ErrInvalidTypeTag},
+		{Marshaler: badMarshaler{Typetag: 'n', Payload: []byte("abcde")}, Err: ErrInvalidTypeTag},
+		{Marshaler: badMarshaler{Typetag: TypetagArrayStart}, Err: ErrInvalidTypeTag},
+		{Marshaler: badMarshaler{Err: failure}, Err: failure},
+	} {
+		if _, err := NewMessage("/bad", int32(1), testcase.Marshaler); !errors.Is(err, testcase.Err) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+		}
+		if _, err := Encode("/bad", struct{ B badMarshaler }{testcase.Marshaler}); !errors.Is(err, testcase.Err) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+		}
+	}
+
+	var got struct{ Color color }
+	if err := MustMessage("/color", int32(1)).Decode(&got); !errors.Is(err, ErrInvalidTypeTag) {
+		t.Fatalf("expected ErrInvalidTypeTag, got %v", err)
+	}
+}
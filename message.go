@@ -2,65 +2,544 @@ package osc
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"regexp"
 	"strings"
-
-	"github.com/pkg/errors"
+	"time"
 )
 
 // Common errors.
 var (
+	ErrArgumentIndex    = errors.New("argument index out of range")
 	ErrIndexOutOfBounds = errors.New("index out of bounds")
 	ErrInvalidTypeTag   = errors.New("invalid type tag")
+	ErrNonASCIIChar     = errors.New("char is not ASCII")
 	ErrNilWriter        = errors.New("writer must not be nil")
 	ErrParse            = errors.New("error parsing message")
+	ErrUnsupportedType  = errors.New("unsupported argument type")
 )
 
 // Message is an OSC message.
 // An OSC message consists of an OSC address pattern and zero or more arguments.
+//
+// The messages that a conn reads with SetLazyArguments are views over its read buffer:
+// their Arguments are nil, and their arguments are decoded by the typed readers such as
+// Int32At. A view, including its address and the strings and blobs read from it,
+// is only valid until the method it was passed to returns, so methods that keep it
+// any longer, or that modify it, must keep a Clone instead.
 type Message struct {
 	Address   string `json:"address"`
 	Arguments []Argument
 	Sender    net.Addr
+
+	// replier is the conn the message was read from.
+	replier replier
+
+	// method is the address of the method the message was dispatched to.
+	method string
+
+	// ctx is done when the method should stop handling the message.
+	ctx context.Context
+
+	// arrays holds the arguments that enclose the arrays
+	// opened with BeginArray, innermost last.
+	arrays [][]Argument
+
+	// view holds the encoded arguments of a message that is a view over a read buffer.
+	view argumentView
+
+	// truncated is true if the arguments after an unknown typetag were dropped,
+	// and unknown holds their typetags and their payload for RawArguments.
+	truncated bool
+	unknown   argumentView
+
+	// raw is the data the message was parsed from, for Raw.
+	raw []byte
+}
+
+// NewMessage creates a message with the given arguments,
+// choosing each argument's typetag from its Go type:
+//
+//	int32, int   'i'
+//	float32      'f'
+//	string       's'
+//	[]byte       'b'
+//	bool         'T' or 'F'
+//	int64        'h'
+//	float64      'd'
+//	nil          'N'
+//	time.Time    't'
+//
+// Values that already implement Argument, such as Symbol or Array, are used as they are,
+// and values that implement Marshaler are encoded by their MarshalOSC method.
+// An error naming the index and type of the first unsupported argument is returned.
+// ArgumentOptions.NewMessage encodes the numbers with other typetags.
+func NewMessage(addr string, args ...interface{}) (Message, error) {
+	msg := Message{Address: addr, Arguments: make([]Argument, len(args))}
+	for i, arg := range args {
+		a, err := toArgument(arg)
+		if err != nil {
+			return Message{}, fmt.Errorf("argument %d: %w", i, err)
+		}
+		msg.Arguments[i] = a
+	}
+	return msg, nil
+}
+
+// MustMessage is like NewMessage but panics if an argument is not supported.
+// It is meant for tests and for messages whose arguments are known in advance.
+func MustMessage(addr string, args ...interface{}) Message {
+	msg, err := NewMessage(addr, args...)
+	if err != nil {
+		panic(err)
+	}
+	return msg
+}
+
+// toArgument converts a Go value to an argument.
+func toArgument(v interface{}) (Argument, error) {
+	switch x := v.(type) {
+	case nil:
+		return Nil{}, nil
+	case Argument:
+		return x, nil
+	case Marshaler:
+		return fromMarshaler(x)
+	case int32:
+		return Int(x), nil
+	case int:
+		if x < math.MinInt32 || x > math.MaxInt32 {
+			return nil, fmt.Errorf("int %d overflows int32, use int64", x)
+		}
+		return Int(x), nil
+	case float32:
+		return Float(x), nil
+	case string:
+		return String(x), nil
+	case []byte:
+		return Blob(x), nil
+	case bool:
+		return Bool(x), nil
+	case int64:
+		return Int64(x), nil
+	case float64:
+		return Double(x), nil
+	case time.Time:
+		return FromTime(x), nil
+	default:
+		return nil, fmt.Errorf("%T: %w", v, ErrUnsupportedType)
+	}
 }
 
 // ParseMessage parses an OSC message from a slice of bytes.
+// Blob arguments share memory with data, so use Clone if data is going to be reused.
+// Errors are a *ParseError with the offset of the argument that could not be read.
 func ParseMessage(data []byte, sender net.Addr) (Message, error) {
+	return parseMessage(data, sender, nil)
+}
+
+// parseMessage parses an OSC message, tolerating the deviations from the
+// specification that o allows. A nil o parses it like ParseMessage.
+func parseMessage(data []byte, sender net.Addr, o *ParseOptions) (Message, error) {
 	address, idx := ReadString(data)
 	msg := Message{
 		Address: address,
 		Sender:  sender,
+		raw:     data[:len(data):len(data)],
+	}
+	if o != nil && (idx >= int64(len(data)) || data[idx] != TypetagPrefix) {
+		if err := o.checkNoTypetags(data, int(idx)); err != nil {
+			return Message{}, &ParseError{Sender: sender, Address: address, Offset: int(idx), Err: fmt.Errorf("parse message %s: %w", address, err)}
+		}
+		msg.Arguments = []Argument{} // The message has no typetags, so it has no arguments either.
+		return msg, nil
 	}
-	data = data[idx:]
-	typetags, idx := ReadString(data)
-	data = data[idx:]
+	if idx > int64(len(data)) {
+		return Message{}, &ParseError{
+			Sender:  sender,
+			Address: address,
+			Offset:  len(data),
+			Err:     fmt.Errorf("parse message %s: no typetags: %w", address, io.ErrUnexpectedEOF),
+		}
+	}
+	offset := int(idx)
+	typetags, idx := ReadString(data[offset:])
+	short := idx > int64(len(data)-offset)
+	if short {
+		idx = int64(len(data) - offset) // A message with no arguments may be missing its padding.
+	}
+	offset += int(idx)
 
 	// Read all arguments.
-	args, err := ReadArguments([]byte(typetags), data)
+	r := newArgumentReader([]byte(typetags), data[offset:])
+	r.skipUnknown = o != nil && o.UnknownTypetags
+	r.paddedBlobs = o == nil || !o.MissingPadding
+	if o != nil {
+		r.maxBlobSize = o.MaxBlobSize
+	}
+	args, err := r.read(0)
 	if err != nil {
-		return Message{}, errors.Wrap(err, "parse message")
+		return Message{}, &ParseError{
+			Sender:  sender,
+			Address: address,
+			Offset:  offset + r.offset,
+			Err:     fmt.Errorf("parse message: %w", err),
+		}
 	}
-	msg.Arguments = args
+	msg.Arguments, msg.truncated, msg.unknown = args, r.truncated, r.unknown
 
+	if o != nil && !r.truncated {
+		if err := o.checkEnd(data, short || r.short); err != nil {
+			return Message{}, &ParseError{Sender: sender, Address: address, Offset: offset + r.offset, Err: fmt.Errorf("parse message %s: %w", address, err)}
+		}
+	}
 	return msg, nil
 }
 
+// WriteInt32 appends an int32 argument to the message.
+func (msg *Message) WriteInt32(i int32) {
+	msg.Arguments = append(msg.Arguments, Int(i))
+}
+
+// WriteFloat32 appends a float32 argument to the message.
+func (msg *Message) WriteFloat32(f float32) {
+	msg.Arguments = append(msg.Arguments, Float(f))
+}
+
+// WriteInt64 appends an int64 argument to the message.
+func (msg *Message) WriteInt64(i int64) {
+	msg.Arguments = append(msg.Arguments, Int64(i))
+}
+
+// WriteFloat64 appends a float64 argument to the message.
+func (msg *Message) WriteFloat64(f float64) {
+	msg.Arguments = append(msg.Arguments, Double(f))
+}
+
+// WriteBool appends a boolean argument to the message,
+// which is encoded as the typetag 'T' or 'F'.
+func (msg *Message) WriteBool(b bool) {
+	msg.Arguments = append(msg.Arguments, Bool(b))
+}
+
+// WriteString appends a string argument to the message.
+func (msg *Message) WriteString(s string) {
+	msg.Arguments = append(msg.Arguments, String(s))
+}
+
+// WriteBlob appends a blob argument to the message.
+func (msg *Message) WriteBlob(b []byte) {
+	msg.Arguments = append(msg.Arguments, Blob(b))
+}
+
+// WriteSymbol appends a symbol argument to the message.
+func (msg *Message) WriteSymbol(s string) {
+	msg.Arguments = append(msg.Arguments, Symbol(s))
+}
+
+// WriteTimetag appends a timetag argument to the message.
+func (msg *Message) WriteTimetag(tt Timetag) {
+	msg.Arguments = append(msg.Arguments, tt)
+}
+
+// WriteChar appends a character argument to the message.
+// Only ASCII characters can be read back with ReadChar.
+func (msg *Message) WriteChar(c rune) {
+	msg.Arguments = append(msg.Arguments, Char(c))
+}
+
+// WriteRGBA appends a color argument to the message.
+func (msg *Message) WriteRGBA(r, g, b, a byte) {
+	msg.Arguments = append(msg.Arguments, RGBA{R: r, G: g, B: b, A: a})
+}
+
+// WriteMIDI appends a MIDI message argument to the message.
+func (msg *Message) WriteMIDI(port, status, data1, data2 byte) {
+	msg.Arguments = append(msg.Arguments, MIDI{Port: port, Status: status, Data1: data1, Data2: data2})
+}
+
+// WriteNil appends a nil argument to the message.
+func (msg *Message) WriteNil() {
+	msg.Arguments = append(msg.Arguments, Nil{})
+}
+
+// WriteInfinitum appends an infinitum argument to the message.
+func (msg *Message) WriteInfinitum() {
+	msg.Arguments = append(msg.Arguments, Infinitum{})
+}
+
+// BeginArray starts an array argument.
+// The arguments that are written until the matching EndArray are its elements.
+func (msg *Message) BeginArray() {
+	msg.arrays = append(msg.arrays, msg.Arguments)
+	msg.Arguments = []Argument{}
+}
+
+// EndArray ends the array started by the last call to BeginArray.
+// It returns ErrUnbalancedArray if there is no such array.
+func (msg *Message) EndArray() error {
+	if len(msg.arrays) == 0 {
+		return fmt.Errorf("EndArray without BeginArray: %w", ErrUnbalancedArray)
+	}
+	last := len(msg.arrays) - 1
+	msg.Arguments = append(msg.arrays[last], Array(msg.Arguments))
+	msg.arrays = msg.arrays[:last]
+	return nil
+}
+
+// argumentAt returns the argument at index i, which must have the given typetag.
+// Booleans have either typetag, so TypetagTrue matches TypetagFalse too,
+// and TypetagAny matches every typetag.
+func (msg Message) argumentAt(i int, typetag byte) (Argument, error) {
+	if msg.view.isSet() {
+		tt, data, err := msg.view.argumentAt(i, typetag)
+		if err != nil {
+			return nil, err
+		}
+		arg, _, err := ReadArgument(tt, data)
+		return arg, err
+	}
+	if i < 0 || i >= len(msg.Arguments) {
+		return nil, fmt.Errorf("argument %d of %d: %w", i, len(msg.Arguments), ErrArgumentIndex)
+	}
+	arg := msg.Arguments[i]
+	if actual := arg.Typetag(); actual != typetag && typetag != TypetagAny && !(typetag == TypetagTrue && actual == TypetagFalse) {
+		return nil, fmt.Errorf("argument %d: expected typetag %q, got %q: %w", i, typetag, actual, ErrInvalidTypeTag)
+	}
+	return arg, nil
+}
+
+// Int32At returns the int32 argument at index i.
+func (msg Message) Int32At(i int) (int32, error) {
+	if msg.view.isSet() {
+		_, data, err := msg.view.argumentAt(i, TypetagInt)
+		if err != nil {
+			return 0, err
+		}
+		return int32(byteOrder.Uint32(data)), nil
+	}
+	arg, err := msg.argumentAt(i, TypetagInt)
+	if err != nil {
+		return 0, err
+	}
+	return arg.ReadInt32()
+}
+
+// Int64At returns the int64 argument at index i.
+func (msg Message) Int64At(i int) (int64, error) {
+	if msg.view.isSet() {
+		_, data, err := msg.view.argumentAt(i, TypetagInt64)
+		if err != nil {
+			return 0, err
+		}
+		return int64(byteOrder.Uint64(data)), nil
+	}
+	arg, err := msg.argumentAt(i, TypetagInt64)
+	if err != nil {
+		return 0, err
+	}
+	return arg.ReadInt64()
+}
+
+// Float32At returns the float32 argument at index i.
+func (msg Message) Float32At(i int) (float32, error) {
+	if msg.view.isSet() {
+		_, data, err := msg.view.argumentAt(i, TypetagFloat)
+		if err != nil {
+			return 0, err
+		}
+		return readFloat32(data), nil
+	}
+	arg, err := msg.argumentAt(i, TypetagFloat)
+	if err != nil {
+		return 0, err
+	}
+	return arg.ReadFloat32()
+}
+
+// Float64At returns the double argument at index i.
+func (msg Message) Float64At(i int) (float64, error) {
+	if msg.view.isSet() {
+		_, data, err := msg.view.argumentAt(i, TypetagDouble)
+		if err != nil {
+			return 0, err
+		}
+		return readFloat64(data), nil
+	}
+	arg, err := msg.argumentAt(i, TypetagDouble)
+	if err != nil {
+		return 0, err
+	}
+	return arg.ReadFloat64()
+}
+
+// StringAt returns the string argument at index i.
+func (msg Message) StringAt(i int) (string, error) {
+	if msg.view.isSet() {
+		_, data, err := msg.view.argumentAt(i, TypetagString)
+		if err != nil {
+			return "", err
+		}
+		return readStringView(data), nil
+	}
+	arg, err := msg.argumentAt(i, TypetagString)
+	if err != nil {
+		return "", err
+	}
+	return arg.ReadString()
+}
+
+// SymbolAt returns the symbol argument at index i.
+func (msg Message) SymbolAt(i int) (string, error) {
+	if msg.view.isSet() {
+		_, data, err := msg.view.argumentAt(i, TypetagSymbol)
+		if err != nil {
+			return "", err
+		}
+		return readStringView(data), nil
+	}
+	arg, err := msg.argumentAt(i, TypetagSymbol)
+	if err != nil {
+		return "", err
+	}
+	return arg.ReadSymbol()
+}
+
+// BlobAt returns the blob argument at index i.
+// The blob of a message that was read by a conn is a view of its read buffer,
+// which is only valid until the method that was passed the message returns,
+// so it must be copied to be kept, e.g. with CopyBlobAt.
+func (msg Message) BlobAt(i int) ([]byte, error) {
+	arg, err := msg.argumentAt(i, TypetagBlob)
+	if err != nil {
+		return nil, err
+	}
+	return arg.ReadBlob()
+}
+
+// CopyBlobAt returns a copy of the blob argument at index i, which can be kept and modified.
+func (msg Message) CopyBlobAt(i int) ([]byte, error) {
+	b, err := msg.BlobAt(i)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
+
+// BoolAt returns the boolean argument at index i.
+// ArgumentOptions.BoolAt accepts int32 arguments too.
+func (msg Message) BoolAt(i int) (bool, error) {
+	arg, err := msg.argumentAt(i, TypetagTrue)
+	if err != nil {
+		return false, err
+	}
+	return arg.ReadBool()
+}
+
+// TimetagAt returns the timetag argument at index i.
+func (msg Message) TimetagAt(i int) (Timetag, error) {
+	arg, err := msg.argumentAt(i, TypetagTimetag)
+	if err != nil {
+		return 0, err
+	}
+	return arg.ReadTimetag()
+}
+
 // Bytes returns the contents of the message as a slice of bytes.
 func (msg Message) Bytes() []byte {
 	b := [][]byte{
 		ToBytes(msg.Address),
 		msg.Typetags(),
 	}
-	for _, a := range msg.Arguments {
+	for _, a := range msg.arguments() {
 		b = append(b, a.Bytes())
 	}
 	return bytes.Join(b, []byte{})
 }
 
-// Equal returns true if the messages are equal, false otherwise.
+// MethodAddress returns the address of the method a dispatcher passed the message to,
+// which may be a pattern or Default. It is empty if the message has not been dispatched.
+func (msg Message) MethodAddress() string {
+	return msg.method
+}
+
+// Context returns a context that is done when the method handling the message
+// should give up on it, e.g. because its timeout has passed or the conn
+// it was read from stopped serving.
+// It is never nil.
+func (msg Message) Context() context.Context {
+	if msg.ctx == nil {
+		return context.Background()
+	}
+	return msg.ctx
+}
+
+// Clone returns a copy of the message that does not share any memory with it,
+// so the buffer the message was parsed from can be reused.
+// The arguments of a view are decoded into the Arguments of the copy.
+func (msg Message) Clone() Message {
+	clone := msg
+	clone.arrays = nil
+	clone.view = argumentView{}
+	if msg.view.isSet() {
+		clone.Address, clone.method = msg.ownedAddress(), string([]byte(msg.method))
+	}
+	if args := msg.arguments(); args != nil {
+		clone.Arguments = cloneArguments(args)
+	}
+	if msg.unknown.isSet() {
+		clone.unknown = argumentView{typetags: append([]byte{}, msg.unknown.typetags...), data: append([]byte{}, msg.unknown.data...)}
+	}
+	if msg.raw != nil {
+		clone.raw = append([]byte{}, msg.raw...)
+	}
+	return clone
+}
+
+// ownedAddress returns the address of the message, copying it if the message is a view,
+// so that it can be kept after the read buffer is reused.
+func (msg Message) ownedAddress() string {
+	if msg.view.isSet() {
+		return string([]byte(msg.Address))
+	}
+	return msg.Address
+}
+
+// arguments returns the arguments of the message, decoding them if it is a view.
+func (msg Message) arguments() []Argument {
+	if msg.view.isSet() {
+		return msg.view.decode()
+	}
+	return msg.Arguments
+}
+
+// cloneArguments deep-copies args.
+func cloneArguments(args []Argument) []Argument {
+	clone := make([]Argument, len(args))
+	for i, a := range args {
+		switch x := a.(type) {
+		case Blob:
+			clone[i] = append(Blob{}, x...)
+		case Array:
+			clone[i] = Array(cloneArguments(x))
+		case RawArgument:
+			clone[i] = RawArgument{Tag: x.Tag, Payload: append([]byte{}, x.Payload...)}
+		default:
+			// Everything else is a value.
+			clone[i] = a
+		}
+	}
+	return clone
+}
+
+// Equal returns true if other is a message with the same address and
+// arguments, false otherwise.
+// Arguments are compared by typetag and value, so Int(1) does not equal Int64(1).
 func (msg Message) Equal(other Packet) bool {
 	msg2, ok := other.(Message)
 	if !ok {
@@ -69,11 +548,12 @@ func (msg Message) Equal(other Packet) bool {
 	if msg.Address != msg2.Address {
 		return false
 	}
-	if len(msg.Arguments) != len(msg2.Arguments) {
+	args, args2 := msg.arguments(), msg2.arguments()
+	if len(args) != len(args2) {
 		return false
 	}
-	for i, a := range msg.Arguments {
-		if !a.Equal(msg2.Arguments[i]) {
+	for i, a := range args {
+		if !a.Equal(args2[i]) {
 			return false
 		}
 	}
@@ -81,53 +561,197 @@ func (msg Message) Equal(other Packet) bool {
 }
 
 // Match returns true if the address of the OSC Message matches the given address.
+// The address of the message is a pattern, unless exactMatch is true.
 func (msg Message) Match(address string, exactMatch bool) (bool, error) {
 	if exactMatch {
 		return address == msg.Address, nil
 	}
-	// Verify same number of parts.
-	if !VerifyParts(address, msg.Address) {
-		return false, nil
-	}
-	exp, err := GetRegex(msg.Address)
+	p, err := CompilePattern(msg.Address)
 	if err != nil {
 		return false, err
 	}
-	return exp.MatchString(address), nil
+	return p.Match(address), nil
+}
+
+// Truncated returns true if the message was parsed with ParseOptions.UnknownTypetags
+// and its arguments were cut short at a typetag that is not known.
+func (msg Message) Truncated() bool {
+	return msg.truncated
 }
 
 // Typetags returns a padded byte slice of the message's type tags.
 func (msg Message) Typetags() []byte {
-	tt := make([]byte, len(msg.Arguments)+1)
-	tt[0] = TypetagPrefix
-	for i, a := range msg.Arguments {
-		tt[i+1] = a.Typetag()
+	return Pad(appendTypetags([]byte{TypetagPrefix}, msg.arguments(), 0))
+}
+
+// TypetagString returns the typetags of the message without the leading ',' or any padding,
+// such as "ifs" for an int, a float and a string, including the typetags of the arguments
+// that ParseOptions.UnknownTypetags dropped.
+func (msg Message) TypetagString() string {
+	if msg.view.isSet() {
+		return string(msg.view.typetags)
 	}
-	return Pad(append(tt, 0))
+	var buf [16]byte
+	return string(appendTypetags(buf[:0], msg.Arguments, msg.unknown.typetags...))
 }
 
-// WriteTo writes the Message to an io.Writer.
-func (msg Message) WriteTo(w io.Writer) (int64, error) {
-	var bytesWritten int
+// Raw returns the encoded message. A message that was read by a conn or parsed
+// returns the bytes it was parsed from, including the arguments that
+// ParseOptions.UnknownTypetags dropped, and the message of a bundle the bytes
+// of its element. Other messages are encoded, like Bytes does.
+//
+// The bytes of a message that was read by a conn share memory with its read buffer,
+// so like the strings and blobs of a view they are only valid until the method
+// it was passed to returns, unless the message is a Clone. They are not updated when
+// the Address or the Arguments of the message are modified after it was parsed.
+func (msg Message) Raw() []byte {
+	if msg.raw != nil {
+		return msg.raw
+	}
+	return msg.Bytes()
+}
 
-	nw, err := fmt.Fprintf(w, "%s%s", msg.Address, msg.Typetags())
+// WriteTo writes the encoded message to w with a single call to Write
+// and returns the number of bytes written.
+// Unlike Bytes it builds the encoding in a single buffer, so it allocates
+// much less for messages with large blobs or many arguments.
+// Messages that can not be encoded, such as ones with an invalid address,
+// are rejected before anything is written.
+// A writer that does not accept every byte causes io.ErrShortWrite,
+// and errors from the writer are returned as they are.
+func (msg Message) WriteTo(w io.Writer) (int64, error) {
+	size, err := msg.encodedSize()
 	if err != nil {
 		return 0, err
 	}
-	bytesWritten += nw
+	return writeEncoded(w, msg.appendTo(make([]byte, 0, size)))
+}
 
-	for _, a := range msg.Arguments {
-		nw, err := a.WriteTo(w)
-		if err != nil {
-			return 0, err
+// AppendTo appends the encoded message to dst and returns the extended slice,
+// which is only reallocated if dst does not have room for it.
+// It encodes the same bytes as Bytes, so a send loop can reuse a single buffer
+// instead of allocating one for every message.
+func (msg Message) AppendTo(dst []byte) []byte {
+	if len(dst)%4 != 0 {
+		// The encoding is padded to multiples of 4 bytes from its start.
+		return append(dst, msg.appendTo(nil)...)
+	}
+	return msg.appendTo(dst)
+}
+
+// encodedSize returns the size of the encoded message,
+// or an error if the message is not valid.
+func (msg Message) encodedSize() (int, error) {
+	if err := msg.Validate(); err != nil {
+		return 0, err
+	}
+	size, typetags, err := argumentsSize(msg.arguments())
+	if err != nil {
+		return 0, fmt.Errorf("encode %s: %w", msg.Address, err)
+	}
+	return stringSize(msg.Address) + padded(typetags+2) + size, nil
+}
+
+// Validate returns an error if the message would not be understood by its receiver.
+// The address must start with '/' and must not contain spaces, '#' or unbalanced
+// brackets and braces, although it may be a pattern.
+// Every argument must encode to the payload its typetag describes, so strings
+// can not contain null bytes and chars must be ASCII.
+// All of the conns validate messages before sending them.
+func (msg Message) Validate() error {
+	if err := validateAddressPattern(msg.Address); err != nil {
+		return err
+	}
+	if len(msg.arrays) > 0 {
+		return fmt.Errorf("%s: BeginArray without EndArray: %w", msg.Address, ErrUnbalancedArray)
+	}
+	if err := validateArguments(msg.arguments()); err != nil {
+		return fmt.Errorf("%s: %w", msg.Address, err)
+	}
+	return nil
+}
+
+// validateAddressPattern returns an error naming the offending byte if addr
+// is not a valid address pattern.
+func validateAddressPattern(addr string) error {
+	if len(addr) == 0 || addr[0] != MessageChar {
+		return &AddressError{Address: addr, Reason: "does not start with '/'"}
+	}
+	if i := strings.IndexAny(addr, " #\x00"); i >= 0 {
+		return addressError(addr, i, "has %q at offset %d", addr[i], i)
+	}
+	if !strings.ContainsAny(addr, "?*[]{}") {
+		return nil // Only patterns and brackets can be invalid.
+	}
+	return checkPattern(addr)
+}
+
+// validateArguments returns an error naming the first argument whose payload
+// does not match its typetag.
+func validateArguments(args []Argument) error {
+	for i, a := range args {
+		if err := validateArgument(a); err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
 		}
-		bytesWritten += int(nw)
 	}
+	return nil
+}
+
+// validateArgument returns an error if the payload of a does not match its typetag.
+func validateArgument(a Argument) error {
+	switch x := a.(type) {
+	case nil:
+		return fmt.Errorf("nil argument: %w", ErrUnsupportedType)
+	case Array:
+		return validateArguments(x)
+	case String:
+		return validateString(string(x))
+	case Symbol:
+		return validateString(string(x))
+	case Char:
+		if x > 0x7F {
+			return fmt.Errorf("char %U: %w", rune(x), ErrNonASCIIChar)
+		}
+		return nil
+	case Int, Int64, Float, Double, Bool, Nil, Infinitum, Blob, RGBA, MIDI, Timetag:
+		return nil
+	case RawArgument:
+		return x.validate()
+	}
+	// Arguments from other packages have to describe their own payload.
+	data := a.Bytes()
+	_, n, err := ReadArgument(a.Typetag(), data)
+	if err != nil {
+		return fmt.Errorf("%T with typetag %q: %w", a, a.Typetag(), err)
+	}
+	if n != int64(len(data)) {
+		return fmt.Errorf("%T with typetag %q has %d bytes, the typetag describes %d: %w", a, a.Typetag(), len(data), n, ErrInvalidTypeTag)
+	}
+	return nil
+}
+
+// validateString returns an error if s contains a null byte, which would end it early.
+func validateString(s string) error {
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		return fmt.Errorf("string %q has a null byte at offset %d", s, i)
+	}
+	return nil
+}
 
-	return int64(bytesWritten), nil
+// appendTo appends the encoded message to b, which must have a multiple of 4 bytes.
+func (msg Message) appendTo(b []byte) []byte {
+	b = appendString(b, msg.Address)
+	args := msg.arguments()
+	b = Pad(appendTypetags(append(b, TypetagPrefix), args, 0))
+	for _, a := range args {
+		b = appendArgument(b, a)
+	}
+	return b
 }
 
 // GetRegex compiles and returns a regular expression object for the given address pattern.
+//
+// Deprecated: Use CompilePattern, which is what Match uses.
 func GetRegex(pattern string) (*regexp.Regexp, error) {
 	pattern = strings.Replace(pattern, ".", "\\.", -1) // Escape all '.' in the pattern
 	pattern = strings.Replace(pattern, "(", "\\(", -1) // Escape all '(' in the pattern
@@ -2,11 +2,14 @@ package osc
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"io/ioutil"
+	"math"
 	"net"
+	"strings"
 	"testing"
-
-	"github.com/pkg/errors"
+	"time"
 )
 
 func TestMessageEqual(t *testing.T) {
@@ -54,6 +57,58 @@ func TestMessageEqual(t *testing.T) {
 	}
 }
 
+func TestMessageEqualTypetags(t *testing.T) {
+	nan32, nan64 := Float(math.NaN()), Double(math.NaN())
+	for i, testcase := range []struct {
+		A, B  Message
+		Equal bool
+	}{
+		{A: MustMessage("/a", 1), B: MustMessage("/a", int64(1)), Equal: false},
+		{A: MustMessage("/a", float32(1)), B: MustMessage("/a", 1.0), Equal: false},
+		{A: MustMessage("/a", "s"), B: MustMessage("/a", Symbol("s")), Equal: false},
+		{A: MustMessage("/a", nan32, nan64), B: MustMessage("/a", nan32, nan64), Equal: true},
+		{A: MustMessage("/a", float32(0)), B: MustMessage("/a", float32(math.Copysign(0, -1))), Equal: false},
+		{A: MustMessage("/a", []byte("abcd")), B: MustMessage("/a", []byte("abcd")), Equal: true},
+		{A: MustMessage("/a", []byte("abcd")), B: MustMessage("/a", []byte("abce")), Equal: false},
+		{A: MustMessage("/a", Array{Int(1)}), B: MustMessage("/a", Array{Int(1)}), Equal: true},
+		{A: MustMessage("/a"), B: MustMessage("/b"), Equal: false},
+	} {
+		if expected, got := testcase.Equal, testcase.A.Equal(testcase.B); expected != got {
+			t.Fatalf("(testcase %d) expected %s equal %s to be %t", i, testcase.A, testcase.B, expected)
+		}
+	}
+}
+
+func TestMessageClone(t *testing.T) {
+	data := MustMessage("/clone", []byte("blob"), Array{Blob("in.."), String("s")}, int32(1)).Bytes()
+
+	msg, err := ParseMessage(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := msg.Clone()
+	if !msg.Equal(clone) {
+		t.Fatalf("expected %s, got %s", msg, clone)
+	}
+	// Reusing the read buffer changes the parsed message, but not the clone.
+	for i := range data {
+		data[i] = 'x'
+	}
+	if expected := MustMessage("/clone", []byte("blob"), Array{Blob("in.."), String("s")}, int32(1)); !expected.Equal(clone) {
+		t.Fatalf("expected %s, got %s", expected, clone)
+	}
+	// Changing the clone does not change the original.
+	msg = MustMessage("/clone", []byte("blob"), Array{Blob("in..")})
+	clone = msg.Clone()
+	clone.Arguments[0].(Blob)[0] = 'B'
+	clone.Arguments[1].(Array)[0].(Blob)[0] = 'I'
+	clone.Arguments[1] = Int(2)
+	clone.Address = "/other"
+	if expected := MustMessage("/clone", []byte("blob"), Array{Blob("in..")}); !expected.Equal(msg) {
+		t.Fatalf("expected %s, got %s", expected, msg)
+	}
+}
+
 func TestVerifyParts(t *testing.T) {
 	// Pairs that should match.
 	for _, pair := range [][2]string{
@@ -185,6 +240,371 @@ func TestMesssageBytes(t *testing.T) {
 	}
 }
 
+func TestNewMessage(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	msg, err := NewMessage("/mixed", int32(1), 2, float32(3), "four", []byte("five"), true, false, int64(6), 7.5, nil, now, Symbol("sym"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Message{
+		Address: "/mixed",
+		Arguments: Arguments{
+			Int(1), Int(2), Float(3), String("four"), Blob("five"), Bool(true), Bool(false),
+			Int64(6), Double(7.5), Nil{}, FromTime(now), Symbol("sym"),
+		},
+	}
+	if !expected.Equal(msg) {
+		t.Fatalf("expected %s, got %s", expected, msg)
+	}
+	if expected, got := []byte(",iifsbTFhdNtS\x00\x00\x00"), msg.Typetags(); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	if _, err := NewMessage("/empty"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewMessageUnsupported(t *testing.T) {
+	for i, testcase := range []struct {
+		Args     []interface{}
+		Expected string
+	}{
+		{
+			Args:     []interface{}{int32(1), struct{}{}},
+			Expected: "argument 1: struct {}: unsupported argument type",
+		},
+		{
+			Args:     []interface{}{uint8(1)},
+			Expected: "argument 0: uint8: unsupported argument type",
+		},
+		{
+			Args:     []interface{}{"a", "b", 1 << 40},
+			Expected: "argument 2: int 1099511627776 overflows int32, use int64",
+		},
+	} {
+		_, err := NewMessage("/bad", testcase.Args...)
+		if err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestMustMessage(t *testing.T) {
+	if expected, got := (Message{Address: "/foo", Arguments: Arguments{Int(1)}}), MustMessage("/foo", 1); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(error).Error(), "unsupported argument type") {
+			t.Fatalf("expected a panic with an unsupported argument error, got %v", r)
+		}
+	}()
+	MustMessage("/foo", complex(1, 2))
+}
+
+func TestNewMessageUDP(t *testing.T) {
+	msgs := make(chan Message, 1)
+	server, conn, errChan := testUDPServer(t, PatternMatching{
+		"/mixed": Method(func(msg Message) error {
+			msgs <- msg
+			return nil
+		}),
+	})
+	defer func() { _ = conn.Close() }() // Best effort.
+
+	expected := MustMessage("/mixed", 1, float32(0.25), "str", []byte("blob"), true, int64(-1), 0.125, nil, time.Unix(0, 0))
+	if err := conn.Send(expected); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-msgs:
+		if !expected.Equal(got) {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+	case err := <-errChan:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMessageGetters(t *testing.T) {
+	msg := MustMessage("/get", int32(1), int64(2), float32(3), 4.0, "five", Symbol("six"), []byte("7777"), true, false, Timetag(8))
+
+	// Getters can be called in any order, any number of times.
+	for n := 0; n < 2; n++ {
+		if b, err := msg.BoolAt(8); err != nil || b {
+			t.Fatalf("expected false, got %t (%v)", b, err)
+		}
+		if b, err := msg.BoolAt(7); err != nil || !b {
+			t.Fatalf("expected true, got %t (%v)", b, err)
+		}
+		if tt, err := msg.TimetagAt(9); err != nil || tt != 8 {
+			t.Fatalf("expected timetag 8, got %d (%v)", tt, err)
+		}
+		if b, err := msg.BlobAt(6); err != nil || string(b) != "7777" {
+			t.Fatalf("expected 7777, got %q (%v)", b, err)
+		}
+		if s, err := msg.SymbolAt(5); err != nil || s != "six" {
+			t.Fatalf("expected six, got %q (%v)", s, err)
+		}
+		if s, err := msg.StringAt(4); err != nil || s != "five" {
+			t.Fatalf("expected five, got %q (%v)", s, err)
+		}
+		if f, err := msg.Float64At(3); err != nil || f != 4 {
+			t.Fatalf("expected 4, got %f (%v)", f, err)
+		}
+		if f, err := msg.Float32At(2); err != nil || f != 3 {
+			t.Fatalf("expected 3, got %f (%v)", f, err)
+		}
+		if i, err := msg.Int64At(1); err != nil || i != 2 {
+			t.Fatalf("expected 2, got %d (%v)", i, err)
+		}
+		if i, err := msg.Int32At(0); err != nil || i != 1 {
+			t.Fatalf("expected 1, got %d (%v)", i, err)
+		}
+	}
+}
+
+func TestMessageGettersErrors(t *testing.T) {
+	msg := MustMessage("/get", int32(1), "two")
+
+	for i, testcase := range []struct {
+		Get      func() error
+		Cause    error
+		Expected string
+	}{
+		{
+			Get:      func() error { _, err := msg.Float32At(0); return err },
+			Cause:    ErrInvalidTypeTag,
+			Expected: "argument 0: expected typetag 'f', got 'i': invalid type tag",
+		},
+		{
+			Get:      func() error { _, err := msg.SymbolAt(1); return err },
+			Cause:    ErrInvalidTypeTag,
+			Expected: "argument 1: expected typetag 'S', got 's': invalid type tag",
+		},
+		{
+			Get:      func() error { _, err := msg.BoolAt(1); return err },
+			Cause:    ErrInvalidTypeTag,
+			Expected: "argument 1: expected typetag 'T', got 's': invalid type tag",
+		},
+		{
+			Get:      func() error { _, err := msg.Int64At(0); return err },
+			Cause:    ErrInvalidTypeTag,
+			Expected: "argument 0: expected typetag 'h', got 'i': invalid type tag",
+		},
+		{
+			Get:      func() error { _, err := msg.StringAt(2); return err },
+			Cause:    ErrArgumentIndex,
+			Expected: "argument 2 of 2: argument index out of range",
+		},
+		{
+			Get:      func() error { _, err := msg.Int32At(-1); return err },
+			Cause:    ErrArgumentIndex,
+			Expected: "argument -1 of 2: argument index out of range",
+		},
+		{
+			Get:      func() error { _, err := msg.BlobAt(5); return err },
+			Cause:    ErrArgumentIndex,
+			Expected: "argument 5 of 2: argument index out of range",
+		},
+		{
+			Get:      func() error { _, err := msg.Float64At(1); return err },
+			Cause:    ErrInvalidTypeTag,
+			Expected: "argument 1: expected typetag 'd', got 's': invalid type tag",
+		},
+		{
+			Get:      func() error { _, err := msg.TimetagAt(0); return err },
+			Cause:    ErrInvalidTypeTag,
+			Expected: "argument 0: expected typetag 't', got 'i': invalid type tag",
+		},
+	} {
+		err := testcase.Get()
+		if !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestMessageWrite(t *testing.T) {
+	msg := Message{Address: "/foo"}
+	msg.WriteInt32(1)
+	msg.WriteFloat32(2)
+	msg.WriteInt64(-1 << 40)
+	msg.WriteFloat64(0.25)
+	msg.WriteBool(true)
+	msg.WriteBool(false)
+	msg.WriteString("bar")
+	msg.WriteBlob([]byte("baz!"))
+	msg.WriteChar('x')
+	msg.WriteRGBA(0x10, 0x20, 0x30, 0xff)
+	msg.WriteMIDI(0, 0x90, 60, 127)
+	msg.WriteNil()
+	msg.WriteInfinitum()
+
+	if expected, got := []byte(",ifhdTFsbcrmNI\x00\x00"), msg.Typetags(); !bytes.Equal(expected, got) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+	parsed, err := ParseMessage(msg.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Equal(parsed) {
+		t.Fatalf("expected %s, got %s", msg, parsed)
+	}
+}
+
+func TestParseMessage64Bit(t *testing.T) {
+	// A sample position and a gain, as sent by a DAW.
+	data := bytes.Join([][]byte{
+		{'/', 'p', 'o', 's', 0, 0, 0, 0},
+		{TypetagPrefix, TypetagInt64, TypetagDouble, 0},
+		{0, 0, 0, 0x01, 0x02, 0x03, 0x04, 0x05},
+		{0x3f, 0xe8, 0, 0, 0, 0, 0, 0},
+	}, []byte{})
+
+	msg, err := ParseMessage(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Message{Address: "/pos", Arguments: Arguments{Int64(0x0102030405), Double(0.75)}}
+	if !expected.Equal(msg) {
+		t.Fatalf("expected %s, got %s", expected, msg)
+	}
+	if got := expected.Bytes(); !bytes.Equal(data, got) {
+		t.Fatalf("expected %x, got %x", data, got)
+	}
+	// The last argument is cut short.
+	if _, err := ParseMessage(data[:len(data)-1], nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMessageString(t *testing.T) {
+	for i, testcase := range []struct {
+		Message  Message
+		Expected string
+	}{
+		{
+			Message:  Message{Address: "/ping"},
+			Expected: "/ping ,",
+		},
+		{
+			Message:  MustMessage("/synth/freq", 440, float32(0.5)),
+			Expected: "/synth/freq ,if 440 0.5",
+		},
+		{
+			Message:  MustMessage("/mixed", int64(-7), 0.1, "say \"hi\"\n", Symbol("sym"), []byte("twelve bytes"), true, nil, Infinitum{}),
+			Expected: `/mixed ,hdsSbTNI -7 0.1 "say \"hi\"\n" 'sym blob[12] true nil inf`,
+		},
+		{
+			Message:  Message{Address: "/grid/led/set", Arguments: Arguments{Int(3), Char('k'), RGBA{R: 0xff, A: 0xff}, MIDI{Status: 0x80, Data1: 1}}},
+			Expected: "/grid/led/set ,icrm 3 'k' RGBA(#ff0000ff) MIDI(00 80 01 00)",
+		},
+		{
+			Message:  Message{Address: "/t", Arguments: Arguments{FromTime(time.Date(2000, 1, 1, 0, 0, 0, 5e8, time.UTC)), Immediately, String("\x00\x7f")}},
+			Expected: `/t ,tts 2000-01-01T00:00:00.5Z immediately "\x00\x7f"`,
+		},
+	} {
+		if expected, got := testcase.Expected, testcase.Message.String(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+// TestParseMessageSymbols parses a SuperCollider style message with
+// symbol and timetag arguments.
+func TestParseMessageSymbols(t *testing.T) {
+	data := bytes.Join([][]byte{
+		{'/', 's', '_', 'n', 'e', 'w', 0, 0},
+		{TypetagPrefix, TypetagSymbol, TypetagInt, TypetagSymbol, TypetagFloat, TypetagTimetag, 0, 0},
+		{'d', 'e', 'f', 'a', 'u', 'l', 't', 0},
+		{0, 0, 0x03, 0xe8},
+		{'f', 'r', 'e', 'q', 0, 0, 0, 0},
+		{0x43, 0xdc, 0, 0},
+		{0xBC, 0x17, 0xC2, 0x00, 0x40, 0, 0, 0},
+	}, []byte{})
+
+	msg, err := ParseMessage(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Message{Address: "/s_new"}
+	expected.WriteSymbol("default")
+	expected.WriteInt32(1000)
+	expected.WriteSymbol("freq")
+	expected.WriteFloat32(440)
+	expected.WriteTimetag(Timetag(0xBC17C20040000000))
+
+	if !expected.Equal(msg) {
+		t.Fatalf("expected %s, got %s", expected, msg)
+	}
+	if got := expected.Bytes(); !bytes.Equal(data, got) {
+		t.Fatalf("expected %x, got %x", data, got)
+	}
+	if _, ok := msg.Arguments[0].(Symbol); !ok {
+		t.Fatalf("expected a Symbol, got %T", msg.Arguments[0])
+	}
+}
+
+// TestParseMessageLiblo parses messages as they are sent by liblo and TouchOSC.
+func TestParseMessageLiblo(t *testing.T) {
+	for i, testcase := range []struct {
+		Data     []byte
+		Expected Message
+	}{
+		{
+			// lo_send(t, "/1/toggle1", "T")
+			Data: []byte("/1/toggle1\x00\x00,T\x00\x00"),
+			Expected: Message{
+				Address:   "/1/toggle1",
+				Arguments: Arguments{Bool(true)},
+			},
+		},
+		{
+			// lo_send(t, "/flags", "TFNI")
+			Data: []byte("/flags\x00\x00,TFNI\x00\x00\x00"),
+			Expected: Message{
+				Address:   "/flags",
+				Arguments: Arguments{Bool(true), Bool(false), Nil{}, Infinitum{}},
+			},
+		},
+		{
+			// lo_send(t, "/mixed", "iNfI", 7, 0.5f)
+			Data: []byte("/mixed\x00\x00,iNfI\x00\x00\x00\x00\x00\x00\x07\x3f\x00\x00\x00"),
+			Expected: Message{
+				Address:   "/mixed",
+				Arguments: Arguments{Int(7), Nil{}, Float(0.5), Infinitum{}},
+			},
+		},
+	} {
+		msg, err := ParseMessage(testcase.Data, nil)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !testcase.Expected.Equal(msg) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, testcase.Expected, msg)
+		}
+		// Writing the message gives back the same bytes.
+		if expected, got := testcase.Data, msg.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
 type errWriter struct {
 	erridx int
 	curr   int
@@ -215,6 +635,241 @@ func TestMessageWriteTo(t *testing.T) {
 	}
 }
 
+func TestMessageWriteToEncoding(t *testing.T) {
+	for i, msg := range []Message{
+		{Address: "/empty"},
+		MustMessage("/ints", int32(-1), int64(1<<40), float32(0.5), 0.25),
+		MustMessage("/strings", "", "abc", "abcd", Symbol(""), Symbol("sym")),
+		MustMessage("/blobs", []byte{}, []byte{1}, []byte("1234"), []byte("12345")),
+		MustMessage("/misc", true, false, nil, Infinitum{}, Char('a'), RGBA{R: 1}, MIDI{Port: 2}, Timetag(3)),
+		MustMessage("/arrays", Array{}, Array{Int(1), Array{String("a")}}, Int(2)),
+	} {
+		var buf bytes.Buffer
+		n, err := msg.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if expected, got := msg.Bytes(), buf.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("(testcase %d) expected %x, got %x", i, expected, got)
+		}
+		if expected, got := int64(buf.Len()), n; expected != got {
+			t.Fatalf("(testcase %d) expected %d bytes written, got %d", i, expected, got)
+		}
+	}
+}
+
+func TestMessageAppendTo(t *testing.T) {
+	for i, msg := range []Message{
+		{Address: "/a"},
+		MustMessage("/a", 1, 2.5, "three", Symbol("four"), []byte{5}, true, nil, Timetag(6)),
+		MustMessage("/arr", Array{Int(1), Array{String("x")}}, Char('c'), RGBA{R: 7}, MIDI{Port: 8}, Infinitum{}),
+		MustMessage("/blob", []byte("odd")),
+	} {
+		for _, prefix := range []string{"", "abcd", "ab"} {
+			expected := append([]byte(prefix), msg.Bytes()...)
+			if got := msg.AppendTo([]byte(prefix)); !bytes.Equal(expected, got) {
+				t.Fatalf("(testcase %d, prefix %q) expected %x, got %x", i, prefix, expected, got)
+			}
+		}
+		// Only a buffer that is too small is reallocated.
+		buf := make([]byte, 0, 256)
+		if got := msg.AppendTo(buf); &got[0] != &buf[:1][0] {
+			t.Fatalf("(testcase %d) expected the buffer to be reused", i)
+		}
+	}
+}
+
+type shortWriter struct{}
+
+func (shortWriter) Write(b []byte) (int, error) { return len(b) / 2, nil }
+
+func TestMessageWriteToErrors(t *testing.T) {
+	open := Message{Address: "/open"}
+	open.BeginArray()
+
+	for i, testcase := range []struct {
+		Message Message
+		Cause   error
+	}{
+		{Message: Message{Address: "/a b"}, Cause: ErrInvalidAddress},
+		{Message: open, Cause: ErrUnbalancedArray},
+		{Message: Message{Address: "/nil", Arguments: []Argument{Int(1), nil}}, Cause: ErrUnsupportedType},
+		{Message: Message{Address: "/nil", Arguments: []Argument{Array{nil}}}, Cause: ErrUnsupportedType},
+	} {
+		var buf bytes.Buffer
+		n, err := testcase.Message.WriteTo(&buf)
+		if !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if n != 0 || buf.Len() != 0 {
+			t.Fatalf("(testcase %d) expected nothing to be written, got %d bytes", i, buf.Len())
+		}
+	}
+
+	// A short write is not an encoding error.
+	n, err := MustMessage("/short", "abc").WriteTo(shortWriter{})
+	if err != io.ErrShortWrite {
+		t.Fatalf("expected %v, got %v", io.ErrShortWrite, err)
+	}
+	if expected, got := int64(8), n; expected != got {
+		t.Fatalf("expected %d bytes written, got %d", expected, got)
+	}
+}
+
+func BenchmarkMessageBytes(b *testing.B) {
+	msg := MustMessage("/b_setn", 1, 0, make([]byte, 4096), make([]byte, 4096), make([]byte, 4096))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = msg.Bytes()
+	}
+}
+
+func BenchmarkMessageAppendTo(b *testing.B) {
+	var (
+		msg = MustMessage("/b_setn", 1, 0, make([]byte, 4096), make([]byte, 4096), make([]byte, 4096))
+		buf []byte
+	)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = msg.AppendTo(buf[:0])
+	}
+}
+
+func BenchmarkMessageWriteTo(b *testing.B) {
+	msg := MustMessage("/b_setn", 1, 0, make([]byte, 4096), make([]byte, 4096), make([]byte, 4096))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := msg.WriteTo(ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// shortInt is an int argument from another package that encodes too few bytes.
+type shortInt struct{ Int }
+
+func (shortInt) Bytes() []byte { return []byte{0, 1} }
+
+func TestMessageValidate(t *testing.T) {
+	open := Message{Address: "/open"}
+	open.BeginArray()
+
+	for i, msg := range []Message{
+		{Address: "/a"},
+		{Address: "/a/*/b?"},
+		{Address: "/a/[0-9]/{x,y}"},
+		MustMessage("/a", 1, "s", Symbol("t"), Char('c'), Array{String("u")}),
+	} {
+		if err := msg.Validate(); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+	}
+	for i, testcase := range []struct {
+		Message  Message
+		Cause    error
+		Expected string
+	}{
+		{
+			Message:  Message{Address: ""},
+			Cause:    ErrInvalidAddress,
+			Expected: `"" does not start with '/': invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "a/b"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"a/b" does not start with '/': invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/a b"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/a b" has ' ' at offset 2: invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/a#"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/a#" has '#' at offset 2: invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/a\x00"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/a\x00" has '\x00' at offset 2: invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/a[0-9"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/a[0-9" has unbalanced '[' at offset 2: invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/a}"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/a}" has unbalanced '}' at offset 2: invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/a[{b}]"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/a[{b}]" has '{' at offset 3 inside '[' at offset 2: invalid OSC address`,
+		},
+		{
+			Message:  Message{Address: "/{a/b}"},
+			Cause:    ErrInvalidAddress,
+			Expected: `"/{a/b}" has '/' at offset 3 inside '{' at offset 1: invalid OSC address`,
+		},
+		{
+			Message:  open,
+			Cause:    ErrUnbalancedArray,
+			Expected: `/open: BeginArray without EndArray: unbalanced array typetags`,
+		},
+		{
+			Message:  Message{Address: "/a", Arguments: Arguments{Int(1), nil}},
+			Cause:    ErrUnsupportedType,
+			Expected: `/a: argument 1: nil argument: unsupported argument type`,
+		},
+		{
+			Message:  MustMessage("/a", "x\x00y"),
+			Expected: `/a: argument 0: string "x\x00y" has a null byte at offset 1`,
+		},
+		{
+			Message:  MustMessage("/a", Array{Symbol("\x00")}),
+			Expected: `/a: argument 0: argument 0: string "\x00" has a null byte at offset 0`,
+		},
+		{
+			Message:  MustMessage("/a", Char(0xE9)),
+			Cause:    ErrNonASCIIChar,
+			Expected: `/a: argument 0: char U+00E9: char is not ASCII`,
+		},
+		{
+			Message:  MustMessage("/a", shortInt{}),
+			Cause:    io.ErrUnexpectedEOF,
+			Expected: `/a: argument 0: osc.shortInt with typetag 'i': read int argument: unexpected EOF`,
+		},
+	} {
+		err := testcase.Message.Validate()
+		if err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+		if testcase.Cause != nil && !errors.Is(err, testcase.Cause) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Cause, err)
+		}
+		if expected, got := testcase.Expected, err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestSendValidates(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	err := c1.Send(Bundle{Timetag: Immediately, Packets: []Packet{Message{Address: "/a b"}}})
+	if !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected %v, got %v", ErrInvalidAddress, err)
+	}
+	if expected, got := `invalid packet: packet 0: "/a b" has ' ' at offset 2: invalid OSC address`, err.Error(); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
 func TestParseMessage(t *testing.T) {
 	type Input struct {
 		data   []byte
@@ -245,7 +900,7 @@ func TestParseMessage(t *testing.T) {
 					Address: "/foo",
 					Arguments: []Argument{
 						Int(1),
-						Blob([]byte{'b', 'a', 'r', 0}),
+						Blob([]byte{'b', 'a', 'r'}),
 					},
 				},
 			},
@@ -275,3 +930,112 @@ func TestParseMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestMessageTypetagStringAndRaw(t *testing.T) {
+	var (
+		msg     = MustMessage("/mixed", int32(1), "two", Array{Float(3), Array{}}, []byte("four"), true)
+		data    = msg.Bytes()
+		unknown = []byte("/unknown\x00\x00\x00\x00,ix\x00\x00\x00\x00\x01\xde\xad\xbe\xef")
+	)
+	built, err := NewBuilder("/mixed").Int32(1).String("two").BeginArray().Float32(3).BeginArray().EndArray().EndArray().Blob([]byte("four")).Bool(true).Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePacket(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := parseMessageView(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := ParsePacket(Bundle{Timetag: 1, Packets: []Packet{msg}}.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		Msg      Message
+		Typetags string
+		Raw      []byte
+	}{
+		{Msg: msg, Typetags: "is[f[]]bT", Raw: data},
+		{Msg: built, Typetags: "is[f[]]bT", Raw: data},
+		{Msg: parsed.(Message), Typetags: "is[f[]]bT", Raw: data},
+		{Msg: view, Typetags: "is[f[]]bT", Raw: data},
+		{Msg: view.Clone(), Typetags: "is[f[]]bT", Raw: data},
+		{Msg: bundle.(Bundle).Packets[0].(Message), Typetags: "is[f[]]bT", Raw: data},
+		{Msg: Message{Address: "/empty"}, Typetags: "", Raw: []byte("/empty\x00\x00,\x00\x00\x00")},
+		{Msg: mustParseLenient(t, unknown), Typetags: "ix", Raw: unknown},
+	} {
+		if got := testcase.Msg.TypetagString(); got != testcase.Typetags {
+			t.Fatalf("(testcase %d) expected typetags %q, got %q", i, testcase.Typetags, got)
+		}
+		if got := testcase.Msg.Raw(); !bytes.Equal(got, testcase.Raw) {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, testcase.Raw, got)
+		}
+		// The typetags agree with the ones that are encoded.
+		if got := string(bytes.TrimRight(testcase.Msg.Typetags(), "\x00")); got != ","+testcase.Typetags && !testcase.Msg.Truncated() {
+			t.Fatalf("(testcase %d) expected typetags %q, got %q", i, ","+testcase.Typetags, got)
+		}
+	}
+
+	// The raw bytes of a parsed message share memory with the data, unless it is cloned.
+	p, err := ParseMessage(append([]byte{}, data...), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, clone := p.Raw(), p.Clone()
+	raw[1] = 'M'
+	if string(p.Raw()[:6]) != "/Mixed" || string(clone.Raw()[:6]) != "/mixed" {
+		t.Fatalf("expected only the raw bytes of the parsed message to change, got %q and %q", p.Raw()[:6], clone.Raw()[:6])
+	}
+}
+
+func TestMessageRawFromConn(t *testing.T) {
+	for i, lazy := range []bool{false, true} {
+		server, err := Listen("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.SetLazyArguments(lazy)
+		type received struct {
+			typetags string
+			raw      []byte
+		}
+		got := make(chan received, 1)
+		go func() {
+			_ = server.Serve(1, PatternMatching{"/raw": Method(func(msg Message) error {
+				got <- received{typetags: msg.TypetagString(), raw: append([]byte{}, msg.Raw()...)}
+				return nil
+			})})
+		}()
+		client, err := Dial(server.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := MustMessage("/raw", int32(1), "s", []byte{1, 2, 3})
+		if err := client.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case r := <-got:
+			if r.typetags != "isb" || !bytes.Equal(r.raw, msg.Bytes()) {
+				t.Fatalf("(testcase %d) expected %q and %q, got %q and %q", i, "isb", msg.Bytes(), r.typetags, r.raw)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(testcase %d) timeout", i)
+		}
+		_ = client.Close() // Best effort.
+		_ = server.Close() // Best effort.
+	}
+}
+
+// mustParseLenient parses data with the typetags that are not known.
+func mustParseLenient(t *testing.T, data []byte) Message {
+	t.Helper()
+	p, err := LenientParsing.ParsePacket(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(Message)
+}
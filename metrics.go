@@ -0,0 +1,126 @@
+package osc
+
+import (
+	"time"
+)
+
+// The reasons that MetricsHook.Dropped is given.
+const (
+	// ReasonQueueFull is a packet that was dropped because the queue of Serve,
+	// the channel of Receive or the channel of a subscription was full.
+	ReasonQueueFull = "queue_full"
+
+	// ReasonSendQueueFull is a packet that was dropped because the queue of an AsyncSender was full.
+	ReasonSendQueueFull = "send_queue_full"
+
+	// ReasonRejected is a packet or a message rejected by the access control.
+	ReasonRejected = "rejected"
+
+	// ReasonRateLimited is a packet beyond the rate limits.
+	ReasonRateLimited = "rate_limited"
+
+	// ReasonUnmatched is a message that no method matched.
+	ReasonUnmatched = "unmatched"
+
+	// ReasonLate is a bundle that was late, with the DropLate policy.
+	ReasonLate = "late"
+)
+
+// BundleAddress is the address that MetricsHook.Dispatched is given for a bundle,
+// which is dispatched as a whole.
+const BundleAddress = "#bundle"
+
+// MetricsHook is told about the traffic of a conn, to export metrics, e.g. to Prometheus.
+// Its methods are called by the goroutines that read, send and dispatch,
+// so they must be safe for concurrent use and should return quickly.
+type MetricsHook interface {
+	// PacketReceived is called with the size of every packet that is read,
+	// before it is parsed.
+	PacketReceived(bytes int)
+
+	// PacketSent is called with the size of every packet that is sent.
+	PacketSent(bytes int)
+
+	// ParseError is called for every packet that can not be parsed.
+	ParseError()
+
+	// Dispatched is called with the address of every message that is dispatched
+	// by Serve, or BundleAddress for a bundle, and how long dispatching took.
+	Dispatched(addr string, d time.Duration)
+
+	// Dropped is called for every packet or message that is dropped,
+	// with one of the Reason constants.
+	Dropped(reason string)
+}
+
+// NopMetrics is a MetricsHook that does nothing. It is the default.
+type NopMetrics struct{}
+
+// PacketReceived does nothing.
+func (NopMetrics) PacketReceived(bytes int) {}
+
+// PacketSent does nothing.
+func (NopMetrics) PacketSent(bytes int) {}
+
+// ParseError does nothing.
+func (NopMetrics) ParseError() {}
+
+// Dispatched does nothing.
+func (NopMetrics) Dispatched(addr string, d time.Duration) {}
+
+// Dropped does nothing.
+func (NopMetrics) Dropped(reason string) {}
+
+// metricsHolder is the type the metrics hook is stored as, so that
+// atomic.Value always holds the same type.
+type metricsHolder struct {
+	hook MetricsHook
+}
+
+// SetMetricsHook makes the conn call the methods of hook as it reads, sends and
+// dispatches packets. It may be set, replaced or removed with nil at any time.
+// The conn does not allocate to call hook, except for the addresses of the
+// messages of a conn with lazy arguments, which are copied out of the read buffer.
+func (s *connState) SetMetricsHook(hook MetricsHook) {
+	s.hooks.metrics.Store(metricsHolder{hook: hook})
+}
+
+// metrics returns the metrics hook, and false if there is none.
+func (s *connState) metrics() (MetricsHook, bool) {
+	h, _ := s.hooks.metrics.Load().(metricsHolder)
+	if h.hook == nil {
+		return NopMetrics{}, false
+	}
+	return h.hook, true
+}
+
+// countDrop tells the metrics hook that a packet or a message was dropped.
+func (s *connState) countDrop(reason string) {
+	if m, ok := s.metrics(); ok {
+		m.Dropped(reason)
+	}
+}
+
+// invokeTimed invokes msg with d and tells the metrics hook how long it took.
+func (s *connState) invokeTimed(d Dispatcher, msg Message, exactMatch bool) error {
+	m, ok := s.metrics()
+	if !ok {
+		return d.Invoke(msg, exactMatch)
+	}
+	start := time.Now()
+	err := d.Invoke(msg, exactMatch)
+	m.Dispatched(msg.ownedAddress(), time.Since(start))
+	return err
+}
+
+// dispatchTimed dispatches bundle with d and tells the metrics hook how long it took.
+func (s *connState) dispatchTimed(d Dispatcher, bundle Bundle, exactMatch bool) error {
+	m, ok := s.metrics()
+	if !ok {
+		return d.Dispatch(bundle, exactMatch)
+	}
+	start := time.Now()
+	err := d.Dispatch(bundle, exactMatch)
+	m.Dispatched(BundleAddress, time.Since(start))
+	return err
+}
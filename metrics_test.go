@@ -0,0 +1,182 @@
+package osc
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingMetrics is a MetricsHook that counts the calls to its methods.
+type countingMetrics struct {
+	mu    sync.Mutex
+	calls map[string]int
+	bytes map[string]int
+}
+
+func newCountingMetrics() *countingMetrics {
+	return &countingMetrics{calls: map[string]int{}, bytes: map[string]int{}}
+}
+
+func (m *countingMetrics) count(call string, bytes int) {
+	m.mu.Lock()
+	m.calls[call]++
+	m.bytes[call] += bytes
+	m.mu.Unlock()
+}
+
+func (m *countingMetrics) PacketReceived(bytes int) { m.count("PacketReceived", bytes) }
+func (m *countingMetrics) PacketSent(bytes int)     { m.count("PacketSent", bytes) }
+func (m *countingMetrics) ParseError()              { m.count("ParseError", 0) }
+func (m *countingMetrics) Dropped(reason string)    { m.count("Dropped "+reason, 0) }
+
+func (m *countingMetrics) Dispatched(addr string, d time.Duration) {
+	if d < 0 {
+		addr += " (negative duration)"
+	}
+	m.count("Dispatched "+addr, 0)
+}
+
+func (m *countingMetrics) snapshot() (map[string]int, map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	calls, bytes := map[string]int{}, map[string]int{}
+	for k, v := range m.calls {
+		calls[k] = v
+	}
+	for k, v := range m.bytes {
+		if v != 0 {
+			bytes[k] = v
+		}
+	}
+	return calls, bytes
+}
+
+func TestMetricsHook(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	serverMetrics, clientMetrics := newCountingMetrics(), newCountingMetrics()
+	server.SetMetricsHook(serverMetrics)
+	client.SetMetricsHook(clientMetrics)
+
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			"/ok": Method(func(msg Message) error {
+				return nil
+			}),
+		})
+	}()
+	var (
+		ok        = MustMessage("/ok", int32(1))
+		unmatched = MustMessage("/unmatched")
+		bundle    = Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/ok")}}
+		garbage   = []byte("garbage!")
+	)
+	size := func(p Packet) int {
+		n, err := packetSize(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	for _, p := range []Packet{ok, unmatched, bundle} {
+		if err := client.Send(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.udpConn.Write(garbage); err != nil {
+		t.Fatal(err)
+	}
+	sent := size(ok) + size(unmatched) + size(bundle)
+
+	for i, testcase := range []struct {
+		Metrics *countingMetrics
+		Calls   map[string]int
+		Bytes   map[string]int
+	}{
+		{
+			Metrics: serverMetrics,
+			Calls: map[string]int{
+				"PacketReceived":              4,
+				"ParseError":                  1,
+				"Dispatched /ok":              1,
+				"Dispatched /unmatched":       1,
+				"Dispatched " + BundleAddress: 1,
+				"Dropped " + ReasonUnmatched:  1,
+			},
+			Bytes: map[string]int{"PacketReceived": sent + len(garbage)},
+		},
+		{
+			Metrics: clientMetrics,
+			Calls:   map[string]int{"PacketSent": 3},
+			Bytes:   map[string]int{"PacketSent": sent},
+		},
+	} {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			calls, bytes := testcase.Metrics.snapshot()
+			if reflect.DeepEqual(calls, testcase.Calls) && reflect.DeepEqual(bytes, testcase.Bytes) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("(testcase %d) expected calls %v and bytes %v, got %v and %v", i, testcase.Calls, testcase.Bytes, calls, bytes)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// Removing the hook stops the calls.
+	client.SetMetricsHook(nil)
+	if err := client.Send(ok); err != nil {
+		t.Fatal(err)
+	}
+	if calls, _ := clientMetrics.snapshot(); calls["PacketSent"] != 3 {
+		t.Fatalf("expected 3 packets sent, got %d", calls["PacketSent"])
+	}
+}
+
+func TestMetricsHook_Allocs(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	c1.SetMetricsHook(allocFreeMetrics{})
+
+	var (
+		d    = c1.withCalls(PatternMatching{"/ok": Method(func(msg Message) error { return nil })})
+		msg  = MustMessage("/ok")
+		p    = Packet(msg)
+		data = []byte("/ok\x00,\x00\x00\x00")
+	)
+	for i, testcase := range []struct {
+		Name string
+		F    func()
+	}{
+		{Name: "received", F: func() { c1.received(nil, data) }},
+		{Name: "sent", F: func() { c1.sent(nil, p, len(data), nil) }},
+		{Name: "dispatched", F: func() { _ = d.Invoke(msg, false) }},
+		{Name: "dropped", F: func() { c1.countDropped(nil) }},
+	} {
+		if allocs := testing.AllocsPerRun(100, testcase.F); allocs != 0 {
+			t.Fatalf("(testcase %d) %s: expected no allocations, got %v", i, testcase.Name, allocs)
+		}
+	}
+}
+
+// allocFreeMetrics is a MetricsHook that does not allocate.
+type allocFreeMetrics struct{ NopMetrics }
@@ -0,0 +1,48 @@
+package osc
+
+import (
+	"log"
+)
+
+// Middleware wraps a method with behavior that many methods share,
+// such as logging, metering or authentication.
+// It can short-circuit a message by returning an error without calling next.
+// The address of the method next calls can be read with msg.MethodAddress.
+type Middleware func(next Method) Method
+
+// chain wraps handler with mws, so that the first of them runs first.
+func chain(handler MessageHandler, mws []Middleware) MessageHandler {
+	if len(mws) == 0 {
+		return handler
+	}
+	next, ok := handler.(Method)
+	if !ok {
+		next = handler.Handle
+	}
+	for i := len(mws) - 1; i >= 0; i-- {
+		next = mws[i](next)
+	}
+	return next
+}
+
+// LogMessages returns a middleware that logs every message along with
+// the address of the method it was dispatched to, and the error the method
+// returned, if any.
+// A nil logger logs with the standard logger of the log package.
+func LogMessages(logger *log.Logger) Middleware {
+	printf := log.Printf
+	if logger != nil {
+		printf = logger.Printf
+	}
+	return func(next Method) Method {
+		return func(msg Message) error {
+			err := next(msg)
+			if err != nil {
+				printf("osc: %s -> %s: %v", msg, msg.MethodAddress(), err)
+			} else {
+				printf("osc: %s -> %s", msg, msg.MethodAddress())
+			}
+			return err
+		}
+	}
+}
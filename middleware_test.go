@@ -0,0 +1,115 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRouterUse(t *testing.T) {
+	var (
+		calls []string
+		r     = NewRouter()
+		sub   = NewRouter()
+	)
+	record := func(name string) Middleware {
+		return func(next Method) Method {
+			return func(msg Message) error {
+				calls = append(calls, name+" "+msg.MethodAddress())
+				return next(msg)
+			}
+		}
+	}
+	method := func(msg Message) error {
+		calls = append(calls, "method "+msg.Address)
+		return nil
+	}
+	if err := r.AddMethod("/a/*", Method(method)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddMethod(Default, Method(method)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.AddMethod("/b", Method(method)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Mount("/sub", sub); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(record("first"), record("second"))
+	r.Use(record("third"))
+	sub.Use(record("inner"))
+
+	for _, addr := range []string{"/a/1", "/sub/b", "/c"} {
+		if err := r.Invoke(Message{Address: addr}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{
+		"first /a/*", "second /a/*", "third /a/*", "method /a/1",
+		"first /sub", "second /sub", "third /sub", "inner /b", "method /b",
+		"first *", "second *", "third *", "method /c",
+	}
+	if expected, got := strings.Join(expected, ", "), strings.Join(calls, ", "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestRouterUseShortCircuit(t *testing.T) {
+	var (
+		r       = NewRouter()
+		invoked = false
+		denied  = errors.New("denied")
+	)
+	if err := r.AddMethod("/secret", Method(func(msg Message) error {
+		invoked = true
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	r.Use(func(next Method) Method {
+		return func(msg Message) error {
+			if _, err := msg.StringAt(0); err != nil {
+				return denied
+			}
+			return next(msg)
+		}
+	})
+	if err := r.Invoke(Message{Address: "/secret"}, false); !errors.Is(err, denied) {
+		t.Fatalf("expected %v, got %v", denied, err)
+	}
+	if invoked {
+		t.Fatal("expected the method to not be invoked")
+	}
+	if err := r.Invoke(Message{Address: "/secret", Arguments: Arguments{String("token")}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if !invoked {
+		t.Fatal("expected the method to be invoked")
+	}
+}
+
+func TestLogMessages(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		logger = log.New(&buf, "", 0)
+		fail   = errors.New("fail")
+	)
+	method := LogMessages(logger)(func(msg Message) error {
+		if msg.Address == "/fail" {
+			return fail
+		}
+		return nil
+	})
+	if err := method(Message{Address: "/ok", Arguments: Arguments{Int(1)}, method: "/o?"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := method(Message{Address: "/fail", method: "/fail"}); err != fail {
+		t.Fatalf("expected %v, got %v", fail, err)
+	}
+	if expected, got := "osc: /ok ,i 1 -> /o?\nosc: /fail , -> /fail: fail\n", buf.String(); expected != got {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
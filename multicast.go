@@ -0,0 +1,159 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Common errors.
+var (
+	ErrNotMulticast = errors.New("connection does not support multicast")
+)
+
+// multicastConn controls the multicast behavior of a UDP socket.
+type multicastConn interface {
+	JoinGroup(ifi *net.Interface, group net.Addr) error
+	LeaveGroup(ifi *net.Interface, group net.Addr) error
+	SetMulticastLoopback(on bool) error
+	SetMulticastTTL(ttl int) error
+}
+
+// ipv6MulticastConn adapts an ipv6.PacketConn to multicastConn.
+type ipv6MulticastConn struct {
+	*ipv6.PacketConn
+}
+
+// SetMulticastTTL sets the hop limit of outgoing multicast packets.
+func (c ipv6MulticastConn) SetMulticastTTL(ttl int) error {
+	return c.SetMulticastHopLimit(ttl)
+}
+
+// ListenMulticastUDP creates a UDP server that has joined the multicast group gaddr.
+// The server listens on the port of gaddr.
+// If ifi is nil the system chooses the interface used to join the group.
+// Several servers on the same machine may listen to the same group.
+func ListenMulticastUDP(network string, ifi *net.Interface, gaddr *net.UDPAddr, opts ...Option) (*UDPConn, error) {
+	return ListenMulticastUDPContext(context.Background(), network, ifi, gaddr, opts...)
+}
+
+// ListenMulticastUDPContext creates a UDP server that has joined the multicast group gaddr
+// and that can be canceled with the provided context.
+func ListenMulticastUDPContext(ctx context.Context, network string, ifi *net.Interface, gaddr *net.UDPAddr, opts ...Option) (*UDPConn, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkDialOptions(); err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP(network, ifi, gaddr)
+	if err != nil {
+		return nil, err
+	}
+	uc := &UDPConn{
+		udpConn:   conn,
+		connState: newConnState(ctx),
+	}
+	return uc.initialize(o)
+}
+
+// WithMulticastLoopback controls whether multicast packets sent by a UDP
+// connection are delivered back to listeners on the sending machine.
+// Most systems enable loopback by default.
+func WithMulticastLoopback(on bool) Option {
+	return func(o *options) error {
+		o.multicastLoopback = &on
+		return nil
+	}
+}
+
+// WithMulticastTTL sets the time-to-live, or hop limit for IPv6,
+// of multicast packets sent by a UDP connection.
+// The default of 1 keeps multicast packets on the local network.
+func WithMulticastTTL(ttl int) Option {
+	return func(o *options) error {
+		if ttl < 0 || ttl > 255 {
+			return fmt.Errorf("multicast TTL must be between 0 and 255, got %d", ttl)
+		}
+		o.multicastTTL = &ttl
+		return nil
+	}
+}
+
+// JoinGroup joins the multicast group gaddr on the interface ifi.
+// If ifi is nil the system chooses the interface.
+// Only packets sent to the port the connection is bound to are received.
+func (conn *UDPConn) JoinGroup(ifi *net.Interface, gaddr *net.UDPAddr) error {
+	mc, err := conn.multicast(gaddr.IP)
+	if err != nil {
+		return err
+	}
+	if err := mc.JoinGroup(ifi, gaddr); err != nil {
+		return fmt.Errorf("joining group %s: %w", gaddr, err)
+	}
+	return nil
+}
+
+// LeaveGroup leaves the multicast group gaddr on the interface ifi.
+func (conn *UDPConn) LeaveGroup(ifi *net.Interface, gaddr *net.UDPAddr) error {
+	mc, err := conn.multicast(gaddr.IP)
+	if err != nil {
+		return err
+	}
+	if err := mc.LeaveGroup(ifi, gaddr); err != nil {
+		return fmt.Errorf("leaving group %s: %w", gaddr, err)
+	}
+	return nil
+}
+
+// multicast returns the multicast controls of the underlying socket
+// for the address family of ip.
+func (conn *UDPConn) multicast(ip net.IP) (multicastConn, error) {
+	c, ok := conn.udpConn.(*net.UDPConn)
+	if !ok {
+		return nil, ErrNotMulticast
+	}
+	if ip == nil || ip.To4() != nil {
+		return ipv4.NewPacketConn(c), nil
+	}
+	return ipv6MulticastConn{PacketConn: ipv6.NewPacketConn(c)}, nil
+}
+
+// multicastIP returns the address that determines the address family
+// used for the multicast options: the remote address of a dialed
+// connection or the local address of a listener.
+func (conn *UDPConn) multicastIP() net.IP {
+	for _, addr := range []net.Addr{conn.RemoteAddr(), conn.LocalAddr()} {
+		if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr != nil && !udpAddr.IP.IsUnspecified() {
+			return udpAddr.IP
+		}
+	}
+	return nil
+}
+
+// setMulticastOptions applies the multicast options to the connection.
+func (conn *UDPConn) setMulticastOptions(o options) error {
+	if o.multicastLoopback == nil && o.multicastTTL == nil {
+		return nil
+	}
+	mc, err := conn.multicast(conn.multicastIP())
+	if err != nil {
+		return err
+	}
+	if o.multicastLoopback != nil {
+		if err := mc.SetMulticastLoopback(*o.multicastLoopback); err != nil {
+			return fmt.Errorf("setting multicast loopback: %w", err)
+		}
+	}
+	if o.multicastTTL != nil {
+		if err := mc.SetMulticastTTL(*o.multicastTTL); err != nil {
+			return fmt.Errorf("setting multicast TTL: %w", err)
+		}
+	}
+	return nil
+}
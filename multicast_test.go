@@ -1,78 +1,136 @@
 package osc
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 )
 
+// multicastInterface returns an interface that is up and supports multicast,
+// or skips the test if there is none.
+func multicastInterface(t *testing.T) *net.Interface {
+	ifis, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ifi := range ifis {
+		if ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagMulticast != 0 {
+			return &ifi
+		}
+	}
+	t.Skip("no multicast interface")
+	return nil
+}
+
+// testMulticastServer listens to gaddr and returns the server and a channel
+// that emits the messages it receives.
+func testMulticastServer(t *testing.T, ifi *net.Interface, gaddr *net.UDPAddr) (*UDPConn, chan Message) {
+	server, err := ListenMulticastUDP("udp4", ifi, gaddr)
+	if err != nil {
+		t.Skipf("can not listen to multicast group: %s", err)
+	}
+	msgs := make(chan Message, 1)
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			"/mcast/method": Method(func(msg Message) error {
+				msgs <- msg
+				return nil
+			}),
+		})
+	}()
+	return server, msgs
+}
+
 func TestMulticastSend(t *testing.T) {
-	// const group = "224.10.10.1:9999"
-
-	// // BUG(briansorahan): How to reliably test multicast everywhere?
-	// ifi, err := net.InterfaceByIndex(4)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// gaddr, err := net.ResolveUDPAddr("udp", group)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// server1, err := ListenMulticastUDP("udp", ifi, gaddr)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// defer func() { _ = server1.Close() }() // Best effort.
-
-	// server2, err := ListenMulticastUDP("udp", ifi, gaddr)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// defer func() { _ = server2.Close() }() // Best effort.
-
-	// var (
-	// 	errChan = make(chan error)
-	// 	wg      = &sync.WaitGroup{}
-	// )
-
-	// wg.Add(2)
-
-	// go func() {
-	// 	errChan <- server1.Serve(map[string]Method{
-	// 		"/mcast/method": func(msg *Message) error {
-	// 			wg.Done()
-	// 			return nil
-	// 		},
-	// 	})
-	// }()
-
-	// go func() {
-	// 	errChan <- server2.Serve(map[string]Method{
-	// 		"/mcast/method": func(msg *Message) error {
-	// 			wg.Done()
-	// 			return nil
-	// 		},
-	// 	})
-	// }()
-
-	// client, err := DialUDP("udp", nil, gaddr)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-
-	// msg, err := NewMessage("/mcast/method")
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-
-	// if err := client.Send(msg); err != nil {
-	// 	t.Fatal(err)
-	// }
-
-	// select {
-	// default:
-	// 	wg.Wait()
-	// case err := <-errChan:
-	// 	if err != nil {
-	// 		t.Fatal(err)
-	// 	}
-	// }
+	ifi := multicastInterface(t)
+
+	gaddr := &net.UDPAddr{IP: net.IPv4(239, 255, 77, 1), Port: 0}
+	server1, msgs1 := testMulticastServer(t, ifi, gaddr)
+	defer func() { _ = server1.Close() }() // Best effort.
+
+	// Listen on the same port as the first server.
+	gaddr.Port = server1.LocalAddr().(*net.UDPAddr).Port
+
+	server2, msgs2 := testMulticastServer(t, ifi, gaddr)
+	defer func() { _ = server2.Close() }() // Best effort.
+
+	client, err := DialUDP("udp4", nil, gaddr, WithMulticastLoopback(true), WithMulticastTTL(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	expected := Message{Address: "/mcast/method", Arguments: Arguments{Int(1)}}
+	if err := client.Send(expected); err != nil {
+		t.Skipf("can not send to multicast group: %s", err)
+	}
+	for _, msgs := range []chan Message{msgs1, msgs2} {
+		select {
+		case got := <-msgs:
+			if !expected.Equal(got) {
+				t.Fatalf("expected %s, got %s", expected, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for multicast message")
+		}
+	}
+}
+
+func TestUDPConnJoinGroup(t *testing.T) {
+	ifi := multicastInterface(t)
+
+	gaddr := &net.UDPAddr{IP: net.IPv4(239, 255, 77, 2), Port: 0}
+	server, msgs := testMulticastServer(t, ifi, gaddr)
+	defer func() { _ = server.Close() }() // Best effort.
+
+	other := &net.UDPAddr{IP: net.IPv4(239, 255, 77, 3), Port: server.LocalAddr().(*net.UDPAddr).Port}
+	if err := server.JoinGroup(ifi, other); err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp4", nil, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	if err := client.Send(Message{Address: "/mcast/method"}); err != nil {
+		t.Skipf("can not send to multicast group: %s", err)
+	}
+	select {
+	case <-msgs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for multicast message")
+	}
+	if err := server.LeaveGroup(ifi, other); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMulticastOptions(t *testing.T) {
+	if _, err := DialUDP("udp", nil, nil, WithMulticastTTL(256)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	uc := &UDPConn{udpConn: errUDPConn{}}
+	if err := uc.JoinGroup(nil, &net.UDPAddr{IP: net.IPv4(239, 255, 77, 1)}); err != ErrNotMulticast {
+		t.Fatalf("expected ErrNotMulticast, got %v", err)
+	}
+}
+
+// socketUDPConn is a socket that is not a *net.UDPConn, so it has no multicast controls.
+type socketUDPConn struct {
+	udpConn
+}
+
+func TestMulticastOptionsError(t *testing.T) {
+	socket := listenSocket(t)
+	o, err := newOptions([]Option{WithMulticastTTL(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := &UDPConn{udpConn: socketUDPConn{udpConn: socket}, connState: newConnState(context.Background())}
+	if _, err := uc.initialize(o); err != ErrNotMulticast {
+		t.Fatalf("expected ErrNotMulticast, got %v", err)
+	}
+	checkSocketClosed(t, socket)
 }
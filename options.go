@@ -0,0 +1,223 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Common errors.
+var (
+	ErrUnsupportedOption = errors.New("option not supported by this transport")
+)
+
+// Option configures a connection.
+// Options are passed to the Dial and Listen functions.
+type Option func(*options) error
+
+// options holds the configuration built up by a list of Option.
+type options struct {
+	framing        Framing
+	maxPacketSize  int
+	readBufferSize int
+	batchSize      int
+	checkOrigin    func(r *http.Request) bool
+	allowSource    func(addr net.Addr) bool
+	allowMethod    func(addr net.Addr, oscAddr string) bool
+
+	rateLimit       rateLimit
+	sourceRateLimit rateLimit
+	maxSources      int
+
+	logger *slog.Logger
+
+	parse *ParseOptions
+
+	reResolve time.Duration
+	resolver  func(ctx context.Context, network, address string) (*net.UDPAddr, error)
+	localAddr *net.UDPAddr
+	iface     string
+
+	multicastLoopback *bool
+	multicastTTL      *int
+	broadcast         bool
+}
+
+// defaultOptions returns the options a connection starts with.
+func defaultOptions() options {
+	return options{
+		framing:       LengthPrefix,
+		maxPacketSize: bufSize,
+	}
+}
+
+// newOptions applies opts on top of the default options.
+func newOptions(opts []Option) (options, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// WithMaxPacketSize sets the largest packet, in bytes, that a stream-oriented
+// connection will accept from its peers.
+// Size prefixes larger than this are rejected before any of the packet is read.
+// The default is 65536 bytes.
+func WithMaxPacketSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("max packet size must be positive, got %d", n)
+		}
+		o.maxPacketSize = n
+		return nil
+	}
+}
+
+// WithReadBufferSize sets the size of the buffer that each datagram is read into,
+// which is the size of the largest packet a UDP connection accepts.
+// Larger datagrams are passed to the error handler as a *ParseError
+// wrapping ErrPacketTruncated instead of being parsed.
+// The default is 65536 bytes.
+func WithReadBufferSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("read buffer size must be positive, got %d", n)
+		}
+		o.readBufferSize = n
+		return nil
+	}
+}
+
+// WithBatchSize makes a UDP connection read up to n datagrams with a single
+// system call when it serves, and write the packets passed to SendBatch with
+// a single system call, on platforms that support it. Elsewhere the option has
+// no effect and datagrams are read and written one at a time.
+// Every datagram of a batch is still dispatched on its own, with its own sender.
+// The connection keeps n read buffers of the read buffer size.
+// The default is 1, which reads one datagram at a time.
+func WithBatchSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("batch size must be positive, got %d", n)
+		}
+		o.batchSize = n
+		return nil
+	}
+}
+
+// WithAccessControl makes a conn drop every packet it reads from a sender that
+// allow returns false for, before the packet is parsed.
+// Dropped packets are counted by Rejected and passed to the error handler
+// as an error wrapping ErrRejectedSource, which never stops the server.
+// allow is called by the goroutine that reads, so it should return quickly.
+func WithAccessControl(allow func(addr net.Addr) bool) Option {
+	return func(o *options) error {
+		o.allowSource = allow
+		return nil
+	}
+}
+
+// WithMethodAccessControl makes Serve drop every message that allow returns false
+// for, given the sender and the address pattern of the message, instead of
+// dispatching it. The messages of bundles are checked one by one.
+// Dropped messages are counted and reported like the packets WithAccessControl drops.
+// allow must be safe for concurrent use by the workers of Serve.
+func WithMethodAccessControl(allow func(addr net.Addr, oscAddr string) bool) Option {
+	return func(o *options) error {
+		o.allowMethod = allow
+		return nil
+	}
+}
+
+// WithRateLimit drops the packets a conn reads beyond packets per second,
+// or beyond bytes per second, before they are parsed. Zero means no limit.
+// Bursts of up to one second of packets and bytes are let through.
+// Dropped packets are counted by RateLimited and are not passed to the error handler.
+func WithRateLimit(packets, bytes int) Option {
+	return func(o *options) error {
+		if packets < 0 || bytes < 0 {
+			return fmt.Errorf("rate limit must not be negative, got %d packets and %d bytes", packets, bytes)
+		}
+		o.rateLimit = rateLimit{packets: packets, bytes: bytes}
+		return nil
+	}
+}
+
+// WithSourceRateLimit is like WithRateLimit, but every source IP has its own limits.
+// The limits of the maxSources IPs that sent packets most recently are remembered,
+// and an IP that is forgotten starts again with a full burst.
+// It can be combined with WithRateLimit, which then limits all the sources together.
+func WithSourceRateLimit(packets, bytes, maxSources int) Option {
+	return func(o *options) error {
+		if packets < 0 || bytes < 0 {
+			return fmt.Errorf("rate limit must not be negative, got %d packets and %d bytes", packets, bytes)
+		}
+		if maxSources <= 0 {
+			return fmt.Errorf("max sources must be positive, got %d", maxSources)
+		}
+		o.sourceRateLimit = rateLimit{packets: packets, bytes: bytes}
+		o.maxSources = maxSources
+		return nil
+	}
+}
+
+// WithLogger makes a conn log the packets it does not dispatch, and the stages
+// of Shutdown, with logger. The packets that are rejected or rate limited and
+// the stages of Shutdown are logged at the Debug level, and the packets that can
+// not be parsed or are dropped otherwise, and a Shutdown that gives up, at the
+// Warn level. A peer whose address WithReResolve changes is logged at the Info level.
+// The attributes are named by the Log constants.
+// The default is nil, which logs nothing and costs nothing.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+// WithParseOptions makes a conn parse the packets it reads with o,
+// which selects the deviations from the OSC specification that are tolerated.
+// Without it packets are parsed like ParsePacket does.
+// Messages are not views over the read buffer while the conn has parse options,
+// even if SetLazyArguments is set.
+func WithParseOptions(o ParseOptions) Option {
+	return func(opts *options) error {
+		opts.parse = &o
+		return nil
+	}
+}
+
+// newStreamOptions applies opts for a stream-oriented connection.
+// Options that only make sense for UDP are rejected.
+func newStreamOptions(opts []Option) (options, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return o, err
+	}
+	if o.broadcast {
+		return o, fmt.Errorf("WithBroadcast requires a UDP connection: %w", ErrUnsupportedOption)
+	}
+	if o.readBufferSize != 0 {
+		return o, fmt.Errorf("WithReadBufferSize requires a UDP connection, use WithMaxPacketSize: %w", ErrUnsupportedOption)
+	}
+	if o.batchSize != 0 {
+		return o, fmt.Errorf("WithBatchSize requires a UDP connection: %w", ErrUnsupportedOption)
+	}
+	if o.multicastLoopback != nil || o.multicastTTL != nil {
+		return o, fmt.Errorf("multicast options require a UDP connection: %w", ErrUnsupportedOption)
+	}
+	if o.reResolve != 0 || o.resolver != nil {
+		return o, fmt.Errorf("WithReResolve and WithResolver require a UDP connection: %w", ErrUnsupportedOption)
+	}
+	if o.localAddr != nil || o.iface != "" {
+		return o, fmt.Errorf("WithLocalAddr and WithInterface require a UDP connection: %w", ErrUnsupportedOption)
+	}
+	return o, nil
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"net"
-	"strings"
-
-	"github.com/pkg/errors"
+	"sync"
+	"time"
 )
 
 const (
@@ -24,6 +26,18 @@ const (
 	TypetagBlob   byte = 'b'
 	TypetagFalse  byte = 'F'
 	TypetagTrue   byte = 'T'
+
+	TypetagSymbol     byte = 'S'
+	TypetagTimetag    byte = 't'
+	TypetagInt64      byte = 'h'
+	TypetagDouble     byte = 'd'
+	TypetagChar       byte = 'c'
+	TypetagRGBA       byte = 'r'
+	TypetagMIDI       byte = 'm'
+	TypetagNil        byte = 'N'
+	TypetagArrayStart byte = '['
+	TypetagArrayEnd   byte = ']'
+	TypetagInfinitum  byte = 'I'
 )
 
 var (
@@ -44,9 +58,6 @@ type Packet interface {
 // This means that the returned byte slice is padded with null bytes
 // so that it's length is a multiple of 4.
 func ToBytes(s string) []byte {
-	if len(s) == 0 {
-		return []byte{}
-	}
 	return Pad(append([]byte(s), 0))
 }
 
@@ -58,6 +69,42 @@ func Pad(b []byte) []byte {
 	return b
 }
 
+// padded returns n rounded up to a multiple of 4.
+func padded(n int) int {
+	return (n + 3) &^ 3
+}
+
+// stringSize returns the size of s when it is encoded with ToBytes.
+func stringSize(s string) int {
+	return padded(len(s) + 1)
+}
+
+// appendString appends s to b the way ToBytes encodes it.
+// b must have a multiple of 4 bytes.
+func appendString(b []byte, s string) []byte {
+	return Pad(append(append(b, s...), 0))
+}
+
+// appendUint32 appends v to b in network byte order.
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendUint64 appends v to b in network byte order.
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v>>32)), uint32(v))
+}
+
+// writeEncoded writes an encoded packet to w with a single call to Write.
+// Writers that accept fewer bytes without an error cause io.ErrShortWrite.
+func writeEncoded(w io.Writer, data []byte) (int64, error) {
+	n, err := w.Write(data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	return int64(n), err
+}
+
 // ReadString reads a string from a byte slice.
 // If the byte slice does not have any null bytes,
 // then one is appended to the end.
@@ -101,6 +148,18 @@ func ReadBlob(length int32, data []byte) ([]byte, int64) {
 type Incoming struct {
 	Data   []byte
 	Sender net.Addr
+
+	// Received is when the data was read.
+	Received time.Time
+
+	// Packet is the packet parsed from Data, and Err is the error that Data
+	// could not be parsed with. Only Receive sets them.
+	// The read error that ends Receive is given with no Data.
+	Packet Packet
+	Err    error
+
+	// buf is the read buffer that holds Data, if it came from one.
+	buf *readBuffer
 }
 
 type netWriter interface {
@@ -108,6 +167,8 @@ type netWriter interface {
 	WriteTo([]byte, net.Addr) (int, error)
 }
 
+// checkDispatcher returns an error if dispatcher is nil
+// or has a method whose address is not Default or a valid address pattern.
 func checkDispatcher(dispatcher Dispatcher) error {
 	if dispatcher == nil {
 		return ErrNilDispatcher
@@ -115,7 +176,10 @@ func checkDispatcher(dispatcher Dispatcher) error {
 	messageHandlers, ok := dispatcher.(PatternMatching)
 	if ok {
 		for addr := range messageHandlers {
-			if err := ValidateAddress(addr); err != nil {
+			if addr == Default {
+				continue
+			}
+			if err := validateAddressPattern(addr); err != nil {
 				return err
 			}
 		}
@@ -123,62 +187,447 @@ func checkDispatcher(dispatcher Dispatcher) error {
 	return nil
 }
 
+// ParsePacket parses a message or a bundle from raw bytes, such as the
+// payload of a captured datagram.
+// The returned packet does not share any memory with data.
+func ParsePacket(data []byte) (Packet, error) {
+	return clonePacket(parsePacket(data, nil, nil))
+}
+
+// clonePacket returns a copy of p that does not share any memory with the data it was parsed from.
+func clonePacket(p Packet, err error) (Packet, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch x := p.(type) {
+	case Bundle:
+		return x.Clone(), nil
+	case Message:
+		return x.Clone(), nil
+	default:
+		return p, nil
+	}
+}
+
+// encodePacket validates p and encodes it for sending.
+func encodePacket(p Packet) ([]byte, error) {
+	size, err := packetSize(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packet: %w", err)
+	}
+	return appendPacket(make([]byte, 0, size), p), nil
+}
+
+// maxSendBuffer is the size of the largest send buffer that is kept for the next packet.
+const maxSendBuffer = 1 << 16
+
+// sendBuffer is the buffer a conn encodes packets into before it sends them,
+// so that sending a packet does not allocate.
+type sendBuffer struct {
+	// sem holds a token while a packet is encoded and written.
+	// It is a channel rather than a mutex so that SendContext can give up waiting for it.
+	semOnce sync.Once
+	sem     chan struct{}
+	data    []byte
+	packets [][]byte
+}
+
+// lock waits for the buffer until ctx is done.
+func (b *sendBuffer) lock(ctx context.Context) error {
+	b.semOnce.Do(func() { b.sem = make(chan struct{}, 1) })
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlock releases the buffer for the next packet.
+func (b *sendBuffer) unlock() {
+	<-b.sem
+}
+
+// send validates p, encodes it and passes it to write, which must not keep it.
+// It returns the size of the encoded packet once it has been written.
+// Packets are encoded and written one at a time, and send gives up
+// waiting for the packets before p when ctx is done.
+func (b *sendBuffer) send(ctx context.Context, p Packet, write func(data []byte) error) (int, error) {
+	size, err := packetSize(p)
+	if err != nil {
+		return 0, fmt.Errorf("invalid packet: %w", err)
+	}
+	if err := b.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer b.unlock()
+
+	if cap(b.data) < size {
+		b.data = make([]byte, 0, size)
+	}
+	data := appendPacket(b.data[:0], p)
+	if cap(data) <= maxSendBuffer {
+		b.data = data
+	} else {
+		b.data = nil
+	}
+	if err := write(data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// sendAll validates ps, encodes them one after the other and passes them to write,
+// which must not keep them.
+func (b *sendBuffer) sendAll(ps []Packet, write func(packets [][]byte) error) error {
+	total := 0
+	for i, p := range ps {
+		size, err := packetSize(p)
+		if err != nil {
+			return fmt.Errorf("invalid packet %d: %w", i, err)
+		}
+		total += size
+	}
+	_ = b.lock(context.Background()) // Never fails.
+	defer b.unlock()
+
+	if cap(b.data) < total {
+		b.data = make([]byte, 0, total)
+	}
+	data, packets := b.data[:0], b.packets[:0]
+	for _, p := range ps {
+		start := len(data)
+		data = appendPacket(data, p)
+		packets = append(packets, data[start:len(data):len(data)])
+	}
+	if cap(data) <= maxSendBuffer {
+		b.data, b.packets = data, packets[:0]
+	} else {
+		b.data, b.packets = nil, nil
+	}
+	return write(packets)
+}
+
+// parsePacket parses a message or a bundle from data.
+// Messages whose address is not a valid address pattern are rejected.
+// Deviations from the specification are tolerated as o allows,
+// or as ParsePacket does if o is nil. Errors are a *ParseError.
+func parsePacket(data []byte, sender net.Addr, o *ParseOptions) (Packet, error) {
+	if len(data) == 0 {
+		return nil, &ParseError{Sender: sender, Err: ErrParse}
+	}
+	switch data[0] {
+	case BundleTag[0]:
+		b, err := parseBundle(data, sender, -1, newParseState(o))
+		if err != nil {
+			return b, parseError(err, sender)
+		}
+		return b, nil
+	case MessageChar:
+		msg, err := parseMessage(data, sender, o)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateAddressPattern(msg.Address); err != nil {
+			pe := &ParseError{Sender: sender, Address: msg.Address, Err: err}
+			var ae *AddressError
+			if errors.As(err, &ae) {
+				pe.Offset = ae.Offset
+			}
+			return nil, pe
+		}
+		return msg, nil
+	default:
+		return nil, &ParseError{Sender: sender, Err: ErrParse}
+	}
+}
+
+// parseError returns err from sender as a *ParseError.
+// The address and the offset come from the *ParseError that err wraps, if there is one.
+func parseError(err error, sender net.Addr) *ParseError {
+	var inner *ParseError
+	if !errors.As(err, &inner) {
+		return &ParseError{Sender: sender, Err: err}
+	}
+	if error(inner) == err {
+		return inner
+	}
+	return &ParseError{Sender: sender, Address: inner.Address, Offset: inner.Offset, Err: err}
+}
+
 // readSender knows how to read bytes and return the net.Addr
 // of the sender of the bytes.
 type readSender interface {
 	CloseChan() <-chan struct{}
 	Context() context.Context
 	read([]byte) (int, net.Addr, error)
+	readBufferSize() int
+	shutdownChan() <-chan struct{}
+	startServing() (bool, error)
+	doneServing()
+	handleError(error)
+	handleUnmatched(msg Message, err error)
+	strictMode() bool
+	recoverPanics() bool
+	newScheduler(dispatcher Dispatcher, exactMatch bool) *scheduler
+	newQueue(gate *dispatchGate) *packetQueue
+	lazyArguments() bool
+	newBuffers() *readBuffers
+	withCalls(d Dispatcher) Dispatcher
+	received(addr net.Addr, data []byte)
+	admit(sender net.Addr, size int) error
+	tracer() func(TraceEvent)
+	parseOptions() *ParseOptions
+	Reply(to net.Addr, msg Message) error
+}
+
+// readDeadliner is implemented by the datagram conns, whose pending read
+// can be interrupted without losing track of packet boundaries.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// dispatchGate tracks the packets that have been handed to workers,
+// so that serve can stop handing them out and wait for the ones in flight.
+type dispatchGate struct {
+	mu       sync.Mutex
+	inFlight sync.WaitGroup
+	stopped  chan struct{}
+}
+
+// newDispatchGate creates an open gate.
+func newDispatchGate() *dispatchGate {
+	return &dispatchGate{stopped: make(chan struct{})}
+}
+
+// enter returns false if the gate has been stopped.
+// Otherwise the caller must hand a packet to a worker, which calls inFlight.Done.
+func (g *dispatchGate) enter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.stopped:
+		return false
+	default:
+	}
+	g.inFlight.Add(1)
+	return true
+}
+
+// stop stops the gate from letting packets through.
+func (g *dispatchGate) stop() {
+	g.mu.Lock()
+	close(g.stopped)
+	g.mu.Unlock()
 }
 
-func serve(r readSender, numWorkers int, exactMatch bool, dispatcher Dispatcher) error {
+func serve(ctx context.Context, r readSender, numWorkers int, exactMatch bool, dispatcher Dispatcher) error {
 	if err := checkDispatcher(dispatcher); err != nil {
 		return err
 	}
+	if ok, err := r.startServing(); !ok {
+		return err
+	}
+	defer r.doneServing()
+
+	dispatcher = r.withCalls(dispatcher)
+
+	// The methods are told to stop when serving stops.
+	methodCtx, cancelMethods := context.WithCancel(ctx)
+	defer cancelMethods()
+
 	var (
-		errChan = make(chan error)
-		ready   = make(chan worker, numWorkers)
+		errChan  = make(chan error)
+		ready    = make(chan worker, numWorkers)
+		gate     = newDispatchGate()
+		queue    = r.newQueue(gate)
+		buffers  = r.newBuffers()
+		sched    = r.newScheduler(dispatcher, exactMatch)
+		loopDone = make(chan struct{})
+		workers  = make([]worker, numWorkers)
 	)
-	for i := 0; i < numWorkers; i++ {
-		go worker{
+	for i := range workers {
+		workers[i] = worker{
 			DataChan:   make(chan Incoming),
 			Dispatcher: dispatcher,
 			ErrChan:    errChan,
 			Ready:      ready,
 			ExactMatch: exactMatch,
-		}.run()
+			InFlight:   &gate.inFlight,
+			Replier:    r,
+			Context:    methodCtx,
+			Scheduler:  sched,
+			Buffers:    buffers,
+			Lazy:       r.lazyArguments(),
+			Parse:      r.parseOptions(),
+
+			HandleError: r.handleError,
+			Recover:     r.recoverPanics(),
+			Strict:      r.strictMode(),
+		}
+		go workers[i].run()
 	}
-	go workerLoop(r, ready, errChan)
+	if queue != nil {
+		go queue.feed(ready)
+	}
+	go func() {
+		workerLoop(r, ready, errChan, gate, queue, buffers)
+		close(loopDone)
+	}()
 
 	// If the connection is closed or the context is canceled then stop serving.
+	var err error
 	select {
-	case err := <-errChan:
-		return errors.Wrap(err, "error serving udp")
+	case err = <-errChan:
+		if err != nil {
+			err = fmt.Errorf("error serving udp: %w", err)
+		}
 	case <-r.CloseChan():
+	case <-r.shutdownChan():
 	case <-r.Context().Done():
-		return r.Context().Err()
+		err = r.Context().Err()
+	case <-ctx.Done():
+		err = ctx.Err()
 	}
-	return nil
+	cancelMethods()
+	stopServing(r, gate, queue, errChan, loopDone, workers)
+	if sched != nil {
+		sched.stop()
+	}
+	return err
+}
+
+// stopServing stops handing out packets, lets the methods that are
+// already running and the packets in the queue finish, and stops the workers.
+// The conn is left open, so it can be served again.
+func stopServing(r readSender, gate *dispatchGate, queue *packetQueue, errChan chan error, loopDone chan struct{}, workers []worker) {
+	gate.stop()
+
+	// Interrupt the pending read if we can, so the read loop is gone
+	// by the time we return.
+	rd, interrupt := r.(readDeadliner)
+	if interrupt {
+		select {
+		case <-r.CloseChan():
+			interrupt = false
+		default:
+			interrupt = rd.SetReadDeadline(time.Now()) == nil
+		}
+	}
+	inFlight := make(chan struct{})
+	go func() {
+		gate.inFlight.Wait()
+		close(inFlight)
+	}()
+	for inFlight != nil || interrupt && loopDone != nil {
+		select {
+		case <-errChan:
+			// We are already stopping, so later errors have nowhere to go.
+		case <-inFlight:
+			inFlight = nil
+		case <-loopDone:
+			loopDone = nil
+		}
+	}
+	if interrupt {
+		_ = rd.SetReadDeadline(time.Time{}) // Best effort.
+	}
+	// Nothing is sent to the workers once the gate is stopped and drained.
+	if queue != nil {
+		queue.close()
+	}
+	for _, w := range workers {
+		close(w.DataChan)
+	}
+}
+
+// reportReadError passes an error caused by a packet the read loop dropped to the error handler.
+// It returns false if the read loop should stop, because the conn is in strict mode.
+func reportReadError(r readSender, errChan chan error, gate *dispatchGate, err error) bool {
+	r.handleError(err)
+	if !r.strictMode() {
+		return true
+	}
+	select {
+	case errChan <- err:
+	case <-r.CloseChan():
+	case <-gate.stopped:
+	}
+	return false
 }
 
-func workerLoop(r readSender, ready chan worker, errChan chan error) {
+// workerLoop reads packets and hands them to the workers, or to queue if it is not nil.
+func workerLoop(r readSender, ready chan worker, errChan chan error, gate *dispatchGate, queue *packetQueue, buffers *readBuffers) {
 	for {
-		data := make([]byte, bufSize)
-		_, sender, err := r.read(data)
+		// Datagrams that do not fit are cut short without an error,
+		// so one more byte than the limit shows which ones are truncated.
+		size := r.readBufferSize()
+		buf := buffers.get(size + 1)
+		n, sender, err := r.read(buf.data)
+		received := time.Now()
 		if err != nil {
-			// Tried non-blocking select on closeChan right before ReadFromUDP
-			// but that didn't stop us from reading a closed connection. [briansorahan]
-			if strings.Contains(err.Error(), "use of closed network connection") {
+			buffers.put(buf)
+			// The conn may have closed itself, e.g. when a stream peer hangs up,
+			// or serve may have interrupted the read.
+			select {
+			case <-r.CloseChan():
+				return
+			case <-gate.stopped:
 				return
+			default:
+			}
+			// The socket may also have been closed under the conn.
+			// Serve returns nil then, as it does when the conn is closed.
+			if errors.Is(err, net.ErrClosed) {
+				err = nil
+			}
+			select {
+			case errChan <- err:
+			case <-r.CloseChan():
+			case <-gate.stopped:
 			}
-			errChan <- err
 			return
 		}
+		r.received(sender, buf.data[:n])
+		if r.admit(sender, n) != nil {
+			buffers.put(buf)
+			continue
+		}
+		if n > size {
+			err := &ParseError{Sender: sender, Err: fmt.Errorf("datagram is larger than the %d byte read buffer: %w", size, ErrPacketTruncated)}
+			buffers.put(buf)
+			if !reportReadError(r, errChan, gate, err) {
+				return
+			}
+			continue
+		}
+		buf.data = buf.data[:n]
+		incoming := Incoming{Data: buf.data, Sender: sender, Received: received, buf: buf}
 
+		if queue != nil {
+			if !gate.enter() {
+				return
+			}
+			queue.push(incoming)
+			continue
+		}
 		// Get the next worker.
-		worker := <-ready
-
+		var worker worker
+		select {
+		case worker = <-ready:
+		case <-gate.stopped:
+			return
+		}
+		if !gate.enter() {
+			return
+		}
 		// Assign them the data we just read.
-		worker.DataChan <- Incoming{Data: data, Sender: sender}
+		worker.DataChan <- incoming
 	}
 }
@@ -12,7 +12,7 @@ func TestToBytes(t *testing.T) {
 	}{
 		{
 			Input:    "",
-			Expected: []byte{},
+			Expected: []byte{0, 0, 0, 0},
 		},
 		{
 			Input:    "a",
@@ -153,3 +153,108 @@ func TestReadBlob(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePacket(t *testing.T) {
+	nested := Bundle{
+		Timetag: 2,
+		Packets: []Packet{MustMessage("/inner", "s")},
+	}
+	for i, testcase := range []struct {
+		Data     []byte
+		Expected Packet
+	}{
+		{
+			Data: bytes.Join([][]byte{
+				{'/', 'f', 'o', 'o', 0, 0, 0, 0},
+				{TypetagPrefix, TypetagInt, TypetagString, 0},
+				{0, 0, 0, 7},
+				{'b', 'a', 'r', 0},
+			}, []byte{}),
+			Expected: MustMessage("/foo", 7, "bar"),
+		},
+		{
+			Data: bytes.Join([][]byte{
+				{'#', 'b', 'u', 'n', 'd', 'l', 'e', 0},
+				{0, 0, 0, 0, 0, 0, 0, 1},
+				{0, 0, 0, 12},
+				{'/', 'b', 'a', 'r', 0, 0, 0, 0},
+				{TypetagPrefix, 0, 0, 0},
+			}, []byte{}),
+			Expected: Bundle{Timetag: Immediately, Packets: []Packet{Message{Address: "/bar", Arguments: Arguments{}}}},
+		},
+		{
+			Data: bytes.Join([][]byte{
+				{'#', 'b', 'u', 'n', 'd', 'l', 'e', 0},
+				{0, 0, 0, 0, 0, 0, 0, 1},
+				{0, 0, 0, byte(len(nested.Bytes()))},
+				nested.Bytes(),
+			}, []byte{}),
+			Expected: Bundle{Timetag: Immediately, Packets: []Packet{nested}},
+		},
+	} {
+		p, err := ParsePacket(testcase.Data)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if !testcase.Expected.Equal(p) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, testcase.Expected, p)
+		}
+		if expected, got := testcase.Data, p.Bytes(); !bytes.Equal(expected, got) {
+			t.Fatalf("(testcase %d) expected %x, got %x", i, expected, got)
+		}
+	}
+	for i, data := range [][]byte{
+		nil,
+		{'x'},
+		[]byte("/a b\x00\x00\x00\x00,\x00\x00\x00"),
+		[]byte("#bundle\x00\x00\x00\x00"),
+		[]byte("/blob\x00\x00\x00,b\x00\x00\xff\xff\xff\xfb"),
+	} {
+		if _, err := ParsePacket(data); err == nil {
+			t.Fatalf("(testcase %d) expected error, got nil", i)
+		}
+	}
+}
+
+func TestParsePacketCopies(t *testing.T) {
+	data := Bundle{
+		Timetag: Immediately,
+		Packets: []Packet{MustMessage("/blob", []byte("blob"))},
+	}.Bytes()
+
+	p, err := ParsePacket(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		data[i] = 0
+	}
+	blob, err := p.(Bundle).Packets[0].(Message).BlobAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "blob", string(blob); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func FuzzParsePacket(f *testing.F) {
+	for _, p := range []Packet{
+		MustMessage("/foo", 1, float32(2), "three", []byte("four"), true, int64(5), 6.0, nil),
+		MustMessage("/arr", Array{Int(1), Array{}}, Symbol("s"), Timetag(7), Char('c')),
+		Bundle{Timetag: Immediately, Packets: []Packet{
+			MustMessage("/a"),
+			Bundle{Timetag: 2, Packets: []Packet{MustMessage("/b", 1)}},
+		}},
+	} {
+		f.Add(p.Bytes())
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := ParsePacket(data)
+		if err != nil {
+			return
+		}
+		// Whatever we parse can be encoded again.
+		_ = p.Bytes()
+	})
+}
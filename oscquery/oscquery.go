@@ -0,0 +1,221 @@
+// Package oscquery serves the OSCQuery namespace of an OSC dispatcher over HTTP,
+// so that clients can discover the addresses of its methods and the
+// arguments they expect.
+//
+// The namespace is built from the methods of the dispatcher every time it is
+// requested, so methods that are added to or removed from a Router are
+// reflected by the next request. Routers, including the sub-dispatchers mounted
+// on them, and PatternMatching dispatchers are supported. The methods at
+// patterns and the Default method have no address that a client could
+// send to, so they are left out.
+//
+// A method reports the typetags it expects as its TYPE if it is wrapped with
+// osc.WithTypetags or added with AddTyped. The TYPE of OSCQuery can not describe
+// an argument of any type or optional arguments, so the methods whose typetags
+// have osc.TypetagAny or osc.TypetagOptional have none.
+package oscquery
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/scgolang/osc"
+)
+
+// The attributes of the nodes of the namespace.
+const (
+	attrFullPath = "FULL_PATH"
+	attrContents = "CONTENTS"
+	attrType     = "TYPE"
+	attrAccess   = "ACCESS"
+)
+
+// hostInfo is the query for the HOST_INFO document.
+const hostInfo = "HOST_INFO"
+
+// accessWrite is the ACCESS of a method, which can be sent messages but has no value to read.
+const accessWrite = 2
+
+// extensions are the OSCQuery extensions the handler supports.
+var extensions = map[string]bool{
+	"ACCESS":        true,
+	"CLIPMODE":      false,
+	"CRITICAL":      false,
+	"DESCRIPTION":   false,
+	"EXTENDED_TYPE": false,
+	"LISTEN":        false,
+	"PATH_CHANGED":  false,
+	"RANGE":         false,
+	"TAGS":          false,
+	"UNIT":          false,
+	"VALUE":         false,
+}
+
+// NewHandler returns a handler that serves the namespace of d,
+// and the HOST_INFO document of the OSC server at host and port,
+// which is assumed to receive UDP.
+//
+// A GET of a path returns the node at that path with everything below it,
+// and a query such as "/synth/freq?TYPE" returns only that attribute of the node.
+// Paths that are not in the namespace are not found, and a node that does not
+// have the attribute that is asked for returns no content.
+func NewHandler(d osc.Dispatcher, host string, port int) http.Handler {
+	return &handler{dispatcher: d, host: host, port: port}
+}
+
+// handler serves the namespace of a dispatcher.
+type handler struct {
+	dispatcher osc.Dispatcher
+	host       string
+	port       int
+}
+
+// ServeHTTP serves a node of the namespace, one of its attributes, or the HOST_INFO document.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.RawQuery == hostInfo {
+		writeJSON(w, h.hostInfo())
+		return
+	}
+	n := h.namespace().find(r.URL.Path)
+	if n == nil {
+		http.NotFound(w, r)
+		return
+	}
+	attrs := n.attributes()
+	if r.URL.RawQuery == "" {
+		writeJSON(w, attrs)
+		return
+	}
+	value, ok := attrs[r.URL.RawQuery]
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, map[string]interface{}{r.URL.RawQuery: value})
+}
+
+// hostInfo returns the HOST_INFO document.
+func (h *handler) hostInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"NAME":          net.JoinHostPort(h.host, strconv.Itoa(h.port)),
+		"OSC_IP":        h.host,
+		"OSC_PORT":      h.port,
+		"OSC_TRANSPORT": "UDP",
+		"EXTENSIONS":    extensions,
+	}
+}
+
+// namespace builds the tree of the addresses of the methods of the dispatcher.
+func (h *handler) namespace() *node {
+	root := &node{fullPath: "/"}
+	walk(h.dispatcher, "", func(addr string, method osc.MessageHandler) {
+		n := root
+		for _, part := range strings.Split(strings.TrimPrefix(addr, "/"), "/") {
+			n = n.child(part)
+		}
+		n.method = true
+		if typed, ok := method.(interface{ Typetags() string }); ok {
+			if typetags := typed.Typetags(); !strings.ContainsAny(typetags, string([]byte{osc.TypetagAny, osc.TypetagOptional})) {
+				n.typetags = typetags
+			}
+		}
+	})
+	return root
+}
+
+// walk calls add with the address of every method of d that a client can send to,
+// prefixed with prefix.
+func walk(d osc.Dispatcher, prefix string, add func(addr string, method osc.MessageHandler)) {
+	switch d := d.(type) {
+	case *osc.Router:
+		walkMethods(d.Methods(), prefix, add)
+		for mounted, sub := range d.Mounts() {
+			walk(sub, prefix+mounted, add)
+		}
+	case osc.PatternMatching:
+		walkMethods(d, prefix, add)
+	}
+}
+
+// walkMethods calls add with the address of every method that is not at a pattern.
+func walkMethods(methods osc.PatternMatching, prefix string, add func(addr string, method osc.MessageHandler)) {
+	for addr, method := range methods {
+		if addr == osc.Default || strings.ContainsAny(addr, "*?[]{}") {
+			continue
+		}
+		add(prefix+addr, method)
+	}
+}
+
+// node is a container or a method of the namespace, or both.
+type node struct {
+	fullPath string
+	contents map[string]*node
+	method   bool
+	typetags string
+}
+
+// child returns the child called name, which is created if there is none.
+func (n *node) child(name string) *node {
+	if c, ok := n.contents[name]; ok {
+		return c
+	}
+	if n.contents == nil {
+		n.contents = map[string]*node{}
+	}
+	c := &node{fullPath: strings.TrimSuffix(n.fullPath, "/") + "/" + name}
+	n.contents[name] = c
+	return c
+}
+
+// find returns the node at path, or nil if there is none.
+func (n *node) find(path string) *node {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return n
+	}
+	for _, part := range strings.Split(path, "/") {
+		if n = n.contents[part]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// attributes returns the attributes of the node and of everything below it.
+func (n *node) attributes() map[string]interface{} {
+	attrs := map[string]interface{}{attrFullPath: n.fullPath}
+	if n.method {
+		attrs[attrAccess] = accessWrite
+	}
+	if n.typetags != "" {
+		attrs[attrType] = n.typetags
+	}
+	if len(n.contents) > 0 {
+		contents := make(map[string]interface{}, len(n.contents))
+		for name, c := range n.contents {
+			contents[name] = c.attributes()
+		}
+		attrs[attrContents] = contents
+	}
+	return attrs
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(data, '\n')) // Best effort.
+}
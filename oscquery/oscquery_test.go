@@ -0,0 +1,125 @@
+package oscquery
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scgolang/osc"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// nop is a method that does nothing.
+var nop = osc.Method(func(msg osc.Message) error { return nil })
+
+// newDispatcher returns the dispatcher of a small synthesizer.
+func newDispatcher(t *testing.T) *osc.Router {
+	voice := osc.NewRouter()
+	for addr, method := range map[string]osc.MessageHandler{
+		"/freq": osc.WithTypetags("f", nop),
+		"/note": osc.WithTypetags("ifT", nop),
+		"/off":  nop,
+	} {
+		if err := voice.AddMethod(addr, method); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := voice.AddTyped("/gate", "i*?", nop); err != nil {
+		t.Fatal(err)
+	}
+	router := osc.NewRouter()
+	for addr, method := range map[string]osc.MessageHandler{
+		"/master/volume": osc.WithTypetags("f", nop),
+		"/master/eq":     osc.WithTypetags("[fff]", nop),
+		"/reset":         nop,
+		"/synth/*/mute":  nop,
+		osc.Default:      nop,
+	} {
+		if err := router.AddMethod(addr, method); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := router.Mount("/voice", voice); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.Mount("/fx", osc.PatternMatching{"/reverb/mix": osc.WithTypetags("f", nop)}); err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(newDispatcher(t), "127.0.0.1", 9000)
+	for i, testcase := range []struct {
+		Target string
+		Status int
+		Golden string
+	}{
+		{Target: "/", Status: http.StatusOK, Golden: "namespace.json"},
+		{Target: "/voice/", Status: http.StatusOK, Golden: "voice.json"},
+		{Target: "/master/eq", Status: http.StatusOK, Golden: "eq.json"},
+		{Target: "/master/eq?TYPE", Status: http.StatusOK, Golden: "eq_type.json"},
+		{Target: "/?HOST_INFO", Status: http.StatusOK, Golden: "host_info.json"},
+		{Target: "/reset?TYPE", Status: http.StatusNoContent},
+		{Target: "/synth", Status: http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, testcase.Target, nil))
+		if w.Code != testcase.Status {
+			t.Fatalf("(testcase %d) expected status %d, got %d", i, testcase.Status, w.Code)
+		}
+		if testcase.Golden == "" {
+			continue
+		}
+		golden := filepath.Join("testdata", testcase.Golden)
+		if *update {
+			if err := os.WriteFile(golden, w.Body.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(w.Body.Bytes(), expected) {
+			t.Fatalf("(testcase %d) expected\n%s\ngot\n%s", i, expected, w.Body.Bytes())
+		}
+	}
+}
+
+func TestHandler_Dynamic(t *testing.T) {
+	router := newDispatcher(t)
+	h := NewHandler(router, "127.0.0.1", 9000)
+
+	get := func(target string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+	if code := get("/lfo/rate"); code != http.StatusNotFound {
+		t.Fatalf("expected status %d before the method is added, got %d", http.StatusNotFound, code)
+	}
+	if err := router.AddMethod("/lfo/rate", nop); err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/lfo/rate"); code != http.StatusOK {
+		t.Fatalf("expected status %d once the method is added, got %d", http.StatusOK, code)
+	}
+	router.Unmount("/voice")
+	if code := get("/voice/freq"); code != http.StatusNotFound {
+		t.Fatalf("expected status %d once the voice is unmounted, got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestHandler_Method(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewHandler(osc.PatternMatching{}, "127.0.0.1", 9000).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
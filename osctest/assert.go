@@ -0,0 +1,36 @@
+package osctest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/scgolang/osc"
+)
+
+// AssertMessage fails the test if got does not have the address wantAddr
+// and the arguments wantArgs, which are converted like the ones of osc.NewMessage.
+func AssertMessage(t testing.TB, got osc.Message, wantAddr string, wantArgs ...interface{}) {
+	t.Helper()
+
+	want, err := osc.NewMessage(wantAddr, wantArgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !want.Equal(got) {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+// AssertPacket fails the test if got does not equal want, and shows
+// how their encodings differ.
+func AssertPacket(t testing.TB, got, want osc.Packet) {
+	t.Helper()
+
+	if got.Equal(want) {
+		return
+	}
+	if wantData, gotData := want.Bytes(), got.Bytes(); !bytes.Equal(wantData, gotData) {
+		t.Fatalf("expected %s, got %s\n%s", want, got, DiffHex(wantData, gotData))
+	}
+	t.Fatalf("expected %s, got %s", want, got)
+}
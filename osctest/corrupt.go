@@ -0,0 +1,108 @@
+package osctest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/scgolang/osc"
+)
+
+// Corrupted is a packet that is not valid OSC, for the tests of how a receiver
+// handles bad input. Its Bytes return the corrupted encoding as it is, so it can be
+// sent with the Send methods of the conns, and nested in a bundle too.
+//
+// The functions that corrupt a packet panic if it can not be corrupted
+// the way they are asked to, like osc.MustMessage does with bad arguments.
+type Corrupted []byte
+
+// Bytes returns the corrupted encoding.
+func (c Corrupted) Bytes() []byte { return c }
+
+// Equal returns true if other has the same encoding.
+func (c Corrupted) Equal(other osc.Packet) bool { return bytes.Equal(c, other.Bytes()) }
+
+// Truncate returns the first n bytes of the encoding of p.
+func Truncate(p osc.Packet, n int) Corrupted {
+	data := encode(p)
+	if n < 0 || n > len(data) {
+		panic(fmt.Sprintf("osctest: truncate %d bytes to %d", len(data), n))
+	}
+	return Corrupted(data[:n])
+}
+
+// FlipTypetagPrefix returns msg with a '.' instead of the comma that starts its typetags.
+func FlipTypetagPrefix(msg osc.Message) Corrupted {
+	data := encode(msg)
+	data[stringSize(msg.Address)] = '.'
+	return Corrupted(data)
+}
+
+// InjectTypetag returns msg with tag, e.g. an unknown typetag like 'Q', inserted
+// before the typetag at index i of msg.TypetagString(), or after the last one
+// if i is the number of typetags. The tag does not have a payload.
+func InjectTypetag(msg osc.Message, i int, tag byte) Corrupted {
+	typetags := msg.TypetagString()
+	if i < 0 || i > len(typetags) {
+		panic(fmt.Sprintf("osctest: inject typetag %d of %q", i, typetags))
+	}
+	data := encode(msg)
+	payload := data[stringSize(msg.Address)+stringSize(","+typetags):]
+
+	out := appendString(nil, msg.Address)
+	out = appendString(out, ","+typetags[:i]+string(tag)+typetags[i:])
+	return Corrupted(append(out, payload...))
+}
+
+// BlobLength returns msg with the length of the blob, whose typetag is at index i
+// of msg.TypetagString(), declared as length, e.g. more bytes than the message has
+// or a negative number. The bytes of the blob are left as they are.
+func BlobLength(msg osc.Message, i int, length int32) Corrupted {
+	typetags := msg.TypetagString()
+	if i < 0 || i >= len(typetags) || typetags[i] != osc.TypetagBlob {
+		panic(fmt.Sprintf("osctest: typetag %d of %q is not a blob", i, typetags))
+	}
+	args, err := msg.RawArguments()
+	if err != nil {
+		panic(fmt.Sprintf("osctest: %s", err))
+	}
+	offset := stringSize(msg.Address) + stringSize(","+typetags)
+	for _, a := range args[:i] {
+		offset += len(a.Bytes())
+	}
+	data := encode(msg)
+	binary.BigEndian.PutUint32(data[offset:], uint32(length))
+	return Corrupted(data)
+}
+
+// ElementSize returns b with the size of its element at index i declared as size,
+// e.g. more bytes than the bundle has, fewer than the element has, or a negative number.
+// The bytes of the element are left as they are.
+func ElementSize(b osc.Bundle, i int, size int32) Corrupted {
+	if i < 0 || i >= len(b.Packets) {
+		panic(fmt.Sprintf("osctest: element %d of a bundle of %d", i, len(b.Packets)))
+	}
+	data := encode(b)
+	offset := stringSize(osc.BundleTag) + 8
+	for j := 0; j < i; j++ {
+		offset += 4 + int(binary.BigEndian.Uint32(data[offset:]))
+	}
+	binary.BigEndian.PutUint32(data[offset:], uint32(size))
+	return Corrupted(data)
+}
+
+// encode returns a copy of the encoding of p, which may share memory with p.
+func encode(p osc.Packet) []byte {
+	return append([]byte(nil), p.Bytes()...)
+}
+
+// stringSize returns the size of the encoded OSC string s.
+func stringSize(s string) int {
+	return (len(s) + 4) &^ 3
+}
+
+// appendString appends the encoded OSC string s to b.
+func appendString(b []byte, s string) []byte {
+	b = append(b, s...)
+	return append(b, make([]byte, stringSize(s)-len(s))...)
+}
@@ -0,0 +1,97 @@
+package osctest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/scgolang/osc"
+)
+
+func TestCorrupted(t *testing.T) {
+	var (
+		foo    = osc.MustMessage("/foo", int32(1), "bar")
+		blob   = osc.MustMessage("/blob", int32(1), []byte{1, 2, 3, 4})
+		bundle = osc.Bundle{Timetag: osc.Immediately, Packets: []osc.Packet{foo, blob}}
+	)
+	for i, testcase := range []struct {
+		Packet   Corrupted
+		Expected []byte
+		Err      error // Any error if nil.
+	}{
+		{
+			Packet:   Truncate(foo, 10),
+			Expected: []byte("/foo\x00\x00\x00\x00,i"),
+			Err:      io.EOF,
+		},
+		{
+			Packet:   FlipTypetagPrefix(foo),
+			Expected: []byte("/foo\x00\x00\x00\x00.is\x00\x00\x00\x00\x01bar\x00"),
+			Err:      osc.ErrInvalidTypeTag,
+		},
+		{
+			Packet:   InjectTypetag(foo, 1, 'Q'),
+			Expected: []byte("/foo\x00\x00\x00\x00,iQs\x00\x00\x00\x00\x00\x00\x00\x01bar\x00"),
+			Err:      osc.ErrInvalidTypeTag,
+		},
+		{
+			Packet:   InjectTypetag(osc.MustMessage("/foo"), 0, 'Q'),
+			Expected: []byte("/foo\x00\x00\x00\x00,Q\x00\x00"),
+			Err:      osc.ErrInvalidTypeTag,
+		},
+		{
+			Packet:   BlobLength(blob, 1, 5),
+			Expected: []byte("/blob\x00\x00\x00,ib\x00\x00\x00\x00\x01\x00\x00\x00\x05\x01\x02\x03\x04"),
+			Err:      osc.ErrBlobPastEnd,
+		},
+		{
+			Packet:   BlobLength(blob, 1, -1),
+			Expected: []byte("/blob\x00\x00\x00,ib\x00\x00\x00\x00\x01\xff\xff\xff\xff\x01\x02\x03\x04"),
+			Err:      osc.ErrNegativeBlobLength,
+		},
+		{
+			Packet: ElementSize(bundle, 1, 1024),
+		},
+	} {
+		if testcase.Expected != nil && !bytes.Equal(testcase.Packet.Bytes(), testcase.Expected) {
+			t.Fatalf("(testcase %d)\n%s", i, DiffHex(testcase.Expected, testcase.Packet.Bytes()))
+		}
+		_, err := osc.ParsePacket(testcase.Packet.Bytes())
+		if err == nil || testcase.Err != nil && !errors.Is(err, testcase.Err) {
+			t.Fatalf("(testcase %d) expected error %v, got %v", i, testcase.Err, err)
+		}
+	}
+}
+
+func TestCorrupted_DoesNotModify(t *testing.T) {
+	msg, err := osc.ParsePacket(osc.MustMessage("/foo", []byte{1}).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := msg.Bytes()
+	_ = FlipTypetagPrefix(msg.(osc.Message))
+	_ = BlobLength(msg.(osc.Message), 0, 100)
+	if !bytes.Equal(msg.Bytes(), data) {
+		t.Fatalf("expected %x, got %x", data, msg.Bytes())
+	}
+}
+
+func TestCorrupted_Panics(t *testing.T) {
+	foo := osc.MustMessage("/foo", int32(1))
+	for i, corrupt := range []func(){
+		func() { Truncate(foo, 100) },
+		func() { InjectTypetag(foo, 2, 'Q') },
+		func() { BlobLength(foo, 0, 1) },
+		func() { ElementSize(osc.Bundle{}, 0, 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("(testcase %d) expected a panic", i)
+				}
+			}()
+			corrupt()
+		}()
+	}
+}
@@ -0,0 +1,87 @@
+package osctest
+
+import (
+	"testing"
+
+	"github.com/scgolang/osc"
+)
+
+func TestVectors(t *testing.T) {
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages, bundles int
+	for i, v := range vectors {
+		if i > 0 && vectors[i-1].Name >= v.Name {
+			t.Fatalf("vectors are not sorted: %s before %s", vectors[i-1].Name, v.Name)
+		}
+		if len(v.Data) == 0 || len(v.Data)%4 != 0 {
+			t.Fatalf("%s: %d bytes, which is not a positive multiple of 4", v.Name, len(v.Data))
+		}
+		switch v.Packet.(type) {
+		case osc.Message:
+			messages++
+		case osc.Bundle:
+			bundles++
+		default:
+			t.Fatalf("%s: unexpected packet %T", v.Name, v.Packet)
+		}
+	}
+	if messages == 0 || bundles == 0 {
+		t.Fatalf("expected messages and bundles, got %d messages and %d bundles", messages, bundles)
+	}
+
+	// Every call returns copies.
+	vectors[0].Data[0] = 'x'
+	again := MustVectors()
+	if again[0].Data[0] == 'x' {
+		t.Fatal("expected a copy of the vector")
+	}
+}
+
+func TestDiffHex(t *testing.T) {
+	for i, testcase := range []struct {
+		Expected []byte
+		Got      []byte
+		Diff     string
+	}{
+		{
+			Expected: []byte("/foo"),
+			Got:      []byte("/foo"),
+			Diff:     "",
+		},
+		{
+			Expected: []byte("/foo\x00\x00\x00\x00"),
+			Got:      []byte("/fob\x00\x00\x00\x00"),
+			Diff: "first difference at offset 3 (0x3), expected 8 bytes, got 8\n" +
+				"expected:\n" +
+				"> 0000  2f  66  6f [6f] 00  00  00  00 \n" +
+				"got:\n" +
+				"> 0000  2f  66  6f [62] 00  00  00  00 \n",
+		},
+		{
+			Expected: []byte("0123456789abcdef/foo"),
+			Got:      []byte("0123456789abcdef"),
+			Diff: "first difference at offset 16 (0x10), expected 20 bytes, got 16\n" +
+				"expected:\n" +
+				"  0000  30  31  32  33  34  35  36  37  38  39  61  62  63  64  65  66 \n" +
+				"> 0010 [2f] 66  6f  6f \n" +
+				"got:\n" +
+				"  0000  30  31  32  33  34  35  36  37  38  39  61  62  63  64  65  66 \n",
+		},
+		{
+			Expected: []byte("/foo"),
+			Got:      nil,
+			Diff: "first difference at offset 0 (0x0), expected 4 bytes, got 0\n" +
+				"expected:\n" +
+				"> 0000 [2f] 66  6f  6f \n" +
+				"got:\n" +
+				"  (empty)\n",
+		},
+	} {
+		if got := DiffHex(testcase.Expected, testcase.Got); got != testcase.Diff {
+			t.Fatalf("(testcase %d) expected\n%q, got\n%q", i, testcase.Diff, got)
+		}
+	}
+}
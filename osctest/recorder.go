@@ -0,0 +1,139 @@
+package osctest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/scgolang/osc"
+)
+
+// ErrTimeout is returned by Recorder.WaitFor when no message arrives in time.
+var ErrTimeout = errors.New("timed out")
+
+// Record is a message that a Recorder recorded.
+type Record struct {
+	Message osc.Message
+
+	// Time is when the message was dispatched, which is when it was received
+	// and not the time of the bundle it was in.
+	Time time.Time
+}
+
+// Recorder is an osc.Dispatcher that records every message it dispatches,
+// including the messages of bundles, nested ones too, in the order they were received.
+// It is safe for the goroutines of a server and a test to use at once.
+type Recorder struct {
+	next osc.Dispatcher
+
+	mu      sync.Mutex
+	records []Record
+	waited  []bool        // Whether WaitFor returned the record with the same index.
+	changed chan struct{} // Closed and replaced whenever a message is recorded.
+}
+
+// NewRecorder returns a Recorder that dispatches the messages it records to next,
+// so that the handlers under test run too, or only records them if next is nil.
+func NewRecorder(next osc.Dispatcher) *Recorder {
+	return &Recorder{next: next, changed: make(chan struct{})}
+}
+
+// Dispatch records the messages of b, and dispatches b to the next dispatcher.
+// It does not wait for the timetag of b.
+func (r *Recorder) Dispatch(b osc.Bundle, exactMatch bool) error {
+	r.recordBundle(b, time.Now())
+	if r.next == nil {
+		return nil
+	}
+	return r.next.Dispatch(b, exactMatch)
+}
+
+// Invoke records msg, and invokes the next dispatcher with it.
+func (r *Recorder) Invoke(msg osc.Message, exactMatch bool) error {
+	r.record(msg, time.Now())
+	if r.next == nil {
+		return nil
+	}
+	return r.next.Invoke(msg, exactMatch)
+}
+
+// recordBundle records the messages of b and of the bundles in it.
+func (r *Recorder) recordBundle(b osc.Bundle, now time.Time) {
+	for _, p := range b.Packets {
+		switch p := p.(type) {
+		case osc.Message:
+			r.record(p, now)
+		case osc.Bundle:
+			r.recordBundle(p, now)
+		}
+	}
+}
+
+// record records a copy of msg, since the buffer it was read from is reused.
+func (r *Recorder) record(msg osc.Message, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, Record{Message: msg.Clone(), Time: now})
+	r.waited = append(r.waited, false)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Records returns the records so far.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Record(nil), r.records...)
+}
+
+// Messages returns the messages recorded so far.
+func (r *Recorder) Messages() []osc.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msgs := make([]osc.Message, len(r.records))
+	for i, rec := range r.records {
+		msgs[i] = rec.Message
+	}
+	return msgs
+}
+
+// Reset forgets the messages recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records, r.waited = nil, nil
+}
+
+// WaitFor returns the first message to addr that an earlier WaitFor did not return,
+// waiting for it to be recorded if there is none yet, so that successive calls
+// return the messages to addr one after the other. The address must be the same,
+// and is not matched as a pattern. If no message arrives within the timeout
+// WaitFor returns an error wrapping ErrTimeout.
+func (r *Recorder) WaitFor(addr string, timeout time.Duration) (osc.Message, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		r.mu.Lock()
+		for i, rec := range r.records {
+			if !r.waited[i] && rec.Message.Address == addr {
+				r.waited[i] = true
+				r.mu.Unlock()
+				return rec.Message, nil
+			}
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return osc.Message{}, fmt.Errorf("waiting %s for a message to %s: %w", timeout, addr, ErrTimeout)
+		}
+	}
+}
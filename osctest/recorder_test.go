@@ -0,0 +1,148 @@
+package osctest
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/scgolang/osc"
+)
+
+func TestRecorder(t *testing.T) {
+	var invoked []string
+	r := NewRecorder(osc.PatternMatching{
+		"/a": osc.Method(func(msg osc.Message) error {
+			invoked = append(invoked, msg.Address)
+			return nil
+		}),
+	})
+	if err := r.Invoke(osc.MustMessage("/a", int32(1)), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Dispatch(osc.Bundle{
+		Timetag: osc.Immediately,
+		Packets: []osc.Packet{
+			osc.MustMessage("/b"),
+			osc.Bundle{Timetag: osc.Immediately, Packets: []osc.Packet{osc.MustMessage("/a", int32(2))}},
+		},
+	}, false); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := 2, len(invoked); expected != got {
+		t.Fatalf("expected %d invocations, got %d", expected, got)
+	}
+	msgs := r.Messages()
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+	for i, addr := range []string{"/a", "/b", "/a"} {
+		if msgs[i].Address != addr {
+			t.Fatalf("(message %d) expected %s, got %s", i, addr, msgs[i].Address)
+		}
+	}
+	records := r.Records()
+	if records[0].Time.IsZero() || records[2].Time.Before(records[0].Time) {
+		t.Fatalf("unexpected times %s and %s", records[0].Time, records[2].Time)
+	}
+
+	// Successive calls return the messages to an address one after the other.
+	for _, expected := range []int32{1, 2} {
+		msg, err := r.WaitFor("/a", time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		AssertMessage(t, msg, "/a", expected)
+	}
+	if _, err := r.WaitFor("/a", 10*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	r.Reset()
+	if got := len(r.Messages()); got != 0 {
+		t.Fatalf("expected no messages after Reset, got %d", got)
+	}
+}
+
+func TestRecorder_WaitFor(t *testing.T) {
+	r := NewRecorder(nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = r.Invoke(osc.MustMessage("/other"), false)
+		_ = r.Invoke(osc.MustMessage("/late", "x"), false)
+	}()
+	msg, err := r.WaitFor("/late", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertMessage(t, msg, "/late", "x")
+}
+
+func TestNewServer(t *testing.T) {
+	r := NewRecorder(nil)
+	s := NewServer(t, r)
+	conn := s.Dial()
+
+	msg := osc.MustMessage("/ping", int32(1), "two", float32(3))
+	if err := conn.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.WaitFor("/ping", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertMessage(t, got, "/ping", int32(1), "two", float32(3))
+	AssertPacket(t, got, msg)
+}
+
+func TestAssert(t *testing.T) {
+	for i, testcase := range []struct {
+		Assert func(t testing.TB)
+		Failed bool
+	}{
+		{
+			Assert: func(t testing.TB) { AssertMessage(t, osc.MustMessage("/a", int32(1)), "/a", int32(1)) },
+		},
+		{
+			Assert: func(t testing.TB) { AssertMessage(t, osc.MustMessage("/a", int32(1)), "/a", int32(2)) },
+			Failed: true,
+		},
+		{
+			Assert: func(t testing.TB) { AssertMessage(t, osc.MustMessage("/a"), "/a", struct{}{}) },
+			Failed: true,
+		},
+		{
+			Assert: func(t testing.TB) { AssertPacket(t, osc.MustMessage("/a"), osc.MustMessage("/b")) },
+			Failed: true,
+		},
+	} {
+		ft := &fakeT{TB: t}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			testcase.Assert(ft)
+		}()
+		<-done
+		if ft.failed != testcase.Failed {
+			t.Fatalf("(testcase %d) expected failed to be %t, got %t", i, testcase.Failed, ft.failed)
+		}
+	}
+}
+
+// fakeT records the failure of an assertion instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Fatal(args ...interface{}) {
+	t.failed = true
+	runtime.Goexit()
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.failed = true
+	runtime.Goexit()
+}
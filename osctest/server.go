@@ -0,0 +1,59 @@
+package osctest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/scgolang/osc"
+)
+
+// Server is a UDP conn on a random port of the loopback interface
+// that serves a dispatcher for a test.
+type Server struct {
+	*osc.UDPConn
+
+	t    testing.TB
+	errs chan error
+}
+
+// NewServer listens on a random port of 127.0.0.1 with the options, and serves d
+// with a single worker, so that the messages are dispatched in the order they arrive.
+// The server is closed when the test ends, and the test fails if Serve
+// returned an error.
+func NewServer(t testing.TB, d osc.Dispatcher, opts ...osc.Option) *Server {
+	t.Helper()
+
+	conn, err := osc.Listen("127.0.0.1:0", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{UDPConn: conn, t: t, errs: make(chan error, 1)}
+	go func() { s.errs <- conn.Serve(1, d) }()
+
+	t.Cleanup(func() {
+		_ = conn.Close() // Best effort.
+		if err := <-s.errs; err != nil {
+			t.Errorf("serve %s: %v", conn.LocalAddr(), err)
+		}
+	})
+	return s
+}
+
+// Addr returns the address of the server, e.g. "127.0.0.1:57120".
+func (s *Server) Addr() string { return s.LocalAddr().String() }
+
+// Dial returns a conn whose peer is the server, which is closed when the test ends.
+func (s *Server) Dial(opts ...osc.Option) *osc.UDPConn {
+	s.t.Helper()
+
+	raddr, err := net.ResolveUDPAddr("udp", s.Addr())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	conn, err := osc.DialUDP("udp", nil, raddr, opts...)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.t.Cleanup(func() { _ = conn.Close() }) // Best effort.
+	return conn
+}
@@ -0,0 +1,229 @@
+#!/usr/bin/env python3
+"""Writes the .osc files of the vectors in testdata/vectors, and SOURCES,
+which says what encoded each of them.
+
+    pip install python-osc
+    python3 osctest/testdata/gen_vectors.py
+
+The vectors are encoded by python-osc, the reference encoder, whenever it
+supports all of their typetags. The values this script passes to python-osc
+are only known for the typetags in PYTHON_OSC_TAGS, so the other vectors, and
+the bundles whose timetags python-osc can not represent, are encoded from the
+OSC 1.0 and 1.1 specifications by the spec_* functions. When python-osc and
+the specification disagree on a vector, python-osc wins and a warning is printed.
+
+Without python-osc, the script refuses to run, unless it is given --spec,
+which encodes every vector from the specification.
+
+The .json files are not written by this script: they describe the packets
+in the JSON of osc.Message and osc.Bundle, and are checked by TestVectors.
+"""
+
+import os
+import struct
+import sys
+
+OUT = os.path.join(os.path.dirname(os.path.abspath(__file__)), "vectors")
+
+# The typetags whose python-osc values are known.
+PYTHON_OSC_TAGS = set("ifdhsbrmTFN[]")
+
+# Seconds from the NTP epoch, 1900, to the Unix one, 1970.
+NTP_UNIX_OFFSET = 2208988800
+
+IMMEDIATELY = 1
+
+
+class M:
+    """A message: an address and (typetag, value) arguments.
+
+    The value of an array, '[', is a list of arguments."""
+
+    def __init__(self, addr, *args):
+        self.addr, self.args = addr, list(args)
+
+
+class B:
+    """A bundle: a timetag and elements."""
+
+    def __init__(self, timetag, *elems):
+        self.timetag, self.elems = timetag, list(elems)
+
+
+VECTORS = {
+    "message_empty": M("/empty"),
+    "message_int32": M("/int32", ("i", 0), ("i", 1), ("i", -2147483648)),
+    "message_float32": M("/float32", ("f", 440.0), ("f", -0.5)),
+    "message_string": M("/string", ("s", "abc"), ("s", "abcd"), ("s", "hello, world")),
+    "message_string_empty": M("/string/empty", ("s", ""), ("s", "a")),
+    "message_blob": M("/blob", ("b", b""), ("b", b"\x01"), ("b", b"\x01\x02\x03\x04"), ("b", b"\x01\x02\x03\x04\x05")),
+    "message_int64": M("/int64", ("h", -1), ("h", 1 << 40)),
+    "message_double": M("/double", ("d", 0.1), ("d", -1e300)),
+    "message_timetag": M("/timetag", ("t", 1), ("t", 0xE0D1C4EF80000000)),
+    "message_symbol": M("/symbol", ("S", "sym")),
+    "message_char": M("/char", ("c", "a")),
+    "message_rgba": M("/rgba", ("r", 0xFF000080)),
+    "message_midi": M("/midi", ("m", (0x00, 0x90, 0x3C, 0x7F))),
+    "message_bool_nil_infinitum": M("/flags", ("T", True), ("F", False), ("N", None), ("I", None)),
+    "message_array": M("/array", ("i", 1), ("[", [("f", 2.0), ("[", [("s", "three")])]), ("i", 4)),
+    "message_empty_array": M("/array", ("[", [])),
+    "message_every_typetag": M(
+        "/every",
+        ("i", 1), ("f", 2.5), ("s", "three"), ("b", b"four"), ("h", 5), ("t", 6), ("d", 7.25),
+        ("S", "eight"), ("c", "9"), ("r", 0x0A0B0C0D), ("m", (14, 0x90, 15, 16)),
+        ("T", True), ("F", False), ("N", None), ("I", None), ("[", [("i", 17)]),
+    ),
+    "pattern_wildcards": M("/synth/*/freq?", ("f", 220.0)),
+    "pattern_brackets": M("/voice/[0-3]/gain", ("f", 0.5)),
+    "pattern_negated_brackets": M("/voice/[!0-3]/mute", ("T", True)),
+    "pattern_braces": M("/mixer/{left,right}/level", ("f", 1.0)),
+    "bundle_empty": B(IMMEDIATELY),
+    "bundle_immediate": B(IMMEDIATELY, M("/a", ("i", 1))),
+    "bundle_two_messages": B(0xE0D1C4EF80000000, M("/a", ("i", 1)), M("/b", ("s", "x"), ("f", 0.25))),
+    "bundle_nested": B(IMMEDIATELY, M("/outer", ("i", 1)), B(2, M("/inner", ("s", "deep")), B(3))),
+}
+
+
+def spec_pad(b):
+    return b + b"\x00" * (-len(b) % 4)
+
+
+def spec_string(s):
+    """An OSC-string: at least one null byte, padded to 4, so "" is 4 null bytes."""
+    return spec_pad(s.encode() + b"\x00")
+
+
+def spec_typetags(args):
+    tags = ""
+    for tag, value in args:
+        tags += "[" + spec_typetags(value) + "]" if tag == "[" else tag
+    return tags
+
+
+def spec_payload(args):
+    data = b""
+    for tag, value in args:
+        if tag == "i":
+            data += struct.pack(">i", value)
+        elif tag == "h":
+            data += struct.pack(">q", value)
+        elif tag == "f":
+            data += struct.pack(">f", value)
+        elif tag == "d":
+            data += struct.pack(">d", value)
+        elif tag == "t":
+            data += struct.pack(">Q", value)
+        elif tag in "sS":
+            data += spec_string(value)
+        elif tag == "b":
+            data += struct.pack(">i", len(value)) + spec_pad(value)
+        elif tag == "c":
+            data += struct.pack(">i", ord(value))
+        elif tag == "r":
+            data += struct.pack(">I", value)
+        elif tag == "m":
+            data += bytes(value)
+        elif tag == "[":
+            data += spec_payload(value)
+        elif tag not in "TFNI":
+            raise ValueError("unknown typetag %r" % tag)
+    return data
+
+
+def spec_encode(p):
+    if isinstance(p, M):
+        return spec_string(p.addr) + spec_string("," + spec_typetags(p.args)) + spec_payload(p.args)
+    data = spec_string("#bundle") + struct.pack(">Q", p.timetag)
+    for elem in p.elems:
+        encoded = spec_encode(elem)
+        data += struct.pack(">i", len(encoded)) + encoded
+    return data
+
+
+def tags_of(p):
+    if isinstance(p, M):
+        return set(spec_typetags(p.args))
+    tags = set()
+    for elem in p.elems:
+        tags |= tags_of(elem)
+    return tags
+
+
+def python_osc_timestamp(timetag, bundle_builder):
+    """The timestamp of OscBundleBuilder for timetag, or None if it has none."""
+    if timetag == IMMEDIATELY:
+        return bundle_builder.IMMEDIATELY
+    seconds, fraction = timetag >> 32, timetag & 0xFFFFFFFF
+    if seconds < NTP_UNIX_OFFSET or fraction not in (0, 0x80000000):
+        return None  # Not exactly a float of Unix seconds.
+    return seconds - NTP_UNIX_OFFSET + fraction / 2**32
+
+
+def python_osc_value(tag, value):
+    if tag == "[":
+        return [python_osc_value(t, v) for t, v in value]
+    return value
+
+
+def python_osc_type(tag, value):
+    if tag == "[":
+        return [python_osc_type(t, v) for t, v in value]
+    return tag
+
+
+def python_osc_build(p, message_builder, bundle_builder):
+    """Builds p with python-osc, or returns None if it can not."""
+    if isinstance(p, M):
+        builder = message_builder.OscMessageBuilder(address=p.addr)
+        for tag, value in p.args:
+            builder.add_arg(python_osc_value(tag, value), python_osc_type(tag, value))
+        return builder.build()
+    timestamp = python_osc_timestamp(p.timetag, bundle_builder)
+    if timestamp is None:
+        return None
+    builder = bundle_builder.OscBundleBuilder(timestamp)
+    for elem in p.elems:
+        content = python_osc_build(elem, message_builder, bundle_builder)
+        if content is None:
+            return None
+        builder.add_content(content)
+    return builder.build()
+
+
+def main():
+    spec_only = "--spec" in sys.argv[1:]
+    message_builder = bundle_builder = None
+    if not spec_only:
+        try:
+            from pythonosc import osc_bundle_builder as bundle_builder
+            from pythonosc import osc_message_builder as message_builder
+        except ImportError:
+            sys.exit("python-osc is not installed: pip install python-osc, or run with --spec")
+        supported = {getattr(message_builder.OscMessageBuilder, name) for name in dir(message_builder.OscMessageBuilder) if name.startswith("ARG_TYPE_")}
+        python_osc_tags = PYTHON_OSC_TAGS & supported
+
+    sources = []
+    for name, p in sorted(VECTORS.items()):
+        data, source = spec_encode(p), "spec"
+        if not spec_only and tags_of(p) <= python_osc_tags:
+            try:
+                built = python_osc_build(p, message_builder, bundle_builder)
+                encoded = None if built is None else built.dgram
+            except Exception as e:  # python-osc rejected the arguments.
+                print("%s: python-osc: %s" % (name, e), file=sys.stderr)
+                encoded = None
+            if encoded is not None:
+                if encoded != data:
+                    print("%s: python-osc and the specification disagree" % name, file=sys.stderr)
+                data, source = encoded, "python-osc"
+        assert len(data) % 4 == 0, name
+        with open(os.path.join(OUT, name + ".osc"), "wb") as fp:
+            fp.write(data)
+        sources.append("%s %s\n" % (name, source))
+
+    with open(os.path.join(OUT, "SOURCES"), "w") as fp:
+        fp.writelines(sources)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,191 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors of the packets that deviate from the OSC specification
+// in ways that the parse options do not allow.
+var (
+	ErrMissingTypetags = errors.New("message has no typetags")
+	ErrMissingPadding  = errors.New("missing padding")
+	ErrUnalignedPacket = errors.New("packet size is not a multiple of 4")
+
+	ErrTruncatedArguments = errors.New("arguments after an unknown typetag were dropped")
+
+	ErrBundleTooDeep   = errors.New("bundles are nested deeper than the maximum bundle depth")
+	ErrTooManyElements = errors.New("bundle has more elements than the maximum")
+	ErrBlobTooLarge    = errors.New("blob is larger than the maximum blob size")
+)
+
+// The limits of the bundles that are parsed, unless the parse options set others.
+const (
+	DefaultMaxBundleDepth    = 16
+	DefaultMaxBundleElements = 4096
+)
+
+// ParseOptions select the deviations from the OSC specification that are tolerated
+// when packets are parsed, so that a conn can accept exactly what a peer that
+// does not follow it sends. The zero value tolerates none of them.
+// Packets that are parsed without parse options, like ParsePacket does,
+// may have no typetags if nothing follows their address, and may be
+// missing the padding of their last string or blob.
+type ParseOptions struct {
+	// MissingTypetags accepts messages without a typetag string, which OSC 1.0
+	// allows, as messages without arguments. Whatever follows their address is ignored.
+	// Otherwise they are rejected with ErrMissingTypetags.
+	MissingTypetags bool
+
+	// MissingPadding accepts messages whose last string or blob, which may be their
+	// address or their typetags, is missing some or all of its NUL padding.
+	// Otherwise they are rejected with ErrMissingPadding.
+	MissingPadding bool
+
+	// UnalignedSize accepts packets whose size is not a multiple of 4,
+	// such as messages that are followed by stray bytes.
+	// Otherwise they are rejected with ErrUnalignedPacket.
+	UnalignedSize bool
+
+	// UnknownTypetags accepts messages with typetags that are not known, as long
+	// as no known typetag follows them. The size of an unknown argument is not known,
+	// so the arguments are cut short at the first unknown typetag and the message
+	// is Truncated, although RawArguments still returns them. A conn passes an error
+	// wrapping ErrTruncatedArguments to its error handler for every such message,
+	// and still dispatches it.
+	// Otherwise the messages are rejected with ErrInvalidTypeTag.
+	UnknownTypetags bool
+
+	// MaxBundleDepth is how deeply bundles may be nested, counting the outermost one,
+	// and MaxBundleElements how many messages and bundles a bundle may have,
+	// counting the elements of the bundles nested in it. Zero means
+	// DefaultMaxBundleDepth and DefaultMaxBundleElements, which also limit the
+	// packets that are parsed without parse options. Bundles beyond the limits are
+	// rejected with ErrBundleTooDeep and ErrTooManyElements, and a conn with a scheduler
+	// does not schedule bundles with more elements than the limit either.
+	MaxBundleDepth    int
+	MaxBundleElements int
+
+	// MaxBlobSize is the size of the largest blob that is accepted, in bytes,
+	// so that a stream can not make a message hold more memory than expected.
+	// Larger blobs are rejected with ErrBlobTooLarge. Zero means no limit.
+	MaxBlobSize int
+}
+
+// The parse options that tolerate none and all of the deviations from the specification.
+var (
+	StrictParsing  = ParseOptions{}
+	LenientParsing = ParseOptions{MissingTypetags: true, MissingPadding: true, UnalignedSize: true, UnknownTypetags: true}
+)
+
+// ParsePacket parses a message or a bundle from raw bytes like the ParsePacket
+// function does, tolerating only the deviations from the specification that o allows.
+// The returned packet does not share any memory with data.
+func (o ParseOptions) ParsePacket(data []byte) (Packet, error) {
+	return clonePacket(parsePacket(data, nil, &o))
+}
+
+// maxBundleDepth returns the limit of the depth of bundles, which o may be nil for.
+func (o *ParseOptions) maxBundleDepth() int {
+	if o == nil || o.MaxBundleDepth <= 0 {
+		return DefaultMaxBundleDepth
+	}
+	return o.MaxBundleDepth
+}
+
+// maxBundleElements returns the limit of the elements of bundles, which o may be nil for.
+func (o *ParseOptions) maxBundleElements() int {
+	if o == nil || o.MaxBundleElements <= 0 {
+		return DefaultMaxBundleElements
+	}
+	return o.MaxBundleElements
+}
+
+// parseState is the state of parsing a packet, which is kept to enforce
+// the limits of its bundles.
+type parseState struct {
+	options *ParseOptions // May be nil.
+
+	depth, maxDepth       int
+	elements, maxElements int
+}
+
+// newParseState returns the state of parsing a packet with o, which may be nil.
+func newParseState(o *ParseOptions) *parseState {
+	return &parseState{options: o, maxDepth: o.maxBundleDepth(), maxElements: o.maxBundleElements()}
+}
+
+// countElements returns how many messages and bundles b has, counting nested ones.
+func countElements(b Bundle) int {
+	n := len(b.Packets)
+	for _, p := range b.Packets {
+		if nested, ok := p.(Bundle); ok {
+			n += countElements(nested)
+		}
+	}
+	return n
+}
+
+// parseOptions returns the parse options of the conn, or nil if it has none.
+func (s *connState) parseOptions() *ParseOptions {
+	return s.parse
+}
+
+// truncatedError returns an error wrapping ErrTruncatedArguments for the first
+// message of p that is Truncated, or nil if there is none.
+func truncatedError(p Packet) error {
+	switch x := p.(type) {
+	case Message:
+		if x.truncated {
+			return fmt.Errorf("message %s from %s: %w", x.Address, x.Sender, ErrTruncatedArguments)
+		}
+	case Bundle:
+		for _, el := range x.Packets {
+			if err := truncatedError(el); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTypetag returns true if tt is a known typetag.
+func isTypetag(tt byte) bool {
+	return tt == TypetagArrayStart || tt == TypetagArrayEnd || strings.IndexByte(scalarTypetags, tt) >= 0
+}
+
+// hasTypetag returns true if typetags has a known typetag.
+func hasTypetag(typetags []byte) bool {
+	for _, tt := range typetags {
+		if isTypetag(tt) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNoTypetags returns an error unless o allows data, a message whose
+// address is not followed by typetags. The address ends at offset, which is
+// past the end of data if the address is missing its padding.
+func (o *ParseOptions) checkNoTypetags(data []byte, offset int) error {
+	if !o.MissingTypetags {
+		return ErrMissingTypetags
+	}
+	return o.checkEnd(data, offset > len(data))
+}
+
+// checkEnd returns an error unless o allows the end of data, a message
+// whose last string or blob is missing its padding if short is true.
+func (o *ParseOptions) checkEnd(data []byte, short bool) error {
+	if short {
+		if !o.MissingPadding {
+			return fmt.Errorf("message of %d bytes: %w", len(data), ErrMissingPadding)
+		}
+		return nil
+	}
+	if !o.UnalignedSize && len(data)%4 != 0 {
+		return fmt.Errorf("message of %d bytes: %w", len(data), ErrUnalignedPacket)
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readFixture reads a packet of testdata/broken, which does not follow the specification.
+// The fixtures are not captures of real senders: they were written by hand, and each one
+// reproduces what a kind of sender does.
+//
+//   - no_typetags.osc is a message without typetags, like those of the senders that
+//     predate OSC 1.0, which the specification asks receivers to tolerate.
+//   - missing_padding.osc is a message whose last string has neither its null byte
+//     nor its padding, like those of senders that size the datagram from the string.
+//   - bundle_missing_padding.osc is a bundle of such a message, whose size is not
+//     a multiple of 4.
+//   - trailing_newline.osc is a message followed by a newline, like those that shell
+//     scripts send by piping the output of echo to netcat.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "broken", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseOptions(t *testing.T) {
+	var (
+		freq   = MustMessage("/freq")
+		name   = MustMessage("/name", String("hello"))
+		note   = MustMessage("/note", Int(60))
+		bundle = Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/a", String("hi"))}}
+	)
+	for i, testcase := range []struct {
+		Fixture string
+		Options *ParseOptions
+		Packet  Packet
+		Err     error
+	}{
+		{Fixture: "no_typetags.osc", Err: ErrInvalidTypeTag},
+		{Fixture: "no_typetags.osc", Options: &StrictParsing, Err: ErrMissingTypetags},
+		{Fixture: "no_typetags.osc", Options: &ParseOptions{MissingPadding: true, UnalignedSize: true}, Err: ErrMissingTypetags},
+		{Fixture: "no_typetags.osc", Options: &ParseOptions{MissingTypetags: true}, Packet: freq},
+		{Fixture: "no_typetags.osc", Options: &LenientParsing, Packet: freq},
+
+		{Fixture: "missing_padding.osc", Packet: name},
+		{Fixture: "missing_padding.osc", Options: &StrictParsing, Err: ErrMissingPadding},
+		{Fixture: "missing_padding.osc", Options: &ParseOptions{UnalignedSize: true}, Err: ErrMissingPadding},
+		{Fixture: "missing_padding.osc", Options: &ParseOptions{MissingPadding: true}, Packet: name},
+		{Fixture: "missing_padding.osc", Options: &LenientParsing, Packet: name},
+
+		{Fixture: "trailing_newline.osc", Packet: note},
+		{Fixture: "trailing_newline.osc", Options: &StrictParsing, Err: ErrUnalignedPacket},
+		{Fixture: "trailing_newline.osc", Options: &ParseOptions{MissingPadding: true}, Err: ErrUnalignedPacket},
+		{Fixture: "trailing_newline.osc", Options: &ParseOptions{UnalignedSize: true}, Packet: note},
+		{Fixture: "trailing_newline.osc", Options: &LenientParsing, Packet: note},
+
+		{Fixture: "bundle_missing_padding.osc", Packet: bundle},
+		{Fixture: "bundle_missing_padding.osc", Options: &StrictParsing, Err: ErrMissingPadding},
+		{Fixture: "bundle_missing_padding.osc", Options: &ParseOptions{MissingPadding: true}, Packet: bundle},
+		{Fixture: "bundle_missing_padding.osc", Options: &LenientParsing, Packet: bundle},
+	} {
+		var (
+			data = readFixture(t, testcase.Fixture)
+			p    Packet
+			err  error
+		)
+		if testcase.Options == nil {
+			p, err = ParsePacket(data)
+		} else {
+			p, err = testcase.Options.ParsePacket(data)
+		}
+		if testcase.Err != nil {
+			var pe *ParseError
+			if !errors.Is(err, testcase.Err) || !errors.As(err, &pe) {
+				t.Fatalf("(testcase %d) %s: expected a *ParseError wrapping %v, got %v", i, testcase.Fixture, testcase.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("(testcase %d) %s: %s", i, testcase.Fixture, err)
+		}
+		if !testcase.Packet.Equal(p) {
+			t.Fatalf("(testcase %d) %s: expected %#v, got %#v", i, testcase.Fixture, testcase.Packet, p)
+		}
+	}
+}
+
+func TestParseOptions_Strict(t *testing.T) {
+	for i, p := range []Packet{
+		MustMessage("/ping"),
+		MustMessage("/synth/1", Int(1), String("abc"), Blob{1, 2, 3}, Array{Float(1), Bool(true)}),
+		Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/a", String("hi")), Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/b")}}}},
+	} {
+		if _, err := StrictParsing.ParsePacket(p.Bytes()); err != nil {
+			t.Fatalf("(testcase %d) expected a packet that follows the specification to be parsed, got %s", i, err)
+		}
+	}
+}
+
+func TestWithParseOptions(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		Options ParseOptions
+		Err     error
+	}{
+		{Options: StrictParsing, Err: ErrMissingTypetags},
+		{Options: LenientParsing},
+	} {
+		server, err := ListenUDP("udp", laddr, WithParseOptions(testcase.Options))
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			errs    = make(chan error, 1)
+			handled = make(chan Message, 1)
+		)
+		server.SetErrorHandler(func(err error) {
+			errs <- err
+		})
+		go func() {
+			_ = server.Serve(1, PatternMatching{"/freq": Method(func(msg Message) error {
+				handled <- msg
+				return nil
+			})})
+		}()
+		if _, err := client.udpConn.Write(readFixture(t, "no_typetags.osc")); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-errs:
+			if testcase.Err == nil || !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected error %v, got %v", i, testcase.Err, err)
+			}
+		case msg := <-handled:
+			if testcase.Err != nil {
+				t.Fatalf("(testcase %d) expected error %v, got %s", i, testcase.Err, msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(testcase %d) timeout waiting for the packet", i)
+		}
+		_ = client.Close() // Best effort.
+		_ = server.Close() // Best effort.
+	}
+}
+
+// unknownTypetagPacket is a message to /foo with typetags that are not known.
+// osctest.InjectTypetag builds such messages too, but osctest imports this package.
+type unknownTypetagPacket struct {
+	typetags string
+	data     []byte
+}
+
+func (p unknownTypetagPacket) Bytes() []byte {
+	return bytes.Join([][]byte{ToBytes("/foo"), ToBytes(p.typetags), p.data}, []byte{})
+}
+
+func (p unknownTypetagPacket) Equal(other Packet) bool {
+	return false
+}
+
+func TestParseOptions_UnknownTypetags(t *testing.T) {
+	var (
+		args    = bytes.Join([][]byte{Int(1).Bytes(), Float(2).Bytes(), {0xde, 0xad, 0xbe, 0xef}}, []byte{})
+		skip    = &ParseOptions{UnknownTypetags: true}
+		foo     = MustMessage("/foo")
+		fooArgs = MustMessage("/foo", Int(1), Float(2))
+		bad     = unknownTypetagPacket{typetags: ",Q"}
+	)
+	for i, testcase := range []struct {
+		Packet    Packet
+		Options   *ParseOptions
+		Expected  Packet
+		Truncated bool
+		Err       error
+	}{
+		{Packet: bad, Err: ErrInvalidTypeTag},
+		{Packet: bad, Options: skip, Expected: foo, Truncated: true},
+		{Packet: bad, Options: &LenientParsing, Expected: foo, Truncated: true},
+		{Packet: unknownTypetagPacket{typetags: ",ifQ", data: args}, Err: ErrInvalidTypeTag},
+		{Packet: unknownTypetagPacket{typetags: ",ifQ", data: args}, Options: &StrictParsing, Err: ErrInvalidTypeTag},
+		{Packet: unknownTypetagPacket{typetags: ",ifQ", data: args}, Options: skip, Expected: fooArgs, Truncated: true},
+		{Packet: unknownTypetagPacket{typetags: ",ifQR", data: args}, Options: skip, Expected: fooArgs, Truncated: true},
+		{Packet: unknownTypetagPacket{typetags: ",if", data: args[:8]}, Options: skip, Expected: fooArgs},
+		{Packet: unknownTypetagPacket{typetags: ",iQf", data: args}, Options: skip, Err: ErrInvalidTypeTag},
+		{Packet: unknownTypetagPacket{typetags: ",i[Q]", data: args}, Options: skip, Err: ErrInvalidTypeTag},
+		{Packet: Bundle{Timetag: Immediately, Packets: []Packet{bad}}, Options: skip, Expected: Bundle{Packets: []Packet{foo}}, Truncated: true},
+	} {
+		p, err := ParsePacket(testcase.Packet.Bytes())
+		if testcase.Options != nil {
+			p, err = testcase.Options.ParsePacket(testcase.Packet.Bytes())
+		}
+		if testcase.Err != nil {
+			if !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected error %v, got %v", i, testcase.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		msg, ok := p.(Message)
+		if b, isBundle := p.(Bundle); isBundle {
+			expected := testcase.Expected.(Bundle)
+			if len(b.Packets) != len(expected.Packets) {
+				t.Fatalf("(testcase %d) expected %d packets, got %d", i, len(expected.Packets), len(b.Packets))
+			}
+			msg, ok = b.Packets[0].(Message)
+			testcase.Expected = expected.Packets[0]
+		}
+		if !ok || !testcase.Expected.Equal(msg) {
+			t.Fatalf("(testcase %d) expected %s, got %#v", i, testcase.Expected, p)
+		}
+		if msg.Truncated() != testcase.Truncated {
+			t.Fatalf("(testcase %d) expected truncated %t, got %t", i, testcase.Truncated, msg.Truncated())
+		}
+		if err := truncatedError(p); (err != nil) != testcase.Truncated || testcase.Truncated && !errors.Is(err, ErrTruncatedArguments) {
+			t.Fatalf("(testcase %d) expected truncated %t, got error %v", i, testcase.Truncated, err)
+		}
+	}
+}
+
+func TestWithParseOptions_UnknownTypetags(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr, WithParseOptions(ParseOptions{UnknownTypetags: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		errs    = make(chan error, 1)
+		handled = make(chan Message, 1)
+	)
+	server.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = server.Serve(1, PatternMatching{"/foo": Method(func(msg Message) error {
+			handled <- msg
+			return nil
+		})})
+	}()
+	packet := unknownTypetagPacket{typetags: ",iQ", data: Int(1).Bytes()}
+	if _, err := client.udpConn.Write(packet.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrTruncatedArguments) {
+			t.Fatalf("expected error %v, got %v", ErrTruncatedArguments, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the warning")
+	}
+	select {
+	case msg := <-handled:
+		if !msg.Truncated() || !msg.Equal(MustMessage("/foo", Int(1))) {
+			t.Fatalf("expected a truncated /foo with an int, got %s", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the message")
+	}
+}
+
+// nestedBundle returns a bundle nested depth deep, counting itself, around a message.
+func nestedBundle(depth int) Bundle {
+	b := Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/a")}}
+	for i := 1; i < depth; i++ {
+		b = Bundle{Timetag: Immediately, Packets: []Packet{b}}
+	}
+	return b
+}
+
+// wideBundle returns a bundle with n bundles that each have a message.
+func wideBundle(n int) Bundle {
+	b := Bundle{Timetag: Immediately}
+	for i := 0; i < n; i++ {
+		b.Packets = append(b.Packets, Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/a")}})
+	}
+	return b
+}
+
+func TestParseOptions_BundleLimits(t *testing.T) {
+	// The smallest element, a message with a one character address and no typetags.
+	tiny := []byte{0, 0, 0, 4, '/', 0, 0, 0}
+	flood := append(Bundle{Timetag: Immediately}.Bytes(), bytes.Repeat(tiny, DefaultMaxBundleElements+1)...)
+
+	for i, testcase := range []struct {
+		Data    []byte
+		Options *ParseOptions
+		Err     error
+	}{
+		{Data: nestedBundle(DefaultMaxBundleDepth).Bytes()},
+		{Data: nestedBundle(DefaultMaxBundleDepth + 1).Bytes(), Err: ErrBundleTooDeep},
+		{Data: nestedBundle(500).Bytes(), Err: ErrBundleTooDeep},
+		{Data: nestedBundle(DefaultMaxBundleDepth + 1).Bytes(), Options: &ParseOptions{MaxBundleDepth: DefaultMaxBundleDepth + 1}},
+		{Data: nestedBundle(3).Bytes(), Options: &ParseOptions{MaxBundleDepth: 2}, Err: ErrBundleTooDeep},
+		{Data: flood[:len(flood)-len(tiny)], Options: &LenientParsing},
+		{Data: flood, Err: ErrTooManyElements},
+		{Data: flood, Options: &LenientParsing, Err: ErrTooManyElements},
+		{Data: flood, Options: &ParseOptions{MissingTypetags: true, MaxBundleElements: DefaultMaxBundleElements + 1}},
+		// The elements of nested bundles count too: 3 bundles and 3 messages.
+		{Data: wideBundle(3).Bytes(), Options: &ParseOptions{MaxBundleElements: 6}},
+		{Data: wideBundle(3).Bytes(), Options: &ParseOptions{MaxBundleElements: 5}, Err: ErrTooManyElements},
+	} {
+		var err error
+		if testcase.Options == nil {
+			_, err = ParsePacket(testcase.Data)
+		} else {
+			_, err = testcase.Options.ParsePacket(testcase.Data)
+		}
+		if testcase.Err == nil {
+			if err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+			continue
+		}
+		var pe *ParseError
+		if !errors.Is(err, testcase.Err) || !errors.As(err, &pe) {
+			t.Fatalf("(testcase %d) expected a *ParseError wrapping %v, got %v", i, testcase.Err, err)
+		}
+	}
+}
+
+func TestScheduler_MaxElements(t *testing.T) {
+	var (
+		clock      = newFakeClock()
+		errs       []error
+		dispatched int
+	)
+	s := &scheduler{
+		clock: clock,
+		dispatcher: PatternMatching{"/a": Method(func(msg Message) error {
+			dispatched++
+			return nil
+		})},
+		handleError: func(err error) { errs = append(errs, err) },
+		maxElements: 5,
+	}
+	later := FromTime(clock.Now().Add(time.Second))
+	for _, b := range []Bundle{wideBundle(2), wideBundle(3)} {
+		b.Timetag = later
+		if !s.schedule(b) {
+			t.Fatal("expected the bundle to be scheduled or dropped")
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTooManyElements) {
+		t.Fatalf("expected the bundle with too many elements to be reported, got %v", errs)
+	}
+	clock.Advance(time.Second)
+	if dispatched != 2 {
+		t.Fatalf("expected the messages of the bundle within the limit to be dispatched, got %d", dispatched)
+	}
+}
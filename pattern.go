@@ -0,0 +1,294 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pattern is a compiled OSC address pattern.
+// See http://opensoundcontrol.org/spec-1_0 "OSC Message Dispatching and Pattern Matching"
+type Pattern struct {
+	pattern string
+	parts   [][]patternToken
+	literal bool // Whether the pattern has no special characters.
+}
+
+// patternToken is a piece of one part of a pattern.
+type patternToken struct {
+	kind   byte     // One of the token kinds below.
+	lit    string   // The literal for tokenLiteral.
+	alts   []string // The alternatives for tokenAlternatives.
+	ranges []rune   // Pairs of first and last runes for tokenClass.
+	negate bool     // Whether tokenClass matches runes outside of ranges.
+}
+
+// Token kinds.
+const (
+	tokenLiteral      byte = 'l'
+	tokenAny          byte = '?'
+	tokenStar         byte = '*'
+	tokenClass        byte = '['
+	tokenAlternatives byte = '{'
+)
+
+// CompilePattern parses an OSC address pattern.
+//
+// The parts of the pattern between slashes are matched with parts of an address,
+// so '?' matches any character but '/' and '*' matches any sequence of characters
+// that does not contain '/'. "[a-z]" matches a character in a range or list,
+// "[!a-z]" matches a character that is not, and "{foo,bar}" matches either
+// of the strings in the braces.
+//
+// Patterns with nested or unbalanced brackets and braces are rejected
+// with an *AddressError that names the offending character.
+// Matching takes time proportional to the length of the pattern times
+// the length of the address, so careless patterns can not stall a server.
+func CompilePattern(pattern string) (*Pattern, error) {
+	p := &Pattern{pattern: pattern}
+	offset := 0
+	for _, part := range strings.Split(pattern, string(MessageChar)) {
+		tokens, err := compilePart(pattern, part, offset, true)
+		if err != nil {
+			return nil, err
+		}
+		p.parts = append(p.parts, tokens)
+		offset += len(part) + 1
+	}
+	p.literal = !isPattern(pattern)
+	return p, nil
+}
+
+// checkPattern returns the error that CompilePattern returns for pattern,
+// without allocating the tokens of the pattern.
+func checkPattern(pattern string) error {
+	for offset := 0; ; {
+		part := pattern[offset:]
+		if end := strings.IndexByte(part, MessageChar); end >= 0 {
+			part = part[:end]
+		}
+		if _, err := compilePart(pattern, part, offset, false); err != nil {
+			return err
+		}
+		offset += len(part) + 1
+		if offset > len(pattern) {
+			return nil
+		}
+	}
+}
+
+// isPattern returns true if addr has any of the special characters of a pattern.
+func isPattern(addr string) bool {
+	return strings.ContainsAny(addr, "?*[{")
+}
+
+// compilePart compiles the part of pattern that starts at offset,
+// or only checks it if compile is false.
+func compilePart(pattern, part string, offset int, compile bool) ([]patternToken, error) {
+	var (
+		tokens []patternToken
+		lit    = -1 // The offset of the literal that is being read, if any.
+	)
+	flush := func(i int) {
+		if lit >= 0 && compile {
+			tokens = append(tokens, patternToken{kind: tokenLiteral, lit: part[lit:i]})
+		}
+		lit = -1
+	}
+	for i := 0; i < len(part); i++ {
+		switch c := part[i]; c {
+		case '?', '*':
+			flush(i)
+			if compile {
+				tokens = append(tokens, patternToken{kind: c})
+			}
+		case '[', '{':
+			end := strings.IndexAny(part[i+1:], "[]{}")
+			if end == -1 {
+				if rest := pattern[offset+len(part):]; strings.ContainsAny(rest, "]}") {
+					return nil, addressError(pattern, offset+len(part), "has '/' at offset %d inside %q at offset %d", offset+len(part), c, offset+i)
+				}
+				return nil, addressError(pattern, offset+i, "has unbalanced %q at offset %d", c, offset+i)
+			}
+			end += i + 1
+			if closing := part[end]; (c == '[' && closing != ']') || (c == '{' && closing != '}') {
+				if closing == '[' || closing == '{' {
+					return nil, addressError(pattern, offset+end, "has %q at offset %d inside %q at offset %d", closing, offset+end, c, offset+i)
+				}
+				return nil, addressError(pattern, offset+end, "has unbalanced %q at offset %d", closing, offset+end)
+			}
+			flush(i)
+			var (
+				tok patternToken
+				err error
+			)
+			if c == '[' {
+				tok, err = compileClass(part[i+1:end], compile)
+			} else if compile {
+				tok = compileAlternatives(part[i+1 : end])
+			}
+			if err != nil {
+				return nil, addressError(pattern, offset+i, "at offset %d: %v", offset+i, err)
+			}
+			if compile {
+				tokens = append(tokens, tok)
+			}
+			i = end
+		case ']', '}':
+			return nil, addressError(pattern, offset+i, "has unbalanced %q at offset %d", c, offset+i)
+		default:
+			if lit < 0 {
+				lit = i
+			}
+		}
+	}
+	flush(len(part))
+	return tokens, nil
+}
+
+// addressError returns an *AddressError for pattern whose reason is formatted
+// from format and args.
+func addressError(pattern string, offset int, format string, args ...interface{}) error {
+	return &AddressError{Address: pattern, Offset: offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+// compileClass compiles the inside of "[...]", or only checks it if compile is false.
+// A '-' at either end is literal, as is a '!' that is not first.
+func compileClass(s string, compile bool) (patternToken, error) {
+	tok := patternToken{kind: tokenClass}
+	if strings.HasPrefix(s, "!") {
+		tok.negate, s = true, s[1:]
+	}
+	if len(s) == 0 {
+		return patternToken{}, errors.New("empty character class")
+	}
+	for len(s) > 0 {
+		first, size := utf8.DecodeRuneInString(s)
+		last, rest := first, s[size:]
+		if dash, n := utf8.DecodeRuneInString(rest); dash == '-' && len(rest) > n {
+			var m int
+			last, m = utf8.DecodeRuneInString(rest[n:])
+			if first > last {
+				return patternToken{}, fmt.Errorf("range %c-%c is reversed", first, last)
+			}
+			rest = rest[n+m:]
+		}
+		if compile {
+			tok.ranges = append(tok.ranges, first, last)
+		}
+		s = rest
+	}
+	return tok, nil
+}
+
+// compileAlternatives compiles the inside of "{...}".
+func compileAlternatives(s string) patternToken {
+	tok := patternToken{kind: tokenAlternatives}
+	tok.alts = strings.Split(s, ",")
+	return tok
+}
+
+// Match returns true if addr matches the pattern.
+func (p *Pattern) Match(addr string) bool {
+	if p.literal {
+		return addr == p.pattern
+	}
+	for i, tokens := range p.parts {
+		end := strings.IndexByte(addr, MessageChar)
+		if i == len(p.parts)-1 {
+			return end == -1 && matchPart(tokens, addr)
+		}
+		if end == -1 || !matchPart(tokens, addr[:end]) {
+			return false
+		}
+		addr = addr[end+1:]
+	}
+	return false
+}
+
+// String returns the pattern that was compiled.
+func (p *Pattern) String() string {
+	return p.pattern
+}
+
+// matchPart returns true if part matches all of tokens.
+// It tracks every offset in part that the tokens so far can end at,
+// instead of backtracking.
+func matchPart(tokens []patternToken, part string) bool {
+	if len(tokens) == 1 && tokens[0].kind == tokenLiteral {
+		return part == tokens[0].lit
+	}
+	var (
+		n    = len(part)
+		buf  [128]bool
+		curr []bool
+		next []bool
+	)
+	if 2*(n+1) <= len(buf) {
+		curr, next = buf[:n+1], buf[n+1:2*(n+1)]
+	} else {
+		curr, next = make([]bool, n+1), make([]bool, n+1)
+	}
+	curr[0] = true
+
+	for _, tok := range tokens {
+		for i := range next {
+			next[i] = false
+		}
+		reachable := false
+		for i := 0; i <= n; i++ {
+			if !curr[i] {
+				continue
+			}
+			reachable = true
+
+			switch tok.kind {
+			case tokenLiteral:
+				if strings.HasPrefix(part[i:], tok.lit) {
+					next[i+len(tok.lit)] = true
+				}
+			case tokenAny:
+				if i < n && utf8.RuneStart(part[i]) {
+					_, size := utf8.DecodeRuneInString(part[i:])
+					next[i+size] = true
+				}
+			case tokenClass:
+				if i < n && utf8.RuneStart(part[i]) {
+					r, size := utf8.DecodeRuneInString(part[i:])
+					if tok.matchClass(r) {
+						next[i+size] = true
+					}
+				}
+			case tokenAlternatives:
+				for _, alt := range tok.alts {
+					if strings.HasPrefix(part[i:], alt) {
+						next[i+len(alt)] = true
+					}
+				}
+			case tokenStar:
+				// Every offset from the first reachable one on is reachable.
+				// The ones inside of multi-byte runes are skipped by the other tokens.
+				for j := i; j <= n; j++ {
+					next[j] = true
+				}
+				i = n
+			}
+		}
+		if !reachable {
+			return false
+		}
+		curr, next = next, curr
+	}
+	return curr[n]
+}
+
+// matchClass returns true if r is matched by a character class.
+func (tok patternToken) matchClass(r rune) bool {
+	for i := 0; i < len(tok.ranges); i += 2 {
+		if r >= tok.ranges[i] && r <= tok.ranges[i+1] {
+			return !tok.negate
+		}
+	}
+	return tok.negate
+}
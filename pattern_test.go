@@ -0,0 +1,182 @@
+package osc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	for i, testcase := range []struct {
+		Pattern string
+		Matches []string
+		Misses  []string
+	}{
+		{
+			Pattern: "/foo/bar",
+			Matches: []string{"/foo/bar"},
+			Misses:  []string{"/foo", "/foo/bar/", "/foo/baz", "/foo/bar/baz"},
+		},
+		{
+			Pattern: "/foo/ba?",
+			Matches: []string{"/foo/bar", "/foo/baz", "/foo/ba♪"},
+			Misses:  []string{"/foo/ba", "/foo/barr", "/foo/ba/"},
+		},
+		{
+			Pattern: "/foo/*",
+			Matches: []string{"/foo/", "/foo/bar", "/foo/*"},
+			Misses:  []string{"/foo", "/foo/bar/baz"},
+		},
+		{
+			Pattern: "/*/b*r*",
+			Matches: []string{"/foo/br", "/x/bar", "/x/barrr", "/y/bxrx"},
+			Misses:  []string{"/foo/b", "/foo/rbr", "/a/b/bar"},
+		},
+		{
+			Pattern: "/ch[a-c]",
+			Matches: []string{"/cha", "/chb", "/chc"},
+			Misses:  []string{"/ch", "/chd", "/chA", "/chab"},
+		},
+		{
+			Pattern: "/ch[0-9x-]",
+			Matches: []string{"/ch0", "/ch9", "/chx", "/ch-"},
+			Misses:  []string{"/chy", "/ch"},
+		},
+		{
+			Pattern: "/ch[!a]",
+			Matches: []string{"/chb", "/ch!", "/ch1"},
+			Misses:  []string{"/cha", "/ch", "/chbb"},
+		},
+		{
+			Pattern: "/ch[a!]",
+			Matches: []string{"/cha", "/ch!"},
+			Misses:  []string{"/chb"},
+		},
+		{
+			Pattern: "/*[!é]",
+			Matches: []string{"/éx", "/x"},
+			Misses:  []string{"/xé", "/é"},
+		},
+		{
+			Pattern: "/*??",
+			Matches: []string{"/éé", "/ab"},
+			Misses:  []string{"/é", "/a"},
+		},
+		{
+			Pattern: "/{foo,bar}/x",
+			Matches: []string{"/foo/x", "/bar/x"},
+			Misses:  []string{"/baz/x", "/foobar/x", "/fo/x"},
+		},
+		{
+			Pattern: "/{foo,foobar,}baz",
+			Matches: []string{"/foobaz", "/foobarbaz", "/baz"},
+			Misses:  []string{"/barbaz"},
+		},
+		{
+			Pattern: "/n_{set,run}/[0-9]*/?",
+			Matches: []string{"/n_set/1000/a", "/n_run/1/b"},
+			Misses:  []string{"/n_get/1000/a", "/n_set/x/a", "/n_set/1/ab"},
+		},
+	} {
+		p, err := CompilePattern(testcase.Pattern)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if err := checkPattern(testcase.Pattern); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if expected, got := testcase.Pattern, p.String(); expected != got {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, expected, got)
+		}
+		for _, addr := range testcase.Matches {
+			if !p.Match(addr) {
+				t.Fatalf("(testcase %d) expected %s to match %s", i, testcase.Pattern, addr)
+			}
+		}
+		for _, addr := range testcase.Misses {
+			if p.Match(addr) {
+				t.Fatalf("(testcase %d) expected %s to not match %s", i, testcase.Pattern, addr)
+			}
+		}
+	}
+}
+
+func TestPatternPathological(t *testing.T) {
+	// A backtracking matcher takes exponential time for these.
+	var (
+		long = "/" + strings.Repeat("a", 10000)
+		pats = []string{
+			"/" + strings.Repeat("*a", 100) + "b",
+			"/" + strings.Repeat("{a,aa,aaa}", 100) + "b",
+			"/" + strings.Repeat("?*", 200) + "b",
+		}
+	)
+	for i, pattern := range pats {
+		p, err := CompilePattern(pattern)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if p.Match(long) {
+			t.Fatalf("(testcase %d) expected %s to not match", i, pattern)
+		}
+	}
+	p, err := CompilePattern("/" + strings.Repeat("*", 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Match(long) {
+		t.Fatalf("expected stars to match")
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for i, testcase := range []struct {
+		Pattern  string
+		Expected string
+	}{
+		{Pattern: "/[", Expected: `"/[" has unbalanced '[' at offset 1`},
+		{Pattern: "/a]", Expected: `"/a]" has unbalanced ']' at offset 2`},
+		{Pattern: "/{a,b", Expected: `"/{a,b" has unbalanced '{' at offset 1`},
+		{Pattern: "/{a]", Expected: `"/{a]" has unbalanced ']' at offset 3`},
+		{Pattern: "/[a{b}]", Expected: `"/[a{b}]" has '{' at offset 3 inside '[' at offset 1`},
+		{Pattern: "/[a/b]", Expected: `"/[a/b]" has '/' at offset 3 inside '[' at offset 1`},
+		{Pattern: "/[]", Expected: `"/[]" at offset 1: empty character class`},
+		{Pattern: "/[!]", Expected: `"/[!]" at offset 1: empty character class`},
+		{Pattern: "/x/[z-a]", Expected: `"/x/[z-a]" at offset 3: range z-a is reversed`},
+	} {
+		_, err := CompilePattern(testcase.Pattern)
+		if !errors.Is(err, ErrInvalidAddress) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, ErrInvalidAddress, err)
+		}
+		if expected, got := testcase.Expected+": invalid OSC address", err.Error(); expected != got {
+			t.Fatalf("(testcase %d) expected %q, got %q", i, expected, got)
+		}
+		if expected, got := err.Error(), checkPattern(testcase.Pattern); got == nil || expected != got.Error() {
+			t.Fatalf("(testcase %d) expected %q, got %v", i, expected, got)
+		}
+	}
+}
+
+func TestPatternMatchingInvoke(t *testing.T) {
+	var got []string
+	d := PatternMatching{
+		"/synth/1/freq": Method(func(msg Message) error {
+			got = append(got, msg.Address)
+			return nil
+		}),
+	}
+	for _, addr := range []string{"/synth/[0-9]/freq", "/synth/*/{amp,freq}", "/synth/2/freq", "/synth/1/freq"} {
+		if err := d.Invoke(Message{Address: addr}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Invoke(Message{Address: "/synth/[0-9]/freq"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Invoke(Message{Address: "/synth/["}, false); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected %v, got %v", ErrInvalidAddress, err)
+	}
+	if expected, got := "/synth/[0-9]/freq /synth/*/{amp,freq} /synth/1/freq", strings.Join(got, " "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
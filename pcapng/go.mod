@@ -0,0 +1,16 @@
+module github.com/scgolang/osc/pcapng
+
+go 1.26.0
+
+require (
+	github.com/google/gopacket v1.1.19
+	github.com/scgolang/osc v0.0.0
+)
+
+require (
+	github.com/gorilla/websocket v1.5.3 // indirect
+	golang.org/x/net v0.59.0 // indirect
+	golang.org/x/sys v0.48.0 // indirect
+)
+
+replace github.com/scgolang/osc => ../
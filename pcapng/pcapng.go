@@ -0,0 +1,176 @@
+// Package pcapng reads the OSC packets of captures of network traffic, such as the
+// pcapng and pcap files that Wireshark and tcpdump write, for offline analysis and
+// to replay them with an osc.Player.
+//
+// It is a module of its own, so that only the programs that read captures
+// depend on gopacket.
+package pcapng
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/scgolang/osc"
+)
+
+// ngMagic is the block type that starts a pcapng file, which is its section header.
+const ngMagic = 0x0A0D0D0A
+
+// ErrUnknownFormat is returned for a capture that is neither pcapng nor pcap.
+var ErrUnknownFormat = errors.New("not a pcapng or pcap capture")
+
+// TimedPacket is an OSC packet of a capture.
+type TimedPacket struct {
+	// Time is when the packet was captured.
+	Time time.Time
+
+	// Src and Dst are the *net.UDPAddr the packet came from and was sent to.
+	Src, Dst net.Addr
+
+	// Data is the UDP payload.
+	Data []byte
+
+	// Packet is the parsed payload, or nil if it could not be parsed.
+	Packet osc.Packet
+
+	// Err is the error parsing the payload, or reading the capture,
+	// after which the channel of ReadPackets is closed.
+	Err error
+}
+
+// packetReader is the reader of pcapng captures and the one of pcap captures.
+type packetReader interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	LinkType() layers.LinkType
+}
+
+// ReadPackets reads the header of a pcapng or pcap capture from r, and then sends
+// the UDP datagrams of the capture that filter returns true for, or all of them
+// if filter is nil, to the channel it returns. Every datagram is parsed with
+// osc.ParsePacket, and the ones that are not valid OSC are sent with the error
+// instead of stopping the stream.
+//
+// The channel is closed at the end of the capture, or after a TimedPacket with
+// the error reading it. It must be drained, or the goroutine that reads
+// the capture is never done.
+// The datagrams of other protocols, and IP fragments, are skipped.
+func ReadPackets(r io.Reader, filter func(src, dst net.Addr) bool) (<-chan TimedPacket, error) {
+	pr, err := newPacketReader(r)
+	if err != nil {
+		return nil, err
+	}
+	packets := make(chan TimedPacket)
+	go func() {
+		defer close(packets)
+
+		for {
+			data, ci, err := pr.ReadPacketData()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				packets <- TimedPacket{Time: ci.Timestamp, Err: fmt.Errorf("read capture: %w", err)}
+				return
+			}
+			tp, ok := udpPacket(data, pr.LinkType())
+			if !ok || filter != nil && !filter(tp.Src, tp.Dst) {
+				continue
+			}
+			tp.Time = ci.Timestamp
+			if tp.Packet, tp.Err = osc.ParsePacket(tp.Data); tp.Err != nil {
+				tp.Packet = nil
+			}
+			packets <- tp
+		}
+	}()
+	return packets, nil
+}
+
+// newPacketReader returns the reader for the format of the capture.
+func newPacketReader(r io.Reader) (packetReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("read capture header: %w: %w", ErrUnknownFormat, err)
+	}
+	if binary.BigEndian.Uint32(magic) == ngMagic {
+		return pcapgo.NewNgReader(br, pcapgo.DefaultNgReaderOptions)
+	}
+	pr, err := pcapgo.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+	return pr, nil
+}
+
+// udpPacket returns the addresses and the payload of a UDP datagram,
+// or false if data is not one.
+func udpPacket(data []byte, linkType layers.LinkType) (TimedPacket, bool) {
+	p := gopacket.NewPacket(data, linkType, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+	udp, ok := p.Layer(layers.LayerTypeUDP).(*layers.UDP)
+	if !ok {
+		return TimedPacket{}, false
+	}
+	var src, dst net.IP
+	switch ip := p.NetworkLayer().(type) {
+	case *layers.IPv4:
+		if ip.Flags&layers.IPv4MoreFragments != 0 || ip.FragOffset != 0 {
+			return TimedPacket{}, false
+		}
+		src, dst = ip.SrcIP, ip.DstIP
+	case *layers.IPv6:
+		src, dst = ip.SrcIP, ip.DstIP
+	default:
+		return TimedPacket{}, false
+	}
+	return TimedPacket{
+		Src:  &net.UDPAddr{IP: append(net.IP(nil), src...), Port: int(udp.SrcPort)},
+		Dst:  &net.UDPAddr{IP: append(net.IP(nil), dst...), Port: int(udp.DstPort)},
+		Data: append(make([]byte, 0, len(udp.Payload)), udp.Payload...),
+	}, true
+}
+
+// WriteSession writes the packets to w as a session log, whose packets are inbound
+// from their sources, so that an osc.Player can replay the capture with the same timing.
+// The packets that could not be parsed are written too, and the first error reading
+// the capture is returned, with the number of packets that were written.
+func WriteSession(w io.Writer, packets <-chan TimedPacket) (int, error) {
+	sw, err := osc.NewSessionWriter(w)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		n     int
+		first time.Time
+	)
+	for tp := range packets {
+		if tp.Data == nil && tp.Err != nil {
+			return n, tp.Err
+		}
+		if n == 0 {
+			first = tp.Time
+		}
+		if err := sw.Write(osc.SessionEntry{
+			Direction: osc.Inbound,
+			Addr:      tp.Src,
+			Time:      tp.Time,
+			Elapsed:   tp.Time.Sub(first),
+			Data:      tp.Data,
+		}); err != nil {
+			for range packets {
+				// Drain the channel, so that ReadPackets is done with the capture.
+			}
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,209 @@
+package pcapng
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/pcapgo"
+	"github.com/scgolang/osc"
+	"github.com/scgolang/osc/osctest"
+)
+
+// readCapture reads the capture of a show: a console at 192.168.1.10 that sends
+// messages to port 8000 of 192.168.1.20, with a datagram that is not OSC,
+// a message to another host, a TCP segment and a message over IPv6.
+func readCapture(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/show.pcapng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// collect returns the packets of the channel.
+func collect(packets <-chan TimedPacket) []TimedPacket {
+	var tps []TimedPacket
+	for tp := range packets {
+		tps = append(tps, tp)
+	}
+	return tps
+}
+
+var (
+	t0      = time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	console = &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 53000}
+	show    = &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 8000}
+)
+
+func TestReadPackets(t *testing.T) {
+	packets, err := ReadPackets(bytes.NewReader(readCapture(t)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tps := collect(packets)
+	for i, expected := range []struct {
+		Time   time.Duration
+		Dst    string
+		Packet osc.Packet
+		Err    bool
+	}{
+		{Time: 0, Dst: show.String(), Packet: osc.MustMessage("/cue/go", int32(1))},
+		{Time: 40 * time.Millisecond, Dst: show.String(), Packet: osc.MustMessage("/fader/1", float32(0.5))},
+		{Time: 80 * time.Millisecond, Dst: show.String(), Packet: osc.Bundle{
+			Timetag: osc.Immediately,
+			Packets: []osc.Packet{osc.MustMessage("/fader/1", float32(0.75)), osc.MustMessage("/cue/go", int32(2))},
+		}},
+		{Time: 100 * time.Millisecond, Dst: show.String(), Err: true},
+		{Time: 120 * time.Millisecond, Dst: "224.0.0.251:5353", Packet: osc.MustMessage("/elsewhere")},
+		{Time: 150 * time.Millisecond, Dst: "[fe80::2]:8000", Packet: osc.MustMessage("/blackout")},
+	} {
+		if i >= len(tps) {
+			t.Fatalf("expected %d packets, got %d", i+1, len(tps))
+		}
+		tp := tps[i]
+		if !tp.Time.Equal(t0.Add(expected.Time)) {
+			t.Fatalf("(packet %d) expected time %s, got %s", i, t0.Add(expected.Time), tp.Time)
+		}
+		if tp.Dst.String() != expected.Dst {
+			t.Fatalf("(packet %d) expected destination %s, got %s", i, expected.Dst, tp.Dst)
+		}
+		if expected.Err {
+			if tp.Err == nil || tp.Packet != nil || string(tp.Data) != "not osc!" {
+				t.Fatalf("(packet %d) expected a parse error with the payload, got %+v", i, tp)
+			}
+			continue
+		}
+		if tp.Err != nil {
+			t.Fatalf("(packet %d) %s", i, tp.Err)
+		}
+		osctest.AssertPacket(t, tp.Packet, expected.Packet)
+		if !bytes.Equal(tp.Data, expected.Packet.Bytes()) {
+			t.Fatalf("(packet %d)\n%s", i, osctest.DiffHex(expected.Packet.Bytes(), tp.Data))
+		}
+	}
+	if len(tps) != 6 {
+		t.Fatalf("expected 6 packets, got %d", len(tps))
+	}
+	if tps[0].Src.String() != console.String() {
+		t.Fatalf("expected source %s, got %s", console, tps[0].Src)
+	}
+}
+
+func TestReadPackets_Filter(t *testing.T) {
+	packets, err := ReadPackets(bytes.NewReader(readCapture(t)), func(src, dst net.Addr) bool {
+		return dst.String() == show.String()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(collect(packets)); got != 4 {
+		t.Fatalf("expected 4 packets, got %d", got)
+	}
+}
+
+func TestReadPackets_Pcap(t *testing.T) {
+	// The same capture in the pcap format that tcpdump writes.
+	ng, err := pcapgo.NewNgReader(bytes.NewReader(readCapture(t)), pcapgo.DefaultNgReaderOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var capture bytes.Buffer
+	w := pcapgo.NewWriterNanos(&capture)
+	if err := w.WriteFileHeader(65536, ng.LinkType()); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		data, ci, err := ng.ReadPacketData()
+		if err != nil {
+			break
+		}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packets, err := ReadPackets(&capture, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tps := collect(packets)
+	if len(tps) != 6 {
+		t.Fatalf("expected 6 packets, got %d", len(tps))
+	}
+	if !tps[1].Time.Equal(t0.Add(40 * time.Millisecond)) {
+		t.Fatalf("expected time %s, got %s", t0.Add(40*time.Millisecond), tps[1].Time)
+	}
+}
+
+func TestReadPackets_Errors(t *testing.T) {
+	for i, data := range [][]byte{nil, []byte("osc"), []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01")} {
+		if _, err := ReadPackets(bytes.NewReader(data), nil); !errors.Is(err, ErrUnknownFormat) {
+			t.Fatalf("(testcase %d) expected ErrUnknownFormat, got %v", i, err)
+		}
+	}
+
+	// A capture that can not be read to its end ends with the error reading it.
+	// gopacket reads a capture that was cut short as if it ended there.
+	capture := readCapture(t)
+	oops := errors.New("oops")
+	packets, err := ReadPackets(io.MultiReader(bytes.NewReader(capture[:len(capture)-40]), errReader{oops}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tps := collect(packets)
+	if len(tps) != 6 {
+		t.Fatalf("expected 5 packets and an error, got %d packets", len(tps))
+	}
+	if last := tps[len(tps)-1]; !errors.Is(last.Err, oops) || last.Data != nil {
+		t.Fatalf("expected an error reading the capture, got %+v", last)
+	}
+}
+
+// errReader is a reader that fails.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestWriteSession(t *testing.T) {
+	packets, err := ReadPackets(bytes.NewReader(readCapture(t)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	n, err := WriteSession(&log, packets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Fatalf("expected 6 packets to be written, got %d", n)
+	}
+
+	// The capture is replayed live, four times as fast.
+	replay := osctest.NewRecorder(nil)
+	player := osc.NewPlayer(osctest.NewServer(t, replay).Dial(), osc.PlayOptions{Speed: 4})
+	start := time.Now()
+	if n, err := player.Play(context.Background(), &log); err != nil || n != 6 {
+		t.Fatalf("expected 6 packets to be sent, got %d and %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond/4 {
+		t.Fatalf("expected the replay to take at least %s, it took %s", 150*time.Millisecond/4, elapsed)
+	}
+	if _, err := replay.WaitFor("/blackout", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	var addrs []string
+	for _, msg := range replay.Messages() {
+		addrs = append(addrs, msg.Address)
+	}
+	if expected, got := "[/cue/go /fader/1 /fader/1 /cue/go /elsewhere /blackout]", fmt.Sprint(addrs); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,71 @@
+package osc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// defaultPingAddress is the OSC address pings are sent to unless SetPingAddress is used.
+const defaultPingAddress = "/ping"
+
+// EchoMethod answers pings, or any other message, by sending the message back
+// to its sender with ".reply" appended to its address, e.g. /ping.reply for /ping.
+// Register it at the ping address of the conns that ping the server.
+var EchoMethod = ReplyMethod(func(msg Message) (*Message, error) {
+	echo := msg.Clone()
+	echo.Address += ".reply"
+	return &echo, nil
+})
+
+// SetPingAddress sets the OSC address that Ping sends its pings to.
+// The peer answers them with EchoMethod, registered at the same address.
+// The default is "/ping".
+func (s *connState) SetPingAddress(addr string) {
+	s.pingAddress = addr
+}
+
+// ping sends a ping with send and returns the time until its echo is read.
+func (s *connState) ping(ctx context.Context, send func(Packet) error) (time.Duration, error) {
+	addr := s.pingAddress
+	if addr == "" {
+		addr = defaultPingAddress
+	}
+	if err := validateAddressPattern(addr); err != nil || isPattern(addr) {
+		return 0, fmt.Errorf("ping address %q: %w", addr, ErrInvalidAddress)
+	}
+	// The token tells the echoes of concurrent pings apart.
+	token := rand.Int63()
+	match := func(msg Message) bool {
+		t, err := msg.Int64At(0)
+		return err == nil && t == token
+	}
+	start := time.Now()
+	if _, err := s.await(ctx, send, Message{Address: addr, Arguments: Arguments{Int64(token)}}, addr+".reply", match); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// pingAddr is the address of a peer that Ping is given, for the conns that
+// only compare the addresses they send to with the addresses of their peers.
+type pingAddr string
+
+// Network returns the name of the network.
+func (a pingAddr) Network() string { return "osc" }
+
+// String returns the address.
+func (a pingAddr) String() string { return string(a) }
+
+// sendToPeer returns a func that sends packets to addr with conn,
+// or to the peer of conn if addr is nil.
+func sendToPeer(conn connectedSender, addr net.Addr) func(Packet) error {
+	if addr == nil {
+		return conn.Send
+	}
+	return func(p Packet) error {
+		return conn.SendTo(addr, p)
+	}
+}
@@ -0,0 +1,104 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var servers []*UDPConn
+	for _, dispatcher := range []PatternMatching{{"/ping": EchoMethod}, {}} {
+		server, err := ListenUDP("udp", laddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = server.Close() }() // Best effort.
+
+		go func(dispatcher PatternMatching) {
+			_ = server.Serve(4, dispatcher)
+		}(dispatcher)
+		servers = append(servers, server)
+	}
+	echo, silent := servers[0].LocalAddr().String(), servers[1].LocalAddr().String()
+
+	client, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	go func() {
+		_ = client.Serve(1, PatternMatching{})
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, 8)
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rtt, err := client.Ping(ctx, echo)
+			if err == nil && (rtt <= 0 || rtt >= time.Second) {
+				err = errors.New("expected a sub-second round-trip time, got " + rtt.String())
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A silent peer never answers.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Ping(ctx, silent); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := client.Ping(context.Background(), "not an address"); err == nil {
+		t.Fatal("expected an error for an invalid address")
+	}
+}
+
+func TestPing_PingAddress(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	go func() {
+		_ = c1.Serve(1, PatternMatching{})
+	}()
+	go func() {
+		_ = c2.Serve(1, PatternMatching{"/monitor/ping": EchoMethod})
+	}()
+	c1.SetPingAddress("/monitor/ping")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, err := c1.Ping(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c1.Ping(ctx, c2.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+	c1.SetPingAddress("/monitor/*")
+	if _, err := c1.Ping(ctx, ""); !errors.Is(err, ErrInvalidAddress) {
+		t.Fatalf("expected ErrInvalidAddress, got %v", err)
+	}
+}
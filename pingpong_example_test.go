@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"net"
 	"time"
 )
 
@@ -12,11 +11,7 @@ func Example_pingPong() {
 	errChan := make(chan error)
 
 	// Setup the server.
-	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
-	if err != nil {
-		log.Fatal(err)
-	}
-	server, err := ListenUDP("udp", laddr)
+	server, err := Listen("127.0.0.1:0")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -25,11 +20,7 @@ func Example_pingPong() {
 	go serverDispatch(server, errChan)
 
 	// Setup the client.
-	raddr, err := net.ResolveUDPAddr("udp", server.LocalAddr().String())
-	if err != nil {
-		log.Fatal(err)
-	}
-	client, err := DialUDP("udp", nil, raddr)
+	client, err := Dial(server.LocalAddr().String())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,270 @@
+package osc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// pipeAddr is the address of one end of a pipe.
+type pipeAddr string
+
+// Network returns the name of the network.
+func (pipeAddr) Network() string { return "pipe" }
+
+// String returns the string form of the address.
+func (a pipeAddr) String() string { return string(a) }
+
+// pipeItem is either a packet or a read error waiting to be read.
+type pipeItem struct {
+	data []byte
+	err  error
+}
+
+// pipeQueue is an unbounded FIFO of items, so that Send never blocks.
+type pipeQueue struct {
+	mu     sync.Mutex
+	items  []pipeItem
+	closed bool
+	signal chan struct{}
+}
+
+// push appends an item to the queue.
+func (q *pipeQueue) push(item pipeItem) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return io.ErrClosedPipe
+	}
+	q.items = append(q.items, item)
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop removes the first item from the queue.
+// It returns false if the queue is empty.
+// Once the queue is empty and closed it returns an io.EOF item.
+func (q *pipeQueue) pop() (pipeItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		if q.closed {
+			return pipeItem{err: io.EOF}, true
+		}
+		return pipeItem{}, false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item, true
+}
+
+// close stops the queue from accepting items.
+// Items already in the queue can still be read.
+func (q *pipeQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// PipeConn is one end of an in-memory OSC connection created by Pipe.
+// Packets are delivered to the other end in the order they were sent,
+// so a peer that serves with a single worker dispatches them in that order.
+type PipeConn struct {
+	connState
+
+	addr      pipeAddr
+	in        *pipeQueue
+	peer      *PipeConn
+	closeOnce sync.Once
+}
+
+// Pipe creates two connected in-memory OSC connections.
+// Packets sent on one end are read by the other and the Sender of every
+// message is the address of the end that sent it.
+// Closing one end makes Serve on the other end return nil once it has
+// read all the packets that were sent before the close.
+// Pipes are meant for tests that would otherwise need real sockets.
+func Pipe() (*PipeConn, *PipeConn) {
+	c1 := newPipeConn("pipe:1")
+	c2 := newPipeConn("pipe:2")
+	c1.peer, c2.peer = c2, c1
+	return c1, c2
+}
+
+// newPipeConn creates one end of a pipe.
+func newPipeConn(addr pipeAddr) *PipeConn {
+	return &PipeConn{
+		connState: newConnState(context.Background()),
+		addr:      addr,
+		in:        &pipeQueue{signal: make(chan struct{}, 1)},
+	}
+}
+
+// Close closes this end of the pipe.
+func (conn *PipeConn) Close() error {
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+		conn.in.close()
+		conn.peer.in.close()
+	})
+	return nil
+}
+
+// LocalAddr returns the address of this end of the pipe.
+func (conn *PipeConn) LocalAddr() net.Addr {
+	return conn.addr
+}
+
+// RemoteAddr returns the address of the other end of the pipe.
+func (conn *PipeConn) RemoteAddr() net.Addr {
+	return conn.peer.addr
+}
+
+// Send sends a packet to the other end of the pipe.
+// It returns io.ErrClosedPipe if either end has been closed.
+func (conn *PipeConn) Send(p Packet) error {
+	return conn.send(conn.peer.addr, p, conn.SendRaw)
+}
+
+// SendTo sends a packet to the given address, which must be the address of the other end.
+func (conn *PipeConn) SendTo(addr net.Addr, p Packet) error {
+	if addr.String() != conn.peer.addr.String() {
+		return fmt.Errorf("pipe conn is connected to %s, not %s", conn.peer.addr, addr)
+	}
+	return conn.Send(p)
+}
+
+// SendBundle sends msgs in a single bundle with the given timetag.
+// Bundles larger than the max packet size of the conn are rejected with ErrPacketTooLarge.
+func (conn *PipeConn) SendBundle(tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.Send(p)
+}
+
+// SendBundleTo sends msgs to addr in a single bundle with the given timetag.
+func (conn *PipeConn) SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.SendTo(addr, p)
+}
+
+// Reply sends msg to the sender of a message that was read from the conn.
+// A connected conn ignores to and sends msg to its peer.
+func (conn *PipeConn) Reply(to net.Addr, msg Message) error {
+	return reply(conn, to, msg)
+}
+
+// Call sends request and returns the first message to replyAddress that the conn
+// reads afterwards, which is not dispatched, or ctx.Err() if ctx is done first.
+// The conn must be serving. Calls waiting for the same address get the replies
+// in the order they were made.
+func (conn *PipeConn) Call(ctx context.Context, request Message, replyAddress string) (Message, error) {
+	return conn.call(ctx, conn.Send, request, replyAddress)
+}
+
+// Ping sends a ping to addr, or to the peer of a connected conn if addr is empty,
+// and returns the time until the echo of the peer's EchoMethod is read,
+// or ctx.Err() if ctx is done first. The conn must be serving.
+// Every ping carries a random token, so concurrent pings get their own echo.
+func (conn *PipeConn) Ping(ctx context.Context, addr string) (time.Duration, error) {
+	if addr == "" {
+		return conn.ping(ctx, conn.Send)
+	}
+	return conn.ping(ctx, sendToPeer(conn, pingAddr(addr)))
+}
+
+// Receive reads packets onto the returned channel instead of dispatching them,
+// until ctx is done or the conn is closed or shut down, which closes the channel.
+// Packets that can not be parsed are passed on with Err set.
+// The channel holds as many packets as the queue size. When it is full Receive
+// waits for room, or drops the oldest packet or the new one and counts it in Dropped
+// if the queue policy is DropOldest or DropNewest.
+// A conn can not be served and received from at once: Receive returns ErrServing
+// while Serve is running, and Serve returns ErrReceiving until Receive has stopped.
+func (conn *PipeConn) Receive(ctx context.Context) (<-chan Incoming, error) {
+	return receive(ctx, conn, &conn.connState)
+}
+
+// ReceivePacket reads and parses a single packet and returns it with its sender.
+// The read gives up with ctx.Err() when ctx is done.
+// Packets that can not be parsed are returned as a *ParseError, so the caller
+// can read the next one. ReceivePacket returns ErrServing while Serve is running
+// and ErrReceiving while Receive is.
+func (conn *PipeConn) ReceivePacket(ctx context.Context) (Packet, net.Addr, error) {
+	return receivePacket(ctx, conn, &conn.connState)
+}
+
+// SendRaw sends data to the other end of the pipe as if it were a packet.
+// This allows tests to deliver malformed packets.
+func (conn *PipeConn) SendRaw(data []byte) error {
+	select {
+	case <-conn.closeChan:
+		return io.ErrClosedPipe
+	default:
+	}
+	return conn.peer.in.push(pipeItem{data: append([]byte{}, data...)})
+}
+
+// InjectReadError makes this end of the pipe fail to read with err
+// once it has read the packets that are already waiting to be read.
+// This simulates a socket error, so Serve will return err.
+func (conn *PipeConn) InjectReadError(err error) error {
+	return conn.in.push(pipeItem{err: err})
+}
+
+// Serve starts dispatching OSC.
+// Packets that can not be parsed and errors returned from methods are passed to
+// the error handler and do not stop the server, unless the conn is in strict mode.
+// If context.Canceled or context.DeadlineExceeded are encountered they will be returned directly.
+func (conn *PipeConn) Serve(numWorkers int, dispatcher Dispatcher) error {
+	return conn.ServeContext(context.Background(), numWorkers, dispatcher)
+}
+
+// ServeContext is like Serve, but it also stops serving when ctx is done
+// and returns ctx.Err() once the methods that are already running have returned.
+// The conn is left open.
+func (conn *PipeConn) ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error {
+	return serve(ctx, conn, numWorkers, conn.exactMatch, dispatcher)
+}
+
+// read reads the next packet sent by the other end.
+func (conn *PipeConn) read(data []byte) (int, net.Addr, error) {
+	for {
+		if item, ok := conn.in.pop(); ok {
+			if item.err == io.EOF {
+				_ = conn.Close()
+			}
+			if item.err != nil {
+				return 0, nil, item.err
+			}
+			if len(item.data) > len(data) {
+				return 0, nil, fmt.Errorf("%d byte packet: %w", len(item.data), ErrPacketTooLarge)
+			}
+			return copy(data, item.data), conn.peer.addr, nil
+		}
+		select {
+		case <-conn.in.signal:
+		case <-conn.closeChan:
+			return 0, nil, net.ErrClosed
+		}
+	}
+}
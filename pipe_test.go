@@ -0,0 +1,145 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// waitServe waits for Serve to return on errChan.
+func waitServe(t *testing.T, errChan chan error) error {
+	select {
+	case err := <-errChan:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Serve to return")
+	}
+	return nil
+}
+
+func TestPipeOrder(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	const n = 100
+
+	// Send everything before serving to make sure Send never blocks.
+	for i := 0; i < n; i++ {
+		if err := c1.Send(Message{Address: "/count", Arguments: Arguments{Int(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		counts  = make(chan int32, n)
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- c2.Serve(1, PatternMatching{
+			"/count": Method(func(msg Message) error {
+				if expected, got := c1.LocalAddr().String(), msg.Sender.String(); expected != got {
+					return fmt.Errorf("expected sender %s, got %s", expected, got)
+				}
+				i, err := msg.Arguments[0].ReadInt32()
+				if err != nil {
+					return err
+				}
+				counts <- i
+				return nil
+			}),
+		})
+	}()
+	for i := int32(0); i < n; i++ {
+		select {
+		case count := <-counts:
+			if i != count {
+				t.Fatalf("expected message %d, got %d", i, count)
+			}
+		case err := <-errChan:
+			t.Fatalf("server stopped: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+	// Closing c1 ends c2's Serve.
+	if err := c1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitServe(t, errChan); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeReply(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			"/ping": Method(func(msg Message) error {
+				return c2.SendTo(msg.Sender, Message{Address: "/pong"})
+			}),
+		})
+	}()
+	var (
+		pongs   = make(chan Message)
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- c1.Serve(1, PatternMatching{
+			"/pong": Method(func(msg Message) error {
+				pongs <- msg
+				return nil
+			}),
+		})
+	}()
+	if err := c1.Send(Message{Address: "/ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitMessage(t, pongs, errChan); msg.Address != "/pong" {
+		t.Fatalf("expected /pong, got %s", msg.Address)
+	}
+	if err := c1.SendTo(c1.LocalAddr(), Message{Address: "/ping"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestPipeErrors(t *testing.T) {
+	// A malformed packet stops a strict server.
+	c1, c2 := Pipe()
+	c2.SetStrict(true)
+	if err := c1.SendRaw([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.Serve(1, PatternMatching{}); !errors.Is(err, ErrParse) {
+		t.Fatalf("expected ErrParse, got %v", err)
+	}
+	_ = c1.Close() // Best effort.
+
+	// A read error.
+	c1, c2 = Pipe()
+	readErr := errors.New("derp")
+	if err := c2.InjectReadError(readErr); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.Serve(1, PatternMatching{}); !errors.Is(err, readErr) {
+		t.Fatalf("expected %v, got %v", readErr, err)
+	}
+
+	// Sending on a closed pipe.
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c1.Send(Message{Address: "/foo"}); err != io.ErrClosedPipe {
+		t.Fatalf("expected io.ErrClosedPipe, got %v", err)
+	}
+	if err := c2.Send(Message{Address: "/foo"}); err != io.ErrClosedPipe {
+		t.Fatalf("expected io.ErrClosedPipe, got %v", err)
+	}
+	// Closing again is harmless.
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,91 @@
+package osc
+
+import (
+	"net"
+)
+
+// QueuePolicy decides what Serve does with a packet that arrives
+// while the queue of packets waiting for a worker is full.
+type QueuePolicy int
+
+// Queue policies.
+const (
+	// BlockWhenFull stops reading packets until a worker takes one from the queue.
+	// Datagrams that arrive in the meantime are buffered by the OS, which may drop them.
+	BlockWhenFull QueuePolicy = iota
+
+	// DropOldest discards the packet that has waited the longest
+	// to make room for the new one.
+	DropOldest
+
+	// DropNewest discards the new packet, and keeps the ones that are waiting.
+	DropNewest
+)
+
+// packetQueue holds the packets that have been read until a worker is ready for them.
+type packetQueue struct {
+	packets chan Incoming
+	policy  QueuePolicy
+
+	// gate is told when a packet is dropped, and countDropped counts it.
+	gate         *dispatchGate
+	countDropped func(sender net.Addr)
+}
+
+// newPacketQueue creates a queue for size packets.
+func newPacketQueue(size int, policy QueuePolicy, gate *dispatchGate, countDropped func(sender net.Addr)) *packetQueue {
+	return &packetQueue{
+		packets:      make(chan Incoming, size),
+		policy:       policy,
+		gate:         gate,
+		countDropped: countDropped,
+	}
+}
+
+// push adds a packet that has entered the gate to the queue.
+// It never blocks if the policy is DropOldest or DropNewest.
+// Only the read loop may call push, so there is room after a packet is dropped.
+func (q *packetQueue) push(incoming Incoming) {
+	switch q.policy {
+	case DropNewest:
+		select {
+		case q.packets <- incoming:
+		default:
+			q.gate.inFlight.Done()
+			q.countDropped(incoming.Sender)
+		}
+		return
+	case DropOldest:
+		select {
+		case q.packets <- incoming:
+			return
+		default:
+		}
+		select {
+		case dropped := <-q.packets:
+			q.gate.inFlight.Done()
+			q.countDropped(dropped.Sender)
+		default:
+		}
+	}
+	q.packets <- incoming
+}
+
+// feed hands the queued packets to the workers as they become ready,
+// until the queue is closed.
+// It waits for a worker before it takes a packet, so that the packets
+// waiting for a worker are all in the queue and can be dropped.
+func (q *packetQueue) feed(ready <-chan worker) {
+	for w := range ready {
+		incoming, ok := <-q.packets
+		if !ok {
+			return
+		}
+		w.DataChan <- incoming
+	}
+}
+
+// close closes the queue. It must be empty and no packet may be pushed afterwards.
+func (q *packetQueue) close() {
+	close(q.packets)
+}
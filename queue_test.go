@@ -0,0 +1,110 @@
+package osc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeQueue(t *testing.T) {
+	c1, c2 := Pipe()
+	c2.SetQueueSize(1)
+	c2.SetQueuePolicy(DropOldest)
+
+	var (
+		started   = make(chan struct{}, 1)
+		release   = make(chan struct{})
+		handled   = make(chan string, 8)
+		serveErrs = make(chan error, 1)
+	)
+	go func() {
+		serveErrs <- c2.Serve(1, PatternMatching{
+			"/*": Method(func(msg Message) error {
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+				<-release
+				handled <- msg.Address
+				return nil
+			}),
+		})
+	}()
+	if err := c1.Send(Message{Address: "/1"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /1")
+	}
+	// The only worker is busy, so /2 and /3 are dropped to make room for /4.
+	for _, addr := range []string{"/2", "/3", "/4"} {
+		if err := c1.Send(Message{Address: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for deadline := time.Now().Add(2 * time.Second); c2.Dropped() < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 dropped packets, got %d", c2.Dropped())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// The queue is drained when the conn is closed.
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	select {
+	case err := <-serveErrs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Serve to return")
+	}
+	close(handled)
+	got := []string{}
+	for addr := range handled {
+		got = append(got, addr)
+	}
+	if expected, got := "/1 /4", strings.Join(got, " "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if expected, got := uint64(2), c2.Dropped(); expected != got {
+		t.Fatalf("expected %d dropped packets, got %d", expected, got)
+	}
+}
+
+func BenchmarkServeSlowMethod(b *testing.B) {
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("%d workers", workers), func(b *testing.B) {
+			c1, c2 := Pipe()
+			defer func() { _ = c2.Close() }() // Best effort.
+
+			c2.SetQueueSize(64)
+			handled := make(chan struct{}, b.N)
+			go func() {
+				_ = c2.Serve(workers, PatternMatching{
+					"/slow": Method(func(msg Message) error {
+						time.Sleep(5 * time.Millisecond)
+						handled <- struct{}{}
+						return nil
+					}),
+				})
+			}()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := c1.Send(Message{Address: "/slow"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			for i := 0; i < b.N; i++ {
+				<-handled
+			}
+		})
+	}
+}
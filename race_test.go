@@ -0,0 +1,6 @@
+//go:build race
+
+package osc
+
+// raceEnabled is true if the tests run with the race detector, which makes some calls allocate.
+const raceEnabled = true
@@ -0,0 +1,206 @@
+package osc
+
+import (
+	"container/list"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Common errors.
+var (
+	ErrRateLimited = errors.New("packet dropped by the rate limit")
+)
+
+// rateLimit is a number of packets and bytes per second.
+// Zero means no limit.
+type rateLimit struct {
+	packets int
+	bytes   int
+}
+
+// isSet returns true if r limits anything.
+func (r rateLimit) isSet() bool {
+	return r.packets > 0 || r.bytes > 0
+}
+
+// tokenBucket holds up to one second of tokens, and gains rate tokens per second.
+// A rate of zero means there is no limit.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket.
+func newTokenBucket(rate int, now time.Time) tokenBucket {
+	return tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+// refill adds the tokens gained since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	if b.rate == 0 {
+		return
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.rate {
+			b.tokens = b.rate
+		}
+	}
+	b.last = now
+}
+
+// has returns true if the bucket holds n tokens.
+func (b *tokenBucket) has(n float64) bool {
+	return b.rate == 0 || b.tokens >= n
+}
+
+// take removes n tokens. The bucket must hold them.
+func (b *tokenBucket) take(n float64) {
+	if b.rate != 0 {
+		b.tokens -= n
+	}
+}
+
+// buckets are the packet and byte buckets of a rate limit.
+type buckets struct {
+	packets tokenBucket
+	bytes   tokenBucket
+}
+
+// newBuckets creates the full buckets of r.
+func newBuckets(r rateLimit, now time.Time) *buckets {
+	return &buckets{
+		packets: newTokenBucket(r.packets, now),
+		bytes:   newTokenBucket(r.bytes, now),
+	}
+}
+
+// has refills the buckets and returns true if they hold a packet of size bytes.
+func (b *buckets) has(now time.Time, size int) bool {
+	b.packets.refill(now)
+	b.bytes.refill(now)
+	return b.packets.has(1) && b.bytes.has(float64(size))
+}
+
+// take takes a packet of size bytes from the buckets.
+func (b *buckets) take(size int) {
+	b.packets.take(1)
+	b.bytes.take(float64(size))
+}
+
+// source is the buckets of a sender, in the list of the most recent senders.
+type source struct {
+	key     string
+	buckets *buckets
+}
+
+// rateLimiter drops the packets that exceed a rate limit for the whole conn,
+// or a rate limit for each source IP. The buckets of the sources that sent
+// least recently are forgotten once there are more than maxSources of them.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	global     *buckets
+	perSource  rateLimit
+	maxSources int
+	sources    map[string]*list.Element
+	recent     *list.List // Most recent first.
+
+	limited uint64
+}
+
+// newRateLimiter returns the rate limiter of o, or nil if o has no rate limit.
+func newRateLimiter(o options) *rateLimiter {
+	if !o.rateLimit.isSet() && !o.sourceRateLimit.isSet() {
+		return nil
+	}
+	l := &rateLimiter{
+		perSource:  o.sourceRateLimit,
+		maxSources: o.maxSources,
+		sources:    map[string]*list.Element{},
+		recent:     list.New(),
+	}
+	if o.rateLimit.isSet() {
+		l.global = newBuckets(o.rateLimit, time.Time{})
+	}
+	return l
+}
+
+// allow returns true if a packet of size bytes from sender is within the rate limits,
+// and otherwise counts it.
+func (l *rateLimiter) allow(now time.Time, sender net.Addr, size int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var src *buckets
+	if l.perSource.isSet() {
+		src = l.source(sender, now)
+	}
+	if (src != nil && !src.has(now, size)) || (l.global != nil && !l.global.has(now, size)) {
+		l.limited++
+		return false
+	}
+	if src != nil {
+		src.take(size)
+	}
+	if l.global != nil {
+		l.global.take(size)
+	}
+	return true
+}
+
+// source returns the buckets of sender, and makes it the most recent sender.
+func (l *rateLimiter) source(sender net.Addr, now time.Time) *buckets {
+	key := sourceKey(sender)
+	if e, ok := l.sources[key]; ok {
+		l.recent.MoveToFront(e)
+		return e.Value.(*source).buckets
+	}
+	s := &source{key: key, buckets: newBuckets(l.perSource, now)}
+	l.sources[key] = l.recent.PushFront(s)
+
+	for l.recent.Len() > l.maxSources {
+		oldest := l.recent.Back()
+		l.recent.Remove(oldest)
+		delete(l.sources, oldest.Value.(*source).key)
+	}
+	return s.buckets
+}
+
+// droppedPackets returns the number of packets that were dropped.
+func (l *rateLimiter) droppedPackets() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limited
+}
+
+// sourceKey returns the IP of sender, or its address if it has no IP.
+// The zone of a link-local IPv6 address is part of it, since the same
+// address on two interfaces is two hosts.
+func sourceKey(sender net.Addr) string {
+	if sender == nil {
+		return ""
+	}
+	switch addr := sender.(type) {
+	case *net.UDPAddr:
+		return (&net.IPAddr{IP: addr.IP, Zone: addr.Zone}).String()
+	case *net.TCPAddr:
+		return (&net.IPAddr{IP: addr.IP, Zone: addr.Zone}).String()
+	}
+	if host, _, err := net.SplitHostPort(sender.String()); err == nil {
+		return host
+	}
+	return sender.String()
+}
+
+// RateLimited returns the number of packets that were dropped because they
+// exceeded the limits of WithRateLimit or WithSourceRateLimit.
+func (s *connState) RateLimited() uint64 {
+	if s.limiter == nil {
+		return 0
+	}
+	return s.limiter.droppedPackets()
+}
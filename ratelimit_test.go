@@ -0,0 +1,185 @@
+package osc
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var (
+		a1 = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}
+		a2 = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5001} // Same IP as a1.
+		b  = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 5000}
+		c  = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 3), Port: 5000}
+	)
+	type packet struct {
+		After   time.Duration // Since the previous packet.
+		From    net.Addr
+		Size    int
+		Allowed bool
+	}
+	for i, testcase := range []struct {
+		Options []Option
+		Packets []packet
+	}{
+		{
+			Options: []Option{WithRateLimit(2, 0)},
+			Packets: []packet{
+				{From: a1, Size: 100, Allowed: true},
+				{From: b, Size: 100, Allowed: true},
+				{From: a1, Size: 100, Allowed: false},
+				{After: 250 * time.Millisecond, From: a1, Size: 100, Allowed: false},
+				{After: 250 * time.Millisecond, From: a1, Size: 100, Allowed: true},
+				{After: 10 * time.Second, From: a1, Size: 100, Allowed: true},
+				{From: a1, Size: 100, Allowed: true},
+				{From: a1, Size: 100, Allowed: false},
+			},
+		},
+		{
+			Options: []Option{WithRateLimit(0, 1000)},
+			Packets: []packet{
+				{From: a1, Size: 600, Allowed: true},
+				{From: a1, Size: 600, Allowed: false},
+				{From: a1, Size: 400, Allowed: true},
+				{After: 100 * time.Millisecond, From: a1, Size: 100, Allowed: true},
+				{From: a1, Size: 1, Allowed: false},
+			},
+		},
+		{
+			Options: []Option{WithSourceRateLimit(1, 0, 2)},
+			Packets: []packet{
+				{From: a1, Size: 10, Allowed: true},
+				{From: a2, Size: 10, Allowed: false},
+				{From: b, Size: 10, Allowed: true},
+				{From: b, Size: 10, Allowed: false},
+				// c makes the conn forget a1, which sent least recently.
+				{From: c, Size: 10, Allowed: true},
+				{From: a1, Size: 10, Allowed: true},
+				{From: c, Size: 10, Allowed: false},
+			},
+		},
+		{
+			Options: []Option{WithRateLimit(3, 0), WithSourceRateLimit(2, 0, 16)},
+			Packets: []packet{
+				{From: a1, Size: 10, Allowed: true},
+				{From: a1, Size: 10, Allowed: true},
+				{From: a1, Size: 10, Allowed: false},
+				{From: b, Size: 10, Allowed: true},
+				{From: c, Size: 10, Allowed: false},
+				{After: time.Second, From: c, Size: 10, Allowed: true},
+			},
+		},
+	} {
+		o, err := newOptions(testcase.Options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			l       = newRateLimiter(o)
+			now     = time.Now()
+			dropped uint64
+		)
+		for j, p := range testcase.Packets {
+			now = now.Add(p.After)
+			if allowed := l.allow(now, p.From, p.Size); allowed != p.Allowed {
+				t.Fatalf("(testcase %d) packet %d: expected allowed %t, got %t", i, j, p.Allowed, allowed)
+			}
+			if !p.Allowed {
+				dropped++
+			}
+		}
+		if l.droppedPackets() != dropped {
+			t.Fatalf("(testcase %d) expected %d dropped packets, got %d", i, dropped, l.droppedPackets())
+		}
+	}
+}
+
+func TestRateLimit_Options(t *testing.T) {
+	for i, opt := range []Option{
+		WithRateLimit(-1, 0),
+		WithRateLimit(0, -1),
+		WithSourceRateLimit(10, 0, 0),
+		WithSourceRateLimit(-1, 0, 10),
+	} {
+		if _, err := newOptions([]Option{opt}); err == nil {
+			t.Fatalf("(testcase %d) expected an error", i)
+		}
+	}
+	o, err := newOptions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRateLimiter(o) != nil {
+		t.Fatal("expected no rate limiter without a rate limit")
+	}
+}
+
+func TestUDPConnRateLimit(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr, WithRateLimit(2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	clk := newFakeClock()
+	server.clock = clk
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	dispatched := make(chan Message, 16)
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			Default: Method(func(msg Message) error {
+				dispatched <- msg
+				return nil
+			}),
+		})
+	}()
+	expect := func(n int, limited uint64) {
+		t.Helper()
+
+		for i := 0; i < n; i++ {
+			select {
+			case <-dispatched:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timeout waiting for message %d", i)
+			}
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for server.RateLimited() != limited {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d rate limited packets, got %d", limited, server.RateLimited())
+			}
+			time.Sleep(time.Millisecond)
+		}
+		select {
+		case msg := <-dispatched:
+			t.Fatalf("expected no more messages, got %s", msg.Address)
+		default:
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if err := client.Send(MustMessage("/flood", int32(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect(2, 3)
+
+	// The bucket refills as the clock moves.
+	clk.Advance(500 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := client.Send(MustMessage("/flood", int32(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect(1, 4)
+}
@@ -0,0 +1,226 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// RawArgument is an argument that is already encoded: a typetag and its payload,
+// padding included, which are encoded as they are. It lets the arguments of a message
+// be relayed in another one without being decoded and encoded again, including
+// the arguments whose typetags this package does not know.
+//
+// The payload of an argument with a known typetag must be the size that the typetag
+// describes, and the payload of an unknown one a multiple of 4 bytes.
+// Its readers decode the payload, and fail with ErrInvalidTypeTag for an unknown typetag.
+type RawArgument struct {
+	Tag     byte
+	Payload []byte
+}
+
+// Bytes returns the payload.
+func (r RawArgument) Bytes() []byte { return r.Payload }
+
+// Equal returns true if other has the same typetag and encodes to the same payload.
+func (r RawArgument) Equal(other Argument) bool { return equalEncoded(r, other) }
+
+// decode decodes the payload as an argument of its typetag.
+func (r RawArgument) decode() (Argument, error) {
+	if !isTypetag(r.Tag) {
+		return nil, fmt.Errorf("raw argument with typetag %q: %w", r.Tag, ErrInvalidTypeTag)
+	}
+	a, _, err := ReadArgument(r.Tag, r.Payload)
+	return a, err
+}
+
+// ReadInt32 decodes a 32-bit integer from the payload.
+func (r RawArgument) ReadInt32() (int32, error) {
+	a, err := r.decode()
+	if err != nil {
+		return 0, err
+	}
+	return a.ReadInt32()
+}
+
+// ReadInt64 decodes a 64-bit integer from the payload.
+func (r RawArgument) ReadInt64() (int64, error) {
+	a, err := r.decode()
+	if err != nil {
+		return 0, err
+	}
+	return a.ReadInt64()
+}
+
+// ReadFloat32 decodes a 32-bit float from the payload.
+func (r RawArgument) ReadFloat32() (float32, error) {
+	a, err := r.decode()
+	if err != nil {
+		return 0, err
+	}
+	return a.ReadFloat32()
+}
+
+// ReadFloat64 decodes a 64-bit float from the payload.
+func (r RawArgument) ReadFloat64() (float64, error) {
+	a, err := r.decode()
+	if err != nil {
+		return 0, err
+	}
+	return a.ReadFloat64()
+}
+
+// ReadBool decodes a boolean from the typetag.
+func (r RawArgument) ReadBool() (bool, error) {
+	a, err := r.decode()
+	if err != nil {
+		return false, err
+	}
+	return a.ReadBool()
+}
+
+// ReadString decodes a string from the payload.
+func (r RawArgument) ReadString() (string, error) {
+	a, err := r.decode()
+	if err != nil {
+		return "", err
+	}
+	return a.ReadString()
+}
+
+// ReadBlob decodes a blob from the payload.
+func (r RawArgument) ReadBlob() ([]byte, error) {
+	a, err := r.decode()
+	if err != nil {
+		return nil, err
+	}
+	return a.ReadBlob()
+}
+
+// ReadChar decodes a character from the payload.
+func (r RawArgument) ReadChar() (rune, error) {
+	a, err := r.decode()
+	if err != nil {
+		return 0, err
+	}
+	return a.ReadChar()
+}
+
+// ReadRGBA decodes a color from the payload.
+func (r RawArgument) ReadRGBA() (RGBA, error) {
+	a, err := r.decode()
+	if err != nil {
+		return RGBA{}, err
+	}
+	return a.ReadRGBA()
+}
+
+// ReadMIDI decodes a MIDI message from the payload.
+func (r RawArgument) ReadMIDI() (MIDI, error) {
+	a, err := r.decode()
+	if err != nil {
+		return MIDI{}, err
+	}
+	return a.ReadMIDI()
+}
+
+// ReadSymbol decodes a symbol from the payload.
+func (r RawArgument) ReadSymbol() (string, error) {
+	a, err := r.decode()
+	if err != nil {
+		return "", err
+	}
+	return a.ReadSymbol()
+}
+
+// ReadTimetag decodes a timetag from the payload.
+func (r RawArgument) ReadTimetag() (Timetag, error) {
+	a, err := r.decode()
+	if err != nil {
+		return 0, err
+	}
+	return a.ReadTimetag()
+}
+
+// ReadArray returns ErrInvalidTypeTag, since the elements of an array
+// are raw arguments of their own.
+func (r RawArgument) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// String converts the argument to a string.
+func (r RawArgument) String() string { return fmt.Sprintf("RawArgument(%q, %x)", r.Tag, r.Payload) }
+
+// Typetag returns the argument's type tag.
+func (r RawArgument) Typetag() byte { return r.Tag }
+
+// WriteTo writes the payload to an io.Writer.
+func (r RawArgument) WriteTo(w io.Writer) (int64, error) {
+	written, err := w.Write(r.Payload)
+	return int64(written), err
+}
+
+// validate returns an error if the payload is not the size that the typetag describes.
+func (r RawArgument) validate() error {
+	switch {
+	case r.Tag == 0 || r.Tag == TypetagPrefix:
+		return fmt.Errorf("raw argument with typetag %q: %w", r.Tag, ErrInvalidTypeTag)
+	case len(r.Payload)%4 != 0:
+		return fmt.Errorf("raw argument with typetag %q has %d bytes, which is not a multiple of 4: %w", r.Tag, len(r.Payload), ErrInvalidTypeTag)
+	case !isTypetag(r.Tag):
+		return nil // Only the peer knows the size of the payload.
+	case (r.Tag == TypetagString || r.Tag == TypetagSymbol) && bytes.IndexByte(r.Payload, 0) < 0:
+		return fmt.Errorf("raw argument with typetag %q is not null-terminated: %w", r.Tag, ErrInvalidTypeTag)
+	}
+	size, err := argumentDataSize(r.Tag, r.Payload)
+	if err != nil {
+		return fmt.Errorf("raw argument with typetag %q: %w: %w", r.Tag, ErrInvalidTypeTag, err)
+	}
+	if size != len(r.Payload) {
+		return fmt.Errorf("raw argument with typetag %q has %d bytes, the typetag describes %d: %w", r.Tag, len(r.Payload), size, ErrInvalidTypeTag)
+	}
+	return nil
+}
+
+// equalEncoded returns true if a and b have the same typetag and payload,
+// which is how arguments of different types are compared.
+func equalEncoded(a, b Argument) bool {
+	return a.Typetag() == b.Typetag() && bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+// RawArguments returns the arguments of the message as RawArguments, without decoding them,
+// so that they can be sent in another message exactly as they were received.
+// Arrays are returned as the raw arguments of their brackets and elements.
+//
+// The arguments that a message parsed with ParseOptions.UnknownTypetags lost are returned
+// too. Their sizes are not known, so the first of them has all of their payload,
+// and the others have none, which encodes to the same bytes.
+// The payloads share memory with the message.
+func (msg Message) RawArguments() ([]Argument, error) {
+	if msg.view.isSet() {
+		return rawArguments(msg.view.typetags, msg.view.data), nil
+	}
+	if err := validateArguments(msg.Arguments); err != nil {
+		return nil, fmt.Errorf("%s: %w", msg.Address, err)
+	}
+	var data []byte
+	for _, a := range msg.Arguments {
+		data = appendArgument(data, a)
+	}
+	typetags := appendTypetags(nil, msg.Arguments, msg.unknown.typetags...)
+	return rawArguments(typetags, append(data, msg.unknown.data...)), nil
+}
+
+// rawArguments splits data into the payloads of typetags.
+func rawArguments(typetags, data []byte) []Argument {
+	args := make([]Argument, 0, len(typetags))
+	for _, tt := range typetags {
+		size := len(data)
+		if isTypetag(tt) {
+			if n, err := argumentDataSize(tt, data); err == nil {
+				size = n
+			}
+		}
+		args = append(args, RawArgument{Tag: tt, Payload: data[:size:size]})
+		data = data[size:]
+	}
+	return args
+}
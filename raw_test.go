@@ -0,0 +1,167 @@
+package osc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// unknownMessage returns a message with a string, a double and an argument with the unknown typetag 'x'.
+func unknownMessage() []byte {
+	data := append([]byte("/relay\x00\x00,sdx\x00\x00\x00\x00abc\x00"), appendUint64(nil, math.Float64bits(0.5))...)
+	return append(data, 1, 2, 3, 4, 5, 6, 7, 8)
+}
+
+func TestRawArguments(t *testing.T) {
+	data := unknownMessage()
+	p, err := ParseOptions{UnknownTypetags: true}.ParsePacket(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := p.(Message)
+	if !msg.Truncated() || len(msg.Arguments) != 2 {
+		t.Fatalf("expected a truncated message with 2 arguments, got %v", msg)
+	}
+	for i, m := range []Message{msg, msg.Clone()} {
+		raw, err := m.RawArguments()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if len(raw) != 3 || raw[1].Typetag() != TypetagDouble || raw[2].Typetag() != 'x' {
+			t.Fatalf("(testcase %d) expected 3 raw arguments, got %v", i, raw)
+		}
+		if f, err := raw[1].ReadFloat64(); err != nil || f != 0.5 {
+			t.Fatalf("(testcase %d) expected 0.5, got %f (%v)", i, f, err)
+		}
+		// The arguments are encoded again byte for byte.
+		if got := (Message{Address: m.Address, Arguments: raw}).Bytes(); !bytes.Equal(got, data) {
+			t.Fatalf("(testcase %d) expected %x, got %x", i, data, got)
+		}
+	}
+
+	// Views, arrays and unknown typetags that come after another one are split the same way.
+	var (
+		array = MustMessage("/array", int32(1), Array{String("a"), Double(2)}, true)
+		two   = append(append([]byte{}, data[:8]...), ",sdxy\x00\x00\x00"...)
+	)
+	two = append(two, data[16:]...)
+	for i, data := range [][]byte{array.Bytes(), two} {
+		p, err := LenientParsing.ParsePacket(data)
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		view := p.(Message)
+		if i == 0 {
+			if view, err = parseMessageView(data, nil); err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+		}
+		raw, err := view.RawArguments()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if got := (Message{Address: view.Address, Arguments: raw}).Bytes(); !bytes.Equal(got, data) {
+			t.Fatalf("(testcase %d) expected %x, got %x", i, data, got)
+		}
+	}
+}
+
+func TestRawArguments_Forward(t *testing.T) {
+	sink, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = sink.Close() }() // Best effort.
+	received := make(chan []byte, 1)
+	sink.SetRecvHook(func(addr net.Addr, data []byte) {
+		received <- append([]byte{}, data...)
+	})
+
+	relay, err := Listen("127.0.0.1:0", WithParseOptions(ParseOptions{UnknownTypetags: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = relay.Close() }() // Best effort.
+	go func() {
+		_ = relay.Serve(1, PatternMatching{"/relay": Method(func(msg Message) error {
+			raw, err := msg.RawArguments()
+			if err != nil {
+				return err
+			}
+			return relay.SendTo(sink.LocalAddr(), Message{Address: msg.Address, Arguments: raw})
+		})})
+	}()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_, _, _ = sink.ReceivePacket(ctx)
+	}()
+
+	client, err := Dial(relay.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+	data := unknownMessage()
+	if _, err := client.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if !bytes.Equal(got, data) {
+			t.Fatalf("expected %x to be forwarded, got %x", data, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the forwarded message")
+	}
+}
+
+func TestRawArgument(t *testing.T) {
+	for i, testcase := range []struct {
+		Raw   RawArgument
+		Valid bool
+	}{
+		{Raw: RawArgument{Tag: TypetagInt, Payload: Int(5).Bytes()}, Valid: true},
+		{Raw: RawArgument{Tag: TypetagInt, Payload: make([]byte, 8)}},
+		{Raw: RawArgument{Tag: TypetagDouble, Payload: make([]byte, 4)}},
+		{Raw: RawArgument{Tag: TypetagString, Payload: []byte("ab\x00\x00")}, Valid: true},
+		{Raw: RawArgument{Tag: TypetagString, Payload: []byte("ab\x00\x00abc\x00")}},
+		{Raw: RawArgument{Tag: TypetagTrue}, Valid: true},
+		{Raw: RawArgument{Tag: TypetagTrue, Payload: make([]byte, 4)}},
+		{Raw: RawArgument{Tag: 'x', Payload: make([]byte, 8)}, Valid: true},
+		{Raw: RawArgument{Tag: 'x', Payload: make([]byte, 6)}},
+		{Raw: RawArgument{Tag: 0}},
+	} {
+		if err := validateArgument(testcase.Raw); (err == nil) != testcase.Valid || err != nil && !errors.Is(err, ErrInvalidTypeTag) {
+			t.Fatalf("(testcase %d) expected valid to be %t, got %v", i, testcase.Valid, err)
+		}
+	}
+
+	// Raw arguments equal the arguments they encode, both ways.
+	raw := RawArgument{Tag: TypetagInt, Payload: Int(5).Bytes()}
+	if !raw.Equal(Int(5)) || !Int(5).Equal(raw) || Int(6).Equal(raw) || (Blob{}).Equal(RawArgument{Tag: TypetagBlob}) {
+		t.Fatal("expected raw arguments to compare by their encoding")
+	}
+	if i, err := raw.ReadInt32(); err != nil || i != 5 {
+		t.Fatalf("expected 5, got %d (%v)", i, err)
+	}
+	if _, err := (RawArgument{Tag: 'x', Payload: make([]byte, 4)}).ReadInt32(); !errors.Is(err, ErrInvalidTypeTag) {
+		t.Fatalf("expected ErrInvalidTypeTag, got %v", err)
+	}
+
+	// The builder writes them as they are.
+	data, err := NewBuilder("/raw").Int32(1).Argument(RawArgument{Tag: 'x', Payload: []byte{1, 2, 3, 4}}).AppendTo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "/raw\x00\x00\x00\x00,ix\x00\x00\x00\x00\x01\x01\x02\x03\x04"; string(data) != expected {
+		t.Fatalf("expected %x, got %x", expected, data)
+	}
+	if _, err := NewBuilder("/raw").Argument(RawArgument{Tag: TypetagInt}).Message(); !errors.Is(err, ErrInvalidTypeTag) {
+		t.Fatalf("expected ErrInvalidTypeTag, got %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Common errors.
+var (
+	ErrReceiving = errors.New("conn is being received from, so it can not be served")
+	ErrServing   = errors.New("conn is being served, so it can not be received from")
+)
+
+// receiver reads the packets of a conn for Receive.
+type receiver struct {
+	r   readSender
+	s   *connState
+	ctx context.Context
+	out chan Incoming
+
+	// mu is held while a packet is sent on out,
+	// so that out is not closed in the meantime.
+	mu     sync.Mutex
+	closed bool
+}
+
+// receive starts reading the packets of r, whose state is s, onto a channel
+// that is closed once ctx is done or r is closed or shut down.
+func receive(ctx context.Context, r readSender, s *connState) (<-chan Incoming, error) {
+	if err := s.startReceiving(); err != nil {
+		return nil, err
+	}
+	size := s.queueSize
+	if size < 0 {
+		size = 0
+	}
+	rc := &receiver{
+		r:   r,
+		s:   s,
+		ctx: ctx,
+		out: make(chan Incoming, size),
+	}
+	loopDone := make(chan struct{})
+	go func() {
+		rc.loop()
+		close(loopDone)
+	}()
+	go rc.closeWhenDone(loopDone)
+	return rc.out, nil
+}
+
+// stopped returns true if Receive has to stop.
+func (rc *receiver) stopped() bool {
+	select {
+	case <-rc.ctx.Done():
+	case <-rc.r.CloseChan():
+	case <-rc.r.shutdownChan():
+	case <-rc.r.Context().Done():
+	default:
+		return false
+	}
+	return true
+}
+
+// closeWhenDone closes the channel of packets once Receive has to stop,
+// or once the read loop has returned.
+// A pending read is interrupted if the conn allows it, so that the conn can
+// be served as soon as the channel is closed. Otherwise the conn can be served
+// once the pending read has returned.
+func (rc *receiver) closeWhenDone(loopDone <-chan struct{}) {
+	select {
+	case <-loopDone:
+	case <-rc.ctx.Done():
+	case <-rc.r.CloseChan():
+	case <-rc.r.shutdownChan():
+	case <-rc.r.Context().Done():
+	}
+	if rd, ok := rc.r.(readDeadliner); ok && !isDone(loopDone) && !isDone(rc.r.CloseChan()) {
+		if rd.SetReadDeadline(time.Now()) == nil {
+			<-loopDone
+			_ = rd.SetReadDeadline(time.Time{}) // Best effort.
+		}
+	}
+	if isDone(loopDone) {
+		rc.s.doneReceiving()
+		rc.close()
+		return
+	}
+	rc.close()
+	<-loopDone
+	rc.s.doneReceiving()
+}
+
+// close closes the channel of packets.
+func (rc *receiver) close() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.closed = true
+	close(rc.out)
+}
+
+// isDone returns true if done is closed.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// loop reads packets until Receive has to stop or a read fails.
+func (rc *receiver) loop() {
+	buffers := rc.r.newBuffers()
+	for {
+		size := rc.r.readBufferSize()
+		buf := buffers.get(size + 1)
+		n, sender, err := rc.r.read(buf.data)
+		received := time.Now()
+		if err != nil {
+			buffers.put(buf)
+			if !rc.stopped() && !errors.Is(err, net.ErrClosed) {
+				rc.send(Incoming{Sender: sender, Received: received, Err: err})
+			}
+			return
+		}
+		rc.r.received(sender, buf.data[:n])
+		if rc.r.admit(sender, n) != nil {
+			buffers.put(buf)
+			continue
+		}
+		incoming := Incoming{Sender: sender, Received: received}
+		incoming.Data, incoming.Packet, incoming.Err = parseRead(buf.data, n, size, sender, rc.r.parseOptions())
+		rc.s.countParseError(incoming.Err)
+		buffers.put(buf)
+		if err := truncatedError(incoming.Packet); err != nil {
+			rc.r.handleError(err)
+		}
+		if incoming.Err == nil {
+			incoming.Packet = withConn(incoming.Packet, rc.r, packetContext(rc.ctx, incoming))
+		}
+		if !rc.send(incoming) {
+			return
+		}
+	}
+}
+
+// send passes incoming to the channel of packets, and returns false
+// if the channel has been closed. If the channel is full the oldest
+// packet is dropped to make room if the queue policy is DropOldest,
+// incoming is dropped if it is DropNewest, and otherwise send waits for room.
+func (rc *receiver) send(incoming Incoming) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.closed {
+		return false
+	}
+	if rc.s.queuePolicy == DropNewest && cap(rc.out) > 0 {
+		select {
+		case rc.out <- incoming:
+		default:
+			rc.s.countDropped(incoming.Sender)
+		}
+		return true
+	}
+	if rc.s.queuePolicy == DropOldest && cap(rc.out) > 0 {
+		select {
+		case rc.out <- incoming:
+			return true
+		default:
+		}
+		select {
+		case dropped := <-rc.out:
+			rc.s.countDropped(dropped.Sender)
+		default:
+		}
+	}
+	select {
+	case rc.out <- incoming:
+		return true
+	case <-rc.ctx.Done():
+	case <-rc.r.CloseChan():
+	case <-rc.r.shutdownChan():
+	case <-rc.r.Context().Done():
+	}
+	return false
+}
+
+// parseRead parses the n bytes that were read into buf, which is one byte
+// larger than the read buffer size, so that datagrams that were truncated
+// are rejected. The data and the packet do not share memory with buf.
+// The packet is parsed with o, which may be nil.
+func parseRead(buf []byte, n, size int, sender net.Addr, o *ParseOptions) ([]byte, Packet, error) {
+	if n > size {
+		return nil, nil, &ParseError{Sender: sender, Err: fmt.Errorf("datagram is larger than the %d byte read buffer: %w", size, ErrPacketTruncated)}
+	}
+	data := append([]byte(nil), buf[:n]...)
+	p, err := parsePacket(data, sender, o)
+	return data, p, err
+}
+
+// receivePacket reads a single packet from r, whose state is s.
+// The read is interrupted when ctx is done if r allows it,
+// and otherwise it is left to return in the background.
+func receivePacket(ctx context.Context, r readSender, s *connState) (Packet, net.Addr, error) {
+	if err := s.startReceiving(); err != nil {
+		return nil, nil, err
+	}
+	type result struct {
+		data   []byte
+		p      Packet
+		sender net.Addr
+		err    error
+	}
+	var (
+		results = make(chan result, 1)
+		size    = r.readBufferSize()
+	)
+	go func() {
+		defer s.doneReceiving()
+
+		buf := make([]byte, size+1)
+		n, sender, err := r.read(buf)
+		if err != nil {
+			results <- result{sender: sender, err: err}
+			return
+		}
+		r.received(sender, buf[:n])
+		if err := r.admit(sender, n); err != nil {
+			results <- result{sender: sender, err: err}
+			return
+		}
+		data, p, err := parseRead(buf, n, size, sender, r.parseOptions())
+		s.countParseError(err)
+		if truncated := truncatedError(p); truncated != nil {
+			r.handleError(truncated)
+		}
+		results <- result{data: data, p: p, sender: sender, err: err}
+	}()
+	rd, interruptible := r.(readDeadliner)
+	if interruptible {
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = rd.SetReadDeadline(deadline) // Best effort, ctx is watched too.
+		}
+		defer func() { _ = rd.SetReadDeadline(time.Time{}) }() // Best effort.
+	}
+	var res result
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		if !interruptible || rd.SetReadDeadline(time.Now()) != nil {
+			return nil, nil, ctx.Err()
+		}
+		res = <-results
+	}
+	if res.err != nil {
+		if _, ok := res.err.(*ParseError); ok {
+			return nil, res.sender, res.err
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if errors.Is(res.err, os.ErrDeadlineExceeded) {
+			// The read deadline may pass just before ctx notices.
+			return nil, nil, context.DeadlineExceeded
+		}
+		return nil, nil, res.err
+	}
+	incoming := Incoming{Data: res.data, Sender: res.sender, Received: time.Now()}
+	return withConn(res.p, r, packetContext(ctx, incoming)), res.sender, nil
+}
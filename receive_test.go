@@ -0,0 +1,266 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestUDPConnReceive(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	packets, err := server.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Receive(ctx); !errors.Is(err, ErrReceiving) {
+		t.Fatalf("expected ErrReceiving, got %v", err)
+	}
+	if err := server.Serve(1, PatternMatching{}); !errors.Is(err, ErrReceiving) {
+		t.Fatalf("expected ErrReceiving, got %v", err)
+	}
+	sent := []Packet{
+		MustMessage("/a", int32(1)),
+		Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/b", "two")}},
+	}
+	for _, p := range sent {
+		if err := client.Send(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Write([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= len(sent); i++ {
+		var incoming Incoming
+		select {
+		case incoming = <-packets:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(packet %d) timeout", i)
+		}
+		if incoming.Sender.String() != client.LocalAddr().String() || incoming.Received.IsZero() {
+			t.Fatalf("(packet %d) unexpected sender %s or time %s", i, incoming.Sender, incoming.Received)
+		}
+		if i == len(sent) {
+			if _, ok := incoming.Err.(*ParseError); !ok || incoming.Packet != nil {
+				t.Fatalf("(packet %d) expected a *ParseError, got %v", i, incoming.Err)
+			}
+			continue
+		}
+		if incoming.Err != nil {
+			t.Fatal(incoming.Err)
+		}
+		if !incoming.Packet.Equal(sent[i]) {
+			t.Fatalf("(packet %d) expected %v, got %v", i, sent[i], incoming.Packet)
+		}
+	}
+	cancel()
+	select {
+	case _, ok := <-packets:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the channel to be closed")
+	}
+	// The conn can be served once Receive has stopped.
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := server.Receive(context.Background()); !errors.Is(err, ErrServing) {
+		t.Fatalf("expected ErrServing, got %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReceive_DropNewest(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	c2.SetQueueSize(2)
+	c2.SetQueuePolicy(DropNewest)
+	packets, err := c2.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first two packets fill the channel, and the ones after them are dropped.
+	for i := int32(0); i < 5; i++ {
+		if err := c1.Send(MustMessage("/n", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for c2.Dropped() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 3 dropped packets, got %d", c2.Dropped())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i := int32(0); i < 2; i++ {
+		incoming := <-packets
+		if msg, ok := incoming.Packet.(Message); !ok || !msg.Equal(MustMessage("/n", i)) {
+			t.Fatalf("(packet %d) expected /n %d, got %v", i, i, incoming.Packet)
+		}
+	}
+}
+
+func TestReceive_DropOldest(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	c2.SetQueueSize(2)
+	c2.SetQueuePolicy(DropOldest)
+	packets, err := c2.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int32(0); i < 5; i++ {
+		if err := c1.Send(MustMessage("/n", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The pipe delivers every packet once it has been read, so all but the last
+	// two have been dropped once the fifth has been sent and another one has been read.
+	if err := c1.Send(MustMessage("/sync")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for c2.Dropped() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 dropped packets, got %d", c2.Dropped())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for i, expected := range []string{"/n", "/sync"} {
+		incoming := <-packets
+		if msg, ok := incoming.Packet.(Message); !ok || msg.Address != expected {
+			t.Fatalf("(packet %d) expected %s, got %v", i, expected, incoming.Packet)
+		}
+	}
+	// Closing the conn closes the channel.
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for range packets {
+	}
+}
+
+func TestUDPConnReceivePacket(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	if _, err := client.Write([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	msg := MustMessage("/status.reply", int32(1))
+	if err := client.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if _, sender, err := server.ReceivePacket(ctx); !errors.As(err, new(*ParseError)) || sender == nil {
+		t.Fatalf("expected a *ParseError with a sender, got %v from %v", err, sender)
+	}
+	p, sender, err := server.ReceivePacket(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Equal(msg) || sender.String() != client.LocalAddr().String() {
+		t.Fatalf("expected %s from %s, got %v from %s", msg, client.LocalAddr(), p, sender)
+	}
+	// Nothing arrives before the deadline.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := server.ReceivePacket(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected ReceivePacket to give up at the deadline, took %s", elapsed)
+	}
+	// The read deadline is cleared, and ReceivePacket fails while the conn is served.
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, _, err := server.ReceivePacket(context.Background()); !errors.Is(err, ErrServing) {
+		t.Fatalf("expected ErrServing, got %v", err)
+	}
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReceivePacket_Canceled(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	// A pipe can not interrupt its read, so ReceivePacket returns at once
+	// and the conn can not be read from again until the read has returned.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := c2.ReceivePacket(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, _, err := c2.ReceivePacket(context.Background()); !errors.Is(err, ErrReceiving) {
+		t.Fatalf("expected ErrReceiving, got %v", err)
+	}
+	if err := c1.Send(MustMessage("/a")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c2.serveMu.Lock()
+		receiving := c2.receiving
+		c2.serveMu.Unlock()
+		if !receiving {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for the pending read")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Common errors.
+var (
+	ErrNoReplier = errors.New("message was not read from a conn, so it can not be replied to")
+)
+
+// ReplyMethod is an OSC method that can answer the sender of a message,
+// e.g. with /status.reply in response to /status.
+// If it returns a non-nil message Serve sends it back to the sender.
+// Errors sending the reply are passed to the conn's error handler
+// and never stop the server.
+type ReplyMethod func(msg Message) (*Message, error)
+
+// Handle handles an OSC message.
+func (method ReplyMethod) Handle(m Message) error {
+	reply, err := method(m)
+	if err != nil || reply == nil {
+		return err
+	}
+	if m.replier == nil {
+		return ErrNoReplier
+	}
+	if err := m.replier.Reply(m.Sender, *reply); err != nil {
+		m.replier.handleError(&MethodError{Sender: m.Sender, Err: fmt.Errorf("reply: %w", err)})
+	}
+	return nil
+}
+
+// replier is a conn that can reply to the messages it reads.
+type replier interface {
+	Reply(to net.Addr, msg Message) error
+	handleError(error)
+	handleUnmatched(msg Message, err error)
+	recoverPanics() bool
+	tracer() func(TraceEvent)
+}
+
+// connectedSender is the subset of a conn that reply needs.
+type connectedSender interface {
+	RemoteAddr() net.Addr
+	Send(Packet) error
+	SendTo(net.Addr, Packet) error
+}
+
+// reply sends msg to the peer of a connected conn, or to the given address otherwise.
+func reply(conn connectedSender, to net.Addr, msg Message) error {
+	if conn.RemoteAddr() != nil {
+		return conn.Send(msg)
+	}
+	if to == nil {
+		return errors.New("can not reply without a sender address")
+	}
+	return conn.SendTo(to, msg)
+}
+
+// BoundSender sends packets through a conn to the address it is bound to,
+// e.g. the sender of a message, which a MethodCtx gets with SenderFromContext.
+type BoundSender struct {
+	conn connectedSender
+	addr net.Addr
+}
+
+// Addr returns the address the sender is bound to.
+func (s BoundSender) Addr() net.Addr {
+	return s.addr
+}
+
+// Send sends p to the address the sender is bound to.
+func (s BoundSender) Send(p Packet) error {
+	return s.conn.SendTo(s.addr, p)
+}
+
+// withConn returns p with the conn it was read from and the context
+// of its methods attached to every message it contains.
+func withConn(p Packet, r replier, ctx context.Context) Packet {
+	switch x := p.(type) {
+	case Message:
+		x.replier, x.ctx = r, ctx
+		return x
+	case Bundle:
+		packets := make([]Packet, len(x.Packets))
+		for i, packet := range x.Packets {
+			packets[i] = withConn(packet, r, ctx)
+		}
+		x.Packets = packets
+		return x
+	default:
+		return p
+	}
+}
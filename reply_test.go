@@ -0,0 +1,140 @@
+package osc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReplyMethodUDP(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			"/status": ReplyMethod(func(msg Message) (*Message, error) {
+				return &Message{Address: "/status.reply", Arguments: Arguments{Int(1)}}, nil
+			}),
+			"/quiet": ReplyMethod(func(msg Message) (*Message, error) {
+				return nil, nil
+			}),
+		})
+	}()
+	// The client is not connected, so the reply has to go to the sender address.
+	client, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		replies = make(chan Message, 2)
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- client.Serve(1, PatternMatching{
+			"/status.reply": Method(func(msg Message) error {
+				replies <- msg
+				return nil
+			}),
+		})
+	}()
+	for _, addr := range []string{"/quiet", "/status"} {
+		if err := client.SendTo(server.LocalAddr(), Message{Address: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := Message{Address: "/status.reply", Arguments: Arguments{Int(1)}}
+	if got := waitMessage(t, replies, errChan); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	select {
+	case msg := <-replies:
+		t.Fatalf("expected no more replies, got %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReplyConnected(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	msgs := make(chan Message)
+	go func() {
+		_ = c1.Serve(1, PatternMatching{
+			"/pong": Method(func(msg Message) error {
+				msgs <- msg
+				return nil
+			}),
+		})
+	}()
+	// A connected conn ignores the address.
+	if err := c2.Reply(nil, Message{Address: "/pong"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitMessage(t, msgs, nil); msg.Address != "/pong" {
+		t.Fatalf("expected /pong, got %s", msg.Address)
+	}
+}
+
+func TestReplyMethodError(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	errs := make(chan error, 1)
+	c2.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	c2.SetStrict(true)
+
+	serveErrs := make(chan error, 1)
+	go func() {
+		serveErrs <- c2.Serve(1, PatternMatching{
+			"/status": ReplyMethod(func(msg Message) (*Message, error) {
+				// The reply will fail since the other end is gone.
+				_ = c1.Close()
+				return &Message{Address: "/status.reply"}, nil
+			}),
+		})
+	}()
+	if err := c1.Send(Message{Address: "/status"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if _, ok := err.(*MethodError); !ok {
+			t.Fatalf("expected *MethodError, got %T", err)
+		}
+		if !errors.Is(err, io.ErrClosedPipe) {
+			t.Fatalf("expected io.ErrClosedPipe, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+	// Serve returns nil once it sees the other end hang up,
+	// rather than returning the reply failure, even in strict mode.
+	select {
+	case err := <-serveErrs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Serve to return")
+	}
+
+	if err := (ReplyMethod(func(msg Message) (*Message, error) {
+		return &Message{Address: "/status.reply"}, nil
+	})).Handle(Message{Address: "/status"}); err != ErrNoReplier {
+		t.Fatalf("expected ErrNoReplier, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package osc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ResolveError is returned by Dial and Listen when their address can not be resolved,
+// so it can be told apart from the *net.OpError of a socket that can not be opened.
+// A conn that re-resolves its peer passes it to the error handler.
+type ResolveError struct {
+	// Addr is the address that was given, including its network.
+	Addr string
+
+	// Err is the error of the resolver.
+	Err error
+}
+
+// Error returns the error message.
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("resolving %q: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the error of the resolver.
+func (e *ResolveError) Unwrap() error { return e.Err }
+
+// WithReResolve makes a conn opened by Dial resolve its address again every interval,
+// and send to the new address from then on if it has changed, e.g. because the
+// host got a new DHCP lease. The packets that are being sent are not interrupted.
+// An address that can not be resolved is passed to the error handler as a
+// *ResolveError, logged at the Warn level, and the conn keeps sending to the old one.
+//
+// The conn is not connected to its peer, so that its peer can change, and it reads
+// only the datagrams that come from the current address, as a connected conn does.
+// Its LocalAddr is the address it is bound to rather than the one it would send from.
+// The other constructors reject this option.
+func WithReResolve(interval time.Duration) Option {
+	return func(o *options) error {
+		if interval <= 0 {
+			return fmt.Errorf("re-resolve interval must be positive, got %s", interval)
+		}
+		o.reResolve = interval
+		return nil
+	}
+}
+
+// WithResolver makes Dial and Listen resolve their "host:port" with resolve,
+// which is given the network, udp, udp4 or udp6, instead of net.ResolveUDPAddr.
+// It is also what WithReResolve resolves the address again with.
+func WithResolver(resolve func(ctx context.Context, network, address string) (*net.UDPAddr, error)) Option {
+	return func(o *options) error {
+		o.resolver = resolve
+		return nil
+	}
+}
+
+// resolveUDP resolves address with the resolver of the options, or net.ResolveUDPAddr.
+func (o options) resolveUDP(ctx context.Context, network, address string) (*net.UDPAddr, error) {
+	if o.resolver != nil {
+		return o.resolver(ctx, network, address)
+	}
+	return net.ResolveUDPAddr(network, address)
+}
+
+// resolvingConn is a UDP socket that behaves like one connected to peer,
+// which can change while it is in use.
+type resolvingConn struct {
+	*net.UDPConn
+
+	peer atomic.Value // *net.UDPAddr
+}
+
+// RemoteAddr returns the current address of the peer.
+func (c *resolvingConn) RemoteAddr() net.Addr {
+	return c.remote()
+}
+
+// remote returns the current address of the peer.
+func (c *resolvingConn) remote() *net.UDPAddr {
+	return c.peer.Load().(*net.UDPAddr)
+}
+
+// Write writes b to the current address of the peer.
+func (c *resolvingConn) Write(b []byte) (int, error) {
+	return c.UDPConn.WriteToUDP(b, c.remote())
+}
+
+// ReadFromUDP reads the next datagram from the current address of the peer,
+// and discards the datagrams from any other address.
+func (c *resolvingConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	for {
+		n, addr, err := c.UDPConn.ReadFromUDP(b)
+		if err != nil || sameUDPAddr(addr, c.remote()) {
+			return n, addr, err
+		}
+	}
+}
+
+// dialResolving returns a conn that sends from laddr to raddr, which addr resolved to,
+// and resolves hostport again every o.reResolve.
+func dialResolving(ctx context.Context, addr, network, hostport string, laddr, raddr *net.UDPAddr, o options) (*UDPConn, error) {
+	socket, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &resolvingConn{UDPConn: socket}
+	rc.peer.Store(raddr)
+
+	uc := &UDPConn{
+		udpConn:   rc,
+		connState: newConnState(ctx),
+	}
+	if _, err := uc.initialize(o); err != nil {
+		return nil, err
+	}
+	go uc.reResolve(rc, addr, network, hostport, o)
+	return uc, nil
+}
+
+// reResolve resolves hostport every o.reResolve until the conn is closed,
+// and switches rc to the new address when it changes.
+func (conn *UDPConn) reResolve(rc *resolvingConn, addr, network, hostport string, o options) {
+	ctx := conn.ctx
+	ticker := time.NewTicker(o.reResolve)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-conn.closeChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+		resolved, err := o.resolveUDP(ctx, network, hostport)
+		if err != nil {
+			select {
+			case <-conn.closeChan:
+				return
+			default:
+			}
+			err = &ResolveError{Addr: addr, Err: err}
+			conn.handleError(err)
+			if conn.logger != nil {
+				conn.log(slog.LevelWarn, "osc: peer can not be resolved, the old address is kept", remoteAttr(rc.remote()), errorAttr(err))
+			}
+			continue
+		}
+		if old := rc.remote(); !sameUDPAddr(old, resolved) {
+			rc.peer.Store(resolved)
+			if conn.logger != nil {
+				conn.log(slog.LevelInfo, "osc: peer address changed", remoteAttr(resolved), slog.String(LogPrevious, old.String()))
+			}
+		}
+	}
+}
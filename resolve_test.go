@@ -0,0 +1,178 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver resolves every address to the address it is set to, or fails with its error.
+type fakeResolver struct {
+	mu   sync.Mutex
+	addr *net.UDPAddr
+	err  error
+}
+
+func (r *fakeResolver) set(addr *net.UDPAddr, err error) {
+	r.mu.Lock()
+	r.addr, r.err = addr, err
+	r.mu.Unlock()
+}
+
+func (r *fakeResolver) resolve(ctx context.Context, network, address string) (*net.UDPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if network != "udp" || address != "lightdesk.local:9000" {
+		return nil, errors.New("unexpected address " + network + "://" + address)
+	}
+	return r.addr, r.err
+}
+
+// echoServer returns a server that sends every message it gets back to its sender,
+// and passes it to msgs.
+func echoServer(t *testing.T, msgs chan Message) *UDPConn {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = server.Serve(1, PatternMatching{Default: Method(func(msg Message) error {
+			msgs <- msg
+			return server.SendTo(msg.Sender, msg)
+		})})
+	}()
+	return server
+}
+
+func TestWithReResolve(t *testing.T) {
+	var (
+		fromA    = make(chan Message, 16)
+		fromB    = make(chan Message, 16)
+		a        = echoServer(t, fromA)
+		b        = echoServer(t, fromB)
+		resolver = &fakeResolver{}
+	)
+	defer func() { _ = a.Close() }() // Best effort.
+	defer func() { _ = b.Close() }() // Best effort.
+
+	resolver.set(a.LocalAddr().(*net.UDPAddr), nil)
+	client, err := Dial("lightdesk.local:9000", WithResolver(resolver.resolve), WithReResolve(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		echoes = make(chan Message, 16)
+		errs   = make(chan error, 16)
+	)
+	client.SetErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	go func() {
+		_ = client.Serve(1, PatternMatching{Default: Method(func(msg Message) error {
+			echoes <- msg
+			return nil
+		})})
+	}()
+
+	// sendUntil sends to the peer of the client until the peer is the server of msgs,
+	// whose echo the client then reads.
+	sendUntil := func(msgs chan Message, peer *UDPConn) {
+		t.Helper()
+
+		deadline := time.After(2 * time.Second)
+		for {
+			if err := client.Send(MustMessage("/ping")); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-msgs:
+				if expected, got := peer.LocalAddr().String(), client.RemoteAddr().String(); expected != got {
+					t.Fatalf("expected the client to send to %s, got %s", expected, got)
+				}
+				// The echoes of the pings that were sent before may come first.
+				for {
+					select {
+					case echo := <-echoes:
+						if echo.Sender.String() == peer.LocalAddr().String() {
+							return
+						}
+					case <-deadline:
+						t.Fatalf("timeout waiting for the echo from %s", peer.LocalAddr())
+					}
+				}
+			case <-time.After(5 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("timeout waiting for a message to %s", peer.LocalAddr())
+			}
+		}
+	}
+	sendUntil(fromA, a)
+
+	// The host got a new address.
+	resolver.set(b.LocalAddr().(*net.UDPAddr), nil)
+	sendUntil(fromB, b)
+
+	// The old address does not reach the client anymore.
+	// The client is not bound to the loopback address, only to its port.
+	stale := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: client.LocalAddr().(*net.UDPAddr).Port}
+	if err := a.SendTo(stale, MustMessage("/stale")); err != nil {
+		t.Fatal(err)
+	}
+	for timeout := time.After(20 * time.Millisecond); ; {
+		select {
+		case echo := <-echoes:
+			if echo.Address == "/stale" {
+				t.Fatalf("expected the datagram from the old address to be discarded, got %s", echo)
+			}
+			continue
+		case <-timeout:
+		}
+		break
+	}
+
+	// The host can not be resolved, so the client keeps sending to the last address.
+	errResolve := errors.New("no such host")
+	resolver.set(nil, errResolve)
+	var re *ResolveError
+	select {
+	case err := <-errs:
+		if !errors.As(err, &re) || !errors.Is(err, errResolve) || re.Addr != "lightdesk.local:9000" {
+			t.Fatalf("expected a *ResolveError for lightdesk.local:9000, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the resolution error")
+	}
+	sendUntil(fromB, b)
+}
+
+func TestWithReResolve_Options(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenUDP("udp", laddr, WithReResolve(time.Second)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+	if _, err := Listen("127.0.0.1:0", WithReResolve(time.Second)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+	if _, err := Dial("127.0.0.1:9000", WithReResolve(0)); err == nil {
+		t.Fatal("expected an error for a zero interval")
+	}
+	if _, err := DialTCP("tcp", nil, nil, WithReResolve(time.Second)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+}
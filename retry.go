@@ -0,0 +1,127 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy decides how SendRetry retries a packet that could not be sent.
+// The fields that are zero take the values of DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times the packet is sent at most, including the first.
+	MaxAttempts int
+
+	// InitialDelay is how long SendRetry waits before the second attempt.
+	// Every delay after that is Backoff times the one before it, up to MaxDelay.
+	InitialDelay time.Duration
+	Backoff      float64
+	MaxDelay     time.Duration
+
+	// Retryable returns true if an attempt that failed with err may be retried.
+	// The default is IsTemporary.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a policy that makes 5 attempts, 50ms, 100ms, 200ms and 400ms apart,
+// while the errors are temporary.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: 50 * time.Millisecond,
+		Backoff:      2,
+		MaxDelay:     time.Second,
+		Retryable:    IsTemporary,
+	}
+}
+
+// withDefaults returns the policy with its zero fields set to the default ones.
+func (policy RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = def.MaxAttempts
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = def.InitialDelay
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = def.Backoff
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = def.MaxDelay
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = def.Retryable
+	}
+	return policy
+}
+
+// delay returns how long to wait after the given attempt, counted from 1, failed.
+func (policy RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(policy.InitialDelay)
+	for i := 1; i < attempt && d < float64(policy.MaxDelay); i++ {
+		d *= policy.Backoff
+	}
+	if d > float64(policy.MaxDelay) {
+		return policy.MaxDelay
+	}
+	return time.Duration(d)
+}
+
+// SendRetry sends p to the peer of conn, and sends it again after a delay as long as
+// it can not be sent with an error that the policy says is retryable,
+// e.g. while the network is unreachable for a moment on a flaky wireless link.
+// It returns the error of the last attempt, wrapped with the number of attempts
+// when there were several, or ctx.Err() too if ctx was done while SendRetry waited.
+//
+// Every attempt is counted in the Stats of conn, and passed to its send hook.
+// Note that a datagram that was sent may still be lost, so a packet that
+// must arrive needs an acknowledgement from the peer, e.g. with Call.
+func SendRetry(ctx context.Context, conn Conn, p Packet, policy RetryPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	policy = policy.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		err := conn.Send(p)
+		if err == nil || !policy.Retryable(err) {
+			if err != nil && attempt > 1 {
+				return fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+		if attempt == policy.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w after %d attempts: %w", ctx.Err(), attempt, err)
+		}
+	}
+}
+
+// IsTemporary returns true if err is an error that sending again may not get,
+// such as a timeout, an unreachable network or host, a full buffer, or a peer
+// that refused a datagram because it is being restarted.
+func IsTemporary(err error) bool {
+	var temporary interface{ Temporary() bool }
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, ErrWriteTimeout):
+		return true
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETDOWN), errors.Is(err, syscall.ENOBUFS),
+		errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	case errors.As(err, &temporary):
+		return temporary.Temporary()
+	default:
+		return false
+	}
+}
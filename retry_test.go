@@ -0,0 +1,116 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyConn is a Conn whose first sends fail.
+type flakyConn struct {
+	Conn
+
+	failures int // The number of sends that fail, or -1 for all of them.
+	err      error
+	attempts int
+}
+
+func (c *flakyConn) Send(p Packet) error {
+	c.attempts++
+	if c.failures < 0 || c.attempts <= c.failures {
+		return c.err
+	}
+	return nil
+}
+
+func TestSendRetry(t *testing.T) {
+	var (
+		unreachable = &net.OpError{Op: "write", Net: "udp", Err: os.NewSyscallError("write", syscall.ENETUNREACH)}
+		fatal       = errors.New("fatal")
+		fast        = RetryPolicy{InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	)
+	for i, testcase := range []struct {
+		Failures int
+		Err      error
+		Policy   RetryPolicy
+		Attempts int
+		Expected error
+	}{
+		{Failures: 0, Err: unreachable, Policy: fast, Attempts: 1},
+		{Failures: 2, Err: unreachable, Policy: fast, Attempts: 3},
+		{Failures: -1, Err: unreachable, Policy: fast, Attempts: 5, Expected: syscall.ENETUNREACH},
+		{Failures: -1, Err: unreachable, Policy: RetryPolicy{MaxAttempts: 2, InitialDelay: time.Millisecond}, Attempts: 2, Expected: syscall.ENETUNREACH},
+		{Failures: -1, Err: fatal, Policy: fast, Attempts: 1, Expected: fatal},
+		{
+			Failures: 2,
+			Err:      fatal,
+			Policy:   RetryPolicy{InitialDelay: time.Millisecond, Retryable: func(err error) bool { return errors.Is(err, fatal) }},
+			Attempts: 3,
+		},
+	} {
+		conn := &flakyConn{failures: testcase.Failures, err: testcase.Err}
+		err := SendRetry(context.Background(), conn, MustMessage("/cue"), testcase.Policy)
+		if testcase.Expected == nil && err != nil || !errors.Is(err, testcase.Expected) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Expected, err)
+		}
+		if conn.attempts != testcase.Attempts {
+			t.Fatalf("(testcase %d) expected %d attempts, got %d", i, testcase.Attempts, conn.attempts)
+		}
+	}
+}
+
+func TestSendRetry_Context(t *testing.T) {
+	conn := &flakyConn{failures: -1, err: syscall.ENETUNREACH}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Waiting for the second attempt stops when ctx is done.
+	start := time.Now()
+	err := SendRetry(ctx, conn, MustMessage("/cue"), RetryPolicy{InitialDelay: time.Hour})
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, syscall.ENETUNREACH) {
+		t.Fatalf("expected the deadline and the last error, got %v", err)
+	}
+	if conn.attempts != 1 || time.Since(start) > time.Second {
+		t.Fatalf("expected 1 attempt and no wait, got %d attempts in %s", conn.attempts, time.Since(start))
+	}
+	// Nothing is sent once ctx is done.
+	if err := SendRetry(ctx, conn, MustMessage("/cue"), RetryPolicy{}); !errors.Is(err, context.DeadlineExceeded) || conn.attempts != 1 {
+		t.Fatalf("expected context.DeadlineExceeded without an attempt, got %v and %d attempts", err, conn.attempts)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}.withDefaults()
+	for i, expected := range []time.Duration{10, 20, 40, 50, 50} {
+		if got := policy.delay(i + 1); got != expected*time.Millisecond {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, expected*time.Millisecond, got)
+		}
+	}
+	if policy := (RetryPolicy{}).withDefaults(); policy.MaxAttempts != 5 || policy.Retryable == nil {
+		t.Fatalf("expected the default policy, got %+v", policy)
+	}
+}
+
+func TestIsTemporary(t *testing.T) {
+	for i, testcase := range []struct {
+		Err       error
+		Temporary bool
+	}{
+		{Err: &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.ENETUNREACH)}, Temporary: true},
+		{Err: syscall.EHOSTUNREACH, Temporary: true},
+		{Err: syscall.ENOBUFS, Temporary: true},
+		{Err: &net.OpError{Op: "write", Err: os.ErrDeadlineExceeded}, Temporary: true},
+		{Err: ErrWriteTimeout, Temporary: true},
+		{Err: net.ErrClosed},
+		{Err: ErrPacketTooLarge},
+		{Err: errors.New("oops")},
+	} {
+		if got := IsTemporary(testcase.Err); got != testcase.Temporary {
+			t.Fatalf("(testcase %d) expected %t for %v, got %t", i, testcase.Temporary, testcase.Err, got)
+		}
+	}
+}
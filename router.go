@@ -0,0 +1,382 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrMountConflict is returned when a method and a sub-dispatcher of a Router
+// would both handle the same addresses.
+var ErrMountConflict = errors.New("mount conflict")
+
+// Router is a Dispatcher whose methods can be added and removed while it is serving.
+// It dispatches like PatternMatching.
+//
+// Other dispatchers can be mounted at a prefix of the address space, such as "/synth",
+// so that a module can own a namespace. The messages under the prefix are passed
+// to the sub-dispatcher with the prefix stripped from their address, and the sub-dispatcher
+// can be a Router with mounts of its own.
+//
+// The methods are kept in a PatternMatching that is copied every time a method
+// is added or removed, and swapped in atomically. Dispatching never sees
+// a half-updated set of methods, while adding or removing a method takes time
+// proportional to the number of methods. This suits apps that change their
+// methods far less often than they receive messages.
+//
+// The first message dispatched after a change builds a trie of the addresses
+// split at every '/', so that matching an address takes time proportional
+// to the number of its parts rather than to the number of methods.
+//
+// The zero value is a router without any methods.
+type Router struct {
+	mu     sync.Mutex   // Serializes the changes to routes.
+	routes atomic.Value // *routes, which is never modified once it is stored.
+}
+
+// routes are the methods and the sub-dispatchers of a Router.
+type routes struct {
+	methods    PatternMatching
+	mounts     map[string]Dispatcher
+	middleware []Middleware
+	timeout    time.Duration
+
+	// trie holds the addresses of the methods.
+	// It is built the first time the routes dispatch a message.
+	trieOnce sync.Once
+	trie     *methodTrie
+}
+
+// NewRouter creates a router without any methods.
+func NewRouter() *Router {
+	r := &Router{}
+	r.routes.Store(&routes{methods: PatternMatching{}})
+	return r
+}
+
+// AddMethod adds a method, or replaces the one that has the same address.
+// The address is validated immediately, and can be a pattern or Default.
+// Addresses that a mounted sub-dispatcher would handle are rejected with ErrMountConflict.
+func (r *Router) AddMethod(addr string, method MessageHandler) error {
+	if method == nil {
+		return fmt.Errorf("nil method for %s", addr)
+	}
+	if addr != Default {
+		if err := validateAddressPattern(addr); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.snapshot()
+	if addr != Default {
+		for prefix := range current.mounts {
+			if overlapsPrefix(addr, prefix) {
+				return fmt.Errorf("method %s is under %s: %w", addr, prefix, ErrMountConflict)
+			}
+		}
+	}
+	next := current.copy()
+	next.methods[addr] = method
+	r.routes.Store(next)
+	return nil
+}
+
+// RemoveMethod removes the method with the given address, if there is one.
+// Messages that are being dispatched when it is removed may still be passed to it.
+func (r *Router) RemoveMethod(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.snapshot()
+	if _, ok := current.methods[addr]; !ok {
+		return
+	}
+	next := current.copy()
+	delete(next.methods, addr)
+	r.routes.Store(next)
+}
+
+// Mount passes the messages whose address is under prefix to sub,
+// with the prefix stripped from their address, or replaces the sub-dispatcher
+// that is already mounted at prefix.
+// For example a message to "/synth/1/freq" is passed to the sub-dispatcher
+// mounted at "/synth" with the address "/1/freq".
+//
+// The prefix must be a valid address without any pattern characters or a trailing '/'.
+// The address patterns of messages are still matched across the prefix,
+// so "/s*/1/freq" also reaches the sub-dispatcher mounted at "/synth".
+// A prefix that is above or below another mounted prefix, or that a method
+// of the router would handle, is rejected with ErrMountConflict.
+func (r *Router) Mount(prefix string, sub Dispatcher) error {
+	if err := checkDispatcher(sub); err != nil {
+		return fmt.Errorf("mount %s: %w", prefix, err)
+	}
+	if err := validateAddressPattern(prefix); err != nil {
+		return err
+	}
+	if isPattern(prefix) || strings.HasSuffix(prefix, string(MessageChar)) {
+		return &AddressError{Address: prefix, Reason: "is not a mount prefix, which must be an address without a trailing '/'"}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.snapshot()
+	for addr := range current.methods {
+		if addr != Default && overlapsPrefix(addr, prefix) {
+			return fmt.Errorf("method %s is under %s: %w", addr, prefix, ErrMountConflict)
+		}
+	}
+	for mounted := range current.mounts {
+		if mounted == prefix {
+			continue
+		}
+		if strings.HasPrefix(mounted, prefix+string(MessageChar)) || strings.HasPrefix(prefix, mounted+string(MessageChar)) {
+			return fmt.Errorf("%s overlaps %s: %w", prefix, mounted, ErrMountConflict)
+		}
+	}
+	next := current.copy()
+	next.mounts[prefix] = sub
+	r.routes.Store(next)
+	return nil
+}
+
+// Unmount removes the sub-dispatcher mounted at prefix, if there is one.
+func (r *Router) Unmount(prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := r.snapshot()
+	if _, ok := current.mounts[prefix]; !ok {
+		return
+	}
+	next := current.copy()
+	delete(next.mounts, prefix)
+	r.routes.Store(next)
+}
+
+// Use adds middleware that wraps every method the router invokes,
+// including the Default method and the sub-dispatchers it passes messages to.
+// The middleware runs in the order it was added, before the middleware of
+// any sub-dispatcher. The address of a sub-dispatcher's method is the prefix
+// it is mounted at.
+func (r *Router) Use(mws ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.snapshot().copy()
+	next.middleware = append(next.middleware, mws...)
+	r.routes.Store(next)
+}
+
+// SetTimeout sets how long each of the methods of the router, including the Default method,
+// may spend on a message before the router gives up on it and returns an error
+// wrapping ErrMethodTimeout. Zero, the default, lets methods take as long as they like.
+// Methods wrapped with WithTimeout keep their own timeout, and sub-dispatchers
+// are not affected.
+func (r *Router) SetTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.snapshot().copy()
+	next.timeout = d
+	r.routes.Store(next)
+}
+
+// Addresses returns the addresses of the methods.
+// The prefixes of the sub-dispatchers are not included.
+func (r *Router) Addresses() []string {
+	methods := r.snapshot().methods
+	addrs := make([]string, 0, len(methods))
+	for addr := range methods {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// Methods returns a copy of the methods, keyed by their address.
+func (r *Router) Methods() PatternMatching {
+	methods := r.snapshot().methods
+	copied := make(PatternMatching, len(methods))
+	for addr, method := range methods {
+		copied[addr] = method
+	}
+	return copied
+}
+
+// Mounts returns a copy of the sub-dispatchers, keyed by the prefix they are mounted at.
+func (r *Router) Mounts() map[string]Dispatcher {
+	mounts := r.snapshot().mounts
+	copied := make(map[string]Dispatcher, len(mounts))
+	for prefix, sub := range mounts {
+		copied[prefix] = sub
+	}
+	return copied
+}
+
+// Dispatch invokes an OSC bundle's messages with the methods the router has when it is called.
+func (r *Router) Dispatch(b Bundle, exactMatch bool) error {
+	return r.snapshot().Dispatch(b, exactMatch)
+}
+
+// Invoke invokes an OSC message with the methods the router has when it is called.
+func (r *Router) Invoke(msg Message, exactMatch bool) error {
+	return r.snapshot().Invoke(msg, exactMatch)
+}
+
+// snapshot returns the current routes, which must not be modified.
+func (r *Router) snapshot() *routes {
+	current, _ := r.routes.Load().(*routes)
+	if current == nil {
+		return &routes{}
+	}
+	return current
+}
+
+// copy returns a copy of the routes that can be modified.
+func (s *routes) copy() *routes {
+	next := &routes{
+		methods: make(PatternMatching, len(s.methods)+1),
+		mounts:  make(map[string]Dispatcher, len(s.mounts)+1),
+	}
+	for addr, method := range s.methods {
+		next.methods[addr] = method
+	}
+	for prefix, sub := range s.mounts {
+		next.mounts[prefix] = sub
+	}
+	next.middleware = append([]Middleware(nil), s.middleware...)
+	next.timeout = s.timeout
+	return next
+}
+
+// Dispatch invokes an OSC bundle's messages.
+func (s *routes) Dispatch(b Bundle, exactMatch bool) error {
+	waitTimetag(b.Timetag)
+	return invokeBundle(s, b, exactMatch)
+}
+
+// Invoke invokes the methods that match msg, and passes it to every sub-dispatcher
+// whose prefix it matches, in the order of their prefixes.
+// The Default method is only invoked if neither a method nor a prefix matches.
+func (s *routes) Invoke(msg Message, exactMatch bool) error {
+	matched, err := s.matching(msg, exactMatch)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, address := range matched {
+		if err := s.handle(msg, address, s.withTimeout(s.methods[address])); err != nil {
+			errs = append(errs, &DispatchError{Address: address, Err: err})
+		}
+	}
+	mounted := 0
+	for _, prefix := range s.prefixes() {
+		rest, ok := stripPrefix(msg.Address, prefix, exactMatch)
+		if !ok {
+			continue
+		}
+		mounted++
+		sub := s.mounts[prefix]
+		if err := s.handle(msg, prefix, Method(func(m Message) error {
+			m.Address, m.method = rest, ""
+			return sub.Invoke(m, exactMatch)
+		})); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(matched) == 0 && mounted == 0 {
+		if handler, ok := s.methods[Default]; ok {
+			if err := s.handle(msg, Default, s.withTimeout(handler)); err != nil {
+				return &DispatchError{Address: Default, Err: err}
+			}
+			return nil
+		}
+		return s.methods.unmatched(msg)
+	}
+	return joinErrors(errs)
+}
+
+// matching returns the sorted addresses of the methods that match msg, not counting Default.
+func (s *routes) matching(msg Message, exactMatch bool) ([]string, error) {
+	if exactMatch {
+		return s.methods.matching(msg, exactMatch)
+	}
+	s.trieOnce.Do(func() { s.trie = newMethodTrie(s.methods) })
+	return s.trie.matching(msg)
+}
+
+// handle passes msg to handler, which is registered at address, through the middleware.
+func (s *routes) handle(msg Message, address string, handler MessageHandler) error {
+	return handleMessage(chain(handler, s.middleware), msg, address)
+}
+
+// withTimeout returns handler with the timeout of the router, unless it has its own.
+func (s *routes) withTimeout(handler MessageHandler) MessageHandler {
+	if _, ok := handler.(timeoutHandler); ok || s.timeout <= 0 {
+		return handler
+	}
+	return timeoutHandler{timeout: s.timeout, handler: handler}
+}
+
+// prefixes returns the sorted prefixes of the sub-dispatchers.
+func (s *routes) prefixes() []string {
+	prefixes := make([]string, 0, len(s.mounts))
+	for prefix := range s.mounts {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// splitPrefix splits addr after as many parts as prefix has.
+// It returns false if addr has fewer parts.
+func splitPrefix(addr, prefix string) (head, rest string, ok bool) {
+	end := 0
+	for n := strings.Count(prefix, string(MessageChar)); n > 0; n-- {
+		if end >= len(addr) || addr[end] != MessageChar {
+			return "", "", false
+		}
+		i := strings.IndexByte(addr[end+1:], MessageChar)
+		if i == -1 {
+			end = len(addr)
+		} else {
+			end += i + 1
+		}
+	}
+	return addr[:end], addr[end:], true
+}
+
+// matchesPrefix returns true if head, the first parts of an address pattern,
+// matches prefix.
+func matchesPrefix(head, prefix string, exactMatch bool) bool {
+	if head == prefix {
+		return true
+	}
+	if exactMatch || !isPattern(head) {
+		return false
+	}
+	p, err := CompilePattern(head)
+	return err == nil && p.Match(prefix)
+}
+
+// stripPrefix returns the rest of the address pattern addr after prefix,
+// or false if addr is not under prefix.
+func stripPrefix(addr, prefix string, exactMatch bool) (string, bool) {
+	head, rest, ok := splitPrefix(addr, prefix)
+	if !ok || len(rest) == 0 || !matchesPrefix(head, prefix, exactMatch) {
+		return "", false
+	}
+	return rest, true
+}
+
+// overlapsPrefix returns true if a method at addr would handle messages under prefix.
+func overlapsPrefix(addr, prefix string) bool {
+	head, _, ok := splitPrefix(addr, prefix)
+	return ok && matchesPrefix(head, prefix, false)
+}
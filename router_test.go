@@ -0,0 +1,263 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouterAddRemove(t *testing.T) {
+	var (
+		r       Router
+		invoked []string
+	)
+	method := func(name string) Method {
+		return func(msg Message) error {
+			invoked = append(invoked, name)
+			return nil
+		}
+	}
+	if err := r.Invoke(Message{Address: "/a"}, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []string{"/a", "/b/*", Default} {
+		if err := r.AddMethod(addr, method(addr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Replacing a method.
+	if err := r.AddMethod("/a", method("/a again")); err != nil {
+		t.Fatal(err)
+	}
+	addrs := r.Addresses()
+	sort.Strings(addrs)
+	if expected, got := "* /a /b/*", strings.Join(addrs, " "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	for _, addr := range []string{"/a", "/b/c", "/c"} {
+		if err := r.Invoke(Message{Address: addr}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.RemoveMethod("/b/*")
+	r.RemoveMethod("/not/there")
+	if err := r.Dispatch(Bundle{Timetag: Immediately, Packets: []Packet{Message{Address: "/b/c"}}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "/a again /b/* * *", strings.Join(invoked, " "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestRouterAddMethodErrors(t *testing.T) {
+	r := NewRouter()
+	for i, addr := range []string{"/a[", "a", "/a b"} {
+		if err := r.AddMethod(addr, Method(func(msg Message) error { return nil })); !errors.Is(err, ErrInvalidAddress) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, ErrInvalidAddress, err)
+		}
+	}
+	if err := r.AddMethod("/a", nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(r.Addresses()) != 0 {
+		t.Fatalf("expected no methods, got %v", r.Addresses())
+	}
+}
+
+// Run with -race.
+func TestRouterConcurrent(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		r        = NewRouter()
+		received = make(chan struct{}, 1)
+		errChan  = make(chan error)
+	)
+	if err := r.AddMethod("/always", Method(func(msg Message) error {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		errChan <- c2.Serve(4, r)
+	}()
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				addr := fmt.Sprintf("/plugin/%d/%d", i, j%8)
+				if err := r.AddMethod(addr, Method(func(msg Message) error { return nil })); err != nil {
+					t.Error(err)
+					return
+				}
+				r.RemoveMethod(addr)
+			}
+		}(i)
+	}
+	deadline := time.After(200 * time.Millisecond)
+loop:
+	for {
+		select {
+		case <-deadline:
+			break loop
+		case err := <-errChan:
+			t.Fatalf("server stopped: %v", err)
+		default:
+		}
+		for _, addr := range []string{"/always", "/plugin/*/*", "/plugin/1/1"} {
+			if err := c1.Send(Message{Address: addr}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	select {
+	case <-received:
+	case err := <-errChan:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /always")
+	}
+	if expected, got := 1, len(r.Addresses()); expected != got {
+		t.Fatalf("expected %d methods, got %d", expected, got)
+	}
+}
+
+func TestRouterMount(t *testing.T) {
+	var (
+		invoked []string
+		top     = NewRouter()
+		synth   = NewRouter()
+		voices  = PatternMatching{}
+	)
+	method := func(name string) Method {
+		return func(msg Message) error {
+			invoked = append(invoked, name+" "+msg.Address)
+			return nil
+		}
+	}
+	voices["/1/freq"] = method("voice")
+	voices["/2/freq"] = method("voice")
+	if err := synth.AddMethod("/gain", method("synth")); err != nil {
+		t.Fatal(err)
+	}
+	if err := synth.Mount("/voices", voices); err != nil {
+		t.Fatal(err)
+	}
+	if err := top.Mount("/synth", synth); err != nil {
+		t.Fatal(err)
+	}
+	if err := top.Mount("/mixer/main", PatternMatching{"/level": method("mixer")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := top.AddMethod(Default, method("default")); err != nil {
+		t.Fatal(err)
+	}
+	for _, testcase := range []struct {
+		Address    string
+		ExactMatch bool
+	}{
+		{Address: "/synth/gain"},
+		{Address: "/synth/voices/1/freq"},
+		{Address: "/s*/voices/[0-9]/freq"},
+		{Address: "/synth/{voices,fx}/2/freq"},
+		{Address: "/mixer/main/level"},
+		{Address: "/mixer/*/level"},
+		{Address: "/*/gain"},
+		{Address: "/synth"},
+		{Address: "/mixer/aux/level"},
+		{Address: "/synth/voices/1/freq", ExactMatch: true},
+		{Address: "/s*/voices/1/freq", ExactMatch: true},
+	} {
+		if err := top.Invoke(Message{Address: testcase.Address}, testcase.ExactMatch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{
+		"synth /gain",
+		"voice /1/freq",
+		"voice /[0-9]/freq",
+		"voice /[0-9]/freq",
+		"voice /2/freq",
+		"mixer /level",
+		"mixer /level",
+		"synth /gain",
+		"default /synth",
+		"default /mixer/aux/level",
+		"voice /1/freq",
+		"default /s*/voices/1/freq",
+	}
+	if expected, got := strings.Join(expected, ", "), strings.Join(invoked, ", "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	top.Unmount("/synth")
+	invoked = nil
+	if err := top.Invoke(Message{Address: "/synth/gain"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "default /synth/gain", strings.Join(invoked, ", "); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestRouterMountConflicts(t *testing.T) {
+	noop := Method(func(msg Message) error { return nil })
+
+	r := NewRouter()
+	if err := r.AddMethod("/synth/gain", noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddMethod("/fx*", noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Mount("/mixer", PatternMatching{}); err != nil {
+		t.Fatal(err)
+	}
+	for i, prefix := range []string{"/synth", "/synth/gain", "/fx", "/mixer/main"} {
+		if err := r.Mount(prefix, PatternMatching{}); !errors.Is(err, ErrMountConflict) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, ErrMountConflict, err)
+		}
+	}
+	for i, addr := range []string{"/mixer", "/mixer/level", "/m*/level", "/*"} {
+		if err := r.AddMethod(addr, noop); !errors.Is(err, ErrMountConflict) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, ErrMountConflict, err)
+		}
+	}
+	for i, prefix := range []string{"/s*", "/synth/", "/", "synth", Default} {
+		if err := r.Mount(prefix, PatternMatching{}); !errors.Is(err, ErrInvalidAddress) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, ErrInvalidAddress, err)
+		}
+	}
+	if err := r.Mount("/x", nil); !errors.Is(err, ErrNilDispatcher) {
+		t.Fatalf("expected %v, got %v", ErrNilDispatcher, err)
+	}
+	// Replacing a sub-dispatcher and adding a method beside it.
+	if err := r.Mount("/mixer", PatternMatching{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddMethod("/mixers", noop); err != nil {
+		t.Fatal(err)
+	}
+}
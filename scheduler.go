@@ -0,0 +1,202 @@
+package osc
+
+import (
+	"container/heap"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// LatePolicy decides what the scheduler does with a bundle
+// whose timetag is already in the past when it arrives.
+type LatePolicy int
+
+// Late bundle policies.
+const (
+	// DispatchLate dispatches late bundles as soon as they arrive.
+	DispatchLate LatePolicy = iota
+
+	// DropLate discards late bundles.
+	DropLate
+)
+
+// clock tells the time and runs functions after a delay.
+// Tests replace it to make scheduling deterministic.
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) stopper
+}
+
+// stopper is a timer that can be stopped.
+type stopper interface {
+	Stop() bool
+}
+
+// realClock is the clock of the time package.
+type realClock struct{}
+
+// Now returns the current time.
+func (realClock) Now() time.Time { return time.Now() }
+
+// AfterFunc calls f in its own goroutine after d.
+func (realClock) AfterFunc(d time.Duration, f func()) stopper { return time.AfterFunc(d, f) }
+
+// scheduledBundle is a bundle waiting for its timetag.
+type scheduledBundle struct {
+	// due has a monotonic clock reading, so changes to the wall clock
+	// don't affect when the bundle is dispatched.
+	due    time.Time
+	seq    uint64
+	bundle Bundle
+}
+
+// scheduledBundles is a min-heap of bundles ordered by due time.
+// Bundles that are due at the same time keep the order they arrived in.
+type scheduledBundles []scheduledBundle
+
+func (q scheduledBundles) Len() int { return len(q) }
+
+func (q scheduledBundles) Less(i, j int) bool {
+	if q[i].due.Equal(q[j].due) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].due.Before(q[j].due)
+}
+
+func (q scheduledBundles) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *scheduledBundles) Push(x interface{}) { *q = append(*q, x.(scheduledBundle)) }
+
+func (q *scheduledBundles) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
+
+// scheduler holds bundles until their timetag and then dispatches them.
+// A single timer is armed for the earliest bundle.
+type scheduler struct {
+	clock       clock
+	dispatcher  Dispatcher
+	exactMatch  bool
+	handleError func(error)
+	countLate   func(b Bundle) // Counts a late bundle that is dropped.
+	latePolicy  LatePolicy
+	recover     bool // Whether panics in methods are recovered.
+	maxElements int  // How many elements a bundle may have to be scheduled.
+
+	mu      sync.Mutex
+	queue   scheduledBundles
+	seq     uint64
+	timer   stopper
+	stopped bool
+
+	// running tracks the bundles that are being dispatched.
+	running sync.WaitGroup
+}
+
+// schedule queues b if its timetag is in the future.
+// It returns false if b is late and should be dispatched now.
+// Late bundles are dropped, and schedule returns true, if the policy is DropLate.
+// Bundles with more elements than the limit are dropped too, and reported.
+func (s *scheduler) schedule(b Bundle) bool {
+	if s.maxElements > 0 {
+		if n := countElements(b); n > s.maxElements {
+			if s.handleError != nil {
+				s.handleError(&ParseError{Sender: b.Sender, Err: fmt.Errorf("schedule bundle of %d elements, more than %d: %w", n, s.maxElements, ErrTooManyElements)})
+			}
+			return true
+		}
+	}
+	now := s.clock.Now()
+	d := b.Timetag.Time().Sub(now)
+	if d <= 0 {
+		if s.latePolicy != DropLate {
+			return false
+		}
+		if s.countLate != nil {
+			s.countLate(b)
+		}
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return true
+	}
+	s.seq++
+	heap.Push(&s.queue, scheduledBundle{due: now.Add(d), seq: s.seq, bundle: b})
+	if s.queue[0].seq == s.seq {
+		s.arm(d)
+	}
+	return true
+}
+
+// arm replaces the timer with one that fires after d.
+// The caller must hold s.mu.
+func (s *scheduler) arm(d time.Duration) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.timer = s.clock.AfterFunc(d, s.fire)
+}
+
+// fire dispatches every bundle that is due and rearms the timer.
+func (s *scheduler) fire() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	now := s.clock.Now()
+	due := []Bundle{}
+	for len(s.queue) > 0 && !s.queue[0].due.After(now) {
+		due = append(due, heap.Pop(&s.queue).(scheduledBundle).bundle)
+	}
+	if len(s.queue) > 0 {
+		s.arm(s.queue[0].due.Sub(now))
+	}
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	defer s.running.Done()
+
+	for _, b := range due {
+		s.dispatch(b)
+	}
+}
+
+// dispatch dispatches a bundle whose time has come.
+func (s *scheduler) dispatch(b Bundle) {
+	sender := b.Sender
+
+	// The bundle is due, so don't let the dispatcher wait for it again.
+	b.Timetag = Immediately
+	if err := callMethods(s.recover, func() error { return s.dispatcher.Dispatch(b, s.exactMatch) }); err != nil {
+		s.report(sender, err)
+	}
+}
+
+// report passes an error returned from a method to the error handler.
+func (s *scheduler) report(sender net.Addr, err error) {
+	if s.handleError != nil {
+		s.handleError(&MethodError{Sender: sender, Err: fmt.Errorf("dispatch scheduled bundle: %w", err)})
+	}
+}
+
+// stop discards the pending bundles and waits for the ones
+// that are being dispatched.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.queue = nil
+	s.mu.Unlock()
+
+	s.running.Wait()
+}
@@ -0,0 +1,208 @@
+package osc
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when it is advanced.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a timer of a fakeClock.
+type fakeTimer struct {
+	clock   *fakeClock
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) stopper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+// Advance moves the clock forward and runs the timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	due := []*fakeTimer{}
+	for _, t := range c.timers {
+		if !t.stopped && !t.at.After(c.now) {
+			t.stopped = true
+			due = append(due, t)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// active returns the number of timers that have neither fired nor been stopped.
+func (c *fakeClock) active() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, t := range c.timers {
+		if !t.stopped {
+			n++
+		}
+	}
+	return n
+}
+
+// testScheduledPipe serves one end of a pipe with the scheduler enabled
+// and returns the other end and a channel that emits the address of every
+// message that gets dispatched.
+func testScheduledPipe(t *testing.T, clk *fakeClock, policy LatePolicy) (*PipeConn, *PipeConn, chan string, chan error) {
+	var (
+		c1, c2    = Pipe()
+		addresses = make(chan string, 16)
+		errChan   = make(chan error)
+		record    = Method(func(msg Message) error {
+			addresses <- msg.Address
+			return nil
+		})
+	)
+	c2.clock = clk
+	c2.SetScheduler(true)
+	c2.SetLatePolicy(policy)
+
+	go func() {
+		errChan <- c2.Serve(1, PatternMatching{"/a": record, "/b": record, "/late": record, "/now": record})
+	}()
+	return c1, c2, addresses, errChan
+}
+
+// expectAddresses checks that exactly the given addresses have been dispatched, in order.
+func expectAddresses(t *testing.T, addresses chan string, expected ...string) {
+	for _, addr := range expected {
+		select {
+		case got := <-addresses:
+			if got != addr {
+				t.Fatalf("expected %s, got %s", addr, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %s", addr)
+		}
+	}
+	select {
+	case got := <-addresses:
+		t.Fatalf("unexpected message %s", got)
+	default:
+	}
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	clk := newFakeClock()
+	c1, c2, addresses, errChan := testScheduledPipe(t, clk, DispatchLate)
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	for _, b := range []Bundle{
+		{Timetag: FromTime(clk.Now().Add(2 * time.Second)), Packets: []Packet{Message{Address: "/a"}}},
+		{Timetag: FromTime(clk.Now().Add(time.Second)), Packets: []Packet{Message{Address: "/b"}}},
+		{Timetag: Immediately, Packets: []Packet{Message{Address: "/now"}}},
+	} {
+		if err := c1.Send(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The other bundles are queued by the time the last one is dispatched.
+	expectAddresses(t, addresses, "/now")
+
+	clk.Advance(time.Second)
+	expectAddresses(t, addresses, "/b")
+
+	clk.Advance(time.Second)
+	expectAddresses(t, addresses, "/a")
+
+	if err := c1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitServe(t, errChan); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerLatePolicy(t *testing.T) {
+	for i, testcase := range []struct {
+		Policy   LatePolicy
+		Expected []string
+	}{
+		{Policy: DispatchLate, Expected: []string{"/late", "/now"}},
+		{Policy: DropLate, Expected: []string{"/now"}},
+	} {
+		clk := newFakeClock()
+		c1, c2, addresses, _ := testScheduledPipe(t, clk, testcase.Policy)
+
+		for _, b := range []Bundle{
+			{Timetag: FromTime(clk.Now().Add(-time.Second)), Packets: []Packet{Message{Address: "/late"}}},
+			{Timetag: Immediately, Packets: []Packet{Message{Address: "/now"}}},
+		} {
+			if err := c1.Send(b); err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+		}
+		expectAddresses(t, addresses, testcase.Expected...)
+		_ = c2.Close() // Best effort.
+	}
+}
+
+func TestSchedulerClose(t *testing.T) {
+	clk := newFakeClock()
+	c1, c2, addresses, errChan := testScheduledPipe(t, clk, DispatchLate)
+
+	for _, b := range []Bundle{
+		{Timetag: FromTime(clk.Now().Add(time.Second)), Packets: []Packet{Message{Address: "/a"}}},
+		{Timetag: Immediately, Packets: []Packet{Message{Address: "/now"}}},
+	} {
+		if err := c1.Send(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectAddresses(t, addresses, "/now")
+
+	if err := c2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitServe(t, errChan); err != nil {
+		t.Fatal(err)
+	}
+	if n := clk.active(); n != 0 {
+		t.Fatalf("expected no pending timers, got %d", n)
+	}
+	clk.Advance(time.Second)
+	expectAddresses(t, addresses)
+}
@@ -0,0 +1,61 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ServeAll serves d on every one of conns at once, e.g. on a UDP port for
+// the controllers of the network and on a unix socket for the local tools.
+// Each conn is served by ServeContext with a single worker, so the messages
+// it reads are dispatched in order, and its methods get the senders of
+// the messages that conn read, which they reply to through that conn.
+//
+// The first conn whose Serve fails stops the others, and ServeAll returns
+// the errors of all of the conns that failed once every one of them has stopped.
+// A conn that is closed stops without an error and leaves the others serving.
+// When ctx is done every conn stops reading, the methods that are running
+// return, and ServeAll returns ctx.Err(). The conns are left open.
+func ServeAll(ctx context.Context, d Dispatcher, conns ...Conn) error {
+	if len(conns) == 0 {
+		return errors.New("no conns to serve")
+	}
+	if err := checkDispatcher(d); err != nil {
+		return err
+	}
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn Conn) {
+			defer wg.Done()
+
+			err := conn.ServeContext(serveCtx, 1, d)
+			if err == nil {
+				return
+			}
+			// The conns that were stopped, by ctx or by another conn, are not failures.
+			if serveCtx.Err() != nil && errors.Is(err, serveCtx.Err()) {
+				return
+			}
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("serving %s: %w", conn.LocalAddr(), err))
+			mu.Unlock()
+			cancel()
+		}(conn)
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// listenLoopback returns n UDP servers on the loopback address.
+func listenLoopback(t *testing.T, n int) []Conn {
+	t.Helper()
+
+	conns := make([]Conn, n)
+	for i := range conns {
+		server, err := Listen("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = server
+	}
+	return conns
+}
+
+func TestServeAll(t *testing.T) {
+	var (
+		servers = listenLoopback(t, 2)
+		msgs    = make(chan Message, 2)
+		d       = PatternMatching{"/hello": ReplyMethod(func(msg Message) (*Message, error) {
+			msgs <- msg
+			reply := MustMessage("/hello.reply")
+			return &reply, nil
+		})}
+		ctx, cancel = context.WithCancel(context.Background())
+		serving     = make(chan error, 1)
+	)
+	defer cancel()
+	for _, server := range servers {
+		defer func(server Conn) { _ = server.Close() }(server) // Best effort.
+	}
+	go func() {
+		serving <- ServeAll(ctx, d, servers...)
+	}()
+
+	for i, server := range servers {
+		client, err := Dial(server.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Send(MustMessage("/hello")); err != nil {
+			t.Fatal(err)
+		}
+		// The method gets the sender that the conn read the message from.
+		if got := waitMessage(t, msgs, serving); got.Sender.String() != client.LocalAddr().String() {
+			t.Fatalf("(testcase %d) expected the sender %s, got %s", i, client.LocalAddr(), got.Sender)
+		}
+		rctx, rcancel := context.WithTimeout(context.Background(), 2*time.Second)
+		p, sender, err := client.ReceivePacket(rctx)
+		rcancel()
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if sender.String() != server.LocalAddr().String() || p.(Message).Address != "/hello.reply" {
+			t.Fatalf("(testcase %d) expected /hello.reply from %s, got %s from %s", i, server.LocalAddr(), p, sender)
+		}
+		_ = client.Close() // Best effort.
+	}
+
+	cancel()
+	select {
+	case err := <-serving:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for ServeAll to return")
+	}
+}
+
+func TestServeAll_Errors(t *testing.T) {
+	servers := listenLoopback(t, 2)
+	for _, server := range servers {
+		defer func(server Conn) { _ = server.Close() }(server) // Best effort.
+	}
+	d := PatternMatching{"/hello": Method(func(msg Message) error { return nil })}
+
+	if err := ServeAll(context.Background(), d); err == nil {
+		t.Fatal("expected an error without conns")
+	}
+	if err := ServeAll(context.Background(), nil, servers...); !errors.Is(err, ErrNilDispatcher) {
+		t.Fatalf("expected ErrNilDispatcher, got %v", err)
+	}
+
+	// A packet that can not be parsed stops the strict conn, and the other one with it.
+	strict := servers[0].(*UDPConn)
+	strict.SetStrict(true)
+	serving := make(chan error, 1)
+	go func() {
+		serving <- ServeAll(context.Background(), d, servers...)
+	}()
+	time.Sleep(10 * time.Millisecond) // Let ServeAll start.
+
+	client, err := Dial(strict.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	if _, err := client.udpConn.Write([]byte("garbage!")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-serving:
+		var pe *ParseError
+		if !errors.As(err, &pe) || errors.Is(err, context.Canceled) {
+			t.Fatalf("expected only the *ParseError, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for ServeAll to return")
+	}
+	// Both conns are still open.
+	for i, server := range servers {
+		select {
+		case <-server.(*UDPConn).CloseChan():
+			t.Fatalf("(testcase %d) expected the conn to be open", i)
+		default:
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package osc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// SessionVersion is the version of the session log format that SessionWriter writes.
+//
+// A session log starts with a header of the 12 bytes "osc-session\x00" and the version
+// as a big-endian uint32. Every packet that follows is a big-endian uint32 size of
+// the rest of the entry, and then:
+//
+//	direction       1 byte, 1 for Inbound and 2 for Outbound
+//	time            int64, the wall clock time in nanoseconds since the Unix epoch
+//	elapsed         int64, the nanoseconds since the recording started, of the monotonic clock
+//	network         uint16 size, then the bytes of the network of the address
+//	address         uint16 size, then the bytes of the address
+//	packet          the rest of the entry, as it was read or sent
+const SessionVersion = 1
+
+// sessionMagic starts every session log.
+const sessionMagic = "osc-session\x00"
+
+// Errors of session logs.
+var (
+	ErrNotSessionLog      = errors.New("not an OSC session log")
+	ErrSessionVersion     = errors.New("unsupported OSC session log version")
+	ErrNoHooks            = errors.New("conn does not have send and receive hooks")
+	errSessionEntryFormat = errors.New("malformed session log entry")
+)
+
+// Direction is whether a packet of a session was read or sent.
+type Direction byte
+
+// The directions of the packets of a session.
+const (
+	Inbound  Direction = 1
+	Outbound Direction = 2
+)
+
+// String returns "inbound" or "outbound".
+func (d Direction) String() string {
+	switch d {
+	case Inbound:
+		return "inbound"
+	case Outbound:
+		return "outbound"
+	default:
+		return fmt.Sprintf("Direction(%d)", byte(d))
+	}
+}
+
+// SessionEntry is a packet of a session log.
+type SessionEntry struct {
+	Direction Direction
+
+	// Addr is the address an inbound packet came from, or the one an outbound
+	// packet was sent to. It is nil if there was none, like for the peer of a connected conn.
+	Addr net.Addr
+
+	// Time is the wall clock time the packet was read or sent,
+	// and Elapsed the time since the recording started, which the changes
+	// to the wall clock during the recording do not affect.
+	Time    time.Time
+	Elapsed time.Duration
+
+	// Data is the packet, as it was read or sent.
+	Data []byte
+}
+
+// sessionAddr is an address read from a session log.
+type sessionAddr struct {
+	network, address string
+}
+
+// Network returns the name of the network.
+func (a sessionAddr) Network() string { return a.network }
+
+// String returns the string form of the address.
+func (a sessionAddr) String() string { return a.address }
+
+// Recorder records the traffic of a conn to a session log, e.g. everything a lighting console
+// sent during a show, so that it can be replayed by a Player. It is the conn it wraps,
+// so the packets can be sent with it or with the conn.
+//
+// Every packet that the conn reads is recorded, before it is parsed, and so is every packet
+// that it sends, once it was sent. The recorder uses the send and receive hooks of the conn,
+// which must not be replaced while it records.
+type Recorder struct {
+	Conn
+
+	hooks interface {
+		SetRecvHook(hook func(addr net.Addr, data []byte))
+		SetSendHook(hook func(addr net.Addr, p Packet, n int, err error))
+	}
+	start time.Time
+
+	mu      sync.Mutex
+	w       *SessionWriter
+	stopped bool
+	err     error
+}
+
+// NewRecorder writes the header of a session log to w, and records the traffic of conn to it
+// with a SessionWriter until Stop or Close is called. Every entry is written to w with
+// a single call to Write, and w is not closed. The conns of this package all have the hooks
+// that it needs, and other conns fail with ErrNoHooks.
+func NewRecorder(conn Conn, w io.Writer) (*Recorder, error) {
+	r := &Recorder{Conn: conn, start: time.Now()}
+	var ok bool
+	if r.hooks, ok = conn.(interface {
+		SetRecvHook(hook func(addr net.Addr, data []byte))
+		SetSendHook(hook func(addr net.Addr, p Packet, n int, err error))
+	}); !ok {
+		return nil, fmt.Errorf("record %T: %w", conn, ErrNoHooks)
+	}
+	sw, err := NewSessionWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	r.w = sw
+	r.hooks.SetRecvHook(func(addr net.Addr, data []byte) {
+		r.record(Inbound, addr, data)
+	})
+	r.hooks.SetSendHook(func(addr net.Addr, p Packet, n int, err error) {
+		if err == nil {
+			r.record(Outbound, addr, p.Bytes())
+		}
+	})
+	return r, nil
+}
+
+// record writes an entry for a packet, unless an earlier write failed.
+func (r *Recorder) record(d Direction, addr net.Addr, data []byte) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil || r.stopped {
+		return
+	}
+	r.err = r.w.Write(SessionEntry{Direction: d, Addr: addr, Time: now, Elapsed: now.Sub(r.start), Data: data})
+}
+
+// Err returns the error of the first write that failed, after which nothing is recorded.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Stop stops recording and removes the hooks of the conn, which stays open.
+// It returns Err. Stopping the recorder again does nothing.
+func (r *Recorder) Stop() error {
+	r.hooks.SetRecvHook(nil)
+	r.hooks.SetSendHook(nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopped = true
+	return r.err
+}
+
+// Close stops recording and closes the conn.
+func (r *Recorder) Close() error {
+	return errors.Join(r.Stop(), r.Conn.Close())
+}
+
+// SessionWriter writes a session log, e.g. of the packets of a capture,
+// for a Player to replay. Recorder writes its logs with one.
+type SessionWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewSessionWriter writes the header of a session log to w.
+func NewSessionWriter(w io.Writer) (*SessionWriter, error) {
+	if _, err := w.Write(appendUint32([]byte(sessionMagic), SessionVersion)); err != nil {
+		return nil, fmt.Errorf("write session log header: %w", err)
+	}
+	return &SessionWriter{w: w}, nil
+}
+
+// Write writes an entry with a single call to the Write method of the writer.
+// It is not safe for concurrent use.
+func (sw *SessionWriter) Write(e SessionEntry) error {
+	if e.Direction != Inbound && e.Direction != Outbound {
+		return fmt.Errorf("session log entry with direction %s: %w", e.Direction, errSessionEntryFormat)
+	}
+	var network, address string
+	if e.Addr != nil {
+		network, address = e.Addr.Network(), e.Addr.String()
+	}
+	buf := append(sw.buf[:0], 0, 0, 0, 0, byte(e.Direction))
+	buf = appendUint64(buf, uint64(e.Time.UnixNano()))
+	buf = appendUint64(buf, uint64(e.Elapsed))
+	buf = appendSessionString(buf, network)
+	buf = appendSessionString(buf, address)
+	buf = append(buf, e.Data...)
+	byteOrder.PutUint32(buf, uint32(len(buf)-4))
+	sw.buf = buf
+
+	if _, err := sw.w.Write(buf); err != nil {
+		return fmt.Errorf("write session log entry: %w", err)
+	}
+	return nil
+}
+
+// appendSessionString appends s with its uint16 size.
+func appendSessionString(b []byte, s string) []byte {
+	if len(s) > 0xFFFF {
+		s = s[:0xFFFF]
+	}
+	return append(append(b, byte(len(s)>>8), byte(len(s))), s...)
+}
+
+// SessionReader reads the entries of a session log.
+type SessionReader struct {
+	r       *bufio.Reader
+	version uint32
+}
+
+// NewSessionReader reads the header of a session log from r. A log that does not
+// start with the header fails with ErrNotSessionLog, and a log of a later version
+// with ErrSessionVersion.
+func NewSessionReader(r io.Reader) (*SessionReader, error) {
+	sr := &SessionReader{r: bufio.NewReader(r)}
+	header := make([]byte, len(sessionMagic)+4)
+	if _, err := io.ReadFull(sr.r, header); err != nil {
+		return nil, fmt.Errorf("read session log header: %w: %w", ErrNotSessionLog, err)
+	}
+	if !bytes.Equal(header[:len(sessionMagic)], []byte(sessionMagic)) {
+		return nil, fmt.Errorf("session log header %q: %w", header, ErrNotSessionLog)
+	}
+	if sr.version = byteOrder.Uint32(header[len(sessionMagic):]); sr.version == 0 || sr.version > SessionVersion {
+		return nil, fmt.Errorf("session log version %d: %w", sr.version, ErrSessionVersion)
+	}
+	return sr, nil
+}
+
+// Version returns the version of the format of the log.
+func (sr *SessionReader) Version() int { return int(sr.version) }
+
+// Next returns the next entry of the log, or io.EOF at its end.
+// A log that ends in the middle of an entry fails with io.ErrUnexpectedEOF.
+func (sr *SessionReader) Next() (SessionEntry, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(sr.r, size[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return SessionEntry{}, fmt.Errorf("read session log entry size: %w", err)
+		}
+		return SessionEntry{}, err
+	}
+	data := make([]byte, byteOrder.Uint32(size[:]))
+	if _, err := io.ReadFull(sr.r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return SessionEntry{}, fmt.Errorf("read session log entry of %d bytes: %w", len(data), err)
+	}
+	e, err := parseSessionEntry(data)
+	if err != nil {
+		return SessionEntry{}, fmt.Errorf("session log entry of %d bytes: %w: %w", len(data), errSessionEntryFormat, err)
+	}
+	return e, nil
+}
+
+// parseSessionEntry parses an entry without its size.
+func parseSessionEntry(data []byte) (SessionEntry, error) {
+	if len(data) < 17 {
+		return SessionEntry{}, io.ErrUnexpectedEOF
+	}
+	e := SessionEntry{
+		Direction: Direction(data[0]),
+		Time:      time.Unix(0, int64(byteOrder.Uint64(data[1:]))),
+		Elapsed:   time.Duration(byteOrder.Uint64(data[9:])),
+	}
+	if e.Direction != Inbound && e.Direction != Outbound {
+		return SessionEntry{}, fmt.Errorf("direction %d", data[0])
+	}
+	data = data[17:]
+	network, data, err := readSessionString(data)
+	if err != nil {
+		return SessionEntry{}, fmt.Errorf("network: %w", err)
+	}
+	address, data, err := readSessionString(data)
+	if err != nil {
+		return SessionEntry{}, fmt.Errorf("address: %w", err)
+	}
+	if network != "" || address != "" {
+		e.Addr = sessionAddr{network: network, address: address}
+	}
+	e.Data = data
+	return e, nil
+}
+
+// readSessionString reads a string with its uint16 size, and returns the data after it.
+func readSessionString(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	n := int(data[0])<<8 | int(data[1])
+	if len(data) < 2+n {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	return string(data[2 : 2+n]), data[2+n:], nil
+}
+
+// PlayOptions are the options of a Player.
+type PlayOptions struct {
+	// Speed is how much faster than they were recorded the packets are sent,
+	// e.g. 2 for twice as fast. The default is 1.
+	Speed float64
+
+	// RewriteTimetags makes the timetags of the bundles that are sent, the ones of nested
+	// bundles too, as far from the time they are sent as they were from the time they were
+	// recorded, so that they are not in the past. Timetags that are Immediately are kept.
+	RewriteTimetags bool
+
+	// Outbound makes the player send the packets that were sent by the recorded conn too.
+	// Otherwise only the ones that it read are sent.
+	Outbound bool
+
+	// To is the address the packets are sent to.
+	// If it is nil they are sent to the peer of the conn.
+	To net.Addr
+}
+
+// Player sends the packets of a session log through a conn, e.g. to replay to a receiver
+// what a console sent it during a show, with the same timing between the packets.
+// The packets are sent as they were read, even if they are not valid OSC.
+type Player struct {
+	conn Conn
+	opts PlayOptions
+}
+
+// NewPlayer returns a Player that sends the packets with conn.
+func NewPlayer(conn Conn, opts PlayOptions) *Player {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	return &Player{conn: conn, opts: opts}
+}
+
+// Play reads the session log from r, and sends its packets with the same time between them
+// as when they were recorded, divided by the speed. The first packet is sent at once.
+// It returns the number of packets that were sent, and stops at the first error,
+// or with ctx.Err() if ctx is done while it waits.
+func (p *Player) Play(ctx context.Context, r io.Reader) (int, error) {
+	sr, err := NewSessionReader(r)
+	if err != nil {
+		return 0, err
+	}
+	send := p.conn.Send
+	if p.opts.To != nil {
+		send = func(pkt Packet) error { return p.conn.SendTo(p.opts.To, pkt) }
+	}
+	var (
+		start time.Time
+		first time.Duration
+		sent  int
+	)
+	for {
+		e, err := sr.Next()
+		if errors.Is(err, io.EOF) {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, err
+		}
+		if e.Direction == Outbound && !p.opts.Outbound {
+			continue
+		}
+		if sent == 0 {
+			start, first = time.Now(), e.Elapsed
+		} else if err := sleepContext(ctx, time.Until(start.Add(time.Duration(float64(e.Elapsed-first)/p.opts.Speed)))); err != nil {
+			return sent, err
+		}
+		data := e.Data
+		if p.opts.RewriteTimetags {
+			data = shiftTimetags(data, time.Since(e.Time))
+		}
+		if err := send(rawPacket(data)); err != nil {
+			return sent, fmt.Errorf("send packet %d of the session: %w", sent, err)
+		}
+		sent++
+	}
+}
+
+// sleepContext waits for d, or returns ctx.Err() if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// shiftTimetags returns a copy of data with the timetags of the bundle and of the bundles
+// nested in it moved by d, or data if it is not a bundle.
+func shiftTimetags(data []byte, d time.Duration) []byte {
+	if _, err := sliceBundleTag(data); err != nil {
+		return data
+	}
+	data = append([]byte(nil), data...)
+	shiftBundleTimetags(data, d)
+	return data
+}
+
+// shiftBundleTimetags moves the timetags of the encoded bundle in place.
+func shiftBundleTimetags(data []byte, d time.Duration) {
+	offset := stringSize(BundleTag)
+	if len(data) < offset+TimetagSize {
+		return
+	}
+	if tt := Timetag(byteOrder.Uint64(data[offset:])); tt != Immediately {
+		byteOrder.PutUint64(data[offset:], uint64(FromTime(tt.Time().Add(d))))
+	}
+	for offset += TimetagSize; offset+4 <= len(data); {
+		size := int(byteOrder.Uint32(data[offset:]))
+		offset += 4
+		if size < 0 || offset+size > len(data) {
+			return
+		}
+		if el := data[offset : offset+size]; bytes.HasPrefix(el, []byte(BundleTag+"\x00")) {
+			shiftBundleTimetags(el, d)
+		}
+		offset += size
+	}
+}
+
+// rawPacket is an encoded packet that is sent as it is.
+type rawPacket []byte
+
+// Bytes returns the encoded packet.
+func (p rawPacket) Bytes() []byte { return p }
+
+// Equal returns true if other has the same encoding.
+func (p rawPacket) Equal(other Packet) bool { return bytes.Equal(p, other.Bytes()) }
@@ -0,0 +1,138 @@
+package osc_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/scgolang/osc"
+	"github.com/scgolang/osc/osctest"
+)
+
+func TestRecorderPlayer(t *testing.T) {
+	// A console sends a scripted session to a show, which answers the pings.
+	var log bytes.Buffer
+	show := osctest.NewRecorder(nil)
+	server := osctest.NewServer(t, show)
+	recorder, err := osc.NewRecorder(server, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	console := server.Dial()
+
+	script := []struct {
+		Packet osc.Packet
+		Delay  time.Duration
+	}{
+		{Packet: osc.MustMessage("/cue/go", int32(1))},
+		{Packet: osc.MustMessage("/fader/1", float32(0.5)), Delay: 40 * time.Millisecond},
+		{Packet: osc.Bundle{
+			Timetag: osc.FromTime(time.Now().Add(time.Second)),
+			Packets: []osc.Packet{osc.MustMessage("/fader/1", float32(0.75)), osc.MustMessage("/cue/go", int32(2))},
+		}, Delay: 40 * time.Millisecond},
+		{Packet: osc.MustMessage("/blackout"), Delay: 80 * time.Millisecond},
+	}
+	for _, step := range script {
+		time.Sleep(step.Delay)
+		if err := console.Send(step.Packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := show.WaitFor("/blackout", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// A send that fails is not recorded.
+	if err := server.Send(osc.MustMessage("/reply")); err == nil {
+		t.Fatal("expected an error sending without a peer, got nil")
+	}
+	if err := server.SendTo(console.LocalAddr(), osc.MustMessage("/reply")); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log has the packets of the console, and the reply of the show.
+	data := log.Bytes()
+	sr, err := osc.NewSessionReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []osc.SessionEntry
+	for {
+		e, err := sr.Next()
+		if err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != len(script)+1 {
+		t.Fatalf("expected %d entries, got %d", len(script)+1, len(entries))
+	}
+	for i, step := range script {
+		if e := entries[i]; e.Direction != osc.Inbound || !bytes.Equal(e.Data, step.Packet.Bytes()) {
+			t.Fatalf("(entry %d) expected inbound %q, got %s %q", i, step.Packet.Bytes(), e.Direction, e.Data)
+		}
+	}
+	if e := entries[len(script)]; e.Direction != osc.Outbound || e.Addr.String() != console.LocalAddr().String() {
+		t.Fatalf("expected the reply to %s, got %s to %s", console.LocalAddr(), e.Direction, e.Addr)
+	}
+
+	// Replaying the log twice as fast sends the same messages in the same order,
+	// about half as far apart.
+	replay := osctest.NewRecorder(nil)
+	player := osc.NewPlayer(osctest.NewServer(t, replay).Dial(), osc.PlayOptions{Speed: 2, RewriteTimetags: true})
+	start := time.Now()
+	n, err := player.Play(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(script) {
+		t.Fatalf("expected %d packets to be sent, got %d", len(script), n)
+	}
+	if elapsed, recorded := time.Since(start), entries[len(script)-1].Elapsed-entries[0].Elapsed; elapsed < recorded/2 {
+		t.Fatalf("expected the replay to take at least %s, it took %s", recorded/2, elapsed)
+	}
+	if _, err := replay.WaitFor("/blackout", 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	expected, got := show.Messages(), replay.Messages()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(got))
+	}
+	for i := range expected {
+		osctest.AssertPacket(t, got[i], expected[i])
+	}
+	records := replay.Records()
+	if gap := records[1].Time.Sub(records[0].Time); gap < 10*time.Millisecond {
+		t.Fatalf("expected the second message about 20ms after the first, got %s", gap)
+	}
+}
+
+func TestPlayer_Cancel(t *testing.T) {
+	var log bytes.Buffer
+	server := osctest.NewServer(t, osctest.NewRecorder(nil))
+	recorder, err := osc.NewRecorder(server, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	console := server.Dial()
+	for _, delay := range []time.Duration{0, 500 * time.Millisecond} {
+		time.Sleep(delay)
+		if err := console.Send(osc.MustMessage("/cue/go")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	player := osc.NewPlayer(osctest.NewServer(t, osctest.NewRecorder(nil)).Dial(), osc.PlayOptions{})
+	if n, err := player.Play(ctx, &log); err != context.DeadlineExceeded || n != 1 {
+		t.Fatalf("expected 1 packet and context.DeadlineExceeded, got %d and %v", n, err)
+	}
+}
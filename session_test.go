@@ -0,0 +1,225 @@
+package osc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		c1, c2 = Pipe()
+		ping   = MustMessage("/ping", int32(1))
+		pong   = MustMessage("/pong", "x")
+	)
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	r, err := NewRecorder(c2, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := c1.Send(ping); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.ReceivePacket(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Send(pong); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c2.Send(ping); err != nil { // Not recorded.
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sr, err := NewSessionReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sr.Version() != SessionVersion {
+		t.Fatalf("expected version %d, got %d", SessionVersion, sr.Version())
+	}
+	for i, expected := range []struct {
+		Direction Direction
+		Packet    Packet
+	}{
+		{Direction: Inbound, Packet: ping},
+		{Direction: Outbound, Packet: pong},
+	} {
+		e, err := sr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Direction != expected.Direction || !bytes.Equal(e.Data, expected.Packet.Bytes()) {
+			t.Fatalf("(entry %d) expected %s %q, got %s %q", i, expected.Direction, expected.Packet.Bytes(), e.Direction, e.Data)
+		}
+		if e.Addr == nil || e.Addr.Network() != "pipe" {
+			t.Fatalf("(entry %d) expected a pipe address, got %v", i, e.Addr)
+		}
+		if e.Time.Before(start.Add(-time.Second)) || e.Elapsed < 0 || e.Elapsed > time.Minute {
+			t.Fatalf("(entry %d) unexpected time %s and elapsed %s", i, e.Time, e.Elapsed)
+		}
+	}
+	if _, err := sr.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestRecorder_WriteError(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	r, err := NewRecorder(c2, &errWriter{erridx: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }() // Best effort.
+
+	for i := 0; i < 2; i++ {
+		if err := r.Send(MustMessage("/foo")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Err(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if _, err := NewRecorder(c2, &errWriter{erridx: 1}); err == nil {
+		t.Fatal("expected error writing the header, got nil")
+	}
+	if _, err := NewRecorder(struct{ Conn }{c2}, io.Discard); !errors.Is(err, ErrNoHooks) {
+		t.Fatalf("expected ErrNoHooks, got %v", err)
+	}
+}
+
+func TestSessionReader(t *testing.T) {
+	header := appendUint32([]byte(sessionMagic), SessionVersion)
+	entry := append([]byte{0, 0, 0, 21, byte(Inbound)}, make([]byte, 16)...)
+	entry = append(entry, 0, 0, 0, 0)
+	for i, testcase := range []struct {
+		Log  []byte
+		Err  error // Of NewSessionReader, or of Next if NewSessionReader succeeds.
+		Next int   // Number of entries that are read before Err.
+	}{
+		{Log: nil, Err: ErrNotSessionLog},
+		{Log: []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01"), Err: ErrNotSessionLog},
+		{Log: appendUint32([]byte(sessionMagic), SessionVersion+1), Err: ErrSessionVersion},
+		{Log: header, Err: io.EOF},
+		{Log: append(header, entry...), Err: io.EOF, Next: 1},
+		{Log: append(header, entry[:3]...), Err: io.ErrUnexpectedEOF},
+		{Log: append(header, entry[:10]...), Err: io.ErrUnexpectedEOF},
+		{Log: append(append(header, entry...), 0, 0, 0, 1, byte(Outbound)), Err: errSessionEntryFormat, Next: 1},
+		{Log: append(header, append([]byte{0, 0, 0, 21, 3}, entry[5:]...)...), Err: errSessionEntryFormat},
+	} {
+		sr, err := NewSessionReader(bytes.NewReader(testcase.Log))
+		if err != nil {
+			if !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+			}
+			continue
+		}
+		for n := 0; n < testcase.Next; n++ {
+			e, err := sr.Next()
+			if err != nil {
+				t.Fatalf("(testcase %d) entry %d: %v", i, n, err)
+			}
+			if e.Addr != nil || len(e.Data) != 0 {
+				t.Fatalf("(testcase %d) expected an empty entry, got %+v", i, e)
+			}
+		}
+		if _, err := sr.Next(); !errors.Is(err, testcase.Err) {
+			t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+		}
+	}
+}
+
+func TestShiftTimetags(t *testing.T) {
+	var (
+		now    = time.Unix(1700000000, 0)
+		msg    = MustMessage("/foo", int32(1))
+		nested = Bundle{
+			Timetag: FromTime(now),
+			Packets: []Packet{
+				msg,
+				Bundle{Timetag: FromTime(now.Add(time.Second)), Packets: []Packet{msg}},
+				Bundle{Timetag: Immediately},
+			},
+		}
+		expected = Bundle{
+			Timetag: FromTime(now.Add(time.Minute)),
+			Packets: []Packet{
+				msg,
+				Bundle{Timetag: FromTime(now.Add(time.Minute + time.Second)), Packets: []Packet{msg}},
+				Bundle{Timetag: Immediately},
+			},
+		}
+		data = nested.Bytes()
+	)
+	if got := shiftTimetags(data, time.Minute); !bytes.Equal(got, expected.Bytes()) {
+		t.Fatalf("expected %x, got %x", expected.Bytes(), got)
+	}
+	if !bytes.Equal(data, nested.Bytes()) {
+		t.Fatal("expected the bundle to be copied")
+	}
+	if got := shiftTimetags(msg.Bytes(), time.Minute); !bytes.Equal(got, msg.Bytes()) {
+		t.Fatalf("expected the message as it is, got %x", got)
+	}
+}
+
+func TestDirectionString(t *testing.T) {
+	for i, testcase := range []struct {
+		Direction Direction
+		Expected  string
+	}{
+		{Direction: Inbound, Expected: "inbound"},
+		{Direction: Outbound, Expected: "outbound"},
+		{Direction: 7, Expected: "Direction(7)"},
+	} {
+		if got := testcase.Direction.String(); got != testcase.Expected {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, testcase.Expected, got)
+		}
+	}
+}
+
+func TestSessionWriter(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		e   = SessionEntry{
+			Direction: Inbound,
+			Addr:      sessionAddr{network: "udp", address: "192.0.2.1:8000"},
+			Time:      time.Unix(1700000000, 5),
+			Elapsed:   3 * time.Millisecond,
+			Data:      MustMessage("/foo").Bytes(),
+		}
+	)
+	sw, err := NewSessionWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Write(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Write(SessionEntry{}); !errors.Is(err, errSessionEntryFormat) {
+		t.Fatalf("expected errSessionEntryFormat, got %v", err)
+	}
+	sr, err := NewSessionReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Direction != e.Direction || got.Addr != e.Addr || !got.Time.Equal(e.Time) || got.Elapsed != e.Elapsed || !bytes.Equal(got.Data, e.Data) {
+		t.Fatalf("expected %+v, got %+v", e, got)
+	}
+}
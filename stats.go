@@ -0,0 +1,155 @@
+package osc
+
+import (
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the counters of a conn.
+// The counters only ever increase, from when the conn was created.
+type Stats struct {
+	// PacketsReceived and BytesReceived count the packets that were read,
+	// including the packets that are dropped before they are parsed.
+	PacketsReceived uint64
+	BytesReceived   uint64
+
+	// PacketsSent and BytesSent count the packets that were sent,
+	// and SendErrors the packets that could not be sent.
+	PacketsSent uint64
+	BytesSent   uint64
+	SendErrors  uint64
+
+	// ParseErrors counts the packets that could not be parsed, DispatchErrors
+	// the errors returned by methods or dispatchers, including the messages that
+	// no method matched with the ErrorUnmatched policy, and OtherErrors the rest
+	// of the errors passed to the error handler, whether or not there is one.
+	ParseErrors    uint64
+	DispatchErrors uint64
+	OtherErrors    uint64
+
+	// Dropped, Unmatched, Rejected and RateLimited are the counts of the
+	// methods of the same name, and SendDropped counts the packets that
+	// the AsyncSenders of the conn dropped because their queue was full.
+	Dropped     uint64
+	Unmatched   uint64
+	Rejected    uint64
+	RateLimited uint64
+	SendDropped uint64
+
+	// LastReceived is when the last packet was read,
+	// or the zero time if none has been.
+	LastReceived time.Time
+}
+
+// counters are the counters of Stats that the send and receive paths update.
+// It is allocated on its own so that its fields are 64-bit aligned for atomic use.
+type counters struct {
+	packetsReceived uint64
+	bytesReceived   uint64
+	packetsSent     uint64
+	bytesSent       uint64
+	sendErrors      uint64
+	parseErrors     uint64
+	dispatchErrors  uint64
+	otherErrors     uint64
+	sendDropped     uint64
+	lastReceived    int64 // Unix nanoseconds, 0 if nothing was read.
+}
+
+// Stats returns a snapshot of the counters of the conn.
+// It is safe to call from many goroutines, while the conn is in use.
+func (s *connState) Stats() Stats {
+	c := s.counters
+	stats := Stats{
+		PacketsReceived: atomic.LoadUint64(&c.packetsReceived),
+		BytesReceived:   atomic.LoadUint64(&c.bytesReceived),
+		PacketsSent:     atomic.LoadUint64(&c.packetsSent),
+		BytesSent:       atomic.LoadUint64(&c.bytesSent),
+		SendErrors:      atomic.LoadUint64(&c.sendErrors),
+		ParseErrors:     atomic.LoadUint64(&c.parseErrors),
+		DispatchErrors:  atomic.LoadUint64(&c.dispatchErrors),
+		OtherErrors:     atomic.LoadUint64(&c.otherErrors),
+		Dropped:         s.Dropped(),
+		Unmatched:       s.Unmatched(),
+		Rejected:        s.Rejected(),
+		RateLimited:     s.RateLimited(),
+		SendDropped:     atomic.LoadUint64(&c.sendDropped),
+	}
+	if last := atomic.LoadInt64(&c.lastReceived); last != 0 {
+		stats.LastReceived = time.Unix(0, last)
+	}
+	return stats
+}
+
+// countReceived counts a packet of n bytes that was read.
+func (s *connState) countReceived(n int) {
+	if m, ok := s.metrics(); ok {
+		m.PacketReceived(n)
+	}
+	c := s.counters
+	atomic.AddUint64(&c.packetsReceived, 1)
+	atomic.AddUint64(&c.bytesReceived, uint64(n))
+	atomic.StoreInt64(&c.lastReceived, time.Now().UnixNano())
+}
+
+// countSent counts a packet of n bytes that was sent, or that failed to be.
+func (s *connState) countSent(n int, err error) {
+	c := s.counters
+	if err != nil {
+		atomic.AddUint64(&c.sendErrors, 1)
+		return
+	}
+	atomic.AddUint64(&c.packetsSent, 1)
+	atomic.AddUint64(&c.bytesSent, uint64(n))
+
+	if m, ok := s.metrics(); ok {
+		m.PacketSent(n)
+	}
+}
+
+// countSendDropped counts a packet that an AsyncSender dropped because its queue was full.
+func (s *connState) countSendDropped() {
+	atomic.AddUint64(&s.counters.sendDropped, 1)
+	s.countDrop(ReasonSendQueueFull)
+
+	if s.logger != nil {
+		s.log(slog.LevelWarn, "osc: send queue full, packet dropped")
+	}
+}
+
+// countError counts an error that is passed to the error handler by its class.
+func (s *connState) countError(err error) {
+	var (
+		c           = s.counters
+		parseErr    *ParseError
+		methodErr   *MethodError
+		dispatchErr *DispatchError
+	)
+	switch {
+	case errors.Is(err, ErrRejectedSource):
+		// Counted by Rejected.
+	case errors.As(err, &parseErr):
+		s.countParseError(err)
+	case errors.As(err, &methodErr), errors.As(err, &dispatchErr):
+		atomic.AddUint64(&c.dispatchErrors, 1)
+	default:
+		atomic.AddUint64(&c.otherErrors, 1)
+	}
+}
+
+// countParseError counts a packet that could not be parsed.
+func (s *connState) countParseError(err error) {
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		return
+	}
+	atomic.AddUint64(&s.counters.parseErrors, 1)
+	if m, ok := s.metrics(); ok {
+		m.ParseError()
+	}
+	if s.logger != nil {
+		s.log(slog.LevelWarn, "osc: packet can not be parsed", remoteAttr(parseErr.Sender), addressAttr(parseErr.Address), errorAttr(parseErr.Err))
+	}
+}
@@ -0,0 +1,97 @@
+package osc
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := client.Stats(); stats != (Stats{}) {
+		t.Fatalf("expected zero stats, got %+v", stats)
+	}
+	// The counters are updated whether or not there is an error handler.
+	errs := make(chan error, 8)
+	server.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			"/ok": Method(func(msg Message) error {
+				return nil
+			}),
+			"/fail": Method(func(msg Message) error {
+				return errors.New("fail")
+			}),
+		})
+	}()
+	start := time.Now()
+	var sentBytes uint64
+	for _, msg := range []Message{
+		MustMessage("/ok", int32(1)),
+		MustMessage("/fail", "a"),
+		MustMessage("/unmatched"),
+	} {
+		if err := client.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+		n, err := msg.encodedSize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sentBytes += uint64(n)
+	}
+	garbage := []byte("garbage!")
+	if _, err := client.udpConn.Write(garbage); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-errs:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for error %d", i)
+		}
+	}
+	stats := server.Stats()
+	if stats.LastReceived.Before(start) || stats.LastReceived.After(time.Now()) {
+		t.Fatalf("expected the last packet to be received after %s, got %s", start, stats.LastReceived)
+	}
+	stats.LastReceived = time.Time{}
+	if expected := (Stats{
+		PacketsReceived: 4,
+		BytesReceived:   sentBytes + uint64(len(garbage)),
+		ParseErrors:     1,
+		DispatchErrors:  2,
+		Unmatched:       1,
+	}); stats != expected {
+		t.Fatalf("expected %+v, got %+v", expected, stats)
+	}
+	if expected := (Stats{PacketsSent: 3, BytesSent: sentBytes}); client.Stats() != expected {
+		t.Fatalf("expected %+v, got %+v", expected, client.Stats())
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Send(MustMessage("/ok")); err == nil {
+		t.Fatal("expected an error sending with a closed conn")
+	}
+	if stats := client.Stats(); stats.SendErrors != 1 || stats.PacketsSent != 3 {
+		t.Fatalf("expected 1 send error and 3 packets sent, got %+v", stats)
+	}
+}
@@ -0,0 +1,190 @@
+package osc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// streamAddr is the address of either end of a stream that is not a net.Conn.
+type streamAddr struct{}
+
+// Network returns the name of the network.
+func (streamAddr) Network() string { return "stream" }
+
+// String returns the string form of the address.
+func (streamAddr) String() string { return "stream" }
+
+// StreamConn is an OSC connection over any io.ReadWriteCloser,
+// such as a serial port or one end of a pipe.
+// Packets are delimited with the framing given to NewConn.
+type StreamConn struct {
+	connState
+
+	rw     io.ReadWriteCloser
+	reader *bufio.Reader
+	opts   options
+
+	closeOnce sync.Once
+	writeMu   sync.Mutex
+}
+
+// NewConn creates an OSC connection that reads and writes packets on rw
+// using the given framing.
+// Most devices that speak OSC over a serial line use SLIP.
+func NewConn(rw io.ReadWriteCloser, framing Framing, opts ...Option) (*StreamConn, error) {
+	o, err := newStreamOptions(append(opts, WithFraming(framing)))
+	if err != nil {
+		return nil, err
+	}
+	conn := &StreamConn{
+		connState: newConnState(context.Background()),
+		rw:        rw,
+		reader:    bufio.NewReaderSize(rw, bufSize),
+		opts:      o,
+	}
+	conn.readBufSize = o.maxPacketSize
+	conn.applyOptions(o)
+	return conn, nil
+}
+
+// Close closes the underlying io.ReadWriteCloser.
+func (conn *StreamConn) Close() error {
+	var err error
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+		err = conn.rw.Close()
+	})
+	return err
+}
+
+// LocalAddr returns the local address if the stream is a net.Conn.
+func (conn *StreamConn) LocalAddr() net.Addr {
+	if nc, ok := conn.rw.(net.Conn); ok {
+		return nc.LocalAddr()
+	}
+	return streamAddr{}
+}
+
+// RemoteAddr returns the remote address if the stream is a net.Conn.
+// Messages read from the stream have this address as their Sender.
+func (conn *StreamConn) RemoteAddr() net.Addr {
+	if nc, ok := conn.rw.(net.Conn); ok {
+		return nc.RemoteAddr()
+	}
+	return streamAddr{}
+}
+
+// Send writes a packet to the stream.
+func (conn *StreamConn) Send(p Packet) error {
+	return conn.send(conn.RemoteAddr(), p, func(data []byte) error {
+		conn.writeMu.Lock()
+		defer conn.writeMu.Unlock()
+
+		return conn.opts.framing.write(conn.rw, data)
+	})
+}
+
+// SendTo writes a packet to the stream.
+// addr must be the remote address of the stream.
+func (conn *StreamConn) SendTo(addr net.Addr, p Packet) error {
+	if addr.String() != conn.RemoteAddr().String() {
+		return fmt.Errorf("stream conn is connected to %s, not %s", conn.RemoteAddr(), addr)
+	}
+	return conn.Send(p)
+}
+
+// SendBundle sends msgs in a single bundle with the given timetag.
+// Bundles larger than the max packet size of the conn are rejected with ErrPacketTooLarge.
+func (conn *StreamConn) SendBundle(tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.Send(p)
+}
+
+// SendBundleTo sends msgs to addr in a single bundle with the given timetag.
+func (conn *StreamConn) SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.SendTo(addr, p)
+}
+
+// Reply sends msg to the sender of a message that was read from the conn.
+// A connected conn ignores to and sends msg to its peer.
+func (conn *StreamConn) Reply(to net.Addr, msg Message) error {
+	return reply(conn, to, msg)
+}
+
+// Call sends request and returns the first message to replyAddress that the conn
+// reads afterwards, which is not dispatched, or ctx.Err() if ctx is done first.
+// The conn must be serving. Calls waiting for the same address get the replies
+// in the order they were made.
+func (conn *StreamConn) Call(ctx context.Context, request Message, replyAddress string) (Message, error) {
+	return conn.call(ctx, conn.Send, request, replyAddress)
+}
+
+// Ping sends a ping to addr, or to the peer of a connected conn if addr is empty,
+// and returns the time until the echo of the peer's EchoMethod is read,
+// or ctx.Err() if ctx is done first. The conn must be serving.
+// Every ping carries a random token, so concurrent pings get their own echo.
+func (conn *StreamConn) Ping(ctx context.Context, addr string) (time.Duration, error) {
+	if addr == "" {
+		return conn.ping(ctx, conn.Send)
+	}
+	return conn.ping(ctx, sendToPeer(conn, pingAddr(addr)))
+}
+
+// Receive reads packets onto the returned channel instead of dispatching them,
+// until ctx is done or the conn is closed or shut down, which closes the channel.
+// Packets that can not be parsed are passed on with Err set.
+// The channel holds as many packets as the queue size. When it is full Receive
+// waits for room, or drops the oldest packet or the new one and counts it in Dropped
+// if the queue policy is DropOldest or DropNewest.
+// A conn can not be served and received from at once: Receive returns ErrServing
+// while Serve is running, and Serve returns ErrReceiving until Receive has stopped.
+func (conn *StreamConn) Receive(ctx context.Context) (<-chan Incoming, error) {
+	return receive(ctx, conn, &conn.connState)
+}
+
+// ReceivePacket reads and parses a single packet and returns it with its sender.
+// The read gives up with ctx.Err() when ctx is done.
+// Packets that can not be parsed are returned as a *ParseError, so the caller
+// can read the next one. ReceivePacket returns ErrServing while Serve is running
+// and ErrReceiving while Receive is.
+func (conn *StreamConn) ReceivePacket(ctx context.Context) (Packet, net.Addr, error) {
+	return receivePacket(ctx, conn, &conn.connState)
+}
+
+// Serve starts dispatching OSC.
+// Packets that can not be parsed and errors returned from methods are passed to
+// the error handler and do not stop the server, unless the conn is in strict mode.
+// Any error reading from the stream is returned.
+// If context.Canceled or context.DeadlineExceeded are encountered they will be returned directly.
+// The connection is closed, and Serve returns nil, when the stream ends between packets.
+func (conn *StreamConn) Serve(numWorkers int, dispatcher Dispatcher) error {
+	return conn.ServeContext(context.Background(), numWorkers, dispatcher)
+}
+
+// ServeContext is like Serve, but it also stops serving when ctx is done
+// and returns ctx.Err() once the methods that are already running have returned.
+// The conn is left open.
+func (conn *StreamConn) ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error {
+	return serve(ctx, conn, numWorkers, conn.exactMatch, dispatcher)
+}
+
+// read reads a single packet and returns the net.Addr of the sender.
+func (conn *StreamConn) read(data []byte) (int, net.Addr, error) {
+	n, err := conn.opts.framing.read(conn.reader, data, conn.opts.maxPacketSize)
+	if err == io.EOF {
+		_ = conn.Close()
+	}
+	return n, conn.RemoteAddr(), err
+}
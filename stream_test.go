@@ -0,0 +1,112 @@
+package osc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// errReadWriteCloser fails every read with err.
+type errReadWriteCloser struct {
+	err error
+}
+
+func (rw errReadWriteCloser) Read(p []byte) (int, error)  { return 0, rw.err }
+func (rw errReadWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (rw errReadWriteCloser) Close() error                { return nil }
+
+// testStreamPipe returns two stream conns connected with net.Pipe.
+func testStreamPipe(t *testing.T, framing Framing) (*StreamConn, *StreamConn) {
+	a, b := net.Pipe()
+
+	c1, err := NewConn(a, framing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewConn(b, framing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c1, c2
+}
+
+func TestStreamConnPipe(t *testing.T) {
+	for _, framing := range []Framing{LengthPrefix, SLIP} {
+		device, host := testStreamPipe(t, framing)
+
+		go func() {
+			_ = device.Serve(1, PatternMatching{
+				"/ping": Method(func(msg Message) error {
+					return device.SendTo(msg.Sender, Message{Address: "/pong", Arguments: msg.Arguments})
+				}),
+			})
+		}()
+		var (
+			pongs   = make(chan Message)
+			errChan = make(chan error)
+		)
+		go func() {
+			errChan <- host.Serve(1, PatternMatching{
+				"/pong": Method(func(msg Message) error {
+					pongs <- msg
+					return nil
+				}),
+			})
+		}()
+		expected := Message{Address: "/pong", Arguments: Arguments{Blob{slipEnd, slipEsc, 0, 1}}}
+		if err := host.Send(Message{Address: "/ping", Arguments: expected.Arguments}); err != nil {
+			t.Fatal(err)
+		}
+		if got := waitMessage(t, pongs, errChan); !expected.Equal(got) {
+			t.Fatalf("(%s) expected %s, got %s", framing, expected, got)
+		}
+		// Closing one end ends the stream for the other.
+		if err := device.Close(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-errChan:
+			if err != nil {
+				t.Fatalf("(%s) %s", framing, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(%s) timeout waiting for Serve to return", framing)
+		}
+	}
+}
+
+func TestStreamConnReadError(t *testing.T) {
+	for _, readErr := range []error{errors.New("derp"), io.ErrUnexpectedEOF} {
+		conn, err := NewConn(errReadWriteCloser{err: readErr}, SLIP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.Serve(1, PatternMatching{}); !errors.Is(err, readErr) {
+			t.Fatalf("expected %v, got %v", readErr, err)
+		}
+	}
+}
+
+func TestNewConn(t *testing.T) {
+	a, b := net.Pipe()
+	defer func() { _ = a.Close(); _ = b.Close() }() // Best effort.
+
+	if _, err := NewConn(a, Framing(42)); !errors.Is(err, ErrInvalidFraming) {
+		t.Fatalf("expected ErrInvalidFraming, got %v", err)
+	}
+	if _, err := NewConn(a, SLIP, WithBroadcast()); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+	conn, err := NewConn(errReadWriteCloser{}, SLIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, got := "stream", conn.RemoteAddr().String(); expected != got {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if err := conn.SendTo(&net.UDPAddr{}, Message{Address: "/foo"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,168 @@
+package osc
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// subscription streams the messages that match a pattern to a channel.
+type subscription struct {
+	pattern *Pattern
+	policy  QueuePolicy
+	ch      chan Message
+	done    chan struct{} // Closed by cancel, to unblock send.
+
+	// mu is held while a message is sent on ch, so that ch is not closed in the meantime.
+	mu     sync.Mutex
+	closed bool
+
+	countDropped func(sender net.Addr)
+}
+
+// send sends msg to the subscriber. If the channel is full the oldest message
+// is dropped to make room if the policy is DropOldest, msg is dropped if it is DropNewest,
+// and otherwise send waits for room or for the subscription to be canceled.
+func (sub *subscription) send(msg Message) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+	if sub.policy == DropNewest && cap(sub.ch) > 0 {
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.countDropped(msg.Sender)
+		}
+		return
+	}
+	if sub.policy == DropOldest && cap(sub.ch) > 0 {
+		select {
+		case sub.ch <- msg:
+			return
+		default:
+		}
+		select {
+		case dropped := <-sub.ch:
+			sub.countDropped(dropped.Sender)
+		default:
+		}
+	}
+	select {
+	case sub.ch <- msg:
+	case <-sub.done:
+	}
+}
+
+// subscriptions holds the subscriptions of a conn.
+type subscriptions struct {
+	mu    sync.Mutex
+	subs  []*subscription
+	count int32 // len(subs), read without mu.
+}
+
+// add adds sub.
+func (t *subscriptions) add(sub *subscription) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.subs = append(t.subs, sub)
+	atomic.StoreInt32(&t.count, int32(len(t.subs)))
+}
+
+// remove removes sub, if it has not been removed already.
+func (t *subscriptions) remove(sub *subscription) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, s := range t.subs {
+		if s == sub {
+			// Copy, so that publish can range over the subscriptions it got without mu.
+			t.subs = append(t.subs[:i:i], t.subs[i+1:]...)
+			break
+		}
+	}
+	atomic.StoreInt32(&t.count, int32(len(t.subs)))
+}
+
+// publish sends a copy of msg to every subscription whose pattern matches its address.
+func (t *subscriptions) publish(msg Message) {
+	if atomic.LoadInt32(&t.count) == 0 {
+		return
+	}
+	t.mu.Lock()
+	subs := t.subs
+	t.mu.Unlock()
+
+	for _, sub := range subs {
+		if sub.pattern.Match(msg.Address) {
+			sub.send(msg.Clone()) // The message may be a view over a read buffer.
+		}
+	}
+}
+
+// publishBundle publishes the messages of b.
+func (t *subscriptions) publishBundle(b Bundle) {
+	if atomic.LoadInt32(&t.count) == 0 {
+		return
+	}
+	for _, p := range b.Packets {
+		switch x := p.(type) {
+		case Message:
+			t.publish(x)
+		case Bundle:
+			t.publishBundle(x)
+		}
+	}
+}
+
+// Subscribe returns a channel that receives a copy of every message that Serve
+// reads whose address matches pattern, such as the notifications a server sends
+// after /notify. The messages are dispatched as usual too.
+// Every subscription whose pattern matches a message gets its own copy.
+// The channel holds buffer messages. When it is full the method that is
+// being dispatched waits for room, or the oldest message or the new one is dropped
+// and counted in Dropped if the subscription policy is DropOldest or DropNewest.
+// The returned func cancels the subscription and closes the channel.
+// If pattern is not a valid address pattern the channel is closed at once.
+func (s *connState) Subscribe(pattern string, buffer int) (<-chan Message, func()) {
+	if buffer < 0 {
+		buffer = 0
+	}
+	ch := make(chan Message, buffer)
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		close(ch)
+		return ch, func() {}
+	}
+	sub := &subscription{
+		pattern:      p,
+		policy:       s.subscriptionPolicy,
+		ch:           ch,
+		done:         make(chan struct{}),
+		countDropped: s.countDropped,
+	}
+	s.subs.add(sub)
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(sub.done)
+			s.subs.remove(sub)
+
+			sub.mu.Lock()
+			sub.closed = true
+			close(sub.ch)
+			sub.mu.Unlock()
+		})
+	}
+}
+
+// SetSubscriptionPolicy sets what happens to the messages of a subscription
+// that arrive while its channel is full. It applies to the subscriptions that
+// are made afterwards. The default is BlockWhenFull.
+func (s *connState) SetSubscriptionPolicy(policy QueuePolicy) {
+	s.subscriptionPolicy = policy
+}
@@ -0,0 +1,133 @@
+package osc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	var (
+		all, cancelAll = c2.Subscribe("/n_*", 8)
+		gos, cancelGos = c2.Subscribe("/n_go", 8)
+		dispatched     = make(chan Message, 8)
+	)
+	defer cancelAll()
+
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			Default: Method(func(msg Message) error {
+				dispatched <- msg
+				return nil
+			}),
+		})
+	}()
+	for _, addr := range []string{"/n_go", "/n_end", "/other", "/n_go"} {
+		if err := c1.Send(MustMessage(addr, int32(1000))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, testcase := range []struct {
+		Ch       <-chan Message
+		Expected []string
+	}{
+		{Ch: all, Expected: []string{"/n_go", "/n_end", "/n_go"}},
+		{Ch: gos, Expected: []string{"/n_go", "/n_go"}},
+		{Ch: dispatched, Expected: []string{"/n_go", "/n_end", "/other", "/n_go"}},
+	} {
+		for j, expected := range testcase.Expected {
+			select {
+			case msg := <-testcase.Ch:
+				if msg.Address != expected {
+					t.Fatalf("(testcase %d) message %d: expected %s, got %s", i, j, expected, msg.Address)
+				}
+				if node, err := msg.Int32At(0); err != nil || node != 1000 {
+					t.Fatalf("(testcase %d) message %d: expected 1000, got %d (%v)", i, j, node, err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("(testcase %d) timeout waiting for message %d", i, j)
+			}
+		}
+	}
+	cancelGos()
+	cancelGos() // Canceling again does nothing.
+	if _, ok := <-gos; ok {
+		t.Fatal("expected the channel to be closed")
+	}
+	if err := c1.Send(MustMessage("/n_go")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-all:
+		if msg.Address != "/n_go" {
+			t.Fatalf("expected /n_go, got %s", msg.Address)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+	invalid, cancel := c2.Subscribe("/n_[", 1)
+	defer cancel()
+	if _, ok := <-invalid; ok {
+		t.Fatal("expected the channel of an invalid pattern to be closed")
+	}
+}
+
+func TestSubscribe_SlowConsumer(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	blocking, cancelBlocking := c2.Subscribe("/n", 0)
+	c2.SetSubscriptionPolicy(DropOldest)
+	dropping, cancelDropping := c2.Subscribe("/n", 1)
+	defer cancelDropping()
+
+	dispatched := make(chan int32, 8)
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			"/n": Method(func(msg Message) error {
+				i, err := msg.Int32At(0)
+				dispatched <- i
+				return err
+			}),
+		})
+	}()
+	for i := int32(0); i < 3; i++ {
+		if err := c1.Send(MustMessage("/n", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The blocking subscription holds up the first message until it is read.
+	if msg := <-blocking; msg.Address != "/n" {
+		t.Fatalf("expected /n, got %s", msg.Address)
+	}
+	select {
+	case i := <-dispatched:
+		if i != 0 {
+			t.Fatalf("expected message 0, got %d", i)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout")
+	}
+	// Canceling it lets the others through.
+	cancelBlocking()
+	for i := int32(1); i < 3; i++ {
+		select {
+		case got := <-dispatched:
+			if got != i {
+				t.Fatalf("expected message %d, got %d", i, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for message %d", i)
+		}
+	}
+	if n := c2.Dropped(); n != 2 {
+		t.Fatalf("expected 2 dropped messages, got %d", n)
+	}
+	if i, err := (<-dropping).Int32At(0); err != nil || i != 2 {
+		t.Fatalf("expected the last message, got %d (%v)", i, err)
+	}
+}
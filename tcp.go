@@ -0,0 +1,386 @@
+package osc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Common errors.
+var (
+	ErrNotConnected = errors.New("tcp listener has no remote address, use SendTo")
+	ErrUnknownPeer  = errors.New("no tcp connection to that address")
+)
+
+// TCPConn is an OSC connection over TCP.
+// OSC 1.0 streams are framed by prefixing every packet with its size
+// as a 4-byte big-endian integer. Use WithFraming(SLIP) to talk to
+// OSC 1.1 peers that delimit packets with SLIP instead.
+//
+// A TCPConn returned by DialTCP talks to a single peer.
+// A TCPConn returned by ListenTCP accepts any number of peers while it is
+// serving, and messages they send carry the peer's address as their Sender
+// so that handlers can reply with SendTo.
+type TCPConn struct {
+	connState
+
+	conn     *net.TCPConn
+	reader   *bufio.Reader
+	listener *net.TCPListener
+	opts     options
+
+	acceptOnce sync.Once
+	closeOnce  sync.Once
+	frames     chan tcpFrame
+
+	mu    sync.Mutex
+	peers map[string]*net.TCPConn
+
+	// unsent is the end of a frame that a send gave up on after it had written the start,
+	// which is written before the next frame so that the peer is not left with half of one.
+	// It is guarded by the send buffer.
+	unsent []byte
+}
+
+// tcpFrame is a packet read by one of a listener's peers.
+type tcpFrame struct {
+	data   []byte
+	sender net.Addr
+	err    error
+}
+
+// DialTCP creates a new OSC connection over TCP.
+func DialTCP(network string, laddr, raddr *net.TCPAddr, opts ...Option) (*TCPConn, error) {
+	return DialTCPContext(context.Background(), network, laddr, raddr, opts...)
+}
+
+// DialTCPContext returns a new OSC connection over TCP that can be canceled with the provided context.
+func DialTCPContext(ctx context.Context, network string, laddr, raddr *net.TCPAddr, opts ...Option) (*TCPConn, error) {
+	o, err := newStreamOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTCP(network, laddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+	tc := &TCPConn{
+		connState: newConnState(ctx),
+		conn:      conn,
+		reader:    bufio.NewReaderSize(conn, bufSize),
+		opts:      o,
+	}
+	tc.readBufSize = o.maxPacketSize
+	tc.applyOptions(o)
+	return tc, nil
+}
+
+// ListenTCP creates a new TCP server.
+func ListenTCP(network string, laddr *net.TCPAddr, opts ...Option) (*TCPConn, error) {
+	return ListenTCPContext(context.Background(), network, laddr, opts...)
+}
+
+// ListenTCPContext creates a TCP listener that can be canceled with the provided context.
+func ListenTCPContext(ctx context.Context, network string, laddr *net.TCPAddr, opts ...Option) (*TCPConn, error) {
+	o, err := newStreamOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.ListenTCP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	tc := &TCPConn{
+		connState: newConnState(ctx),
+		listener:  listener,
+		opts:      o,
+		frames:    make(chan tcpFrame),
+		peers:     map[string]*net.TCPConn{},
+	}
+	tc.readBufSize = o.maxPacketSize
+	tc.applyOptions(o)
+	return tc, nil
+}
+
+// Close closes the connection.
+// Closing a listener also closes the connections it has accepted.
+func (conn *TCPConn) Close() error {
+	closed := false
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+		closed = true
+	})
+	if !closed {
+		return nil
+	}
+	if conn.listener == nil {
+		return conn.conn.Close()
+	}
+	conn.mu.Lock()
+	for key, peer := range conn.peers {
+		_ = peer.Close() // Best effort.
+		delete(conn.peers, key)
+	}
+	conn.mu.Unlock()
+
+	return conn.listener.Close()
+}
+
+// LocalAddr returns the local network address.
+func (conn *TCPConn) LocalAddr() net.Addr {
+	if conn.listener != nil {
+		return conn.listener.Addr()
+	}
+	return conn.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+// It returns nil for a listener.
+func (conn *TCPConn) RemoteAddr() net.Addr {
+	if conn.listener != nil {
+		return nil
+	}
+	return conn.conn.RemoteAddr()
+}
+
+// Send sends a packet to the peer of a dialed connection.
+// Listeners return ErrNotConnected since they may have many peers.
+func (conn *TCPConn) Send(p Packet) error {
+	if conn.listener != nil {
+		return ErrNotConnected
+	}
+	return conn.send(conn.conn.RemoteAddr(), p, conn.writeFrame)
+}
+
+// SendContext sends a packet to the peer of a dialed connection like Send, but gives up
+// once ctx is done, e.g. when the peer does not read and the socket buffers are full.
+// It returns an error wrapping ErrWriteTimeout if the deadline of ctx passes first,
+// and one wrapping ctx.Err() if ctx is canceled. Either way the conn can still be used:
+// if the start of the packet had already been written, its end is written before the next packet.
+func (conn *TCPConn) SendContext(ctx context.Context, p Packet) error {
+	if conn.listener != nil {
+		return ErrNotConnected
+	}
+	return conn.sendContext(ctx, conn.conn, conn.conn.RemoteAddr(), p, conn.writeFrame)
+}
+
+// AsyncSender returns an AsyncSender that sends packets to the peer of a dialed connection with Send,
+// through a queue of queueSize packets. When the queue is full, Enqueue waits or drops a packet as policy says.
+// The sender should be closed before the conn, so that the packets it has queued are sent.
+func (conn *TCPConn) AsyncSender(queueSize int, policy QueuePolicy) *AsyncSender {
+	return newAsyncSender(&conn.connState, conn.Send, queueSize, policy)
+}
+
+// writeFrame writes data to the peer in a single frame, after what remains of the last one.
+func (conn *TCPConn) writeFrame(data []byte) error {
+	if len(conn.unsent) > 0 {
+		n, err := conn.conn.Write(conn.unsent)
+		if conn.unsent = conn.unsent[n:]; err != nil {
+			return err
+		}
+	}
+	frame := conn.opts.framing.frame(data)
+	n, err := conn.conn.Write(frame)
+	if err != nil && n > 0 {
+		conn.unsent = frame[n:]
+	}
+	return err
+}
+
+// SendTo sends a packet to the given address.
+// For a listener addr must be the address of a peer that is currently connected,
+// usually the Sender of a message the peer sent.
+func (conn *TCPConn) SendTo(addr net.Addr, p Packet) error {
+	if conn.listener == nil {
+		if addr.String() != conn.conn.RemoteAddr().String() {
+			return fmt.Errorf("tcp conn is connected to %s, not %s", conn.conn.RemoteAddr(), addr)
+		}
+		return conn.Send(p)
+	}
+	// A listener does not share a buffer between its peers,
+	// so that a slow peer does not hold up the others.
+	data, err := encodePacket(p)
+	if err != nil {
+		conn.sent(addr, p, 0, err)
+		return err
+	}
+	conn.mu.Lock()
+	peer, ok := conn.peers[addr.String()]
+	conn.mu.Unlock()
+
+	if !ok {
+		err = fmt.Errorf("%s: %w", addr.String(), ErrUnknownPeer)
+	} else {
+		err = conn.opts.framing.write(peer, data)
+	}
+	if err != nil {
+		conn.sent(addr, p, 0, err)
+		return err
+	}
+	conn.sent(addr, p, len(data), nil)
+	return nil
+}
+
+// SendBundle sends msgs in a single bundle with the given timetag.
+// Bundles larger than the max packet size of the conn are rejected with ErrPacketTooLarge.
+func (conn *TCPConn) SendBundle(tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.Send(p)
+}
+
+// SendBundleTo sends msgs to addr in a single bundle with the given timetag.
+func (conn *TCPConn) SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.SendTo(addr, p)
+}
+
+// Reply sends msg to the sender of a message that was read from the conn.
+// A connected conn ignores to and sends msg to its peer.
+func (conn *TCPConn) Reply(to net.Addr, msg Message) error {
+	return reply(conn, to, msg)
+}
+
+// Call sends request and returns the first message to replyAddress that the conn
+// reads afterwards, which is not dispatched, or ctx.Err() if ctx is done first.
+// The conn must be serving. Calls waiting for the same address get the replies
+// in the order they were made.
+func (conn *TCPConn) Call(ctx context.Context, request Message, replyAddress string) (Message, error) {
+	return conn.call(ctx, conn.Send, request, replyAddress)
+}
+
+// Ping sends a ping to addr, or to the peer of a connected conn if addr is empty,
+// and returns the time until the echo of the peer's EchoMethod is read,
+// or ctx.Err() if ctx is done first. The conn must be serving.
+// Every ping carries a random token, so concurrent pings get their own echo.
+func (conn *TCPConn) Ping(ctx context.Context, addr string) (time.Duration, error) {
+	if addr == "" {
+		return conn.ping(ctx, conn.Send)
+	}
+	return conn.ping(ctx, sendToPeer(conn, pingAddr(addr)))
+}
+
+// Receive reads packets onto the returned channel instead of dispatching them,
+// until ctx is done or the conn is closed or shut down, which closes the channel.
+// Packets that can not be parsed are passed on with Err set.
+// The channel holds as many packets as the queue size. When it is full Receive
+// waits for room, or drops the oldest packet or the new one and counts it in Dropped
+// if the queue policy is DropOldest or DropNewest.
+// A conn can not be served and received from at once: Receive returns ErrServing
+// while Serve is running, and Serve returns ErrReceiving until Receive has stopped.
+func (conn *TCPConn) Receive(ctx context.Context) (<-chan Incoming, error) {
+	return receive(ctx, conn, &conn.connState)
+}
+
+// ReceivePacket reads and parses a single packet and returns it with its sender.
+// The read gives up with ctx.Err() when ctx is done.
+// Packets that can not be parsed are returned as a *ParseError, so the caller
+// can read the next one. ReceivePacket returns ErrServing while Serve is running
+// and ErrReceiving while Receive is.
+func (conn *TCPConn) ReceivePacket(ctx context.Context) (Packet, net.Addr, error) {
+	return receivePacket(ctx, conn, &conn.connState)
+}
+
+// Serve starts dispatching OSC.
+// Packets that can not be parsed and errors returned from methods are passed to
+// the error handler and do not stop the server, unless the conn is in strict mode.
+// If context.Canceled or context.DeadlineExceeded are encountered they will be returned directly.
+// A dialed connection is closed, and Serve returns nil, when its peer hangs up.
+func (conn *TCPConn) Serve(numWorkers int, dispatcher Dispatcher) error {
+	return conn.ServeContext(context.Background(), numWorkers, dispatcher)
+}
+
+// ServeContext is like Serve, but it also stops serving when ctx is done
+// and returns ctx.Err() once the methods that are already running have returned.
+// The conn is left open.
+func (conn *TCPConn) ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error {
+	return serve(ctx, conn, numWorkers, conn.exactMatch, dispatcher)
+}
+
+// read reads a single packet and returns the net.Addr of the sender.
+func (conn *TCPConn) read(data []byte) (int, net.Addr, error) {
+	if conn.listener == nil {
+		n, err := conn.opts.framing.read(conn.reader, data, conn.opts.maxPacketSize)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The peer hung up, so there is nothing more to serve.
+			_ = conn.Close()
+		}
+		return n, conn.conn.RemoteAddr(), err
+	}
+	conn.acceptOnce.Do(func() {
+		go conn.accept()
+	})
+	select {
+	case frame := <-conn.frames:
+		if frame.err != nil {
+			return 0, nil, frame.err
+		}
+		return copy(data, frame.data), frame.sender, nil
+	case <-conn.closeChan:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+// accept accepts new peers until the listener is closed.
+func (conn *TCPConn) accept() {
+	for {
+		peer, err := conn.listener.AcceptTCP()
+		if err != nil {
+			select {
+			case <-conn.closeChan:
+			case conn.frames <- tcpFrame{err: err}:
+			}
+			return
+		}
+		key := peer.RemoteAddr().String()
+
+		conn.mu.Lock()
+		conn.peers[key] = peer
+		conn.mu.Unlock()
+
+		go conn.readPeer(key, peer)
+	}
+}
+
+// readPeer reads packets from one of a listener's peers.
+// The peer is closed and forgotten when it hangs up, even in the middle of a packet,
+// or when it sends a packet that is too large.
+func (conn *TCPConn) readPeer(key string, peer *net.TCPConn) {
+	defer func() {
+		conn.mu.Lock()
+		delete(conn.peers, key)
+		conn.mu.Unlock()
+		_ = peer.Close() // Best effort.
+	}()
+
+	var (
+		data   = make([]byte, conn.opts.maxPacketSize)
+		reader = bufio.NewReaderSize(peer, bufSize)
+		sender = peer.RemoteAddr()
+	)
+	for {
+		n, err := conn.opts.framing.read(reader, data, conn.opts.maxPacketSize)
+		if err != nil {
+			return
+		}
+		frame := tcpFrame{
+			data:   append([]byte{}, data[:n]...),
+			sender: sender,
+		}
+		select {
+		case conn.frames <- frame:
+		case <-conn.closeChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package osc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// testTCPServer creates a TCP server listening on an ephemeral port and
+// returns the server and a channel that emits the error returned from Serve.
+func testTCPServer(t *testing.T, dispatcher PatternMatching, opts ...Option) (*TCPConn, chan error) {
+	laddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenTCP("tcp", laddr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error)
+
+	go func() {
+		if err := server.Serve(1, dispatcher); err != nil {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+	return server, errChan
+}
+
+// rawTCPClient dials the server without any OSC framing.
+func rawTCPClient(t *testing.T, server *TCPConn) *net.TCPConn {
+	raddr, err := net.ResolveTCPAddr("tcp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.DialTCP("tcp", nil, raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetNoDelay(true); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// frame returns p with its size prefix.
+func frame(p Packet) []byte {
+	b := p.Bytes()
+	return append(Int(len(b)).Bytes(), b...)
+}
+
+func waitMessage(t *testing.T, c chan Message, errChan chan error) Message {
+	select {
+	case msg := <-c:
+		return msg
+	case err := <-errChan:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+	return Message{}
+}
+
+func TestTCPConnSendAndReply(t *testing.T) {
+	var server *TCPConn
+	server, errChan := testTCPServer(t, PatternMatching{
+		"/ping": Method(func(msg Message) error {
+			return server.SendTo(msg.Sender, Message{Address: "/pong"})
+		}),
+	})
+	defer func() { _ = server.Close() }() // Best effort.
+
+	raddr, err := net.ResolveTCPAddr("tcp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialTCP("tcp", nil, raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pongs := make(chan Message)
+	clientErrs := make(chan error)
+	go func() {
+		clientErrs <- client.Serve(1, PatternMatching{
+			"/pong": Method(func(msg Message) error {
+				pongs <- msg
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitMessage(t, pongs, errChan); msg.Address != "/pong" {
+		t.Fatalf("expected /pong, got %s", msg.Address)
+	}
+	if expected, got := server.LocalAddr().String(), client.RemoteAddr().String(); expected != got {
+		t.Fatalf("expected remote addr %s, got %s", expected, got)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-clientErrs; err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTCPConnSplitSegments(t *testing.T) {
+	msgs := make(chan Message)
+	server, errChan := testTCPServer(t, PatternMatching{
+		"/foo": Method(func(msg Message) error {
+			msgs <- msg
+			return nil
+		}),
+	})
+	defer func() { _ = server.Close() }() // Best effort.
+
+	conn := rawTCPClient(t, server)
+	defer func() { _ = conn.Close() }() // Best effort.
+
+	var (
+		expected = Message{Address: "/foo", Arguments: Arguments{Int(7), String("split")}}
+		data     = frame(expected)
+	)
+	// Split the size prefix itself as well as the packet.
+	for _, chunk := range [][]byte{data[:2], data[2:9], data[9:]} {
+		if _, err := conn.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := waitMessage(t, msgs, errChan); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestTCPConnTwoPacketsOneWrite(t *testing.T) {
+	msgs := make(chan Message, 2)
+	server, errChan := testTCPServer(t, PatternMatching{
+		"/foo": Method(func(msg Message) error {
+			msgs <- msg
+			return nil
+		}),
+	})
+	defer func() { _ = server.Close() }() // Best effort.
+
+	conn := rawTCPClient(t, server)
+	defer func() { _ = conn.Close() }() // Best effort.
+
+	var (
+		m1 = Message{Address: "/foo", Arguments: Arguments{Int(1)}}
+		m2 = Message{Address: "/foo", Arguments: Arguments{Int(2)}}
+	)
+	if _, err := conn.Write(append(frame(m1), frame(m2)...)); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []Message{m1, m2} {
+		if got := waitMessage(t, msgs, errChan); !expected.Equal(got) {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestTCPConnMaxPacketSize(t *testing.T) {
+	msgs := make(chan Message)
+	server, errChan := testTCPServer(t, PatternMatching{
+		"/foo": Method(func(msg Message) error {
+			msgs <- msg
+			return nil
+		}),
+	}, WithMaxPacketSize(16))
+	defer func() { _ = server.Close() }() // Best effort.
+
+	// The server hangs up on a peer whose size prefix is too large.
+	conn := rawTCPClient(t, server)
+	defer func() { _ = conn.Close() }() // Best effort.
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0x20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 4)); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	// Other peers are still served.
+	conn2 := rawTCPClient(t, server)
+	defer func() { _ = conn2.Close() }() // Best effort.
+
+	expected := Message{Address: "/foo"}
+	if _, err := conn2.Write(frame(expected)); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMessage(t, msgs, errChan); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestTCPConnPeerDisconnectMidFrame(t *testing.T) {
+	msgs := make(chan Message)
+	server, errChan := testTCPServer(t, PatternMatching{
+		"/foo": Method(func(msg Message) error {
+			msgs <- msg
+			return nil
+		}),
+	})
+	defer func() { _ = server.Close() }() // Best effort.
+
+	conn := rawTCPClient(t, server)
+	data := frame(Message{Address: "/foo"})
+	if _, err := conn.Write(data[:6]); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn2 := rawTCPClient(t, server)
+	defer func() { _ = conn2.Close() }() // Best effort.
+
+	expected := Message{Address: "/foo", Arguments: Arguments{Int(2)}}
+	if _, err := conn2.Write(frame(expected)); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMessage(t, msgs, errChan); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestDialTCPPeerHangup(t *testing.T) {
+	laddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.ListenTCP("tcp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }() // Best effort.
+
+	go func() {
+		peer, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		// Hang up in the middle of a packet.
+		_, _ = peer.Write([]byte{0, 0, 0, 8, '/', 'f'})
+		_ = peer.Close()
+	}()
+	client, err := DialTCP("tcp", nil, listener.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errChan := make(chan error)
+	go func() {
+		errChan <- client.Serve(1, PatternMatching{})
+	}()
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Serve to return")
+	}
+	// Closing again is harmless.
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTCPConnSendErrors(t *testing.T) {
+	server, _ := testTCPServer(t, PatternMatching{})
+	defer func() { _ = server.Close() }() // Best effort.
+
+	if err := server.Send(Message{Address: "/foo"}); err != ErrNotConnected {
+		t.Fatalf("expected ErrNotConnected, got %v", err)
+	}
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+	if err := server.SendTo(addr, Message{Address: "/foo"}); !errors.Is(err, ErrUnknownPeer) {
+		t.Fatalf("expected ErrUnknownPeer, got %v", err)
+	}
+	if server.RemoteAddr() != nil {
+		t.Fatalf("expected nil remote addr, got %s", server.RemoteAddr())
+	}
+}
+
+func TestDialTCP(t *testing.T) {
+	if _, err := DialTCP("asdfiauosweif", nil, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if _, err := DialTCP("tcp", nil, nil, WithMaxPacketSize(0)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	if _, err := ListenTCP("asdfiauosweif", nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if _, err := ListenTCP("tcp", nil, WithMaxPacketSize(-1)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,138 @@
+package osc
+
+import (
+	"sync"
+	"time"
+)
+
+// ThrottledSender sends messages to the peer of a conn at most once per interval
+// for every address, e.g. the hundreds of messages a second of a fader that is moved,
+// which some receivers can not keep up with.
+//
+// The first message to an address is sent at once, and opens a window of the interval.
+// The messages to the address that are sent during the window are coalesced: only
+// the last of them is sent, when the window ends, and it opens the next window.
+// So the receiver always ends up with the most recent values, shortly after
+// the burst stops. Every address has windows of its own, so the messages to
+// an address never hold up the messages to the others.
+//
+// It is safe to use from many goroutines at once.
+type ThrottledSender struct {
+	conn     Conn
+	interval time.Duration
+	clock    clock
+
+	mu    sync.Mutex
+	addrs map[string]*throttledAddress
+}
+
+// throttledAddress is an address that is in a window.
+type throttledAddress struct {
+	// pending is the last message that was sent during the window, if there is one.
+	pending *Message
+
+	// sending is held while a message is sent to the address,
+	// so that the messages to it are sent in order.
+	sending sync.Mutex
+}
+
+// NewThrottledSender returns a ThrottledSender that sends messages to the peer of conn,
+// at most one every perAddressInterval for each address.
+// An interval that is not positive sends every message at once.
+func NewThrottledSender(conn Conn, perAddressInterval time.Duration) *ThrottledSender {
+	return &ThrottledSender{
+		conn:     conn,
+		interval: perAddressInterval,
+		clock:    realClock{},
+		addrs:    map[string]*throttledAddress{},
+	}
+}
+
+// Send sends p at once if it is a message whose address is not in a window,
+// or a bundle, which is never held back. A message whose address is in a window
+// replaces the one that was held back for it, and Send returns nil.
+// A message that is held back is sent as it is when the window ends, so its arguments
+// must not be modified in the meantime. Errors sending it are passed to the error
+// handler of the conn.
+func (t *ThrottledSender) Send(p Packet) error {
+	msg, ok := p.(Message)
+	if !ok || t.interval <= 0 {
+		return t.conn.Send(p)
+	}
+	if msg.view.isSet() {
+		msg = msg.Clone() // The message may outlive the read buffer.
+	}
+	t.mu.Lock()
+	if a, ok := t.addrs[msg.Address]; ok {
+		a.pending = &msg
+		t.mu.Unlock()
+		return nil
+	}
+	a := &throttledAddress{}
+	t.addrs[msg.Address] = a
+	t.clock.AfterFunc(t.interval, func() { t.windowEnded(msg.Address, a) })
+	a.sending.Lock()
+	t.mu.Unlock()
+
+	defer a.sending.Unlock()
+	return t.conn.Send(msg)
+}
+
+// windowEnded sends the message that was held back for addr, which opens the next window,
+// or forgets addr if there is none.
+func (t *ThrottledSender) windowEnded(addr string, a *throttledAddress) {
+	t.mu.Lock()
+	msg := a.pending
+	if msg == nil {
+		delete(t.addrs, addr)
+		t.mu.Unlock()
+		return
+	}
+	a.pending = nil
+	t.clock.AfterFunc(t.interval, func() { t.windowEnded(addr, a) })
+	t.mu.Unlock()
+
+	a.sending.Lock()
+	defer a.sending.Unlock()
+	t.sendHeldBack(*msg)
+}
+
+// Flush sends the messages that are held back at once, instead of when their windows end.
+// It returns the first error, and the windows of their addresses stay open.
+func (t *ThrottledSender) Flush() error {
+	t.mu.Lock()
+	type heldBack struct {
+		a   *throttledAddress
+		msg Message
+	}
+	var msgs []heldBack
+	for _, a := range t.addrs {
+		if a.pending != nil {
+			msgs = append(msgs, heldBack{a: a, msg: *a.pending})
+			a.pending = nil
+		}
+	}
+	t.mu.Unlock()
+
+	var first error
+	for _, h := range msgs {
+		h.a.sending.Lock()
+		if err := t.conn.Send(h.msg); err != nil && first == nil {
+			first = err
+		}
+		h.a.sending.Unlock()
+	}
+	return first
+}
+
+// sendHeldBack sends a message that was held back, and passes the error
+// to the error handler of the conn, if it has one.
+func (t *ThrottledSender) sendHeldBack(msg Message) {
+	err := t.conn.Send(msg)
+	if err == nil {
+		return
+	}
+	if h, ok := t.conn.(interface{ handleError(error) }); ok {
+		h.handleError(err)
+	}
+}
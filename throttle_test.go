@@ -0,0 +1,148 @@
+package osc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingConn is a Conn that keeps the packets it is asked to send,
+// and the errors that are passed to its error handler.
+type recordingConn struct {
+	Conn
+
+	mu   sync.Mutex
+	sent []Packet
+	err  error
+	errs []error
+}
+
+func (c *recordingConn) Send(p Packet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	c.sent = append(c.sent, p)
+	return nil
+}
+
+func (c *recordingConn) handleError(err error) {
+	c.mu.Lock()
+	c.errs = append(c.errs, err)
+	c.mu.Unlock()
+}
+
+// takeSent returns the packets that were sent since the last call.
+func (c *recordingConn) takeSent() []Packet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sent := c.sent
+	c.sent = nil
+	return sent
+}
+
+func TestThrottledSender(t *testing.T) {
+	var (
+		conn   = &recordingConn{}
+		clock  = newFakeClock()
+		sender = NewThrottledSender(conn, 10*time.Millisecond)
+		bundle = Bundle{Timetag: Immediately, Packets: []Packet{MustMessage("/fader", int32(0))}}
+	)
+	sender.clock = clock
+
+	for i, testcase := range []struct {
+		Send    []Packet
+		Advance time.Duration
+		Sent    []Packet
+	}{
+		// The first message is sent at once, and the ones in its window are held back.
+		{
+			Send: []Packet{MustMessage("/fader", int32(1)), MustMessage("/fader", int32(2)), MustMessage("/fader", int32(3))},
+			Sent: []Packet{MustMessage("/fader", int32(1))},
+		},
+		// Other addresses and bundles are not held up.
+		{
+			Send: []Packet{MustMessage("/other", int32(1)), bundle},
+			Sent: []Packet{MustMessage("/other", int32(1)), bundle},
+		},
+		{Advance: 5 * time.Millisecond},
+		// The last message of the window is sent when it ends.
+		{Advance: 5 * time.Millisecond, Sent: []Packet{MustMessage("/fader", int32(3))}},
+		// And it opens the next window.
+		{Send: []Packet{MustMessage("/fader", int32(4))}},
+		{Advance: 10 * time.Millisecond, Sent: []Packet{MustMessage("/fader", int32(4))}},
+		// A window without messages closes the address's last one.
+		{Advance: 10 * time.Millisecond},
+		{
+			Send: []Packet{MustMessage("/fader", int32(5)), MustMessage("/other", int32(2))},
+			Sent: []Packet{MustMessage("/fader", int32(5)), MustMessage("/other", int32(2))},
+		},
+	} {
+		for _, p := range testcase.Send {
+			if err := sender.Send(p); err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+		}
+		clock.Advance(testcase.Advance)
+		sent := conn.takeSent()
+		if len(sent) != len(testcase.Sent) {
+			t.Fatalf("(testcase %d) expected %v to be sent, got %v", i, testcase.Sent, sent)
+		}
+		for j, p := range sent {
+			if !p.Equal(testcase.Sent[j]) {
+				t.Fatalf("(testcase %d) expected %v to be sent, got %v", i, testcase.Sent, sent)
+			}
+		}
+	}
+	if len(sender.addrs) != 2 {
+		t.Fatalf("expected 2 addresses in a window, got %d", len(sender.addrs))
+	}
+	clock.Advance(10 * time.Millisecond)
+	if len(sender.addrs) != 0 {
+		t.Fatalf("expected no address in a window, got %d", len(sender.addrs))
+	}
+}
+
+func TestThrottledSender_Flush(t *testing.T) {
+	var (
+		conn   = &recordingConn{}
+		clock  = newFakeClock()
+		sender = NewThrottledSender(conn, 10*time.Millisecond)
+	)
+	sender.clock = clock
+
+	for _, value := range []int32{1, 2} {
+		if err := sender.Send(MustMessage("/fader", value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sender.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if sent := conn.takeSent(); len(sent) != 2 || !sent[1].Equal(MustMessage("/fader", int32(2))) {
+		t.Fatalf("expected the held back message to be flushed, got %v", sent)
+	}
+	clock.Advance(10 * time.Millisecond)
+	if sent := conn.takeSent(); len(sent) != 0 {
+		t.Fatalf("expected nothing to be sent, got %v", sent)
+	}
+
+	// The errors of the messages sent when their window ends go to the error handler.
+	for _, value := range []int32{3, 4} {
+		if err := sender.Send(MustMessage("/fader", value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oops := errors.New("oops")
+	conn.mu.Lock()
+	conn.err = oops
+	conn.mu.Unlock()
+	clock.Advance(10 * time.Millisecond)
+	if len(conn.errs) != 1 || !errors.Is(conn.errs[0], oops) {
+		t.Fatalf("expected the error to be handled, got %v", conn.errs)
+	}
+}
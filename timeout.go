@@ -0,0 +1,57 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMethodTimeout is returned when a method takes longer than its timeout.
+var ErrMethodTimeout = errors.New("method timed out")
+
+// WithTimeout returns a handler that gives up on handler once it has spent d on a message,
+// and returns an error wrapping ErrMethodTimeout.
+// The timeout overrides the one set with Router.SetTimeout.
+//
+// Goroutines can not be stopped, so handler keeps running after the timeout,
+// and whatever it returns then is discarded. Methods that may take long should
+// stop when the context of the message is done.
+func WithTimeout(d time.Duration, handler MessageHandler) MessageHandler {
+	return timeoutHandler{timeout: d, handler: handler}
+}
+
+// timeoutHandler is a handler with a timeout.
+type timeoutHandler struct {
+	timeout time.Duration
+	handler MessageHandler
+}
+
+// Handle handles an OSC message.
+func (h timeoutHandler) Handle(msg Message) error {
+	if h.timeout <= 0 {
+		return h.handler.Handle(msg)
+	}
+	ctx, cancel := context.WithTimeout(msg.Context(), h.timeout)
+	defer cancel()
+	msg.ctx = ctx
+	if msg.view.isSet() {
+		msg = msg.Clone() // The method may outlive the read buffer.
+	}
+
+	// The conn decides whether a panic after the method was given up on crashes the program.
+	recoverPanics := msg.replier != nil && msg.replier.recoverPanics()
+	done := make(chan error, 1)
+	go func() {
+		done <- callMethods(recoverPanics, func() error { return h.handler.Handle(msg) })
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			return fmt.Errorf("%s: %w", msg.MethodAddress(), ctx.Err())
+		}
+		return fmt.Errorf("%s after %s: %w", msg.MethodAddress(), h.timeout, ErrMethodTimeout)
+	}
+}
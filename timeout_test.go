@@ -0,0 +1,123 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRouterTimeout(t *testing.T) {
+	var (
+		r         = NewRouter()
+		cancelled = make(chan error, 1)
+		block     = make(chan struct{})
+	)
+	defer close(block)
+
+	r.SetTimeout(20 * time.Millisecond)
+	for addr, method := range map[string]MessageHandler{
+		"/fast": Method(func(msg Message) error { return nil }),
+		"/slow": Method(func(msg Message) error {
+			<-msg.Context().Done()
+			cancelled <- msg.Context().Err()
+			return nil
+		}),
+		"/stuck": Method(func(msg Message) error {
+			<-block
+			return nil
+		}),
+		"/patient": WithTimeout(time.Hour, Method(func(msg Message) error {
+			time.Sleep(40 * time.Millisecond)
+			return nil
+		})),
+	} {
+		if err := r.AddMethod(addr, method); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, addr := range []string{"/fast", "/patient"} {
+		if err := r.Invoke(Message{Address: addr}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, addr := range []string{"/slow", "/stuck"} {
+		err := r.Invoke(Message{Address: addr}, false)
+		if !errors.Is(err, ErrMethodTimeout) {
+			t.Fatalf("expected %v, got %v", ErrMethodTimeout, err)
+		}
+		if expected, got := addr+" after 20ms: method timed out", err.Error(); expected != got {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+	}
+	select {
+	case err := <-cancelled:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /slow to see its context done")
+	}
+}
+
+func TestServeTimeout(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		r     = NewRouter()
+		errs  = make(chan error, 1)
+		fast  = make(chan struct{}, 1)
+		block = make(chan struct{})
+	)
+	defer close(block)
+
+	r.SetTimeout(50 * time.Millisecond)
+	if err := r.AddMethod("/stuck", Method(func(msg Message) error {
+		<-block
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddMethod("/fast", Method(func(msg Message) error {
+		fast <- struct{}{}
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	c2.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = c2.Serve(2, r)
+	}()
+	for _, addr := range []string{"/stuck", "/fast"} {
+		if err := c1.Send(Message{Address: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The fast method is not held up by the stuck one.
+	select {
+	case <-fast:
+	case err := <-errs:
+		t.Fatalf("expected /fast before an error, got %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /fast")
+	}
+	select {
+	case err := <-errs:
+		if _, ok := err.(*MethodError); !ok {
+			t.Fatalf("expected *MethodError, got %T", err)
+		}
+		if !errors.Is(err, ErrMethodTimeout) {
+			t.Fatalf("expected %v, got %v", ErrMethodTimeout, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the timeout error")
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("expected a single error, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
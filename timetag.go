@@ -3,9 +3,10 @@ package osc
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 const (
@@ -42,16 +43,105 @@ func (tt Timetag) String() string {
 }
 
 // Time converts an OSC timetag to a time.Time.
+// Timetags only have 32 bits of seconds, which roll over on 2036-02-07.
+// Following RFC 4330, timetags whose most significant bit is set are
+// in the range 1968-2036 and the others are in the range 2036-2104.
 func (tt Timetag) Time() time.Time {
-	secs := (uint64(tt) >> 32) - SecondsFrom1900To1970
-	return time.Unix(int64(secs), int64(tt)&0xFFFFFFFF).UTC()
+	var (
+		secs = int64(uint32(tt >> 32))
+		frac = uint64(uint32(tt))
+	)
+	if secs&0x80000000 == 0 {
+		secs += 1 << 32
+	}
+	// Round the fraction to the nearest nanosecond.
+	nsecs := (frac*1e9 + 1<<31) >> 32
+
+	return time.Unix(secs-SecondsFrom1900To1970, int64(nsecs)).UTC()
 }
 
 // FromTime converts the given time to an OSC timetag.
+// Times outside of the range 1968-2104 can not be represented
+// and will be converted to a time in that range.
 func FromTime(t time.Time) Timetag {
-	t = t.UTC()
-	secs := uint64((SecondsFrom1900To1970 + t.Unix()) << 32)
-	return Timetag(secs + uint64(uint32(t.Nanosecond())))
+	var (
+		secs = uint64(uint32(t.Unix() + SecondsFrom1900To1970))
+		frac = (uint64(t.Nanosecond())<<32 + 5e8) / 1e9
+	)
+	return Timetag(secs<<32 | frac)
+}
+
+// TimetagAfter returns the timetag for the time d from now.
+func TimetagAfter(d time.Duration) Timetag {
+	return FromTime(time.Now().Add(d))
+}
+
+// ReadTimetagFrom reads a timetag argument from a byte slice.
+func ReadTimetagFrom(data []byte) (Argument, int64, error) {
+	if len(data) < TimetagSize {
+		return nil, 0, fmt.Errorf("read timetag argument: %d bytes left: %w", len(data), io.ErrUnexpectedEOF)
+	}
+	return Timetag(byteOrder.Uint64(data)), TimetagSize, nil
+}
+
+// Equal returns true if the argument equals the other one, false otherwise.
+func (tt Timetag) Equal(other Argument) bool {
+	if other.Typetag() != TypetagTimetag {
+		return false
+	}
+	tt2, ok := other.(Timetag)
+	if !ok {
+		return equalEncoded(tt, other)
+	}
+	return tt == tt2
+}
+
+// ReadInt32 reads a 32-bit integer from the arg.
+func (tt Timetag) ReadInt32() (int32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadInt64 reads a 64-bit integer from the arg.
+func (tt Timetag) ReadInt64() (int64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat32 reads a 32-bit float from the arg.
+func (tt Timetag) ReadFloat32() (float32, error) { return 0, ErrInvalidTypeTag }
+
+// ReadFloat64 reads a 64-bit float from the arg.
+func (tt Timetag) ReadFloat64() (float64, error) { return 0, ErrInvalidTypeTag }
+
+// ReadBool bool reads a boolean from the arg.
+func (tt Timetag) ReadBool() (bool, error) { return false, ErrInvalidTypeTag }
+
+// ReadString string reads a string from the arg.
+func (tt Timetag) ReadString() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadBlob reads a slice of bytes from the arg.
+func (tt Timetag) ReadBlob() ([]byte, error) { return nil, ErrInvalidTypeTag }
+
+// ReadChar reads an ASCII character from the arg.
+func (tt Timetag) ReadChar() (rune, error) { return 0, ErrInvalidTypeTag }
+
+// ReadRGBA reads a color from the arg.
+func (tt Timetag) ReadRGBA() (RGBA, error) { return RGBA{}, ErrInvalidTypeTag }
+
+// ReadMIDI reads a MIDI message from the arg.
+func (tt Timetag) ReadMIDI() (MIDI, error) { return MIDI{}, ErrInvalidTypeTag }
+
+// ReadSymbol reads a symbol from the arg.
+func (tt Timetag) ReadSymbol() (string, error) { return "", ErrInvalidTypeTag }
+
+// ReadTimetag reads a timetag from the arg.
+func (tt Timetag) ReadTimetag() (Timetag, error) { return tt, nil }
+
+// ReadArray reads an array of arguments from the arg.
+func (tt Timetag) ReadArray() (Arguments, error) { return nil, ErrInvalidTypeTag }
+
+// Typetag returns the argument's type tag.
+func (tt Timetag) Typetag() byte { return TypetagTimetag }
+
+// WriteTo writes the arg to an io.Writer.
+func (tt Timetag) WriteTo(w io.Writer) (int64, error) {
+	written, err := fmt.Fprint(w, tt.String())
+	return int64(written), err
 }
 
 // ReadTimetag parses a timetag from a byte slice.
@@ -2,10 +2,9 @@ package osc
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 func TestFromTime(t *testing.T) {
@@ -25,6 +24,74 @@ func TestFromTime(t *testing.T) {
 	}
 }
 
+func TestTimetagNTP(t *testing.T) {
+	for i, testcase := range []struct {
+		Timetag Timetag
+		Time    time.Time
+	}{
+		{
+			// The Unix epoch.
+			Timetag: 0x83AA7E80 << 32,
+			Time:    time.Unix(0, 0),
+		},
+		{
+			Timetag: 0xBC17C200<<32 | 0x80000000,
+			Time:    time.Date(2000, 1, 1, 0, 0, 0, 5e8, time.UTC),
+		},
+		{
+			Timetag: 0xBC17C200<<32 | 0x40000000,
+			Time:    time.Date(2000, 1, 1, 0, 0, 0, 25e7, time.UTC),
+		},
+		{
+			// One microsecond is 4294.967296 units of 2^-32 seconds.
+			Timetag: 0xBC17C200<<32 | 4295,
+			Time:    time.Date(2000, 1, 1, 0, 0, 0, 1000, time.UTC),
+		},
+		{
+			// The earliest time that can be represented.
+			Timetag: 0x80000000 << 32,
+			Time:    time.Date(1968, 1, 20, 3, 14, 8, 0, time.UTC),
+		},
+		{
+			// The 2036 rollover.
+			Timetag: 0,
+			Time:    time.Date(2036, 2, 7, 6, 28, 16, 0, time.UTC),
+		},
+		{
+			Timetag: 0x0754FD00 << 32,
+			Time:    time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		if expected, got := testcase.Timetag, FromTime(testcase.Time); expected != got {
+			t.Fatalf("(testcase %d) expected 0x%016X, got 0x%016X", i, uint64(expected), uint64(got))
+		}
+		if expected, got := testcase.Time, testcase.Timetag.Time(); !expected.Equal(got) {
+			t.Fatalf("(testcase %d) expected %s, got %s", i, expected, got)
+		}
+	}
+}
+
+func TestTimetagPrecision(t *testing.T) {
+	// Round trips are exact to the nanosecond, since 2^-32 seconds is about 233 picoseconds.
+	for _, nsec := range []int{0, 1, 499999999, 500000000, 999999999, 123456789} {
+		expected := time.Date(2020, 6, 1, 12, 0, 0, nsec, time.UTC)
+		if got := FromTime(expected).Time(); !expected.Equal(got) {
+			t.Fatalf("expected %s, got %s", expected.Format(time.RFC3339Nano), got.Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func TestTimetagAfter(t *testing.T) {
+	var (
+		before = time.Now().Add(time.Second)
+		tt     = TimetagAfter(time.Second)
+		after  = time.Now().Add(time.Second)
+	)
+	if got := tt.Time(); got.Before(before.Add(-time.Microsecond)) || got.After(after.Add(time.Microsecond)) {
+		t.Fatalf("expected a time between %s and %s, got %s", before, after, got)
+	}
+}
+
 func TestTimetagBytes(t *testing.T) {
 	for _, testcase := range []struct {
 		Input    Timetag
@@ -50,7 +117,9 @@ func TestTimetagString(t *testing.T) {
 		Input    Timetag
 		Expected string
 	}{
-		{Input: Timetag(10), Expected: "1900-01-01T00:00:00Z"},
+		// Timetags with the most significant bit clear are after the 2036 rollover.
+		{Input: Timetag(10), Expected: "2036-02-07T06:28:16Z"},
+		{Input: Timetag(0x83AA7E80 << 32), Expected: "1970-01-01T00:00:00Z"},
 	} {
 		if expected, got := testcase.Expected, testcase.Input.String(); expected != got {
 			t.Fatalf("expected, %s, got %s", expected, got)
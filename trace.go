@@ -0,0 +1,86 @@
+package osc
+
+import (
+	"net"
+	"time"
+)
+
+// TraceEvent describes a message that a dispatcher handled.
+type TraceEvent struct {
+	// Address is the address pattern of the message.
+	Address string
+
+	// Method is the address of the method the message was passed to,
+	// which is Default for the Default method and empty if there is none.
+	Method string
+
+	// Matched is false if no method matched the message,
+	// whether or not the Default method handled it.
+	Matched bool
+
+	// Sender is the address the message came from.
+	Sender net.Addr
+
+	// Start is when the method was invoked, and Duration how long it took.
+	Start    time.Time
+	Duration time.Duration
+
+	// Err is the error returned by the method, or the *DispatchError wrapping
+	// ErrNoMatchingMethod for a message that no method handled.
+	Err error
+}
+
+// traceFunc is the type the trace func is stored as, so that
+// atomic.Value always holds the same type, even for a nil func.
+type traceFunc func(TraceEvent)
+
+// SetTraceFunc makes the PatternMatching dispatchers and the Routers served by the
+// conn call trace after each method they invoke returns, and for every message
+// that no method matched. trace is called by the workers of Serve, so it must be
+// safe for concurrent use. It may be set, replaced or removed with nil at any time,
+// and nothing is allocated for tracing while there is no trace func.
+func (s *connState) SetTraceFunc(trace func(TraceEvent)) {
+	s.hooks.trace.Store(traceFunc(trace))
+}
+
+// tracer returns the trace func, or nil if there is none.
+func (s *connState) tracer() func(TraceEvent) {
+	trace, _ := s.hooks.trace.Load().(traceFunc)
+	return trace
+}
+
+// handleMessage passes msg to handler, the method registered at address,
+// and traces it if the conn that msg was read from has a trace func.
+func handleMessage(handler MessageHandler, msg Message, address string) error {
+	msg.method = address
+
+	var trace func(TraceEvent)
+	if msg.replier != nil {
+		trace = msg.replier.tracer()
+	}
+	if trace == nil {
+		return handler.Handle(msg)
+	}
+	start := time.Now()
+	err := handler.Handle(msg)
+	trace(TraceEvent{
+		Address:  msg.ownedAddress(),
+		Method:   address,
+		Matched:  address != Default,
+		Sender:   msg.Sender,
+		Start:    start,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+	return err
+}
+
+// traceUnmatched traces a message that no method handled.
+func traceUnmatched(msg Message, err error) {
+	if msg.replier == nil {
+		return
+	}
+	if trace := msg.replier.tracer(); trace != nil {
+		trace(TraceEvent{Address: msg.ownedAddress(), Sender: msg.Sender, Start: time.Now(), Err: err})
+	}
+}
@@ -0,0 +1,74 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+)
+
+// slogTracer returns a trace func that logs every message that is dispatched with logger,
+// as an error if its method failed.
+func slogTracer(logger *slog.Logger) func(TraceEvent) {
+	return func(e TraceEvent) {
+		attrs := []slog.Attr{
+			slog.String("address", e.Address),
+			slog.String("method", e.Method),
+			slog.Bool("matched", e.Matched),
+			slog.Duration("duration", e.Duration),
+		}
+		if e.Sender != nil {
+			attrs = append(attrs, slog.String("sender", e.Sender.String()))
+		}
+		level := slog.LevelInfo
+		if e.Err != nil {
+			level = slog.LevelError
+			attrs = append(attrs, slog.Any("err", e.Err))
+		}
+		logger.LogAttrs(context.Background(), level, "dispatch", attrs...)
+	}
+}
+
+func Example_slogTracer() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// The times change from run to run.
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	c1, c2 := Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	var (
+		trace  = slogTracer(logger)
+		traced = make(chan struct{})
+	)
+	c2.SetTraceFunc(func(e TraceEvent) {
+		trace(e)
+		traced <- struct{}{}
+	})
+	go func() {
+		_ = c2.Serve(1, PatternMatching{
+			"/synth/*": Method(func(msg Message) error {
+				return nil
+			}),
+			"/fail": Method(func(msg Message) error {
+				return errors.New("out of voices")
+			}),
+		})
+	}()
+	for _, addr := range []string{"/synth/1", "/fail", "/mixer/1"} {
+		if err := c1.Send(Message{Address: addr}); err != nil {
+			logger.Error("send", slog.Any("err", err))
+		}
+		<-traced
+	}
+	// Output:
+	// level=INFO msg=dispatch address=/synth/1 method=/synth/* matched=true sender=pipe:1
+	// level=ERROR msg=dispatch address=/fail method=/fail matched=true sender=pipe:1 err="out of voices"
+	// level=ERROR msg=dispatch address=/mixer/1 method="" matched=false sender=pipe:1 err="no method matches the address"
+}
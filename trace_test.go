@@ -0,0 +1,85 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetTraceFunc(t *testing.T) {
+	errFail := errors.New("fail")
+	ok := Method(func(msg Message) error {
+		return nil
+	})
+	fail := Method(func(msg Message) error {
+		return errFail
+	})
+	router := NewRouter()
+	if err := router.AddMethod("/synth/*", ok); err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		Dispatcher Dispatcher
+		Address    string
+		Method     string
+		Matched    bool
+		Err        error
+	}{
+		{Dispatcher: PatternMatching{"/synth/1": ok}, Address: "/synth/1", Method: "/synth/1", Matched: true},
+		{Dispatcher: PatternMatching{"/synth/*": ok}, Address: "/synth/1", Method: "/synth/*", Matched: true},
+		{Dispatcher: PatternMatching{"/synth/1": ok}, Address: "/synth/[1-2]", Method: "/synth/1", Matched: true},
+		{Dispatcher: PatternMatching{"/fail": fail}, Address: "/fail", Method: "/fail", Matched: true, Err: errFail},
+		{Dispatcher: PatternMatching{Default: ok}, Address: "/other", Method: Default},
+		{Dispatcher: PatternMatching{}, Address: "/other", Err: ErrNoMatchingMethod},
+		{Dispatcher: router, Address: "/synth/2", Method: "/synth/*", Matched: true},
+	} {
+		c1, c2 := Pipe()
+		events := make(chan TraceEvent, 1)
+		c2.SetTraceFunc(func(e TraceEvent) {
+			events <- e
+		})
+		go func(d Dispatcher) {
+			_ = c2.Serve(1, d)
+		}(testcase.Dispatcher)
+
+		start := time.Now()
+		if err := c1.Send(MustMessage(testcase.Address)); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-events:
+			if e.Address != testcase.Address || e.Method != testcase.Method || e.Matched != testcase.Matched {
+				t.Fatalf("(testcase %d) expected %s, %q and matched %t, got %s, %q and %t", i, testcase.Address, testcase.Method, testcase.Matched, e.Address, e.Method, e.Matched)
+			}
+			if e.Sender == nil || e.Sender.String() != c1.LocalAddr().String() {
+				t.Fatalf("(testcase %d) expected sender %s, got %v", i, c1.LocalAddr(), e.Sender)
+			}
+			if e.Start.Before(start) || e.Duration < 0 {
+				t.Fatalf("(testcase %d) expected a start after %s, got %s and duration %s", i, start, e.Start, e.Duration)
+			}
+			if (testcase.Err == nil) != (e.Err == nil) || !errors.Is(e.Err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected error %v, got %v", i, testcase.Err, e.Err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(testcase %d) timeout waiting for the trace", i)
+		}
+		_ = c1.Close() // Best effort.
+		_ = c2.Close() // Best effort.
+	}
+}
+
+func TestSetTraceFunc_Allocs(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	var (
+		d   = PatternMatching{"/synth/1": Method(func(msg Message) error { return nil })}
+		msg = MustMessage("/synth/1")
+	)
+	msg.replier = c1
+
+	if allocs := testing.AllocsPerRun(100, func() { _ = d.Invoke(msg, false) }); allocs != 0 {
+		t.Fatalf("expected no allocations without a trace func, got %v", allocs)
+	}
+}
@@ -0,0 +1,180 @@
+package osc
+
+import (
+	"sort"
+	"strings"
+)
+
+// The ways an address can match the method addresses in a trie.
+const (
+	// matchMessage matches the methods whose address the message's address pattern matches.
+	matchMessage = iota
+
+	// matchMethod matches the methods whose address pattern matches the message's address.
+	matchMethod
+
+	// matchEqual matches the method with the same address pattern as the message.
+	matchEqual
+)
+
+// methodTrie holds the addresses of methods split into their parts between slashes,
+// so that finding the methods that match an address takes time proportional to
+// the number of parts of the address rather than to the number of methods.
+// Parts with pattern characters are branches that are matched with the part of the address.
+// A trie is never modified once it is built.
+type methodTrie struct {
+	root trieNode
+}
+
+// trieNode is a part of the method addresses in a trie.
+type trieNode struct {
+	literals map[string]*trieNode
+	patterns []trieBranch // Sorted by part.
+
+	// address is the address of the method that ends at the node, if any.
+	address string
+}
+
+// trieBranch is a child of a node whose part is a pattern.
+type trieBranch struct {
+	part   string
+	tokens []patternToken
+	node   *trieNode
+}
+
+// newMethodTrie builds a trie of the addresses of methods, except Default.
+// Addresses that are not valid patterns are left out.
+func newMethodTrie(methods PatternMatching) *methodTrie {
+	t := &methodTrie{}
+	for addr := range methods {
+		if addr == Default {
+			continue
+		}
+		p, err := CompilePattern(addr)
+		if err != nil {
+			continue
+		}
+		t.insert(addr, p)
+	}
+	return t
+}
+
+// insert adds the address of a method, which compiles to p.
+func (t *methodTrie) insert(addr string, p *Pattern) {
+	n := &t.root
+	for i, part := range strings.Split(addr, string(MessageChar)) {
+		if !isPattern(part) {
+			child, ok := n.literals[part]
+			if !ok {
+				if n.literals == nil {
+					n.literals = map[string]*trieNode{}
+				}
+				child = &trieNode{}
+				n.literals[part] = child
+			}
+			n = child
+			continue
+		}
+		j := sort.Search(len(n.patterns), func(j int) bool { return n.patterns[j].part >= part })
+		if j == len(n.patterns) || n.patterns[j].part != part {
+			n.patterns = append(n.patterns, trieBranch{})
+			copy(n.patterns[j+1:], n.patterns[j:])
+			n.patterns[j] = trieBranch{part: part, tokens: p.parts[i], node: &trieNode{}}
+		}
+		n = n.patterns[j].node
+	}
+	n.address = addr
+}
+
+// matching returns the sorted addresses of the methods that PatternMatching.Invoke
+// would invoke with msg, not counting the Default method.
+func (t *methodTrie) matching(msg Message) ([]string, error) {
+	var tokens [][]patternToken
+	if isPattern(msg.Address) {
+		p, err := CompilePattern(msg.Address)
+		if err != nil {
+			return nil, err
+		}
+		tokens = p.parts
+	}
+	// Methods with a literal address take precedence.
+	matched := t.root.collect(msg.Address, tokens, 0, matchMessage, true, nil)
+	if len(matched) == 0 {
+		for _, mode := range []int{matchMessage, matchMethod, matchEqual} {
+			matched = t.root.collect(msg.Address, tokens, 0, mode, false, matched)
+		}
+	}
+	if len(matched) > 1 {
+		sort.Strings(matched)
+		matched = uniqueStrings(matched)
+	}
+	return matched, nil
+}
+
+// collect appends the addresses of the methods below n that match addr, the rest of the
+// address of a message after depth parts, to matched. tokens are the parts of the address
+// if it is a pattern. With literalsOnly only the methods with a literal address are collected,
+// and without it only the methods with a pattern.
+func (n *trieNode) collect(addr string, tokens [][]patternToken, depth, mode int, literalsOnly bool, matched []string) []string {
+	part, rest, last := addr, "", true
+	if i := strings.IndexByte(addr, MessageChar); i >= 0 {
+		part, rest, last = addr[:i], addr[i+1:], false
+	}
+	visit := func(child *trieNode) {
+		if !last {
+			matched = child.collect(rest, tokens, depth+1, mode, literalsOnly, matched)
+		} else if child.address != "" && literalsOnly != isPattern(child.address) {
+			matched = append(matched, child.address)
+		}
+	}
+	// matchesMessage returns true if the part of the message matches methodPart as it is.
+	matchesMessage := func(methodPart string) bool {
+		if tokens == nil {
+			return part == methodPart
+		}
+		return matchPart(tokens[depth], methodPart)
+	}
+	if mode == matchMessage && tokens != nil && !isLiteralPart(tokens[depth]) {
+		for key, child := range n.literals {
+			if matchesMessage(key) {
+				visit(child)
+			}
+		}
+	} else if child, ok := n.literals[part]; ok {
+		visit(child)
+	}
+	if literalsOnly {
+		return matched
+	}
+	for _, b := range n.patterns {
+		var ok bool
+		switch mode {
+		case matchMessage:
+			ok = matchesMessage(b.part)
+		case matchMethod:
+			ok = matchPart(b.tokens, part)
+		case matchEqual:
+			ok = b.part == part
+		}
+		if ok {
+			visit(b.node)
+		}
+	}
+	return matched
+}
+
+// isLiteralPart returns true if tokens are a part of a pattern without any special characters.
+func isLiteralPart(tokens []patternToken) bool {
+	return len(tokens) == 0 || (len(tokens) == 1 && tokens[0].kind == tokenLiteral)
+}
+
+// uniqueStrings removes the duplicates from the sorted slice ss.
+func uniqueStrings(ss []string) []string {
+	out := ss[:1]
+	for _, s := range ss[1:] {
+		if s != out[len(out)-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
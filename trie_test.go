@@ -0,0 +1,75 @@
+package osc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMethodTrieMatching(t *testing.T) {
+	methods := PatternMatching{}
+	for _, addr := range []string{
+		"/fader/*", "/fader/[0-9]", "/fader/1", "/{xy,rotary}/1",
+		"/mixer/1/level", "/mixer/2/level", "/mixer/2/pan", "/mixer/*/mute",
+		"/synth/?/freq", "/synth/[!a-z]*/gain", "/a", "/a/b/c", Default,
+	} {
+		methods[addr] = Method(func(msg Message) error { return nil })
+	}
+	trie := newMethodTrie(methods)
+	for i, addr := range []string{
+		"/fader/1", "/fader/2", "/fader/10", "/rotary/1", "/fader/2/x",
+		"/fader/?", "/fader/[0-9]", "/fader/*", "/{xy,rotary}/1", "/*/1",
+		"/mixer/*/level", "/mixer/[0-9]/{level,pan}", "/mixer/3/mute", "/mixer/*/*",
+		"/synth/1/freq", "/synth/12/freq", "/synth/9x/gain", "/synth/?/freq",
+		"/a", "/*", "/a/*/c", "/a/b", "/b", "/*/*/*",
+	} {
+		msg := Message{Address: addr}
+		expected, err := methods.matching(msg, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := trie.matching(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(expected, " ") != strings.Join(got, " ") {
+			t.Fatalf("(testcase %d) %s: expected %q, got %q", i, addr, expected, got)
+		}
+	}
+	if _, err := trie.matching(Message{Address: "/a["}); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
+func BenchmarkRouterMatching(b *testing.B) {
+	var (
+		methods = PatternMatching{}
+		noop    = Method(func(msg Message) error { return nil })
+	)
+	for ch := 0; ch < 1000; ch++ {
+		for _, param := range []string{"level", "pan", "mute", "solo"} {
+			methods["/mixer/"+strconv.Itoa(ch)+"/"+param] = noop
+		}
+		methods[fmt.Sprintf("/fx/%d/*", ch)] = noop
+	}
+	r := NewRouter()
+	for addr, method := range methods {
+		if err := r.AddMethod(addr, method); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, addr := range []string{"/mixer/7/level", "/mixer/7/*", "/fx/7/param"} {
+		msg := Message{Address: addr}
+		for name, d := range map[string]Dispatcher{"linear": methods, "trie": r} {
+			b.Run(addr+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := d.Invoke(msg, false); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
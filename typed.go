@@ -0,0 +1,208 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTypetagMismatch is matched by the *TypetagError of a message
+// whose typetags a typed method does not expect.
+var ErrTypetagMismatch = errors.New("typetag mismatch")
+
+// The characters of the typetags a typed method expects that are not typetags themselves.
+const (
+	// TypetagAny stands for any argument, including an array.
+	TypetagAny byte = '*'
+
+	// TypetagOptional follows the typetags of the optional arguments,
+	// which have to come after the ones that are required.
+	TypetagOptional byte = '?'
+)
+
+// TypetagError is the error caused by a message that a typed method was not passed,
+// because its typetags are not the ones the method expects.
+// It matches ErrTypetagMismatch with errors.Is.
+type TypetagError struct {
+	// Address is the address pattern of the message.
+	Address string
+
+	// Expected are the typetags the method expects, and Actual those of the message,
+	// both without the leading ','.
+	Expected string
+	Actual   string
+}
+
+// Error returns the error message.
+func (e *TypetagError) Error() string {
+	return fmt.Sprintf("%s: expected typetags %q, got %q: %v", e.Address, e.Expected, e.Actual, ErrTypetagMismatch)
+}
+
+// Unwrap returns ErrTypetagMismatch.
+func (e *TypetagError) Unwrap() error { return ErrTypetagMismatch }
+
+// WithTypetags returns a handler that declares that handler expects messages
+// with the given typetags, without the leading ',', such as "if" for an int
+// and a float. Messages with other typetags are not passed to handler,
+// and a *TypetagError is returned instead, which Serve passes to the error handler.
+//
+// TypetagAny stands for any argument, and TypetagOptional after a typetag
+// makes its argument optional, so "s*?" expects a string followed by zero
+// or one argument of any type. Only the arguments at the end can be optional,
+// and arrays are matched as a single argument, like "[ff]".
+//
+// The typetags are what the oscquery package reports as the TYPE of the method,
+// so WithTypetags should wrap the handler that is added to the dispatcher.
+// It panics if the typetags are not valid; use AddTyped to get an error instead.
+func WithTypetags(typetags string, handler MessageHandler) MessageHandler {
+	if err := validateTypetags(typetags); err != nil {
+		panic(err)
+	}
+	return typedHandler{typetags: typetags, handler: handler}
+}
+
+// AddTyped adds method at addr, declaring that it expects messages with typetags,
+// which are described by WithTypetags. Invalid typetags are rejected with an error
+// wrapping ErrInvalidTypeTag.
+func (r *Router) AddTyped(addr, typetags string, method MessageHandler) error {
+	if err := validateTypetags(typetags); err != nil {
+		return fmt.Errorf("%s: %w", addr, err)
+	}
+	if method == nil {
+		return fmt.Errorf("nil method for %s", addr)
+	}
+	return r.AddMethod(addr, typedHandler{typetags: typetags, handler: method})
+}
+
+// AddTyped adds method at addr, declaring that it expects messages with typetags,
+// which are described by WithTypetags. Invalid typetags are rejected with an error
+// wrapping ErrInvalidTypeTag.
+func (h PatternMatching) AddTyped(addr, typetags string, method MessageHandler) error {
+	if err := validateTypetags(typetags); err != nil {
+		return fmt.Errorf("%s: %w", addr, err)
+	}
+	if method == nil {
+		return fmt.Errorf("nil method for %s", addr)
+	}
+	h[addr] = typedHandler{typetags: typetags, handler: method}
+	return nil
+}
+
+// typedHandler is a handler that declares the typetags it expects.
+type typedHandler struct {
+	typetags string
+	handler  MessageHandler
+}
+
+// Handle handles an OSC message.
+func (h typedHandler) Handle(msg Message) error {
+	var buf [16]byte
+	if actual := appendTypetags(buf[:0], msg.arguments()); !matchTypetags(h.typetags, actual) {
+		return &TypetagError{Address: msg.ownedAddress(), Expected: h.typetags, Actual: string(actual)}
+	}
+	return h.handler.Handle(msg)
+}
+
+// Typetags returns the typetags the handler expects.
+func (h typedHandler) Typetags() string {
+	return h.typetags
+}
+
+// validateTypetags checks the typetags a typed method expects.
+func validateTypetags(typetags string) error {
+	optional := false
+	for i := 0; i < len(typetags); {
+		end, err := nextTypetag(typetags, i)
+		if err != nil {
+			return err
+		}
+		if end < len(typetags) && typetags[end] == TypetagOptional {
+			optional = true
+			end++
+		} else if optional {
+			return fmt.Errorf("typetags %q: required argument %d after an optional one: %w", typetags, i, ErrInvalidTypeTag)
+		}
+		i = end
+	}
+	return nil
+}
+
+// nextTypetag returns the end of the argument whose typetag starts at i,
+// which is past the closing ']' of an array.
+func nextTypetag(typetags string, i int) (int, error) {
+	switch c := typetags[i]; c {
+	case TypetagArrayStart:
+		depth := 0
+		for j := i; j < len(typetags); j++ {
+			switch typetags[j] {
+			case TypetagArrayStart:
+				depth++
+			case TypetagArrayEnd:
+				if depth--; depth == 0 {
+					return j + 1, nil
+				}
+			case TypetagAny, TypetagOptional:
+				return 0, fmt.Errorf("typetags %q: %q in an array: %w", typetags, typetags[j], ErrInvalidTypeTag)
+			default:
+				if !strings.ContainsRune(scalarTypetags, rune(typetags[j])) {
+					return 0, fmt.Errorf("typetags %q: unknown typetag %q: %w", typetags, typetags[j], ErrInvalidTypeTag)
+				}
+			}
+		}
+		return 0, fmt.Errorf("typetags %q: unterminated array: %w", typetags, ErrInvalidTypeTag)
+	case TypetagAny:
+		return i + 1, nil
+	default:
+		if !strings.ContainsRune(scalarTypetags, rune(c)) {
+			return 0, fmt.Errorf("typetags %q: unknown typetag %q: %w", typetags, c, ErrInvalidTypeTag)
+		}
+		return i + 1, nil
+	}
+}
+
+// scalarTypetags are the typetags of the arguments that are not arrays.
+const scalarTypetags = "ifsbFTStdhcrmNI"
+
+// matchTypetags reports whether actual, the typetags of a message, are the ones
+// that the valid typetags expected describe.
+func matchTypetags(expected string, actual []byte) bool {
+	i, j := 0, 0
+	for i < len(expected) {
+		end, _ := nextTypetag(expected, i)
+		optional := end < len(expected) && expected[end] == TypetagOptional
+		if j == len(actual) {
+			if !optional {
+				return false
+			}
+		} else {
+			next := nextActualTypetag(actual, j)
+			if expected[i] != TypetagAny && expected[i:end] != string(actual[j:next]) {
+				return false
+			}
+			j = next
+		}
+		i = end
+		if optional {
+			i++
+		}
+	}
+	return j == len(actual)
+}
+
+// nextActualTypetag returns the end of the argument whose typetag starts at i in the
+// typetags of a message, which is past the closing ']' of an array.
+func nextActualTypetag(actual []byte, i int) int {
+	depth := 0
+	for j := i; j < len(actual); j++ {
+		switch actual[j] {
+		case TypetagArrayStart:
+			depth++
+		case TypetagArrayEnd:
+			depth--
+		}
+		if depth == 0 {
+			return j + 1
+		}
+	}
+	return len(actual)
+}
@@ -0,0 +1,131 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTypetags(t *testing.T) {
+	for i, testcase := range []struct {
+		Typetags string
+		Message  Message
+		Err      error
+	}{
+		{Typetags: "if", Message: MustMessage("/a", Int(1), Float(2))},
+		{Typetags: "if", Message: MustMessage("/a", Int(1)), Err: ErrTypetagMismatch},
+		{Typetags: "if", Message: MustMessage("/a", Float(2), Int(1)), Err: ErrTypetagMismatch},
+		{Typetags: "if", Message: MustMessage("/a", Int(1), Float(2), Int(3)), Err: ErrTypetagMismatch},
+		{Typetags: "", Message: MustMessage("/a")},
+		{Typetags: "", Message: MustMessage("/a", Int(1)), Err: ErrTypetagMismatch},
+		{Typetags: "s*", Message: MustMessage("/a", String("x"), Float(2))},
+		{Typetags: "s*", Message: MustMessage("/a", String("x"), Array{Int(1), Int(2)})},
+		{Typetags: "s*", Message: MustMessage("/a", String("x")), Err: ErrTypetagMismatch},
+		{Typetags: "if?s?", Message: MustMessage("/a", Int(1))},
+		{Typetags: "if?s?", Message: MustMessage("/a", Int(1), Float(2))},
+		{Typetags: "if?s?", Message: MustMessage("/a", Int(1), Float(2), String("x"))},
+		{Typetags: "if?s?", Message: MustMessage("/a", Int(1), String("x")), Err: ErrTypetagMismatch},
+		{Typetags: "i*?", Message: MustMessage("/a", Int(1), Bool(true))},
+		{Typetags: "[ff]", Message: MustMessage("/a", Array{Float(1), Float(2)})},
+		{Typetags: "[ff]", Message: MustMessage("/a", Array{Float(1)}), Err: ErrTypetagMismatch},
+		{Typetags: "[ff]", Message: MustMessage("/a", Float(1), Float(2)), Err: ErrTypetagMismatch},
+	} {
+		handled := 0
+		h := WithTypetags(testcase.Typetags, Method(func(msg Message) error {
+			handled++
+			return nil
+		}))
+		err := h.Handle(testcase.Message)
+		if (testcase.Err == nil) != (err == nil) || !errors.Is(err, testcase.Err) {
+			t.Fatalf("(testcase %d) expected error %v, got %v", i, testcase.Err, err)
+		}
+		expected := 1
+		if testcase.Err != nil {
+			expected = 0
+			var typetagErr *TypetagError
+			if !errors.As(err, &typetagErr) || typetagErr.Expected != testcase.Typetags || typetagErr.Actual != string(appendTypetags(nil, testcase.Message.Arguments)) {
+				t.Fatalf("(testcase %d) expected a *TypetagError with the expected and actual typetags, got %#v", i, err)
+			}
+		}
+		if handled != expected {
+			t.Fatalf("(testcase %d) expected the method to be called %d times, got %d", i, expected, handled)
+		}
+		if typed, ok := h.(interface{ Typetags() string }); !ok || typed.Typetags() != testcase.Typetags {
+			t.Fatalf("(testcase %d) expected the handler to declare typetags %q", i, testcase.Typetags)
+		}
+	}
+}
+
+func TestAddTyped(t *testing.T) {
+	nop := Method(func(msg Message) error { return nil })
+	for i, testcase := range []struct {
+		Typetags string
+		Err      error
+	}{
+		{Typetags: "ifs"},
+		{Typetags: "i[f[ss]]*?"},
+		{Typetags: "x", Err: ErrInvalidTypeTag},
+		{Typetags: "i?f", Err: ErrInvalidTypeTag},
+		{Typetags: "i??", Err: ErrInvalidTypeTag},
+		{Typetags: "[f", Err: ErrInvalidTypeTag},
+		{Typetags: "[*]", Err: ErrInvalidTypeTag},
+	} {
+		for _, d := range []interface {
+			AddTyped(addr, typetags string, method MessageHandler) error
+		}{NewRouter(), PatternMatching{}} {
+			err := d.AddTyped("/synth/freq", testcase.Typetags, nop)
+			if (testcase.Err == nil) != (err == nil) || !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) %T: expected error %v, got %v", i, d, testcase.Err, err)
+			}
+		}
+	}
+}
+
+func TestAddTyped_Serve(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+	defer func() { _ = c2.Close() }() // Best effort.
+
+	var (
+		errs    = make(chan error, 1)
+		handled = make(chan Message, 1)
+		router  = NewRouter()
+	)
+	if err := router.AddTyped("/synth/freq", "if", Method(func(msg Message) error {
+		handled <- msg
+		return nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	c2.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = c2.Serve(1, router)
+	}()
+
+	if err := c1.Send(MustMessage("/synth/freq", String("440"))); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		var typetagErr *TypetagError
+		if !errors.As(err, &typetagErr) || typetagErr.Expected != "if" || typetagErr.Actual != "s" {
+			t.Fatalf("expected a typetag mismatch, got %v", err)
+		}
+	case msg := <-handled:
+		t.Fatalf("expected the mismatched message not to be handled, got %s", msg)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the typetag mismatch")
+	}
+	if err := c1.Send(MustMessage("/synth/freq", Int(1), Float(440))); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	case <-handled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the message to be handled")
+	}
+}
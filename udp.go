@@ -2,128 +2,484 @@ package osc
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
-
-	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
 )
 
+// ErrAddressFamily is returned when a connected conn is asked to send to an address
+// whose family, IPv4 or IPv6, is not the family of its peer.
+var ErrAddressFamily = errors.New("address family does not match the peer of the conn")
+
 // udpConn includes exactly the methods we need from *net.UDPConn
 type udpConn interface {
 	net.Conn
 	netWriter
 
 	ReadFromUDP([]byte) (int, *net.UDPAddr, error)
+	SetReadBuffer(bytes int) error
 }
 
 // UDPConn is an OSC connection over UDP.
+//
+// Send, SendTo, SendBundle and SendBundleTo may be called by any number of
+// goroutines at once, including the methods invoked by Serve. Every packet is
+// encoded into a buffer that is locked until it has been written in a single
+// datagram, so concurrent packets are never interleaved, although they may
+// arrive in any order, or not at all, as UDP does not guarantee either.
+// The setters, such as SetExactMatch, must be called before Serve.
 type UDPConn struct {
 	udpConn
 
-	closeChan  chan struct{}
-	ctx        context.Context
-	errChan    chan error
-	exactMatch bool
+	connState
+	closeOnce sync.Once
+
+	// batch reads and writes several datagrams at once, if it is not nil.
+	batch *udpBatch
 }
 
 // DialUDP creates a new OSC connection over UDP.
-func DialUDP(network string, laddr, raddr *net.UDPAddr) (*UDPConn, error) {
-	return DialUDPContext(context.Background(), network, laddr, raddr)
+func DialUDP(network string, laddr, raddr *net.UDPAddr, opts ...Option) (*UDPConn, error) {
+	return DialUDPContext(context.Background(), network, laddr, raddr, opts...)
 }
 
 // DialUDPContext returns a new OSC connection over UDP that can be canceled with the provided context.
-func DialUDPContext(ctx context.Context, network string, laddr, raddr *net.UDPAddr) (*UDPConn, error) {
+func DialUDPContext(ctx context.Context, network string, laddr, raddr *net.UDPAddr, opts ...Option) (*UDPConn, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkDialOptions(); err != nil {
+		return nil, err
+	}
+	return dialUDP(ctx, network, laddr, raddr, o)
+}
+
+// dialUDP returns a new OSC connection over UDP with the options o.
+func dialUDP(ctx context.Context, network string, laddr, raddr *net.UDPAddr, o options) (*UDPConn, error) {
 	conn, err := net.DialUDP(network, laddr, raddr)
 	if err != nil {
 		return nil, err
 	}
 	uc := &UDPConn{
 		udpConn:   conn,
-		closeChan: make(chan struct{}),
-		ctx:       ctx,
-		errChan:   make(chan error),
+		connState: newConnState(ctx),
 	}
-	return uc.initialize()
+	return uc.initialize(o)
 }
 
 // ListenUDP creates a new UDP server.
-func ListenUDP(network string, laddr *net.UDPAddr) (*UDPConn, error) {
-	return ListenUDPContext(context.Background(), network, laddr)
+func ListenUDP(network string, laddr *net.UDPAddr, opts ...Option) (*UDPConn, error) {
+	return ListenUDPContext(context.Background(), network, laddr, opts...)
 }
 
 // ListenUDPContext creates a UDP listener that can be canceled with the provided context.
-func ListenUDPContext(ctx context.Context, network string, laddr *net.UDPAddr) (*UDPConn, error) {
+func ListenUDPContext(ctx context.Context, network string, laddr *net.UDPAddr, opts ...Option) (*UDPConn, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkDialOptions(); err != nil {
+		return nil, err
+	}
+	return listenUDP(ctx, network, laddr, o)
+}
+
+// listenUDP creates a UDP listener with the options o.
+func listenUDP(ctx context.Context, network string, laddr *net.UDPAddr, o options) (*UDPConn, error) {
 	conn, err := net.ListenUDP(network, laddr)
 	if err != nil {
 		return nil, err
 	}
 	uc := &UDPConn{
 		udpConn:   conn,
-		closeChan: make(chan struct{}),
-		ctx:       ctx,
-		errChan:   make(chan error),
+		connState: newConnState(ctx),
 	}
-	return uc.initialize()
+	return uc.initialize(o)
 }
 
-// Close closes the udp conn.
+// Close closes the udp conn, which makes Serve return nil.
+// It is safe to call more than once, and concurrently with Send, which returns
+// an error wrapping net.ErrClosed once the conn is closed.
+// Only the first call closes the socket and returns its error.
 func (conn *UDPConn) Close() error {
-	close(conn.closeChan)
-	return conn.udpConn.Close()
+	var err error
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+		err = conn.udpConn.Close()
+	})
+	return err
+}
+
+// Shutdown stops reading packets, waits for the methods that are running to return
+// and then closes the conn, which makes Serve return nil.
+// If ctx is done before the methods have returned, the conn is closed anyway
+// and ctx.Err() is returned.
+// Calling Shutdown from a method invoked by Serve blocks until ctx is done.
+func (conn *UDPConn) Shutdown(ctx context.Context) error {
+	return conn.shutdownAndClose(ctx, conn.Close)
+}
+
+// Dial resolves addr and returns an OSC connection over UDP to it,
+// from a local address that the operating system chooses,
+// unless it is given one with WithLocalAddr or WithInterface.
+// addr is a "host:port", which may be prefixed with the network,
+// e.g. "udp4://localhost:9000" or "udp6://[::1]:9000". The network defaults to udp.
+func Dial(addr string, opts ...Option) (*UDPConn, error) {
+	return DialContext(context.Background(), addr, opts...)
+}
+
+// DialContext is like Dial, and the conn can be canceled with ctx.
+func DialContext(ctx context.Context, addr string, opts ...Option) (*UDPConn, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	network, hostport, err := splitNetwork(addr)
+	if err != nil {
+		return nil, err
+	}
+	raddr, err := o.resolveUDP(ctx, network, hostport)
+	if err != nil {
+		return nil, &ResolveError{Addr: addr, Err: err}
+	}
+	laddr, err := o.localUDPAddr(raddr)
+	if err != nil {
+		return nil, err
+	}
+	var conn *UDPConn
+	if o.reResolve > 0 {
+		conn, err = dialResolving(ctx, addr, network, hostport, laddr, raddr, o)
+	} else {
+		conn, err = dialUDP(ctx, network, laddr, raddr, o)
+	}
+	if err != nil {
+		return nil, o.bindError(err)
+	}
+	return conn, nil
+}
+
+// Listen resolves addr and returns a UDP server listening on it.
+// addr is like the address of Dial, except that the host may be left out
+// to listen on every interface, e.g. ":9000". With the udp network, which is
+// the default, that is a dual-stack socket where the system supports it:
+// it reads from IPv4 and IPv6 peers, the IPv4 ones at their IPv4-mapped address,
+// and SendTo reaches both.
+func Listen(addr string, opts ...Option) (*UDPConn, error) {
+	return ListenContext(context.Background(), addr, opts...)
+}
+
+// ListenContext is like Listen, and the conn can be canceled with ctx.
+func ListenContext(ctx context.Context, addr string, opts ...Option) (*UDPConn, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkDialOptions(); err != nil {
+		return nil, err
+	}
+	network, hostport, err := splitNetwork(addr)
+	if err != nil {
+		return nil, err
+	}
+	laddr, err := o.resolveUDP(ctx, network, hostport)
+	if err != nil {
+		return nil, &ResolveError{Addr: addr, Err: err}
+	}
+	return listenUDP(ctx, network, laddr, o)
 }
 
-// CloseChan returns a channel that is closed when the connection gets closed.
-func (conn *UDPConn) CloseChan() <-chan struct{} {
-	return conn.closeChan
+// splitNetwork splits the network off addr.
+func splitNetwork(addr string) (network, hostport string, err error) {
+	network, hostport = "udp", addr
+	if i := strings.Index(addr, "://"); i >= 0 {
+		network, hostport = addr[:i], addr[i+len("://"):]
+	}
+	switch network {
+	case "udp", "udp4", "udp6":
+		return network, hostport, nil
+	default:
+		return "", "", &ResolveError{Addr: addr, Err: net.UnknownNetworkError(network)}
+	}
+}
+
+// SetReadBuffer sets the size of the operating system's receive buffer for the socket.
+// A larger buffer holds more datagrams while every worker is busy.
+func (conn *UDPConn) SetReadBuffer(bytes int) error {
+	return conn.udpConn.SetReadBuffer(bytes)
 }
 
-// Context returns the context associated with the conn.
-func (conn *UDPConn) Context() context.Context {
-	return conn.ctx
+// SetWriteBuffer sets the size of the operating system's transmit buffer for the socket.
+func (conn *UDPConn) SetWriteBuffer(bytes int) error {
+	return conn.udpConn.SetWriteBuffer(bytes)
 }
 
 // initialize initializes a UDP connection.
-func (conn *UDPConn) initialize() (*UDPConn, error) {
+// It closes the socket if it returns an error.
+func (conn *UDPConn) initialize(o options) (*UDPConn, error) {
+	if o.readBufferSize > 0 {
+		conn.readBufSize = o.readBufferSize
+	}
+	conn.applyOptions(o)
 	if err := conn.udpConn.SetWriteBuffer(bufSize); err != nil {
-		return nil, errors.Wrap(err, "setting write buffer size")
+		_ = conn.udpConn.Close() // Best effort.
+		return nil, fmt.Errorf("setting write buffer size: %w", err)
+	}
+	if err := conn.setMulticastOptions(o); err != nil {
+		_ = conn.udpConn.Close() // Best effort.
+		return nil, err
+	}
+	if err := conn.setBroadcastOption(o); err != nil {
+		_ = conn.udpConn.Close() // Best effort.
+		return nil, err
+	}
+	if c, ok := conn.udpConn.(*net.UDPConn); ok {
+		conn.batch = newUDPBatch(c, o.batchSize)
 	}
 	return conn, nil
-
 }
 
 // read reads bytes and returns the net.Addr of the sender.
 func (conn *UDPConn) read(data []byte) (int, net.Addr, error) {
+	if conn.batch != nil {
+		return conn.batch.readFrom(data)
+	}
 	return conn.ReadFromUDP(data)
 }
 
 // Send sends an OSC message over UDP.
+// It is safe to call from many goroutines.
 func (conn *UDPConn) Send(p Packet) error {
-	_, err := conn.Write(p.Bytes())
-	return err
+	return conn.send(conn.RemoteAddr(), p, func(data []byte) error {
+		_, err := conn.Write(data)
+		return err
+	})
+}
+
+// SendContext sends an OSC message over UDP like Send, but gives up once ctx is done,
+// e.g. when the socket buffer stays full. It returns an error wrapping ErrWriteTimeout
+// if the deadline of ctx passes first, and one wrapping ctx.Err() if ctx is canceled.
+// The conn can still be used afterwards.
+func (conn *UDPConn) SendContext(ctx context.Context, p Packet) error {
+	return conn.sendContext(ctx, conn.udpConn, conn.RemoteAddr(), p, func(data []byte) error {
+		_, err := conn.Write(data)
+		return err
+	})
 }
 
-// SendTo sends a packet to the given address.
+// AsyncSender returns an AsyncSender that sends packets to the peer of the conn with Send,
+// through a queue of queueSize packets. When the queue is full, Enqueue waits or drops a packet as policy says.
+// The sender should be closed before the conn, so that the packets it has queued are sent.
+func (conn *UDPConn) AsyncSender(queueSize int, policy QueuePolicy) *AsyncSender {
+	return newAsyncSender(&conn.connState, conn.Send, queueSize, policy)
+}
+
+// SendTo sends a packet to the given address, which may be any net.Addr
+// whose String is a host and a port, such as the sender of a message.
+// A connected conn can only send to its peer, and does so with SendTo too.
 func (conn *UDPConn) SendTo(addr net.Addr, p Packet) error {
-	_, err := conn.WriteTo(p.Bytes(), addr)
+	to, connected, err := conn.destination(addr)
+	if err != nil {
+		conn.sent(addr, p, 0, err)
+		return err
+	}
+	return conn.send(to, p, func(data []byte) error {
+		var err error
+		if connected {
+			_, err = conn.Write(data)
+		} else {
+			_, err = conn.WriteTo(data, to)
+		}
+		return err
+	})
+}
+
+// destination returns addr as a *net.UDPAddr, and true if it is the peer of a connected conn.
+// It returns ErrAddressFamily if the conn is connected to a peer of another family.
+func (conn *UDPConn) destination(addr net.Addr) (*net.UDPAddr, bool, error) {
+	to, err := udpAddr(addr)
+	if err != nil {
+		return nil, false, err
+	}
+	peer, ok := conn.RemoteAddr().(*net.UDPAddr)
+	if !ok || peer == nil {
+		return to, false, nil
+	}
+	if (to.IP.To4() == nil) != (peer.IP.To4() == nil) {
+		return nil, false, fmt.Errorf("sending to %s on a conn connected to %s: %w", to, peer, ErrAddressFamily)
+	}
+	return to, sameUDPAddr(to, peer), nil
+}
+
+// sameUDPAddr returns true if a and b are the same address.
+func sameUDPAddr(a, b *net.UDPAddr) bool {
+	return a.IP.Equal(b.IP) && a.Port == b.Port && a.Zone == b.Zone
+}
+
+// udpAddr returns addr as a *net.UDPAddr, which any other net.Addr is resolved to.
+func udpAddr(addr net.Addr) (*net.UDPAddr, error) {
+	if addr == nil {
+		return nil, errors.New("can not send without an address")
+	}
+	if a, ok := addr.(*net.UDPAddr); ok {
+		return a, nil
+	}
+	a, err := net.ResolveUDPAddr("udp", addr.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s address %s is not a udp address: %w", addr.Network(), addr, err)
+	}
+	return a, nil
+}
+
+// SendBatch sends each of ps in its own datagram, in order.
+// With WithBatchSize the datagrams are written with as few system calls as possible,
+// otherwise SendBatch is the same as calling Send with each packet.
+// No packet is sent if any of them is invalid.
+func (conn *UDPConn) SendBatch(ps ...Packet) error {
+	return conn.sendBatch(nil, ps)
+}
+
+// SendBatchTo sends each of ps in its own datagram to the given address, in order.
+func (conn *UDPConn) SendBatchTo(addr net.Addr, ps ...Packet) error {
+	return conn.sendBatch(addr, ps)
+}
+
+// sendBatch sends ps to addr, or to the peer of a connected conn if addr is nil.
+func (conn *UDPConn) sendBatch(addr net.Addr, ps []Packet) error {
+	if addr != nil {
+		to, connected, err := conn.destination(addr)
+		if err != nil {
+			for _, p := range ps {
+				conn.sent(addr, p, 0, err)
+			}
+			return err
+		}
+		if connected {
+			addr = nil
+		} else {
+			addr = to
+		}
+	}
+	err := conn.sendBuf.sendAll(ps, func(packets [][]byte) error {
+		if conn.batch != nil {
+			return conn.batch.writeTo(packets, addr)
+		}
+		for _, data := range packets {
+			var err error
+			if addr == nil {
+				_, err = conn.Write(data)
+			} else {
+				_, err = conn.WriteTo(data, addr)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	to := addr
+	if to == nil {
+		to = conn.RemoteAddr()
+	}
+	for _, p := range ps {
+		n := 0
+		if err == nil {
+			n, _ = packetSize(p)
+		}
+		conn.sent(to, p, n, err)
+	}
 	return err
 }
 
+// SendBundle sends msgs in a single bundle with the given timetag.
+// Bundles larger than the payload of a single IPv4 datagram are rejected with ErrPacketTooLarge.
+func (conn *UDPConn) SendBundle(tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, udpMaxPacketSize)
+	if err != nil {
+		return err
+	}
+	return conn.Send(p)
+}
+
+// SendBundleTo sends msgs to addr in a single bundle with the given timetag.
+func (conn *UDPConn) SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, udpMaxPacketSize)
+	if err != nil {
+		return err
+	}
+	return conn.SendTo(addr, p)
+}
+
+// ReplyTo returns a BoundSender that sends packets to sender through the conn.
+func (conn *UDPConn) ReplyTo(sender net.Addr) BoundSender {
+	return BoundSender{conn: conn, addr: sender}
+}
+
+// Reply sends msg to the sender of a message that was read from the conn.
+// A connected conn ignores to and sends msg to its peer.
+func (conn *UDPConn) Reply(to net.Addr, msg Message) error {
+	return reply(conn, to, msg)
+}
+
+// Call sends request and returns the first message to replyAddress that the conn
+// reads afterwards, which is not dispatched, or ctx.Err() if ctx is done first.
+// The conn must be serving. Calls waiting for the same address get the replies
+// in the order they were made.
+func (conn *UDPConn) Call(ctx context.Context, request Message, replyAddress string) (Message, error) {
+	return conn.call(ctx, conn.Send, request, replyAddress)
+}
+
+// Ping sends a ping to addr, or to the peer of a connected conn if addr is empty,
+// and returns the time until the echo of the peer's EchoMethod is read,
+// or ctx.Err() if ctx is done first. The conn must be serving.
+// Every ping carries a random token, so concurrent pings get their own echo.
+func (conn *UDPConn) Ping(ctx context.Context, addr string) (time.Duration, error) {
+	if addr == "" {
+		return conn.ping(ctx, conn.Send)
+	}
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return 0, err
+	}
+	return conn.ping(ctx, sendToPeer(conn, raddr))
+}
+
+// Receive reads packets onto the returned channel instead of dispatching them,
+// until ctx is done or the conn is closed or shut down, which closes the channel.
+// Packets that can not be parsed are passed on with Err set.
+// The channel holds as many packets as the queue size. When it is full Receive
+// waits for room, or drops the oldest packet or the new one and counts it in Dropped
+// if the queue policy is DropOldest or DropNewest.
+// A conn can not be served and received from at once: Receive returns ErrServing
+// while Serve is running, and Serve returns ErrReceiving until Receive has stopped.
+func (conn *UDPConn) Receive(ctx context.Context) (<-chan Incoming, error) {
+	return receive(ctx, conn, &conn.connState)
+}
+
+// ReceivePacket reads and parses a single packet and returns it with its sender.
+// The read gives up with ctx.Err() when ctx is done.
+// Packets that can not be parsed are returned as a *ParseError, so the caller
+// can read the next one. ReceivePacket returns ErrServing while Serve is running
+// and ErrReceiving while Receive is.
+func (conn *UDPConn) ReceivePacket(ctx context.Context) (Packet, net.Addr, error) {
+	return receivePacket(ctx, conn, &conn.connState)
+}
+
 // Serve starts dispatching OSC.
-// Any errors returned from a dispatched method will be returned.
-// Note that this means that errors returned from a dispatcher method will kill your server.
+// Packets that can not be parsed and errors returned from methods are passed to
+// the error handler and do not stop the server, unless the conn is in strict mode.
 // If context.Canceled or context.DeadlineExceeded are encountered they will be returned directly.
+// Serve returns nil when the conn is closed, whether from a method or from another goroutine.
 func (conn *UDPConn) Serve(numWorkers int, dispatcher Dispatcher) error {
-	return serve(conn, numWorkers, conn.exactMatch, dispatcher)
-}
-
-// SetContext sets the context associated with the conn.
-func (conn *UDPConn) SetContext(ctx context.Context) {
-	conn.ctx = ctx
+	return conn.ServeContext(context.Background(), numWorkers, dispatcher)
 }
 
-// SetExactMatch changes the behavior of the Serve method so that
-// messages will only be dispatched to methods whose addresses
-// match the message's address exactly.
-// This should provide some performance improvement.
-func (conn *UDPConn) SetExactMatch(value bool) {
-	conn.exactMatch = value
+// ServeContext is like Serve, but it also stops serving when ctx is done
+// and returns ctx.Err() once the methods that are already running have returned.
+// The conn is left open.
+func (conn *UDPConn) ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error {
+	return serve(ctx, conn, numWorkers, conn.exactMatch, dispatcher)
 }
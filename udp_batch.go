@@ -0,0 +1,94 @@
+package osc
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// batchConn reads and writes several datagrams with a single system call.
+// ipv4.Message and ipv6.Message are the same type.
+type batchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// udpBatch reads datagrams in batches and hands them out one at a time,
+// and writes packets in batches.
+// Reads are made by the single goroutine that reads for Serve, and writes
+// with the send buffer of the conn locked.
+type udpBatch struct {
+	conn batchConn
+
+	in         []ipv4.Message
+	next, read int
+
+	out  []ipv4.Message
+	bufs [][]byte
+}
+
+// newUDPBatch returns a batch of n datagrams over conn,
+// or nil if batch I/O is not supported on this platform.
+func newUDPBatch(conn *net.UDPConn, n int) *udpBatch {
+	if !batchSupported || n <= 1 {
+		return nil
+	}
+	b := &udpBatch{in: make([]ipv4.Message, n)}
+	if laddr, ok := conn.LocalAddr().(*net.UDPAddr); ok && laddr.IP.To4() != nil {
+		b.conn = ipv4.NewPacketConn(conn)
+	} else {
+		b.conn = ipv6.NewPacketConn(conn)
+	}
+	return b
+}
+
+// readFrom copies the next datagram into data and returns its sender.
+// A batch of datagrams is read once all of the previous batch has been handed out.
+// Each datagram is read into a buffer as large as data, so that datagrams that
+// do not fit are truncated just as they would be if they were read into data.
+func (b *udpBatch) readFrom(data []byte) (int, net.Addr, error) {
+	if b.next == b.read {
+		for i := range b.in {
+			if bufs := b.in[i].Buffers; len(bufs) != 1 || len(bufs[0]) != len(data) {
+				b.in[i].Buffers = [][]byte{make([]byte, len(data))}
+			}
+		}
+		n, err := b.conn.ReadBatch(b.in, 0)
+		if err != nil {
+			return 0, nil, err
+		}
+		b.next, b.read = 0, n
+	}
+	m := &b.in[b.next]
+	b.next++
+	return copy(data, m.Buffers[0][:m.N]), m.Addr, nil
+}
+
+// writeTo writes each of packets in its own datagram to addr,
+// which must be nil if the conn is connected.
+func (b *udpBatch) writeTo(packets [][]byte, addr net.Addr) error {
+	if cap(b.out) < len(packets) {
+		b.out = make([]ipv4.Message, len(packets))
+		b.bufs = make([][]byte, len(packets))
+	}
+	out := b.out[:len(packets)]
+	for i, p := range packets {
+		b.bufs[i] = p
+		out[i] = ipv4.Message{Buffers: b.bufs[i : i+1], Addr: addr}
+	}
+	defer func() {
+		// Do not keep the packets alive.
+		for i := range out {
+			b.bufs[i], out[i] = nil, ipv4.Message{}
+		}
+	}()
+	for unsent := out; len(unsent) > 0; {
+		n, err := b.conn.WriteBatch(unsent, 0)
+		if err != nil {
+			return err
+		}
+		unsent = unsent[n:]
+	}
+	return nil
+}
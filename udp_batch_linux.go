@@ -0,0 +1,4 @@
+package osc
+
+// batchSupported is true if datagrams can be read and written in batches.
+const batchSupported = true
@@ -0,0 +1,6 @@
+//go:build !linux
+
+package osc
+
+// batchSupported is true if datagrams can be read and written in batches.
+const batchSupported = false
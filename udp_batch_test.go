@@ -0,0 +1,196 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+func TestUDPConnBatch(t *testing.T) {
+	for _, batchSize := range []int{1, 16} {
+		t.Run(fmt.Sprintf("batch=%d", batchSize), func(t *testing.T) {
+			laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server, err := ListenUDP("udp", laddr, WithBatchSize(batchSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = server.Close() }() // Best effort.
+
+			var clients []*UDPConn
+			for i := 0; i < 2; i++ {
+				client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr), WithBatchSize(batchSize))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer func() { _ = client.Close() }() // Best effort.
+				clients = append(clients, client)
+			}
+			type received struct {
+				client, seq int32
+				sender      string
+			}
+			msgs := make(chan received, 64)
+			go func() {
+				_ = server.Serve(1, PatternMatching{
+					"/seq": Method(func(msg Message) error {
+						client, err := msg.Int32At(0)
+						if err != nil {
+							return err
+						}
+						seq, err := msg.Int32At(1)
+						if err != nil {
+							return err
+						}
+						msgs <- received{client: client, seq: seq, sender: msg.Sender.String()}
+						return nil
+					}),
+				})
+			}()
+			// Each batch is received before the next one is sent, so that none are
+			// dropped for a lack of space in the receive buffer of the socket.
+			const batches, perBatch = 20, 24
+			next := make([]int32, len(clients))
+			for batch := 0; batch < batches; batch++ {
+				for i, client := range clients {
+					ps := make([]Packet, perBatch)
+					for j := range ps {
+						ps[j] = MustMessage("/seq", int32(i), int32(batch*perBatch+j))
+					}
+					if err := client.SendBatch(ps...); err != nil {
+						t.Fatal(err)
+					}
+				}
+				for j := 0; j < len(clients)*perBatch; j++ {
+					select {
+					case r := <-msgs:
+						if expected := clients[r.client].LocalAddr().String(); r.sender != expected {
+							t.Fatalf("(client %d) expected sender %s, got %s", r.client, expected, r.sender)
+						}
+						if r.seq != next[r.client] {
+							t.Fatalf("(client %d) expected message %d, got %d", r.client, next[r.client], r.seq)
+						}
+						next[r.client]++
+					case <-time.After(2 * time.Second):
+						t.Fatalf("(batch %d) timeout waiting for message %d", batch, j)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUDPConnSendBatch_Invalid(t *testing.T) {
+	raddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, raddr, WithBatchSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	if err := client.SendBatch(MustMessage("/a"), Message{Address: "a"}); err == nil {
+		t.Fatal("expected an error for an invalid packet")
+	}
+	if _, err := ListenTCP("tcp", nil, WithBatchSize(8)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected ErrUnsupportedOption, got %v", err)
+	}
+	if _, err := ListenUDP("udp", nil, WithBatchSize(0)); err == nil {
+		t.Fatal("expected an error for a batch size of 0")
+	}
+}
+
+// countingBatchConn counts the system calls of a batchConn.
+type countingBatchConn struct {
+	batchConn
+
+	reads, writes int64
+}
+
+func (c *countingBatchConn) ReadBatch(ms []ipv4.Message, flags int) (int, error) {
+	atomic.AddInt64(&c.reads, 1)
+	return c.batchConn.ReadBatch(ms, flags)
+}
+
+func (c *countingBatchConn) WriteBatch(ms []ipv4.Message, flags int) (int, error) {
+	atomic.AddInt64(&c.writes, 1)
+	return c.batchConn.WriteBatch(ms, flags)
+}
+
+// BenchmarkUDPConnBatch sends and serves batches of 32 messages, which takes
+// one system call to write and as few as one to read with batching, instead of
+// one each per message. The system calls are reported per message.
+func BenchmarkUDPConnBatch(b *testing.B) {
+	for _, batchSize := range []int{1, 32} {
+		b.Run(fmt.Sprintf("batch=%d", batchSize), func(b *testing.B) {
+			laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+			if err != nil {
+				b.Fatal(err)
+			}
+			server, err := ListenUDP("udp", laddr, WithBatchSize(batchSize), WithReadBufferSize(512))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer func() { _ = server.Close() }() // Best effort.
+
+			client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr), WithBatchSize(batchSize))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer func() { _ = client.Close() }() // Best effort.
+
+			var (
+				serverCalls = &countingBatchConn{}
+				clientCalls = &countingBatchConn{}
+			)
+			if server.batch != nil {
+				serverCalls.batchConn, server.batch.conn = server.batch.conn, serverCalls
+				clientCalls.batchConn, client.batch.conn = client.batch.conn, clientCalls
+			}
+			const perBatch = 32
+			handled := make(chan struct{}, perBatch)
+			go func() {
+				_ = server.Serve(1, PatternMatching{
+					"/synth/1/freq": Method(func(msg Message) error {
+						handled <- struct{}{}
+						return nil
+					}),
+				})
+			}()
+			ps := make([]Packet, perBatch)
+			for i := range ps {
+				ps[i] = MustMessage("/synth/1/freq", 440.0, float32(0.5), "sine")
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := client.SendBatch(ps...); err != nil {
+					b.Fatal(err)
+				}
+				for range ps {
+					select {
+					case <-handled:
+					case <-time.After(time.Second):
+						b.Fatal("timeout, a datagram was dropped")
+					}
+				}
+			}
+			b.StopTimer()
+			if server.batch != nil {
+				msgs := float64(b.N * perBatch)
+				b.ReportMetric(float64(atomic.LoadInt64(&serverCalls.reads))/msgs, "reads/msg")
+				b.ReportMetric(float64(atomic.LoadInt64(&clientCalls.writes))/msgs, "writes/msg")
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package osc_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/scgolang/osc"
+	"github.com/scgolang/osc/osctest"
+)
+
+// The tests that use osctest are in package osc_test, since osctest imports osc.
+
+func TestUDPConnSendBundle_Nested(t *testing.T) {
+	recorder := osctest.NewRecorder(osc.PatternMatching{
+		"/foo": osc.Method(func(msg osc.Message) error { return nil }),
+		"/bar": osc.Method(func(msg osc.Message) error { return nil }),
+	})
+	conn := osctest.NewServer(t, recorder).Dial()
+
+	b := osc.Bundle{
+		Timetag: osc.Immediately,
+		Packets: []osc.Packet{
+			osc.MustMessage("/foo", int32(1)),
+			osc.Bundle{
+				Timetag: osc.Immediately,
+				Packets: []osc.Packet{osc.MustMessage("/bar", int32(2))},
+			},
+			osc.Bundle{Timetag: osc.Immediately},
+			osc.MustMessage("/foo", int32(3)),
+		},
+	}
+	if err := conn.Send(b); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []struct {
+		Addr string
+		Arg  int32
+	}{
+		{Addr: "/foo", Arg: 1},
+		{Addr: "/bar", Arg: 2},
+		{Addr: "/foo", Arg: 3},
+	} {
+		msg, err := recorder.WaitFor(expected.Addr, 2*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		osctest.AssertMessage(t, msg, expected.Addr, expected.Arg)
+	}
+	if got := len(recorder.Messages()); got != 3 {
+		t.Fatalf("expected 3 messages, got %d", got)
+	}
+}
+
+// badMessage is a message to /foo with the unknown typetag 'Q'.
+var badMessage = osctest.InjectTypetag(osc.MustMessage("/foo"), 0, 'Q')
+
+// strictServer serves the dispatcher with a conn that stops serving at the first bad packet,
+// and returns a conn whose peer it is and the error that Serve returned.
+func strictServer(t *testing.T, dispatcher osc.PatternMatching) (*osc.UDPConn, chan error) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := osc.ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() }) // Best effort.
+	server.SetStrict(true)
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- server.Serve(1, dispatcher) }()
+
+	conn, err := osc.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() }) // Best effort.
+	return conn, errChan
+}
+
+func TestUDPConnServe_BadInboundAddr(t *testing.T) {
+	for i, packet := range []osc.Packet{
+		osc.Message{Address: "/["},
+		osc.Message{Address: "["},
+		badMessage,
+	} {
+		conn, errChan := strictServer(t, osc.PatternMatching{
+			"/foo": osc.Method(func(msg osc.Message) error {
+				return nil
+			}),
+		})
+		// Send rejects bad addresses, so write the packet directly.
+		if _, err := conn.Write(packet.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errChan; err == nil {
+			t.Fatalf("(packet %d) expected error, got nil", i)
+		}
+	}
+}
+
+func TestUDPConnServe_BadPacketsKeepServing(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := osc.ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	errs := make(chan error, 8)
+	server.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	recorder := osctest.NewRecorder(osc.PatternMatching{
+		"/foo": osc.Method(func(msg osc.Message) error {
+			return nil
+		}),
+		"/fail": osc.Method(func(msg osc.Message) error {
+			return errors.New("oops")
+		}),
+	})
+	errChan := make(chan error)
+	go func() {
+		errChan <- server.Serve(1, recorder)
+	}()
+	client, err := osc.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		blob    = osc.MustMessage("/foo", []byte{1, 2, 3, 4})
+		bundle  = osc.Bundle{Timetag: osc.Immediately, Packets: []osc.Packet{osc.MustMessage("/foo", int32(1))}}
+		bundled = osc.Bundle{Timetag: osc.FromTime(time.Now()), Packets: []osc.Packet{badMessage}}
+	)
+	for i, testcase := range []struct {
+		Packet osc.Packet
+		Parse  bool
+	}{
+		{Packet: osc.Message{Address: "/["}, Parse: true},
+		{Packet: osc.Message{Address: "["}, Parse: true},
+		{Packet: badMessage, Parse: true},
+		{Packet: bundled, Parse: true},
+		{Packet: osctest.Truncate(blob, 14), Parse: true},
+		{Packet: osctest.BlobLength(blob, 0, 5), Parse: true},
+		{Packet: osctest.BlobLength(blob, 0, -1), Parse: true},
+		{Packet: osctest.ElementSize(bundle, 0, 64), Parse: true},
+		{Packet: osctest.ElementSize(bundle, 0, -4), Parse: true},
+		{Packet: osc.Message{Address: "/fail"}, Parse: false},
+	} {
+		// Send rejects bad addresses, so write the packet directly.
+		if _, err := client.Write(testcase.Packet.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-errs:
+			if testcase.Parse {
+				if _, ok := err.(*osc.ParseError); !ok {
+					t.Fatalf("(testcase %d) expected *ParseError, got %T", i, err)
+				}
+			} else {
+				if _, ok := err.(*osc.MethodError); !ok {
+					t.Fatalf("(testcase %d) expected *MethodError, got %T", i, err)
+				}
+			}
+		case err := <-errChan:
+			t.Fatalf("(testcase %d) server stopped: %v", i, err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(testcase %d) timeout waiting for error", i)
+		}
+	}
+	// The server is still dispatching.
+	if err := client.Send(osc.MustMessage("/foo", int32(1))); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := recorder.WaitFor("/foo", 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	osctest.AssertMessage(t, msg, "/foo", int32(1))
+}
+
+func TestUDPConnSendTo(t *testing.T) {
+	conn, errChan := strictServer(t, osc.PatternMatching{})
+	laddr2, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn2, err := osc.ListenUDP("udp", laddr2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn2.Close() }() // Best effort.
+
+	if err := conn2.SendTo(conn.RemoteAddr(), badMessage); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestUDPConnSendBundle_BadTypetag(t *testing.T) {
+	conn, errChan := strictServer(t, osc.PatternMatching{})
+	b := osc.Bundle{Timetag: osc.FromTime(time.Now()), Packets: []osc.Packet{badMessage}}
+	if err := conn.Send(b); err != nil {
+		t.Fatal(err)
+	}
+	err := <-errChan
+	if err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	expected, got := `error serving udp: read packets: read packet: parse message from packet: parse message: read argument 0: typetag "Q": invalid type tag`, err.Error()
+	if expected != got {
+		t.Fatal(err)
+	}
+}
@@ -1,13 +1,12 @@
 package osc
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"net"
+	"sync"
 	"testing"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 func TestInvalidAddress(t *testing.T) {
@@ -25,7 +24,7 @@ func TestInvalidAddress(t *testing.T) {
 		"/[": Method(func(msg Message) error {
 			return nil
 		}),
-	}); err != ErrInvalidAddress {
+	}); !errors.Is(err, ErrInvalidAddress) {
 		t.Fatal("expected invalid address error")
 	}
 }
@@ -37,14 +36,36 @@ func TestDialUDP(t *testing.T) {
 }
 
 func TestDialUDPSetWriteBufferError(t *testing.T) {
-	uc := &UDPConn{udpConn: errUDPConn{}}
-	_, err := uc.initialize()
+	socket := listenSocket(t)
+	uc := &UDPConn{udpConn: errUDPConn{udpConn: socket}}
+	_, err := uc.initialize(defaultOptions())
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 	if expected, got := `setting write buffer size: derp`, err.Error(); expected != got {
 		t.Fatalf("expected %s, got %s", expected, got)
 	}
+	checkSocketClosed(t, socket)
+}
+
+// listenSocket returns a UDP socket on the loopback interface, which is closed
+// when the test ends if it is still open.
+func listenSocket(t *testing.T) *net.UDPConn {
+	t.Helper()
+	socket, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = socket.Close() }) // Best effort.
+	return socket
+}
+
+// checkSocketClosed fails the test if socket is still open.
+func checkSocketClosed(t *testing.T, socket *net.UDPConn) {
+	t.Helper()
+	if err := socket.SetReadDeadline(time.Now()); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected the socket to be closed, got %v", err)
+	}
 }
 
 func TestListenUDP(t *testing.T) {
@@ -53,6 +74,69 @@ func TestListenUDP(t *testing.T) {
 	}
 }
 
+func TestDialAndListen(t *testing.T) {
+	for i, testcase := range []struct {
+		Addr    string
+		Network string
+		Resolve bool
+		Open    bool
+	}{
+		{Addr: "127.0.0.1:0", Network: "udp"},
+		{Addr: "udp4://127.0.0.1:0", Network: "udp4"},
+		{Addr: "udp6://[::1]:0", Network: "udp6"},
+		{Addr: "udp6://127.0.0.1:0", Resolve: true},
+		{Addr: "tcp://127.0.0.1:0", Resolve: true},
+		{Addr: "127.0.0.1", Resolve: true},
+		{Addr: "udp4://[::1", Resolve: true},
+		{Addr: "192.0.2.1:0", Open: true},
+	} {
+		server, err := Listen(testcase.Addr)
+		var (
+			re *ResolveError
+			oe *net.OpError
+		)
+		switch {
+		case testcase.Resolve:
+			if !errors.As(err, &re) || re.Addr != testcase.Addr {
+				t.Fatalf("(testcase %d) expected a *ResolveError for %s, got %v", i, testcase.Addr, err)
+			}
+			continue
+		case testcase.Open:
+			if errors.As(err, &re) || !errors.As(err, &oe) {
+				t.Fatalf("(testcase %d) expected a *net.OpError, got %v", i, err)
+			}
+			continue
+		case err != nil && testcase.Network == "udp6":
+			t.Logf("(testcase %d) skipping IPv6: %s", i, err)
+			continue
+		case err != nil:
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		client, err := Dial(testcase.Network + "://" + server.LocalAddr().String())
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if expected, got := server.LocalAddr().String(), client.RemoteAddr().String(); expected != got {
+			t.Fatalf("(testcase %d) expected the client to be connected to %s, got %s", i, expected, got)
+		}
+		if err := client.Send(MustMessage("/dialed")); err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		p, _, err := server.ReceivePacket(context.Background())
+		if err != nil {
+			t.Fatalf("(testcase %d) %s", i, err)
+		}
+		if msg, ok := p.(Message); !ok || msg.Address != "/dialed" {
+			t.Fatalf("(testcase %d) expected /dialed, got %v", i, p)
+		}
+		_ = client.Close() // Best effort.
+		_ = server.Close() // Best effort.
+	}
+	if _, err := Dial("udp4://[::1]:9000"); !errors.As(err, new(*ResolveError)) {
+		t.Fatalf("expected a *ResolveError, got %v", err)
+	}
+}
+
 func TestDialUDPContext(t *testing.T) {
 	raddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:12345")
 	if err != nil {
@@ -98,6 +182,9 @@ func testUDPServer(t *testing.T, dispatcher PatternMatching) (*UDPConn, *UDPConn
 	dispatcher["/server/close"] = Method(func(msg Message) error {
 		return server.Close()
 	})
+	// These tests expect bad packets to stop the server.
+	server.SetStrict(true)
+
 	errChan := make(chan error)
 
 	go func() {
@@ -170,6 +257,196 @@ func TestUDPConnServe_ContextTimeout(t *testing.T) {
 	}
 }
 
+func TestUDPConnServeContext(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		errChan     = make(chan error)
+		started     = make(chan struct{})
+		finished    = make(chan struct{})
+	)
+	defer cancel()
+
+	go func() {
+		errChan <- server.ServeContext(ctx, 1, PatternMatching{
+			"/slow": Method(func(msg Message) error {
+				close(started)
+				time.Sleep(50 * time.Millisecond)
+				close(finished)
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/slow"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the slow method")
+	}
+	cancel()
+
+	select {
+	case err := <-errChan:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %+v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for ServeContext to return")
+	}
+	// The method that was running when the context was canceled has finished.
+	select {
+	case <-finished:
+	default:
+		t.Fatal("ServeContext returned before the slow method finished")
+	}
+
+	// The conn is still open and can be served again.
+	msgs := make(chan Message)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{
+			"/fast": Method(func(msg Message) error {
+				msgs <- msg
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/fast"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitMessage(t, msgs, errChan); msg.Address != "/fast" {
+		t.Fatalf("expected /fast, got %s", msg.Address)
+	}
+}
+
+func TestUDPConnShutdown(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		errChan = make(chan error)
+		started = make(chan struct{})
+		replies = make(chan error, 1)
+	)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{
+			"/slow": Method(func(msg Message) error {
+				close(started)
+				time.Sleep(100 * time.Millisecond)
+
+				// The socket is still open.
+				replies <- server.SendTo(msg.Sender, Message{Address: "/done"})
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/slow"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the slow method")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Shutdown waited for the slow method to reply.
+	select {
+	case err := <-replies:
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatal("Shutdown returned before the slow method finished")
+	}
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatalf("expected nil, got %+v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for Serve to return")
+	}
+}
+
+func TestUDPConnShutdownTimeout(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		errChan = make(chan error)
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{
+			"/stuck": Method(func(msg Message) error {
+				close(started)
+				<-release
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/stuck"}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %+v", err)
+	}
+	close(release)
+
+	if err := <-errChan; err != nil {
+		t.Fatalf("expected nil, got %+v", err)
+	}
+}
+
 func TestUDPConnServe_ReadError(t *testing.T) {
 	errChan := make(chan error)
 
@@ -183,8 +460,8 @@ func TestUDPConnServe_ReadError(t *testing.T) {
 		t.Fatal(err)
 	}
 	server := &UDPConn{
-		udpConn: errUDPConn{udpConn: serverConn},
-		ctx:     context.Background(),
+		udpConn:   errUDPConn{udpConn: serverConn},
+		connState: newConnState(context.Background()),
 	}
 	go func() {
 		errChan <- server.Serve(1, PatternMatching{
@@ -211,6 +488,80 @@ func TestUDPConnServe_ReadError(t *testing.T) {
 	}
 }
 
+func TestUDPConnServe_CloseConcurrently(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		server, err := ListenUDP("udp", laddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := ListenUDP("udp", laddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			serving = make(chan error, 1)
+			sending = make(chan error, 1)
+			wg      sync.WaitGroup
+		)
+		go func() {
+			serving <- server.Serve(2, PatternMatching{Default: Method(func(msg Message) error { return nil })})
+		}()
+		go func() {
+			// Feed the server, so that it is reading or dispatching when it is closed.
+			for {
+				if err := client.SendTo(server.LocalAddr(), MustMessage("/feed")); err != nil {
+					sending <- err
+					return
+				}
+				select {
+				case <-server.CloseChan():
+					sending <- nil
+					return
+				default:
+				}
+			}
+		}()
+		go func() {
+			// Send from the server while it is closed.
+			for server.SendTo(client.LocalAddr(), MustMessage("/reply")) == nil {
+			}
+		}()
+		time.Sleep(time.Millisecond)
+
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = server.Close() // Only the first Close returns an error.
+			}()
+		}
+		wg.Wait()
+
+		select {
+		case err := <-serving:
+			if err != nil {
+				t.Fatalf("(testcase %d) expected nil, got %v", i, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("(testcase %d) timeout waiting for Serve to return", i)
+		}
+		if err := server.SendTo(client.LocalAddr(), MustMessage("/closed")); !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("(testcase %d) expected net.ErrClosed, got %v", i, err)
+		}
+		if err := server.Close(); err != nil {
+			t.Fatalf("(testcase %d) expected closing again to return nil, got %v", i, err)
+		}
+		if err := <-sending; err != nil {
+			t.Fatal(err)
+		}
+		_ = client.Close() // Best effort.
+	}
+}
+
 func TestUDPConnServe_NilDispatcher(t *testing.T) {
 	// Setup the server.
 	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
@@ -226,44 +577,182 @@ func TestUDPConnServe_NilDispatcher(t *testing.T) {
 	}
 }
 
-func TestUDPConnServe_BadInboundAddr(t *testing.T) {
-	for i, packet := range []Packet{
-		Message{Address: "/["},
-		Message{Address: "["},
-		badPacket{},
-	} {
-		// Send a message with a bad address.
-		_, conn, errChan := testUDPServer(t, PatternMatching{
-			"/foo": Method(func(msg Message) error {
+func TestUDPConnEchoToSender(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *UDPConn
+	echo := Method(func(msg Message) error {
+		return server.SendTo(msg.Sender, msg)
+	})
+	server, err = ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	go func() {
+		_ = server.Serve(1, PatternMatching{"/echo": echo})
+	}()
+
+	// Neither conn is connected, so the server has to use the sender of each message.
+	client, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	var (
+		echoes  = make(chan Message)
+		errChan = make(chan error)
+	)
+	go func() {
+		errChan <- client.Serve(1, PatternMatching{
+			"/echo": Method(func(msg Message) error {
+				echoes <- msg
 				return nil
 			}),
 		})
-		if err := conn.Send(packet); err != nil {
+	}()
+	expected := Message{Address: "/echo", Arguments: Arguments{String("hello")}}
+
+	for _, p := range []Packet{
+		expected,
+		Bundle{Timetag: Immediately, Packets: []Packet{expected}},
+	} {
+		if err := client.SendTo(server.LocalAddr(), p); err != nil {
 			t.Fatal(err)
 		}
-		t.Logf("sent message %s", string(packet.Bytes()))
-
-		if err := <-errChan; err == nil {
-			t.Fatalf("(packet %d) expected error, got nil", i)
+		got := waitMessage(t, echoes, errChan)
+		if !expected.Equal(got) {
+			t.Fatalf("expected %s, got %s", expected, got)
+		}
+		if expected, got := server.LocalAddr().String(), got.Sender.String(); expected != got {
+			t.Fatalf("expected sender %s, got %s", expected, got)
 		}
 	}
 }
 
-func TestUDPConnSendTo(t *testing.T) {
-	_, conn, errChan := testUDPServer(t, nil)
-	laddr2, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+// hostPortAddr is a net.Addr that is only a host and a port.
+type hostPortAddr string
+
+func (a hostPortAddr) Network() string { return "hostport" }
+func (a hostPortAddr) String() string  { return string(a) }
+
+func TestUDPConnSendTo_Addr(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
 	if err != nil {
 		t.Fatal(err)
 	}
-	conn2, err := ListenUDP("udp", laddr2)
+	defer func() { _ = server.Close() }() // Best effort.
+
+	var (
+		msgs    = make(chan Message, 1)
+		errChan = make(chan error, 1)
+	)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{"/to": Method(func(msg Message) error {
+			msgs <- msg
+			return nil
+		})})
+	}()
+	listening, err := ListenUDP("udp", laddr)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := conn2.SendTo(conn.RemoteAddr(), badPacket{}); err != nil {
+	defer func() { _ = listening.Close() }() // Best effort.
+
+	connected, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
 		t.Fatal(err)
 	}
-	if err := <-errChan; err == nil {
-		t.Fatal("expected error, got nil")
+	defer func() { _ = connected.Close() }() // Best effort.
+
+	for i, testcase := range []struct {
+		Conn *UDPConn
+		Addr net.Addr
+		Err  error
+	}{
+		{Conn: listening, Addr: server.LocalAddr()},
+		{Conn: listening, Addr: hostPortAddr(server.LocalAddr().String())},
+		{Conn: listening, Addr: hostPortAddr("nowhere")},
+		{Conn: listening},
+		{Conn: connected, Addr: server.LocalAddr()},
+		{Conn: connected, Addr: hostPortAddr(server.LocalAddr().String())},
+		{Conn: connected, Addr: &net.UDPAddr{IP: net.IPv6loopback, Port: 1}, Err: ErrAddressFamily},
+		{Conn: connected, Addr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}, Err: net.ErrWriteToConnected},
+	} {
+		err := testcase.Conn.SendTo(testcase.Addr, Message{Address: "/to", Arguments: Arguments{Int(i)}})
+		switch {
+		case testcase.Err != nil:
+			if !errors.Is(err, testcase.Err) {
+				t.Fatalf("(testcase %d) expected %v, got %v", i, testcase.Err, err)
+			}
+		case testcase.Addr == nil || testcase.Addr.String() == "nowhere":
+			if err == nil {
+				t.Fatalf("(testcase %d) expected an error", i)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("(testcase %d) %s", i, err)
+			}
+			if expected, got := (Message{Address: "/to", Arguments: Arguments{Int(i)}}), waitMessage(t, msgs, errChan); !expected.Equal(got) {
+				t.Fatalf("(testcase %d) expected argument %d, got %s", i, i, got)
+			}
+		}
+	}
+}
+
+func TestUDPConnReplyTo(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server *UDPConn
+	server, err = ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	serverErrs := make(chan error, 1)
+	go func() {
+		serverErrs <- server.Serve(1, PatternMatching{
+			"/ping": MethodCtx(func(ctx context.Context, msg Message) error {
+				sender, ok := SenderFromContext(ctx)
+				if !ok {
+					return errors.New("no sender in the context")
+				}
+				return server.ReplyTo(sender).Send(Message{Address: "/pong", Arguments: msg.Arguments})
+			}),
+		})
+	}()
+	// The clients are not connected either, so the server can only reply to the senders.
+	for i := 0; i < 3; i++ {
+		client, err := ListenUDP("udp", laddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = client.Close() }() // Best effort.
+
+		pongs := make(chan Message, 1)
+		go func() {
+			_ = client.Serve(1, PatternMatching{"/pong": Method(func(msg Message) error {
+				pongs <- msg
+				return nil
+			})})
+		}()
+		if err := client.SendTo(server.LocalAddr(), Message{Address: "/ping", Arguments: Arguments{Int(i)}}); err != nil {
+			t.Fatal(err)
+		}
+		if expected, got := (Message{Address: "/pong", Arguments: Arguments{Int(i)}}), waitMessage(t, pongs, serverErrs); !expected.Equal(got) {
+			t.Fatalf("(testcase %d) expected argument %d, got %s", i, i, got)
+		}
 	}
 }
 
@@ -283,21 +772,6 @@ func TestUDPConnSendBundle(t *testing.T) {
 	}
 }
 
-func TestUDPConnSendBundle_BadTypetag(t *testing.T) {
-	_, conn, errChan := testUDPServer(t, nil)
-	if err := conn.Send(badBundle{}); err != nil {
-		t.Fatal(err)
-	}
-	err := <-errChan
-	if err == nil {
-		t.Fatalf("expected error, got nil")
-	}
-	expected, got := `error serving udp: read packets: read packet: parse message from packet: parse message: read argument 0: typetag "Q": invalid type tag`, err.Error()
-	if expected != got {
-		t.Fatal(err)
-	}
-}
-
 func TestUDPConnSendBundle_DispatchError(t *testing.T) {
 	b := Bundle{
 		Timetag: FromTime(time.Now()),
@@ -323,38 +797,110 @@ func TestUDPConnSendBundle_DispatchError(t *testing.T) {
 	}
 }
 
-// badPacket is a Packet that returns an OSC message with typetag 'Q'
-type badPacket struct{}
+func TestUDPConnReadBufferSize(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr, WithReadBufferSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	if err := server.SetReadBuffer(1 << 16); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.SetWriteBuffer(1 << 16); err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
 
-func (bp badPacket) Bytes() []byte {
-	return bytes.Join(
-		[][]byte{
-			{'/', 'f', 'o', 'o', 0, 0, 0, 0},
-			{TypetagPrefix, 'Q', 0, 0},
-		},
-		[]byte{},
+	var (
+		errs  = make(chan error, 1)
+		small = make(chan Message, 1)
 	)
+	server.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		_ = server.Serve(1, PatternMatching{
+			"/blob": Method(func(msg Message) error {
+				t.Errorf("expected the truncated blob to not be dispatched, got %s", msg)
+				return nil
+			}),
+			"/small": Method(func(msg Message) error {
+				small <- msg
+				return nil
+			}),
+		})
+	}()
+	if err := client.Send(Message{Address: "/blob", Arguments: Arguments{Blob(make([]byte, 200))}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		var pe *ParseError
+		if !errors.As(err, &pe) || !errors.Is(err, ErrPacketTruncated) {
+			t.Fatalf("expected *ParseError wrapping ErrPacketTruncated, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the truncation error")
+	}
+	// A message that fits the buffer exactly is not truncated.
+	// It has 8 bytes of address, 4 of typetags and 4 of blob size.
+	msg := Message{Address: "/small", Arguments: Arguments{Blob(make([]byte, 48))}}
+	if expected, got := 64, len(msg.Bytes()); expected != got {
+		t.Fatalf("expected %d bytes, got %d", expected, got)
+	}
+	if err := client.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-small:
+		if !got.Equal(msg) {
+			t.Fatalf("expected %s, got %s", msg, got)
+		}
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /small")
+	}
+	if _, err := ListenUDP("udp", laddr, WithReadBufferSize(0)); err == nil {
+		t.Fatal("expected an error for a zero read buffer size")
+	}
+	if _, err := ListenTCP("tcp", nil, WithReadBufferSize(64)); !errors.Is(err, ErrUnsupportedOption) {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedOption, err)
+	}
 }
 
-func (bp badPacket) Equal(other Packet) bool {
-	return false
-}
-
-type badBundle struct{}
+func BenchmarkUDPConnSend(b *testing.B) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	server, err := ListenUDP("udp", laddr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
 
-func (bb badBundle) Bytes() []byte {
-	msg := badPacket{}.Bytes()
-	return bytes.Join(
-		[][]byte{
-			ToBytes(BundleTag),
-			FromTime(time.Now()).Bytes(),
-			Int(len(msg)).Bytes(),
-			msg,
-		},
-		[]byte{},
-	)
-}
+	client, err := DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
 
-func (bb badBundle) Equal(other Packet) bool {
-	return false
+	msg := MustMessage("/synth/1/freq", 440.0, float32(0.5), "sine")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.Send(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
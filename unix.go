@@ -2,12 +2,20 @@ package osc
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
+)
 
-	ulid "github.com/imdario/go-ulid"
-	"github.com/pkg/errors"
+// Common errors.
+var (
+	ErrSocketExists = errors.New("socket file already exists")
 )
 
 type unixConn interface {
@@ -15,16 +23,18 @@ type unixConn interface {
 	netWriter
 
 	ReadFromUnix([]byte) (int, *net.UnixAddr, error)
+	SetReadBuffer(bytes int) error
 }
 
 // UnixConn handles OSC over a unix socket.
+// If the conn is bound to a path it owns the socket file
+// and removes it when it is closed.
 type UnixConn struct {
 	unixConn
 
-	closeChan  chan struct{}
-	ctx        context.Context
-	errChan    chan error
-	exactMatch bool
+	connState
+	path      string
+	closeOnce sync.Once
 }
 
 // DialUnix opens a unix socket for OSC communication.
@@ -34,15 +44,17 @@ func DialUnix(network string, laddr, raddr *net.UnixAddr) (*UnixConn, error) {
 
 // DialUnixContext creates a new UnixConn.
 func DialUnixContext(ctx context.Context, network string, laddr, raddr *net.UnixAddr) (*UnixConn, error) {
+	if err := checkSocketPath(laddr); err != nil {
+		return nil, err
+	}
 	conn, err := net.DialUnix(network, laddr, raddr)
 	if err != nil {
 		return nil, err
 	}
 	uc := &UnixConn{
 		unixConn:  conn,
-		closeChan: make(chan struct{}),
-		ctx:       ctx,
-		errChan:   make(chan error),
+		connState: newConnState(ctx),
+		path:      socketPath(laddr),
 	}
 	return uc.initialize()
 }
@@ -54,39 +66,52 @@ func ListenUnix(network string, laddr *net.UnixAddr) (*UnixConn, error) {
 
 // ListenUnixContext creates a Unix listener that can be canceled with the provided context.
 func ListenUnixContext(ctx context.Context, network string, laddr *net.UnixAddr) (*UnixConn, error) {
+	if err := checkSocketPath(laddr); err != nil {
+		return nil, err
+	}
 	conn, err := net.ListenUnixgram(network, laddr)
 	if err != nil {
 		return nil, err
 	}
 	uc := &UnixConn{
 		unixConn:  conn,
-		closeChan: make(chan struct{}),
-		ctx:       ctx,
-		errChan:   make(chan error),
+		connState: newConnState(ctx),
+		path:      socketPath(laddr),
 	}
 	return uc.initialize()
 }
 
-// Close closes the connection.
+// Close closes the connection and removes its socket file.
+// Like UDPConn.Close, it may be called more than once.
 func (conn *UnixConn) Close() error {
-	close(conn.closeChan)
-	return conn.unixConn.Close()
+	var err error
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+		err = conn.unixConn.Close()
+
+		if conn.path != "" {
+			if rerr := os.Remove(conn.path); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+				err = fmt.Errorf("removing socket file: %w", rerr)
+			}
+		}
+	})
+	return err
 }
 
-// CloseChan returns a channel that is closed when the connection gets closed.
-func (conn *UnixConn) CloseChan() <-chan struct{} {
-	return conn.closeChan
+// SetReadBuffer sets the size of the operating system's receive buffer for the socket.
+func (conn *UnixConn) SetReadBuffer(bytes int) error {
+	return conn.unixConn.SetReadBuffer(bytes)
 }
 
-// Context returns the context for the unix conn.
-func (conn *UnixConn) Context() context.Context {
-	return conn.ctx
+// SetWriteBuffer sets the size of the operating system's transmit buffer for the socket.
+func (conn *UnixConn) SetWriteBuffer(bytes int) error {
+	return conn.unixConn.SetWriteBuffer(bytes)
 }
 
 // initialize initializes the connection.
 func (conn *UnixConn) initialize() (*UnixConn, error) {
 	if err := conn.unixConn.SetWriteBuffer(bufSize); err != nil {
-		return nil, errors.Wrap(err, "setting write buffer size")
+		return nil, fmt.Errorf("setting write buffer size: %w", err)
 	}
 	return conn, nil
 }
@@ -97,33 +122,179 @@ func (conn *UnixConn) read(data []byte) (int, net.Addr, error) {
 
 // Send sends a Packet.
 func (conn *UnixConn) Send(p Packet) error {
-	_, err := conn.Write(p.Bytes())
-	return err
+	return conn.send(conn.RemoteAddr(), p, func(data []byte) error {
+		_, err := conn.Write(data)
+		return err
+	})
+}
+
+// SendContext sends an OSC message over the socket like Send, but gives up once ctx is done,
+// e.g. when the reader of the socket falls behind. It returns an error wrapping ErrWriteTimeout
+// if the deadline of ctx passes first, and one wrapping ctx.Err() if ctx is canceled.
+// The conn can still be used afterwards.
+func (conn *UnixConn) SendContext(ctx context.Context, p Packet) error {
+	return conn.sendContext(ctx, conn.unixConn, conn.RemoteAddr(), p, func(data []byte) error {
+		_, err := conn.Write(data)
+		return err
+	})
 }
 
-// SendTo sends a Packet to the provided net.Addr.
+// AsyncSender returns an AsyncSender that sends packets to the peer of the conn with Send,
+// through a queue of queueSize packets. When the queue is full, Enqueue waits or drops a packet as policy says.
+// The sender should be closed before the conn, so that the packets it has queued are sent.
+func (conn *UnixConn) AsyncSender(queueSize int, policy QueuePolicy) *AsyncSender {
+	return newAsyncSender(&conn.connState, conn.Send, queueSize, policy)
+}
+
+// SendTo sends a Packet to the provided net.Addr, which may be any net.Addr
+// whose String is the path of a socket, such as the sender of a message.
+// A connected conn can only send to its peer, and does so with SendTo too.
 func (conn *UnixConn) SendTo(addr net.Addr, p Packet) error {
-	_, err := conn.WriteTo(p.Bytes(), addr)
-	return err
+	to, err := unixAddr(addr)
+	if err != nil {
+		conn.sent(addr, p, 0, err)
+		return err
+	}
+	peer, connected := conn.RemoteAddr().(*net.UnixAddr)
+	connected = connected && peer != nil && peer.Name == to.Name
+
+	return conn.send(to, p, func(data []byte) error {
+		var err error
+		if connected {
+			_, err = conn.Write(data)
+		} else {
+			_, err = conn.WriteTo(data, to)
+		}
+		return err
+	})
+}
+
+// unixAddr returns addr as a *net.UnixAddr, which any other net.Addr is resolved to.
+func unixAddr(addr net.Addr) (*net.UnixAddr, error) {
+	if addr == nil {
+		return nil, errors.New("can not send without an address")
+	}
+	if a, ok := addr.(*net.UnixAddr); ok {
+		return a, nil
+	}
+	return net.ResolveUnixAddr("unixgram", addr.String())
+}
+
+// SendBundle sends msgs in a single bundle with the given timetag.
+// Bundles larger than the max packet size of the conn are rejected with ErrPacketTooLarge.
+func (conn *UnixConn) SendBundle(tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.Send(p)
+}
+
+// SendBundleTo sends msgs to addr in a single bundle with the given timetag.
+func (conn *UnixConn) SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.SendTo(addr, p)
+}
+
+// ReplyTo returns a BoundSender that sends packets to sender through the conn.
+func (conn *UnixConn) ReplyTo(sender net.Addr) BoundSender {
+	return BoundSender{conn: conn, addr: sender}
+}
+
+// Reply sends msg to the sender of a message that was read from the conn.
+// A connected conn ignores to and sends msg to its peer.
+func (conn *UnixConn) Reply(to net.Addr, msg Message) error {
+	return reply(conn, to, msg)
+}
+
+// Call sends request and returns the first message to replyAddress that the conn
+// reads afterwards, which is not dispatched, or ctx.Err() if ctx is done first.
+// The conn must be serving. Calls waiting for the same address get the replies
+// in the order they were made.
+func (conn *UnixConn) Call(ctx context.Context, request Message, replyAddress string) (Message, error) {
+	return conn.call(ctx, conn.Send, request, replyAddress)
+}
+
+// Ping sends a ping to addr, or to the peer of a connected conn if addr is empty,
+// and returns the time until the echo of the peer's EchoMethod is read,
+// or ctx.Err() if ctx is done first. The conn must be serving.
+// Every ping carries a random token, so concurrent pings get their own echo.
+func (conn *UnixConn) Ping(ctx context.Context, addr string) (time.Duration, error) {
+	if addr == "" {
+		return conn.ping(ctx, conn.Send)
+	}
+	raddr, err := net.ResolveUnixAddr("unixgram", addr)
+	if err != nil {
+		return 0, err
+	}
+	return conn.ping(ctx, sendToPeer(conn, raddr))
+}
+
+// Receive reads packets onto the returned channel instead of dispatching them,
+// until ctx is done or the conn is closed or shut down, which closes the channel.
+// Packets that can not be parsed are passed on with Err set.
+// The channel holds as many packets as the queue size. When it is full Receive
+// waits for room, or drops the oldest packet or the new one and counts it in Dropped
+// if the queue policy is DropOldest or DropNewest.
+// A conn can not be served and received from at once: Receive returns ErrServing
+// while Serve is running, and Serve returns ErrReceiving until Receive has stopped.
+func (conn *UnixConn) Receive(ctx context.Context) (<-chan Incoming, error) {
+	return receive(ctx, conn, &conn.connState)
+}
+
+// ReceivePacket reads and parses a single packet and returns it with its sender.
+// The read gives up with ctx.Err() when ctx is done.
+// Packets that can not be parsed are returned as a *ParseError, so the caller
+// can read the next one. ReceivePacket returns ErrServing while Serve is running
+// and ErrReceiving while Receive is.
+func (conn *UnixConn) ReceivePacket(ctx context.Context) (Packet, net.Addr, error) {
+	return receivePacket(ctx, conn, &conn.connState)
 }
 
 // Serve starts dispatching OSC.
-// Any errors returned from a dispatched method will be returned.
-// Note that this means that errors returned from a dispatcher method will kill your server.
+// Packets that can not be parsed and errors returned from methods are passed to
+// the error handler and do not stop the server, unless the conn is in strict mode.
 // If context.Canceled or context.DeadlineExceeded are encountered they will be returned directly.
 func (conn *UnixConn) Serve(numWorkers int, dispatcher Dispatcher) error {
-	return serve(conn, numWorkers, conn.exactMatch, dispatcher)
+	return conn.ServeContext(context.Background(), numWorkers, dispatcher)
 }
 
-// TempSocket creates an absolute path to a temporary socket file.
-func TempSocket() string {
-	return filepath.Join(os.TempDir(), ulid.New().String()) + ".sock"
+// ServeContext is like Serve, but it also stops serving when ctx is done
+// and returns ctx.Err() once the methods that are already running have returned.
+// The conn is left open.
+func (conn *UnixConn) ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error {
+	return serve(ctx, conn, numWorkers, conn.exactMatch, dispatcher)
 }
 
-// SetExactMatch changes the behavior of the Serve method so that
-// messages will only be dispatched to methods whose addresses
-// match the message's address exactly.
-// This should provide some performance improvement.
-func (conn *UnixConn) SetExactMatch(value bool) {
-	conn.exactMatch = value
+// checkSocketPath returns ErrSocketExists if there is already a file at the path of addr.
+// Binding to an existing path would fail with a less helpful error.
+func checkSocketPath(addr *net.UnixAddr) error {
+	path := socketPath(addr)
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s: %w", path, ErrSocketExists)
+	}
+	return nil
+}
+
+// socketPath returns the path of the socket file for addr.
+// Unnamed and abstract sockets (whose names start with '@') have no file.
+func socketPath(addr *net.UnixAddr) string {
+	if addr == nil || addr.Name == "" || addr.Name[0] == '@' {
+		return ""
+	}
+	return addr.Name
+}
+
+// TempSocket creates an absolute path to a temporary socket file.
+// The file itself is not created.
+func TempSocket() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:]) // Never fails.
+	return filepath.Join(os.TempDir(), "osc-"+hex.EncodeToString(b[:])) + ".sock"
 }
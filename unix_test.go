@@ -1,11 +1,11 @@
 package osc
 
 import (
+	"errors"
 	"net"
+	"os"
 	"testing"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 func tmpListener(t *testing.T, dispatcher Dispatcher) (*UnixConn, chan error) {
@@ -127,6 +127,8 @@ func TestUnixSendTo(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() { _ = conn.Close() }() // Best effort.
+
 	if err := conn.SendTo(server.LocalAddr(), Message{Address: "/foo"}); err != nil {
 		t.Fatal(err)
 	}
@@ -144,3 +146,103 @@ func TestUnixSendTo(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUnixCloseRemovesSocket(t *testing.T) {
+	addr, err := net.ResolveUnixAddr("unixgram", TempSocket())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ListenUnix("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(addr.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(addr.Name); !os.IsNotExist(err) {
+		t.Fatalf("expected socket file to be removed, got %v", err)
+	}
+}
+
+func TestListenUnixPathExists(t *testing.T) {
+	addr, err := net.ResolveUnixAddr("unixgram", TempSocket())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ListenUnix("unixgram", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }() // Best effort.
+
+	if _, err := ListenUnix("unixgram", addr); !errors.Is(err, ErrSocketExists) {
+		t.Fatalf("expected ErrSocketExists, got %v", err)
+	}
+	if _, err := DialUnix("unixgram", addr, addr); !errors.Is(err, ErrSocketExists) {
+		t.Fatalf("expected ErrSocketExists, got %v", err)
+	}
+}
+
+func TestUnixRoundTrip(t *testing.T) {
+	pongs := make(chan Message)
+
+	// The server is created before it serves, since its method uses it.
+	saddr, err := net.ResolveUnixAddr("unixgram", TempSocket())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ListenUnix("unixgram", saddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.Serve(1, PatternMatching{
+			"/ping": Method(func(m Message) error {
+				return server.SendTo(m.Sender, Message{Address: "/pong", Arguments: m.Arguments})
+			}),
+		})
+	}()
+
+	laddr, err := net.ResolveUnixAddr("unixgram", TempSocket())
+	if err != nil {
+		t.Fatal(err)
+	}
+	raddr, err := net.ResolveUnixAddr("unixgram", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialUnix("unixgram", laddr, raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	go func() {
+		_ = client.Serve(1, PatternMatching{
+			"/pong": Method(func(m Message) error {
+				pongs <- m
+				return nil
+			}),
+		})
+	}()
+	ping := Message{Address: "/ping", Arguments: Arguments{String("hello"), Int(42)}}
+	if err := client.Send(ping); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errChan:
+		t.Fatal(err)
+	case <-time.After(1 * time.Second):
+		t.Fatal("timeout")
+	case pong := <-pongs:
+		if expected := (Message{Address: "/pong", Arguments: ping.Arguments}); !expected.Equal(pong) {
+			t.Fatalf("expected %s, got %s", expected, pong)
+		}
+	}
+}
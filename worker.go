@@ -1,7 +1,10 @@
 package osc
 
 import (
-	"github.com/pkg/errors"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
 )
 
 // worker is a worker who can process OSC messages.
@@ -11,43 +14,123 @@ type worker struct {
 	ErrChan    chan error
 	Ready      chan<- worker
 	ExactMatch bool
+
+	// InFlight is marked done after each packet has been handled.
+	InFlight *sync.WaitGroup
+
+	// Replier is attached to every message so that methods can reply to it.
+	Replier replier
+
+	// Context is the parent of the contexts of the messages.
+	Context context.Context
+
+	// Scheduler, if not nil, holds bundles until their timetag.
+	Scheduler *scheduler
+
+	// Buffers recycles the read buffers of the packets.
+	// If Lazy is true messages are views over their read buffer,
+	// which is recycled once they have been dispatched.
+	Buffers *readBuffers
+	Lazy    bool
+
+	// Parse, if not nil, are the deviations from the specification that are tolerated.
+	// Messages are not views if it is set.
+	Parse *ParseOptions
+
+	// HandleError is called with every error caused by a packet.
+	// If Strict is true the error is also sent on ErrChan, which stops the server.
+	HandleError func(error)
+	Strict      bool
+
+	// Recover makes a method that panics return a *PanicError instead.
+	Recover bool
 }
 
 // run runs the worker.
 func (w worker) run() {
 	w.Ready <- w
 
-DataLoop:
 	for incoming := range w.DataChan {
-		data := incoming.Data
-
-		switch data[0] {
-		case BundleTag[0]:
-			bundle, err := ParseBundle(data, incoming.Sender)
-			if err != nil {
-				w.ErrChan <- err
-			}
-			if err := w.Dispatcher.Dispatch(bundle, w.ExactMatch); err != nil {
-				w.ErrChan <- errors.Wrap(err, "dispatch bundle")
-			}
-		case MessageChar:
-			msg, err := ParseMessage(data, incoming.Sender)
-			if err != nil {
-				w.ErrChan <- err
-				continue DataLoop
-			}
-			if err := ValidateAddress(msg.Address); err != nil {
-				w.ErrChan <- err
-				continue DataLoop
-			}
-			if err := w.Dispatcher.Invoke(msg, w.ExactMatch); err != nil {
-				w.ErrChan <- errors.Wrap(err, "dispatch message")
-				continue DataLoop
-			}
-		default:
-			w.ErrChan <- ErrParse
+		if err := w.handle(incoming); err != nil {
+			w.report(err)
 		}
+		w.InFlight.Done()
+
 		// Announce the worker is ready again.
 		w.Ready <- w
 	}
 }
+
+// handle parses and dispatches a single packet.
+func (w worker) handle(incoming Incoming) error {
+	if w.Lazy && w.Parse == nil && len(incoming.Data) > 0 && incoming.Data[0] == MessageChar {
+		return w.handleView(incoming)
+	}
+	p, err := parsePacket(incoming.Data, incoming.Sender, w.Parse)
+	if err != nil {
+		w.Buffers.put(incoming.buf)
+		return parseError(err, incoming.Sender)
+	}
+	if w.Parse != nil && w.Parse.UnknownTypetags {
+		if err := truncatedError(p); err != nil {
+			w.HandleError(err)
+		}
+	}
+	p = w.Buffers.release(incoming.buf, p)
+	p = withConn(p, w.Replier, packetContext(w.Context, incoming))
+	switch x := p.(type) {
+	case Bundle:
+		if w.Scheduler != nil && x.Timetag != Immediately && w.Scheduler.schedule(x) {
+			return nil
+		}
+		if err := callMethods(w.Recover, func() error { return w.Dispatcher.Dispatch(x, w.ExactMatch) }); err != nil {
+			return &MethodError{Sender: incoming.Sender, Err: fmt.Errorf("dispatch bundle: %w", err)}
+		}
+	case Message:
+		if err := callMethods(w.Recover, func() error { return w.Dispatcher.Invoke(x, w.ExactMatch) }); err != nil {
+			return &MethodError{Sender: incoming.Sender, Err: fmt.Errorf("dispatch message: %w", err)}
+		}
+	}
+	return nil
+}
+
+// handleView dispatches a message that is a view over its read buffer,
+// and recycles the buffer.
+func (w worker) handleView(incoming Incoming) error {
+	defer w.Buffers.put(incoming.buf)
+
+	msg, err := parseMessageView(incoming.Data, incoming.Sender)
+	if err != nil || validateAddressPattern(msg.Address) != nil {
+		// The error outlives the buffer, so it comes from parsing the packet the usual way.
+		_, err := parsePacket(incoming.Data, incoming.Sender, nil)
+		return parseError(err, incoming.Sender)
+	}
+	msg.replier, msg.ctx = w.Replier, packetContext(w.Context, incoming)
+	if err := callMethods(w.Recover, func() error { return w.Dispatcher.Invoke(msg, w.ExactMatch) }); err != nil {
+		return &MethodError{Sender: incoming.Sender, Err: fmt.Errorf("dispatch message: %w", err)}
+	}
+	return nil
+}
+
+// callMethods calls dispatch, which invokes methods.
+// If recoverPanics is true a panic in dispatch is returned as a *PanicError.
+func callMethods(recoverPanics bool, dispatch func() error) (err error) {
+	if recoverPanics {
+		defer func() {
+			if v := recover(); v != nil {
+				err = &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+	}
+	return dispatch()
+}
+
+// report reports an error caused by a packet.
+func (w worker) report(err error) {
+	if w.HandleError != nil {
+		w.HandleError(err)
+	}
+	if w.Strict {
+		w.ErrChan <- err
+	}
+}
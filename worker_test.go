@@ -1,23 +1,27 @@
 package osc
 
 import (
+	"errors"
+	"strings"
+	"sync"
 	"testing"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 func TestWorkerRun(t *testing.T) {
 	var (
-		data  = make(chan Incoming)
-		errch = make(chan error)
-		ready = make(chan worker)
+		data     = make(chan Incoming)
+		errch    = make(chan error)
+		ready    = make(chan worker)
+		inFlight = &sync.WaitGroup{}
 	)
 	wrk := worker{
 		DataChan:   data,
 		Dispatcher: errorDispatcher{},
 		ErrChan:    errch,
 		Ready:      ready,
+		InFlight:   inFlight,
+		Strict:     true,
 	}
 	// Worker exits when the data chan is closed.
 	defer close(data)
@@ -35,6 +39,7 @@ func TestWorkerRun(t *testing.T) {
 	incoming := Incoming{
 		Data: Message{Address: "/foo"}.Bytes(),
 	}
+	inFlight.Add(1)
 	select {
 	case data <- incoming:
 	case <-time.After(1 * time.Second):
@@ -51,6 +56,72 @@ func TestWorkerRun(t *testing.T) {
 	}
 }
 
+func TestServeRecoversPanics(t *testing.T) {
+	c1, c2 := Pipe()
+	defer func() { _ = c1.Close() }() // Best effort.
+
+	var (
+		errs      = make(chan error, 1)
+		ok        = make(chan struct{}, 1)
+		serveErrs = make(chan error, 1)
+	)
+	c2.SetErrorHandler(func(err error) {
+		errs <- err
+	})
+	go func() {
+		serveErrs <- c2.Serve(1, PatternMatching{
+			"/panic": Method(func(msg Message) error {
+				panic("oops")
+			}),
+			"/ok": Method(func(msg Message) error {
+				ok <- struct{}{}
+				return nil
+			}),
+		})
+	}()
+	for _, addr := range []string{"/panic", "/ok"} {
+		if err := c1.Send(Message{Address: addr}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case err := <-errs:
+		if _, ok := err.(*MethodError); !ok {
+			t.Fatalf("expected *MethodError, got %T", err)
+		}
+		var pe *PanicError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expected *PanicError, got %v", err)
+		}
+		if pe.Value != "oops" {
+			t.Fatalf("expected oops, got %v", pe.Value)
+		}
+		if !strings.Contains(string(pe.Stack), "TestServeRecoversPanics") {
+			t.Fatalf("expected the stack of the method, got %s", pe.Stack)
+		}
+	case err := <-serveErrs:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+	select {
+	case <-ok:
+	case err := <-serveErrs:
+		t.Fatalf("server stopped: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for /ok")
+	}
+}
+
+func TestCallMethodsWithoutRecover(t *testing.T) {
+	defer func() {
+		if v := recover(); v != "oops" {
+			t.Fatalf("expected the panic to propagate, got %v", v)
+		}
+	}()
+	_ = callMethods(false, func() error { panic("oops") })
+}
+
 type errorDispatcher struct {
 }
 
@@ -0,0 +1,59 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrWriteTimeout is returned by SendContext when its context expires before the packet is sent,
+// either while it waits for the packets that are being sent before it or while it writes.
+var ErrWriteTimeout = errors.New("write timed out")
+
+// deadliner is a conn whose writes can be given a deadline.
+type deadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// writeContext calls write with the deadline of ctx set on c, and makes it return
+// as soon as ctx is canceled. The deadline is cleared before writeContext returns,
+// so that it does not apply to the writes that follow.
+func writeContext(ctx context.Context, c deadliner, write func() error) error {
+	if ctx.Done() == nil {
+		return write()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := c.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	canceled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(canceled)
+		_ = c.SetWriteDeadline(time.Unix(1, 0)) // Best effort.
+	})
+	err := write()
+	if !stop() {
+		<-canceled // Or the deadline in the past could outlive the write.
+	}
+	if clearErr := c.SetWriteDeadline(time.Time{}); err == nil {
+		err = clearErr
+	}
+	return err
+}
+
+// writeTimeoutError returns the error of a send that was given up on because ctx
+// was done as an error wrapping ErrWriteTimeout or ctx.Err(), and any other error as is.
+func writeTimeoutError(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, ctx.Err()) {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return fmt.Errorf("%w: %w", ErrWriteTimeout, err)
+}
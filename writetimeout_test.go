@@ -0,0 +1,240 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingUDPConn is a udpConn whose writes block while it is blocking, until their deadline.
+// It keeps the addresses of the messages that it was written instead of sending them.
+type blockingUDPConn struct {
+	udpConn
+
+	blocked chan struct{} // Gets a value when a write starts to block.
+
+	mu       sync.Mutex
+	blocking bool
+	deadline time.Time
+	written  []string
+}
+
+func (c *blockingUDPConn) Write(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		blocking, deadline := c.blocking, c.deadline
+		c.mu.Unlock()
+
+		if !blocking {
+			p, err := ParsePacket(b)
+			if err != nil {
+				return 0, err
+			}
+			c.mu.Lock()
+			c.written = append(c.written, p.(Message).Address)
+			c.mu.Unlock()
+			return len(b), nil
+		}
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		select {
+		case c.blocked <- struct{}{}:
+		default:
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *blockingUDPConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *blockingUDPConn) writeDeadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline
+}
+
+func (c *blockingUDPConn) writtenAddresses() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strings.Join(c.written, " ")
+}
+
+func (c *blockingUDPConn) setBlocking(blocking bool) {
+	c.mu.Lock()
+	c.blocking = blocking
+	c.mu.Unlock()
+}
+
+func TestUDPConnSendContext(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp, err := ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = udp.Close() }() // Best effort.
+
+	fake := &blockingUDPConn{udpConn: udp.udpConn, blocked: make(chan struct{}, 1), blocking: true}
+	conn := &UDPConn{udpConn: fake, connState: newConnState(context.Background())}
+	timeout := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		return conn.SendContext(ctx, MustMessage("/timeout"))
+	}
+
+	// A send that waits for the packet before it times out too, without holding it up.
+	sent := make(chan error, 1)
+	go func() {
+		sent <- conn.Send(MustMessage("/blocked"))
+	}()
+	<-fake.blocked
+	if err := timeout(); !errors.Is(err, ErrWriteTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrWriteTimeout waiting for the send buffer, got %v", err)
+	}
+	fake.setBlocking(false)
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+
+	// A send that times out writing leaves no deadline behind.
+	fake.setBlocking(true)
+	if err := timeout(); !errors.Is(err, ErrWriteTimeout) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrWriteTimeout writing, got %v", err)
+	}
+	if deadline := fake.writeDeadline(); !deadline.IsZero() {
+		t.Fatalf("expected the write deadline to be cleared, got %s", deadline)
+	}
+
+	// Canceling the context stops the write too.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := conn.SendContext(ctx, MustMessage("/canceled")); !errors.Is(err, context.Canceled) || errors.Is(err, ErrWriteTimeout) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if deadline := fake.writeDeadline(); !deadline.IsZero() {
+		t.Fatalf("expected the write deadline to be cleared, got %s", deadline)
+	}
+
+	// The conn is still usable.
+	fake.setBlocking(false)
+	if err := conn.SendContext(context.Background(), MustMessage("/after")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Send(MustMessage("/after")); err != nil {
+		t.Fatal(err)
+	}
+	if stats := conn.Stats(); stats.PacketsSent != 3 || stats.SendErrors != 3 {
+		t.Fatalf("expected 3 packets sent and 3 errors, got %+v", stats)
+	}
+}
+
+func TestTCPConnSendContext(t *testing.T) {
+	laddr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.ListenTCP("tcp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = listener.Close() }() // Best effort.
+
+	peers := make(chan *net.TCPConn, 1)
+	go func() {
+		peer, err := listener.AcceptTCP()
+		if err != nil {
+			close(peers)
+			return
+		}
+		_ = peer.SetReadBuffer(1024) // Best effort.
+		peers <- peer
+	}()
+	client, err := DialTCP("tcp", nil, listener.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	peer := <-peers
+	if peer == nil {
+		t.Fatal("accept failed")
+	}
+	defer func() { _ = peer.Close() }() // Best effort.
+	if err := client.conn.SetWriteBuffer(1024); err != nil {
+		t.Fatal(err)
+	}
+
+	// The peer does not read, so the socket buffers fill up until a send times out.
+	var (
+		msg  = MustMessage("/fill", make([]byte, 1000))
+		sent int
+	)
+	for ; ; sent++ {
+		if sent == 10000 {
+			t.Fatal("expected a send to time out")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := client.SendContext(ctx, msg)
+		cancel()
+		if errors.Is(err, ErrWriteTimeout) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The peer reads every packet whole, and then the ones sent after the timeout.
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Send(MustMessage("/after"))
+	}()
+	if err := peer.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		p, err := ReadPacket(peer, 2048)
+		if err != nil {
+			t.Fatalf("(packet %d) %s", i, err)
+		}
+		got := p.(Message)
+		if got.Address == "/after" {
+			if i < sent {
+				t.Fatalf("expected at least %d packets before /after, got %d", sent, i)
+			}
+			break
+		}
+		if !got.Equal(msg) {
+			t.Fatalf("(packet %d) expected %s, got %s", i, msg, got)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendContext(context.Background(), MustMessage("/again")); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := ReadPacket(peer, 2048); err != nil || p.(Message).Address != "/again" {
+		t.Fatalf("expected /again, got %v (%v)", p, err)
+	}
+
+	// A listener has no peer to send to.
+	server, _ := testTCPServer(t, PatternMatching{})
+	defer func() { _ = server.Close() }() // Best effort.
+	if err := server.SendContext(context.Background(), msg); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected ErrNotConnected, got %v", err)
+	}
+}
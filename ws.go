@@ -0,0 +1,264 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Common errors.
+var (
+	ErrTextFrame = errors.New("websocket text frames can not carry OSC packets")
+)
+
+// wsCloseTimeout is how long Close waits to send a close frame to the peer.
+const wsCloseTimeout = time.Second
+
+// WSConn is an OSC connection over a WebSocket.
+// Every binary frame carries exactly one OSC packet, which is the
+// convention used by browser-based OSC clients.
+//
+// Text frames are reported to the error handler as a *ParseError
+// and otherwise ignored, like frames that do not contain a valid OSC packet.
+type WSConn struct {
+	connState
+
+	ws   *websocket.Conn
+	opts options
+
+	closeOnce sync.Once
+	writeMu   sync.Mutex
+}
+
+// DialWebsocket creates a new OSC connection to a WebSocket server.
+// url is a ws:// or wss:// URL.
+func DialWebsocket(url string, opts ...Option) (*WSConn, error) {
+	return DialWebsocketContext(context.Background(), url, opts...)
+}
+
+// DialWebsocketContext creates a new OSC connection to a WebSocket server that can be canceled with the provided context.
+func DialWebsocketContext(ctx context.Context, url string, opts ...Option) (*WSConn, error) {
+	o, err := newStreamOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dialing websocket: %w", err)
+	}
+	return newWSConn(ctx, ws, o), nil
+}
+
+// UpgradeWebsocket upgrades an HTTP request to an OSC connection over a WebSocket.
+// The connection uses the request's context.
+// Requests whose Origin header does not match the Host header are rejected
+// unless a custom check is provided with WithCheckOrigin.
+func UpgradeWebsocket(w http.ResponseWriter, r *http.Request, opts ...Option) (*WSConn, error) {
+	o, err := newStreamOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: o.checkOrigin}
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return nil, fmt.Errorf("upgrading to websocket: %w", err)
+	}
+	return newWSConn(r.Context(), ws, o), nil
+}
+
+// WebsocketHandler returns an http.Handler that upgrades each request to an
+// OSC connection and calls serve with it.
+// The connection is closed when serve returns.
+// serve will typically call conn.Serve with a dispatcher whose methods
+// reply to the client with conn.Send.
+func WebsocketHandler(serve func(conn *WSConn), opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := UpgradeWebsocket(w, r, opts...)
+		if err != nil {
+			// The upgrader has already replied with an HTTP error.
+			return
+		}
+		defer func() { _ = conn.Close() }() // Best effort.
+
+		serve(conn)
+	})
+}
+
+// WithCheckOrigin sets the function a WebSocket server uses to decide
+// whether to accept a request based on its Origin header.
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(o *options) error {
+		o.checkOrigin = check
+		return nil
+	}
+}
+
+// newWSConn creates a WSConn.
+func newWSConn(ctx context.Context, ws *websocket.Conn, o options) *WSConn {
+	ws.SetReadLimit(int64(o.maxPacketSize))
+
+	conn := &WSConn{
+		connState: newConnState(ctx),
+		ws:        ws,
+		opts:      o,
+	}
+	conn.readBufSize = o.maxPacketSize
+	conn.applyOptions(o)
+	return conn
+}
+
+// Close sends a close frame to the peer and closes the connection.
+func (conn *WSConn) Close() error {
+	closed := false
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+		closed = true
+	})
+	if !closed {
+		return nil
+	}
+	var (
+		msg      = websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		deadline = time.Now().Add(wsCloseTimeout)
+	)
+	_ = conn.ws.WriteControl(websocket.CloseMessage, msg, deadline) // Best effort.
+
+	return conn.ws.Close()
+}
+
+// LocalAddr returns the local network address.
+func (conn *WSConn) LocalAddr() net.Addr {
+	return conn.ws.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (conn *WSConn) RemoteAddr() net.Addr {
+	return conn.ws.RemoteAddr()
+}
+
+// Send sends a packet to the peer in a single binary frame.
+func (conn *WSConn) Send(p Packet) error {
+	return conn.send(conn.RemoteAddr(), p, func(data []byte) error {
+		conn.writeMu.Lock()
+		defer conn.writeMu.Unlock()
+
+		return conn.ws.WriteMessage(websocket.BinaryMessage, data)
+	})
+}
+
+// SendTo sends a packet to the given address, which must be the address of the peer.
+func (conn *WSConn) SendTo(addr net.Addr, p Packet) error {
+	if addr.String() != conn.RemoteAddr().String() {
+		return fmt.Errorf("websocket conn is connected to %s, not %s", conn.RemoteAddr(), addr)
+	}
+	return conn.Send(p)
+}
+
+// SendBundle sends msgs in a single bundle with the given timetag.
+// Bundles larger than the max packet size of the conn are rejected with ErrPacketTooLarge.
+func (conn *WSConn) SendBundle(tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.Send(p)
+}
+
+// SendBundleTo sends msgs to addr in a single bundle with the given timetag.
+func (conn *WSConn) SendBundleTo(addr net.Addr, tt Timetag, msgs ...Message) error {
+	p, err := encodeBundle(tt, msgs, conn.sendLimit())
+	if err != nil {
+		return err
+	}
+	return conn.SendTo(addr, p)
+}
+
+// Reply sends msg to the sender of a message that was read from the conn.
+// A connected conn ignores to and sends msg to its peer.
+func (conn *WSConn) Reply(to net.Addr, msg Message) error {
+	return reply(conn, to, msg)
+}
+
+// Call sends request and returns the first message to replyAddress that the conn
+// reads afterwards, which is not dispatched, or ctx.Err() if ctx is done first.
+// The conn must be serving. Calls waiting for the same address get the replies
+// in the order they were made.
+func (conn *WSConn) Call(ctx context.Context, request Message, replyAddress string) (Message, error) {
+	return conn.call(ctx, conn.Send, request, replyAddress)
+}
+
+// Ping sends a ping to addr, or to the peer of a connected conn if addr is empty,
+// and returns the time until the echo of the peer's EchoMethod is read,
+// or ctx.Err() if ctx is done first. The conn must be serving.
+// Every ping carries a random token, so concurrent pings get their own echo.
+func (conn *WSConn) Ping(ctx context.Context, addr string) (time.Duration, error) {
+	if addr == "" {
+		return conn.ping(ctx, conn.Send)
+	}
+	return conn.ping(ctx, sendToPeer(conn, pingAddr(addr)))
+}
+
+// Receive reads packets onto the returned channel instead of dispatching them,
+// until ctx is done or the conn is closed or shut down, which closes the channel.
+// Packets that can not be parsed are passed on with Err set.
+// The channel holds as many packets as the queue size. When it is full Receive
+// waits for room, or drops the oldest packet or the new one and counts it in Dropped
+// if the queue policy is DropOldest or DropNewest.
+// A conn can not be served and received from at once: Receive returns ErrServing
+// while Serve is running, and Serve returns ErrReceiving until Receive has stopped.
+func (conn *WSConn) Receive(ctx context.Context) (<-chan Incoming, error) {
+	return receive(ctx, conn, &conn.connState)
+}
+
+// ReceivePacket reads and parses a single packet and returns it with its sender.
+// The read gives up with ctx.Err() when ctx is done.
+// Packets that can not be parsed are returned as a *ParseError, so the caller
+// can read the next one. ReceivePacket returns ErrServing while Serve is running
+// and ErrReceiving while Receive is.
+func (conn *WSConn) ReceivePacket(ctx context.Context) (Packet, net.Addr, error) {
+	return receivePacket(ctx, conn, &conn.connState)
+}
+
+// Serve starts dispatching OSC.
+// Packets that can not be parsed and errors returned from methods are passed to
+// the error handler and do not stop the server, unless the conn is in strict mode.
+// If context.Canceled or context.DeadlineExceeded are encountered they will be returned directly.
+// The connection is closed, and Serve returns nil, when the peer closes the WebSocket.
+func (conn *WSConn) Serve(numWorkers int, dispatcher Dispatcher) error {
+	return conn.ServeContext(context.Background(), numWorkers, dispatcher)
+}
+
+// ServeContext is like Serve, but it also stops serving when ctx is done
+// and returns ctx.Err() once the methods that are already running have returned.
+// The conn is left open.
+func (conn *WSConn) ServeContext(ctx context.Context, numWorkers int, dispatcher Dispatcher) error {
+	return serve(ctx, conn, numWorkers, conn.exactMatch, dispatcher)
+}
+
+// read reads the next binary frame.
+func (conn *WSConn) read(data []byte) (int, net.Addr, error) {
+	sender := conn.RemoteAddr()
+
+	for {
+		messageType, p, err := conn.ws.ReadMessage()
+		if err != nil {
+			if _, ok := err.(*websocket.CloseError); ok {
+				// The peer hung up, so there is nothing more to serve.
+				_ = conn.Close()
+			}
+			return 0, nil, err
+		}
+		if messageType != websocket.BinaryMessage {
+			conn.handleError(&ParseError{Sender: sender, Err: ErrTextFrame})
+			continue
+		}
+		return copy(data, p), sender, nil
+	}
+}
@@ -0,0 +1,132 @@
+package osc
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testWebsocketServer starts an HTTP server that serves OSC over WebSockets
+// and returns its ws:// URL and a channel that emits the errors reported by
+// the server connections.
+func testWebsocketServer(t *testing.T) (*httptest.Server, string, chan error) {
+	errs := make(chan error, 8)
+	server := httptest.NewServer(WebsocketHandler(func(conn *WSConn) {
+		conn.SetErrorHandler(func(err error) {
+			errs <- err
+		})
+		_ = conn.Serve(1, PatternMatching{
+			"/ping": Method(func(msg Message) error {
+				return conn.Send(Message{Address: "/pong", Arguments: msg.Arguments})
+			}),
+		})
+	}))
+	return server, "ws" + strings.TrimPrefix(server.URL, "http"), errs
+}
+
+// waitError waits for an error to be reported on errs.
+func waitError(t *testing.T, errs chan error) error {
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for error")
+	}
+	return nil
+}
+
+func TestWSConnSendAndReply(t *testing.T) {
+	server, url, _ := testWebsocketServer(t)
+	defer server.Close()
+
+	client, err := DialWebsocket(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pongs := make(chan Message)
+	clientErrs := make(chan error)
+	go func() {
+		clientErrs <- client.Serve(1, PatternMatching{
+			"/pong": Method(func(msg Message) error {
+				pongs <- msg
+				return nil
+			}),
+		})
+	}()
+	expected := Message{Address: "/pong", Arguments: Arguments{Int(4), String("ws")}}
+	if err := client.Send(Message{Address: "/ping", Arguments: expected.Arguments}); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMessage(t, pongs, clientErrs); !expected.Equal(got) {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-clientErrs; err != nil {
+		t.Fatal(err)
+	}
+	// Closing again is harmless.
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWSConnBadFrames(t *testing.T) {
+	server, url, errs := testWebsocketServer(t)
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ws.Close() }() // Best effort.
+
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(`{"address":"/ping"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitError(t, errs); !errors.Is(err, ErrTextFrame) {
+		t.Fatalf("expected ErrTextFrame, got %v", err)
+	}
+	if err := ws.WriteMessage(websocket.BinaryMessage, []byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitError(t, errs); !errors.Is(err, ErrParse) {
+		t.Fatalf("expected ErrParse, got %v", err)
+	}
+
+	// The connection is still served.
+	if err := ws.WriteMessage(websocket.BinaryMessage, Message{Address: "/ping"}.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.SetReadDeadline(time.Now().Add(2 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	messageType, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != websocket.BinaryMessage {
+		t.Fatalf("expected a binary frame, got frame type %d", messageType)
+	}
+	msg, err := ParseMessage(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Address != "/pong" {
+		t.Fatalf("expected /pong, got %s", msg.Address)
+	}
+}
+
+func TestDialWebsocket(t *testing.T) {
+	if _, err := DialWebsocket("ws://127.0.0.1:1"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if _, err := DialWebsocket("ws://127.0.0.1:1", WithMaxPacketSize(0)); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
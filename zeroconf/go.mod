@@ -0,0 +1,15 @@
+module github.com/scgolang/osc/zeroconf
+
+go 1.26.0
+
+require (
+	github.com/scgolang/osc v0.0.0
+	golang.org/x/net v0.59.0
+)
+
+require (
+	github.com/gorilla/websocket v1.5.3 // indirect
+	golang.org/x/sys v0.48.0 // indirect
+)
+
+replace github.com/scgolang/osc => ../
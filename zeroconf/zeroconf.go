@@ -0,0 +1,476 @@
+// Package zeroconf advertises and discovers OSC services with multicast DNS
+// service discovery (mDNS/DNS-SD, also known as Bonjour), under the _osc._udp
+// service type that TouchOSC and the liblo-based apps use.
+//
+// It speaks just enough of the protocol for that: the responder of Advertise
+// answers the queries for its own service, and Discover sends queries from its
+// own port, which responders answer directly. It depends on golang.org/x/net,
+// like the osc package, and on nothing else.
+//
+// It is a module of its own, so that only the programs that use mDNS
+// depend on it.
+package zeroconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scgolang/osc"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ServiceType is the DNS-SD service type of OSC over UDP.
+const ServiceType = "_osc._udp"
+
+const (
+	// domain is the domain of multicast DNS.
+	domain = "local."
+
+	// ttl is the time to live of the records of a service, in seconds.
+	ttl = 120
+
+	// maxPacketSize is the size of the largest mDNS packet that is read.
+	maxPacketSize = 9000
+)
+
+// mdnsGroup is the multicast group of mDNS over IPv4.
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Common errors.
+var (
+	ErrInvalidName = errors.New("invalid service name")
+	ErrNoAddress   = errors.New("service has no address")
+)
+
+// Service is an OSC service on the local network.
+type Service struct {
+	// Name is the name of the instance of the service, e.g. "TouchOSC Bridge".
+	Name string
+
+	// Host is the host name of the service, e.g. "studio.local.", and Port its port.
+	Host string
+	Port int
+
+	// Addrs are the IP addresses of the host.
+	Addrs []net.IP
+
+	// Text are the TXT records of the service, which are usually "key=value" pairs.
+	Text []string
+}
+
+// Advertise advertises conn, which must be listening, as an OSC service called name
+// on every interface that supports multicast, with the given TXT records. It answers
+// queries until stop is called. stop tells the peers that the service is gone.
+func Advertise(name string, conn *osc.UDPConn, text ...string) (stop func(), err error) {
+	laddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return nil, fmt.Errorf("advertise: conn has no UDP address: %w", ErrNoAddress)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("advertise: %w", err)
+	}
+	service := Service{
+		Name:  name,
+		Host:  strings.SplitN(host, ".", 2)[0] + "." + domain,
+		Port:  laddr.Port,
+		Addrs: hostAddrs(laddr.IP),
+		Text:  text,
+	}
+	pc, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("advertise: %w", err)
+	}
+	r, err := newResponder(pc, service, mdnsGroup)
+	if err != nil {
+		_ = pc.Close() // Best effort.
+		return nil, fmt.Errorf("advertise: %w", err)
+	}
+	go r.serve()
+	r.announce()
+	return r.stop, nil
+}
+
+// Discover looks for OSC services until ctx is done, and sends every service it
+// finds on the channel it returns, once, which is closed when ctx is done.
+func Discover(ctx context.Context) (<-chan Service, error) {
+	pc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, fmt.Errorf("discover: %w", err)
+	}
+	return discover(ctx, pc, mdnsGroup), nil
+}
+
+// DialService dials the service with the given options.
+// If the service has no address its host name is resolved.
+func DialService(s Service, opts ...osc.Option) (*osc.UDPConn, error) {
+	raddr := &net.UDPAddr{Port: s.Port}
+	if len(s.Addrs) > 0 {
+		raddr.IP = s.Addrs[0]
+	} else {
+		if s.Host == "" {
+			return nil, fmt.Errorf("dial service %q: %w", s.Name, ErrNoAddress)
+		}
+		addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(strings.TrimSuffix(s.Host, "."), fmt.Sprint(s.Port)))
+		if err != nil {
+			return nil, fmt.Errorf("dial service %q: %w", s.Name, err)
+		}
+		raddr = addr
+	}
+	return osc.DialUDP("udp", nil, raddr, opts...)
+}
+
+// names are the DNS names of a service.
+type names struct {
+	service  dnsmessage.Name // _osc._udp.local.
+	instance dnsmessage.Name // name._osc._udp.local.
+	host     dnsmessage.Name
+}
+
+// newNames returns the names of s.
+func newNames(s Service) (names, error) {
+	if s.Name == "" || strings.ContainsAny(s.Name, ".\\") {
+		return names{}, fmt.Errorf("%q: %w", s.Name, ErrInvalidName)
+	}
+	var (
+		n   names
+		err error
+	)
+	if n.service, err = dnsmessage.NewName(ServiceType + "." + domain); err != nil {
+		return n, err
+	}
+	if n.instance, err = dnsmessage.NewName(s.Name + "." + ServiceType + "." + domain); err != nil {
+		return n, fmt.Errorf("%q: %w", s.Name, ErrInvalidName)
+	}
+	if n.host, err = dnsmessage.NewName(s.Host); err != nil {
+		return n, fmt.Errorf("host %q: %w", s.Host, err)
+	}
+	return n, nil
+}
+
+// responder answers the queries for a service.
+type responder struct {
+	pc      net.PacketConn
+	service Service
+	names   names
+	group   *net.UDPAddr
+
+	stopOnce sync.Once
+}
+
+// newResponder creates a responder that reads queries from pc and
+// multicasts its answers to group.
+func newResponder(pc net.PacketConn, service Service, group *net.UDPAddr) (*responder, error) {
+	n, err := newNames(service)
+	if err != nil {
+		return nil, err
+	}
+	return &responder{pc: pc, service: service, names: n, group: group}, nil
+}
+
+// serve answers queries until the responder is stopped.
+func (r *responder) serve() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := r.pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		r.answer(buf[:n], from)
+	}
+}
+
+// answer answers a query if it asks about the service.
+// Queries that are not sent from the mDNS port are answered directly,
+// and the others with multicast.
+func (r *responder) answer(query []byte, from net.Addr) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil || h.Response {
+		return
+	}
+	questions, err := p.AllQuestions()
+	if err != nil || !r.asked(questions) {
+		return
+	}
+	if addr, ok := from.(*net.UDPAddr); ok && addr.Port != r.group.Port {
+		// A legacy unicast query gets its ID and questions back.
+		if resp, err := r.response(h.ID, questions, ttl); err == nil {
+			_, _ = r.pc.WriteTo(resp, from) // Best effort.
+		}
+		return
+	}
+	if resp, err := r.response(0, nil, ttl); err == nil {
+		_, _ = r.pc.WriteTo(resp, r.group) // Best effort.
+	}
+}
+
+// asked returns true if one of questions is about the service.
+func (r *responder) asked(questions []dnsmessage.Question) bool {
+	for _, q := range questions {
+		name := q.Name.String()
+		switch {
+		case strings.EqualFold(name, r.names.service.String()) && (q.Type == dnsmessage.TypePTR || q.Type == dnsmessage.TypeALL):
+			return true
+		case strings.EqualFold(name, r.names.instance.String()):
+			return true
+		}
+	}
+	return false
+}
+
+// response returns the records of the service with the given time to live,
+// as an answer to questions.
+func (r *responder) response(id uint16, questions []dnsmessage.Question, ttl uint32) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, Authoritative: true})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, q := range questions {
+		if err := b.Question(q); err != nil {
+			return nil, err
+		}
+	}
+	header := func(name dnsmessage.Name) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if err := b.PTRResource(header(r.names.service), dnsmessage.PTRResource{PTR: r.names.instance}); err != nil {
+		return nil, err
+	}
+	if err := b.StartAdditionals(); err != nil {
+		return nil, err
+	}
+	srv := dnsmessage.SRVResource{Port: uint16(r.service.Port), Target: r.names.host}
+	if err := b.SRVResource(header(r.names.instance), srv); err != nil {
+		return nil, err
+	}
+	text := r.service.Text
+	if len(text) == 0 {
+		text = []string{""} // A TXT record can not be empty.
+	}
+	if err := b.TXTResource(header(r.names.instance), dnsmessage.TXTResource{TXT: text}); err != nil {
+		return nil, err
+	}
+	for _, ip := range r.service.Addrs {
+		if ip4 := ip.To4(); ip4 != nil {
+			var a dnsmessage.AResource
+			copy(a.A[:], ip4)
+			if err := b.AResource(header(r.names.host), a); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var a dnsmessage.AAAAResource
+		copy(a.AAAA[:], ip.To16())
+		if err := b.AAAAResource(header(r.names.host), a); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// announce multicasts the records of the service, so the peers that are
+// already looking for services find it.
+func (r *responder) announce() {
+	if resp, err := r.response(0, nil, ttl); err == nil {
+		_, _ = r.pc.WriteTo(resp, r.group) // Best effort.
+	}
+}
+
+// stop multicasts the records of the service with a time to live of zero,
+// which tells the peers that it is gone, and stops answering queries.
+func (r *responder) stop() {
+	r.stopOnce.Do(func() {
+		if resp, err := r.response(0, nil, 0); err == nil {
+			_, _ = r.pc.WriteTo(resp, r.group) // Best effort.
+		}
+		_ = r.pc.Close() // Best effort.
+	})
+}
+
+// discover sends queries for OSC services from pc to dst, and sends the services
+// in the answers on the channel it returns, until ctx is done.
+// The queries are repeated with an interval that doubles from one second to a minute.
+func discover(ctx context.Context, pc net.PacketConn, dst net.Addr) <-chan Service {
+	var (
+		services = make(chan Service)
+		done     = make(chan struct{})
+	)
+	go func() {
+		<-ctx.Done()
+		_ = pc.Close() // Best effort, this stops the read loop.
+		close(done)
+	}()
+	go func() {
+		interval := time.Second
+		for {
+			if query, err := newQuery(); err == nil {
+				_, _ = pc.WriteTo(query, dst) // Best effort.
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(interval):
+			}
+			if interval *= 2; interval > time.Minute {
+				interval = time.Minute
+			}
+		}
+	}()
+	go func() {
+		defer close(services)
+
+		var (
+			buf  = make([]byte, maxPacketSize)
+			seen = map[string]bool{}
+		)
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			for _, s := range parseServices(buf[:n]) {
+				if seen[s.Name] {
+					continue
+				}
+				seen[s.Name] = true
+				select {
+				case services <- s:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	return services
+}
+
+// newQuery returns a query for the instances of the OSC service.
+func newQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(ServiceType + "." + domain)
+	if err != nil {
+		return nil, err
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Intn(1 << 16))})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// parseServices returns the OSC services that a response describes completely.
+// Records with a time to live of zero say that a service is gone, so they are skipped.
+func parseServices(resp []byte) []Service {
+	var p dnsmessage.Parser
+	h, err := p.Start(resp)
+	if err != nil || !h.Response {
+		return nil
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil
+	}
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return nil
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		return nil
+	}
+	additionals, err := p.AllAdditionals()
+	if err != nil {
+		return nil
+	}
+	var (
+		suffix    = "." + ServiceType + "." + domain
+		instances []string
+		srvs      = map[string]*dnsmessage.SRVResource{}
+		texts     = map[string][]string{}
+		addrs     = map[string][]net.IP{}
+	)
+	for _, rr := range append(answers, additionals...) {
+		if rr.Header.TTL == 0 {
+			continue
+		}
+		name := strings.ToLower(rr.Header.Name.String())
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if strings.EqualFold(name, ServiceType+"."+domain) {
+				instances = append(instances, body.PTR.String())
+			}
+		case *dnsmessage.SRVResource:
+			srvs[name] = body
+		case *dnsmessage.TXTResource:
+			texts[name] = body.TXT
+		case *dnsmessage.AResource:
+			addrs[name] = append(addrs[name], net.IP(append([]byte(nil), body.A[:]...)))
+		case *dnsmessage.AAAAResource:
+			addrs[name] = append(addrs[name], net.IP(append([]byte(nil), body.AAAA[:]...)))
+		}
+	}
+	var services []Service
+	for _, instance := range instances {
+		srv, ok := srvs[strings.ToLower(instance)]
+		if !ok || len(instance) <= len(suffix) {
+			continue
+		}
+		host := srv.Target.String()
+		s := Service{
+			Name:  instance[:len(instance)-len(suffix)],
+			Host:  host,
+			Port:  int(srv.Port),
+			Addrs: addrs[strings.ToLower(host)],
+		}
+		for _, t := range texts[strings.ToLower(instance)] {
+			if t != "" {
+				s.Text = append(s.Text, t)
+			}
+		}
+		services = append(services, s)
+	}
+	return services
+}
+
+// hostAddrs returns the addresses of a conn that listens on ip: ip itself,
+// or the addresses of the interfaces of the host if ip is unspecified,
+// not counting the loopback addresses unless there is nothing else.
+func hostAddrs(ip net.IP) []net.IP {
+	if ip != nil && !ip.IsUnspecified() {
+		return []net.IP{ip}
+	}
+	ifaddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	var addrs, loopback []net.IP
+	for _, a := range ifaddrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		switch {
+		case ipnet.IP.IsLoopback():
+			loopback = append(loopback, ipnet.IP)
+		case ipnet.IP.IsGlobalUnicast():
+			addrs = append(addrs, ipnet.IP)
+		}
+	}
+	if len(addrs) == 0 {
+		return loopback
+	}
+	return addrs
+}
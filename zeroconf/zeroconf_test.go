@@ -0,0 +1,172 @@
+package zeroconf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scgolang/osc"
+)
+
+// listenLocal starts a responder for s on a local unicast address,
+// and returns the address.
+func listenLocal(t *testing.T, s Service) (*responder, net.Addr) {
+	t.Helper()
+
+	pc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newResponder(pc, s, mdnsGroup)
+	if err != nil {
+		_ = pc.Close() // Best effort.
+		t.Fatal(err)
+	}
+	go r.serve()
+	return r, pc.LocalAddr()
+}
+
+func TestDiscover(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := osc.ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	service := Service{
+		Name:  "Stage Left",
+		Host:  "studio.local.",
+		Port:  server.LocalAddr().(*net.UDPAddr).Port,
+		Addrs: []net.IP{net.IPv4(127, 0, 0, 1).To4()},
+		Text:  []string{"txtvers=1", "app=test"},
+	}
+	r, raddr := listenLocal(t, service)
+	defer r.stop()
+
+	pc, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var found Service
+	select {
+	case s, ok := <-discover(ctx, pc, raddr):
+		if !ok {
+			t.Fatal("expected a service")
+		}
+		found = s
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for the service")
+	}
+	if !reflect.DeepEqual(found, service) {
+		t.Fatalf("expected %+v, got %+v", service, found)
+	}
+	// The service that was found can be dialed.
+	received := make(chan osc.Message, 1)
+	go func() {
+		_ = server.Serve(1, osc.PatternMatching{
+			"/hello": osc.Method(func(msg osc.Message) error {
+				received <- msg
+				return nil
+			}),
+		})
+	}()
+	client, err := DialService(found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }() // Best effort.
+
+	if err := client.Send(osc.MustMessage("/hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the message")
+	}
+}
+
+func TestResponder(t *testing.T) {
+	r, err := newResponder(nil, Service{Name: "synth", Host: "box.local.", Port: 57110}, mdnsGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, testcase := range []struct {
+		TTL      uint32
+		Expected []Service
+	}{
+		{TTL: ttl, Expected: []Service{{Name: "synth", Host: "box.local.", Port: 57110}}},
+		{TTL: 0}, // Goodbye.
+	} {
+		resp, err := r.response(0, nil, testcase.TTL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if services := parseServices(resp); !reflect.DeepEqual(services, testcase.Expected) {
+			t.Fatalf("(testcase %d) expected %+v, got %+v", i, testcase.Expected, services)
+		}
+	}
+	// Queries for other services are not answered.
+	query, err := newQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseServices(query) != nil {
+		t.Fatal("expected a query to describe no services")
+	}
+}
+
+func TestNewNames(t *testing.T) {
+	for i, name := range []string{"", "a.b", `a\b`} {
+		if _, err := newNames(Service{Name: name, Host: "box.local."}); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("(testcase %d) expected ErrInvalidName, got %v", i, err)
+		}
+	}
+	if _, err := DialService(Service{Name: "nowhere"}); !errors.Is(err, ErrNoAddress) {
+		t.Fatalf("expected ErrNoAddress, got %v", err)
+	}
+}
+
+func TestAdvertise(t *testing.T) {
+	laddr, err := net.ResolveUDPAddr("udp", "0.0.0.0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := osc.ListenUDP("udp", laddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = server.Close() }() // Best effort.
+
+	stop, err := Advertise("osc zeroconf test", server, "txtvers=1")
+	if err != nil {
+		t.Skipf("can not advertise with multicast: %s", err)
+	}
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	services, err := Discover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s := range services {
+		if s.Name == "osc zeroconf test" {
+			if s.Port != server.LocalAddr().(*net.UDPAddr).Port {
+				t.Fatalf("expected port %d, got %d", server.LocalAddr().(*net.UDPAddr).Port, s.Port)
+			}
+			return
+		}
+	}
+	t.Skip("multicast is not delivered on this host")
+}